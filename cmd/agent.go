@@ -0,0 +1,493 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"golang.org/x/term"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/agent"
+	"github.com/DylanBlakemore/initflow-cli/internal/client"
+	"github.com/DylanBlakemore/initflow-cli/internal/config"
+	"github.com/DylanBlakemore/initflow-cli/internal/storage"
+)
+
+var agentCmd = &cobra.Command{
+	Use:   "agent",
+	Short: "Keep workspace keys in a background agent",
+	Long: `The agent keeps the workspace keys it has opened in locked memory and answers secret
+lookups over a Unix socket only you can use, so 'secrets get', 'run', 'render' and the other
+commands that read secrets don't log in, look up the workspace and unwrap its key every
+time. Secrets are still fetched on each lookup, so changes show up at once.
+
+Commands use the agent whenever it is running for the same API and profile, and fall back
+to working on their own when it isn't. The socket is ~/.initflow/agent.sock, or
+agent-<profile>.sock for other profiles; set ` + agent.SocketEnvVar + ` to use another.`,
+}
+
+var agentStartCmd = &cobra.Command{
+	Use:   "start",
+	Short: "Start the agent in the background",
+	Long: "Start the agent in the background and wait until it is listening. With encrypt_keys set " +
+		"the key passphrase is asked for now, as the agent can't prompt later. --foreground keeps " +
+		"the agent attached to the terminal until it is stopped or interrupted.",
+	Args: cobra.NoArgs,
+	RunE: runAgentStart,
+}
+
+var agentStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show whether the agent is running and the workspaces it holds keys for",
+	Args:  cobra.NoArgs,
+	RunE:  runAgentStatus,
+}
+
+var agentStopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop the agent, wiping the keys it holds",
+	Args:  cobra.NoArgs,
+	RunE:  runAgentStop,
+}
+
+var agentForeground bool
+
+// agentStartTimeout is how long start waits for the agent to listen
+var agentStartTimeout = 10 * time.Second
+
+func init() {
+	rootCmd.AddCommand(agentCmd)
+	agentCmd.AddCommand(agentStartCmd)
+	agentCmd.AddCommand(agentStatusCmd)
+	agentCmd.AddCommand(agentStopCmd)
+
+	agentStartCmd.Flags().BoolVar(&agentForeground, "foreground", false, "run the agent in this terminal instead of the background")
+}
+
+// agentSocket returns the socket of the active profile's agent
+func agentSocket() (string, error) {
+	path, err := agent.SocketPath(config.Get().ActiveProfile())
+	if err != nil {
+		return "", fmt.Errorf("❌ Failed to find the agent socket: %w", err)
+	}
+	return path, nil
+}
+
+// agentIdentity names the API and credential store of this command; the agent
+// only answers commands with its own
+func agentIdentity() string {
+	cfg := config.Get()
+	return strings.Join([]string{cfg.APIBaseURL, cfg.ServiceName, cfg.ActiveProfile()}, " ")
+}
+
+// secretsFromAgent asks a running agent for secrets. It returns
+// agent.ErrNotRunning when there is no agent to ask, which includes commands
+// run with INITFLOW_TOKEN or against another API or profile than the agent's.
+func secretsFromAgent(req agent.Request) (*client.Workspace, map[string]string, error) {
+	if scopedToken() != "" {
+		return nil, nil, agent.ErrNotRunning
+	}
+	path, err := agent.SocketPath(config.Get().ActiveProfile())
+	if err != nil {
+		return nil, nil, agent.ErrNotRunning
+	}
+
+	req.Op, req.Identity = agent.OpSecrets, agentIdentity()
+	resp, err := agent.Call(path, req)
+	if errors.Is(err, agent.ErrWrongIdentity) {
+		return nil, nil, agent.ErrNotRunning
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.Secrets == nil {
+		resp.Secrets = map[string]string{}
+	}
+	return resp.Workspace, resp.Secrets, nil
+}
+
+// agentWorkspace is a workspace the agent has opened, with its key. mu keeps
+// the key from being destroyed while a request decrypts with it.
+type agentWorkspace struct {
+	workspace *client.Workspace
+	mu        sync.RWMutex
+	key       *agent.LockedKey
+}
+
+// withKey calls fn with the workspace key, unless the key was destroyed
+func (w *agentWorkspace) withKey(fn func(key []byte) error) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.key == nil {
+		return fmt.Errorf("the key of %s was forgotten", w.workspace.Slug)
+	}
+	return fn(w.key.Bytes())
+}
+
+// destroy wipes the key once no request is using it
+func (w *agentWorkspace) destroy() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.key != nil {
+		w.key.Destroy()
+		w.key = nil
+	}
+}
+
+// agentServer answers the agent's requests. It keeps an API client per
+// environment and the workspaces it has opened. mu only guards those maps;
+// requests talk to the API without holding it.
+type agentServer struct {
+	mu         sync.Mutex
+	store      *storage.Storage
+	identity   string
+	status     agent.Status
+	clients    map[string]*client.Client
+	workspaces map[string]*agentWorkspace
+	stop       func()
+}
+
+func newAgentServer(socket string) *agentServer {
+	return &agentServer{
+		store:      storage.New(),
+		identity:   agentIdentity(),
+		status:     agent.Status{PID: os.Getpid(), Socket: socket, StartedAt: time.Now().UTC()},
+		clients:    map[string]*client.Client{},
+		workspaces: map[string]*agentWorkspace{},
+	}
+}
+
+func (s *agentServer) handle(req agent.Request) agent.Response {
+	switch req.Op {
+	case agent.OpStatus:
+		status := s.status
+		status.Workspaces = s.openWorkspaces()
+		return agent.Response{Status: &status}
+
+	case agent.OpStop:
+		s.stop()
+		return agent.Response{}
+
+	case agent.OpSecrets:
+		if req.Identity != s.identity {
+			return agent.Response{Code: agent.CodeWrongIdentity, Error: agent.ErrWrongIdentity.Error()}
+		}
+		cached := s.cached(req.Workspace) != nil
+		workspace, secrets, err := s.secrets(req)
+		if err != nil && cached {
+			// The workspace key may have been rotated since it was opened
+			s.forget(req.Workspace)
+			workspace, secrets, err = s.secrets(req)
+		}
+		if err != nil {
+			return agent.Response{Error: strings.TrimPrefix(err.Error(), "❌ ")}
+		}
+		return agent.Response{Workspace: workspace, Secrets: secrets}
+	}
+
+	return agent.Response{Error: fmt.Sprintf("unknown operation %q", req.Op)}
+}
+
+// openWorkspaces lists the slugs of the opened workspaces
+func (s *agentServer) openWorkspaces() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	slugs := make([]string, 0, len(s.workspaces))
+	for slug := range s.workspaces {
+		slugs = append(slugs, slug)
+	}
+	sort.Strings(slugs)
+	return slugs
+}
+
+// cached returns the opened workspace, or nil
+func (s *agentServer) cached(slug string) *agentWorkspace {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.workspaces[slug]
+}
+
+// client returns the API client of an environment, creating it the first time
+func (s *agentServer) client(env string) (*client.Client, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c, ok := s.clients[env]; ok {
+		return c, nil
+	}
+	c, err := newSecretsClient(env, client.WithCache())
+	if err != nil {
+		return nil, err
+	}
+	s.clients[env] = c
+	return c, nil
+}
+
+// secrets fetches and decrypts what req asks for
+func (s *agentServer) secrets(req agent.Request) (*client.Workspace, map[string]string, error) {
+	c, err := s.client(req.Env)
+	if err != nil {
+		return nil, nil, err
+	}
+	opened, err := s.open(c, req.Workspace)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if req.Key != "" {
+		secret, err := c.GetSecret(opened.workspace.ID, req.Key)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Failed to get %s: %w", req.Key, err)
+		}
+		var value []byte
+		err = opened.withKey(func(key []byte) (err error) {
+			value, err = openSecret(key, opened.workspace, req.Env, secret)
+			return err
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("Failed to read %s: %w", req.Key, err)
+		}
+		return opened.workspace, map[string]string{req.Key: string(value)}, nil
+	}
+
+	secrets, err := c.FetchSecrets(opened.workspace.ID, req.Prefix)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to fetch secrets: %w", err)
+	}
+	var values map[string]string
+	err = opened.withKey(func(key []byte) (err error) {
+		values, err = decryptSecrets(key, opened.workspace, req.Env, secrets)
+		return err
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to decrypt secrets: %w", err)
+	}
+	return opened.workspace, values, nil
+}
+
+// open returns the opened workspace, opening it and moving its key into
+// locked memory the first time. When two requests open the same workspace
+// at once, the first one stored wins and the other key is wiped.
+func (s *agentServer) open(c *client.Client, slug string) (*agentWorkspace, error) {
+	if opened := s.cached(slug); opened != nil {
+		return opened, nil
+	}
+
+	workspace, workspaceKey, err := openWorkspace(c, s.store, slug)
+	if err != nil {
+		return nil, err
+	}
+	key, err := agent.NewLockedKey(workspaceKey)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if opened, ok := s.workspaces[slug]; ok {
+		key.Destroy()
+		return opened, nil
+	}
+	opened := &agentWorkspace{workspace: workspace, key: key}
+	s.workspaces[slug] = opened
+	return opened, nil
+}
+
+// forget wipes the key of a workspace
+func (s *agentServer) forget(slug string) {
+	s.mu.Lock()
+	opened, ok := s.workspaces[slug]
+	delete(s.workspaces, slug)
+	s.mu.Unlock()
+
+	if ok {
+		opened.destroy()
+	}
+}
+
+// unlock asks for the key passphrase once at startup, as nobody is there to
+// answer a prompt later. start passes it on stdin.
+func (s *agentServer) unlock() error {
+	if !config.Get().EncryptKeys || scopedToken() != "" {
+		return nil
+	}
+	if os.Getenv(keyPassphraseEnvVar) == "" && !term.IsTerminal(int(os.Stdin.Fd())) {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("failed to read the key passphrase: %w", err)
+		}
+		passphrase := []byte(strings.TrimSuffix(line, "\n"))
+		storage.SetPassphraseSource(func(bool) ([]byte, error) { return passphrase, nil })
+	}
+
+	// Opening a sealed key checks the passphrase
+	if _, err := s.store.GetEncryptionPrivateKey(); err != nil {
+		return fmt.Errorf("failed to unlock keys: %w", err)
+	}
+	return nil
+}
+
+func (s *agentServer) destroy() {
+	s.mu.Lock()
+	workspaces := s.workspaces
+	s.workspaces = map[string]*agentWorkspace{}
+	s.mu.Unlock()
+
+	for _, opened := range workspaces {
+		opened.destroy()
+	}
+}
+
+// serveAgent runs the agent until it is stopped or interrupted
+func serveAgent(socket string) error {
+	server := newAgentServer(socket)
+	if err := server.unlock(); err != nil {
+		return fmt.Errorf("❌ %w", err)
+	}
+
+	listener, err := agent.Listen(socket)
+	if err != nil {
+		return fmt.Errorf("❌ %w", err)
+	}
+	server.stop = func() { _ = listener.Close() }
+
+	// The agent outlives the terminal it was started from
+	signal.Ignore(syscall.SIGHUP)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-signals:
+			server.stop()
+		case <-done:
+		}
+	}()
+
+	infof("🔑 Agent listening on %s (pid %d)\n", socket, os.Getpid())
+	err = agent.Serve(listener, server.handle)
+	server.destroy()
+	if err != nil {
+		return fmt.Errorf("❌ Agent failed: %w", err)
+	}
+	infoln("👋 Agent stopped")
+	return nil
+}
+
+func runAgentStart(cmd *cobra.Command, args []string) error {
+	socket, err := agentSocket()
+	if err != nil {
+		return err
+	}
+	if agentForeground {
+		return serveAgent(socket)
+	}
+
+	if resp, err := agent.Call(socket, agent.Request{Op: agent.OpStatus}); err == nil && resp.Status != nil {
+		return fmt.Errorf("❌ The agent is already running (pid %d)", resp.Status.PID)
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("❌ Failed to find the initflow executable: %w", err)
+	}
+	// The agent runs with the same config, API and profile as this command
+	childArgs := []string{"agent", "start", "--foreground"}
+	cmd.Root().PersistentFlags().VisitAll(func(flag *pflag.Flag) {
+		if flag.Changed {
+			childArgs = append(childArgs, "--"+flag.Name+"="+flag.Value.String())
+		}
+	})
+	child := exec.Command(executable, childArgs...) // #nosec G204 - runs this same binary
+
+	if config.Get().EncryptKeys && scopedToken() == "" && os.Getenv(keyPassphraseEnvVar) == "" {
+		passphrase, err := readKeyPassphrase(false)
+		if err != nil {
+			return fmt.Errorf("❌ Failed to read key passphrase: %w", err)
+		}
+		child.Stdin = bytes.NewReader(append(passphrase, '\n'))
+	}
+
+	if err := child.Start(); err != nil {
+		return fmt.Errorf("❌ Failed to start the agent: %w", err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- child.Wait() }()
+
+	deadline := time.After(agentStartTimeout)
+	for {
+		if _, err := agent.Call(socket, agent.Request{Op: agent.OpStatus}); err == nil {
+			infof("✅ Agent started (pid %d), listening on %s\n", child.Process.Pid, socket)
+			return nil
+		}
+		select {
+		case <-exited:
+			return fmt.Errorf("❌ The agent exited while starting. Run 'initflow agent start --foreground' to see why")
+		case <-deadline:
+			return fmt.Errorf("❌ The agent didn't start listening within %s", agentStartTimeout)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+// callAgent sends req to the running agent
+func callAgent(req agent.Request) (*agent.Response, error) {
+	socket, err := agentSocket()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := agent.Call(socket, req)
+	if errors.Is(err, agent.ErrNotRunning) {
+		return nil, fmt.Errorf("❌ The agent isn't running. Start it with 'initflow agent start'")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("❌ %w", err)
+	}
+	return resp, nil
+}
+
+func runAgentStatus(cmd *cobra.Command, args []string) error {
+	resp, err := callAgent(agent.Request{Op: agent.OpStatus})
+	if err != nil {
+		return err
+	}
+	status := resp.Status
+
+	if structuredOutput() {
+		return writeOutput(status)
+	}
+
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "🟢 Agent running (pid %d) on %s\n", status.PID, status.Socket)
+	fmt.Fprintf(out, "Started: %s\n", status.StartedAt.Local().Format(time.RFC3339))
+	if len(status.Workspaces) == 0 {
+		fmt.Fprintln(out, "Workspaces: none opened yet")
+	} else {
+		fmt.Fprintf(out, "Workspaces: %s\n", strings.Join(status.Workspaces, ", "))
+	}
+	return nil
+}
+
+func runAgentStop(cmd *cobra.Command, args []string) error {
+	if _, err := callAgent(agent.Request{Op: agent.OpStop}); err != nil {
+		return err
+	}
+	infoln("✅ Agent stopped; the keys it held are wiped")
+	return nil
+}
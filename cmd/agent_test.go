@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/agent"
+	"github.com/DylanBlakemore/initflow-cli/internal/config"
+	"github.com/DylanBlakemore/initflow-cli/internal/storage"
+)
+
+func TestAgentServesSecrets(t *testing.T) {
+	setupSecretsTest(t)
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	t.Setenv(agent.SocketEnvVar, socket)
+	t.Cleanup(func() { agentForeground = false })
+
+	if err := runSecretsAdd(secretsAddCmd, []string{"API_KEY=s3cret", "DB_URL=postgres://db"}); err != nil {
+		t.Fatalf("runSecretsAdd failed: %v", err)
+	}
+
+	agentForeground = true
+	stopped := make(chan error, 1)
+	go func() { stopped <- runAgentStart(agentStartCmd, nil) }()
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		if _, err := agent.Call(socket, agent.Request{Op: agent.OpStatus}); err == nil {
+			break
+		}
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("Expected the agent to listen")
+		}
+	}
+
+	_, values, err := loadSecrets("my-project", "", "")
+	if err != nil || values["DB_URL"] != "postgres://db" {
+		t.Fatalf("Expected loadSecrets to go through the agent, got %v, %v", values, err)
+	}
+
+	// The agent holds the key now, so the stored one isn't needed
+	if err := storage.New().DeleteWorkspaceKey("my-project"); err != nil {
+		t.Fatalf("Failed to delete workspace key: %v", err)
+	}
+	out := captureStdout(t, func() {
+		if err := runSecretsGet(secretsGetCmd, []string{"API_KEY"}); err != nil {
+			t.Fatalf("runSecretsGet failed: %v", err)
+		}
+	})
+	if out != "s3cret" {
+		t.Errorf("Expected the agent to decrypt API_KEY, got %q", out)
+	}
+
+	_, _, err = loadSecrets("other-project", "", "")
+	if err == nil || !strings.Contains(err.Error(), "no key for other-project") {
+		t.Errorf("Expected the agent's error to be passed on, got %v", err)
+	}
+
+	out = captureStdout(t, func() {
+		if err := runAgentStatus(agentStatusCmd, nil); err != nil {
+			t.Fatalf("runAgentStatus failed: %v", err)
+		}
+	})
+	if !strings.Contains(out, "Workspaces: my-project") {
+		t.Errorf("Expected the status to list the opened workspace, got %q", out)
+	}
+
+	if err := runAgentStop(agentStopCmd, nil); err != nil {
+		t.Fatalf("runAgentStop failed: %v", err)
+	}
+	select {
+	case err := <-stopped:
+		if err != nil {
+			t.Errorf("Expected the agent to stop cleanly, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the agent to stop")
+	}
+
+	if _, _, err := secretsFromAgent(agent.Request{Workspace: "my-project"}); !errors.Is(err, agent.ErrNotRunning) {
+		t.Errorf("Expected no agent after stop, got %v", err)
+	}
+	err = runAgentStatus(agentStatusCmd, nil)
+	if err == nil || !strings.Contains(err.Error(), "isn't running") {
+		t.Errorf("Expected status to report the agent stopped, got %v", err)
+	}
+}
+
+func TestAgentServerAnswersDuringFetch(t *testing.T) {
+	fake, _ := setupSecretsTest(t)
+	if err := runSecretsAdd(secretsAddCmd, []string{"API_KEY=s3cret"}); err != nil {
+		t.Fatalf("runSecretsAdd failed: %v", err)
+	}
+
+	// Hold the secrets fetch until the status request is answered
+	fetching, release := make(chan struct{}), make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/secrets") {
+			close(fetching)
+			<-release
+		}
+		fake.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	if err := config.Set("api_base_url", server.URL); err != nil {
+		t.Fatalf("Failed to set API URL: %v", err)
+	}
+
+	s := newAgentServer("agent.sock")
+	t.Cleanup(s.destroy)
+	answered := make(chan agent.Response, 1)
+	go func() {
+		answered <- s.handle(agent.Request{Op: agent.OpSecrets, Identity: agentIdentity(), Workspace: "my-project"})
+	}()
+	<-fetching
+
+	status := make(chan agent.Response, 1)
+	go func() { status <- s.handle(agent.Request{Op: agent.OpStatus}) }()
+	select {
+	case resp := <-status:
+		if resp.Status == nil || len(resp.Status.Workspaces) != 1 {
+			t.Errorf("Expected the opened workspace in the status, got %+v", resp.Status)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected status to be answered while secrets are fetched")
+	}
+
+	close(release)
+	if resp := <-answered; resp.Error != "" || resp.Secrets["API_KEY"] != "s3cret" {
+		t.Errorf("Expected API_KEY from the agent, got %+v", resp)
+	}
+}
@@ -1,14 +1,21 @@
 package cmd
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"regexp"
 	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
 	"golang.org/x/term"
 
 	"github.com/DylanBlakemore/initflow-cli/internal/client"
+	"github.com/DylanBlakemore/initflow-cli/internal/config"
 	"github.com/DylanBlakemore/initflow-cli/internal/storage"
 )
 
@@ -19,51 +26,530 @@ var authCmd = &cobra.Command{
 }
 
 var loginCmd = &cobra.Command{
-	Use:   "login <email>",
+	Use:   "login [email]",
 	Short: "Login to InitFlow",
 	Long:  "Authenticate with InitFlow using your email and password",
-	Args:  cobra.ExactArgs(1),
+	Args:  cobra.MaximumNArgs(1),
 	RunE:  runLogin,
 }
 
+var authStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show whether this machine is authenticated",
+	Long: "Report from local state only (registered device, registration token and its expiry) whether " +
+		"this machine can talk to InitFlow. Makes no network calls, so it is cheap enough for shell prompts. " +
+		"Also counts secret changes queued offline for 'secrets sync'. Exits non-zero when not authenticated.",
+	Args: cobra.NoArgs,
+	RunE: runAuthStatus,
+}
+
+var whoamiCmd = &cobra.Command{
+	Use:   "whoami",
+	Short: "Show which account and device this shell uses",
+	Long: "Ask the server which account this machine acts as and print it with the registered device, " +
+		"the registration token's expiry and the API endpoint, e.g. before running destructive commands. " +
+		"Use 'auth status' for a quick check without a network call.",
+	Args: cobra.NoArgs,
+	RunE: runWhoami,
+}
+
+var logoutCmd = &cobra.Command{
+	Use:   "logout",
+	Short: "Log out of InitFlow on this machine",
+	Long: "Delete the stored registration token. With --purge-keys, also remove this device's private keys " +
+		"and every cached workspace key from local storage, after confirmation unless --force is set. " +
+		"A registered device signs its own requests, so only purging its keys stops it working. " +
+		"The device stays registered on the server until it is revoked.",
+	Args: cobra.NoArgs,
+	RunE: runLogout,
+}
+
+var loginOTP string
+
+var (
+	logoutPurgeKeys bool
+	logoutForce     bool
+)
+
+// otpPattern matches the 6-8 digit codes produced by TOTP authenticator apps
+var otpPattern = regexp.MustCompile(`^[0-9]{6,8}$`)
+
+const (
+	// registrationTokenTTL applies when the login response carries no expiry
+	registrationTokenTTL = 15 * time.Minute
+	// tokenExpiryWarning is how close to expiry the countdown turns into a warning
+	tokenExpiryWarning = 2 * time.Minute
+	// clockSkewWarning is how far this machine's clock may drift from the server's before we say so
+	clockSkewWarning = 2 * time.Minute
+)
+
 func init() {
 	rootCmd.AddCommand(authCmd)
 	authCmd.AddCommand(loginCmd)
+	authCmd.AddCommand(authStatusCmd)
+	authCmd.AddCommand(logoutCmd)
+	authCmd.AddCommand(whoamiCmd)
+
+	loginCmd.Flags().StringVar(&loginOTP, "otp", "",
+		"authentication code for accounts with two-factor authentication (or set INITFLOW_OTP)")
+
+	logoutCmd.Flags().BoolVar(&logoutPurgeKeys, "purge-keys", false,
+		"also remove device and workspace keys from local storage")
+	logoutCmd.Flags().BoolVarP(&logoutForce, "force", "f", false, "purge keys without asking for confirmation")
 }
 
-func runLogin(cmd *cobra.Command, args []string) error {
-	email := strings.TrimSpace(args[0])
+func validateOTP(code string) error {
+	if !otpPattern.MatchString(code) {
+		return fmt.Errorf("authentication code must be 6-8 digits")
+	}
+	return nil
+}
+
+// readOTP returns the TOTP code from --otp, INITFLOW_OTP or a hidden prompt
+func readOTP() (string, error) {
+	code := loginOTP
+	if code == "" {
+		code = os.Getenv("INITFLOW_OTP")
+	}
+
+	if code == "" {
+		var err error
+		if code, err = currentPasswordReader().ReadPassword("Authentication code"); err != nil {
+			return "", fmt.Errorf("failed to read authentication code: %w", err)
+		}
+	}
+
+	code = strings.TrimSpace(code)
+	if err := validateOTP(code); err != nil {
+		return "", err
+	}
+
+	return code, nil
+}
+
+// authenticate logs in with email and password, answering the server's
+// two-factor challenge with a TOTP code when the account requires one.
+func authenticate(apiClient *client.Client, email, password string) (*client.LoginResponse, error) {
+	loginResp, err := apiClient.Login(email, password)
+	if !errors.Is(err, client.ErrOTPRequired) {
+		return loginResp, err
+	}
+
+	infoln("🔢 Two-factor authentication required")
+	otp, err := readOTP()
+	if err != nil {
+		return nil, err
+	}
+
+	return apiClient.LoginWithOTP(email, password, otp)
+}
+
+// tokenExpiresAt returns the absolute expiry of a login's registration token
+func tokenExpiresAt(loginResp *client.LoginResponse, now time.Time) time.Time {
+	if expiresAt, err := time.Parse(time.RFC3339, loginResp.ExpiresAt); err == nil {
+		return expiresAt
+	}
+	return now.Add(registrationTokenTTL)
+}
+
+// storeLogin keeps the registration token, the refresh token that renews it
+// and their expiries in the keychain, along with the server clock offset seen
+// by apiClient so later expiry checks use server time.
+func storeLogin(store *storage.Storage, apiClient *client.Client, loginResp *client.LoginResponse) error {
+	if err := store.StoreToken(loginResp.Token); err != nil {
+		return fmt.Errorf("❌ Failed to store authentication token: %w", err)
+	}
+
+	// A login without a refresh token must not leave an older one behind
+	if loginResp.RefreshToken == "" {
+		_ = store.DeleteRefreshToken()
+	} else {
+		if err := store.StoreRefreshToken(loginResp.RefreshToken); err != nil {
+			return fmt.Errorf("❌ Failed to store refresh token: %w", err)
+		}
+		if expiresAt, err := time.Parse(time.RFC3339, loginResp.RefreshExpiresAt); err == nil {
+			if err := store.StoreRefreshTokenExpiry(expiresAt); err != nil {
+				infof("⚠️  Could not store refresh token expiry: %v\n", err)
+			}
+		}
+	}
+
+	if offset, ok := apiClient.ClockOffset(); ok {
+		if offset.Abs() > clockSkewWarning {
+			infof("⚠️  This machine's clock is %s off from the server's; token expiry uses server time\n", offset.Abs())
+		}
+		if err := store.StoreClockOffset(offset); err != nil {
+			infof("⚠️  Could not store clock offset: %v\n", err)
+		}
+	}
+
+	if err := store.StoreTokenExpiry(tokenExpiresAt(loginResp, serverNow(store, time.Now()))); err != nil {
+		infof("⚠️  Could not store token expiry: %v\n", err)
+	}
+
+	return nil
+}
+
+// serverNow converts a local time to the server's clock using the last
+// recorded offset
+func serverNow(store *storage.Storage, now time.Time) time.Time {
+	return now.Add(store.ClockOffset())
+}
+
+// formatTokenExpiry renders the time left on a token, e.g. "expires in 7m12s",
+// with a warning once less than tokenExpiryWarning remains.
+func formatTokenExpiry(expiresAt, now time.Time) string {
+	remaining := expiresAt.Sub(now).Truncate(time.Second)
+
+	switch {
+	case remaining <= 0:
+		return fmt.Sprintf("⚠️  expired %s ago", -remaining)
+	case remaining < tokenExpiryWarning:
+		return fmt.Sprintf("⚠️  expires in %s", remaining)
+	default:
+		return fmt.Sprintf("expires in %s", remaining)
+	}
+}
+
+// resolveLoginEmail returns the email given as an argument, falling back to the
+// last email used to log in after the user confirms it.
+func resolveLoginEmail(args []string, in io.Reader) (string, error) {
+	if len(args) > 0 {
+		email := strings.TrimSpace(args[0])
+		if email == "" {
+			return "", fmt.Errorf("email cannot be empty")
+		}
+		return email, nil
+	}
+
+	stored := strings.TrimSpace(config.Get().DefaultEmail)
+	if stored == "" {
+		return "", fmt.Errorf("email cannot be empty: run 'initflow auth login <email>'")
+	}
+
+	reader := bufio.NewReader(in)
+	fmt.Fprintf(stdout(), "Log in as %s? [Y/n]: ", stored)
+	answer, err := reader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed to read confirmation: %w", err)
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "", "y", "yes":
+		return stored, nil
+	}
+
+	fmt.Fprint(stdout(), "Email: ")
+	email, err := reader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed to read email: %w", err)
+	}
+	email = strings.TrimSpace(email)
 	if email == "" {
-		return fmt.Errorf("email cannot be empty")
+		return "", fmt.Errorf("email cannot be empty")
+	}
+
+	return email, nil
+}
+
+func runLogin(cmd *cobra.Command, args []string) error {
+	if loginSSO {
+		return runSSOLogin(args)
 	}
 
-	fmt.Print("Password: ")
-	passwordBytes, err := term.ReadPassword(int(os.Stdin.Fd()))
+	email, err := resolveLoginEmail(args, os.Stdin)
 	if err != nil {
-		return fmt.Errorf("failed to read password: %w", err)
+		return err
 	}
-	fmt.Println()
 
-	password := string(passwordBytes)
-	if password == "" {
-		return fmt.Errorf("password cannot be empty")
+	password, err := readPassword()
+	if err != nil {
+		return err
 	}
 
-	fmt.Println("🔐 Authenticating...")
+	infoln("🔐 Authenticating...")
 
-	apiClient := client.New()
-	loginResp, err := apiClient.Login(email, password)
+	apiClient := newClient()
+	loginResp, err := authenticate(apiClient, email, password)
 	if err != nil {
 		return fmt.Errorf("❌ Authentication failed: %w", err)
 	}
 
-	storage := storage.New()
-	if err := storage.StoreToken(loginResp.Token); err != nil {
-		return fmt.Errorf("❌ Failed to store authentication token: %w", err)
+	store := storage.New()
+	if err := storeLogin(store, apiClient, loginResp); err != nil {
+		return err
+	}
+
+	if err := config.Persist("default_email", email); err != nil {
+		infof("⚠️  Could not remember email for next login: %v\n", err)
+	}
+
+	printLoginSuccess(store, loginResp)
+	return nil
+}
+
+// printLoginSuccess greets the user after a login and points at the next step
+func printLoginSuccess(store *storage.Storage, loginResp *client.LoginResponse) {
+	now := serverNow(store, time.Now())
+	infof("✅ Login successful! Registration token %s.\n", formatTokenExpiry(tokenExpiresAt(loginResp, now), now))
+	infof("👋 Welcome, %s %s!\n", loginResp.User.Name, loginResp.User.Surname)
+	infoln("💡 Next: Register this device with 'initflow device register <name>'")
+}
+
+// whoami is what auth whoami reports
+type whoami struct {
+	Name           string     `json:"name"`
+	Email          string     `json:"email"`
+	DeviceID       string     `json:"device_id,omitempty"`
+	DeviceName     string     `json:"device_name,omitempty"`
+	ScopedToken    bool       `json:"scoped_token"`
+	TokenExpiresAt *time.Time `json:"token_expires_at,omitempty"`
+	APIBaseURL     string     `json:"api_base_url"`
+}
+
+// print renders whoami as aligned lines
+func (w whoami) print(out io.Writer, now time.Time) {
+	device := "not registered"
+	switch {
+	case w.DeviceID != "" && w.DeviceName != "":
+		device = fmt.Sprintf("%s (%s)", w.DeviceName, w.DeviceID)
+	case w.DeviceID != "":
+		device = w.DeviceID
 	}
-	fmt.Println("✅ Login successful! Registration token expires in 15 minutes.")
-	fmt.Printf("👋 Welcome, %s %s!\n", loginResp.User.Name, loginResp.User.Surname)
-	fmt.Println("💡 Next: Register this device with 'initflow device register <name>'")
 
+	token := "none"
+	switch {
+	case w.ScopedToken:
+		token = "scoped token from " + scopedTokenEnvVar
+	case w.TokenExpiresAt != nil:
+		token = "registration token, " + formatTokenExpiry(*w.TokenExpiresAt, now)
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "User:\t%s <%s>\n", w.Name, w.Email)
+	fmt.Fprintf(tw, "Device:\t%s\n", device)
+	fmt.Fprintf(tw, "Token:\t%s\n", token)
+	fmt.Fprintf(tw, "API:\t%s\n", w.APIBaseURL)
+	_ = tw.Flush()
+}
+
+func runWhoami(cmd *cobra.Command, args []string) error {
+	store := storage.New()
+	if err := requireAPIAccess(store); err != nil {
+		return err
+	}
+
+	user, err := newClient().CurrentUser()
+	if err != nil {
+		return fmt.Errorf("❌ Failed to get current user: %w", err)
+	}
+
+	info := whoami{
+		Name:        strings.TrimSpace(user.Name + " " + user.Surname),
+		Email:       user.Email,
+		ScopedToken: scopedToken() != "",
+		APIBaseURL:  config.Get().APIBaseURL,
+	}
+	if deviceID, err := store.GetDeviceID(); err == nil {
+		info.DeviceID = deviceID
+		info.DeviceName, _ = store.GetDeviceName()
+	}
+	if expiresAt, err := store.TokenExpiry(); err == nil && store.HasToken() {
+		info.TokenExpiresAt = &expiresAt
+	}
+
+	if structuredOutput() {
+		return writeOutput(info)
+	}
+	info.print(cmd.OutOrStdout(), serverNow(store, time.Now()))
+	return nil
+}
+
+// purgeWorkspaceKeys removes the cached key of every workspace the device can
+// list. Keys can't be enumerated locally, so this has to run while the device
+// can still sign requests.
+func purgeWorkspaceKeys(store *storage.Storage) (int, error) {
+	workspaces, err := newClient().ListWorkspaces()
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, workspace := range workspaces {
+		if !store.HasWorkspaceKey(workspace.Slug) {
+			continue
+		}
+		if err := store.DeleteWorkspaceKey(workspace.Slug); err != nil {
+			return purged, fmt.Errorf("failed to delete the key of %s: %w", workspace.Slug, err)
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+func runLogout(cmd *cobra.Command, args []string) error {
+	store := storage.New()
+
+	if logoutPurgeKeys {
+		if !logoutForce {
+			if !term.IsTerminal(int(os.Stdin.Fd())) {
+				return fmt.Errorf("❌ Purging keys can't be undone. Re-run with --force to confirm")
+			}
+			proceed, err := askYesNo(bufio.NewReader(os.Stdin),
+				"Remove this device's keys and all cached workspace keys from this machine? "+
+					"Getting access back needs a new registration", false)
+			if err != nil {
+				return err
+			}
+			if !proceed {
+				return fmt.Errorf("ℹ️ Logout cancelled")
+			}
+		}
+
+		deviceID, _ := store.GetDeviceID()
+		if deviceID != "" {
+			infoln("🔐 Removing cached workspace keys...")
+			purged, err := purgeWorkspaceKeys(store)
+			if err != nil {
+				infof("⚠️  Couldn't remove every workspace key: %v\n", err)
+			}
+			infof("🗑️  Removed %d workspace keys\n", purged)
+		}
+
+		if deviceID != "" || store.HasSigningPrivateKey() || store.HasEncryptionPrivateKey() {
+			if err := store.ClearDeviceCredentials(); err != nil {
+				return fmt.Errorf("❌ Failed to clear device credentials: %w", err)
+			}
+			infoln("✅ Device credentials removed")
+		}
+		if deviceID != "" {
+			infof("💡 %s is still registered. Revoke it from another device with 'initflow device revoke %s'\n",
+				deviceID, deviceID)
+		}
+	}
+
+	hadToken := store.HasToken()
+	if hadToken {
+		if err := store.DeleteToken(); err != nil {
+			return fmt.Errorf("❌ Failed to delete authentication token: %w", err)
+		}
+	}
+
+	switch {
+	case logoutPurgeKeys:
+		infoln("✅ Logged out and removed local keys")
+	case !hadToken:
+		infoln("ℹ️  No authentication token found in local storage")
+	default:
+		infoln("✅ Logged out")
+	}
+	if !logoutPurgeKeys && store.HasDeviceID() {
+		infoln("💡 This device still signs its own requests. Use --purge-keys to remove its keys too")
+	}
+	return nil
+}
+
+// Local authentication states reported by auth status
+const (
+	authStateAuthenticated    = "authenticated"
+	authStateNotAuthenticated = "not authenticated"
+	authStateExpired          = "session expired"
+)
+
+type authStatus struct {
+	State            string     `json:"state"`
+	DeviceID         string     `json:"device_id,omitempty"`
+	TokenPresent     bool       `json:"token_present"`
+	TokenExpiresAt   *time.Time `json:"token_expires_at,omitempty"`
+	Refreshable      bool       `json:"refreshable"`
+	RefreshExpiresAt *time.Time `json:"refresh_expires_at,omitempty"`
+	APIBaseURL       string     `json:"api_base_url"`
+	PendingChanges   int        `json:"pending_changes"`
+}
+
+// inspectAuth works out the authentication state from the keychain alone. A
+// registered device signs its own requests; without one, a registration token
+// that hasn't expired still counts, as does an expired one that a valid refresh
+// token renews. Tokens stored without an expiry are trusted.
+func inspectAuth(store *storage.Storage, now time.Time) authStatus {
+	status := authStatus{State: authStateNotAuthenticated}
+	now = serverNow(store, now)
+
+	if deviceID, err := store.GetDeviceID(); err == nil {
+		status.DeviceID = deviceID
+		status.State = authStateAuthenticated
+	}
+
+	if store.HasToken() {
+		status.TokenPresent = true
+		if expiresAt, err := store.TokenExpiry(); err == nil {
+			status.TokenExpiresAt = &expiresAt
+		}
+		if store.HasRefreshToken() {
+			status.Refreshable = true
+			if expiresAt, err := store.RefreshTokenExpiry(); err == nil {
+				status.RefreshExpiresAt = &expiresAt
+				status.Refreshable = now.Before(expiresAt)
+			}
+		}
+
+		if status.DeviceID == "" {
+			status.State = authStateAuthenticated
+			if status.TokenExpiresAt != nil && !now.Before(*status.TokenExpiresAt) && !status.Refreshable {
+				status.State = authStateExpired
+			}
+		}
+	}
+
+	return status
+}
+
+// describe renders the one-line summary printed by auth status
+func (s authStatus) describe(now time.Time) string {
+	switch {
+	case s.State == authStateAuthenticated && s.DeviceID != "":
+		return fmt.Sprintf("✅ authenticated (device %s)", s.DeviceID)
+	case s.State == authStateAuthenticated && s.Refreshable && s.RefreshExpiresAt != nil:
+		return fmt.Sprintf("✅ authenticated (registration token renewable until %s)",
+			s.RefreshExpiresAt.UTC().Format("2006-01-02 15:04 UTC"))
+	case s.State == authStateAuthenticated && s.Refreshable:
+		return "✅ authenticated (registration token renewable)"
+	case s.State == authStateAuthenticated && s.TokenExpiresAt != nil:
+		return fmt.Sprintf("✅ authenticated (registration token %s)", formatTokenExpiry(*s.TokenExpiresAt, now))
+	case s.State == authStateAuthenticated:
+		return "✅ authenticated (registration token)"
+	case s.State == authStateExpired:
+		return fmt.Sprintf("⚠️  session expired (registration token %s)",
+			strings.TrimPrefix(formatTokenExpiry(*s.TokenExpiresAt, now), "⚠️  "))
+	default:
+		return "❌ not authenticated"
+	}
+}
+
+func runAuthStatus(cmd *cobra.Command, args []string) error {
+	store := storage.New()
+	status := inspectAuth(store, time.Now())
+	status.APIBaseURL = config.Get().APIBaseURL
+	if pending, err := readPending(); err == nil {
+		status.PendingChanges = len(pending)
+	}
+	now := serverNow(store, time.Now())
+
+	if structuredOutput() {
+		if err := writeOutput(status); err != nil {
+			return err
+		}
+	} else {
+		fmt.Fprintln(cmd.OutOrStdout(), status.describe(now))
+		fmt.Fprintf(cmd.OutOrStdout(), "🌐 API: %s\n", status.APIBaseURL)
+		if status.PendingChanges > 0 {
+			fmt.Fprintf(cmd.OutOrStdout(), "📥 %d changes queued offline; send them with 'initflow secrets sync'\n",
+				status.PendingChanges)
+		}
+	}
+
+	if status.State != authStateAuthenticated {
+		return &silentExit{code: exitAuth}
+	}
 	return nil
 }
@@ -6,7 +6,10 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/assert"
@@ -14,6 +17,7 @@ import (
 
 	"github.com/DylanBlakemore/initflow-cli/internal/client"
 	"github.com/DylanBlakemore/initflow-cli/internal/config"
+	"github.com/DylanBlakemore/initflow-cli/internal/storage"
 )
 
 func TestLoginCmd_Success(t *testing.T) {
@@ -56,19 +60,18 @@ func TestLoginCmd_Success(t *testing.T) {
 
 	loginSubCmd, _, err := authCmd.Find([]string{"login"})
 	require.NoError(t, err)
-	assert.Equal(t, "login <email>", loginSubCmd.Use)
+	assert.Equal(t, "login [email]", loginSubCmd.Use)
 	assert.Equal(t, "Login to InitFlow", loginSubCmd.Short)
 }
 
 func TestLoginCmd_InvalidArgs(t *testing.T) {
 	assert.NotNil(t, loginCmd.Args)
 	err := loginCmd.Args(loginCmd, []string{})
-	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "accepts 1 arg(s), received 0")
+	assert.NoError(t, err)
 
 	err = loginCmd.Args(loginCmd, []string{"email1", "email2"})
 	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "accepts 1 arg(s), received 2")
+	assert.Contains(t, err.Error(), "accepts at most 1 arg(s), received 2")
 
 	err = loginCmd.Args(loginCmd, []string{"test@example.com"})
 	assert.NoError(t, err)
@@ -84,13 +87,55 @@ func TestLoginCmd_EmptyEmail(t *testing.T) {
 	assert.Contains(t, err.Error(), "email cannot be empty")
 }
 
+func TestLoginCmd_FallsBackToStoredEmail(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalHome := os.Getenv("HOME")
+	_ = os.Setenv("HOME", tmpDir)
+	defer func() { _ = os.Setenv("HOME", originalHome) }()
+
+	require.NoError(t, config.InitConfig())
+	require.NoError(t, config.Persist("default_email", "stored@example.com"))
+	defer func() { _ = config.Set("default_email", "") }()
+
+	email, err := resolveLoginEmail(nil, strings.NewReader("\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "stored@example.com", email)
+
+	email, err = resolveLoginEmail(nil, strings.NewReader("n\nother@example.com\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "other@example.com", email)
+
+	email, err = resolveLoginEmail([]string{"explicit@example.com"}, strings.NewReader(""))
+	require.NoError(t, err)
+	assert.Equal(t, "explicit@example.com", email)
+
+	content, err := os.ReadFile(filepath.Join(tmpDir, ".initflow", "config.yaml")) // #nosec G304 - test path
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "default_email: stored@example.com")
+}
+
+func TestLoginCmd_NoArgumentAndNoStoredEmail(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalHome := os.Getenv("HOME")
+	_ = os.Setenv("HOME", tmpDir)
+	defer func() { _ = os.Setenv("HOME", originalHome) }()
+
+	require.NoError(t, config.InitConfig())
+	require.NoError(t, config.Set("default_email", "   "))
+	defer func() { _ = config.Set("default_email", "") }()
+
+	err := runLogin(loginCmd, []string{})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "email cannot be empty")
+}
+
 func TestAuthCmd_Structure(t *testing.T) {
 	assert.Equal(t, "auth", authCmd.Use)
 	assert.Equal(t, "Authentication commands", authCmd.Short)
 	assert.Equal(t, "Manage authentication with InitFlow", authCmd.Long)
 	var loginFound bool
 	for _, cmd := range authCmd.Commands() {
-		if cmd.Use == "login <email>" {
+		if cmd.Use == "login [email]" {
 			loginFound = true
 			assert.Equal(t, "Login to InitFlow", cmd.Short)
 			assert.Equal(t, "Authenticate with InitFlow using your email and password", cmd.Long)
@@ -141,6 +186,11 @@ func executeCommand(root *cobra.Command, args ...string) (output string, err err
 	root.SetOut(buf)
 	root.SetErr(buf)
 	root.SetArgs(args)
+	// Later tests capture os.Stdout, so don't leave the buffer behind
+	defer func() {
+		root.SetOut(nil)
+		root.SetErr(nil)
+	}()
 
 	err = root.Execute()
 	return buf.String(), err
@@ -158,5 +208,297 @@ func TestAuthCmd_Integration(t *testing.T) {
 	output, err = executeCommand(rootCmd, "auth", "login", "--help")
 	assert.NoError(t, err)
 	assert.Contains(t, output, "Authenticate with InitFlow using your email and password")
-	assert.Contains(t, output, "login <email>")
+	assert.Contains(t, output, "login [email]")
+}
+
+func TestValidateOTP(t *testing.T) {
+	for _, code := range []string{"123456", "1234567", "12345678"} {
+		assert.NoError(t, validateOTP(code), code)
+	}
+	for _, code := range []string{"", "12345", "123456789", "12a456", "123 456"} {
+		assert.Error(t, validateOTP(code), code)
+	}
+}
+
+func TestAuthenticate_OTPChallenge(t *testing.T) {
+	var attempts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req client.LoginRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		attempts = append(attempts, req.OTP)
+
+		w.Header().Set("Content-Type", "application/json")
+		switch req.OTP {
+		case "":
+			w.WriteHeader(http.StatusUnauthorized)
+			_ = json.NewEncoder(w).Encode(client.ErrorResponse{Error: "otp_required", Message: "Code required"})
+		case "246810":
+			_ = json.NewEncoder(w).Encode(client.LoginResponse{Token: "token-after-otp"})
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+			_ = json.NewEncoder(w).Encode(client.ErrorResponse{Error: "invalid_otp", Message: "Invalid code"})
+		}
+	}))
+	defer server.Close()
+
+	apiClient := client.NewWithBaseURL(server.URL)
+
+	loginOTP = "246810"
+	defer func() { loginOTP = "" }()
+
+	resp, err := authenticate(apiClient, "test@example.com", "password123")
+	require.NoError(t, err)
+	assert.Equal(t, "token-after-otp", resp.Token)
+	assert.Equal(t, []string{"", "246810"}, attempts)
+
+	loginOTP = "111111"
+	_, err = authenticate(apiClient, "test@example.com", "password123")
+	assert.ErrorIs(t, err, client.ErrInvalidOTP)
+
+	t.Setenv("INITFLOW_OTP", "12ab")
+	loginOTP = ""
+	_, err = authenticate(apiClient, "test@example.com", "password123")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "authentication code must be 6-8 digits")
+
+	// Without --otp or INITFLOW_OTP the code is asked through the password reader
+	t.Setenv("INITFLOW_OTP", "")
+	stub := &stubPasswordReader{password: " 246810\n"}
+	usePasswordReader(t, stub)
+	resp, err = authenticate(apiClient, "test@example.com", "password123")
+	require.NoError(t, err)
+	assert.Equal(t, "token-after-otp", resp.Token)
+	assert.Equal(t, []string{"Authentication code"}, stub.prompts)
+}
+
+func TestAuthenticate_InvalidPasswordIsNotAnOTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		_ = json.NewEncoder(w).Encode(client.ErrorResponse{Error: "unauthorized", Message: "Invalid email or password"})
+	}))
+	defer server.Close()
+
+	_, err := authenticate(client.NewWithBaseURL(server.URL), "test@example.com", "wrong")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, client.ErrInvalidOTP)
+	assert.Contains(t, err.Error(), "Invalid email or password")
+}
+
+func TestFormatTokenExpiry(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		remaining time.Duration
+		expected  string
+	}{
+		{15 * time.Minute, "expires in 15m0s"},
+		{7*time.Minute + 12*time.Second, "expires in 7m12s"},
+		{2 * time.Minute, "expires in 2m0s"},
+		{2*time.Minute - time.Second, "⚠️  expires in 1m59s"},
+		{time.Second + 500*time.Millisecond, "⚠️  expires in 1s"},
+		{0, "⚠️  expired 0s ago"},
+		{-3 * time.Minute, "⚠️  expired 3m0s ago"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.remaining.String(), func(t *testing.T) {
+			assert.Equal(t, tt.expected, formatTokenExpiry(now.Add(tt.remaining), now))
+		})
+	}
+}
+
+func TestTokenExpiresAt(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	fromServer := &client.LoginResponse{ExpiresAt: "2026-01-01T12:30:00Z"}
+	assert.Equal(t, now.Add(30*time.Minute), tokenExpiresAt(fromServer, now))
+
+	assert.Equal(t, now.Add(registrationTokenTTL), tokenExpiresAt(&client.LoginResponse{}, now))
+}
+
+func TestInspectAuth(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		deviceID    string
+		token       string
+		expiresAt   time.Time
+		refresh     time.Time
+		state       string
+		description string
+	}{
+		{name: "nothing stored", state: authStateNotAuthenticated, description: "❌ not authenticated"},
+		{name: "registered device", deviceID: "dev-1", state: authStateAuthenticated,
+			description: "✅ authenticated (device dev-1)"},
+		{name: "registered device with stale token", deviceID: "dev-1", token: "tok", expiresAt: now.Add(-time.Hour),
+			state: authStateAuthenticated, description: "✅ authenticated (device dev-1)"},
+		{name: "valid token", token: "tok", expiresAt: now.Add(7 * time.Minute), state: authStateAuthenticated,
+			description: "✅ authenticated (registration token expires in 7m0s)"},
+		{name: "expired token", token: "tok", expiresAt: now.Add(-3 * time.Minute), state: authStateExpired,
+			description: "⚠️  session expired (registration token expired 3m0s ago)"},
+		{name: "token without expiry", token: "tok", state: authStateAuthenticated,
+			description: "✅ authenticated (registration token)"},
+		{name: "expired token with refresh token", token: "tok", expiresAt: now.Add(-3 * time.Minute),
+			refresh: now.Add(30 * 24 * time.Hour), state: authStateAuthenticated,
+			description: "✅ authenticated (registration token renewable until 2026-01-31 12:00 UTC)"},
+		{name: "expired refresh token", token: "tok", expiresAt: now.Add(-3 * time.Minute),
+			refresh: now.Add(-time.Minute), state: authStateExpired,
+			description: "⚠️  session expired (registration token expired 3m0s ago)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := storage.NewWithServiceName("initflow-cli-test-" + t.Name())
+			if tt.deviceID != "" {
+				require.NoError(t, store.StoreDeviceID(tt.deviceID))
+			}
+			if tt.token != "" {
+				require.NoError(t, store.StoreToken(tt.token))
+			}
+			if !tt.expiresAt.IsZero() {
+				require.NoError(t, store.StoreTokenExpiry(tt.expiresAt))
+			}
+			if !tt.refresh.IsZero() {
+				require.NoError(t, store.StoreRefreshToken("refresh"))
+				require.NoError(t, store.StoreRefreshTokenExpiry(tt.refresh))
+			}
+			t.Cleanup(func() {
+				_ = store.DeleteDeviceID()
+				_ = store.DeleteToken()
+			})
+
+			status := inspectAuth(store, now)
+			assert.Equal(t, tt.state, status.State)
+			assert.Equal(t, tt.description, status.describe(now))
+		})
+	}
+}
+
+func TestAuthStatusCmd(t *testing.T) {
+	setupTestEnvironment(t, "http://127.0.0.1:0")
+
+	outputFormat = outputJSON
+	t.Cleanup(func() { outputFormat = outputTable })
+
+	var err error
+	out := captureStdout(t, func() {
+		err = runAuthStatus(authStatusCmd, []string{})
+	})
+	require.NoError(t, err)
+
+	var status authStatus
+	require.NoError(t, json.Unmarshal([]byte(out), &status))
+	assert.Equal(t, authStateAuthenticated, status.State)
+	assert.Equal(t, "test-device-123", status.DeviceID)
+	assert.Equal(t, "http://127.0.0.1:0", status.APIBaseURL)
+
+	require.NoError(t, storage.New().DeleteDeviceID())
+	var stderr bytes.Buffer
+	out = captureStdout(t, func() {
+		err = runAuthStatus(authStatusCmd, []string{})
+	})
+	assert.Equal(t, exitAuth, handleError(&stderr, err))
+	assert.Empty(t, stderr.String(), "the status line already explains the failure")
+	assert.Contains(t, out, `"state": "not authenticated"`)
+}
+
+func TestInspectAuthAppliesClockOffset(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	store := storage.NewWithServiceName("initflow-cli-test-" + t.Name())
+	require.NoError(t, store.StoreToken("tok"))
+	require.NoError(t, store.StoreTokenExpiry(now.Add(time.Minute)))
+	t.Cleanup(func() { _ = store.DeleteToken() })
+
+	assert.Equal(t, authStateAuthenticated, inspectAuth(store, now).State)
+
+	// The server's clock is three minutes ahead of this machine's
+	require.NoError(t, store.StoreClockOffset(3*time.Minute))
+	status := inspectAuth(store, now)
+	assert.Equal(t, authStateExpired, status.State)
+	assert.Equal(t, "⚠️  session expired (registration token expired 2m0s ago)", status.describe(serverNow(store, now)))
+}
+
+func TestLogout(t *testing.T) {
+	setupSecretsTest(t)
+	t.Cleanup(func() { logoutPurgeKeys, logoutForce = false, false })
+
+	store := storage.New()
+	require.NoError(t, store.StoreToken("registration-token"))
+
+	require.NoError(t, runLogout(logoutCmd, nil))
+	assert.False(t, store.HasToken())
+	assert.True(t, store.HasDeviceID(), "a plain logout keeps the device keys")
+	assert.True(t, store.HasWorkspaceKey("my-project"))
+
+	// Purging asks for confirmation, which needs --force without a terminal
+	logoutPurgeKeys = true
+	err := runLogout(logoutCmd, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--force")
+	assert.True(t, store.HasDeviceID())
+
+	logoutForce = true
+	require.NoError(t, runLogout(logoutCmd, nil))
+	assert.False(t, store.HasDeviceID())
+	assert.False(t, store.HasSigningPrivateKey())
+	assert.False(t, store.HasEncryptionPrivateKey())
+	assert.False(t, store.HasWorkspaceKey("my-project"))
+}
+
+func TestWhoami(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/auth/me", r.URL.Path)
+		assert.Equal(t, "Device test-device-123", r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(client.CurrentUserResponse{
+			User: client.User{ID: 1, Email: "jane@example.com", Name: "Jane", Surname: "Doe"},
+		})
+	}))
+	defer server.Close()
+	setupTestEnvironment(t, server.URL)
+	require.NoError(t, storage.New().StoreDeviceName("Work Laptop"))
+
+	var err error
+	out := captureStdout(t, func() {
+		err = runWhoami(whoamiCmd, nil)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "User:    Jane Doe <jane@example.com>\n"+
+		"Device:  Work Laptop (test-device-123)\n"+
+		"Token:   none\n"+
+		"API:     "+server.URL+"\n", out)
+
+	outputFormat = outputJSON
+	t.Cleanup(func() { outputFormat = outputTable })
+	out = captureStdout(t, func() {
+		err = runWhoami(whoamiCmd, nil)
+	})
+	require.NoError(t, err)
+	var info whoami
+	require.NoError(t, json.Unmarshal([]byte(out), &info))
+	assert.Equal(t, "jane@example.com", info.Email)
+	assert.Equal(t, server.URL, info.APIBaseURL)
+}
+
+func TestStoreLoginKeepsRefreshToken(t *testing.T) {
+	store := storage.NewWithServiceName("initflow-cli-test-" + t.Name())
+	t.Cleanup(func() { _ = store.DeleteToken() })
+	apiClient := client.NewWithBaseURL("http://127.0.0.1:0")
+
+	require.NoError(t, storeLogin(store, apiClient, &client.LoginResponse{
+		Token: "tok", RefreshToken: "refresh", RefreshExpiresAt: "2026-02-01T00:00:00Z",
+	}))
+	refreshToken, err := store.GetRefreshToken()
+	require.NoError(t, err)
+	assert.Equal(t, "refresh", refreshToken)
+	expiresAt, err := store.RefreshTokenExpiry()
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC), expiresAt)
+
+	// Logging in to a server without refresh tokens forgets the old one
+	require.NoError(t, storeLogin(store, apiClient, &client.LoginResponse{Token: "tok2"}))
+	assert.False(t, store.HasRefreshToken())
 }
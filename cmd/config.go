@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/config"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "View and change settings",
+	Long: "View and change the settings saved in the config file ($HOME/.initflow/config.yaml unless --config " +
+		"names another). A setting's value comes from, in order of precedence: a command-line flag, an " +
+		"INITFLOW_<SETTING> environment variable, the config file, then the built-in default.",
+}
+
+var configListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List settings",
+	Long:  "List every setting with its effective value and where that value comes from.",
+	Args:  cobra.NoArgs,
+	RunE:  runConfigList,
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <setting>",
+	Short: "Print a setting",
+	Long:  "Print the effective value of a setting, after flags and environment variables are applied.",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runConfigGet,
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <setting> <value>",
+	Short: "Save a setting to the config file",
+	Long: "Save a setting to the config file. Durations look like 45s or 2m and lists are comma separated. " +
+		"The value is checked first, so an invalid one never reaches the file.",
+	Example: "  initflow config set default_workspace my-project\n" +
+		"  initflow config set output json\n" +
+		"  initflow config set api_base_url https://initflow.internal.example.com",
+	Args: cobra.ExactArgs(2),
+	RunE: runConfigSet,
+}
+
+// configColumns are the columns config list can show
+var configColumns = []tableColumn{
+	{Name: "setting", Header: "Setting", Default: true},
+	{Name: "value", Header: "Value", Default: true},
+	{Name: "source", Header: "Source", Default: true},
+	{Name: "description", Header: "Description"},
+}
+
+var configListTable tableOptions
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configListCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+
+	addTableFlags(configListCmd, &configListTable, configColumns)
+}
+
+// configSetting is a setting as shown by config list and get
+type configSetting struct {
+	Setting string `json:"setting"`
+	Value   string `json:"value"`
+	Source  string `json:"source"`
+}
+
+func runConfigList(cmd *cobra.Command, args []string) error {
+	columns, err := selectColumns(configColumns, configListTable.columns)
+	if err != nil {
+		return err
+	}
+
+	settings := config.Settings()
+	results := make([]configSetting, len(settings))
+	for i, setting := range settings {
+		results[i] = configSetting{Setting: setting.Key, Value: config.Value(setting.Key), Source: config.Source(setting.Key)}
+	}
+
+	if structuredOutput() {
+		return writeOutput(results)
+	}
+
+	if file := config.File(); file != "" {
+		infof("📄 Config file: %s\n", file)
+	} else {
+		infoln("📄 No config file yet; 'initflow config set' creates one")
+	}
+
+	rows := make([]map[string]string, len(results))
+	for i, result := range results {
+		rows[i] = map[string]string{
+			"setting":     result.Setting,
+			"value":       result.Value,
+			"source":      result.Source,
+			"description": settings[i].Description,
+		}
+	}
+	writeTable(cmd.OutOrStdout(), columns, rows, !configListTable.noHeader)
+
+	return nil
+}
+
+func runConfigGet(cmd *cobra.Command, args []string) error {
+	key := args[0]
+	if _, err := config.LookupSetting(key); err != nil {
+		return fmt.Errorf("❌ %w", err)
+	}
+
+	result := configSetting{Setting: key, Value: config.Value(key), Source: config.Source(key)}
+	if structuredOutput() {
+		return writeOutput(result)
+	}
+
+	fmt.Fprintln(cmd.OutOrStdout(), result.Value)
+	return nil
+}
+
+func runConfigSet(cmd *cobra.Command, args []string) error {
+	key, value := args[0], args[1]
+	if err := config.Update(key, value); err != nil {
+		return fmt.Errorf("❌ Failed to save %s: %w", key, err)
+	}
+
+	infof("✅ Saved %s = %s to %s\n", key, config.Value(key), config.File())
+	switch config.Source(key) {
+	case config.SourceFlag:
+		infof("⚠️  A command-line flag overrides %s for this command\n", key)
+	case config.SourceEnv:
+		infof("⚠️  An environment variable overrides %s in this shell\n", key)
+	}
+	return nil
+}
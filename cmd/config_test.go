@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/config"
+)
+
+func TestConfigCommands(t *testing.T) {
+	t.Setenv("HOME", t.TempDir()) // config set writes the config file
+	setupTestEnvironment(t, "http://localhost")
+	t.Cleanup(func() {
+		config.Set("default_workspace", "")
+		config.Set("output", "")
+		outputFormat = outputTable
+		runWorkspace = ""
+		runCmd.Flags().Lookup("workspace").Changed = false
+	})
+
+	if err := runConfigSet(configSetCmd, []string{"retries", "lots"}); err == nil {
+		t.Error("Expected an invalid value to be rejected")
+	}
+	if err := runConfigGet(configGetCmd, []string{"nope"}); err == nil {
+		t.Error("Expected an unknown setting to be rejected")
+	}
+
+	captureStdout(t, func() {
+		if err := runConfigSet(configSetCmd, []string{"default_workspace", "my-project"}); err != nil {
+			t.Fatalf("runConfigSet failed: %v", err)
+		}
+		if err := runConfigSet(configSetCmd, []string{"output", "json"}); err != nil {
+			t.Fatalf("runConfigSet failed: %v", err)
+		}
+	})
+
+	output := captureStdout(t, func() {
+		if err := runConfigGet(configGetCmd, []string{"default_workspace"}); err != nil {
+			t.Fatalf("runConfigGet failed: %v", err)
+		}
+	})
+	if strings.TrimSpace(output) != "my-project" {
+		t.Errorf("Expected config get to print the value, got %q", output)
+	}
+
+	output = captureStdout(t, func() {
+		if err := runConfigList(configListCmd, nil); err != nil {
+			t.Fatalf("runConfigList failed: %v", err)
+		}
+	})
+	if !strings.Contains(output, "default_workspace") || !strings.Contains(output, "file") {
+		t.Errorf("Expected config list to show the saved setting and its source, got %q", output)
+	}
+
+	if err := applyConfigDefaults(runCmd); err != nil {
+		t.Fatalf("applyConfigDefaults failed: %v", err)
+	}
+	if runWorkspace != "my-project" {
+		t.Errorf("Expected the default workspace to fill in --workspace, got %q", runWorkspace)
+	}
+	if outputFormat != outputJSON {
+		t.Errorf("Expected the configured output format, got %q", outputFormat)
+	}
+
+	// Flags given on the command line still win over the config
+	runWorkspace = "other"
+	runCmd.Flags().Lookup("workspace").Changed = true
+	if err := applyConfigDefaults(runCmd); err != nil {
+		t.Fatalf("applyConfigDefaults failed: %v", err)
+	}
+	if runWorkspace != "other" {
+		t.Errorf("Expected an explicit --workspace to be kept, got %q", runWorkspace)
+	}
+}
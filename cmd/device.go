@@ -5,8 +5,12 @@ import (
 	"crypto/ed25519"
 	"crypto/rand"
 	"fmt"
+	"io"
 	"os"
+	"sort"
 	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
 	"golang.org/x/crypto/curve25519"
@@ -47,26 +51,148 @@ var clearTokenCmd = &cobra.Command{
 	RunE: runClearToken,
 }
 
+var deviceAuditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "List devices across all workspaces",
+	Long: "List every device with access to any workspace you can see, deduplicated by key fingerprint, " +
+		"along with the workspaces each device can access.",
+	Args: cobra.NoArgs,
+	RunE: runDeviceAudit,
+}
+
+var renameDeviceCmd = &cobra.Command{
+	Use:   "rename [<device-id>] <new-name>",
+	Short: "Rename a registered device",
+	Long: "Change a device's display name. Use --self to rename this device without looking up its ID. " +
+		"The name must not already be used by another device in a workspace the device belongs to.",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if renameSelf {
+			return cobra.ExactArgs(1)(cmd, args)
+		}
+		return cobra.ExactArgs(2)(cmd, args)
+	},
+	RunE: runRenameDevice,
+}
+
+var approveDeviceCmd = &cobra.Command{
+	Use:   "approve <workspace-slug> <device-id>",
+	Short: "Approve a device waiting to join a workspace",
+	Long: "Let a newly registered device that is pending approval into the workspace. " +
+		"Requires the owner or admin role.",
+	Args: cobra.ExactArgs(2),
+	RunE: runApproveDevice,
+}
+
+var deviceListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the devices registered to your account",
+	Long: "List every device registered to your account with its platform, key fingerprint and when it " +
+		"was last seen. This device is marked as current.",
+	Args: cobra.NoArgs,
+	RunE: runDeviceList,
+}
+
+var revokeDeviceCmd = &cobra.Command{
+	Use:   "revoke <device-id>",
+	Short: "Revoke a lost or compromised device",
+	Long: "Cut a device off from your account and every workspace, e.g. after a laptop is lost. Asks for " +
+		"confirmation unless --force is set. The device may still hold copies of workspace keys and anything " +
+		"it already decrypted, so you're then offered to rotate the key of each workspace it could access.",
+	Args: cobra.ExactArgs(1),
+	RunE: runRevokeDevice,
+}
+
+var deviceWhoisCmd = &cobra.Command{
+	Use:   "whois <fingerprint-or-prefix>",
+	Short: "Find the device with a key fingerprint",
+	Long: "Look up a device by its key fingerprint, as shown by 'device audit', across every workspace " +
+		"you can see. A prefix of the fingerprint is enough if only one device matches; the SHA256: " +
+		"part may be left out.",
+	Args: cobra.ExactArgs(1),
+	RunE: runDeviceWhois,
+}
+
+var auditStaleDays int
+
+// auditColumns are the columns device audit can show
+var auditColumns = []tableColumn{
+	{Name: "name", Header: "Name", Default: true},
+	{Name: "device-id", Header: "Device ID"},
+	{Name: "fingerprint", Header: "Fingerprint", Default: true},
+	{Name: "workspaces", Header: "Workspaces", Default: true},
+	{Name: "last-seen", Header: "Last Seen", Default: true},
+}
+
+var auditTable tableOptions
+
+// deviceColumns are the columns device list can show
+var deviceColumns = []tableColumn{
+	{Name: "current", Header: "Current", Default: true},
+	{Name: "name", Header: "Name", Default: true},
+	{Name: "device-id", Header: "Device ID"},
+	{Name: "platform", Header: "Platform", Default: true},
+	{Name: "fingerprint", Header: "Fingerprint", Default: true},
+	{Name: "last-seen", Header: "Last Seen", Default: true},
+}
+
+var deviceListTable tableOptions
+
+var registerWait bool
+
+var (
+	// approvalPollInterval is how often register --wait checks the device status
+	approvalPollInterval = 5 * time.Second
+	// approvalTimeout bounds how long register --wait waits for an admin
+	approvalTimeout = 15 * time.Minute
+)
+
+var renameSelf bool
+
+var revokeForce bool
+
 func init() {
 	rootCmd.AddCommand(deviceCmd)
 	deviceCmd.AddCommand(registerDeviceCmd)
 	deviceCmd.AddCommand(unregisterDeviceCmd)
 	deviceCmd.AddCommand(clearTokenCmd)
+	deviceCmd.AddCommand(deviceAuditCmd)
+	deviceCmd.AddCommand(renameDeviceCmd)
+	deviceCmd.AddCommand(approveDeviceCmd)
+	deviceCmd.AddCommand(deviceWhoisCmd)
+	deviceCmd.AddCommand(deviceListCmd)
+	deviceCmd.AddCommand(revokeDeviceCmd)
+
+	deviceAuditCmd.Flags().IntVar(&auditStaleDays, "stale", 0,
+		"mark devices not seen in more than this many days (0 disables)")
+	addTableFlags(deviceAuditCmd, &auditTable, auditColumns)
+	addTableFlags(deviceListCmd, &deviceListTable, deviceColumns)
+
+	renameDeviceCmd.Flags().BoolVar(&renameSelf, "self", false, "rename this device")
+
+	revokeDeviceCmd.Flags().BoolVarP(&revokeForce, "force", "f", false, "revoke without asking for confirmation")
+
+	registerDeviceCmd.Flags().BoolVar(&registerWait, "wait", false,
+		"if the device needs admin approval, wait until it is approved")
 }
 
 func ensureAuthenticated() error {
 	storage := storage.New()
 
 	if storage.HasToken() {
-		fmt.Println("ℹ️  Found existing authentication token")
+		if expiresAt, err := storage.TokenExpiry(); err == nil {
+			infof("ℹ️  Found existing authentication token (%s)\n",
+				formatTokenExpiry(expiresAt, serverNow(storage, time.Now())))
+		} else {
+			infoln("ℹ️  Found existing authentication token")
+		}
 		return nil
 	}
 
-	fmt.Println("🔐 Authentication required for device registration")
-	fmt.Println()
+	infoln("🔐 Authentication required for device registration")
+	infoln()
 
 	reader := bufio.NewReader(os.Stdin)
-	fmt.Print("Email: ")
+	fmt.Fprint(stdout(), "Email: ")
 	email, err := reader.ReadString('\n')
 	if err != nil {
 		return fmt.Errorf("failed to read email: %w", err)
@@ -76,32 +202,25 @@ func ensureAuthenticated() error {
 		return fmt.Errorf("email cannot be empty")
 	}
 
-	fmt.Print("Password: ")
-	passwordBytes, err := term.ReadPassword(int(os.Stdin.Fd()))
+	password, err := readPassword()
 	if err != nil {
-		return fmt.Errorf("failed to read password: %w", err)
-	}
-	fmt.Println()
-
-	password := string(passwordBytes)
-	if password == "" {
-		return fmt.Errorf("password cannot be empty")
+		return err
 	}
 
-	fmt.Println("🔐 Authenticating...")
+	infoln("🔐 Authenticating...")
 
-	apiClient := client.New()
-	loginResp, err := apiClient.Login(email, password)
+	apiClient := newClient()
+	loginResp, err := authenticate(apiClient, email, password)
 	if err != nil {
 		return fmt.Errorf("❌ Authentication failed: %w", err)
 	}
 
-	if err := storage.StoreToken(loginResp.Token); err != nil {
-		return fmt.Errorf("❌ Failed to store authentication token: %w", err)
+	if err := storeLogin(storage, apiClient, loginResp); err != nil {
+		return err
 	}
 
-	fmt.Printf("✅ Authenticated as %s %s\n", loginResp.User.Name, loginResp.User.Surname)
-	fmt.Println()
+	infof("✅ Authenticated as %s %s\n", loginResp.User.Name, loginResp.User.Surname)
+	infoln()
 
 	return nil
 }
@@ -136,24 +255,24 @@ func checkExistingDevice(storage *storage.Storage) error {
 	}
 
 	deviceID, _ := storage.GetDeviceID()
-	fmt.Printf("⚠️  Device already registered with ID: %s\n", deviceID)
-	fmt.Println()
-	fmt.Println("If you deleted this device from the server, you can:")
-	fmt.Println("• Clear local credentials: initflow device unregister")
-	fmt.Println("• Then register again: initflow device register <name>")
-	fmt.Println()
-	fmt.Println("Or use 'initflow device list' to view registered devices")
+	infof("⚠️  Device already registered with ID: %s\n", deviceID)
+	infoln()
+	infoln("If you deleted this device from the server, you can:")
+	infoln("• Clear local credentials: initflow device unregister")
+	infoln("• Then register again: initflow device register <name>")
+	infoln()
+	infoln("Or use 'initflow device list' to view registered devices")
 	return fmt.Errorf("device already registered")
 }
 
 func generateKeypairs() (ed25519.PublicKey, ed25519.PrivateKey, []byte, []byte, error) {
-	fmt.Println("🔑 Generating Ed25519 signing keypair...")
+	infoln("🔑 Generating Ed25519 signing keypair...")
 	signingPublicKey, signingPrivateKey, err := generateEd25519Keypair()
 	if err != nil {
 		return nil, nil, nil, nil, fmt.Errorf("failed to generate signing keypair: %w", err)
 	}
 
-	fmt.Println("🔒 Generating X25519 encryption keypair...")
+	infoln("🔒 Generating X25519 encryption keypair...")
 	encryptionPublicKey, encryptionPrivateKey, err := generateX25519Keypair()
 	if err != nil {
 		return nil, nil, nil, nil, fmt.Errorf("failed to generate encryption keypair: %w", err)
@@ -168,25 +287,25 @@ func performDeviceRegistration(
 	encryptionPublicKey []byte,
 	storage *storage.Storage,
 ) (*client.DeviceRegistrationResponse, error) {
-	fmt.Println("📡 Registering device with server...")
-	apiClient := client.New()
+	infoln("📡 Registering device with server...")
+	apiClient := newClient()
 
 	// Debug: show current config
 	cfg := config.Get()
-	fmt.Printf("🔍 Debug: API URL: %s\n", cfg.APIBaseURL)
+	infof("🔍 Debug: API URL: %s\n", cfg.APIBaseURL)
 
 	token, err := storage.GetToken()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get authentication token: %w", err)
 	}
 
-	fmt.Printf("🔍 Debug: Using API client with token length: %d\n", len(token))
-	fmt.Printf("🔍 Debug: Ed25519 public key size: %d bytes\n", len(signingPublicKey))
-	fmt.Printf("🔍 Debug: X25519 public key size: %d bytes\n", len(encryptionPublicKey))
+	infof("🔍 Debug: Using API client with token length: %d\n", len(token))
+	infof("🔍 Debug: Ed25519 public key size: %d bytes\n", len(signingPublicKey))
+	infof("🔍 Debug: X25519 public key size: %d bytes\n", len(encryptionPublicKey))
 
 	deviceResp, err := apiClient.RegisterDevice(token, deviceName, signingPublicKey, encryptionPublicKey)
 	if err != nil {
-		fmt.Printf("🔍 Debug: Registration error details: %v\n", err)
+		infof("🔍 Debug: Registration error details: %v\n", err)
 		return nil, fmt.Errorf("❌ Device registration failed: %w", err)
 	}
 
@@ -199,7 +318,7 @@ func storeDeviceCredentials(
 	encryptionPrivateKey []byte,
 	deviceID string,
 ) error {
-	fmt.Println("🔐 Storing keys securely in system keychain...")
+	infoln("🔐 Storing keys securely in system keychain...")
 
 	if err := storage.StoreSigningPrivateKey(signingPrivateKey); err != nil {
 		return fmt.Errorf("failed to store signing private key: %w", err)
@@ -232,7 +351,7 @@ func runRegisterDevice(cmd *cobra.Command, args []string) error {
 		return nil // Not a real error, just early return
 	}
 
-	fmt.Printf("🔑 Registering device: %s\n", deviceName)
+	infof("🔑 Registering device: %s\n", deviceName)
 
 	signingPublicKey, signingPrivateKey, encryptionPublicKey, encryptionPrivateKey, err := generateKeypairs()
 	if err != nil {
@@ -248,16 +367,194 @@ func runRegisterDevice(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	if err := storage.StoreDeviceName(deviceResp.Device.Name); err != nil {
+		return fmt.Errorf("failed to store device name: %w", err)
+	}
 
 	_ = storage.DeleteToken()
-	fmt.Println("✅ Device registered successfully!")
-	fmt.Println()
-	fmt.Printf("Device ID: %s\n", deviceResp.Device.DeviceID)
-	fmt.Printf("Device Name: %s\n", deviceResp.Device.Name)
-	fmt.Printf("Created: %s\n", deviceResp.Device.CreatedAt)
-	fmt.Println()
-	fmt.Println("🔐 Keys stored securely in system keychain")
-	fmt.Println("💡 Next: Initialize workspace keys with 'initflow workspace list'")
+	infoln("✅ Device registered successfully!")
+	infoln()
+	if structuredOutput() {
+		if err := writeOutput(deviceResp.Device); err != nil {
+			return err
+		}
+	} else {
+		out := cmd.OutOrStdout()
+		fmt.Fprintf(out, "Device ID: %s\n", deviceResp.Device.DeviceID)
+		fmt.Fprintf(out, "Device Name: %s\n", deviceResp.Device.Name)
+		fmt.Fprintf(out, "Created: %s\n", deviceResp.Device.CreatedAt)
+	}
+	infoln()
+	infoln("🔐 Keys stored securely in system keychain")
+
+	if deviceResp.Device.Status == client.DeviceStatusPending {
+		if !registerWait {
+			infoln("⏳ This device is waiting for a workspace admin to approve it with")
+			infof("   'initflow device approve <workspace> %s'. Re-check with 'initflow device audit'.\n",
+				deviceResp.Device.DeviceID)
+			return nil
+		}
+
+		infoln("⏳ Waiting for a workspace admin to approve this device...")
+		if err := waitForApproval(newClient(), deviceResp.Device.DeviceID, approvalPollInterval, approvalTimeout); err != nil {
+			return err
+		}
+		infoln("✅ Device approved")
+	}
+
+	infoln("💡 Next: Initialize workspace keys with 'initflow workspace list'")
+
+	return nil
+}
+
+// waitForApproval polls the device until it leaves the pending state, failing
+// if it is denied or still pending after timeout.
+func waitForApproval(c *client.Client, deviceID string, interval, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		device, err := c.GetDevice(deviceID)
+		if err != nil {
+			return fmt.Errorf("❌ Failed to check device approval: %w", err)
+		}
+
+		switch device.Status {
+		case client.DeviceStatusDenied:
+			return fmt.Errorf("❌ Device %s was denied by a workspace admin. "+
+				"Run 'initflow device unregister' before registering again", deviceID)
+		case client.DeviceStatusPending:
+		default:
+			return nil
+		}
+
+		if time.Now().Add(interval).After(deadline) {
+			return fmt.Errorf("❌ Device %s is still pending approval after %s. "+
+				"Run 'initflow device register' again with --wait once an admin has approved it", deviceID, timeout)
+		}
+		time.Sleep(interval)
+	}
+}
+
+func runApproveDevice(cmd *cobra.Command, args []string) error {
+	workspaceSlug, deviceID := args[0], args[1]
+
+	store := storage.New()
+	if err := requireAPIAccess(store); err != nil {
+		return err
+	}
+
+	c := newClient()
+	workspace, err := c.GetWorkspaceBySlug(workspaceSlug)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to get workspace info: %w", err)
+	}
+
+	if !hasRole(workspace.Role, workspaceAdminRoles) {
+		return fmt.Errorf("❌ Approving devices in %s requires the owner or admin role (you are %s)",
+			workspaceSlug, workspace.Role)
+	}
+
+	device, err := c.ApproveDevice(workspace.ID, deviceID)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to approve device: %w", err)
+	}
+
+	infof("✅ Device \"%s\" (%s) approved for %s\n", device.Name, deviceID, workspaceSlug)
+
+	return nil
+}
+
+// deviceWorkspaces returns the slugs of the workspaces in results that deviceID can access
+func deviceWorkspaces(results []workspaceDevices, deviceID string) []string {
+	var slugs []string
+	for _, result := range results {
+		for _, device := range result.Devices {
+			if device.DeviceID == deviceID {
+				slugs = append(slugs, result.Workspace.Slug)
+				break
+			}
+		}
+	}
+	sort.Strings(slugs)
+	return slugs
+}
+
+func runRevokeDevice(cmd *cobra.Command, args []string) error {
+	deviceID := args[0]
+
+	store := storage.New()
+	if !store.HasDeviceID() {
+		return fmt.Errorf("❌ Device not registered. Please run 'initflow device register <name>' first")
+	}
+	if ownID, err := store.GetDeviceID(); err == nil && ownID == deviceID {
+		return fmt.Errorf("❌ %s is this device. Revoke it from another device, or run 'initflow device unregister' "+
+			"to clear its credentials here", deviceID)
+	}
+
+	c := newClient()
+	device, err := c.GetDevice(deviceID)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to get device: %w", err)
+	}
+
+	// Find the exposed workspaces first: once revoked, the device is no longer listed
+	workspaces, err := c.ListWorkspaces()
+	if err != nil {
+		return fmt.Errorf("❌ Failed to fetch workspaces: %w", err)
+	}
+	exposed := deviceWorkspaces(fetchWorkspaceDevices(c, workspaces), deviceID)
+
+	in := bufio.NewReader(os.Stdin)
+	if !revokeForce {
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			return fmt.Errorf("❌ Revoking \"%s\" can't be undone. Re-run with --force to confirm", device.Name)
+		}
+		proceed, err := askYesNo(in,
+			fmt.Sprintf("Revoke \"%s\" (%s)? It will need to register again to regain access", device.Name, deviceID), false)
+		if err != nil {
+			return err
+		}
+		if !proceed {
+			return fmt.Errorf("ℹ️ Revoke cancelled")
+		}
+	}
+
+	if err := c.RevokeDevice(deviceID); err != nil {
+		return fmt.Errorf("❌ Failed to revoke device: %w", err)
+	}
+
+	if structuredOutput() {
+		return writeOutput(map[string]any{"device_id": deviceID, "name": device.Name, "workspaces": exposed})
+	}
+
+	infof("✅ Device \"%s\" (%s) revoked\n", device.Name, deviceID)
+	if len(exposed) == 0 {
+		return nil
+	}
+	infof("⚠️  It could read %s and may still hold copies of their workspace keys.\n", strings.Join(exposed, ", "))
+
+	// Offer to rotate each key right away, but only when someone is there to answer
+	if revokeForce || !term.IsTerminal(int(os.Stdin.Fd())) || store.EnsureDeviceReady() != nil {
+		for _, slug := range exposed {
+			infof("   Rotate its key with 'initflow workspace rotate %s'\n", slug)
+		}
+		infoln("   Also change any secrets it could have decrypted.")
+		return nil
+	}
+
+	for _, slug := range exposed {
+		rotate, err := askYesNo(in, fmt.Sprintf("Rotate the key of %s now?", slug), true)
+		if err != nil {
+			return err
+		}
+		if !rotate {
+			infof("   Rotate it later with 'initflow workspace rotate %s'\n", slug)
+			continue
+		}
+		if err := rotateWorkspace(c, store, slug); err != nil {
+			return err
+		}
+	}
+	infoln("💡 Rotating a key doesn't change the secrets themselves. Change any the device could have decrypted.")
 
 	return nil
 }
@@ -267,20 +564,20 @@ func runUnregisterDevice(cmd *cobra.Command, args []string) error {
 
 	// Check if there are any device credentials to clear
 	if !storage.HasDeviceID() && !storage.HasSigningPrivateKey() && !storage.HasEncryptionPrivateKey() {
-		fmt.Println("ℹ️  No device credentials found in local storage")
+		infoln("ℹ️  No device credentials found in local storage")
 		return nil
 	}
 
-	fmt.Println("🔐 Clearing local device credentials...")
+	infoln("🔐 Clearing local device credentials...")
 
 	err := storage.ClearDeviceCredentials()
 	if err != nil {
 		return fmt.Errorf("❌ Failed to clear device credentials: %w", err)
 	}
 
-	fmt.Println("✅ Device credentials cleared successfully!")
-	fmt.Println()
-	fmt.Println("💡 You can now register a new device with 'initflow device register <name>'")
+	infoln("✅ Device credentials cleared successfully!")
+	infoln()
+	infoln("💡 You can now register a new device with 'initflow device register <name>'")
 
 	return nil
 }
@@ -289,19 +586,465 @@ func runClearToken(cmd *cobra.Command, args []string) error {
 	storage := storage.New()
 
 	if !storage.HasToken() {
-		fmt.Println("ℹ️  No authentication token found in local storage")
+		infoln("ℹ️  No authentication token found in local storage")
 		return nil
 	}
 
-	fmt.Println("🔐 Clearing authentication token...")
+	infoln("🔐 Clearing authentication token...")
 
 	err := storage.DeleteToken()
 	if err != nil {
 		return fmt.Errorf("❌ Failed to clear authentication token: %w", err)
 	}
 
-	fmt.Println("✅ Authentication token cleared successfully!")
-	fmt.Println("💡 You will need to authenticate again for device registration")
+	infoln("✅ Authentication token cleared successfully!")
+	infoln("💡 You will need to authenticate again for device registration")
+
+	return nil
+}
+
+// auditedDevice is a device seen in one or more workspaces
+type auditedDevice struct {
+	Fingerprint string   `json:"fingerprint"`
+	DeviceID    string   `json:"device_id"`
+	Name        string   `json:"name"`
+	CreatedAt   string   `json:"created_at,omitempty"`
+	LastSeenAt  string   `json:"last_seen_at,omitempty"`
+	Workspaces  []string `json:"workspaces"`
+	Stale       bool     `json:"stale"`
+	Pending     bool     `json:"pending_approval,omitempty"`
+}
+
+// workspaceDevices holds the devices fetched for a single workspace
+type workspaceDevices struct {
+	Workspace client.Workspace
+	Devices   []client.Device
+}
+
+// fetchWorkspaceDevices lists the devices of each workspace, warning about and
+// skipping workspaces whose devices can't be fetched.
+func fetchWorkspaceDevices(c *client.Client, workspaces []client.Workspace) []workspaceDevices {
+	results := make([]workspaceDevices, 0, len(workspaces))
+	for _, workspace := range workspaces {
+		devices, err := c.ListWorkspaceDevices(workspace.ID)
+		if err != nil {
+			fmt.Fprintf(stderr(), "⚠️  Skipping %s: %v\n", workspace.Slug, err)
+			continue
+		}
+		results = append(results, workspaceDevices{Workspace: workspace, Devices: devices})
+	}
+	return results
+}
+
+// aggregateDevices merges per-workspace device lists into one entry per device,
+// keyed by fingerprint. Devices last seen before now-staleAfter, or never seen,
+// are marked stale; a zero staleAfter disables the check.
+func aggregateDevices(results []workspaceDevices, staleAfter time.Duration, now time.Time) []auditedDevice {
+	byKey := make(map[string]*auditedDevice)
+
+	for _, result := range results {
+		for _, device := range result.Devices {
+			fingerprint, err := device.Fingerprint()
+			key := fingerprint
+			if err != nil {
+				key = "id:" + device.DeviceID
+			}
+
+			entry, ok := byKey[key]
+			if !ok {
+				entry = &auditedDevice{
+					Fingerprint: fingerprint,
+					DeviceID:    device.DeviceID,
+					Name:        device.Name,
+					CreatedAt:   device.CreatedAt,
+				}
+				byKey[key] = entry
+			}
+
+			entry.Pending = entry.Pending || device.Pending()
+			if seenLater(device.LastSeenAt, entry.LastSeenAt) {
+				entry.LastSeenAt = device.LastSeenAt
+			}
+			entry.Workspaces = append(entry.Workspaces, result.Workspace.Slug)
+		}
+	}
+
+	devices := make([]auditedDevice, 0, len(byKey))
+	for _, entry := range byKey {
+		sort.Strings(entry.Workspaces)
+		if staleAfter > 0 {
+			entry.Stale = isStale(entry.LastSeenAt, now.Add(-staleAfter))
+		}
+		devices = append(devices, *entry)
+	}
+
+	sort.Slice(devices, func(i, j int) bool {
+		if devices[i].Name != devices[j].Name {
+			return devices[i].Name < devices[j].Name
+		}
+		return devices[i].Fingerprint < devices[j].Fingerprint
+	})
+
+	return devices
+}
+
+// seenLater reports whether timestamp a is after b, treating empty as never seen
+func seenLater(a, b string) bool {
+	if a == "" {
+		return false
+	}
+	if b == "" {
+		return true
+	}
+
+	timeA, errA := time.Parse(time.RFC3339, a)
+	timeB, errB := time.Parse(time.RFC3339, b)
+	if errA != nil || errB != nil {
+		return a > b
+	}
+
+	return timeA.After(timeB)
+}
+
+func isStale(lastSeenAt string, cutoff time.Time) bool {
+	if lastSeenAt == "" {
+		return true
+	}
+
+	lastSeen, err := time.Parse(time.RFC3339, lastSeenAt)
+	if err != nil {
+		return false
+	}
+
+	return lastSeen.Before(cutoff)
+}
+
+func runDeviceAudit(cmd *cobra.Command, args []string) error {
+	if auditStaleDays < 0 {
+		return fmt.Errorf("--stale must not be negative")
+	}
+	columns, err := selectColumns(auditColumns, auditTable.columns)
+	if err != nil {
+		return err
+	}
+
+	infoln("🔍 Fetching devices across workspaces...")
+
+	store := storage.New()
+	if err := requireAPIAccess(store); err != nil {
+		return err
+	}
+
+	c := newClient()
+	workspaces, err := c.ListWorkspaces()
+	if err != nil {
+		return fmt.Errorf("❌ Failed to fetch workspaces: %w", err)
+	}
+
+	results := fetchWorkspaceDevices(c, workspaces)
+	if len(workspaces) > 0 && len(results) == 0 {
+		return fmt.Errorf("❌ Failed to fetch devices for every workspace")
+	}
+
+	staleAfter := time.Duration(auditStaleDays) * 24 * time.Hour
+	devices := aggregateDevices(results, staleAfter, time.Now())
+
+	if structuredOutput() {
+		return writeOutput(devices)
+	}
+
+	if len(devices) == 0 {
+		infoln("No devices found")
+		return nil
+	}
+
+	rows := make([]map[string]string, len(devices))
+	for i, device := range devices {
+		lastSeen := device.LastSeenAt
+		if lastSeen == "" {
+			lastSeen = "never"
+		}
+		if device.Stale {
+			lastSeen += " ⚠️ stale"
+		}
+		if device.Pending {
+			lastSeen += " ⏳ pending approval"
+		}
+
+		fingerprint := device.Fingerprint
+		if fingerprint == "" {
+			fingerprint = "(invalid key)"
+		}
+
+		rows[i] = map[string]string{
+			"name":        device.Name,
+			"device-id":   device.DeviceID,
+			"fingerprint": fingerprint,
+			"workspaces":  strings.Join(device.Workspaces, ", "),
+			"last-seen":   lastSeen,
+		}
+	}
+	writeTable(cmd.OutOrStdout(), columns, rows, !auditTable.noHeader)
+
+	return nil
+}
+
+// accountDevice is a device of the signed-in account as device list shows it
+type accountDevice struct {
+	client.Device
+	Fingerprint string `json:"fingerprint"`
+	Current     bool   `json:"current"`
+}
+
+// describeAccountDevices adds fingerprints to devices, marks the one with
+// currentID and sorts them by name
+func describeAccountDevices(devices []client.Device, currentID string) []accountDevice {
+	described := make([]accountDevice, len(devices))
+	for i, device := range devices {
+		fingerprint, _ := device.Fingerprint()
+		described[i] = accountDevice{
+			Device:      device,
+			Fingerprint: fingerprint,
+			Current:     currentID != "" && device.DeviceID == currentID,
+		}
+	}
+
+	sort.Slice(described, func(i, j int) bool {
+		if described[i].Name != described[j].Name {
+			return described[i].Name < described[j].Name
+		}
+		return described[i].DeviceID < described[j].DeviceID
+	})
+	return described
+}
+
+func runDeviceList(cmd *cobra.Command, args []string) error {
+	columns, err := selectColumns(deviceColumns, deviceListTable.columns)
+	if err != nil {
+		return err
+	}
+
+	store := storage.New()
+	if !store.HasDeviceID() {
+		return fmt.Errorf("❌ Device not registered. Please run 'initflow device register <name>' first")
+	}
+	currentID, err := store.GetDeviceID()
+	if err != nil {
+		return fmt.Errorf("❌ Failed to read device ID: %w", err)
+	}
+
+	list, err := newClient().ListDevices()
+	if err != nil {
+		return fmt.Errorf("❌ Failed to list devices: %w", err)
+	}
+	devices := describeAccountDevices(list, currentID)
+
+	if structuredOutput() {
+		return writeOutput(devices)
+	}
+
+	if len(devices) == 0 {
+		infoln("No devices found")
+		return nil
+	}
+
+	rows := make([]map[string]string, len(devices))
+	for i, device := range devices {
+		current := ""
+		if device.Current {
+			current = "*"
+		}
+
+		lastSeen := device.LastSeenAt
+		if lastSeen == "" {
+			lastSeen = "never"
+		}
+		if device.Pending() {
+			lastSeen += " ⏳ pending approval"
+		}
+
+		fingerprint := device.Fingerprint
+		if fingerprint == "" {
+			fingerprint = "(invalid key)"
+		}
+
+		rows[i] = map[string]string{
+			"current":     current,
+			"name":        device.Name,
+			"device-id":   device.DeviceID,
+			"platform":    orUnknown(device.Platform),
+			"fingerprint": fingerprint,
+			"last-seen":   lastSeen,
+		}
+	}
+	writeTable(cmd.OutOrStdout(), columns, rows, !deviceListTable.noHeader)
+
+	return nil
+}
+
+// fingerprintPrefix is the label encoding.Fingerprint puts before the hash
+const fingerprintPrefix = "SHA256:"
+
+// matchFingerprint returns the devices whose fingerprint starts with query.
+// The SHA256: label is optional in the query.
+func matchFingerprint(devices []auditedDevice, query string) []auditedDevice {
+	query = strings.TrimPrefix(strings.TrimSpace(query), fingerprintPrefix)
+	if query == "" {
+		return nil
+	}
+
+	var matches []auditedDevice
+	for _, device := range devices {
+		if device.Fingerprint != "" && strings.HasPrefix(strings.TrimPrefix(device.Fingerprint, fingerprintPrefix), query) {
+			matches = append(matches, device)
+		}
+	}
+	return matches
+}
+
+func runDeviceWhois(cmd *cobra.Command, args []string) error {
+	query := args[0]
+	if strings.TrimPrefix(strings.TrimSpace(query), fingerprintPrefix) == "" {
+		return fmt.Errorf("❌ Fingerprint cannot be empty")
+	}
+
+	store := storage.New()
+	if err := requireAPIAccess(store); err != nil {
+		return err
+	}
+
+	infoln("🔍 Searching devices across workspaces...")
+
+	c := newClient()
+	workspaces, err := c.ListWorkspaces()
+	if err != nil {
+		return fmt.Errorf("❌ Failed to fetch workspaces: %w", err)
+	}
+
+	results := fetchWorkspaceDevices(c, workspaces)
+	if len(workspaces) > 0 && len(results) == 0 {
+		return fmt.Errorf("❌ Failed to fetch devices for every workspace")
+	}
+
+	matches := matchFingerprint(aggregateDevices(results, 0, time.Now()), query)
+	if len(matches) == 0 {
+		return fmt.Errorf("❌ No device in your workspaces has a fingerprint starting with %s", query)
+	}
+
+	// JSON is always a list, so scripts handle one and several matches alike
+	switch {
+	case structuredOutput():
+		if err := writeOutput(matches); err != nil {
+			return err
+		}
+	case len(matches) == 1:
+		printWhois(cmd.OutOrStdout(), matches[0])
+	default:
+		rows := make([]map[string]string, len(matches))
+		for i, device := range matches {
+			rows[i] = map[string]string{
+				"name":        device.Name,
+				"fingerprint": device.Fingerprint,
+				"workspaces":  strings.Join(device.Workspaces, ", "),
+			}
+		}
+		columns, _ := selectColumns(auditColumns, []string{"name", "fingerprint", "workspaces"})
+		writeTable(cmd.OutOrStdout(), columns, rows, true)
+	}
+
+	if len(matches) > 1 {
+		return fmt.Errorf("❌ %s matches %d devices; use a longer prefix", query, len(matches))
+	}
+	return nil
+}
+
+// printWhois shows one device found by device whois
+func printWhois(out io.Writer, device auditedDevice) {
+	lastSeen := device.LastSeenAt
+	if lastSeen == "" {
+		lastSeen = "never"
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Name:\t%s\n", device.Name)
+	fmt.Fprintf(w, "Device ID:\t%s\n", device.DeviceID)
+	fmt.Fprintf(w, "Fingerprint:\t%s\n", device.Fingerprint)
+	fmt.Fprintf(w, "Registered:\t%s\n", orUnknown(device.CreatedAt))
+	fmt.Fprintf(w, "Last Seen:\t%s\n", lastSeen)
+	fmt.Fprintf(w, "Workspaces:\t%s\n", strings.Join(device.Workspaces, ", "))
+	if device.Pending {
+		fmt.Fprintf(w, "Status:\t⏳ pending approval\n")
+	}
+	_ = w.Flush()
+}
+
+// duplicateDeviceName returns the slug of a workspace where another device
+// sharing a workspace with deviceID is already called name, or "".
+func duplicateDeviceName(results []workspaceDevices, deviceID, name string) string {
+	for _, result := range results {
+		member := false
+		for _, device := range result.Devices {
+			if device.DeviceID == deviceID {
+				member = true
+				break
+			}
+		}
+		if !member {
+			continue
+		}
+
+		for _, device := range result.Devices {
+			if device.DeviceID != deviceID && strings.EqualFold(strings.TrimSpace(device.Name), name) {
+				return result.Workspace.Slug
+			}
+		}
+	}
+	return ""
+}
+
+func runRenameDevice(cmd *cobra.Command, args []string) error {
+	store := storage.New()
+	if !store.HasDeviceID() {
+		return fmt.Errorf("❌ Device not registered. Please run 'initflow device register <name>' first")
+	}
+
+	localID, err := store.GetDeviceID()
+	if err != nil {
+		return fmt.Errorf("❌ Failed to read device ID: %w", err)
+	}
+
+	deviceID, newName := localID, args[0]
+	if !renameSelf {
+		deviceID, newName = args[0], args[1]
+	}
+	newName = strings.TrimSpace(newName)
+	if newName == "" {
+		return fmt.Errorf("❌ Device name cannot be empty")
+	}
+
+	c := newClient()
+
+	infoln("🔍 Checking device names in your workspaces...")
+	workspaces, err := c.ListWorkspaces()
+	if err != nil {
+		return fmt.Errorf("❌ Failed to fetch workspaces: %w", err)
+	}
+	if slug := duplicateDeviceName(fetchWorkspaceDevices(c, workspaces), deviceID, newName); slug != "" {
+		return fmt.Errorf("❌ Another device in %s is already named \"%s\"", slug, newName)
+	}
+
+	device, err := c.RenameDevice(deviceID, newName)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to rename device: %w", err)
+	}
+
+	if deviceID == localID {
+		if err := store.StoreDeviceName(device.Name); err != nil {
+			return fmt.Errorf("❌ Device renamed, but updating the local device name failed: %w", err)
+		}
+	}
+
+	infof("✅ Device %s renamed to \"%s\"\n", deviceID, device.Name)
 
 	return nil
 }
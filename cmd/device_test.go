@@ -2,9 +2,20 @@ package cmd
 
 import (
 	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"golang.org/x/crypto/curve25519"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/client"
+	"github.com/DylanBlakemore/initflow-cli/internal/encoding"
+	"github.com/DylanBlakemore/initflow-cli/internal/storage"
 )
 
 func TestGenerateEd25519Keypair(t *testing.T) {
@@ -93,3 +104,505 @@ func TestGenerateKeypairsUnique(t *testing.T) {
 		t.Error("Generated X25519 private keys are identical")
 	}
 }
+
+func testDevice(t *testing.T, id, name, lastSeen string) (client.Device, string) {
+	t.Helper()
+
+	publicKey, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	device := client.Device{
+		DeviceID:         id,
+		Name:             name,
+		PublicKeyEd25519: encoding.Encode(publicKey),
+		LastSeenAt:       lastSeen,
+	}
+	return device, encoding.Fingerprint(publicKey)
+}
+
+func TestAggregateDevices(t *testing.T) {
+	now := time.Date(2025, 9, 30, 12, 0, 0, 0, time.UTC)
+
+	laptop, laptopFingerprint := testDevice(t, "dev-1", "Laptop", "2025-09-29T10:00:00Z")
+	laptopOlder := laptop
+	laptopOlder.LastSeenAt = "2025-09-01T10:00:00Z"
+	ci, _ := testDevice(t, "dev-2", "CI Runner", "2025-08-01T10:00:00Z")
+	neverSeen, _ := testDevice(t, "dev-3", "Desktop", "")
+
+	results := []workspaceDevices{
+		{Workspace: client.Workspace{Slug: "team-secrets"}, Devices: []client.Device{laptop, ci}},
+		{Workspace: client.Workspace{Slug: "my-project"}, Devices: []client.Device{laptopOlder, neverSeen}},
+	}
+
+	devices := aggregateDevices(results, 30*24*time.Hour, now)
+	if len(devices) != 3 {
+		t.Fatalf("Expected 3 deduplicated devices, got %d: %+v", len(devices), devices)
+	}
+
+	byName := make(map[string]auditedDevice)
+	for _, device := range devices {
+		byName[device.Name] = device
+	}
+
+	laptopEntry := byName["Laptop"]
+	if laptopEntry.Fingerprint != laptopFingerprint {
+		t.Errorf("Expected fingerprint %s, got %s", laptopFingerprint, laptopEntry.Fingerprint)
+	}
+	if strings.Join(laptopEntry.Workspaces, ",") != "my-project,team-secrets" {
+		t.Errorf("Expected laptop in both workspaces, got %v", laptopEntry.Workspaces)
+	}
+	if laptopEntry.LastSeenAt != "2025-09-29T10:00:00Z" {
+		t.Errorf("Expected most recent last seen, got %s", laptopEntry.LastSeenAt)
+	}
+	if laptopEntry.Stale {
+		t.Error("Expected recently seen laptop not to be stale")
+	}
+
+	if !byName["CI Runner"].Stale {
+		t.Error("Expected CI runner unseen for 60 days to be stale")
+	}
+	if !byName["Desktop"].Stale {
+		t.Error("Expected never-seen device to be stale")
+	}
+
+	if devices[0].Name != "CI Runner" || devices[2].Name != "Laptop" {
+		t.Errorf("Expected devices sorted by name, got %s, %s, %s", devices[0].Name, devices[1].Name, devices[2].Name)
+	}
+
+	for _, device := range aggregateDevices(results, 0, now) {
+		if device.Stale {
+			t.Errorf("Expected no stale devices when threshold is disabled, got %s", device.Name)
+		}
+	}
+}
+
+func TestDeviceAuditSkipsFailedWorkspaces(t *testing.T) {
+	laptop, _ := testDevice(t, "dev-1", "Laptop", "2025-09-29T10:00:00Z")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v1/workspaces":
+			json.NewEncoder(w).Encode(client.ListWorkspacesResponse{
+				Workspaces: []client.Workspace{
+					{ID: 1, Slug: "my-project"},
+					{ID: 2, Slug: "team-secrets"},
+				},
+			})
+		case "/api/v1/workspaces/1/devices":
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(client.ErrorResponse{Error: "forbidden", Message: "Not allowed"})
+		case "/api/v1/workspaces/2/devices":
+			json.NewEncoder(w).Encode(client.ListDevicesResponse{Devices: []client.Device{laptop}})
+		default:
+			t.Errorf("Unexpected request path: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	setupTestEnvironment(t, server.URL)
+
+	outputFormat = outputJSON
+	defer func() { outputFormat = outputTable }()
+
+	out := captureStdout(t, func() {
+		if err := runDeviceAudit(deviceAuditCmd, []string{}); err != nil {
+			t.Errorf("runDeviceAudit failed: %v", err)
+		}
+	})
+
+	var devices []auditedDevice
+	if err := json.Unmarshal([]byte(out), &devices); err != nil {
+		t.Fatalf("Expected JSON output, got %q: %v", out, err)
+	}
+	if len(devices) != 1 || devices[0].DeviceID != "dev-1" {
+		t.Errorf("Expected devices from the reachable workspace, got %+v", devices)
+	}
+}
+
+func TestDeviceList(t *testing.T) {
+	laptop, laptopFingerprint := testDevice(t, "test-device-123", "Laptop", "2025-09-29T10:00:00Z")
+	laptop.Platform = "darwin"
+	ci, _ := testDevice(t, "dev-2", "CI Runner", "")
+	broken := client.Device{DeviceID: "dev-3", Name: "Broken", PublicKeyEd25519: "not-a-key"}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" || r.URL.Path != "/api/v1/devices" {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(client.ListDevicesResponse{Devices: []client.Device{laptop, ci, broken}})
+	}))
+	defer server.Close()
+
+	setupTestEnvironment(t, server.URL)
+	t.Cleanup(func() { deviceListTable = tableOptions{} })
+
+	var err error
+	out := captureStdout(t, func() {
+		err = runDeviceList(deviceListCmd, []string{})
+	})
+	if err != nil {
+		t.Fatalf("runDeviceList failed: %v", err)
+	}
+	for _, want := range []string{"Platform", "darwin", "unknown", laptopFingerprint, "never", "(invalid key)"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in device list, got %q", want, out)
+		}
+	}
+
+	deviceListTable = tableOptions{columns: []string{"current", "name"}, noHeader: true}
+	out = captureStdout(t, func() {
+		err = runDeviceList(deviceListCmd, []string{})
+	})
+	if err != nil || out != "  |Broken\n  |CI Runner\n* |Laptop\n" {
+		t.Errorf("Expected devices sorted by name with this one marked, got %q, %v", out, err)
+	}
+
+	devices := describeAccountDevices([]client.Device{laptop, ci}, "test-device-123")
+	encoded, _ := json.Marshal(devices[1])
+	if !strings.Contains(string(encoded), `"device_id":"test-device-123"`) || !strings.Contains(string(encoded), `"current":true`) {
+		t.Errorf("Expected the device fields and current flag in JSON, got %s", encoded)
+	}
+}
+
+func TestRevokeDevice(t *testing.T) {
+	laptop, _ := testDevice(t, "lost-laptop", "Laptop", "2025-09-29T10:00:00Z")
+	ci, _ := testDevice(t, "dev-2", "CI Runner", "")
+	revoked := false
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/api/v1/devices/lost-laptop":
+			json.NewEncoder(w).Encode(client.DeviceResponse{Device: laptop})
+		case r.URL.Path == "/api/v1/workspaces":
+			json.NewEncoder(w).Encode(client.ListWorkspacesResponse{Workspaces: []client.Workspace{
+				{ID: 1, Slug: "team-secrets"}, {ID: 2, Slug: "my-project"}, {ID: 3, Slug: "ci-only"},
+			}})
+		case r.URL.Path == "/api/v1/workspaces/1/devices", r.URL.Path == "/api/v1/workspaces/2/devices":
+			json.NewEncoder(w).Encode(client.ListDevicesResponse{Devices: []client.Device{ci, laptop}})
+		case r.URL.Path == "/api/v1/workspaces/3/devices":
+			json.NewEncoder(w).Encode(client.ListDevicesResponse{Devices: []client.Device{ci}})
+		case r.Method == "POST" && r.URL.Path == "/api/v1/devices/lost-laptop/revoke":
+			revoked = true
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	setupTestEnvironment(t, server.URL)
+	t.Cleanup(func() { revokeForce = false })
+
+	err := runRevokeDevice(revokeDeviceCmd, []string{"lost-laptop"})
+	if err == nil || !strings.Contains(err.Error(), "--force") || revoked {
+		t.Fatalf("Expected revoking without a terminal to require --force, got %v", err)
+	}
+
+	if err := runRevokeDevice(revokeDeviceCmd, []string{"test-device-123"}); err == nil ||
+		!strings.Contains(err.Error(), "is this device") {
+		t.Errorf("Expected revoking this device to be refused, got %v", err)
+	}
+
+	revokeForce = true
+	outputFormat = outputJSON
+	defer func() { outputFormat = outputTable }()
+	out := captureStdout(t, func() {
+		err = runRevokeDevice(revokeDeviceCmd, []string{"lost-laptop"})
+	})
+	if err != nil || !revoked {
+		t.Fatalf("runRevokeDevice failed: %v", err)
+	}
+	var result struct {
+		Workspaces []string `json:"workspaces"`
+	}
+	if err := json.Unmarshal([]byte(out), &result); err != nil {
+		t.Fatalf("Expected JSON output, got %q: %v", out, err)
+	}
+	if !reflect.DeepEqual(result.Workspaces, []string{"my-project", "team-secrets"}) {
+		t.Errorf("Expected the workspaces the device could read, got %v", result.Workspaces)
+	}
+}
+
+// renameDeviceServer serves one workspace holding devices and renames via PATCH,
+// counting rename requests.
+func renameDeviceServer(t *testing.T, devices []client.Device, renames *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/api/v1/workspaces":
+			json.NewEncoder(w).Encode(client.ListWorkspacesResponse{
+				Workspaces: []client.Workspace{{ID: 1, Slug: "my-project"}},
+			})
+		case r.URL.Path == "/api/v1/workspaces/1/devices":
+			json.NewEncoder(w).Encode(client.ListDevicesResponse{Devices: devices})
+		case r.Method == "PATCH" && r.URL.Path == "/api/v1/devices/test-device-123":
+			*renames++
+			var req client.RenameDeviceRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Errorf("Failed to decode request: %v", err)
+			}
+			json.NewEncoder(w).Encode(client.RenameDeviceResponse{
+				Device: client.Device{DeviceID: "test-device-123", Name: req.Name},
+			})
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestRenameDeviceSelfUpdatesLocalName(t *testing.T) {
+	self, _ := testDevice(t, "test-device-123", "Old Laptop", "")
+	other, _ := testDevice(t, "dev-2", "CI Runner", "")
+
+	var renames int
+	server := renameDeviceServer(t, []client.Device{self, other}, &renames)
+	defer server.Close()
+
+	setupTestEnvironment(t, server.URL)
+	renameSelf = true
+	t.Cleanup(func() { renameSelf = false })
+
+	store := storage.New()
+	if err := store.StoreDeviceName("Old Laptop"); err != nil {
+		t.Fatalf("Failed to store device name: %v", err)
+	}
+	t.Cleanup(func() { store.DeleteDeviceName() })
+
+	if err := runRenameDevice(renameDeviceCmd, []string{"  Work Laptop "}); err != nil {
+		t.Fatalf("runRenameDevice failed: %v", err)
+	}
+
+	if renames != 1 {
+		t.Errorf("Expected one rename request, got %d", renames)
+	}
+	name, err := store.GetDeviceName()
+	if err != nil {
+		t.Fatalf("Expected a stored device name: %v", err)
+	}
+	if name != "Work Laptop" {
+		t.Errorf("Expected local name 'Work Laptop', got %q", name)
+	}
+}
+
+func TestRenameDeviceRejectsDuplicateName(t *testing.T) {
+	self, _ := testDevice(t, "test-device-123", "Old Laptop", "")
+	other, _ := testDevice(t, "dev-2", "CI Runner", "")
+
+	var renames int
+	server := renameDeviceServer(t, []client.Device{self, other}, &renames)
+	defer server.Close()
+
+	setupTestEnvironment(t, server.URL)
+
+	err := runRenameDevice(renameDeviceCmd, []string{"test-device-123", "ci runner"})
+	if err == nil || !strings.Contains(err.Error(), "my-project") {
+		t.Fatalf("Expected duplicate name error naming the workspace, got %v", err)
+	}
+	if renames != 0 {
+		t.Errorf("Expected no rename request, got %d", renames)
+	}
+
+	if err := runRenameDevice(renameDeviceCmd, []string{"test-device-123", " "}); err == nil {
+		t.Error("Expected error for empty name")
+	}
+}
+
+// approvalServer reports the device as pending for the first polls, then as final
+func approvalServer(t *testing.T, pendingPolls int, final string, polls *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" || r.URL.Path != "/api/v1/devices/test-device-123" {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		*polls++
+		status := final
+		if *polls <= pendingPolls {
+			status = client.DeviceStatusPending
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(client.DeviceResponse{
+			Device: client.Device{DeviceID: "test-device-123", Status: status},
+		})
+	}))
+}
+
+func TestWaitForApprovalPollsUntilApproved(t *testing.T) {
+	var polls int
+	server := approvalServer(t, 2, client.DeviceStatusApproved, &polls)
+	defer server.Close()
+
+	setupTestEnvironment(t, server.URL)
+
+	if err := waitForApproval(client.New(), "test-device-123", time.Millisecond, time.Minute); err != nil {
+		t.Fatalf("waitForApproval failed: %v", err)
+	}
+	if polls != 3 {
+		t.Errorf("Expected 3 polls, got %d", polls)
+	}
+}
+
+func TestWaitForApprovalDenied(t *testing.T) {
+	var polls int
+	server := approvalServer(t, 1, client.DeviceStatusDenied, &polls)
+	defer server.Close()
+
+	setupTestEnvironment(t, server.URL)
+
+	err := waitForApproval(client.New(), "test-device-123", time.Millisecond, time.Minute)
+	if err == nil || !strings.Contains(err.Error(), "denied") {
+		t.Fatalf("Expected a denied error, got %v", err)
+	}
+}
+
+func TestWaitForApprovalTimesOut(t *testing.T) {
+	var polls int
+	server := approvalServer(t, 1000, client.DeviceStatusApproved, &polls)
+	defer server.Close()
+
+	setupTestEnvironment(t, server.URL)
+
+	err := waitForApproval(client.New(), "test-device-123", time.Millisecond, 5*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "still pending") {
+		t.Fatalf("Expected a timeout error, got %v", err)
+	}
+}
+
+func TestApproveDeviceRequiresAdmin(t *testing.T) {
+	var approvals int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/api/v1/workspaces":
+			json.NewEncoder(w).Encode(client.ListWorkspacesResponse{Workspaces: []client.Workspace{
+				{ID: 1, Slug: "my-project", Role: "admin"},
+				{ID: 2, Slug: "other", Role: "member"},
+			}})
+		case r.Method == "POST" && r.URL.Path == "/api/v1/workspaces/1/devices/dev-2/approve":
+			approvals++
+			json.NewEncoder(w).Encode(client.DeviceResponse{
+				Device: client.Device{DeviceID: "dev-2", Name: "CI Runner", Status: client.DeviceStatusApproved},
+			})
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	setupTestEnvironment(t, server.URL)
+
+	err := runApproveDevice(approveDeviceCmd, []string{"other", "dev-2"})
+	if err == nil || !strings.Contains(err.Error(), "owner or admin") {
+		t.Fatalf("Expected a role error, got %v", err)
+	}
+
+	if err := runApproveDevice(approveDeviceCmd, []string{"my-project", "dev-2"}); err != nil {
+		t.Fatalf("runApproveDevice failed: %v", err)
+	}
+	if approvals != 1 {
+		t.Errorf("Expected one approval request, got %d", approvals)
+	}
+}
+
+func TestMatchFingerprint(t *testing.T) {
+	devices := []auditedDevice{
+		{Name: "Laptop", Fingerprint: "SHA256:abcdef123"},
+		{Name: "CI Runner", Fingerprint: "SHA256:abcxyz789"},
+		{Name: "Broken", Fingerprint: ""},
+	}
+
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{"SHA256:abcdef123", []string{"Laptop"}},
+		{"abcd", []string{"Laptop"}},
+		{"SHA256:abcx", []string{"CI Runner"}},
+		{"abc", []string{"Laptop", "CI Runner"}},
+		{"ABC", nil},
+		{"zzz", nil},
+		{"SHA256:", nil},
+	}
+
+	for _, tt := range tests {
+		var names []string
+		for _, device := range matchFingerprint(devices, tt.query) {
+			names = append(names, device.Name)
+		}
+		if !reflect.DeepEqual(names, tt.expected) {
+			t.Errorf("matchFingerprint(%q) = %v, expected %v", tt.query, names, tt.expected)
+		}
+	}
+}
+
+func TestDeviceWhois(t *testing.T) {
+	laptop, laptopFingerprint := testDevice(t, "dev-1", "Laptop", "2025-09-29T10:00:00Z")
+	laptop.CreatedAt = "2025-06-01T09:00:00Z"
+
+	// Find a second device whose fingerprint shares its first character with the laptop's
+	var ci client.Device
+	var ciFingerprint string
+	for ciFingerprint == "" || ciFingerprint[:8] != laptopFingerprint[:8] {
+		ci, ciFingerprint = testDevice(t, "dev-2", "CI Runner", "")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v1/workspaces":
+			json.NewEncoder(w).Encode(client.ListWorkspacesResponse{
+				Workspaces: []client.Workspace{{ID: 1, Slug: "my-project"}, {ID: 2, Slug: "team-secrets"}},
+			})
+		case "/api/v1/workspaces/1/devices":
+			json.NewEncoder(w).Encode(client.ListDevicesResponse{Devices: []client.Device{laptop, ci}})
+		case "/api/v1/workspaces/2/devices":
+			json.NewEncoder(w).Encode(client.ListDevicesResponse{Devices: []client.Device{laptop}})
+		default:
+			t.Errorf("Unexpected request path: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	setupTestEnvironment(t, server.URL)
+	quiet = true
+	defer func() { quiet = false }()
+
+	var err error
+	out := captureStdout(t, func() {
+		err = runDeviceWhois(deviceWhoisCmd, []string{laptopFingerprint[:16]})
+	})
+	if err != nil {
+		t.Fatalf("runDeviceWhois failed: %v", err)
+	}
+	for _, want := range []string{"Laptop", laptopFingerprint, "2025-06-01T09:00:00Z", "my-project, team-secrets"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in whois output, got %q", want, out)
+		}
+	}
+
+	out = captureStdout(t, func() {
+		err = runDeviceWhois(deviceWhoisCmd, []string{laptopFingerprint[:8]})
+	})
+	if err == nil || !strings.Contains(err.Error(), "matches 2 devices") {
+		t.Errorf("Expected an ambiguous prefix error, got %v", err)
+	}
+	if !strings.Contains(out, laptopFingerprint) || !strings.Contains(out, ciFingerprint) {
+		t.Errorf("Expected both matches listed, got %q", out)
+	}
+
+	err = runDeviceWhois(deviceWhoisCmd, []string{"SHA256:%%%"})
+	if err == nil || !strings.Contains(err.Error(), "No device") {
+		t.Errorf("Expected a no-match error, got %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/spf13/cobra"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/client"
+	"github.com/DylanBlakemore/initflow-cli/internal/encoding"
+	"github.com/DylanBlakemore/initflow-cli/internal/storage"
+)
+
+var rotateKeyDeviceCmd = &cobra.Command{
+	Use:   "rotate-key",
+	Short: "Replace this device's encryption key",
+	Long: "Generate a new X25519 keypair for this device, re-wrap every workspace key it holds to the new " +
+		"public key and upload both in one request. The old private key is only discarded once the server " +
+		"has accepted the new one, so an interrupted rotation leaves the device working with its old key. " +
+		"The signing key, device ID and fingerprint stay the same.",
+	Args: cobra.NoArgs,
+	RunE: runRotateDeviceKey,
+}
+
+func init() {
+	deviceCmd.AddCommand(rotateKeyDeviceCmd)
+}
+
+// heldWorkspaceKey returns the key of workspace this device can decrypt, from
+// the local cache or else wrapped to it on the server, and the key's version.
+// ok is false if the key was never shared with this device.
+func heldWorkspaceKey(c *client.Client, store *storage.Storage, workspace *client.Workspace) (key []byte, version int, ok bool, err error) {
+	if store.HasWorkspaceKey(workspace.Slug) {
+		key, err := currentWorkspaceKey(c, store, workspace)
+		return key, workspace.KeyVersion, err == nil, err
+	}
+
+	wrapped, version, err := c.GetDeviceWorkspaceKey(workspace.ID)
+	var apiErr *client.APIError
+	if errors.As(err, &apiErr) && apiErr.Status == http.StatusNotFound {
+		return nil, 0, false, nil
+	}
+	if err != nil {
+		return nil, 0, false, err
+	}
+
+	key, err = unwrapWorkspaceKey(wrapped, workspace.ID, store)
+	if err != nil {
+		return nil, 0, false, fmt.Errorf("failed to decrypt the workspace key: %w", err)
+	}
+	return key, version, true, nil
+}
+
+func runRotateDeviceKey(cmd *cobra.Command, args []string) error {
+	store := storage.New()
+	if err := store.EnsureDeviceReady(); err != nil {
+		return fmt.Errorf("❌ %w", err)
+	}
+	deviceID, err := store.GetDeviceID()
+	if err != nil {
+		return fmt.Errorf("❌ Failed to read device ID: %w", err)
+	}
+
+	c := newClient()
+	workspaces, err := c.ListWorkspaces()
+	if err != nil {
+		return fmt.Errorf("❌ Failed to list workspaces: %w", err)
+	}
+
+	infoln("🔑 Generating new encryption keypair...")
+	publicKey, privateKey, err := generateX25519Keypair()
+	if err != nil {
+		return fmt.Errorf("❌ Failed to generate encryption keypair: %w", err)
+	}
+	encodedPublicKey, err := encoding.EncodeX25519PublicKey(publicKey)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to encode public key: %w", err)
+	}
+
+	var workspaceKeys []client.DeviceWorkspaceKey
+	for i := range workspaces {
+		workspace := &workspaces[i]
+		if !workspace.KeyInitialized {
+			continue
+		}
+		key, version, ok, err := heldWorkspaceKey(c, store, workspace)
+		if err != nil {
+			return fmt.Errorf("❌ Failed to read the key of %s: %w", workspace.Slug, err)
+		}
+		if !ok {
+			continue
+		}
+
+		wrapped, err := wrapWorkspaceKeyFor(key, workspace.ID, publicKey)
+		if err != nil {
+			return fmt.Errorf("❌ Failed to encrypt the key of %s: %w", workspace.Slug, err)
+		}
+		workspaceKeys = append(workspaceKeys, client.DeviceWorkspaceKey{
+			WorkspaceID:         workspace.ID,
+			WrappedWorkspaceKey: encoding.Encode(wrapped),
+			KeyVersion:          version,
+		})
+	}
+
+	infof("📡 Uploading the new public key and %d re-encrypted workspace keys...\n", len(workspaceKeys))
+	err = c.RotateDeviceKey(deviceID, client.RotateDeviceKeyRequest{
+		PublicKeyX25519: encodedPublicKey,
+		WorkspaceKeys:   workspaceKeys,
+	})
+	var apiErr *client.APIError
+	if errors.As(err, &apiErr) && apiErr.Status == http.StatusConflict {
+		return fmt.Errorf("❌ A workspace key was rotated while re-encrypting. Nothing was replaced; "+
+			"run the command again: %w", err)
+	}
+	if err != nil {
+		return fmt.Errorf("❌ Failed to rotate device key: %w", err)
+	}
+
+	if err := store.StoreEncryptionPrivateKey(privateKey); err != nil {
+		return fmt.Errorf("❌ The server switched to the new key but it couldn't be stored locally, "+
+			"so this device can no longer decrypt workspace keys. Recover them with "+
+			"'initflow workspace recover' or ask an admin to invite this device again: %w", err)
+	}
+
+	infof("✅ Rotated this device's encryption key and re-encrypted %d workspace keys\n", len(workspaceKeys))
+	return nil
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/client"
+	"github.com/DylanBlakemore/initflow-cli/internal/encoding"
+	"github.com/DylanBlakemore/initflow-cli/internal/storage"
+)
+
+// deviceKeyServer holds four workspaces: "cached" with its key stored
+// locally, "shared" with its key only wrapped on the server, "unshared" whose
+// key this device never got and "empty" without a key. It remembers the last
+// device key rotation it accepted.
+type deviceKeyServer struct {
+	t         *testing.T
+	sharedKey []byte
+	conflict  bool
+	rotation  *client.RotateDeviceKeyRequest
+}
+
+func (s *deviceKeyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case r.Method == "GET" && r.URL.Path == "/api/v1/workspaces":
+		json.NewEncoder(w).Encode(client.ListWorkspacesResponse{Workspaces: []client.Workspace{
+			{ID: 1, Slug: "cached", Role: "member", KeyInitialized: true, KeyVersion: 1},
+			{ID: 2, Slug: "shared", Role: "member", KeyInitialized: true, KeyVersion: 3},
+			{ID: 3, Slug: "unshared", Role: "member", KeyInitialized: true, KeyVersion: 1},
+			{ID: 4, Slug: "empty", Role: "owner"},
+		}})
+	case r.Method == "GET" && r.URL.Path == "/api/v1/workspaces/2/device-key":
+		wrapped, err := wrapWorkspaceKey(s.sharedKey, 2, storage.New())
+		if err != nil {
+			s.t.Fatalf("Failed to wrap the shared key: %v", err)
+		}
+		json.NewEncoder(w).Encode(client.DeviceKeyResponse{WrappedWorkspaceKey: encoding.Encode(wrapped), KeyVersion: 3})
+	case r.Method == "GET" && r.URL.Path == "/api/v1/workspaces/3/device-key":
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(client.ErrorResponse{Error: "not_found", Message: "No key for this device"})
+	case r.Method == "PUT" && r.URL.Path == "/api/v1/devices/test-device-123/encryption-key":
+		if s.conflict {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(client.ErrorResponse{Error: "conflict", Message: "Key version changed"})
+			return
+		}
+		s.rotation = &client.RotateDeviceKeyRequest{}
+		json.NewDecoder(r.Body).Decode(s.rotation)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		s.t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestRotateDeviceKey(t *testing.T) {
+	cachedKey := make([]byte, encoding.WorkspaceKeySize)
+	sharedKey := make([]byte, encoding.WorkspaceKeySize)
+	rand.Read(cachedKey)
+	rand.Read(sharedKey)
+
+	fake := &deviceKeyServer{t: t, sharedKey: sharedKey, conflict: true}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	setupTestEnvironment(t, server.URL)
+
+	store := storage.New()
+	if err := store.StoreWorkspaceKey("cached", cachedKey); err != nil {
+		t.Fatalf("Failed to store workspace key: %v", err)
+	}
+	t.Cleanup(func() { store.DeleteWorkspaceKey("cached") })
+	oldPrivate, _ := store.GetEncryptionPrivateKey()
+
+	// A rejected upload keeps the old key
+	err := runRotateDeviceKey(rotateKeyDeviceCmd, nil)
+	if err == nil || !strings.Contains(err.Error(), "run the command again") {
+		t.Errorf("Expected a conflict to ask for a retry, got %v", err)
+	}
+	if current, _ := store.GetEncryptionPrivateKey(); !bytes.Equal(current, oldPrivate) {
+		t.Fatal("Expected the old private key to be kept after a failed rotation")
+	}
+
+	fake.conflict = false
+	if err := runRotateDeviceKey(rotateKeyDeviceCmd, nil); err != nil {
+		t.Fatalf("runRotateDeviceKey failed: %v", err)
+	}
+
+	newPrivate, _ := store.GetEncryptionPrivateKey()
+	if bytes.Equal(newPrivate, oldPrivate) {
+		t.Fatal("Expected a new private key to be stored")
+	}
+	if fake.rotation == nil || len(fake.rotation.WorkspaceKeys) != 2 {
+		t.Fatalf("Expected the keys of the cached and shared workspaces, got %+v", fake.rotation)
+	}
+	if publicKey, err := encoding.Decode(fake.rotation.PublicKeyX25519); err != nil || len(publicKey) != 32 {
+		t.Errorf("Expected a new X25519 public key, got %q", fake.rotation.PublicKeyX25519)
+	}
+
+	want := map[int][]byte{1: cachedKey, 2: sharedKey}
+	versions := map[int]int{1: 1, 2: 3}
+	for _, uploaded := range fake.rotation.WorkspaceKeys {
+		wrapped, _ := encoding.Decode(uploaded.WrappedWorkspaceKey)
+		key, err := unwrapWorkspaceKeyWith(wrapped, uploaded.WorkspaceID, newPrivate)
+		if err != nil || !bytes.Equal(key, want[uploaded.WorkspaceID]) {
+			t.Errorf("Expected workspace %d's key to unwrap with the new private key, got %v", uploaded.WorkspaceID, err)
+		}
+		if uploaded.KeyVersion != versions[uploaded.WorkspaceID] {
+			t.Errorf("Expected workspace %d at key version %d, got %d",
+				uploaded.WorkspaceID, versions[uploaded.WorkspaceID], uploaded.KeyVersion)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"sort"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/output"
+)
+
+// maxProbedArgs is how many positional arguments describeArgs tries before
+// treating a command's argument count as unbounded
+const maxProbedArgs = 8
+
+var dumpCmd = &cobra.Command{
+	Use:    "__dump",
+	Short:  "Dump machine-readable descriptions of the CLI",
+	Hidden: true,
+}
+
+var dumpCommandsCmd = &cobra.Command{
+	Use:   "commands",
+	Short: "Describe every command, its arguments and flags as JSON",
+	Long: "Walk the live command tree and print each command with its argument count and flags, " +
+		"for GUIs and other tools that wrap initflow.",
+	Args: cobra.NoArgs,
+	RunE: runDumpCommands,
+}
+
+func init() {
+	rootCmd.AddCommand(dumpCmd)
+	dumpCmd.AddCommand(dumpCommandsCmd)
+}
+
+type flagSpec struct {
+	Name      string `json:"name"`
+	Shorthand string `json:"shorthand,omitempty"`
+	Type      string `json:"type"`
+	Default   string `json:"default"`
+	Usage     string `json:"usage"`
+	Required  bool   `json:"required,omitempty"`
+}
+
+// argsSpec is the number of positional arguments a command accepts. Max is -1
+// when there is no upper bound.
+type argsSpec struct {
+	Min int `json:"min"`
+	Max int `json:"max"`
+}
+
+type commandSpec struct {
+	Path    string     `json:"path"`
+	Use     string     `json:"use"`
+	Short   string     `json:"short,omitempty"`
+	Long    string     `json:"long,omitempty"`
+	Aliases []string   `json:"aliases,omitempty"`
+	Args    argsSpec   `json:"args"`
+	Flags   []flagSpec `json:"flags,omitempty"`
+}
+
+type commandTree struct {
+	GlobalFlags []flagSpec    `json:"global_flags"`
+	Commands    []commandSpec `json:"commands"`
+}
+
+// describeFlags lists a flag set sorted by name
+func describeFlags(flags *pflag.FlagSet) []flagSpec {
+	var specs []flagSpec
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if flag.Hidden || flag.Name == "help" {
+			return
+		}
+		_, required := flag.Annotations[cobra.BashCompOneRequiredFlag]
+		specs = append(specs, flagSpec{
+			Name:      flag.Name,
+			Shorthand: flag.Shorthand,
+			Type:      flag.Value.Type(),
+			Default:   flag.DefValue,
+			Usage:     flag.Usage,
+			Required:  required,
+		})
+	})
+	sort.Slice(specs, func(i, j int) bool { return specs[i].Name < specs[j].Name })
+	return specs
+}
+
+// describeArgs finds the range of argument counts cmd accepts by probing its
+// Args validator, since cobra only exposes it as a function.
+func describeArgs(cmd *cobra.Command) argsSpec {
+	if cmd.Args == nil {
+		return argsSpec{Min: 0, Max: -1}
+	}
+
+	spec := argsSpec{Min: -1, Max: -1}
+	for n := 0; n <= maxProbedArgs; n++ {
+		args := make([]string, n)
+		for i := range args {
+			args[i] = "arg"
+		}
+		if cmd.Args(cmd, args) != nil {
+			if spec.Min >= 0 && spec.Max < 0 {
+				spec.Max = n - 1
+			}
+			continue
+		}
+		if spec.Min < 0 {
+			spec.Min = n
+		}
+	}
+	if spec.Min < 0 {
+		spec.Min = 0
+	}
+	return spec
+}
+
+// describeCommands walks cmd and its visible subcommands depth first. The
+// root's persistent flags are the global flags, so they are listed separately.
+func describeCommands(cmd *cobra.Command) []commandSpec {
+	spec := commandSpec{
+		Path:    cmd.CommandPath(),
+		Use:     cmd.Use,
+		Short:   cmd.Short,
+		Long:    cmd.Long,
+		Aliases: cmd.Aliases,
+		Args:    describeArgs(cmd),
+		Flags:   describeFlags(cmd.NonInheritedFlags()),
+	}
+	if !cmd.HasParent() {
+		spec.Flags = describeFlags(cmd.LocalNonPersistentFlags())
+	}
+	specs := []commandSpec{spec}
+
+	for _, sub := range cmd.Commands() {
+		if sub.Hidden || sub.Name() == "help" {
+			continue
+		}
+		specs = append(specs, describeCommands(sub)...)
+	}
+	return specs
+}
+
+func runDumpCommands(cmd *cobra.Command, args []string) error {
+	return output.Write(stdout(), output.JSON, commandTree{
+		GlobalFlags: describeFlags(rootCmd.PersistentFlags()),
+		Commands:    describeCommands(rootCmd),
+	})
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDumpCommands(t *testing.T) {
+	var err error
+	out := captureStdout(t, func() {
+		err = runDumpCommands(dumpCommandsCmd, []string{})
+	})
+	if err != nil {
+		t.Fatalf("runDumpCommands failed: %v", err)
+	}
+
+	var tree commandTree
+	if err := json.Unmarshal([]byte(out), &tree); err != nil {
+		t.Fatalf("Expected JSON output, got %q: %v", out, err)
+	}
+
+	commands := make(map[string]commandSpec, len(tree.Commands))
+	for _, command := range tree.Commands {
+		commands[command.Path] = command
+	}
+
+	init, ok := commands["initflow workspace init"]
+	if !ok {
+		t.Fatal("Expected workspace init in the command tree")
+	}
+	if init.Args != (argsSpec{Min: 1, Max: 1}) {
+		t.Errorf("Expected workspace init to take exactly one argument, got %+v", init.Args)
+	}
+	if commands["initflow auth login"].Args != (argsSpec{Min: 0, Max: 1}) {
+		t.Errorf("Expected auth login to take at most one argument, got %+v", commands["initflow auth login"].Args)
+	}
+	if _, ok := commands["initflow __dump"]; ok {
+		t.Error("Expected hidden commands to be left out")
+	}
+
+	globals := make(map[string]flagSpec)
+	for _, flag := range tree.GlobalFlags {
+		globals[flag.Name] = flag
+	}
+	if flag := globals["output"]; flag.Shorthand != "o" || flag.Default != outputTable || flag.Type != "string" {
+		t.Errorf("Expected the --output global flag, got %+v", flag)
+	}
+	if _, ok := globals["api-url"]; !ok {
+		t.Error("Expected the --api-url global flag")
+	}
+}
@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/client"
+)
+
+const jsonErrorsEnvVar = "INITFLOW_JSON_ERRORS"
+
+// Exit codes, so scripts can branch on the kind of failure without parsing messages
+const (
+	exitError        = 1
+	exitAuth         = 3
+	exitNotFound     = 4
+	exitConflict     = 5
+	exitRateLimited  = 6
+	exitServer       = 7
+	exitNetwork      = 8
+	exitPrecondition = 9
+)
+
+var jsonErrors bool
+
+// silentExit ends a command with a non-zero exit code without reporting an
+// error, for commands whose normal output already says what is wrong.
+type silentExit struct {
+	code int
+}
+
+func (e *silentExit) Error() string {
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
+type errorDetail struct {
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+	Status     int    `json:"status,omitempty"`
+	RetryAfter int    `json:"retry_after,omitempty"` // seconds, for rate limited requests
+}
+
+type errorPayload struct {
+	Error errorDetail `json:"error"`
+}
+
+func jsonErrorsEnabled() bool {
+	if jsonErrors {
+		return true
+	}
+	enabled, _ := strconv.ParseBool(os.Getenv(jsonErrorsEnvVar))
+	return enabled
+}
+
+// exitCodeFor maps an error to the process exit code
+func exitCodeFor(err error) int {
+	var silent *silentExit
+	if errors.As(err, &silent) {
+		return silent.code
+	}
+
+	if errors.Is(err, client.ErrOTPRequired) || errors.Is(err, client.ErrInvalidOTP) {
+		return exitAuth
+	}
+	if errors.Is(err, client.ErrPreconditionFailed) {
+		return exitPrecondition
+	}
+
+	var apiErr *client.APIError
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.Status == http.StatusUnauthorized || apiErr.Status == http.StatusForbidden:
+			return exitAuth
+		case apiErr.Status == http.StatusNotFound:
+			return exitNotFound
+		case apiErr.Status == http.StatusConflict:
+			return exitConflict
+		case apiErr.Status == http.StatusTooManyRequests:
+			return exitRateLimited
+		case apiErr.Status >= http.StatusInternalServerError:
+			return exitServer
+		}
+		return exitError
+	}
+
+	var urlErr *url.Error
+	var netErr net.Error
+	if errors.As(err, &urlErr) || errors.As(err, &netErr) {
+		return exitNetwork
+	}
+
+	return exitError
+}
+
+// describeError builds the structured form of err for --json-errors
+func describeError(err error) errorDetail {
+	detail := errorDetail{
+		Code:    "error",
+		Message: strings.TrimLeftFunc(err.Error(), isMessagePrefix),
+	}
+
+	var apiErr *client.APIError
+	switch {
+	case errors.Is(err, client.ErrOTPRequired):
+		detail.Code = "otp_required"
+	case errors.Is(err, client.ErrInvalidOTP):
+		detail.Code = "invalid_otp"
+	case errors.Is(err, client.ErrPreconditionFailed) && !errors.As(err, &apiErr):
+		detail.Code = "precondition_failed"
+	case errors.As(err, &apiErr):
+		detail.Status = apiErr.Status
+		detail.RetryAfter = int(apiErr.RetryAfter.Round(time.Second) / time.Second)
+		detail.Code = apiErr.Code
+		if detail.Code == "" {
+			detail.Code = strings.ReplaceAll(strings.ToLower(http.StatusText(apiErr.Status)), " ", "_")
+		}
+	case exitCodeFor(err) == exitNetwork:
+		detail.Code = "network_error"
+	}
+
+	return detail
+}
+
+// isMessagePrefix matches the emoji and spacing that lead human error messages
+func isMessagePrefix(r rune) bool {
+	return r > unicode.MaxASCII || unicode.IsSpace(r)
+}
+
+// handleError reports err to w, as JSON when --json-errors is set, and returns
+// the exit code for it.
+func handleError(w io.Writer, err error) int {
+	var silent *silentExit
+	if errors.As(err, &silent) {
+		return silent.code
+	}
+
+	if !jsonErrorsEnabled() {
+		_, _ = fmt.Fprintln(w, err)
+		return exitCodeFor(err)
+	}
+
+	data, marshalErr := json.Marshal(errorPayload{Error: describeError(err)})
+	if marshalErr != nil {
+		_, _ = fmt.Fprintln(w, err)
+	} else {
+		_, _ = fmt.Fprintln(w, string(data))
+	}
+	return exitCodeFor(err)
+}
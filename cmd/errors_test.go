@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/client"
+)
+
+func TestJSONErrorsNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(client.ErrorResponse{Error: "not_found", Message: "Organization not found"})
+	}))
+	defer server.Close()
+
+	setupTestEnvironment(t, server.URL)
+	jsonErrors = true
+	t.Cleanup(func() { jsonErrors = false })
+
+	var err error
+	stdout := captureStdout(t, func() {
+		err = runWorkspaceList(workspaceListCmd, []string{})
+	})
+	require.Error(t, err)
+	assert.NotContains(t, stdout, "Organization not found", "errors belong on stderr")
+
+	var stderr bytes.Buffer
+	code := handleError(&stderr, err)
+	assert.Equal(t, exitNotFound, code)
+
+	var payload map[string]map[string]any
+	require.NoError(t, json.Unmarshal(stderr.Bytes(), &payload))
+	assert.Equal(t, map[string]any{
+		"code":    "not_found",
+		"message": "Failed to fetch workspaces: list workspaces failed: Organization not found",
+		"status":  float64(404),
+	}, payload["error"])
+}
+
+func TestHandleErrorHumanOutput(t *testing.T) {
+	t.Setenv(jsonErrorsEnvVar, "")
+
+	var stderr bytes.Buffer
+	code := handleError(&stderr, errors.New("❌ Device not registered"))
+	assert.Equal(t, exitError, code)
+	assert.Equal(t, "❌ Device not registered\n", stderr.String())
+}
+
+func TestJSONErrorsFromEnvironment(t *testing.T) {
+	t.Setenv(jsonErrorsEnvVar, "1")
+
+	var stderr bytes.Buffer
+	code := handleError(&stderr, errors.New("❌ Device not registered"))
+	assert.Equal(t, exitError, code)
+	assert.JSONEq(t, `{"error":{"code":"error","message":"Device not registered"}}`, stderr.String())
+}
+
+func TestExitCodeFor(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{"generic", errors.New("boom"), exitError},
+		{"unauthorized", &client.APIError{Status: http.StatusUnauthorized}, exitAuth},
+		{"forbidden", &client.APIError{Status: http.StatusForbidden}, exitAuth},
+		{"otp", fmt.Errorf("❌ Authentication failed: %w", client.ErrInvalidOTP), exitAuth},
+		{"not found", &client.APIError{Status: http.StatusNotFound}, exitNotFound},
+		{"conflict", &client.APIError{Status: http.StatusConflict}, exitConflict},
+		{"rate limited", &client.APIError{Status: http.StatusTooManyRequests}, exitRateLimited},
+		{"server", fmt.Errorf("wrapped: %w", &client.APIError{Status: http.StatusBadGateway}), exitServer},
+		{"bad request", &client.APIError{Status: http.StatusBadRequest}, exitError},
+		{"precondition", &client.APIError{Status: http.StatusPreconditionFailed}, exitPrecondition},
+		{"expected value", fmt.Errorf("❌ KEY changed: %w", client.ErrPreconditionFailed), exitPrecondition},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, exitCodeFor(tt.err))
+		})
+	}
+}
+
+func TestDescribeErrorRateLimited(t *testing.T) {
+	err := fmt.Errorf("❌ Failed to fetch workspaces: %w",
+		&client.APIError{Op: "list workspaces", Status: http.StatusTooManyRequests, RetryAfter: 12 * time.Second})
+
+	assert.Equal(t, errorDetail{
+		Code:       "too_many_requests",
+		Message:    "Failed to fetch workspaces: list workspaces failed: rate limited, retry in 12s",
+		Status:     http.StatusTooManyRequests,
+		RetryAfter: 12,
+	}, describeError(err))
+	assert.Equal(t, exitRateLimited, exitCodeFor(err))
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// exportTemplate is a named secrets export --format. Header is rendered
+// once with .Name, then Line for each secret, sorted by key, with .Key and
+// .Value.
+type exportTemplate struct {
+	Header string
+	Line   string
+}
+
+// exportTemplates are the named formats beyond dotenv, json and snapshot. Each escapes
+// values for what reads the file back.
+var exportTemplates = map[string]exportTemplate{
+	// A Kubernetes Secret manifest; data values are base64 as the API expects
+	"k8s": {
+		Header: "apiVersion: v1\nkind: Secret\nmetadata:\n  name: {{ .Name }}\ntype: Opaque\ndata:\n",
+		Line:   "  {{ .Key }}: {{ base64 .Value }}\n",
+	},
+	// export lines to source from a POSIX shell
+	"shell": {
+		Line: "export {{ .Key }}={{ shellquote .Value }}\n",
+	},
+	// A docker --env-file, which takes values verbatim up to the end of the line
+	"docker": {
+		Line: "{{ .Key }}={{ dockervalue .Value }}\n",
+	},
+}
+
+// exportFormats lists every accepted --format, for help and errors
+func exportFormats() []string {
+	formats := []string{"dotenv", "json", "snapshot"}
+	named := make([]string, 0, len(exportTemplates))
+	for name := range exportTemplates {
+		named = append(named, name)
+	}
+	sort.Strings(named)
+	return append(formats, named...)
+}
+
+// exportTemplateFuncs escape values in export templates, named or --template
+var exportTemplateFuncs = template.FuncMap{
+	"base64": func(value string) string {
+		return base64.StdEncoding.EncodeToString([]byte(value))
+	},
+	// shellquote single-quotes a value, closing and reopening the quotes
+	// around any single quote inside it
+	"shellquote": func(value string) string {
+		return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+	},
+	"dockervalue": func(value string) (string, error) {
+		if strings.ContainsAny(value, "\r\n") {
+			return "", fmt.Errorf("docker env files can't hold values with line breaks")
+		}
+		return value, nil
+	},
+}
+
+// k8sNameInvalid matches what a Kubernetes object name can't contain
+var k8sNameInvalid = regexp.MustCompile(`[^a-z0-9.-]+`)
+
+// k8sSecretName names the Secret of a workspace environment, e.g. api-prod
+func k8sSecretName(slug, env string) string {
+	name := slug
+	if env != "" {
+		name += "-" + env
+	}
+	return strings.Trim(k8sNameInvalid.ReplaceAllString(strings.ToLower(name), "-"), "-.")
+}
+
+// parseExportTemplate parses a --template, or the named format's templates
+func parseExportTemplate(format, text string) (*template.Template, *template.Template, error) {
+	header := ""
+	if text == "" {
+		named := exportTemplates[format]
+		header, text = named.Header, named.Line
+	}
+
+	headerTmpl, err := template.New("header").Option("missingkey=error").Parse(header)
+	if err != nil {
+		return nil, nil, err
+	}
+	lineTmpl, err := template.New("template").Funcs(exportTemplateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid --template: %w", err)
+	}
+	return headerTmpl, lineTmpl, nil
+}
+
+// renderSecrets writes values sorted by key through a named format's
+// templates, or through text when it is set. Lines of a --template that
+// don't end in a newline get one.
+func renderSecrets(w io.Writer, values map[string]string, format, text, name string) error {
+	headerTmpl, lineTmpl, err := parseExportTemplate(format, text)
+	if err != nil {
+		return err
+	}
+	if err := headerTmpl.Execute(w, struct{ Name string }{name}); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var line strings.Builder
+	for _, key := range keys {
+		line.Reset()
+		data := struct{ Key, Value string }{key, values[key]}
+		if err := lineTmpl.Execute(&line, data); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		if !strings.HasSuffix(line.String(), "\n") {
+			line.WriteString("\n")
+		}
+		if _, err := io.WriteString(w, line.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
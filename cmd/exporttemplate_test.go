@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// exportFixture has values each named format has to escape
+var exportFixture = map[string]string{
+	"API_KEY": "abc",
+	"QUOTED":  `it's "$HOME"`,
+	"MULTI":   "line1\nline2",
+}
+
+func TestRenderSecretsNamedFormats(t *testing.T) {
+	tests := []struct {
+		format string
+		values map[string]string
+		want   string
+	}{
+		{"k8s", exportFixture, "apiVersion: v1\nkind: Secret\nmetadata:\n  name: api-prod\ntype: Opaque\ndata:\n" +
+			"  API_KEY: YWJj\n  MULTI: bGluZTEKbGluZTI=\n  QUOTED: aXQncyAiJEhPTUUi\n"},
+		{"shell", exportFixture, "export API_KEY='abc'\nexport MULTI='line1\nline2'\nexport QUOTED='it'\\''s \"$HOME\"'\n"},
+		{"docker", map[string]string{"API_KEY": "abc", "QUOTED": `it's "$HOME"`}, "API_KEY=abc\nQUOTED=it's \"$HOME\"\n"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.format, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := renderSecrets(&out, tc.values, tc.format, "", "api-prod"); err != nil {
+				t.Fatalf("renderSecrets failed: %v", err)
+			}
+			if out.String() != tc.want {
+				t.Errorf("Unexpected %s output\n got: %q\nwant: %q", tc.format, out.String(), tc.want)
+			}
+		})
+	}
+
+	var out bytes.Buffer
+	err := renderSecrets(&out, exportFixture, "docker", "", "")
+	if err == nil || !strings.Contains(err.Error(), "MULTI: ") || !strings.Contains(err.Error(), "line breaks") {
+		t.Errorf("Expected docker to reject a multi-line value, got %v", err)
+	}
+}
+
+func TestRenderSecretsShellRoundTrip(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("no sh")
+	}
+
+	var script bytes.Buffer
+	if err := renderSecrets(&script, exportFixture, "shell", "", ""); err != nil {
+		t.Fatalf("renderSecrets failed: %v", err)
+	}
+	script.WriteString(`printf '%s|%s|%s' "$API_KEY" "$QUOTED" "$MULTI"`)
+
+	out, err := exec.Command(sh, "-c", script.String()).Output() // #nosec G204 - the test's own script
+	if err != nil {
+		t.Fatalf("sh failed: %v", err)
+	}
+	if want := "abc|it's \"$HOME\"|line1\nline2"; string(out) != want {
+		t.Errorf("Expected the shell to read the values back unchanged\n got: %q\nwant: %q", out, want)
+	}
+}
+
+func TestRenderSecretsCustomTemplate(t *testing.T) {
+	var out bytes.Buffer
+	err := renderSecrets(&out, exportFixture, "dotenv", `{{ .Key }}={{ .Value | printf "%q" }}`, "")
+	if err != nil {
+		t.Fatalf("renderSecrets failed: %v", err)
+	}
+	want := "API_KEY=\"abc\"\nMULTI=\"line1\\nline2\"\nQUOTED=\"it's \\\"$HOME\\\"\"\n"
+	if out.String() != want {
+		t.Errorf("Unexpected template output\n got: %q\nwant: %q", out.String(), want)
+	}
+
+	out.Reset()
+	if err := renderSecrets(&out, exportFixture, "dotenv", "{{ .Key }}: {{ base64 .Value }}\n", ""); err != nil {
+		t.Fatalf("renderSecrets failed: %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "API_KEY: YWJj\nMULTI: ") {
+		t.Errorf("Expected the escaping functions in custom templates, got %q", out.String())
+	}
+
+	if err := renderSecrets(&out, exportFixture, "dotenv", "{{ .Key", ""); err == nil || !strings.Contains(err.Error(), "invalid --template") {
+		t.Errorf("Expected a parse error, got %v", err)
+	}
+	if err := renderSecrets(&out, exportFixture, "dotenv", "{{ .Missing }}", ""); err == nil {
+		t.Error("Expected an unknown field to fail")
+	}
+}
+
+func TestK8sSecretName(t *testing.T) {
+	for _, tc := range []struct{ slug, env, want string }{
+		{"api", "", "api"},
+		{"api", "prod", "api-prod"},
+		{"My_Project", "eu_west", "my-project-eu-west"},
+	} {
+		if got := k8sSecretName(tc.slug, tc.env); got != tc.want {
+			t.Errorf("k8sSecretName(%q, %q) = %q, want %q", tc.slug, tc.env, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/client"
+	"github.com/DylanBlakemore/initflow-cli/internal/encoding"
+	"github.com/DylanBlakemore/initflow-cli/internal/storage"
+)
+
+var workspaceInviteDeviceCmd = &cobra.Command{
+	Use:   "invite-device <workspace-slug> <device-id>",
+	Short: "Share the workspace key with another device",
+	Long: "Wrap this device's copy of the workspace key to another registered device's X25519 public key " +
+		"and upload it, so that device can read and write the workspace's secrets. The server only ever sees " +
+		"the wrapped key. Requires the owner or admin role.",
+	Args: cobra.ExactArgs(2),
+	RunE: runWorkspaceInviteDevice,
+}
+
+func init() {
+	workspaceCmd.AddCommand(workspaceInviteDeviceCmd)
+}
+
+func runWorkspaceInviteDevice(cmd *cobra.Command, args []string) error {
+	workspaceSlug, deviceID := args[0], args[1]
+
+	store := storage.New()
+	if err := store.EnsureDeviceReady(); err != nil {
+		return fmt.Errorf("❌ %w", err)
+	}
+	if ownID, err := store.GetDeviceID(); err == nil && ownID == deviceID {
+		return fmt.Errorf("❌ %s is this device. Invite another device, or use 'initflow workspace recover' "+
+			"to restore this one's key", deviceID)
+	}
+
+	if !store.HasWorkspaceKey(workspaceSlug) {
+		return fmt.Errorf("❌ This device has no key for %s to share", workspaceSlug)
+	}
+
+	c := newClient()
+	workspace, err := c.GetWorkspaceBySlug(workspaceSlug)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to get workspace info: %w", err)
+	}
+
+	if !hasRole(workspace.Role, workspaceAdminRoles) {
+		return fmt.Errorf("❌ Inviting devices to %s requires the owner or admin role (you are %s)",
+			workspaceSlug, workspace.Role)
+	}
+
+	workspaceKey, err := currentWorkspaceKey(c, store, workspace)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to read workspace key: %w", err)
+	}
+
+	device, err := c.GetDevice(deviceID)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to get device: %w", err)
+	}
+	if device.Pending() {
+		return fmt.Errorf("❌ Device \"%s\" is waiting for approval. Run 'initflow device approve %s %s' first",
+			device.Name, workspaceSlug, deviceID)
+	}
+	if device.Status == client.DeviceStatusDenied {
+		return fmt.Errorf("❌ Device \"%s\" was denied by a workspace admin and can't be given the key of %s. "+
+			"It has to register again and be approved", device.Name, workspaceSlug)
+	}
+
+	publicKey, err := encoding.Decode(device.PublicKeyX25519)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to decode the device's public key: %w", err)
+	}
+
+	infof("🔒 Encrypting the workspace key for \"%s\"...\n", device.Name)
+	wrapped, err := wrapWorkspaceKeyFor(workspaceKey, workspace.ID, publicKey)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to encrypt workspace key: %w", err)
+	}
+
+	infoln("📡 Uploading encrypted key to server...")
+	if err := c.InviteDevice(workspace.ID, deviceID, wrapped); err != nil {
+		return fmt.Errorf("❌ Failed to invite device: %w", err)
+	}
+
+	infof("✅ Device \"%s\" (%s) can now use %s\n", device.Name, deviceID, workspaceSlug)
+	return nil
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/client"
+	"github.com/DylanBlakemore/initflow-cli/internal/encoding"
+	"github.com/DylanBlakemore/initflow-cli/internal/storage"
+)
+
+// inviteServer holds workspace 1, "my-project", and a set of devices, and
+// remembers the last invite it received
+type inviteServer struct {
+	t       *testing.T
+	role    string
+	devices map[string]client.Device
+	invite  *client.InviteDeviceRequest
+}
+
+func (s *inviteServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case r.Method == "GET" && r.URL.Path == "/api/v1/workspaces":
+		json.NewEncoder(w).Encode(client.ListWorkspacesResponse{Workspaces: []client.Workspace{
+			{ID: 1, Name: "My Project", Slug: "my-project", Role: s.role, KeyInitialized: true},
+		}})
+	case r.Method == "GET" && strings.HasPrefix(r.URL.Path, "/api/v1/devices/"):
+		device, ok := s.devices[strings.TrimPrefix(r.URL.Path, "/api/v1/devices/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(client.ErrorResponse{Error: "not_found", Message: "Device not found"})
+			return
+		}
+		json.NewEncoder(w).Encode(client.DeviceResponse{Device: device})
+	case r.Method == "POST" && r.URL.Path == "/api/v1/workspaces/1/invite-device":
+		s.invite = &client.InviteDeviceRequest{}
+		json.NewDecoder(r.Body).Decode(s.invite)
+		w.WriteHeader(http.StatusCreated)
+	default:
+		s.t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestWorkspaceInviteDevice(t *testing.T) {
+	otherPublic, otherPrivate, err := generateX25519Keypair()
+	if err != nil {
+		t.Fatalf("generateX25519Keypair failed: %v", err)
+	}
+	fake := &inviteServer{t: t, role: "owner", devices: map[string]client.Device{
+		"laptop-2":  {DeviceID: "laptop-2", Name: "Laptop", PublicKeyX25519: encoding.Encode(otherPublic)},
+		"pending-1": {DeviceID: "pending-1", Name: "New", Status: client.DeviceStatusPending},
+		"denied-1":  {DeviceID: "denied-1", Name: "Stolen", Status: client.DeviceStatusDenied},
+	}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	setupTestEnvironment(t, server.URL)
+
+	workspaceKey := make([]byte, encoding.WorkspaceKeySize)
+	rand.Read(workspaceKey)
+	store := storage.New()
+	if err := store.StoreWorkspaceKey("my-project", workspaceKey); err != nil {
+		t.Fatalf("Failed to store workspace key: %v", err)
+	}
+	t.Cleanup(func() { store.DeleteWorkspaceKey("my-project") })
+
+	if err := runWorkspaceInviteDevice(workspaceInviteDeviceCmd, []string{"my-project", "laptop-2"}); err != nil {
+		t.Fatalf("runWorkspaceInviteDevice failed: %v", err)
+	}
+	if fake.invite == nil || fake.invite.DeviceID != "laptop-2" {
+		t.Fatalf("Expected an invite for laptop-2, got %+v", fake.invite)
+	}
+	wrapped, err := encoding.Decode(fake.invite.WrappedWorkspaceKey)
+	if err != nil {
+		t.Fatalf("Failed to decode the wrapped key: %v", err)
+	}
+	unwrapped, err := unwrapWorkspaceKeyWith(wrapped, 1, otherPrivate)
+	if err != nil || !bytes.Equal(unwrapped, workspaceKey) {
+		t.Errorf("Expected the invited device to unwrap the workspace key, got %v", err)
+	}
+
+	fake.invite = nil
+	for _, tc := range []struct {
+		deviceID string
+		role     string
+		want     string
+	}{
+		{"pending-1", "owner", "device approve my-project pending-1"},
+		{"denied-1", "owner", "was denied by a workspace admin"},
+		{"test-device-123", "owner", "is this device"},
+		{"unknown", "owner", "Device not found"},
+		{"laptop-2", "member", "requires the owner or admin role"},
+	} {
+		fake.role = tc.role
+		err := runWorkspaceInviteDevice(workspaceInviteDeviceCmd, []string{"my-project", tc.deviceID})
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("Expected inviting %s as %s to fail with %q, got %v", tc.deviceID, tc.role, tc.want, err)
+		}
+	}
+	if fake.invite != nil {
+		t.Errorf("Expected no key uploaded by the failed invites, got %+v", fake.invite)
+	}
+}
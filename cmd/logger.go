@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+)
+
+// quiet suppresses informational output such as progress messages and hints.
+// Errors and the data a command was asked for are always printed.
+var quiet bool
+
+// infoWriter returns where informational messages go. With JSON or YAML output
+// they move to stderr so stdout stays machine-readable.
+func infoWriter() io.Writer {
+	if structuredOutput() {
+		return stderr()
+	}
+	return stdout()
+}
+
+// infoln prints an informational message unless --quiet is set
+func infoln(a ...any) {
+	if quiet {
+		return
+	}
+	fmt.Fprintln(infoWriter(), a...)
+}
+
+// infof prints a formatted informational message unless --quiet is set
+func infof(format string, a ...any) {
+	if quiet {
+		return
+	}
+	fmt.Fprintf(infoWriter(), format, a...)
+}
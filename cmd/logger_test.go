@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/client"
+)
+
+// captureStdout runs fn with os.Stdout redirected and returns what was written
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+
+	oldStdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = oldStdout }()
+
+	done := make(chan string)
+	go func() {
+		out, _ := io.ReadAll(r)
+		done <- string(out)
+	}()
+
+	fn()
+	_ = w.Close()
+
+	return <-done
+}
+
+func TestQuietWorkspaceListPrintsOnlyData(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response := client.ListWorkspacesResponse{
+			Workspaces: []client.Workspace{
+				{ID: 1, Name: "My Project", Slug: "my-project", Role: "Owner"},
+			},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	setupTestEnvironment(t, server.URL)
+
+	quiet = true
+	defer func() { quiet = false }()
+
+	out := captureStdout(t, func() {
+		if err := runWorkspaceList(workspaceListCmd, []string{}); err != nil {
+			t.Errorf("runWorkspaceList failed: %v", err)
+		}
+	})
+
+	if strings.Contains(out, "Fetching workspaces") {
+		t.Errorf("Expected progress message to be suppressed, got:\n%s", out)
+	}
+	if strings.Contains(out, "💡") {
+		t.Errorf("Expected hint to be suppressed, got:\n%s", out)
+	}
+	if !strings.Contains(out, "my-project") {
+		t.Errorf("Expected workspace data on stdout, got:\n%s", out)
+	}
+}
+
+func TestInfoSuppressedWhenQuiet(t *testing.T) {
+	quiet = true
+	out := captureStdout(t, func() {
+		infoln("🔍 progress")
+		infof("%s\n", "🔍 progress")
+	})
+	quiet = false
+
+	if out != "" {
+		t.Errorf("Expected no output in quiet mode, got %q", out)
+	}
+
+	out = captureStdout(t, func() {
+		infoln("🔍 progress")
+	})
+	if out != "🔍 progress\n" {
+		t.Errorf("Expected informational output, got %q", out)
+	}
+}
@@ -0,0 +1,20 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zalando/go-keyring"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/agent"
+)
+
+// TestMain swaps the OS keychain for go-keyring's in-memory mock so the
+// command tests don't depend on a running secret service, and points them
+// away from any agent running for the real config.
+func TestMain(m *testing.M) {
+	keyring.MockInit()
+	os.Setenv(agent.SocketEnvVar, filepath.Join(os.TempDir(), "initflow-test-no-agent.sock"))
+	os.Exit(m.Run())
+}
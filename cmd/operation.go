@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/client"
+)
+
+var (
+	// waitForOperations makes commands block on work the server accepted but hasn't finished
+	waitForOperations bool
+	// operationWaitTimeout bounds how long --wait waits
+	operationWaitTimeout = 10 * time.Minute
+)
+
+// finishOperation waits for op when --wait is set, spinning at a terminal, and
+// otherwise reports that the server is still working on what.
+func finishOperation(c *client.Client, op *client.Operation, what string) error {
+	if !waitForOperations {
+		infof("⏳ The server accepted the %s and is finishing in the background (operation %s). "+
+			"Use --wait to block until it is done\n", what, op.ID)
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), operationWaitTimeout)
+	defer cancel()
+
+	spin := newSpinner(fmt.Sprintf("Waiting for the server to finish the %s...", what))
+	final, err := c.PollOperation(ctx, op.ID)
+	spin.Stop()
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return fmt.Errorf("operation %s still %s after %s; it may yet finish", op.ID, final.Status, operationWaitTimeout)
+	case err != nil:
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/output"
+)
+
+const (
+	outputTable = string(output.Table)
+	outputJSON  = string(output.JSON)
+	outputYAML  = string(output.YAML)
+)
+
+// outputFormat selects how commands render the data they were asked for
+var outputFormat = outputTable
+
+func validateOutputFormat(format string) error {
+	_, err := output.Parse(format)
+	return err
+}
+
+// structuredOutput reports whether --output asks for JSON or YAML instead of a table
+func structuredOutput() bool {
+	return output.Format(outputFormat).Structured()
+}
+
+// stdout returns where command output goes. Every command shares the root
+// command's writers, so tests can capture output with rootCmd.SetOut.
+func stdout() io.Writer {
+	return rootCmd.OutOrStdout()
+}
+
+// stderr returns where warnings and errors go, settable with rootCmd.SetErr
+func stderr() io.Writer {
+	return rootCmd.ErrOrStderr()
+}
+
+// writeOutput prints v to stdout in the --output format
+func writeOutput(v any) error {
+	return output.Write(stdout(), output.Format(outputFormat), v)
+}
+
+// tableColumn is a column a command can show in table output. Name is what
+// --columns accepts; columns marked Default are shown when none are requested.
+type tableColumn struct {
+	Name    string
+	Header  string
+	Default bool
+}
+
+// tableOptions holds the --columns and --no-header flags of a table command
+type tableOptions struct {
+	columns  []string
+	noHeader bool
+}
+
+// addTableFlags registers --columns and --no-header on cmd
+func addTableFlags(cmd *cobra.Command, opts *tableOptions, known []tableColumn) {
+	names := make([]string, len(known))
+	for i, column := range known {
+		names[i] = column.Name
+	}
+	cmd.Flags().StringSliceVar(&opts.columns, "columns", nil,
+		"comma-separated columns to show, in order: "+strings.Join(names, ", "))
+	cmd.Flags().BoolVar(&opts.noHeader, "no-header", false, "omit the table header, e.g. when piping")
+}
+
+// selectColumns resolves the requested column names against known, keeping
+// the requested order. With no names the default columns are used.
+func selectColumns(known []tableColumn, names []string) ([]tableColumn, error) {
+	if len(names) == 0 {
+		var defaults []tableColumn
+		for _, column := range known {
+			if column.Default {
+				defaults = append(defaults, column)
+			}
+		}
+		return defaults, nil
+	}
+
+	selected := make([]tableColumn, 0, len(names))
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		found := false
+		for _, column := range known {
+			if column.Name == name {
+				selected = append(selected, column)
+				found = true
+				break
+			}
+		}
+		if !found {
+			valid := make([]string, len(known))
+			for i, column := range known {
+				valid[i] = column.Name
+			}
+			return nil, fmt.Errorf("unknown column %q: must be one of %s", name, strings.Join(valid, ", "))
+		}
+	}
+	return selected, nil
+}
+
+// orUnknown shows an empty field as "unknown"
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
+
+// writeTable renders rows, each keyed by column name, under the given columns
+func writeTable(out io.Writer, columns []tableColumn, rows []map[string]string, header bool) {
+	w := tabwriter.NewWriter(out, 0, 0, 1, ' ', tabwriter.Debug)
+
+	if header {
+		headers := make([]string, len(columns))
+		rules := make([]string, len(columns))
+		for i, column := range columns {
+			headers[i] = column.Header
+			rules[i] = strings.Repeat("─", utf8.RuneCountInString(column.Header))
+		}
+		fmt.Fprintln(w, strings.Join(headers, "\t"))
+		fmt.Fprintln(w, strings.Join(rules, "\t"))
+	}
+
+	for _, row := range rows {
+		values := make([]string, len(columns))
+		for i, column := range columns {
+			values[i] = row[column.Name]
+		}
+		fmt.Fprintln(w, strings.Join(values, "\t"))
+	}
+	_ = w.Flush()
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/config"
+)
+
+// forEachParallel calls fn for every index below n on at most
+// config concurrency goroutines. It waits for all of them and returns every
+// error, joined in index order so the report doesn't depend on scheduling.
+func forEachParallel(n int, fn func(i int) error) error {
+	workers := min(config.Get().Concurrency, n)
+	if workers < 1 {
+		workers = 1
+	}
+
+	errs := make([]error, n)
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Go(func() {
+			for i := range indexes {
+				errs[i] = fn(i)
+			}
+		})
+	}
+	for i := range n {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/client"
+	"github.com/DylanBlakemore/initflow-cli/internal/config"
+	"github.com/DylanBlakemore/initflow-cli/internal/encoding"
+)
+
+func TestForEachParallelReportsEveryError(t *testing.T) {
+	var running, peak atomic.Int32
+	err := forEachParallel(50, func(i int) error {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			old := peak.Load()
+			if n <= old || peak.CompareAndSwap(old, n) {
+				break
+			}
+		}
+		if i%10 == 3 {
+			return fmt.Errorf("item %d failed", i)
+		}
+		return nil
+	})
+
+	want := "item 3 failed\nitem 13 failed\nitem 23 failed\nitem 33 failed\nitem 43 failed"
+	if err == nil || err.Error() != want {
+		t.Errorf("Expected every failure in index order, got %v", err)
+	}
+	if limit := int32(config.Get().Concurrency); peak.Load() > limit {
+		t.Errorf("Expected at most %d workers, saw %d", limit, peak.Load())
+	}
+	if err := forEachParallel(0, func(int) error { return fmt.Errorf("called") }); err != nil {
+		t.Errorf("Expected nothing to run for no items, got %v", err)
+	}
+}
+
+// sealedFixture encrypts n secrets under key for workspace
+func sealedFixture(tb testing.TB, key []byte, workspace *client.Workspace, n int) []client.Secret {
+	secrets := make([]client.Secret, n)
+	for i := range secrets {
+		name := fmt.Sprintf("KEY_%03d", i)
+		sealed, err := encryptSecret(key, workspace.ID, "", name, []byte("value-"+name))
+		if err != nil {
+			tb.Fatalf("encryptSecret failed: %v", err)
+		}
+		secrets[i] = client.Secret{Key: name, Ciphertext: encoding.Encode(sealed)}
+	}
+	return secrets
+}
+
+func TestDecryptSecretsReportsEveryFailure(t *testing.T) {
+	key := make([]byte, encoding.WorkspaceKeySize)
+	rand.Read(key)
+	workspace := &client.Workspace{ID: 1}
+	secrets := sealedFixture(t, key, workspace, 40)
+
+	values, err := decryptSecrets(key, workspace, "", secrets)
+	if err != nil || len(values) != 40 || values["KEY_017"] != "value-KEY_017" {
+		t.Fatalf("Expected every secret decrypted, got %d values, %v", len(values), err)
+	}
+
+	// Swap two ciphertexts and corrupt a third
+	secrets[5].Ciphertext, secrets[30].Ciphertext = secrets[30].Ciphertext, secrets[5].Ciphertext
+	secrets[12].Ciphertext = "not base64!"
+	_, err = decryptSecrets(key, workspace, "", secrets)
+	if err == nil {
+		t.Fatal("Expected tampered secrets to fail")
+	}
+	lines := strings.Split(err.Error(), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "KEY_005: ") ||
+		!strings.HasPrefix(lines[1], "KEY_012: ") || !strings.HasPrefix(lines[2], "KEY_030: ") {
+		t.Errorf("Expected all three failures in order, got %q", err)
+	}
+}
+
+func BenchmarkDecryptSecrets(b *testing.B) {
+	key := make([]byte, encoding.WorkspaceKeySize)
+	rand.Read(key)
+	workspace := &client.Workspace{ID: 1}
+	secrets := sealedFixture(b, key, workspace, 500)
+	if err := config.InitConfig(); err != nil {
+		b.Fatal(err)
+	}
+
+	for _, workers := range []int{1, 8} {
+		b.Run(fmt.Sprintf("concurrency=%d", workers), func(b *testing.B) {
+			if err := config.Set("concurrency", workers); err != nil {
+				b.Fatal(err)
+			}
+			for b.Loop() {
+				if _, err := decryptSecrets(key, workspace, "", secrets); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
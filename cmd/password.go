@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// PasswordReader supplies a secret in answer to a prompt such as "Password".
+type PasswordReader interface {
+	ReadPassword(prompt string) (string, error)
+}
+
+// terminalPasswordReader prompts on stdout and reads without echo from stdin
+type terminalPasswordReader struct{}
+
+func (terminalPasswordReader) ReadPassword(prompt string) (string, error) {
+	fmt.Fprintf(stdout(), "%s: ", prompt)
+	secret, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(stdout())
+	if err != nil {
+		return "", err
+	}
+	return string(secret), nil
+}
+
+// askPassReader runs an external helper with the prompt as its only argument
+// and takes the first line it prints, like git's and ssh's askpass programs.
+type askPassReader struct {
+	program string
+}
+
+func (r askPassReader) ReadPassword(prompt string) (string, error) {
+	var stdout bytes.Buffer
+	helper := exec.Command(r.program, prompt) // #nosec G204 - the helper is the program the user named
+	helper.Stdout = &stdout
+	helper.Stderr = stderr()
+	if err := helper.Run(); err != nil {
+		return "", fmt.Errorf("ask-pass helper %s: %w", r.program, err)
+	}
+
+	secret, _, _ := strings.Cut(stdout.String(), "\n")
+	return strings.TrimSuffix(secret, "\r"), nil
+}
+
+var (
+	askPassProgram string
+	// passwordReader replaces the terminal prompt when set, e.g. by tests
+	passwordReader PasswordReader
+)
+
+// currentPasswordReader picks the injected reader, then --ask-pass, then the terminal
+func currentPasswordReader() PasswordReader {
+	switch {
+	case passwordReader != nil:
+		return passwordReader
+	case askPassProgram != "":
+		return askPassReader{program: askPassProgram}
+	default:
+		return terminalPasswordReader{}
+	}
+}
+
+// readPassword asks for the account password, rejecting an empty answer
+func readPassword() (string, error) {
+	password, err := currentPasswordReader().ReadPassword("Password")
+	if err != nil {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+	if password == "" {
+		return "", fmt.Errorf("password cannot be empty")
+	}
+	return password, nil
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/client"
+	"github.com/DylanBlakemore/initflow-cli/internal/config"
+)
+
+type stubPasswordReader struct {
+	password string
+	prompts  []string
+}
+
+func (s *stubPasswordReader) ReadPassword(prompt string) (string, error) {
+	s.prompts = append(s.prompts, prompt)
+	return s.password, nil
+}
+
+func usePasswordReader(t *testing.T, reader PasswordReader) {
+	passwordReader = reader
+	t.Cleanup(func() { passwordReader = nil })
+}
+
+func TestRunLoginUsesPasswordReader(t *testing.T) {
+	var received client.LoginRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(client.LoginResponse{Token: "test-token"})
+	}))
+	defer server.Close()
+
+	t.Setenv("HOME", t.TempDir())
+	setupTestEnvironment(t, server.URL)
+
+	stub := &stubPasswordReader{password: "hunter2"}
+	usePasswordReader(t, stub)
+
+	captureStdout(t, func() {
+		require.NoError(t, runLogin(loginCmd, []string{"user@example.com"}))
+	})
+	assert.Equal(t, []string{"Password"}, stub.prompts)
+	assert.Equal(t, "user@example.com", received.Email)
+	assert.Equal(t, "hunter2", received.Password)
+	require.NoError(t, config.Set("default_email", ""))
+}
+
+func TestReadPasswordRejectsEmpty(t *testing.T) {
+	usePasswordReader(t, &stubPasswordReader{})
+
+	_, err := readPassword()
+	assert.EqualError(t, err, "password cannot be empty")
+}
+
+func TestAskPassReader(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("helper is a shell script")
+	}
+
+	helper := filepath.Join(t.TempDir(), "askpass")
+	script := "#!/bin/sh\necho \"s3cret for $1\"\necho ignored\n"
+	require.NoError(t, os.WriteFile(helper, []byte(script), 0o700)) // #nosec G306 - test helper must be executable
+
+	askPassProgram = helper
+	t.Cleanup(func() { askPassProgram = "" })
+
+	password, err := readPassword()
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret for Password", password)
+
+	askPassProgram = filepath.Join(t.TempDir(), "missing")
+	_, err = readPassword()
+	assert.ErrorContains(t, err, "ask-pass helper")
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/spf13/cobra"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/client"
+	"github.com/DylanBlakemore/initflow-cli/internal/config"
+)
+
+var printPin bool
+
+func init() {
+	rootCmd.RunE = runRoot
+	rootCmd.Flags().BoolVar(&printPin, "print-pin", false,
+		"print the SHA-256 pin of the API server's certificate for pinned_cert_sha256, then exit")
+}
+
+// runRoot handles 'initflow --print-pin' and shows help otherwise
+func runRoot(cmd *cobra.Command, args []string) error {
+	if !printPin {
+		return cmd.Help()
+	}
+
+	// Deliberately not pinned, so the pin can be read again after the server's key changes
+	httpClient := &http.Client{Timeout: config.Get().Timeout}
+	return printCertificatePin(httpClient, config.Get().APIBaseURL)
+}
+
+// printCertificatePin connects to url and prints its certificate pin
+func printCertificatePin(httpClient *http.Client, url string) error {
+	pin, err := client.FetchCertificatePin(httpClient, url)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to read the server certificate: %w", err)
+	}
+
+	if structuredOutput() {
+		return writeOutput(map[string]string{"url": url, "pinned_cert_sha256": pin})
+	}
+
+	fmt.Fprintln(stdout(), pin)
+	infof("ℹ️ Verify this pin out of band, then add it to pinned_cert_sha256 in your config to pin %s\n", url)
+	return nil
+}
@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/spf13/cobra"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/config"
+	"github.com/DylanBlakemore/initflow-cli/internal/storage"
+)
+
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Manage named profiles",
+	Long: "Profiles keep separate logins, device keys and workspace keys on one machine, e.g. for a personal " +
+		"and a work account. Pick one for a single command with --profile or INITFLOW_PROFILE, or switch the " +
+		"default with 'profile use'.",
+}
+
+var profileListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List profiles",
+	Long:  "List the profiles on this machine, marking the active one, with the device each is registered as.",
+	Args:  cobra.NoArgs,
+	RunE:  runProfileList,
+}
+
+var profileUseCmd = &cobra.Command{
+	Use:   "use <name>",
+	Short: "Switch to a profile",
+	Long: "Make a profile the one commands use, creating it if it's new. A new profile starts logged out; " +
+		"log in and register this machine again for it. Use 'default' to go back to the original profile.",
+	Args: cobra.ExactArgs(1),
+	RunE: runProfileUse,
+}
+
+// profileColumns are the columns profile list can show
+var profileColumns = []tableColumn{
+	{Name: "current", Header: "Current", Default: true},
+	{Name: "name", Header: "Name", Default: true},
+	{Name: "device", Header: "Device", Default: true},
+	{Name: "device-id", Header: "Device ID"},
+}
+
+var profileListTable tableOptions
+
+func init() {
+	rootCmd.AddCommand(profileCmd)
+	profileCmd.AddCommand(profileListCmd)
+	profileCmd.AddCommand(profileUseCmd)
+
+	addTableFlags(profileListCmd, &profileListTable, profileColumns)
+}
+
+// profileInfo is a profile as shown by profile list
+type profileInfo struct {
+	Name       string `json:"name"`
+	Current    bool   `json:"current"`
+	DeviceID   string `json:"device_id,omitempty"`
+	DeviceName string `json:"device_name,omitempty"`
+}
+
+// knownProfiles returns the default profile, the ones created with profile
+// use and the active one, which may only be set by flag or environment
+func knownProfiles(cfg *config.Config) []string {
+	var names []string
+	for _, name := range append(slices.Clone(cfg.Profiles), cfg.ActiveProfile()) {
+		if name != config.DefaultProfile && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return append([]string{config.DefaultProfile}, names...)
+}
+
+func runProfileList(cmd *cobra.Command, args []string) error {
+	columns, err := selectColumns(profileColumns, profileListTable.columns)
+	if err != nil {
+		return err
+	}
+
+	cfg := config.Get()
+	var profiles []profileInfo
+	for _, name := range knownProfiles(cfg) {
+		profile := profileInfo{Name: name, Current: name == cfg.ActiveProfile()}
+		store := storage.ForProfile(name)
+		if deviceID, err := store.GetDeviceID(); err == nil {
+			profile.DeviceID = deviceID
+			profile.DeviceName, _ = store.GetDeviceName()
+		}
+		profiles = append(profiles, profile)
+	}
+
+	if structuredOutput() {
+		return writeOutput(profiles)
+	}
+
+	rows := make([]map[string]string, len(profiles))
+	for i, profile := range profiles {
+		current := ""
+		if profile.Current {
+			current = "*"
+		}
+
+		device := "not registered"
+		switch {
+		case profile.DeviceName != "":
+			device = profile.DeviceName
+		case profile.DeviceID != "":
+			device = profile.DeviceID
+		}
+
+		rows[i] = map[string]string{
+			"current":   current,
+			"name":      profile.Name,
+			"device":    device,
+			"device-id": profile.DeviceID,
+		}
+	}
+	writeTable(cmd.OutOrStdout(), columns, rows, !profileListTable.noHeader)
+
+	return nil
+}
+
+func runProfileUse(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	if err := config.ValidateProfile(name); err != nil {
+		return fmt.Errorf("❌ %w", err)
+	}
+
+	cfg := config.Get()
+	if name != config.DefaultProfile && !slices.Contains(cfg.Profiles, name) {
+		if err := config.Persist("profiles", append(slices.Clone(cfg.Profiles), name)); err != nil {
+			return fmt.Errorf("❌ Failed to save profile: %w", err)
+		}
+	}
+	if err := config.Persist("profile", name); err != nil {
+		return fmt.Errorf("❌ Failed to switch profile: %w", err)
+	}
+
+	infof("✅ Now using profile %s\n", name)
+	if !storage.ForProfile(name).HasDeviceID() {
+		infoln("💡 Next: Log in with 'initflow auth login' and register this machine with 'initflow device register <name>'")
+	}
+	return nil
+}
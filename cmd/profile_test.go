@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/config"
+	"github.com/DylanBlakemore/initflow-cli/internal/storage"
+)
+
+func TestProfiles(t *testing.T) {
+	t.Setenv("HOME", t.TempDir()) // profile use writes the config file
+	setupTestEnvironment(t, "http://localhost")
+	t.Cleanup(func() {
+		config.Set("profile", "")
+		config.Set("profiles", []string{})
+	})
+
+	defaultStore := storage.New()
+	if err := defaultStore.StoreToken("default-token"); err != nil {
+		t.Fatalf("Failed to store token: %v", err)
+	}
+
+	if err := runProfileUse(profileUseCmd, []string{"Work!"}); err == nil {
+		t.Error("Expected an invalid profile name to be rejected")
+	}
+
+	output := captureStdout(t, func() {
+		if err := runProfileUse(profileUseCmd, []string{"work"}); err != nil {
+			t.Fatalf("runProfileUse failed: %v", err)
+		}
+	})
+	if !strings.Contains(output, "Now using profile work") || !strings.Contains(output, "auth login") {
+		t.Errorf("Expected the switch to suggest logging in, got %q", output)
+	}
+
+	workStore := storage.New()
+	if workStore.HasToken() {
+		t.Error("Expected the work profile not to see the default profile's token")
+	}
+	if !storage.ForProfile(config.DefaultProfile).HasToken() {
+		t.Error("Expected the default profile to keep its token")
+	}
+
+	output = captureStdout(t, func() {
+		if err := runProfileList(profileListCmd, nil); err != nil {
+			t.Fatalf("runProfileList failed: %v", err)
+		}
+	})
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Expected a header and two profiles, got %q", output)
+	}
+	if !strings.Contains(lines[2], "default") || !strings.Contains(lines[2], "test-device-123") {
+		t.Errorf("Expected the default profile with its device, got %q", lines[2])
+	}
+	if !strings.HasPrefix(lines[3], "*") || !strings.Contains(lines[3], "work") {
+		t.Errorf("Expected the work profile to be current, got %q", lines[3])
+	}
+
+	if err := runProfileUse(profileUseCmd, []string{"default"}); err != nil {
+		t.Fatalf("runProfileUse failed: %v", err)
+	}
+	if token, _ := storage.New().GetToken(); token != "default-token" {
+		t.Errorf("Expected switching back to see the default token, got %q", token)
+	}
+	if profiles := config.Get().Profiles; len(profiles) != 1 || profiles[0] != "work" {
+		t.Errorf("Expected the work profile to be remembered, got %v", profiles)
+	}
+}
@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+)
+
+const progressBarWidth = 30
+
+// progress reports how far a bulk operation has got. It draws a bar on stderr,
+// so piped stdout stays clean, and stays silent under --quiet or when stderr
+// isn't a terminal. Increment is safe to call from concurrent workers.
+type progress struct {
+	mu    sync.Mutex
+	w     io.Writer // nil when disabled
+	label string
+	total int
+	done  int
+}
+
+func newProgress(label string, total int) *progress {
+	return newProgressTo(stderr(), label, total)
+}
+
+// newProgressTo draws on w if it is a terminal
+func newProgressTo(w io.Writer, label string, total int) *progress {
+	p := &progress{label: label, total: total}
+	if total > 0 {
+		p.w = terminalWriter(w)
+	}
+	return p
+}
+
+// isTerminal reports whether w writes to an interactive terminal
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(interface{ Fd() uintptr })
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
+// terminalWriter returns w if it is a terminal and --quiet isn't set, else nil
+func terminalWriter(w io.Writer) io.Writer {
+	if !quiet && isTerminal(w) {
+		return w
+	}
+	return nil
+}
+
+// Increment marks one more item as finished and redraws the bar
+func (p *progress) Increment() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.done++
+	p.render()
+}
+
+// Clear erases the bar so a message can be printed in its place; the next
+// Increment draws it again.
+func (p *progress) Clear() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.w != nil {
+		fmt.Fprint(p.w, "\r\033[K")
+	}
+}
+
+// Finish erases the bar once the operation is over
+func (p *progress) Finish() {
+	p.Clear()
+}
+
+func (p *progress) render() {
+	if p.w == nil {
+		return
+	}
+
+	done := min(p.done, p.total)
+	filled := done * progressBarWidth / p.total
+	fmt.Fprintf(p.w, "\r\033[K%s [%s%s] %d/%d %d%%",
+		p.label,
+		strings.Repeat("█", filled),
+		strings.Repeat("░", progressBarWidth-filled),
+		done, p.total, done*100/p.total)
+}
+
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+const spinnerInterval = 100 * time.Millisecond
+
+// spinner shows that the CLI is waiting on something with no measurable
+// progress. Like progress it draws on stderr and only at a terminal.
+type spinner struct {
+	w     io.Writer // nil when disabled
+	label string
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+func newSpinner(label string) *spinner {
+	return newSpinnerTo(stderr(), label)
+}
+
+// newSpinnerTo starts spinning on w if it is a terminal
+func newSpinnerTo(w io.Writer, label string) *spinner {
+	s := &spinner{w: terminalWriter(w), label: label, stop: make(chan struct{}), done: make(chan struct{})}
+	if s.w == nil {
+		close(s.done)
+		return s
+	}
+
+	go s.run()
+	return s
+}
+
+func (s *spinner) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(spinnerInterval)
+	defer ticker.Stop()
+
+	for frame := 0; ; frame++ {
+		fmt.Fprintf(s.w, "\r\033[K%s %s", spinnerFrames[frame%len(spinnerFrames)], s.label)
+		select {
+		case <-s.stop:
+			fmt.Fprint(s.w, "\r\033[K")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Stop erases the spinner. It must be called exactly once.
+func (s *spinner) Stop() {
+	close(s.stop)
+	<-s.done
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProgressSilentWithoutTerminal(t *testing.T) {
+	var buf bytes.Buffer
+	bar := newProgressTo(&buf, "Copying", 3)
+	bar.Increment()
+	bar.Clear()
+	bar.Finish()
+	if buf.Len() != 0 {
+		t.Errorf("Expected no output for a non-terminal writer, got %q", buf.String())
+	}
+
+	file, err := os.Create(filepath.Join(t.TempDir(), "stderr"))
+	if err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	defer file.Close()
+
+	bar = newProgressTo(file, "Copying", 3)
+	bar.Increment()
+	bar.Finish()
+	if info, err := file.Stat(); err != nil || info.Size() != 0 {
+		t.Errorf("Expected nothing written to a regular file, got size %d (%v)", info.Size(), err)
+	}
+}
+
+func TestProgressRender(t *testing.T) {
+	var buf bytes.Buffer
+	bar := &progress{w: &buf, label: "Copying", total: 4}
+	bar.Increment()
+
+	want := "\r\033[KCopying [" + "███████" + "░░░░░░░░░░░░░░░░░░░░░░░" + "] 1/4 25%"
+	if buf.String() != want {
+		t.Errorf("Expected %q, got %q", want, buf.String())
+	}
+}
+
+func TestSpinnerSilentWithoutTerminal(t *testing.T) {
+	var buf bytes.Buffer
+	spin := newSpinnerTo(&buf, "Waiting")
+	spin.Stop()
+	if buf.Len() != 0 {
+		t.Errorf("Expected no output for a non-terminal writer, got %q", buf.String())
+	}
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// askYesNo prints question with a [Y/n] or [y/N] hint and reads the answer.
+// An empty answer picks the default.
+func askYesNo(in *bufio.Reader, question string, defaultYes bool) (bool, error) {
+	hint := "[y/N]"
+	if defaultYes {
+		hint = "[Y/n]"
+	}
+	fmt.Fprintf(stdout(), "%s %s: ", question, hint)
+
+	answer, err := in.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, fmt.Errorf("failed to read confirmation: %w", err)
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "":
+		return defaultYes, nil
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/curve25519"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/client"
+	"github.com/DylanBlakemore/initflow-cli/internal/encoding"
+	"github.com/DylanBlakemore/initflow-cli/internal/storage"
+)
+
+const recoveryKeyEnvVar = "INITFLOW_RECOVERY_KEY"
+
+const recoveryWarning = `⚠️  Store this recovery key offline, e.g. in a password manager or on paper. It is shown
+   only once and is not kept on this machine. Anyone holding it can read this
+   workspace's secrets; 'initflow workspace recover' uses it if every device is lost.`
+
+var workspaceRecoverCmd = &cobra.Command{
+	Use:   "recover <workspace-slug>",
+	Short: "Regain a workspace key with its recovery key",
+	Long: `Unwrap the workspace key with the recovery key printed by 'initflow workspace init --with-recovery'
+and give this device its own copy. Use this when no device holding the key is left.
+Set INITFLOW_RECOVERY_KEY to avoid the prompt.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runWorkspaceRecover,
+}
+
+var initWithRecovery bool
+
+func init() {
+	workspaceCmd.AddCommand(workspaceRecoverCmd)
+
+	workspaceInitCmd.Flags().BoolVar(&initWithRecovery, "with-recovery", false,
+		"also print a one-time recovery key that can restore the workspace key if every device is lost")
+}
+
+// generateRecoveryKey returns a fresh X25519 recovery keypair
+func generateRecoveryKey() ([]byte, []byte, error) {
+	privateKey := make([]byte, encoding.X25519PrivateKeySize)
+	if _, err := rand.Read(privateKey); err != nil {
+		return nil, nil, fmt.Errorf("failed to generate recovery key: %w", err)
+	}
+
+	publicKey, err := curve25519.X25519(privateKey, curve25519.Basepoint)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to derive recovery public key: %w", err)
+	}
+
+	return privateKey, publicKey, nil
+}
+
+// setUpRecovery wraps the workspace key to a new recovery key, uploads the
+// wrapped copy and returns the encoded recovery secret for the user to keep.
+func setUpRecovery(c *client.Client, workspace *client.Workspace, workspaceKey []byte) (string, error) {
+	privateKey, publicKey, err := generateRecoveryKey()
+	if err != nil {
+		return "", err
+	}
+
+	wrapped, err := wrapWorkspaceKeyFor(workspaceKey, workspace.ID, publicKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to wrap workspace key: %w", err)
+	}
+
+	if err := c.SetRecoveryKey(workspace.ID, wrapped); err != nil {
+		return "", err
+	}
+
+	return encoding.EncodeKey(privateKey), nil
+}
+
+// recoverWorkspaceKey unwraps a recovery-wrapped workspace key with the
+// encoded recovery secret
+func recoverWorkspaceKey(wrapped []byte, workspaceID int, secret string) ([]byte, error) {
+	privateKey, err := encoding.DecodeKey(strings.TrimSpace(secret))
+	if err != nil || len(privateKey) != encoding.X25519PrivateKeySize {
+		return nil, fmt.Errorf("not a valid recovery key")
+	}
+
+	workspaceKey, err := unwrapWorkspaceKeyWith(wrapped, workspaceID, privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("recovery key does not match this workspace")
+	}
+	return workspaceKey, nil
+}
+
+// readRecoveryKey takes the recovery secret from the environment or a hidden prompt
+func readRecoveryKey() (string, error) {
+	if secret := os.Getenv(recoveryKeyEnvVar); secret != "" {
+		return secret, nil
+	}
+
+	secret, err := currentPasswordReader().ReadPassword("Recovery key")
+	if err != nil {
+		return "", fmt.Errorf("failed to read recovery key: %w", err)
+	}
+	if strings.TrimSpace(secret) == "" {
+		return "", fmt.Errorf("recovery key cannot be empty")
+	}
+	return secret, nil
+}
+
+// printRecoveryKey shows the recovery secret once, with the warning about keeping it safe
+func printRecoveryKey(secret string) {
+	infoln()
+	fmt.Fprintf(stdout(), "Recovery key: %s\n", secret)
+	infoln(recoveryWarning)
+}
+
+// addRecovery sets up recovery for a workspace whose key this device already holds
+func addRecovery(c *client.Client, store *storage.Storage, workspace *client.Workspace) error {
+	workspaceKey, err := currentWorkspaceKey(c, store, workspace)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to read workspace key: %w", err)
+	}
+
+	infoln("🛟 Setting up a recovery key...")
+	secret, err := setUpRecovery(c, workspace, workspaceKey)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to set up recovery: %w", err)
+	}
+
+	printRecoveryKey(secret)
+	return nil
+}
+
+func runWorkspaceRecover(cmd *cobra.Command, args []string) error {
+	workspaceSlug := args[0]
+
+	store := storage.New()
+	if err := store.EnsureDeviceReady(); err != nil {
+		return fmt.Errorf("❌ %w", err)
+	}
+
+	if store.HasWorkspaceKey(workspaceSlug) {
+		infoln("ℹ️ This device already has the workspace key; nothing to recover")
+		return nil
+	}
+
+	secret, err := readRecoveryKey()
+	if err != nil {
+		return err
+	}
+
+	c := newClient()
+	workspace, err := c.GetWorkspaceBySlug(workspaceSlug)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to get workspace info: %w", err)
+	}
+
+	infof("🛟 Recovering workspace key for \"%s\"...\n", workspaceSlug)
+	wrapped, err := c.GetRecoveryKey(workspace.ID)
+	var apiErr *client.APIError
+	if errors.As(err, &apiErr) && apiErr.Status == http.StatusNotFound {
+		return fmt.Errorf("❌ %s has no recovery key. Set one up with 'initflow workspace init %s --with-recovery' "+
+			"from a device that has the key: %w", workspaceSlug, workspaceSlug, err)
+	}
+	if err != nil {
+		return fmt.Errorf("❌ Failed to fetch recovery key: %w", err)
+	}
+
+	workspaceKey, err := recoverWorkspaceKey(wrapped, workspace.ID, secret)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to recover workspace key: %w", err)
+	}
+
+	infoln("🔒 Encrypting with your device's X25519 key...")
+	deviceWrapped, err := wrapWorkspaceKey(workspaceKey, workspace.ID, store)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to encrypt workspace key: %w", err)
+	}
+
+	infoln("📡 Uploading encrypted key to server...")
+	if err := c.SetDeviceWorkspaceKey(workspace.ID, deviceWrapped); err != nil {
+		return fmt.Errorf("❌ Failed to upload workspace key: %w", err)
+	}
+
+	if err := store.StoreWorkspaceKey(workspaceSlug, workspaceKey); err != nil {
+		return fmt.Errorf("❌ Failed to store workspace key locally: %w", err)
+	}
+
+	infof("✅ Recovered the workspace key for %s on this device\n", workspaceSlug)
+	return nil
+}
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/client"
+	"github.com/DylanBlakemore/initflow-cli/internal/encoding"
+	"github.com/DylanBlakemore/initflow-cli/internal/storage"
+)
+
+func TestRecoveryKeyRoundTrip(t *testing.T) {
+	workspaceKey := make([]byte, encoding.WorkspaceKeySize)
+	rand.Read(workspaceKey)
+
+	privateKey, publicKey, err := generateRecoveryKey()
+	if err != nil {
+		t.Fatalf("generateRecoveryKey failed: %v", err)
+	}
+	wrapped, err := wrapWorkspaceKeyFor(workspaceKey, 7, publicKey)
+	if err != nil {
+		t.Fatalf("wrapWorkspaceKeyFor failed: %v", err)
+	}
+
+	recovered, err := recoverWorkspaceKey(wrapped, 7, encoding.EncodeKey(privateKey)+"\n")
+	if err != nil {
+		t.Fatalf("recoverWorkspaceKey failed: %v", err)
+	}
+	if !bytes.Equal(recovered, workspaceKey) {
+		t.Error("Recovered key does not match the workspace key")
+	}
+
+	if _, err := recoverWorkspaceKey(wrapped, 8, encoding.EncodeKey(privateKey)); err == nil {
+		t.Error("Expected recovery into another workspace to fail")
+	}
+
+	otherKey, _, _ := generateRecoveryKey()
+	if _, err := recoverWorkspaceKey(wrapped, 7, encoding.EncodeKey(otherKey)); err == nil {
+		t.Error("Expected a different recovery key to fail")
+	}
+
+	if _, err := recoverWorkspaceKey(wrapped, 7, "not-a-key"); err == nil {
+		t.Error("Expected a malformed recovery key to fail")
+	}
+}
+
+// recoveryServer holds one workspace and remembers the keys uploaded for it
+type recoveryServer struct {
+	initialized bool
+	recoveryKey string
+	deviceKey   string
+}
+
+func (s *recoveryServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case r.Method == "GET" && r.URL.Path == "/api/v1/workspaces":
+		json.NewEncoder(w).Encode(client.ListWorkspacesResponse{Workspaces: []client.Workspace{
+			{ID: 1, Name: "My Project", Slug: "my-project", Role: "Owner", KeyInitialized: s.initialized},
+		}})
+	case r.Method == "POST" && r.URL.Path == "/api/v1/workspaces/1/initialize":
+		var req client.InitializeWorkspaceKeyRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		s.initialized, s.deviceKey = true, req.WrappedWorkspaceKey
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == "PUT" && r.URL.Path == "/api/v1/workspaces/1/recovery-key":
+		var req client.RecoveryKeyRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		s.recoveryKey = req.WrappedRecoveryKey
+	case r.Method == "GET" && r.URL.Path == "/api/v1/workspaces/1/recovery-key":
+		json.NewEncoder(w).Encode(client.RecoveryKeyResponse{WrappedRecoveryKey: s.recoveryKey})
+	case r.Method == "PUT" && r.URL.Path == "/api/v1/workspaces/1/device-key":
+		var req client.InitializeWorkspaceKeyRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		s.deviceKey = req.WrappedWorkspaceKey
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestWorkspaceRecoverOnNewDevice(t *testing.T) {
+	state := &recoveryServer{}
+	server := httptest.NewServer(state)
+	defer server.Close()
+
+	setupTestEnvironment(t, server.URL)
+	initWithRecovery = true
+	t.Cleanup(func() { initWithRecovery = false })
+
+	var err error
+	out := captureStdout(t, func() {
+		err = runWorkspaceInit(workspaceInitCmd, []string{"my-project"})
+	})
+	if err != nil {
+		t.Fatalf("runWorkspaceInit failed: %v", err)
+	}
+	match := regexp.MustCompile(`Recovery key: (\S+)`).FindStringSubmatch(out)
+	if match == nil || state.recoveryKey == "" {
+		t.Fatalf("Expected a recovery key to be printed and uploaded, got %q", out)
+	}
+
+	store := storage.New()
+	workspaceKey, err := store.GetWorkspaceKey("my-project")
+	if err != nil {
+		t.Fatalf("Expected the workspace key to be cached: %v", err)
+	}
+
+	// Lose the device: a new encryption key and no cached workspace key
+	newDeviceKey := make([]byte, encoding.X25519PrivateKeySize)
+	rand.Read(newDeviceKey)
+	if err := store.StoreEncryptionPrivateKey(newDeviceKey); err != nil {
+		t.Fatalf("Failed to replace encryption key: %v", err)
+	}
+	if err := store.DeleteWorkspaceKey("my-project"); err != nil {
+		t.Fatalf("Failed to drop workspace key: %v", err)
+	}
+
+	t.Setenv(recoveryKeyEnvVar, match[1])
+	if err := runWorkspaceRecover(workspaceRecoverCmd, []string{"my-project"}); err != nil {
+		t.Fatalf("runWorkspaceRecover failed: %v", err)
+	}
+
+	recovered, err := store.GetWorkspaceKey("my-project")
+	if err != nil || !bytes.Equal(recovered, workspaceKey) {
+		t.Fatalf("Expected the original workspace key to be restored, got %v", err)
+	}
+
+	wrapped, err := encoding.Decode(state.deviceKey)
+	if err != nil {
+		t.Fatalf("Failed to decode uploaded device key: %v", err)
+	}
+	unwrapped, err := unwrapWorkspaceKey(wrapped, 1, store)
+	if err != nil || !bytes.Equal(unwrapped, workspaceKey) {
+		t.Errorf("Expected the uploaded key to be wrapped to the new device, got %v", err)
+	}
+}
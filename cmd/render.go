@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"text/template"
+
+	"github.com/spf13/cobra"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/fsutil"
+)
+
+var renderCmd = &cobra.Command{
+	Use:   "render -w <workspace> <template>",
+	Short: "Render a template with workspace secrets",
+	Long: `Render a Go template, replacing {{ secret "DB_PASSWORD" }}, {{ .DB_PASSWORD }} or the
+envsubst-style ${DB_PASSWORD} with the decrypted value of the secret. Keys in folders are
+written as they are stored, e.g. {{ secret "backend/db/PASSWORD" }} or ${backend/db/PASSWORD}.
+A template naming a secret the workspace doesn't have fails without writing anything, unless
+--keep-missing is set, which leaves such references as they are. The result goes to stdout,
+or with --out to a file only you can read. Use - to read the template from stdin. Also
+available as 'initflow secrets template'.`,
+	Example: "  initflow render -w api nginx.conf.tmpl --out /etc/nginx/conf.d/api.conf\n" +
+		"  initflow secrets template -w api config.yml.tmpl --keep-missing --out config.yml",
+	Args: cobra.ExactArgs(1),
+	RunE: runRender,
+}
+
+var secretsTemplateCmd = &cobra.Command{
+	Use:   "template <template>",
+	Short: "Render a template with workspace secrets",
+	Long:  "The same as 'initflow render', for the --workspace and --env of the secrets commands.",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runSecretsTemplate,
+}
+
+var (
+	renderWorkspace   string
+	renderEnv         string
+	renderOut         string
+	renderKeepMissing bool
+)
+
+// envsubstPattern matches ${KEY} references, KEY being a secret key
+var envsubstPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*(?:/[A-Za-z_][A-Za-z0-9_]*)*)\}`)
+
+// fieldPattern matches {{ .KEY }} references, as written without a pipeline
+var fieldPattern = regexp.MustCompile(`\{\{-?\s*\.([A-Za-z_][A-Za-z0-9_]*)\s*-?\}\}`)
+
+func init() {
+	rootCmd.AddCommand(renderCmd)
+	secretsCmd.AddCommand(secretsTemplateCmd)
+
+	renderCmd.Flags().StringVarP(&renderWorkspace, "workspace", "w", "", "slug of the workspace whose secrets to render")
+	renderCmd.Flags().StringVarP(&renderEnv, "env", "e", "", "environment whose secrets to render, e.g. prod (default: the workspace default)")
+	for _, c := range []*cobra.Command{renderCmd, secretsTemplateCmd} {
+		c.Flags().StringVar(&renderOut, "out", "", "file to write the result to with 0600 permissions (default: stdout)")
+		c.Flags().BoolVar(&renderKeepMissing, "keep-missing", false, "leave references to missing secrets as they are")
+	}
+	_ = renderCmd.MarkFlagRequired("workspace")
+}
+
+// readTemplate reads the template at path, or stdin for -
+func readTemplate(path string) (string, []byte, error) {
+	if path == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		return "stdin", data, err
+	}
+	data, err := os.ReadFile(path) // #nosec G304 - path is the template the user asked to render
+	return filepath.Base(path), data, err
+}
+
+// renderTemplate executes text with values as its data and a secret
+// function looking them up. ${KEY} references become secret calls before
+// parsing, so values are never parsed as template text. Missing secrets and
+// map keys are errors rather than empty output, unless keepMissing is set,
+// when the reference is written back as it was.
+func renderTemplate(name, text string, values map[string]string, location string, keepMissing bool) ([]byte, error) {
+	lookup := func(key, reference string) (string, error) {
+		value, ok := values[key]
+		switch {
+		case ok:
+			return value, nil
+		case keepMissing:
+			return reference, nil
+		}
+		return "", fmt.Errorf("secret %s not found in %s", key, location)
+	}
+	funcs := template.FuncMap{
+		"secret": func(key string) (string, error) {
+			return lookup(key, fmt.Sprintf("{{ secret %q }}", key))
+		},
+		"envsubst": lookup,
+	}
+
+	text = envsubstPattern.ReplaceAllStringFunc(text, func(reference string) string {
+		key := envsubstPattern.FindStringSubmatch(reference)[1]
+		return fmt.Sprintf("{{ envsubst %q %q }}", key, reference)
+	})
+	if keepMissing {
+		text = fieldPattern.ReplaceAllStringFunc(text, func(reference string) string {
+			if _, ok := values[fieldPattern.FindStringSubmatch(reference)[1]]; ok {
+				return reference
+			}
+			return fmt.Sprintf("{{ %q }}", reference)
+		})
+	}
+
+	tmpl, err := template.New(name).Funcs(funcs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, values); err != nil {
+		return nil, err
+	}
+	return rendered.Bytes(), nil
+}
+
+func runRender(cmd *cobra.Command, args []string) error {
+	return renderSecretsTemplate(cmd, args[0], renderWorkspace, renderEnv)
+}
+
+func runSecretsTemplate(cmd *cobra.Command, args []string) error {
+	return renderSecretsTemplate(cmd, args[0], secretsWorkspace, secretsEnv)
+}
+
+// renderSecretsTemplate renders the template at path with the secrets of a
+// workspace environment, to stdout or --out
+func renderSecretsTemplate(cmd *cobra.Command, path, slug, env string) error {
+	name, text, err := readTemplate(path)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to read template: %w", err)
+	}
+
+	workspace, secrets, err := loadSecrets(slug, env, "")
+	if err != nil {
+		return err
+	}
+
+	rendered, err := renderTemplate(name, string(text), secrets, secretsLocation(workspace.Slug, env), renderKeepMissing)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to render %s: %w", path, err)
+	}
+
+	if renderOut == "" {
+		_, err := cmd.OutOrStdout().Write(rendered)
+		return err
+	}
+
+	if err := fsutil.WriteFile(renderOut, rendered, fsutil.PrivateFilePermissions); err != nil {
+		return fmt.Errorf("❌ Failed to write %s: %w", renderOut, err)
+	}
+	infof("✅ Rendered %s to %s\n", path, renderOut)
+	return nil
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	values := map[string]string{"DB_PASSWORD": "s3cret", "backend/db/HOST": "db.internal"}
+
+	rendered, err := renderTemplate("app.conf", `host={{ secret "backend/db/HOST" }} password={{ secret "DB_PASSWORD" }}`,
+		values, "my-project", false)
+	if err != nil {
+		t.Fatalf("renderTemplate failed: %v", err)
+	}
+	if string(rendered) != "host=db.internal password=s3cret" {
+		t.Errorf("Unexpected rendering %q", rendered)
+	}
+
+	_, err = renderTemplate("app.conf", `{{ secret "MISSING" }}`, values, "my-project", false)
+	if err == nil || !strings.Contains(err.Error(), "secret MISSING not found in my-project") {
+		t.Errorf("Expected a missing secret to fail, got %v", err)
+	}
+
+	_, err = renderTemplate("app.conf", `{{ secret "DB_PASSWORD" `, values, "my-project", false)
+	if err == nil || !strings.Contains(err.Error(), "app.conf:1") {
+		t.Errorf("Expected a parse error naming the template line, got %v", err)
+	}
+}
+
+func TestRenderTemplateReferences(t *testing.T) {
+	values := map[string]string{"DB_PASSWORD": "s3cret", "backend/db/HOST": "db.internal", "RAW": "{{ .DB_PASSWORD }} ${DB_PASSWORD}"}
+
+	rendered, err := renderTemplate("app.conf", "host=${backend/db/HOST} password={{ .DB_PASSWORD }} raw=${RAW} cost=$5",
+		values, "my-project", false)
+	if err != nil {
+		t.Fatalf("renderTemplate failed: %v", err)
+	}
+	if string(rendered) != "host=db.internal password=s3cret raw={{ .DB_PASSWORD }} ${DB_PASSWORD} cost=$5" {
+		t.Errorf("Expected every reference substituted once, got %q", rendered)
+	}
+
+	for _, text := range []string{"${MISSING}", "{{ .MISSING }}"} {
+		_, err := renderTemplate("app.conf", text, values, "my-project", false)
+		if err == nil || !strings.Contains(err.Error(), "MISSING") {
+			t.Errorf("Expected %s to fail, got %v", text, err)
+		}
+	}
+
+	text := `a=${MISSING} b={{ .MISSING }} c={{ secret "MISSING" }} d=${DB_PASSWORD}`
+	rendered, err = renderTemplate("app.conf", text, values, "my-project", true)
+	if err != nil {
+		t.Fatalf("renderTemplate with keepMissing failed: %v", err)
+	}
+	if string(rendered) != `a=${MISSING} b={{ .MISSING }} c={{ secret "MISSING" }} d=s3cret` {
+		t.Errorf("Expected missing references left as they are, got %q", rendered)
+	}
+}
+
+func TestRenderWritesPrivateFile(t *testing.T) {
+	setupSecretsTest(t)
+	renderWorkspace = "my-project"
+	t.Cleanup(func() {
+		renderWorkspace, renderOut = "", ""
+		renderKeepMissing = false
+	})
+
+	if err := runSecretsAdd(secretsAddCmd, []string{"DB_PASSWORD=s3cret"}); err != nil {
+		t.Fatalf("runSecretsAdd failed: %v", err)
+	}
+
+	dir := t.TempDir()
+	tmpl := filepath.Join(dir, "app.conf.tmpl")
+	if err := os.WriteFile(tmpl, []byte("password = {{ secret \"DB_PASSWORD\" }}\n"), 0644); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+
+	out := captureStdout(t, func() {
+		if err := runRender(renderCmd, []string{tmpl}); err != nil {
+			t.Fatalf("runRender failed: %v", err)
+		}
+	})
+	if out != "password = s3cret\n" {
+		t.Errorf("Expected the rendered template on stdout, got %q", out)
+	}
+
+	renderOut = filepath.Join(dir, "app.conf")
+	captureStdout(t, func() {
+		if err := runRender(renderCmd, []string{tmpl}); err != nil {
+			t.Fatalf("runRender failed: %v", err)
+		}
+	})
+	info, err := os.Stat(renderOut)
+	if err != nil {
+		t.Fatalf("Expected %s to be written: %v", renderOut, err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected 0600 permissions, got %v", info.Mode().Perm())
+	}
+
+	// secrets template renders the same, with the secrets commands' flags
+	if err := os.WriteFile(tmpl, []byte("password = ${DB_PASSWORD}\nport = ${PORT}\n"), 0644); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+	if err := runSecretsTemplate(secretsTemplateCmd, []string{tmpl}); err == nil || !strings.Contains(err.Error(), "secret PORT not found") {
+		t.Errorf("Expected a missing secret to fail, got %v", err)
+	}
+	renderKeepMissing = true
+	captureStdout(t, func() {
+		if err := runSecretsTemplate(secretsTemplateCmd, []string{tmpl}); err != nil {
+			t.Fatalf("runSecretsTemplate failed: %v", err)
+		}
+	})
+	data, err := os.ReadFile(renderOut)
+	if err != nil || string(data) != "password = s3cret\nport = ${PORT}\n" {
+		t.Errorf("Expected the missing reference kept, got %q, %v", data, err)
+	}
+}
@@ -3,23 +3,42 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 
+	"github.com/DylanBlakemore/initflow-cli/internal/client"
 	"github.com/DylanBlakemore/initflow-cli/internal/config"
+	"github.com/DylanBlakemore/initflow-cli/internal/logging"
+	"github.com/DylanBlakemore/initflow-cli/internal/storage"
 )
 
 var (
 	cfgFile     string
 	apiURL      string
 	serviceName string
+	profileName string
+	timeout     time.Duration
+	retries     int
+	logFile     string
+
+	commandStarted time.Time
 )
 
 var rootCmd = &cobra.Command{
 	Use:   "initflow",
 	Short: "InitFlow CLI",
 	Long:  `InitFlow CLI — secure secrets, onboarding, and policy tooling.`,
+	// Execute reports errors itself so --json-errors can control the format
+	SilenceErrors: true,
+	SilenceUsage:  true,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := validateOutputFormat(outputFormat); err != nil {
+			return err
+		}
+
+		config.SetFile(cfgFile)
 		if err := config.InitConfig(); err != nil {
 			return fmt.Errorf("failed to initialize config: %w", err)
 		}
@@ -36,20 +55,137 @@ var rootCmd = &cobra.Command{
 			}
 		}
 
-		return nil
+		if profileName != "" {
+			if err := config.Set("profile", profileName); err != nil {
+				return fmt.Errorf("failed to set profile: %w", err)
+			}
+		}
+
+		if cmd.Flags().Changed("timeout") {
+			if err := config.Set("timeout", timeout); err != nil {
+				return fmt.Errorf("failed to set timeout: %w", err)
+			}
+		}
+
+		if cmd.Flags().Changed("retries") {
+			if err := config.Set("retries", retries); err != nil {
+				return fmt.Errorf("failed to set retries: %w", err)
+			}
+		}
+
+		if logFile != "" {
+			if err := config.Set("log_file", logFile); err != nil {
+				return fmt.Errorf("failed to set log file: %w", err)
+			}
+		}
+
+		if err := config.Get().Validate(); err != nil {
+			return err
+		}
+
+		if err := applyConfigDefaults(cmd); err != nil {
+			return err
+		}
+
+		return startLogging(cmd)
 	},
 }
 
 func init() {
-	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.initflow/config.yaml)")
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file, .yaml, .yml or .json (default is $HOME/.initflow/config.yaml)")
 	rootCmd.PersistentFlags().StringVar(&apiURL, "api-url", "", "API base URL (default: https://api.initflow.com)")
 	rootCmd.PersistentFlags().StringVar(&serviceName, "service-name", "initflow-cli",
 		"keyring service name for credential storage")
+	rootCmd.PersistentFlags().StringVar(&profileName, "profile", "",
+		"profile whose login and keys to use (or set INITFLOW_PROFILE; see 'initflow profile')")
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", outputTable, "output format: table, json or yaml")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false,
+		"suppress informational output, printing only errors and requested data")
+	rootCmd.PersistentFlags().DurationVar(&timeout, "timeout", 0,
+		"HTTP request timeout, e.g. 45s (default: 30s, overrides config and env)")
+	rootCmd.PersistentFlags().IntVar(&retries, "retries", 0,
+		"retries for idempotent requests on network or server errors (default: 2)")
+	rootCmd.PersistentFlags().StringVar(&logFile, "log-file", "",
+		"append a redacted JSON log of commands and API calls to this file")
+	rootCmd.PersistentFlags().BoolVar(&jsonErrors, "json-errors", false,
+		"report errors as JSON on stderr (or set INITFLOW_JSON_ERRORS)")
+	rootCmd.PersistentFlags().StringVar(&askPassProgram, "ask-pass", "",
+		"program that prints the password when run with the prompt as its argument, instead of prompting")
+}
+
+// applyConfigDefaults fills in --output and --workspace from the config when
+// they weren't given on the command line
+func applyConfigDefaults(cmd *cobra.Command) error {
+	cfg := config.Get()
+
+	if cfg.Output != "" && !cmd.Flags().Changed("output") {
+		outputFormat = cfg.Output
+	}
+
+	if flag := cmd.Flags().Lookup("workspace"); flag != nil && !flag.Changed && cfg.DefaultWorkspace != "" {
+		if err := cmd.Flags().Set("workspace", cfg.DefaultWorkspace); err != nil {
+			return fmt.Errorf("failed to apply default_workspace: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// newClient returns an API client for the current command. At a terminal it
+// waits out rate limits; scripts get the rate limit error and its exit code
+// right away. With INITFLOW_TOKEN set it authenticates with that token;
+// otherwise an expired registration token is renewed with the stored refresh
+// token and the new tokens are stored.
+func newClient(opts ...client.Option) *client.Client {
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		opts = append(opts, client.WithRateLimitWait())
+	}
+
+	var c *client.Client
+	store := storage.New()
+	if token := scopedToken(); token != "" {
+		opts = append(opts, client.WithScopedToken(token))
+	} else if refreshToken, err := store.GetRefreshToken(); err == nil {
+		opts = append(opts, client.WithTokenRefresh(refreshToken, func(loginResp *client.LoginResponse) error {
+			return storeLogin(store, c, loginResp)
+		}))
+	}
+
+	c = client.New(opts...)
+	return c
+}
+
+// startLogging opens the configured log file and records the command being run
+func startLogging(cmd *cobra.Command) error {
+	path := config.Get().LogFile
+	if path == "" {
+		return nil
+	}
+
+	if err := logging.Open(path, logging.DefaultMaxSize); err != nil {
+		return err
+	}
+
+	commandStarted = time.Now()
+	logging.Logger().Info("command started", "command", cmd.CommandPath(), "version", version)
+	return nil
+}
+
+// finishLogging records how the command ended and closes the log file
+func finishLogging(err error) {
+	attrs := []any{"duration_ms", time.Since(commandStarted).Milliseconds()}
+	if err != nil {
+		logging.Logger().Error("command failed", append(attrs, "error", err.Error())...)
+	} else {
+		logging.Logger().Info("command finished", attrs...)
+	}
+	_ = logging.Close()
 }
 
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+	err := rootCmd.Execute()
+	finishLogging(err)
+	if err != nil {
+		os.Exit(handleError(rootCmd.ErrOrStderr(), err))
 	}
 }
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/client"
+	"github.com/DylanBlakemore/initflow-cli/internal/config"
+)
+
+// resetRequestFlags clears --timeout, --retries and their viper overrides so
+// later tests see defaults
+func resetRequestFlags(t *testing.T) {
+	t.Helper()
+
+	t.Cleanup(func() {
+		for _, name := range []string{"timeout", "retries"} {
+			flag := rootCmd.PersistentFlags().Lookup(name)
+			_ = flag.Value.Set(flag.DefValue)
+			flag.Changed = false
+		}
+		rootCmd.SetArgs(nil)
+		viper.Reset()
+	})
+}
+
+func setupRequestConfig(t *testing.T) {
+	t.Helper()
+	viper.Reset()
+
+	home := t.TempDir()
+	configDir := filepath.Join(home, ".initflow")
+	require.NoError(t, os.MkdirAll(configDir, 0750))
+	require.NoError(t, os.WriteFile(filepath.Join(configDir, "config.yaml"),
+		[]byte("timeout: 45s\nretries: 3\n"), 0600))
+
+	t.Setenv("HOME", home)
+	t.Setenv("INITFLOW_TIMEOUT", "60s")
+	t.Setenv("INITFLOW_RETRIES", "4")
+}
+
+func TestRequestFlagsOverrideEnvAndConfig(t *testing.T) {
+	setupRequestConfig(t)
+	resetRequestFlags(t)
+
+	captureStdout(t, func() {
+		_, err := executeCommand(rootCmd, "version", "--timeout", "90s", "--retries", "5")
+		require.NoError(t, err)
+	})
+
+	cfg := config.Get()
+	assert.Equal(t, 90*time.Second, cfg.Timeout)
+	assert.Equal(t, 5, cfg.Retries)
+}
+
+func TestRequestSettingsFallBackToEnv(t *testing.T) {
+	setupRequestConfig(t)
+	resetRequestFlags(t)
+
+	captureStdout(t, func() {
+		_, err := executeCommand(rootCmd, "version")
+		require.NoError(t, err)
+	})
+
+	cfg := config.Get()
+	assert.Equal(t, 60*time.Second, cfg.Timeout)
+	assert.Equal(t, 4, cfg.Retries)
+}
+
+func TestRequestFlagsValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"negative timeout", []string{"--timeout", "-1s"}},
+		{"zero timeout", []string{"--timeout", "0s"}},
+		{"timeout too long", []string{"--timeout", "1h"}},
+		{"negative retries", []string{"--retries", "-1"}},
+		{"too many retries", []string{"--retries", "11"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupRequestConfig(t)
+			resetRequestFlags(t)
+
+			_, err := executeCommand(rootCmd, append([]string{"version"}, tt.args...)...)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestPrintCertificatePin(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	var err error
+	out := captureStdout(t, func() {
+		err = printCertificatePin(server.Client(), server.URL)
+	})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(out, client.CertificatePin(server.Certificate())+"\n"), "got %q", out)
+}
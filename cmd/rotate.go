@@ -0,0 +1,264 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/spf13/cobra"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/client"
+	"github.com/DylanBlakemore/initflow-cli/internal/encoding"
+	"github.com/DylanBlakemore/initflow-cli/internal/storage"
+)
+
+var workspaceRotateCmd = &cobra.Command{
+	Use:   "rotate <workspace-slug>",
+	Short: "Replace the workspace key and re-encrypt every secret",
+	Long: `Generate a new workspace key, re-encrypt every secret in every environment with it on this
+device, wrap it for each approved device in the workspace and swap it in on the server in one
+request. Run it after revoking a device, so copies of the old key stop working for anything
+stored from now on. Other devices fetch the new key the next time they use the workspace.
+Earlier secret versions stay encrypted with the old key and can't be rolled back to.
+Requires the owner or admin role.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runWorkspaceRotate,
+}
+
+func init() {
+	workspaceCmd.AddCommand(workspaceRotateCmd)
+
+	workspaceRotateCmd.Flags().BoolVar(&waitForOperations, "wait", false,
+		"if the server finishes the rotation in the background, wait until it is done")
+	workspaceRotateCmd.Flags().DurationVar(&operationWaitTimeout, "wait-timeout", operationWaitTimeout,
+		"how long --wait waits before giving up")
+}
+
+// currentWorkspaceKey returns this device's copy of the workspace key, first
+// fetching the new one from the server if the key was rotated since it was
+// cached. Keys cached before versions were recorded count as version 1.
+func currentWorkspaceKey(c *client.Client, store *storage.Storage, workspace *client.Workspace) ([]byte, error) {
+	cached := store.WorkspaceKeyVersion(workspace.Slug)
+	if cached == 0 {
+		cached = 1
+	}
+	if workspace.KeyVersion <= cached {
+		return store.GetWorkspaceKey(workspace.Slug)
+	}
+
+	wrapped, version, err := c.GetDeviceWorkspaceKey(workspace.ID)
+	if err != nil {
+		return nil, fmt.Errorf("the key was rotated to version %d and this device couldn't fetch it. "+
+			"Ask a workspace admin to run 'initflow workspace invite-device %s <this device's ID>': %w",
+			workspace.KeyVersion, workspace.Slug, err)
+	}
+
+	workspaceKey, err := unwrapWorkspaceKey(wrapped, workspace.ID, store)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt the rotated key: %w", err)
+	}
+
+	if err := store.StoreWorkspaceKey(workspace.Slug, workspaceKey); err != nil {
+		return nil, fmt.Errorf("failed to store the rotated key: %w", err)
+	}
+	if err := store.StoreWorkspaceKeyVersion(workspace.Slug, version); err != nil {
+		return nil, fmt.Errorf("failed to store the rotated key version: %w", err)
+	}
+	return workspaceKey, nil
+}
+
+// plainSecret is a decrypted secret waiting to be encrypted under a new key
+type plainSecret struct {
+	env   string
+	key   string
+	value []byte
+}
+
+// fetchAllSecrets downloads and decrypts the secrets of every environment
+func fetchAllSecrets(c *client.Client, workspace *client.Workspace, workspaceKey []byte) ([]plainSecret, error) {
+	envs, err := c.ListEnvironments(workspace.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list environments: %w", err)
+	}
+
+	var plain []plainSecret
+	for _, env := range append([]string{""}, envs...) {
+		envClient, err := newSecretsClient(env)
+		if err != nil {
+			return nil, err
+		}
+		secrets, err := envClient.FetchSecrets(workspace.ID, "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch secrets of %s: %w", secretsLocation(workspace.Slug, env), err)
+		}
+		for i := range secrets {
+			value, err := openSecret(workspaceKey, workspace, env, &secrets[i])
+			if err != nil {
+				return nil, fmt.Errorf("failed to decrypt %s in %s: %w",
+					secrets[i].Key, secretsLocation(workspace.Slug, env), err)
+			}
+			plain = append(plain, plainSecret{env: env, key: secrets[i].Key, value: value})
+		}
+	}
+	return plain, nil
+}
+
+// wrapForDevices wraps workspaceKey for every approved device in devices and
+// for this one, which may be missing from the list
+func wrapForDevices(workspaceKey []byte, workspaceID int, devices []client.Device, store *storage.Storage) ([]client.WrappedDeviceKey, error) {
+	ownID, err := store.GetDeviceID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read device ID: %w", err)
+	}
+
+	var wrappedKeys []client.WrappedDeviceKey
+	wrappedOwn := false
+	for _, device := range devices {
+		if !device.Approved() {
+			continue
+		}
+		var wrapped []byte
+		if device.DeviceID == ownID {
+			wrapped, err = wrapWorkspaceKey(workspaceKey, workspaceID, store)
+			wrappedOwn = true
+		} else {
+			var publicKey []byte
+			publicKey, err = encoding.Decode(device.PublicKeyX25519)
+			if err == nil {
+				wrapped, err = wrapWorkspaceKeyFor(workspaceKey, workspaceID, publicKey)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to wrap the key for device \"%s\" (%s), revoke it if it's no longer used: %w",
+				device.Name, device.DeviceID, err)
+		}
+		wrappedKeys = append(wrappedKeys, client.WrappedDeviceKey{
+			DeviceID: device.DeviceID, WrappedWorkspaceKey: encoding.Encode(wrapped),
+		})
+	}
+
+	if !wrappedOwn {
+		wrapped, err := wrapWorkspaceKey(workspaceKey, workspaceID, store)
+		if err != nil {
+			return nil, fmt.Errorf("failed to wrap the key for this device: %w", err)
+		}
+		wrappedKeys = append(wrappedKeys, client.WrappedDeviceKey{DeviceID: ownID, WrappedWorkspaceKey: encoding.Encode(wrapped)})
+	}
+	return wrappedKeys, nil
+}
+
+// rotateWorkspace replaces the key of the workspace with slug and re-encrypts
+// its secrets, as 'workspace rotate' does
+func rotateWorkspace(c *client.Client, store *storage.Storage, slug string) error {
+	workspace, err := c.GetWorkspaceBySlug(slug)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to get workspace info: %w", err)
+	}
+	if !workspace.KeyInitialized {
+		return fmt.Errorf("❌ %s has no key to rotate yet. Run 'initflow workspace init %s'", slug, slug)
+	}
+	if !hasRole(workspace.Role, workspaceAdminRoles) {
+		return fmt.Errorf("❌ Rotating the key of %s requires the owner or admin role (you are %s)", slug, workspace.Role)
+	}
+	if !store.HasWorkspaceKey(slug) {
+		return fmt.Errorf("❌ This device has no key for %s. Ask a workspace admin to invite this device first", slug)
+	}
+
+	oldKey, err := currentWorkspaceKey(c, store, workspace)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to read workspace key: %w", err)
+	}
+
+	infof("📥 Downloading and decrypting the secrets of %s...\n", slug)
+	plain, err := fetchAllSecrets(c, workspace, oldKey)
+	if err != nil {
+		return fmt.Errorf("❌ %w", err)
+	}
+
+	infoln("⚡ Generating secure 256-bit workspace key...")
+	newKey := make([]byte, encoding.WorkspaceKeySize)
+	if _, err := rand.Read(newKey); err != nil {
+		return fmt.Errorf("❌ Failed to generate workspace key: %w", err)
+	}
+	newVersion := workspace.KeyVersion + 1
+
+	infof("🔐 Re-encrypting %d secrets...\n", len(plain))
+	uploads := make([]client.SecretUpload, len(plain))
+	err = forEachParallel(len(plain), func(i int) error {
+		secret := plain[i]
+		ciphertext, err := encryptSecret(newKey, workspace.ID, secret.env, secret.key, secret.value)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt %s: %w", secret.key, err)
+		}
+		uploads[i] = client.SecretUpload{
+			Key:         secret.key,
+			Environment: secret.env,
+			Ciphertext:  encoding.Encode(ciphertext),
+			Size:        len(secret.value),
+			KeyVersion:  newVersion,
+			Checksum:    secretChecksum(newKey, workspace.ID, secret.env, secret.key, secret.value),
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("❌ %w", err)
+	}
+
+	devices, err := c.ListWorkspaceDevices(workspace.ID)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to list workspace devices: %w", err)
+	}
+	deviceKeys, err := wrapForDevices(newKey, workspace.ID, devices, store)
+	if err != nil {
+		return fmt.Errorf("❌ %w", err)
+	}
+
+	infof("📡 Swapping in key version %d for %d devices...\n", newVersion, len(deviceKeys))
+	op, err := c.RotateWorkspaceKey(workspace.ID, client.RotateWorkspaceKeyRequest{
+		FromKeyVersion: workspace.KeyVersion,
+		KeyVersion:     newVersion,
+		DeviceKeys:     deviceKeys,
+		Secrets:        uploads,
+	})
+	var apiErr *client.APIError
+	if errors.As(err, &apiErr) && apiErr.Status == http.StatusConflict {
+		return fmt.Errorf("❌ %s changed during the rotation, e.g. a secret was added or another rotation ran. "+
+			"Nothing was replaced; run the command again: %w", slug, err)
+	}
+	if err != nil {
+		return fmt.Errorf("❌ Failed to rotate workspace key: %w", err)
+	}
+	if op != nil {
+		if err := finishOperation(c, op, "key rotation"); err != nil {
+			return fmt.Errorf("❌ Failed to rotate workspace key: %w", err)
+		}
+	}
+
+	// Unless the server is still finishing, cache the new key now; otherwise
+	// currentWorkspaceKey fetches it once the new version is live
+	if op == nil || waitForOperations {
+		if err := store.StoreWorkspaceKey(slug, newKey); err != nil {
+			return fmt.Errorf("❌ Failed to store workspace key locally: %w", err)
+		}
+		if err := store.StoreWorkspaceKeyVersion(slug, newVersion); err != nil {
+			return fmt.Errorf("❌ Failed to store workspace key version locally: %w", err)
+		}
+	}
+
+	infof("✅ Rotated the key of %s to version %d and re-encrypted %d secrets\n", slug, newVersion, len(plain))
+	if _, err := c.GetRecoveryKey(workspace.ID); err == nil {
+		infof("⚠️  The recovery key of %s only unlocks the old key. Replace it with "+
+			"'initflow workspace init %s --with-recovery'\n", slug, slug)
+	}
+	return nil
+}
+
+func runWorkspaceRotate(cmd *cobra.Command, args []string) error {
+	store := storage.New()
+	if err := store.EnsureDeviceReady(); err != nil {
+		return fmt.Errorf("❌ %w", err)
+	}
+
+	return rotateWorkspace(newClient(), store, args[0])
+}
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/curve25519"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/client"
+	"github.com/DylanBlakemore/initflow-cli/internal/encoding"
+	"github.com/DylanBlakemore/initflow-cli/internal/storage"
+)
+
+func TestWorkspaceRotate(t *testing.T) {
+	fake, oldKey := setupSecretsTest(t)
+	t.Cleanup(func() { secretsEnv = "" })
+
+	if err := runSecretsAdd(secretsAddCmd, []string{"API_KEY=one"}); err != nil {
+		t.Fatalf("runSecretsAdd failed: %v", err)
+	}
+	secretsEnv = "prod"
+	if err := runSecretsAdd(secretsAddCmd, []string{"DB_URL=two"}); err != nil {
+		t.Fatalf("runSecretsAdd failed: %v", err)
+	}
+	secretsEnv = ""
+	oldCiphertext := fake.secrets["API_KEY"].Ciphertext
+
+	store := storage.New()
+	encryptionKey, _ := store.GetEncryptionPrivateKey()
+	ownPublic, _ := curve25519.X25519(encryptionKey, curve25519.Basepoint)
+	laptopPublic, laptopPrivate, _ := generateX25519Keypair()
+	fake.devices = []client.Device{
+		{DeviceID: "test-device-123", Name: "This one", PublicKeyX25519: encoding.Encode(ownPublic)},
+		{DeviceID: "laptop-2", Name: "Laptop", PublicKeyX25519: encoding.Encode(laptopPublic)},
+		{DeviceID: "pending-1", Name: "New", Status: client.DeviceStatusPending},
+		{DeviceID: "denied-1", Name: "Revoked", Status: client.DeviceStatusDenied,
+			PublicKeyX25519: encoding.Encode(laptopPublic)},
+	}
+
+	if err := runWorkspaceRotate(workspaceRotateCmd, []string{"my-project"}); err != nil {
+		t.Fatalf("runWorkspaceRotate failed: %v", err)
+	}
+
+	newKey, _ := store.GetWorkspaceKey("my-project")
+	if bytes.Equal(newKey, oldKey) || store.WorkspaceKeyVersion("my-project") != 2 || fake.keyVersion != 2 {
+		t.Fatalf("Expected a new key at version 2, got local version %d, server version %d",
+			store.WorkspaceKeyVersion("my-project"), fake.keyVersion)
+	}
+	for id, secret := range fake.secrets {
+		if secret.KeyVersion != 2 {
+			t.Errorf("Expected %s re-encrypted under key version 2, got %d", id, secret.KeyVersion)
+		}
+	}
+	if fake.secrets["API_KEY"].Ciphertext == oldCiphertext {
+		t.Error("Expected API_KEY to have a new ciphertext")
+	}
+	_, pending := fake.deviceKeys["pending-1"]
+	if _, denied := fake.deviceKeys["denied-1"]; pending || denied || len(fake.deviceKeys) != 2 {
+		t.Errorf("Expected the key wrapped for the two approved devices only, got %v", fake.deviceKeys)
+	}
+	wrapped, _ := encoding.Decode(fake.deviceKeys["laptop-2"])
+	if unwrapped, err := unwrapWorkspaceKeyWith(wrapped, 1, laptopPrivate); err != nil || !bytes.Equal(unwrapped, newKey) {
+		t.Errorf("Expected the other device to unwrap the new key, got %v", err)
+	}
+
+	// A device still caching the old key fetches the new one on next use
+	store.StoreWorkspaceKey("my-project", oldKey)
+	store.StoreWorkspaceKeyVersion("my-project", 1)
+	for _, tc := range []struct{ env, key, want string }{{"", "API_KEY", "one"}, {"prod", "DB_URL", "two"}} {
+		secretsEnv = tc.env
+		var err error
+		out := captureStdout(t, func() {
+			err = runSecretsGet(secretsGetCmd, []string{tc.key})
+		})
+		if err != nil || out != tc.want {
+			t.Errorf("Expected %q for %s after the rotation, got %q, %v", tc.want, tc.key, out, err)
+		}
+	}
+	if refreshed, _ := store.GetWorkspaceKey("my-project"); !bytes.Equal(refreshed, newKey) ||
+		store.WorkspaceKeyVersion("my-project") != 2 {
+		t.Error("Expected the rotated key to be fetched and cached")
+	}
+}
+
+func TestWorkspaceRotateInBackground(t *testing.T) {
+	fake, oldKey := setupSecretsTest(t)
+	fake.rotateInBackground = true
+	t.Cleanup(func() { waitForOperations = false })
+
+	if err := runSecretsAdd(secretsAddCmd, []string{"API_KEY=one"}); err != nil {
+		t.Fatalf("runSecretsAdd failed: %v", err)
+	}
+
+	store := storage.New()
+	var err error
+	out := captureStdout(t, func() { err = runWorkspaceRotate(workspaceRotateCmd, []string{"my-project"}) })
+	if err != nil || !strings.Contains(out, "operation op-rotate") || !strings.Contains(out, "Use --wait") {
+		t.Fatalf("Expected the rotation to be reported as running, got %q, %v", out, err)
+	}
+	if cached, _ := store.GetWorkspaceKey("my-project"); !bytes.Equal(cached, oldKey) ||
+		store.WorkspaceKeyVersion("my-project") > 1 {
+		t.Error("Expected the new key not to be cached before the rotation finished")
+	}
+
+	if err := workspaceRotateCmd.Flags().Set("wait", "true"); err != nil {
+		t.Fatalf("Expected rotate to take --wait: %v", err)
+	}
+	captureStdout(t, func() { err = runWorkspaceRotate(workspaceRotateCmd, []string{"my-project"}) })
+	if err != nil {
+		t.Fatalf("runWorkspaceRotate --wait failed: %v", err)
+	}
+	if store.WorkspaceKeyVersion("my-project") != 3 || fake.keyVersion != 3 {
+		t.Errorf("Expected --wait to cache key version 3, got %d", store.WorkspaceKeyVersion("my-project"))
+	}
+	var values map[string]string
+	captureStdout(t, func() { _, values, err = loadSecrets("my-project", "", "") })
+	if err != nil || values["API_KEY"] != "one" {
+		t.Errorf("Expected the cached key to decrypt the secrets, got %v, %v", values, err)
+	}
+}
+
+func TestWorkspaceRotateRequiresTheNewKeyToBeShared(t *testing.T) {
+	fake, _ := setupSecretsTest(t)
+
+	// The key was rotated elsewhere without wrapping it for this device
+	fake.keyVersion = 3
+	err := runSecretsGet(secretsGetCmd, []string{"API_KEY"})
+	if err == nil || !strings.Contains(err.Error(), "rotated to version 3") ||
+		!strings.Contains(err.Error(), "invite-device my-project") {
+		t.Errorf("Expected a missing rotated key to be explained, got %v", err)
+	}
+}
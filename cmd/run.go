@@ -0,0 +1,244 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/client"
+)
+
+var runCmd = &cobra.Command{
+	Use:   "run -w <workspace> -- <command> [args...]",
+	Short: "Run a command with workspace secrets in its environment",
+	Long: `Fetch and decrypt every secret in a workspace, then run the command with them set as
+environment variables. Keys in folders are joined with _, so backend/db/PASSWORD is set as
+backend_db_PASSWORD, or APP_backend_db_PASSWORD with --env-prefix APP_. Secrets override
+variables of the same name already set. Plaintext stays in memory and the child's
+environment and is never written to disk. The command's exit code is passed through.
+
+With --watch the workspace is checked for changed secrets every --interval, and when any
+secret is added, removed or written the command is stopped with SIGTERM and started again
+with the new values. It is killed if it hasn't exited after 10s.`,
+	Example: "  initflow run --workspace api -- npm start\n" +
+		"  initflow run -w api --watch --interval 1m -- ./server",
+	Args: cobra.MinimumNArgs(1),
+	RunE: runRun,
+}
+
+var (
+	runWorkspace string
+	runEnv       string
+	runWatch     bool
+	runEnvPrefix string
+	runInterval  time.Duration
+)
+
+// runStopTimeout is how long --watch waits for the command to exit on SIGTERM
+// before killing it
+var runStopTimeout = 10 * time.Second
+
+func init() {
+	rootCmd.AddCommand(runCmd)
+
+	// Everything after the command name belongs to the command, even without --
+	runCmd.Flags().SetInterspersed(false)
+	runCmd.Flags().StringVarP(&runWorkspace, "workspace", "w", "", "slug of the workspace whose secrets to inject")
+	runCmd.Flags().StringVarP(&runEnv, "env", "e", "", "environment whose secrets to inject, e.g. prod (default: the workspace default)")
+	runCmd.Flags().StringVar(&runEnvPrefix, "env-prefix", "", "prepend this to every variable name, e.g. APP_")
+	runCmd.Flags().BoolVar(&runWatch, "watch", false, "restart the command when the workspace secrets change")
+	runCmd.Flags().DurationVar(&runInterval, "interval", 30*time.Second, "how often --watch checks for changed secrets")
+	_ = runCmd.MarkFlagRequired("workspace")
+}
+
+// mergeEnv returns base with values set, replacing entries of the same name
+func mergeEnv(base []string, values map[string]string) []string {
+	env := make([]string, 0, len(base)+len(values))
+	for _, entry := range base {
+		name, _, _ := strings.Cut(entry, "=")
+		if _, ok := values[name]; !ok {
+			env = append(env, entry)
+		}
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		env = append(env, key+"="+values[key])
+	}
+	return env
+}
+
+// secretsWatcher notices changes to the secrets run injects by comparing
+// fingerprints of their metadata, and only decrypts them again when one changed
+type secretsWatcher struct {
+	client      *client.Client
+	workspaceID int
+	fingerprint string
+}
+
+// newSecretsWatcher takes the fingerprint to compare against. It is taken
+// before the secrets are first loaded, so a change in between is noticed.
+func newSecretsWatcher() (*secretsWatcher, error) {
+	c, err := newSecretsClient(runEnv, client.WithCache())
+	if err != nil {
+		return nil, err
+	}
+	workspace, err := c.GetWorkspaceBySlug(runWorkspace)
+	if err != nil {
+		return nil, fmt.Errorf("❌ Failed to get workspace info: %w", err)
+	}
+
+	w := &secretsWatcher{client: c, workspaceID: workspace.ID}
+	if w.fingerprint, _, err = w.poll(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// poll fingerprints the secrets and reports whether they changed since the
+// fingerprint was last kept. The caller keeps it once the new values are
+// loaded, so a failed load is retried on the next poll.
+func (w *secretsWatcher) poll() (string, bool, error) {
+	secrets, err := w.client.ListSecrets(w.workspaceID, "")
+	if err != nil {
+		return "", false, fmt.Errorf("❌ Failed to check for changed secrets: %w", err)
+	}
+	fingerprint := client.SecretsFingerprint(secrets)
+	return fingerprint, fingerprint != w.fingerprint, nil
+}
+
+// loadRunEnv loads the secrets to inject as environment variables
+func loadRunEnv() (map[string]string, error) {
+	_, secrets, err := loadSecrets(runWorkspace, runEnv, "")
+	if err != nil {
+		return nil, err
+	}
+	values, err := variableNames(secrets, "")
+	if err != nil {
+		return nil, fmt.Errorf("❌ Failed to prepare the environment: %w", err)
+	}
+	return withEnvPrefix(values, runEnvPrefix), nil
+}
+
+// startChild starts the command with values in its environment, returning a
+// channel that receives its result when it exits
+func startChild(cmd *cobra.Command, args []string, values map[string]string) (*exec.Cmd, <-chan error, error) {
+	child := exec.Command(args[0], args[1:]...) // #nosec G204 - running the user's command is the point
+	child.Env = mergeEnv(os.Environ(), values)
+	child.Stdin = os.Stdin
+	child.Stdout = cmd.OutOrStdout()
+	child.Stderr = cmd.ErrOrStderr()
+
+	if err := child.Start(); err != nil {
+		return nil, nil, fmt.Errorf("❌ Failed to start %s: %w", args[0], err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- child.Wait() }()
+	return child, done, nil
+}
+
+// stopChild asks the child to exit and kills it if it hasn't within
+// runStopTimeout
+func stopChild(child *exec.Cmd, done <-chan error) {
+	if err := child.Process.Signal(syscall.SIGTERM); err != nil {
+		_ = child.Process.Kill()
+	}
+	select {
+	case <-done:
+	case <-time.After(runStopTimeout):
+		_ = child.Process.Kill()
+		<-done
+	}
+}
+
+// childResult passes the child's exit code through
+func childResult(name string, err error) error {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		code := exitErr.ExitCode()
+		if code < 0 {
+			code = exitError
+		}
+		return &silentExit{code: code}
+	}
+	if err != nil {
+		return fmt.Errorf("❌ %s failed: %w", name, err)
+	}
+	return nil
+}
+
+func runRun(cmd *cobra.Command, args []string) error {
+	if runWatch && runInterval <= 0 {
+		return fmt.Errorf("❌ --interval must be positive, got %s", runInterval)
+	}
+	if err := validateEnvPrefix("--env-prefix", runEnvPrefix); err != nil {
+		return fmt.Errorf("❌ %w", err)
+	}
+
+	var watcher *secretsWatcher
+	var ticks <-chan time.Time
+	if runWatch {
+		var err error
+		if watcher, err = newSecretsWatcher(); err != nil {
+			return err
+		}
+		ticker := time.NewTicker(runInterval)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+
+	values, err := loadRunEnv()
+	if err != nil {
+		return err
+	}
+	child, done, err := startChild(cmd, args, values)
+	if err != nil {
+		return err
+	}
+
+	// The child gets terminal signals itself; relay the rest and keep running until it exits
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case err := <-done:
+			return childResult(args[0], err)
+		case sig := <-signals:
+			_ = child.Process.Signal(sig)
+		case <-ticks:
+			fingerprint, changed, err := watcher.poll()
+			if err == nil && changed {
+				values, err = loadRunEnv()
+			}
+			if err != nil {
+				infof("%v; %s keeps running with the previous secrets\n", err, args[0])
+				continue
+			}
+			if !changed {
+				continue
+			}
+			watcher.fingerprint = fingerprint
+
+			infof("🔄 Secrets changed, restarting %s\n", args[0])
+			stopChild(child, done)
+			if child, done, err = startChild(cmd, args, values); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMergeEnv(t *testing.T) {
+	base := []string{"PATH=/usr/bin", "API_KEY=local", "HOME=/home/me"}
+	got := mergeEnv(base, map[string]string{"API_KEY": "from-workspace", "DB_URL": "postgres://db"})
+
+	want := []string{"PATH=/usr/bin", "HOME=/home/me", "API_KEY=from-workspace", "DB_URL=postgres://db"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestRunInjectsSecrets(t *testing.T) {
+	setupSecretsTest(t)
+	runWorkspace = "my-project"
+	t.Cleanup(func() { runWorkspace = "" })
+
+	if err := runSecretsAdd(secretsAddCmd, []string{"API_KEY=s3cret value", "GREETING=hi"}); err != nil {
+		t.Fatalf("runSecretsAdd failed: %v", err)
+	}
+
+	var err error
+	out := captureStdout(t, func() {
+		err = runRun(runCmd, []string{"sh", "-c", `printf '%s|%s' "$API_KEY" "$GREETING"`})
+	})
+	if err != nil {
+		t.Fatalf("runRun failed: %v", err)
+	}
+	if out != "s3cret value|hi" {
+		t.Errorf("Expected the child to see the decrypted secrets, got %q", out)
+	}
+
+	err = runRun(runCmd, []string{"sh", "-c", "exit 3"})
+	if code := exitCodeFor(err); code != 3 {
+		t.Errorf("Expected the child's exit code 3 to pass through, got %d (%v)", code, err)
+	}
+
+	err = runRun(runCmd, []string{"initflow-no-such-command"})
+	if err == nil || !strings.Contains(err.Error(), "Failed to start initflow-no-such-command") {
+		t.Errorf("Expected a clear error for a missing command, got %v", err)
+	}
+}
+
+func TestRunWatchRestartsOnChange(t *testing.T) {
+	setupSecretsTest(t)
+	runWorkspace, runWatch, runInterval = "my-project", true, 20*time.Millisecond
+	t.Cleanup(func() { runWorkspace, runWatch, runInterval = "", false, 30*time.Second })
+
+	if err := runSecretsAdd(secretsAddCmd, []string{"API_KEY=old"}); err != nil {
+		t.Fatalf("runSecretsAdd failed: %v", err)
+	}
+
+	// The first child waits to be stopped; the restarted one sees the new value and exits
+	script := `printf '%s\n' "$API_KEY"; [ "$API_KEY" = new ] && exit 0; exec sleep 10`
+	var err error
+	out := captureStdout(t, func() {
+		result := make(chan error, 1)
+		go func() { result <- runRun(runCmd, []string{"sh", "-c", script}) }()
+
+		time.Sleep(200 * time.Millisecond)
+		if err := runSecretsAdd(secretsAddCmd, []string{"API_KEY=new"}); err != nil {
+			t.Errorf("runSecretsAdd failed: %v", err)
+		}
+
+		select {
+		case err = <-result:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected the command to be restarted with the new secret")
+		}
+	})
+	if err != nil {
+		t.Fatalf("runRun failed: %v", err)
+	}
+	if !strings.Contains(out, "old\n") || !strings.HasSuffix(out, "new\n") {
+		t.Errorf("Expected the child to run with the old and then the new value, got %q", out)
+	}
+}
@@ -0,0 +1,1444 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/term"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/agent"
+	"github.com/DylanBlakemore/initflow-cli/internal/client"
+	"github.com/DylanBlakemore/initflow-cli/internal/dotenv"
+	"github.com/DylanBlakemore/initflow-cli/internal/encoding"
+	"github.com/DylanBlakemore/initflow-cli/internal/fsutil"
+	"github.com/DylanBlakemore/initflow-cli/internal/storage"
+)
+
+var secretsCmd = &cobra.Command{
+	Use:   "secrets",
+	Short: "Manage workspace secrets",
+	Long: "Store and read secrets in a workspace. Values are encrypted on this device with the " +
+		"workspace key before upload, so the server only ever holds ciphertext.",
+}
+
+var secretsAddCmd = &cobra.Command{
+	Use:   "add <KEY=VALUE | KEY>... | -",
+	Short: "Encrypt and store secrets",
+	Long: "Encrypt each value with the workspace key and upload it, replacing any existing secret with " +
+		"the same key. Give just KEY to be prompted for the value without echo, which keeps it out of " +
+		"your shell history. Give - alone to read KEY=VALUE lines from stdin, in the dotenv format " +
+		"import takes, and upload them in one batch; blank and # comment lines are skipped, and if any " +
+		"line is invalid each one is reported and nothing is stored. KEY=- stores a literal -. " +
+		"--if-not-exists only creates secrets, and --expected-version or --expected-value only replace a " +
+		"secret nobody changed since you read it; a write they refuse fails with exit code 9. --ttl has the " +
+		"server expire the secrets after a duration, for temporary tokens. When the server can't be " +
+		"reached, unguarded writes are queued on this device for 'secrets sync' and the command exits with 8.",
+	Example: "  initflow secrets add -w api STRIPE_KEY\n" +
+		"  grep ^STRIPE_ .env | initflow secrets add -w api -\n" +
+		"  initflow secrets add -w api --expected-version 3 STRIPE_KEY=sk_live_new\n" +
+		"  initflow secrets add -w api --ttl 24h DEPLOY_TOKEN",
+	Args: cobra.MinimumNArgs(1),
+	RunE: runSecretsAdd,
+}
+
+var secretsGetCmd = &cobra.Command{
+	Use:   "get <KEY>",
+	Short: "Decrypt and print one secret",
+	Long: "Download a secret, decrypt it with the workspace key and print only the value, so it can be " +
+		"piped or captured: DB_URL=$(initflow secrets get DATABASE_URL -w my-project). A trailing newline " +
+		"is added only at a terminal, or always with --newline. --only-prefixed APP_ reads APP_KEY when given KEY. " +
+		"--out writes the value byte for byte to a file only you can read instead, e.g. a TLS key; it " +
+		"won't replace an existing file without --force, nor create missing folders without --mkdir.",
+	Example: "  initflow secrets get -w api TLS_PRIVATE_KEY --out certs/tls.key --mkdir",
+	Args:    cobra.ExactArgs(1),
+	RunE:    runSecretsGet,
+}
+
+var secretsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List secret keys and metadata",
+	Long: "List the secrets in a workspace with their sizes and timestamps, sorted by key. Values are " +
+		"never downloaded or decrypted, so this works without the workspace key. Keys may be paths such as " +
+		"backend/db/PASSWORD: --path backend/ lists one folder and --tree shows the folders as a tree. " +
+		"--only-prefixed APP_ lists only keys starting with APP_, without the prefix. Secrets stored with a " +
+		"--ttl show the time they have left; expired ones are hidden unless --include-expired is set. " +
+		"--page-size asks the server for that many secrets at a time, and --ndjson prints each secret as " +
+		"one JSON line as its page arrives, in the server's order, instead of waiting for the whole list.",
+	Args: cobra.NoArgs,
+	RunE: runSecretsList,
+}
+
+var secretsRmCmd = &cobra.Command{
+	Use:     "rm <KEY>...",
+	Aliases: []string{"remove"},
+	Short:   "Delete secrets",
+	Long: "Delete secrets from a workspace for every member. Asks for confirmation unless --force is set, " +
+		"which is required when stdin isn't a terminal. When the server can't be reached the deletes are " +
+		"queued on this device for 'secrets sync' and the command exits with 8.",
+	Args: cobra.MinimumNArgs(1),
+	RunE: runSecretsRm,
+}
+
+var secretsImportCmd = &cobra.Command{
+	Use:   "import <FILE>",
+	Short: "Encrypt and upload the entries of a .env file",
+	Long: "Parse a dotenv file (quotes, comments, multiline values and export prefixes are supported), " +
+		"encrypt every entry with the workspace key and upload them in one batch, replacing secrets with the " +
+		"same keys. Use - to read from stdin. When a key is set twice the last value wins.",
+	Args: cobra.ExactArgs(1),
+	RunE: runSecretsImport,
+}
+
+var secretsExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Decrypt all secrets into a dotenv, JSON or other file",
+	Long: "Decrypt every secret in a workspace and print them as a dotenv file, quoted so dotenv " +
+		"libraries and shells read the values back unchanged, or as a JSON object. --format k8s writes a " +
+		"Kubernetes Secret named after the workspace and environment, shell writes export lines and docker " +
+		"a --env-file; values are escaped for each. --template renders every secret through a Go template " +
+		"with .Key and .Value, and the base64, shellquote and dockervalue functions. --out writes the file " +
+		"with owner-only permissions instead. --path backend/ exports one folder, dropping the prefix; any " +
+		"remaining / in a key becomes _, so backend/db/PASSWORD is exported as DB_PASSWORD, and --env-prefix " +
+		"APP_ prepends APP_ to every name. --format snapshot writes JSON with the secrets' own keys, where and " +
+		"when they were taken and a checksum, for 'secrets restore'. Prefer " +
+		"'initflow run' where you can: an exported file holds plaintext.",
+	Example: "  initflow secrets export -w api --env prod --format k8s | kubectl apply -f -\n" +
+		"  initflow secrets export -w api --template '{{ .Key }}: {{ .Value | printf \"%q\" }}'",
+	Args: cobra.NoArgs,
+	RunE: runSecretsExport,
+}
+
+var secretsHistoryCmd = &cobra.Command{
+	Use:   "history <KEY>",
+	Short: "List the versions of a secret",
+	Long: "List every version of a secret, newest first. Each 'secrets add' or import of an existing key " +
+		"creates a new version. Values are not decrypted.",
+	Args: cobra.ExactArgs(1),
+	RunE: runSecretsHistory,
+}
+
+var secretsRollbackCmd = &cobra.Command{
+	Use:   "rollback <KEY> --version <N>",
+	Short: "Restore an earlier version of a secret",
+	Long: "Decrypt an earlier version of a secret on this device and store it again as the newest version, " +
+		"so the history is kept. Versions encrypted with an older workspace key can't be restored.",
+	Args: cobra.ExactArgs(1),
+	RunE: runSecretsRollback,
+}
+
+var (
+	secretsWorkspace       string
+	secretsEnv             string
+	secretsGetVersion      int
+	secretsRollbackVersion int
+	secretsListPath        string
+	secretsListTree        bool
+	secretsExportFormat    string
+	secretsExportTemplate  string
+	secretsExportOut       string
+	secretsExportPath      string
+	secretsExportEnvPrefix string
+	secretsListOnlyPrefix  string
+	secretsGetOnlyPrefix   string
+	secretsGetOut          string
+	secretsGetMkdir        bool
+	secretsGetForce        bool
+	secretsGetNewline      bool
+	secretsRmForce         bool
+
+	secretsAddIfNotExists     bool
+	secretsAddExpectedVersion int
+	secretsAddExpectedValue   string
+	secretsAddTTL             time.Duration
+
+	secretsListIncludeExpired bool
+	secretsListPageSize       int
+	secretsListNDJSON         bool
+)
+
+// secretColumns are the columns secrets list can show
+var secretColumns = []tableColumn{
+	{Name: "key", Header: "Key", Default: true},
+	{Name: "size", Header: "Size", Default: true},
+	{Name: "created", Header: "Created", Default: true},
+	{Name: "updated", Header: "Updated", Default: true},
+	{Name: "expires", Header: "Expires", Default: true},
+	{Name: "key-version", Header: "Key Version"},
+	{Name: "environment", Header: "Environment"},
+	{Name: "tags", Header: "Tags"},
+}
+
+var secretsListTable tableOptions
+
+// secretsOutDirPermissions are for folders secrets get --mkdir creates
+const secretsOutDirPermissions = 0700
+
+// maxSecretsPageSize is the largest page secrets list --page-size asks for
+const maxSecretsPageSize = 1000
+
+// secretExpiryWarning is how close to expiry secrets list flags a secret
+const secretExpiryWarning = 24 * time.Hour
+
+// secretKeyPattern is the shape of a secret key: environment variable names
+// joined by / into a path, e.g. backend/db/PASSWORD
+var secretKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(/[A-Za-z_][A-Za-z0-9_]*)*$`)
+
+// envPrefixPattern is the shape of an --env-prefix or --only-prefixed value:
+// the start of an environment variable name, e.g. APP_
+var envPrefixPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// environmentPattern is the shape of an environment name such as staging or prod
+var environmentPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+func init() {
+	rootCmd.AddCommand(secretsCmd)
+	secretsCmd.AddCommand(secretsAddCmd)
+	secretsCmd.AddCommand(secretsGetCmd)
+	secretsCmd.AddCommand(secretsListCmd)
+	secretsCmd.AddCommand(secretsRmCmd)
+	secretsCmd.AddCommand(secretsImportCmd)
+	secretsCmd.AddCommand(secretsExportCmd)
+	secretsCmd.AddCommand(secretsHistoryCmd)
+	secretsCmd.AddCommand(secretsRollbackCmd)
+
+	secretsCmd.PersistentFlags().StringVarP(&secretsWorkspace, "workspace", "w", "", "slug of the workspace holding the secrets")
+	secretsCmd.PersistentFlags().StringVarP(&secretsEnv, "env", "e", "",
+		"environment of the secrets, e.g. staging or prod (default: the workspace default)")
+	_ = secretsCmd.MarkPersistentFlagRequired("workspace")
+	addTableFlags(secretsListCmd, &secretsListTable, secretColumns)
+	secretsListCmd.Flags().StringVar(&secretsListPath, "path", "", "only list secrets in this folder, e.g. backend/")
+	secretsListCmd.Flags().BoolVar(&secretsListTree, "tree", false, "show keys as a tree of folders")
+	secretsListCmd.Flags().StringVar(&secretsListOnlyPrefix, "only-prefixed", "",
+		"only list keys starting with this prefix, shown without it, e.g. APP_")
+	secretsGetCmd.Flags().StringVar(&secretsGetOnlyPrefix, "only-prefixed", "",
+		"read KEY with this prefix added, e.g. APP_ to read APP_DB_URL as DB_URL")
+	secretsAddCmd.Flags().BoolVar(&secretsAddIfNotExists, "if-not-exists", false, "fail instead of replacing a secret that exists")
+	secretsAddCmd.Flags().IntVar(&secretsAddExpectedVersion, "expected-version", 0,
+		"only replace the secret while it is still this version, from 'secrets history'")
+	secretsAddCmd.Flags().StringVar(&secretsAddExpectedValue, "expected-value", "",
+		"only replace the secret while it still holds this value")
+	secretsAddCmd.MarkFlagsMutuallyExclusive("if-not-exists", "expected-version", "expected-value")
+	secretsAddCmd.Flags().DurationVar(&secretsAddTTL, "ttl", 0, "expire the secrets after this long, e.g. 30m or 168h")
+	secretsListCmd.Flags().BoolVar(&secretsListIncludeExpired, "include-expired", false, "also list secrets past their expiry")
+	secretsListCmd.Flags().IntVar(&secretsListPageSize, "page-size", 0,
+		fmt.Sprintf("secrets to fetch per request, 1 to %d (default: the server's)", maxSecretsPageSize))
+	secretsListCmd.Flags().BoolVar(&secretsListNDJSON, "ndjson", false, "stream one JSON object per secret as pages arrive")
+	secretsListCmd.MarkFlagsMutuallyExclusive("ndjson", "tree")
+	secretsGetCmd.Flags().IntVar(&secretsGetVersion, "version", 0, "print this earlier version instead of the current one")
+	secretsGetCmd.Flags().StringVar(&secretsGetOut, "out", "", "write the value to this file, owner-only, instead of stdout")
+	secretsGetCmd.Flags().BoolVar(&secretsGetMkdir, "mkdir", false, "create the --out file's missing folders")
+	secretsGetCmd.Flags().BoolVar(&secretsGetForce, "force", false, "replace an existing --out file")
+	secretsGetCmd.Flags().BoolVar(&secretsGetNewline, "newline", false, "end the value with a newline")
+	secretsRollbackCmd.Flags().IntVar(&secretsRollbackVersion, "version", 0, "version to restore, from 'secrets history'")
+	_ = secretsRollbackCmd.MarkFlagRequired("version")
+	secretsExportCmd.Flags().StringVar(&secretsExportFormat, "format", "dotenv",
+		"dotenv, json, snapshot, k8s, shell or docker")
+	secretsExportCmd.Flags().StringVar(&secretsExportTemplate, "template", "",
+		"Go template rendered for each secret with .Key and .Value, instead of --format")
+	secretsExportCmd.Flags().StringVar(&secretsExportOut, "out", "", "file to write instead of stdout, e.g. .env")
+	secretsExportCmd.Flags().StringVar(&secretsExportPath, "path", "", "only export secrets in this folder, e.g. backend/")
+	secretsExportCmd.Flags().StringVar(&secretsExportEnvPrefix, "env-prefix", "", "prepend this to every exported name, e.g. APP_")
+	secretsRmCmd.Flags().BoolVarP(&secretsRmForce, "force", "f", false, "delete without asking for confirmation")
+}
+
+// secretFormatVersion identifies the encrypted secret layout and is bound into
+// the AEAD associated data, like wrapFormatVersion for workspace keys
+const secretFormatVersion = 1
+
+// secretAssociatedData binds a secret's ciphertext to its workspace, environment
+// and key, so the server can't swap values between them undetected. The
+// default environment adds nothing, keeping secrets written before
+// environments existed readable; keys can't contain the 0 separator.
+func secretAssociatedData(workspaceID int, env, key string) []byte {
+	ad := []byte{secretFormatVersion}
+	ad = binary.BigEndian.AppendUint64(ad, uint64(workspaceID)) // #nosec G115 - IDs are non-negative
+	ad = append(ad, key...)
+	if env != "" {
+		ad = append(append(ad, 0), env...)
+	}
+	return ad
+}
+
+// encryptSecret seals value with the workspace key as nonce || ciphertext
+func encryptSecret(workspaceKey []byte, workspaceID int, env, key string, value []byte) ([]byte, error) {
+	cipher, err := chacha20poly1305.New(workspaceKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	nonce := make([]byte, encoding.ChaCha20NonceSize, encoding.ChaCha20NonceSize+len(value)+cipher.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	// #nosec G407 - nonce is randomly generated above
+	return cipher.Seal(nonce, nonce, value, secretAssociatedData(workspaceID, env, key)), nil
+}
+
+// decryptSecret opens a value sealed by encryptSecret
+func decryptSecret(workspaceKey []byte, workspaceID int, env, key string, sealed []byte) ([]byte, error) {
+	cipher, err := chacha20poly1305.New(workspaceKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	if len(sealed) < encoding.ChaCha20NonceSize+cipher.Overhead() {
+		return nil, fmt.Errorf("ciphertext is too short: %d bytes", len(sealed))
+	}
+
+	nonce, ciphertext := sealed[:encoding.ChaCha20NonceSize], sealed[encoding.ChaCha20NonceSize:]
+	value, err := cipher.Open(nil, nonce, ciphertext, secretAssociatedData(workspaceID, env, key))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: wrong workspace key, or the ciphertext was altered")
+	}
+	return value, nil
+}
+
+// openSecret decodes and decrypts a secret fetched from the server. A value
+// sealed under another workspace key version is reported as such rather than
+// as a failed decryption, since this device only holds the current key.
+func openSecret(workspaceKey []byte, workspace *client.Workspace, env string, secret *client.Secret) ([]byte, error) {
+	if secret.KeyVersion != 0 && workspace.KeyVersion != 0 && secret.KeyVersion != workspace.KeyVersion {
+		return nil, fmt.Errorf("it was encrypted with workspace key version %d, but this device holds version %d",
+			secret.KeyVersion, workspace.KeyVersion)
+	}
+
+	sealed, err := encoding.Decode(secret.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode ciphertext: %w", err)
+	}
+	value, err := decryptSecret(workspaceKey, workspace.ID, env, secret.Key, sealed)
+	if err != nil {
+		return nil, err
+	}
+	if secret.Checksum != "" &&
+		!hmac.Equal([]byte(secret.Checksum), []byte(secretChecksum(workspaceKey, workspace.ID, env, secret.Key, value))) {
+		return nil, fmt.Errorf("integrity check failed: the value doesn't match its checksum")
+	}
+	return value, nil
+}
+
+// checksumKeyLabel derives the checksum key from the workspace key, so the
+// MAC and the cipher never share a key
+const checksumKeyLabel = "initflow secret checksum v1"
+
+// secretChecksum is the HMAC-SHA256 of a secret's associated data and
+// plaintext under a key derived from the workspace key. Stored beside the
+// ciphertext, it detects corruption and a ciphertext paired with the wrong
+// checksum only: an old version put back whole carries its own valid
+// checksum, so it is no defence against a server rolling a secret back.
+func secretChecksum(workspaceKey []byte, workspaceID int, env, key string, value []byte) string {
+	derive := hmac.New(sha256.New, workspaceKey)
+	derive.Write([]byte(checksumKeyLabel))
+	mac := hmac.New(sha256.New, derive.Sum(nil))
+	mac.Write(secretAssociatedData(workspaceID, env, key))
+	mac.Write(value)
+	return "hmac-sha256:" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// sealUploads encrypts values[key] for each of keys under a workspace key, for
+// an environment of that workspace, in parallel. encryptSecret makes its own
+// cipher and nonce per call, so the workers share nothing but the key.
+func sealUploads(workspaceKey []byte, workspace *client.Workspace, env string, keys []string, values map[string]string) ([]client.SecretUpload, error) {
+	uploads := make([]client.SecretUpload, len(keys))
+	err := forEachParallel(len(keys), func(i int) error {
+		key := keys[i]
+		ciphertext, err := encryptSecret(workspaceKey, workspace.ID, env, key, []byte(values[key]))
+		if err != nil {
+			return fmt.Errorf("failed to encrypt %s: %w", key, err)
+		}
+		uploads[i] = client.SecretUpload{
+			Key:        key,
+			Ciphertext: encoding.Encode(ciphertext),
+			Size:       len(values[key]),
+			KeyVersion: workspace.KeyVersion,
+			Checksum:   secretChecksum(workspaceKey, workspace.ID, env, key, []byte(values[key])),
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return uploads, nil
+}
+
+// decryptSecrets decrypts every fetched secret into a map keyed by secret key,
+// in parallel, reporting every secret that fails
+func decryptSecrets(workspaceKey []byte, workspace *client.Workspace, env string, secrets []client.Secret) (map[string]string, error) {
+	plain := make([][]byte, len(secrets))
+	err := forEachParallel(len(secrets), func(i int) error {
+		value, err := openSecret(workspaceKey, workspace, env, &secrets[i])
+		if err != nil {
+			return fmt.Errorf("%s: %w", secrets[i].Key, err)
+		}
+		plain[i] = value
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string, len(secrets))
+	for i, secret := range secrets {
+		values[secret.Key] = string(plain[i])
+	}
+	return values, nil
+}
+
+// loadSecrets fetches and decrypts every secret under prefix in an environment
+// of a workspace, through the agent when it is running
+func loadSecrets(slug, env, prefix string) (*client.Workspace, map[string]string, error) {
+	request := agent.Request{Workspace: slug, Env: env, Prefix: prefix}
+	if workspace, values, err := secretsFromAgent(request); !errors.Is(err, agent.ErrNotRunning) {
+		if err != nil {
+			return nil, nil, fmt.Errorf("❌ %w", err)
+		}
+		return workspace, values, nil
+	}
+
+	store := storage.New()
+	if err := ensureSecretsAccess(store); err != nil {
+		return nil, nil, fmt.Errorf("❌ %w", err)
+	}
+
+	c, err := newSecretsClient(env)
+	if err != nil {
+		return nil, nil, err
+	}
+	workspace, workspaceKey, err := openWorkspace(c, store, slug)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	secrets, err := c.FetchSecrets(workspace.ID, prefix)
+	if err != nil {
+		return nil, nil, fmt.Errorf("❌ Failed to fetch secrets: %w", err)
+	}
+
+	values, err := decryptSecrets(workspaceKey, workspace, env, secrets)
+	if err != nil {
+		return nil, nil, fmt.Errorf("❌ Failed to decrypt secrets: %w", err)
+	}
+	return workspace, values, nil
+}
+
+func validateEnvironment(env string) error {
+	if env != "" && !environmentPattern.MatchString(env) {
+		return fmt.Errorf("invalid environment %q: use lowercase letters, digits, - and _", env)
+	}
+	return nil
+}
+
+// newSecretsClient is newClient scoped to env, the --env of a secrets command
+func newSecretsClient(env string, opts ...client.Option) (*client.Client, error) {
+	if err := validateEnvironment(env); err != nil {
+		return nil, fmt.Errorf("❌ %w", err)
+	}
+	return newClient(append(opts, client.WithEnvironment(env))...), nil
+}
+
+// orDefault shows the unnamed environment as "default"
+func orDefault(env string) string {
+	if env == "" {
+		return "default"
+	}
+	return env
+}
+
+// secretsLocation names a workspace and, unless it is the default, an environment
+func secretsLocation(slug, env string) string {
+	if env == "" {
+		return slug
+	}
+	return fmt.Sprintf("%s (%s)", slug, env)
+}
+
+func validateSecretKey(key string) error {
+	if !secretKeyPattern.MatchString(key) {
+		return fmt.Errorf("invalid secret key %q: use letters, digits and underscores, not starting with a digit, "+
+			"and / between folders", key)
+	}
+	return nil
+}
+
+// parseSecretPath turns a --path folder into the key prefix it selects:
+// backend and backend/ both give "backend/". No path gives "".
+func parseSecretPath(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	folder := strings.TrimSuffix(path, "/")
+	if !secretKeyPattern.MatchString(folder) {
+		return "", fmt.Errorf("invalid --path %q: use folder names of letters, digits and underscores, "+
+			"separated by /", path)
+	}
+	return folder + "/", nil
+}
+
+// variableNames renames secrets for use as environment variables: prefix is
+// dropped and the remaining folders are joined with _, so backend/db/PASSWORD
+// under backend/ becomes DB_PASSWORD.
+func variableNames(values map[string]string, prefix string) (map[string]string, error) {
+	renamed := make(map[string]string, len(values))
+	keys := make(map[string]string, len(values))
+	for key, value := range values {
+		name := strings.ReplaceAll(strings.TrimPrefix(key, prefix), "/", "_")
+		if other, ok := keys[name]; ok {
+			first, second := other, key
+			if second < first {
+				first, second = second, first
+			}
+			return nil, fmt.Errorf("%s and %s would both be named %s", first, second, name)
+		}
+		keys[name] = key
+		renamed[name] = value
+	}
+	return renamed, nil
+}
+
+// validateEnvPrefix checks the value of an --env-prefix or --only-prefixed flag
+func validateEnvPrefix(flag, prefix string) error {
+	if prefix != "" && !envPrefixPattern.MatchString(prefix) {
+		return fmt.Errorf("invalid %s %q: use letters, digits and underscores, not starting with a digit", flag, prefix)
+	}
+	return nil
+}
+
+// withEnvPrefix prepends prefix to every name in values
+func withEnvPrefix(values map[string]string, prefix string) map[string]string {
+	if prefix == "" {
+		return values
+	}
+	prefixed := make(map[string]string, len(values))
+	for name, value := range values {
+		prefixed[prefix+name] = value
+	}
+	return prefixed
+}
+
+// writeSecretTree prints keys, relative to root, as a tree of folders
+func writeSecretTree(w io.Writer, root string, keys []string) {
+	type folder map[string]folder
+
+	tree := folder{}
+	for _, key := range keys {
+		node := tree
+		parts := strings.Split(key, "/")
+		for _, part := range parts[:len(parts)-1] {
+			name := part + "/"
+			if node[name] == nil {
+				node[name] = folder{}
+			}
+			node = node[name]
+		}
+		node[parts[len(parts)-1]] = nil
+	}
+
+	var walk func(node folder, indent string)
+	walk = func(node folder, indent string) {
+		names := make([]string, 0, len(node))
+		for name := range node {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for i, name := range names {
+			branch, next := "├── ", "│   "
+			if i == len(names)-1 {
+				branch, next = "└── ", "    "
+			}
+			fmt.Fprintf(w, "%s%s%s\n", indent, branch, name)
+			if node[name] != nil {
+				walk(node[name], indent+next)
+			}
+		}
+	}
+
+	fmt.Fprintln(w, root)
+	walk(tree, "")
+}
+
+// parseSecretArg splits KEY=VALUE. A bare KEY has no value yet.
+func parseSecretArg(arg string) (string, string, bool, error) {
+	key, value, hasValue := strings.Cut(arg, "=")
+	key = strings.TrimSpace(key)
+	if err := validateSecretKey(key); err != nil {
+		return "", "", false, err
+	}
+	return key, value, hasValue, nil
+}
+
+// openWorkspace looks up a workspace and the key this device, or the scoped
+// token in INITFLOW_TOKEN, holds for it
+func openWorkspace(c *client.Client, store *storage.Storage, slug string) (*client.Workspace, []byte, error) {
+	if scopedToken() != "" {
+		return openWorkspaceWithToken(c, slug)
+	}
+	if !store.HasWorkspaceKey(slug) {
+		return nil, nil, fmt.Errorf("❌ This device has no key for %s. Run 'initflow workspace init %s', "+
+			"or ask a workspace member to invite this device", slug, slug)
+	}
+
+	workspace, err := c.GetWorkspaceBySlug(slug)
+	if err != nil {
+		return nil, nil, fmt.Errorf("❌ Failed to get workspace info: %w", err)
+	}
+
+	workspaceKey, err := currentWorkspaceKey(c, store, workspace)
+	if err != nil {
+		return nil, nil, fmt.Errorf("❌ Failed to read workspace key: %w", err)
+	}
+
+	return workspace, workspaceKey, nil
+}
+
+// runSecretsAddStdin stores the KEY=VALUE lines of stdin, for secrets add -.
+// Nothing is stored unless every line is valid.
+func runSecretsAddStdin(cmd *cobra.Command) error {
+	data, err := io.ReadAll(cmd.InOrStdin())
+	if err != nil {
+		return fmt.Errorf("❌ Failed to read stdin: %w", err)
+	}
+	entries, err := parseDotenv("stdin", data)
+	if err != nil {
+		return fmt.Errorf("❌ Nothing was stored; fix these lines:\n%w", err)
+	}
+	if len(entries) == 0 {
+		infoln("ℹ️ No secrets found on stdin")
+		return nil
+	}
+	return importEntries("stdin", entries)
+}
+
+// addConditionSet reports whether a secrets add guard flag was given
+func addConditionSet(cmd *cobra.Command) bool {
+	return secretsAddIfNotExists || cmd.Flags().Changed("expected-version") || cmd.Flags().Changed("expected-value")
+}
+
+// addCondition turns the secrets add guard flags into the condition for
+// writing key. --expected-value is checked here against the current value,
+// then sent as the version that value was read from, so a write in between
+// still fails.
+func addCondition(cmd *cobra.Command, c *client.Client, workspace *client.Workspace, workspaceKey []byte, key string) (client.PutOptions, error) {
+	switch {
+	case secretsAddIfNotExists:
+		return client.PutOptions{IfNotExists: true}, nil
+	case cmd.Flags().Changed("expected-version"):
+		if secretsAddExpectedVersion < 1 {
+			return client.PutOptions{}, fmt.Errorf("--expected-version must be 1 or more")
+		}
+		return client.PutOptions{IfVersion: secretsAddExpectedVersion}, nil
+	case !cmd.Flags().Changed("expected-value"):
+		return client.PutOptions{}, nil
+	}
+
+	secret, err := c.GetSecret(workspace.ID, key)
+	var apiErr *client.APIError
+	if errors.As(err, &apiErr) && apiErr.Status == http.StatusNotFound {
+		return client.PutOptions{}, fmt.Errorf("%s doesn't exist: %w", key, client.ErrPreconditionFailed)
+	}
+	if err != nil {
+		return client.PutOptions{}, fmt.Errorf("failed to get %s: %w", key, err)
+	}
+	current, err := openSecret(workspaceKey, workspace, secretsEnv, secret)
+	if err != nil {
+		return client.PutOptions{}, fmt.Errorf("failed to read %s: %w", key, err)
+	}
+	if subtle.ConstantTimeCompare(current, []byte(secretsAddExpectedValue)) != 1 {
+		return client.PutOptions{}, fmt.Errorf("%s doesn't hold the expected value: %w", key, client.ErrPreconditionFailed)
+	}
+	return client.PutOptions{IfVersion: secret.Version}, nil
+}
+
+func runSecretsAdd(cmd *cobra.Command, args []string) error {
+	if slices.Contains(args, "-") {
+		if len(args) > 1 {
+			return fmt.Errorf("❌ - reads every secret from stdin; don't give other secrets with it")
+		}
+		if addConditionSet(cmd) || cmd.Flags().Changed("ttl") {
+			return fmt.Errorf("❌ --if-not-exists, --expected-version, --expected-value and --ttl don't apply to secrets read from stdin")
+		}
+		return runSecretsAddStdin(cmd)
+	}
+	if len(args) > 1 && (cmd.Flags().Changed("expected-version") || cmd.Flags().Changed("expected-value")) {
+		return fmt.Errorf("❌ --expected-version and --expected-value guard one secret; give only one")
+	}
+	if cmd.Flags().Changed("ttl") && secretsAddTTL <= 0 {
+		return fmt.Errorf("❌ --ttl must be positive, got %s", secretsAddTTL)
+	}
+
+	type entry struct{ key, value string }
+	entries := make([]entry, 0, len(args))
+	for _, arg := range args {
+		key, value, hasValue, err := parseSecretArg(arg)
+		if err != nil {
+			return fmt.Errorf("❌ %w", err)
+		}
+		if !hasValue {
+			if value, err = currentPasswordReader().ReadPassword(key); err != nil {
+				return fmt.Errorf("❌ Failed to read value for %s: %w", key, err)
+			}
+		}
+		entries = append(entries, entry{key, value})
+	}
+
+	store := storage.New()
+	if err := ensureSecretsAccess(store); err != nil {
+		return fmt.Errorf("❌ %w", err)
+	}
+
+	var expiresAt time.Time
+	if secretsAddTTL > 0 {
+		expiresAt = serverNow(store, time.Now()).Add(secretsAddTTL)
+	}
+	// queueRest queues the entries from i on after the server couldn't be
+	// reached; guarded writes need the server, so they are never queued
+	queueRest := func(cause error, i int) error {
+		if !offline(cause) || addConditionSet(cmd) {
+			return cause
+		}
+		keys := make([]string, 0, len(entries)-i)
+		values := make(map[string]string, len(entries)-i)
+		for _, e := range entries[i:] {
+			keys = append(keys, e.key)
+			values[e.key] = e.value
+		}
+		return queuePuts(store, cause, keys, values, expiresAt)
+	}
+
+	c, err := newSecretsClient(secretsEnv, client.WithCache())
+	if err != nil {
+		return err
+	}
+	workspace, workspaceKey, err := openWorkspace(c, store, secretsWorkspace)
+	if err != nil {
+		return queueRest(err, 0)
+	}
+
+	stored := make([]*client.Secret, 0, len(entries))
+	for i, e := range entries {
+		ciphertext, err := encryptSecret(workspaceKey, workspace.ID, secretsEnv, e.key, []byte(e.value))
+		if err != nil {
+			return fmt.Errorf("❌ Failed to encrypt %s: %w", e.key, err)
+		}
+
+		opts, err := addCondition(cmd, c, workspace, workspaceKey, e.key)
+		if err != nil {
+			return fmt.Errorf("❌ %w", err)
+		}
+		opts.ExpiresAt = expiresAt
+		opts.Checksum = secretChecksum(workspaceKey, workspace.ID, secretsEnv, e.key, []byte(e.value))
+		secret, err := c.PutSecretWith(workspace.ID, e.key, ciphertext, len(e.value), workspace.KeyVersion, opts)
+		if err != nil {
+			return queueRest(fmt.Errorf("❌ Failed to store %s: %w", e.key, err), i)
+		}
+		stored = append(stored, secret)
+
+		infof("✅ Stored %s in %s\n", e.key, secretsLocation(workspace.Slug, secretsEnv))
+	}
+
+	if structuredOutput() {
+		return writeOutput(stored)
+	}
+	return nil
+}
+
+// getSecretValue decrypts the current secret, or the --version one, asking
+// the agent first when it is running
+func getSecretValue(key string) ([]byte, error) {
+	if secretsGetVersion == 0 {
+		_, values, err := secretsFromAgent(agent.Request{Workspace: secretsWorkspace, Env: secretsEnv, Key: key})
+		if !errors.Is(err, agent.ErrNotRunning) {
+			if err != nil {
+				return nil, fmt.Errorf("❌ %w", err)
+			}
+			return []byte(values[key]), nil
+		}
+	}
+
+	store := storage.New()
+	if err := ensureSecretsAccess(store); err != nil {
+		return nil, fmt.Errorf("❌ %w", err)
+	}
+
+	c, err := newSecretsClient(secretsEnv)
+	if err != nil {
+		return nil, err
+	}
+	workspace, workspaceKey, err := openWorkspace(c, store, secretsWorkspace)
+	if err != nil {
+		return nil, err
+	}
+
+	var secret *client.Secret
+	if secretsGetVersion > 0 {
+		secret, err = c.GetSecretVersion(workspace.ID, key, secretsGetVersion)
+	} else {
+		secret, err = c.GetSecret(workspace.ID, key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("❌ Failed to get %s: %w", key, err)
+	}
+
+	value, err := openSecret(workspaceKey, workspace, secretsEnv, secret)
+	if err != nil {
+		return nil, fmt.Errorf("❌ Failed to read %s: %w", key, err)
+	}
+	return value, nil
+}
+
+func runSecretsGet(cmd *cobra.Command, args []string) error {
+	if err := validateEnvPrefix("--only-prefixed", secretsGetOnlyPrefix); err != nil {
+		return fmt.Errorf("❌ %w", err)
+	}
+	key := secretsGetOnlyPrefix + args[0]
+	if err := validateSecretKey(key); err != nil {
+		return fmt.Errorf("❌ %w", err)
+	}
+	if secretsGetOut == "" && (secretsGetMkdir || secretsGetForce) {
+		return fmt.Errorf("❌ --mkdir and --force need --out")
+	}
+	if secretsGetOut != "" {
+		if err := checkGetOut(secretsGetOut); err != nil {
+			return fmt.Errorf("❌ %w", err)
+		}
+	}
+
+	value, err := getSecretValue(key)
+	if err != nil {
+		return err
+	}
+
+	if secretsGetOut != "" {
+		return writeSecretFile(args[0], value)
+	}
+
+	if structuredOutput() {
+		return writeOutput(map[string]string{"key": args[0], "value": string(value)})
+	}
+
+	out := cmd.OutOrStdout()
+	if _, err := out.Write(value); err != nil {
+		return fmt.Errorf("failed to write %s: %w", key, err)
+	}
+	if secretsGetNewline || isTerminal(out) {
+		fmt.Fprintln(out)
+	}
+	return nil
+}
+
+// checkGetOut refuses an --out path that exists without --force, or whose
+// folder is missing without --mkdir, before anything is downloaded
+func checkGetOut(path string) error {
+	if _, err := os.Lstat(path); err == nil && !secretsGetForce {
+		return fmt.Errorf("%s already exists; re-run with --force to replace it", path)
+	}
+	dir := filepath.Dir(path)
+	if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) && !secretsGetMkdir {
+		return fmt.Errorf("folder %s doesn't exist; re-run with --mkdir to create it", dir)
+	}
+	return nil
+}
+
+// writeSecretFile writes a secret value to --out with owner-only permissions,
+// replacing the file atomically so a reader never sees half a key
+func writeSecretFile(name string, value []byte) error {
+	if secretsGetMkdir {
+		if err := os.MkdirAll(filepath.Dir(secretsGetOut), secretsOutDirPermissions); err != nil {
+			return fmt.Errorf("❌ Failed to create %s: %w", filepath.Dir(secretsGetOut), err)
+		}
+	}
+	err := fsutil.WriteFileAtomic(secretsGetOut, fsutil.PrivateFilePermissions, func(w io.Writer) error {
+		if _, err := w.Write(value); err != nil {
+			return err
+		}
+		if secretsGetNewline {
+			_, err := io.WriteString(w, "\n")
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("❌ Failed to write %s: %w", secretsGetOut, err)
+	}
+
+	if structuredOutput() {
+		return writeOutput(map[string]string{"key": name, "out": secretsGetOut})
+	}
+	infof("✅ Wrote %s to %s\n", name, secretsGetOut)
+	return nil
+}
+
+// secretExpired reports whether a secret stored with a TTL is past it
+func secretExpired(secret client.Secret, now time.Time) bool {
+	expiresAt, err := time.Parse(time.RFC3339, secret.ExpiresAt)
+	return err == nil && !expiresAt.After(now)
+}
+
+// formatSecretExpiry renders the time a secret has left, e.g. "in 3h20m", with
+// a warning once less than secretExpiryWarning remains; "never" without a TTL
+func formatSecretExpiry(expiresAt string, now time.Time) string {
+	if expiresAt == "" {
+		return "never"
+	}
+	at, err := time.Parse(time.RFC3339, expiresAt)
+	if err != nil {
+		return expiresAt
+	}
+
+	remaining := at.Sub(now).Round(time.Minute)
+	switch {
+	case !at.After(now):
+		return fmt.Sprintf("⚠️  expired %s ago", shortDuration(-remaining))
+	case remaining < secretExpiryWarning:
+		return fmt.Sprintf("⚠️  in %s", shortDuration(remaining))
+	default:
+		return fmt.Sprintf("in %s", shortDuration(remaining))
+	}
+}
+
+// shortDuration renders a duration rounded to minutes without zero units,
+// e.g. 3h20m, 2h or 45m, and days for anything longer than two
+func shortDuration(d time.Duration) string {
+	if d < time.Minute {
+		return "<1m"
+	}
+	if d > 48*time.Hour {
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+	s := strings.TrimSuffix(d.Round(time.Minute).String(), "0s")
+	return strings.Replace(s, "h0m", "h", 1)
+}
+
+// onlyPrefixed keeps the secrets whose key, below folder, starts with
+// prefix, and strips the prefix from their keys
+func onlyPrefixed(secrets []client.Secret, folder, prefix string) []client.Secret {
+	kept := secrets[:0]
+	for _, secret := range secrets {
+		name, ok := strings.CutPrefix(strings.TrimPrefix(secret.Key, folder), prefix)
+		if !ok || name == "" {
+			continue
+		}
+		secret.Key = folder + name
+		kept = append(kept, secret)
+	}
+	return kept
+}
+
+// listedSecrets applies the list filters to a page of secrets and drops
+// their ciphertexts and checksums, which mean nothing without the key
+func listedSecrets(secrets []client.Secret, folder string, now time.Time) []client.Secret {
+	if secretsListOnlyPrefix != "" {
+		secrets = onlyPrefixed(secrets, folder, secretsListOnlyPrefix)
+	}
+	if !secretsListIncludeExpired {
+		secrets = slices.DeleteFunc(secrets, func(secret client.Secret) bool { return secretExpired(secret, now) })
+	}
+	for i := range secrets {
+		secrets[i].Ciphertext, secrets[i].Checksum = "", ""
+	}
+	return secrets
+}
+
+// streamSecrets prints the secrets under folder as NDJSON, one page at a
+// time, so memory stays flat however many there are
+func streamSecrets(w io.Writer, c *client.Client, workspaceID int, folder string, now time.Time) error {
+	encoder := json.NewEncoder(w)
+	return c.ListSecretPages(workspaceID, folder, secretsListPageSize, func(page []client.Secret) error {
+		for _, secret := range listedSecrets(page, folder, now) {
+			if err := encoder.Encode(secret); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func runSecretsList(cmd *cobra.Command, args []string) error {
+	columns, err := selectColumns(secretColumns, secretsListTable.columns)
+	if err != nil {
+		return err
+	}
+	if secretsListPageSize < 0 || secretsListPageSize > maxSecretsPageSize {
+		return fmt.Errorf("❌ --page-size must be between 1 and %d", maxSecretsPageSize)
+	}
+	prefix, err := parseSecretPath(secretsListPath)
+	if err != nil {
+		return fmt.Errorf("❌ %w", err)
+	}
+	if err := validateEnvPrefix("--only-prefixed", secretsListOnlyPrefix); err != nil {
+		return fmt.Errorf("❌ %w", err)
+	}
+
+	store := storage.New()
+	if err := requireAPIAccess(store); err != nil {
+		return err
+	}
+
+	c, err := newSecretsClient(secretsEnv)
+	if err != nil {
+		return err
+	}
+	workspace, err := c.GetWorkspaceBySlug(secretsWorkspace)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to get workspace info: %w", err)
+	}
+
+	now := serverNow(store, time.Now())
+	if secretsListNDJSON {
+		if err := streamSecrets(stdout(), c, workspace.ID, prefix, now); err != nil {
+			return fmt.Errorf("❌ Failed to list secrets: %w", err)
+		}
+		return nil
+	}
+
+	// Tables and trees size themselves to every row, so the whole list is
+	// collected before printing
+	var secrets []client.Secret
+	err = c.ListSecretPages(workspace.ID, prefix, secretsListPageSize, func(page []client.Secret) error {
+		secrets = append(secrets, listedSecrets(page, prefix, now)...)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("❌ Failed to list secrets: %w", err)
+	}
+	sort.Slice(secrets, func(i, j int) bool { return secrets[i].Key < secrets[j].Key })
+
+	if structuredOutput() {
+		return writeOutput(secrets)
+	}
+
+	if len(secrets) == 0 && prefix != "" {
+		infof("No secrets under %s in %s\n", prefix, secretsLocation(workspace.Slug, secretsEnv))
+		return nil
+	}
+	if len(secrets) == 0 {
+		infof("No secrets in %s. Add one with 'initflow secrets add -w %s KEY=VALUE'\n",
+			secretsLocation(workspace.Slug, secretsEnv), workspace.Slug)
+		return nil
+	}
+
+	if secretsListTree {
+		keys := make([]string, len(secrets))
+		for i, secret := range secrets {
+			keys[i] = strings.TrimPrefix(secret.Key, prefix)
+		}
+		root := prefix
+		if root == "" {
+			root = "."
+		}
+		writeSecretTree(cmd.OutOrStdout(), root, keys)
+		return nil
+	}
+
+	rows := make([]map[string]string, len(secrets))
+	for i, secret := range secrets {
+		rows[i] = map[string]string{
+			"key":         secret.Key,
+			"size":        fmt.Sprintf("%d B", secret.Size),
+			"created":     orUnknown(secret.CreatedAt),
+			"updated":     orUnknown(secret.UpdatedAt),
+			"expires":     formatSecretExpiry(secret.ExpiresAt, now),
+			"key-version": strconv.Itoa(secret.KeyVersion),
+			"environment": orDefault(secret.Environment),
+			"tags":        strings.Join(secret.Tags, ","),
+		}
+	}
+	writeTable(cmd.OutOrStdout(), columns, rows, !secretsListTable.noHeader)
+
+	return nil
+}
+
+func runSecretsRm(cmd *cobra.Command, args []string) error {
+	for _, key := range args {
+		if err := validateSecretKey(key); err != nil {
+			return fmt.Errorf("❌ %w", err)
+		}
+	}
+
+	store := storage.New()
+	if err := requireAPIAccess(store); err != nil {
+		return err
+	}
+
+	c, err := newSecretsClient(secretsEnv)
+	if err != nil {
+		return err
+	}
+	workspace, err := c.GetWorkspaceBySlug(secretsWorkspace)
+	if err != nil {
+		err = fmt.Errorf("❌ Failed to get workspace info: %w", err)
+		if !offline(err) {
+			return err
+		}
+		// Confirm against the workspace named, then queue the deletes
+		if err := confirmSecretsRm(args, secretsLocation(secretsWorkspace, secretsEnv)); err != nil {
+			return err
+		}
+		return queueDeletes(store, err, args)
+	}
+
+	if err := confirmSecretsRm(args, secretsLocation(workspace.Slug, secretsEnv)); err != nil {
+		return err
+	}
+
+	removed := make([]string, 0, len(args))
+	for i, key := range args {
+		if err := c.DeleteSecret(workspace.ID, key); err != nil {
+			err = fmt.Errorf("❌ Failed to delete %s: %w", key, err)
+			if offline(err) {
+				return queueDeletes(store, err, args[i:])
+			}
+			return err
+		}
+		removed = append(removed, key)
+		infof("🗑️  Removed %s from %s\n", key, secretsLocation(workspace.Slug, secretsEnv))
+	}
+
+	if structuredOutput() {
+		return writeOutput(map[string]any{"workspace": workspace.Slug, "environment": secretsEnv, "removed": removed})
+	}
+	return nil
+}
+
+// confirmSecretsRm asks before deleting keys from location unless --force is set
+func confirmSecretsRm(keys []string, location string) error {
+	if secretsRmForce {
+		return nil
+	}
+	names := strings.Join(keys, ", ")
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return fmt.Errorf("❌ Deleting %s from %s can't be undone. Re-run with --force to confirm", names, location)
+	}
+	proceed, err := askYesNo(bufio.NewReader(os.Stdin),
+		fmt.Sprintf("Delete %s from %s for every member? This can't be undone", names, location), false)
+	if err != nil {
+		return err
+	}
+	if !proceed {
+		return fmt.Errorf("ℹ️ Delete cancelled")
+	}
+	return nil
+}
+
+// readDotenv parses a dotenv file, or stdin for -, keeping the last value of
+// each key in first-seen order
+func readDotenv(path string) ([]dotenv.Entry, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path) // #nosec G304 - the user names the file to import
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return parseDotenv(path, data)
+}
+
+// parseDotenv parses dotenv data read from source like readDotenv. Every line
+// with an invalid key is reported, not just the first.
+func parseDotenv(source string, data []byte) ([]dotenv.Entry, error) {
+	entries, err := dotenv.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s %w", source, err)
+	}
+
+	var invalid []error
+	index := make(map[string]int, len(entries))
+	unique := make([]dotenv.Entry, 0, len(entries))
+	for _, entry := range entries {
+		if err := validateSecretKey(entry.Key); err != nil {
+			invalid = append(invalid, fmt.Errorf("%s line %d: %w", source, entry.Line, err))
+			continue
+		}
+		if i, ok := index[entry.Key]; ok {
+			unique[i] = entry
+			continue
+		}
+		index[entry.Key] = len(unique)
+		unique = append(unique, entry)
+	}
+	if len(invalid) > 0 {
+		return nil, errors.Join(invalid...)
+	}
+	return unique, nil
+}
+
+func runSecretsImport(cmd *cobra.Command, args []string) error {
+	path := args[0]
+	entries, err := readDotenv(path)
+	if err != nil {
+		return fmt.Errorf("❌ %w", err)
+	}
+	if len(entries) == 0 {
+		infof("ℹ️ No secrets found in %s\n", path)
+		return nil
+	}
+	return importEntries(path, entries)
+}
+
+// importEntries encrypts entries read from source and uploads them in one batch
+func importEntries(source string, entries []dotenv.Entry) error {
+	store := storage.New()
+	if err := ensureSecretsAccess(store); err != nil {
+		return fmt.Errorf("❌ %w", err)
+	}
+
+	c, err := newSecretsClient(secretsEnv)
+	if err != nil {
+		return err
+	}
+	workspace, workspaceKey, err := openWorkspace(c, store, secretsWorkspace)
+	if err != nil {
+		return err
+	}
+
+	infof("🔐 Encrypting %d secrets from %s...\n", len(entries), source)
+	keys := make([]string, len(entries))
+	values := make(map[string]string, len(entries))
+	for i, entry := range entries {
+		keys[i], values[entry.Key] = entry.Key, entry.Value
+	}
+	uploads, err := sealUploads(workspaceKey, workspace, secretsEnv, keys, values)
+	if err != nil {
+		return fmt.Errorf("❌ %w", err)
+	}
+
+	infoln("📡 Uploading...")
+	result, err := c.PutSecrets(workspace.ID, uploads)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to import secrets: %w", err)
+	}
+
+	if structuredOutput() {
+		return writeOutput(result)
+	}
+
+	infof("✅ Imported %d secrets into %s (%d created, %d updated)\n",
+		len(entries), secretsLocation(workspace.Slug, secretsEnv), len(result.Created), len(result.Updated))
+	for _, key := range result.Created {
+		infof("  + %s\n", key)
+	}
+	for _, key := range result.Updated {
+		infof("  ~ %s\n", key)
+	}
+	return nil
+}
+
+func validateExportFormat(format string) error {
+	if slices.Contains(exportFormats(), format) {
+		return nil
+	}
+	return fmt.Errorf("invalid --format %q: must be one of %s", format, strings.Join(exportFormats(), ", "))
+}
+
+// writeSecrets writes values sorted by key in the given export format
+func writeSecrets(w io.Writer, values map[string]string, format string) error {
+	if format == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(values)
+	}
+
+	entries := make([]dotenv.Entry, 0, len(values))
+	for key, value := range values {
+		entries = append(entries, dotenv.Entry{Key: key, Value: value})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	return dotenv.Write(w, entries)
+}
+
+func runSecretsExport(cmd *cobra.Command, args []string) error {
+	if err := validateExportFormat(secretsExportFormat); err != nil {
+		return err
+	}
+	if secretsExportTemplate != "" && cmd.Flags().Changed("format") {
+		return fmt.Errorf("❌ --template replaces --format; use one of them")
+	}
+	if secretsExportFormat == "snapshot" && secretsExportEnvPrefix != "" {
+		return fmt.Errorf("❌ a snapshot keeps the secrets' own keys; --env-prefix doesn't apply")
+	}
+	prefix, err := parseSecretPath(secretsExportPath)
+	if err != nil {
+		return fmt.Errorf("❌ %w", err)
+	}
+	if err := validateEnvPrefix("--env-prefix", secretsExportEnvPrefix); err != nil {
+		return fmt.Errorf("❌ %w", err)
+	}
+
+	workspace, secrets, err := loadSecrets(secretsWorkspace, secretsEnv, prefix)
+	if err != nil {
+		return err
+	}
+	values, err := variableNames(secrets, prefix)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to export secrets: %w", err)
+	}
+	values = withEnvPrefix(values, secretsExportEnvPrefix)
+
+	var workspaceKey []byte
+	if secretsExportFormat == "snapshot" {
+		if workspace, workspaceKey, err = snapshotWorkspaceKey(workspace.Slug, secretsEnv); err != nil {
+			return err
+		}
+	}
+
+	write := func(w io.Writer) error {
+		if secretsExportFormat == "snapshot" {
+			return writeSnapshot(w, workspace, workspaceKey, secretsEnv, prefix, secrets)
+		}
+		if _, named := exportTemplates[secretsExportFormat]; named || secretsExportTemplate != "" {
+			return renderSecrets(w, values, secretsExportFormat, secretsExportTemplate,
+				k8sSecretName(workspace.Slug, secretsEnv))
+		}
+		return writeSecrets(w, values, secretsExportFormat)
+	}
+
+	if secretsExportOut == "" {
+		// Render fully first, so a value a format can't hold leaves no partial output
+		var buf bytes.Buffer
+		if err := write(&buf); err != nil {
+			return fmt.Errorf("❌ Failed to write secrets: %w", err)
+		}
+		_, err := buf.WriteTo(cmd.OutOrStdout())
+		return err
+	}
+
+	err = fsutil.WriteFileAtomic(secretsExportOut, fsutil.PrivateFilePermissions, write)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to write %s: %w", secretsExportOut, err)
+	}
+
+	infof("✅ Exported %d secrets from %s to %s\n", len(values), secretsLocation(workspace.Slug, secretsEnv), secretsExportOut)
+	infof("⚠️  %s holds plaintext secrets. Keep it out of version control and delete it when done.\n", secretsExportOut)
+	return nil
+}
+
+func runSecretsHistory(cmd *cobra.Command, args []string) error {
+	key := args[0]
+	if err := validateSecretKey(key); err != nil {
+		return fmt.Errorf("❌ %w", err)
+	}
+
+	store := storage.New()
+	if err := requireAPIAccess(store); err != nil {
+		return err
+	}
+
+	c, err := newSecretsClient(secretsEnv)
+	if err != nil {
+		return err
+	}
+	workspace, err := c.GetWorkspaceBySlug(secretsWorkspace)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to get workspace info: %w", err)
+	}
+
+	versions, err := c.ListSecretVersions(workspace.ID, key)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to get history of %s: %w", key, err)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Version > versions[j].Version })
+
+	if structuredOutput() {
+		return writeOutput(versions)
+	}
+
+	columns := []tableColumn{
+		{Name: "version", Header: "Version"},
+		{Name: "size", Header: "Size"},
+		{Name: "key-version", Header: "Key Version"},
+		{Name: "created", Header: "Created"},
+	}
+	rows := make([]map[string]string, len(versions))
+	for i, version := range versions {
+		label := strconv.Itoa(version.Version)
+		if i == 0 {
+			label += " (current)"
+		}
+		rows[i] = map[string]string{
+			"version":     label,
+			"size":        fmt.Sprintf("%d B", version.Size),
+			"key-version": strconv.Itoa(version.KeyVersion),
+			"created":     orUnknown(version.CreatedAt),
+		}
+	}
+	writeTable(cmd.OutOrStdout(), columns, rows, true)
+
+	return nil
+}
+
+func runSecretsRollback(cmd *cobra.Command, args []string) error {
+	key := args[0]
+	if err := validateSecretKey(key); err != nil {
+		return fmt.Errorf("❌ %w", err)
+	}
+	if secretsRollbackVersion < 1 {
+		return fmt.Errorf("--version must be at least 1")
+	}
+
+	store := storage.New()
+	if err := ensureSecretsAccess(store); err != nil {
+		return fmt.Errorf("❌ %w", err)
+	}
+
+	c, err := newSecretsClient(secretsEnv)
+	if err != nil {
+		return err
+	}
+	workspace, workspaceKey, err := openWorkspace(c, store, secretsWorkspace)
+	if err != nil {
+		return err
+	}
+
+	old, err := c.GetSecretVersion(workspace.ID, key, secretsRollbackVersion)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to get version %d of %s: %w", secretsRollbackVersion, key, err)
+	}
+
+	value, err := openSecret(workspaceKey, workspace, secretsEnv, old)
+	if err != nil {
+		return fmt.Errorf("❌ Can't restore version %d of %s: %w", secretsRollbackVersion, key, err)
+	}
+
+	ciphertext, err := encryptSecret(workspaceKey, workspace.ID, secretsEnv, key, value)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to encrypt %s: %w", key, err)
+	}
+
+	secret, err := c.PutSecretWith(workspace.ID, key, ciphertext, len(value), workspace.KeyVersion,
+		client.PutOptions{Checksum: secretChecksum(workspaceKey, workspace.ID, secretsEnv, key, value)})
+	if err != nil {
+		return fmt.Errorf("❌ Failed to store %s: %w", key, err)
+	}
+
+	if structuredOutput() {
+		return writeOutput(secret)
+	}
+
+	infof("✅ Restored %s in %s to version %d, saved as version %d\n",
+		key, secretsLocation(workspace.Slug, secretsEnv), secretsRollbackVersion, secret.Version)
+	return nil
+}
@@ -0,0 +1,1200 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/chacha20poly1305"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/client"
+	"github.com/DylanBlakemore/initflow-cli/internal/encoding"
+	"github.com/DylanBlakemore/initflow-cli/internal/storage"
+)
+
+// secretsServer is a fake API holding the secrets of workspace 1, "my-project".
+// secrets has the current version of each key and versions every write, both
+// indexed by secretID. deviceKeys holds the workspace key wrapped to each of
+// devices, as uploaded by the last key rotation.
+type secretsServer struct {
+	mu         sync.Mutex
+	t          *testing.T
+	secrets    map[string]client.Secret
+	versions   map[string][]client.Secret
+	keyVersion int
+	devices    []client.Device
+	deviceKeys map[string]string
+	pages      int // paged list requests served
+	// idempotencyKeys are the keys of the mutations served, in order
+	idempotencyKeys []string
+	// forbidden are the secretIDs whose writes are refused with a 403
+	forbidden map[string]bool
+	// rotateInBackground answers key rotations with 202 and an operation
+	rotateInBackground bool
+	// liveTimestamps stamps writes with the current time, not a fixed one
+	liveTimestamps bool
+	batches        int // bulk PUT requests served
+}
+
+func newSecretsServer(t *testing.T) (*secretsServer, *httptest.Server) {
+	fake := &secretsServer{t: t, secrets: map[string]client.Secret{}, versions: map[string][]client.Secret{},
+		keyVersion: 1, deviceKeys: map[string]string{}}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	return fake, server
+}
+
+// secretID indexes the fake's maps: the key itself in the default environment,
+// else env:key
+func secretID(env, key string) string {
+	if env == "" {
+		return key
+	}
+	return env + ":" + key
+}
+
+// put stores a new version of key and reports whether the key already existed
+func (s *secretsServer) put(env, key, ciphertext string, size, keyVersion int) (client.Secret, bool) {
+	id := secretID(env, key)
+	_, existed := s.secrets[id]
+	secret := client.Secret{Key: key, Environment: env, Version: len(s.versions[id]) + 1, Ciphertext: ciphertext,
+		Size: size, KeyVersion: keyVersion, CreatedAt: "2025-10-01T12:00:00Z", UpdatedAt: "2025-10-01T12:00:00Z"}
+	if s.liveTimestamps {
+		secret.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+		secret.UpdatedAt = secret.CreatedAt
+	}
+	s.secrets[id] = secret
+	s.versions[id] = append(s.versions[id], secret)
+	return secret, existed
+}
+
+// setChecksum records the checksum a client stored with the newest version of a secret
+func (s *secretsServer) setChecksum(secret client.Secret, checksum string) client.Secret {
+	id := secretID(secret.Environment, secret.Key)
+	secret.Checksum = checksum
+	s.secrets[id] = secret
+	s.versions[id][len(s.versions[id])-1] = secret
+	return secret
+}
+
+func notFound(w http.ResponseWriter, message string) {
+	w.WriteHeader(http.StatusNotFound)
+	json.NewEncoder(w).Encode(client.ErrorResponse{Error: "not_found", Message: message})
+}
+
+func (s *secretsServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != "GET" {
+		s.idempotencyKeys = append(s.idempotencyKeys, r.Header.Get(client.IdempotencyKeyHeader))
+	}
+
+	const collection = "/api/v1/workspaces/1/secrets"
+	env := r.URL.Query().Get("environment")
+	// Below the collection: KEY, KEY/versions or KEY/versions/N
+	var key, id string
+	var parts []string
+	if rest, ok := strings.CutPrefix(r.URL.EscapedPath(), collection+"/"); ok {
+		parts = strings.Split(rest, "/")
+		key, _ = url.PathUnescape(parts[0])
+		id = secretID(env, key)
+	}
+
+	if r.Method != "GET" && s.forbidden[id] {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(client.ErrorResponse{Error: "forbidden", Message: "Not allowed"})
+		return
+	}
+
+	switch {
+	case r.Method == "GET" && r.URL.Path == "/api/v1/workspaces":
+		json.NewEncoder(w).Encode(client.ListWorkspacesResponse{
+			Workspaces: []client.Workspace{{ID: 1, Slug: "my-project", Role: "Owner", KeyInitialized: true,
+				KeyVersion: s.keyVersion}},
+		})
+	case r.Method == "GET" && r.URL.Path == "/api/v1/workspaces/1/environments":
+		var envs []string
+		for _, secret := range s.secrets {
+			if secret.Environment != "" && !slices.Contains(envs, secret.Environment) {
+				envs = append(envs, secret.Environment)
+			}
+		}
+		json.NewEncoder(w).Encode(client.ListEnvironmentsResponse{Environments: envs})
+	case r.Method == "GET" && r.URL.Path == "/api/v1/workspaces/1/devices":
+		json.NewEncoder(w).Encode(client.ListDevicesResponse{Devices: s.devices})
+	case r.Method == "GET" && r.URL.Path == "/api/v1/workspaces/1/device-key":
+		holder := "test-device-123"
+		if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			holder = "token:" + token
+		}
+		wrapped, ok := s.deviceKeys[holder]
+		if !ok {
+			notFound(w, "No key for this device")
+			return
+		}
+		json.NewEncoder(w).Encode(client.DeviceKeyResponse{WrappedWorkspaceKey: wrapped, KeyVersion: s.keyVersion})
+	case r.Method == "POST" && r.URL.Path == "/api/v1/tokens":
+		var req client.CreateScopedTokenRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		s.deviceKeys["token:tok-secret"] = req.WrappedWorkspaceKey
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(client.ScopedTokenResponse{Token: client.ScopedToken{
+			ID: "tok-1", WorkspaceID: req.WorkspaceID, Scope: req.Scope, Token: "tok-secret",
+		}})
+	case r.Method == "GET" && r.URL.Path == "/api/v1/workspaces/1/recovery-key":
+		notFound(w, "No recovery key")
+	case r.Method == "POST" && r.URL.Path == "/api/v1/workspaces/1/rotate-key":
+		var req client.RotateWorkspaceKeyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.t.Errorf("Failed to decode rotation: %v", err)
+		}
+		if req.FromKeyVersion != s.keyVersion {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(client.ErrorResponse{Error: "conflict", Message: "Key version changed"})
+			return
+		}
+		for _, upload := range req.Secrets {
+			s.put(upload.Environment, upload.Key, upload.Ciphertext, upload.Size, upload.KeyVersion)
+		}
+		s.keyVersion = req.KeyVersion
+		s.deviceKeys = map[string]string{}
+		for _, deviceKey := range req.DeviceKeys {
+			s.deviceKeys[deviceKey.DeviceID] = deviceKey.WrappedWorkspaceKey
+		}
+		if s.rotateInBackground {
+			w.WriteHeader(http.StatusAccepted)
+			json.NewEncoder(w).Encode(client.OperationResponse{
+				Operation: client.Operation{ID: "op-rotate", Status: client.OperationRunning},
+			})
+		}
+	case r.Method == "GET" && r.URL.Path == "/api/v1/operations/op-rotate":
+		json.NewEncoder(w).Encode(client.OperationResponse{
+			Operation: client.Operation{ID: "op-rotate", Status: client.OperationSucceeded},
+		})
+	case r.Method == "GET" && r.URL.Path == collection:
+		secrets := make([]client.Secret, 0, len(s.secrets))
+		for _, secret := range s.secrets {
+			if secret.Environment != env || !strings.HasPrefix(secret.Key, r.URL.Query().Get("prefix")) {
+				continue
+			}
+			if r.URL.Query().Get("include") != "ciphertext" {
+				secret.Ciphertext = ""
+			}
+			secrets = append(secrets, secret)
+		}
+		// Page by key when asked, with the offset as the cursor
+		resp := client.ListSecretsResponse{Secrets: secrets}
+		if limit, _ := strconv.Atoi(r.URL.Query().Get("limit")); limit > 0 {
+			sort.Slice(secrets, func(i, j int) bool { return secrets[i].Key < secrets[j].Key })
+			start, _ := strconv.Atoi(r.URL.Query().Get("cursor"))
+			end := min(start+limit, len(secrets))
+			resp.Secrets = secrets[start:end]
+			if end < len(secrets) {
+				resp.NextCursor = strconv.Itoa(end)
+			}
+			s.pages++
+		}
+		json.NewEncoder(w).Encode(resp)
+	case r.Method == "PUT" && r.URL.Path == collection:
+		var req client.PutSecretsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.t.Errorf("Failed to decode secrets: %v", err)
+		}
+		// The batch is atomic: one forbidden key refuses all of it
+		for _, upload := range req.Secrets {
+			if s.forbidden[secretID(env, upload.Key)] {
+				w.WriteHeader(http.StatusForbidden)
+				json.NewEncoder(w).Encode(client.ErrorResponse{Error: "forbidden", Message: "Not allowed"})
+				return
+			}
+		}
+		s.batches++
+		var resp client.PutSecretsResponse
+		for _, upload := range req.Secrets {
+			secret, existed := s.put(env, upload.Key, upload.Ciphertext, upload.Size, upload.KeyVersion)
+			secret = s.setChecksum(secret, upload.Checksum)
+			if upload.ExpiresAt != "" {
+				secret.ExpiresAt = upload.ExpiresAt
+				s.secrets[secretID(env, upload.Key)] = secret
+			}
+			if existed {
+				resp.Updated = append(resp.Updated, upload.Key)
+			} else {
+				resp.Created = append(resp.Created, upload.Key)
+			}
+		}
+		json.NewEncoder(w).Encode(resp)
+	case r.Method == "GET" && len(parts) == 2 && parts[1] == "versions":
+		versions, ok := s.versions[id]
+		if !ok {
+			notFound(w, "Secret not found")
+			return
+		}
+		listed := make([]client.Secret, len(versions))
+		for i, version := range versions {
+			version.Ciphertext = ""
+			listed[i] = version
+		}
+		json.NewEncoder(w).Encode(client.ListSecretVersionsResponse{Versions: listed})
+	case r.Method == "GET" && len(parts) == 3 && parts[1] == "versions":
+		n, _ := strconv.Atoi(parts[2])
+		versions := s.versions[id]
+		if n < 1 || n > len(versions) {
+			notFound(w, "Version not found")
+			return
+		}
+		json.NewEncoder(w).Encode(client.SecretResponse{Secret: versions[n-1]})
+	case r.Method == "GET" && len(parts) == 1:
+		secret, ok := s.secrets[id]
+		if !ok {
+			notFound(w, "Secret not found")
+			return
+		}
+		json.NewEncoder(w).Encode(client.SecretResponse{Secret: secret})
+	case r.Method == "DELETE" && len(parts) == 1:
+		if _, ok := s.secrets[id]; !ok {
+			notFound(w, "Secret not found")
+			return
+		}
+		delete(s.secrets, id)
+		delete(s.versions, id)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == "PUT" && len(parts) == 1:
+		var req client.PutSecretRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.t.Errorf("Failed to decode secret: %v", err)
+		}
+		current, exists := s.secrets[id]
+		if (r.Header.Get("If-None-Match") == "*" && exists) ||
+			(r.Header.Get("If-Match") != "" && r.Header.Get("If-Match") != strconv.Quote(strconv.Itoa(current.Version))) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			json.NewEncoder(w).Encode(client.ErrorResponse{Error: "precondition_failed", Message: "Secret changed"})
+			return
+		}
+		secret, _ := s.put(env, key, req.Ciphertext, req.Size, req.KeyVersion)
+		secret = s.setChecksum(secret, req.Checksum)
+		if req.ExpiresAt != "" {
+			secret.ExpiresAt = req.ExpiresAt
+			s.secrets[id] = secret
+		}
+		json.NewEncoder(w).Encode(client.SecretResponse{Secret: secret})
+	case r.Method == "PATCH" && len(parts) == 1:
+		var req client.UpdateSecretTagsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.t.Errorf("Failed to decode tags: %v", err)
+		}
+		secret, ok := s.secrets[id]
+		if !ok {
+			notFound(w, "Secret not found")
+			return
+		}
+		tags := slices.DeleteFunc(slices.Clone(secret.Tags), func(tag string) bool { return slices.Contains(req.RemoveTags, tag) })
+		for _, tag := range req.AddTags {
+			if !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+		secret.Tags = tags
+		s.secrets[id] = secret
+		json.NewEncoder(w).Encode(client.SecretResponse{Secret: secret})
+	default:
+		s.t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// setupSecretsTest points the CLI at a fake secrets API and gives this device
+// a key for my-project
+func setupSecretsTest(t *testing.T) (*secretsServer, []byte) {
+	fake, server := newSecretsServer(t)
+	setupTestEnvironment(t, server.URL)
+
+	workspaceKey := make([]byte, encoding.WorkspaceKeySize)
+	rand.Read(workspaceKey)
+	store := storage.New()
+	if err := store.StoreWorkspaceKey("my-project", workspaceKey); err != nil {
+		t.Fatalf("Failed to store workspace key: %v", err)
+	}
+	t.Cleanup(func() { store.DeleteWorkspaceKey("my-project") })
+
+	secretsWorkspace = "my-project"
+	t.Cleanup(func() { secretsWorkspace = "" })
+
+	return fake, workspaceKey
+}
+
+func TestSecretsAddEncryptsOnDevice(t *testing.T) {
+	fake, workspaceKey := setupSecretsTest(t)
+
+	if err := runSecretsAdd(secretsAddCmd, []string{"API_KEY=s3cret=value", "EMPTY="}); err != nil {
+		t.Fatalf("runSecretsAdd failed: %v", err)
+	}
+
+	secret, ok := fake.secrets["API_KEY"]
+	if !ok {
+		t.Fatal("Expected API_KEY to be uploaded")
+	}
+	if strings.Contains(secret.Ciphertext, "s3cret") || secret.Size != len("s3cret=value") || secret.KeyVersion != 1 {
+		t.Errorf("Unexpected upload %+v", secret)
+	}
+
+	sealed, err := encoding.Decode(secret.Ciphertext)
+	if err != nil {
+		t.Fatalf("Failed to decode ciphertext: %v", err)
+	}
+	cipher, _ := chacha20poly1305.New(workspaceKey)
+	nonce, ciphertext := sealed[:encoding.ChaCha20NonceSize], sealed[encoding.ChaCha20NonceSize:]
+	plaintext, err := cipher.Open(nil, nonce, ciphertext, secretAssociatedData(1, "", "API_KEY"))
+	if err != nil || string(plaintext) != "s3cret=value" {
+		t.Errorf("Expected the workspace key to decrypt the value, got %q, %v", plaintext, err)
+	}
+	if _, err := cipher.Open(nil, nonce, ciphertext, secretAssociatedData(1, "", "OTHER_KEY")); err == nil {
+		t.Error("Expected the ciphertext to be bound to its key")
+	}
+
+	if fake.secrets["EMPTY"].Size != 0 {
+		t.Errorf("Expected an empty value to be stored, got %+v", fake.secrets["EMPTY"])
+	}
+}
+
+func TestSecretsAddPromptsForBareKey(t *testing.T) {
+	fake, _ := setupSecretsTest(t)
+	usePasswordReader(t, &stubPasswordReader{password: "from-prompt"})
+
+	if err := runSecretsAdd(secretsAddCmd, []string{"TOKEN"}); err != nil {
+		t.Fatalf("runSecretsAdd failed: %v", err)
+	}
+	if fake.secrets["TOKEN"].Size != len("from-prompt") {
+		t.Errorf("Expected the prompted value to be stored, got %+v", fake.secrets["TOKEN"])
+	}
+}
+
+func TestSecretsAddFromStdin(t *testing.T) {
+	fake, _ := setupSecretsTest(t)
+	t.Cleanup(func() { secretsAddCmd.SetIn(nil) })
+
+	// KEY=- is a literal value, not the stdin mode
+	if err := runSecretsAdd(secretsAddCmd, []string{"DASH=-"}); err != nil {
+		t.Fatalf("runSecretsAdd failed: %v", err)
+	}
+	if fake.secrets["DASH"].Size != 1 {
+		t.Errorf("Expected DASH to hold a literal -, got %+v", fake.secrets["DASH"])
+	}
+
+	secretsAddCmd.SetIn(strings.NewReader("# comment\n\nAPI_KEY=abc\nexport DB_URL=\"postgres://db\"\nAPI_KEY=abcd\n"))
+	var err error
+	out := captureStdout(t, func() { err = runSecretsAdd(secretsAddCmd, []string{"-"}) })
+	if err != nil {
+		t.Fatalf("runSecretsAdd - failed: %v", err)
+	}
+	if !strings.Contains(out, "Imported 2 secrets into my-project (2 created, 0 updated)") {
+		t.Errorf("Expected a final count, got %q", out)
+	}
+	if fake.secrets["API_KEY"].Size != len("abcd") || fake.secrets["DB_URL"].Size != len("postgres://db") {
+		t.Errorf("Expected the last value of each key, got %+v", fake.secrets)
+	}
+	if _, ok := fake.secrets["-"]; ok {
+		t.Error("Expected - not to be stored as a key")
+	}
+
+	secretsAddCmd.SetIn(strings.NewReader("GOOD=1\n1BAD=2\nALSO_GOOD=3\nbad-key=4\n"))
+	err = runSecretsAdd(secretsAddCmd, []string{"-"})
+	if err == nil || !strings.Contains(err.Error(), "stdin line 2: invalid secret key") ||
+		!strings.Contains(err.Error(), "stdin line 4: invalid secret key") {
+		t.Errorf("Expected every invalid line to be reported, got %v", err)
+	}
+	if _, ok := fake.secrets["GOOD"]; ok {
+		t.Error("Expected nothing stored when a line is invalid")
+	}
+
+	err = runSecretsAdd(secretsAddCmd, []string{"-", "OTHER=1"})
+	if err == nil || !strings.Contains(err.Error(), "don't give other secrets with it") {
+		t.Errorf("Expected - to stand alone, got %v", err)
+	}
+}
+
+// resetAddGuards clears the secrets add guard flags
+func resetAddGuards() {
+	for _, name := range []string{"if-not-exists", "expected-version", "expected-value"} {
+		flag := secretsAddCmd.Flags().Lookup(name)
+		_ = flag.Value.Set(flag.DefValue)
+		flag.Changed = false
+	}
+}
+
+func TestSecretsAddGuards(t *testing.T) {
+	fake, _ := setupSecretsTest(t)
+	t.Cleanup(resetAddGuards)
+	setFlag := func(name, value string) {
+		resetAddGuards()
+		if err := secretsAddCmd.Flags().Set(name, value); err != nil {
+			t.Fatalf("Failed to set --%s: %v", name, err)
+		}
+	}
+
+	setFlag("if-not-exists", "true")
+	if err := runSecretsAdd(secretsAddCmd, []string{"API_KEY=first"}); err != nil {
+		t.Fatalf("Expected --if-not-exists to create API_KEY: %v", err)
+	}
+	err := runSecretsAdd(secretsAddCmd, []string{"API_KEY=second"})
+	if !errors.Is(err, client.ErrPreconditionFailed) || exitCodeFor(err) != exitPrecondition {
+		t.Errorf("Expected --if-not-exists to refuse an existing key, got %v", err)
+	}
+
+	setFlag("expected-version", "2")
+	err = runSecretsAdd(secretsAddCmd, []string{"API_KEY=second"})
+	if !errors.Is(err, client.ErrPreconditionFailed) {
+		t.Errorf("Expected a stale --expected-version to fail, got %v", err)
+	}
+	setFlag("expected-version", "1")
+	if err := runSecretsAdd(secretsAddCmd, []string{"API_KEY=second"}); err != nil {
+		t.Fatalf("Expected the current --expected-version to succeed: %v", err)
+	}
+
+	setFlag("expected-value", "first")
+	err = runSecretsAdd(secretsAddCmd, []string{"API_KEY=third"})
+	if !errors.Is(err, client.ErrPreconditionFailed) || exitCodeFor(err) != exitPrecondition {
+		t.Errorf("Expected a wrong --expected-value to fail, got %v", err)
+	}
+	setFlag("expected-value", "second")
+	if err := runSecretsAdd(secretsAddCmd, []string{"API_KEY=third"}); err != nil {
+		t.Fatalf("Expected the current --expected-value to succeed: %v", err)
+	}
+	if secret := fake.secrets["API_KEY"]; secret.Version != 3 || secret.Size != len("third") {
+		t.Errorf("Expected exactly the guarded writes to land, got %+v", secret)
+	}
+
+	err = runSecretsAdd(secretsAddCmd, []string{"MISSING=x"})
+	if !errors.Is(err, client.ErrPreconditionFailed) {
+		t.Errorf("Expected --expected-value on a missing key to fail, got %v", err)
+	}
+	err = runSecretsAdd(secretsAddCmd, []string{"API_KEY=x", "OTHER=y"})
+	if err == nil || !strings.Contains(err.Error(), "give only one") {
+		t.Errorf("Expected --expected-value to guard one secret, got %v", err)
+	}
+}
+
+func TestSecretsAddRejectsBadKeysAndMissingWorkspaceKey(t *testing.T) {
+	fake, _ := setupSecretsTest(t)
+
+	for _, key := range []string{"1BAD", "backend/", "/API_KEY", "backend//API_KEY", "backend/1db/PASSWORD"} {
+		err := runSecretsAdd(secretsAddCmd, []string{key + "=value"})
+		if err == nil || !strings.Contains(err.Error(), "invalid secret key") {
+			t.Errorf("Expected an invalid key error for %q, got %v", key, err)
+		}
+	}
+
+	secretsWorkspace = "other-project"
+	err := runSecretsAdd(secretsAddCmd, []string{"API_KEY=value"})
+	if err == nil || !strings.Contains(err.Error(), "no key for other-project") {
+		t.Errorf("Expected a missing workspace key error, got %v", err)
+	}
+
+	if len(fake.secrets) != 0 {
+		t.Errorf("Expected nothing uploaded, got %v", fake.secrets)
+	}
+}
+
+func TestSecretsGetPrintsOnlyThePlaintext(t *testing.T) {
+	setupSecretsTest(t)
+
+	if err := runSecretsAdd(secretsAddCmd, []string{"DATABASE_URL=postgres://u:p@db/app"}); err != nil {
+		t.Fatalf("runSecretsAdd failed: %v", err)
+	}
+
+	var err error
+	out := captureStdout(t, func() {
+		err = runSecretsGet(secretsGetCmd, []string{"DATABASE_URL"})
+	})
+	if err != nil {
+		t.Fatalf("runSecretsGet failed: %v", err)
+	}
+	if out != "postgres://u:p@db/app" {
+		t.Errorf("Expected only the plaintext with no newline off a terminal, got %q", out)
+	}
+
+	err = runSecretsGet(secretsGetCmd, []string{"MISSING"})
+	if exitCodeFor(err) != exitNotFound {
+		t.Errorf("Expected a missing secret to exit %d, got %v", exitNotFound, err)
+	}
+}
+
+func TestSecretsGetOut(t *testing.T) {
+	setupSecretsTest(t)
+	t.Cleanup(func() {
+		secretsGetOut, secretsGetMkdir, secretsGetForce, secretsGetNewline = "", false, false, false
+	})
+
+	captureStdout(t, func() {
+		if err := runSecretsAdd(secretsAddCmd, []string{"TLS_KEY=-----BEGIN KEY-----"}); err != nil {
+			t.Fatalf("runSecretsAdd failed: %v", err)
+		}
+	})
+
+	path := filepath.Join(t.TempDir(), "certs", "tls.key")
+	secretsGetOut = path
+	if err := runSecretsGet(secretsGetCmd, []string{"TLS_KEY"}); err == nil || !strings.Contains(err.Error(), "--mkdir") {
+		t.Errorf("Expected a missing folder to need --mkdir, got %v", err)
+	}
+
+	secretsGetMkdir = true
+	var err error
+	out := captureStdout(t, func() { err = runSecretsGet(secretsGetCmd, []string{"TLS_KEY"}) })
+	if err != nil {
+		t.Fatalf("runSecretsGet --out failed: %v", err)
+	}
+	if strings.Contains(out, "BEGIN KEY") {
+		t.Errorf("Expected the value only in the file, got %q", out)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "-----BEGIN KEY-----" {
+		t.Errorf("Expected the value without a newline, got %q, %v", data, err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected an owner-only file, got %v, %v", info.Mode(), err)
+	}
+	if info, err := os.Stat(filepath.Dir(path)); err != nil || info.Mode().Perm() != 0700 {
+		t.Errorf("Expected an owner-only folder, got %v, %v", info.Mode(), err)
+	}
+
+	if err := os.WriteFile(path, []byte("keep"), 0600); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+	if err := runSecretsGet(secretsGetCmd, []string{"TLS_KEY"}); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("Expected an existing file to need --force, got %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "keep" {
+		t.Errorf("Expected the existing file left alone, got %q", data)
+	}
+
+	secretsGetForce, secretsGetNewline = true, true
+	captureStdout(t, func() { err = runSecretsGet(secretsGetCmd, []string{"TLS_KEY"}) })
+	if data, _ := os.ReadFile(path); err != nil || string(data) != "-----BEGIN KEY-----\n" {
+		t.Errorf("Expected --force --newline to replace the file with a newline, got %q, %v", data, err)
+	}
+}
+
+func TestSecretsGetRejectsSwappedCiphertext(t *testing.T) {
+	fake, _ := setupSecretsTest(t)
+
+	if err := runSecretsAdd(secretsAddCmd, []string{"A=first", "B=second"}); err != nil {
+		t.Fatalf("runSecretsAdd failed: %v", err)
+	}
+	swapped := fake.secrets["B"]
+	swapped.Ciphertext = fake.secrets["A"].Ciphertext
+	fake.secrets["B"] = swapped
+
+	err := runSecretsGet(secretsGetCmd, []string{"B"})
+	if err == nil || !strings.Contains(err.Error(), "ciphertext was altered") {
+		t.Fatalf("Expected a ciphertext moved between keys to fail to decrypt, got %v", err)
+	}
+}
+
+func TestSecretsListShowsMetadataOnly(t *testing.T) {
+	fake, _ := setupSecretsTest(t)
+	t.Cleanup(func() { secretsListTable = tableOptions{} })
+
+	if err := runSecretsAdd(secretsAddCmd, []string{"ZETA=last", "ALPHA=first-value"}); err != nil {
+		t.Fatalf("runSecretsAdd failed: %v", err)
+	}
+	if err := storage.New().DeleteWorkspaceKey("my-project"); err != nil {
+		t.Fatalf("Failed to delete workspace key: %v", err)
+	}
+
+	var err error
+	out := captureStdout(t, func() {
+		err = runSecretsList(secretsListCmd, []string{})
+	})
+	if err != nil {
+		t.Fatalf("runSecretsList failed without the workspace key: %v", err)
+	}
+	if strings.Index(out, "ALPHA") > strings.Index(out, "ZETA") {
+		t.Errorf("Expected secrets sorted by key, got %q", out)
+	}
+	for _, want := range []string{"Key", "Size", "Updated", "11 B", "2025-10-01T12:00:00Z"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in secrets list, got %q", want, out)
+		}
+	}
+	for _, secret := range fake.secrets {
+		if strings.Contains(out, secret.Ciphertext) || strings.Contains(out, "first-value") {
+			t.Errorf("Expected no values or ciphertexts in secrets list, got %q", out)
+		}
+	}
+
+	secretsListTable = tableOptions{columns: []string{"key"}, noHeader: true}
+	out = captureStdout(t, func() {
+		err = runSecretsList(secretsListCmd, []string{})
+	})
+	if err != nil || out != "ALPHA\nZETA\n" {
+		t.Errorf("Expected bare keys with --columns key --no-header, got %q, %v", out, err)
+	}
+}
+
+func TestSecretsRm(t *testing.T) {
+	fake, _ := setupSecretsTest(t)
+	t.Cleanup(func() { secretsRmForce = false })
+
+	if err := runSecretsAdd(secretsAddCmd, []string{"A=1", "B=2", "C=3"}); err != nil {
+		t.Fatalf("runSecretsAdd failed: %v", err)
+	}
+
+	err := runSecretsRm(secretsRmCmd, []string{"A"})
+	if err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Fatalf("Expected deleting without a terminal to require --force, got %v", err)
+	}
+	if _, ok := fake.secrets["A"]; !ok {
+		t.Fatal("Expected nothing to be deleted without confirmation")
+	}
+
+	secretsRmForce = true
+	out := captureStdout(t, func() {
+		err = runSecretsRm(secretsRmCmd, []string{"A", "B"})
+	})
+	if err != nil {
+		t.Fatalf("runSecretsRm failed: %v", err)
+	}
+	if len(fake.secrets) != 1 || !strings.Contains(out, "Removed A from my-project") || !strings.Contains(out, "Removed B") {
+		t.Errorf("Expected A and B to be removed and reported, got %v and %q", fake.secrets, out)
+	}
+
+	err = runSecretsRm(secretsRmCmd, []string{"A"})
+	if exitCodeFor(err) != exitNotFound {
+		t.Errorf("Expected deleting a missing secret to exit %d, got %v", exitNotFound, err)
+	}
+}
+
+func TestSecretsImport(t *testing.T) {
+	setupSecretsTest(t)
+
+	if err := runSecretsAdd(secretsAddCmd, []string{"API_KEY=old"}); err != nil {
+		t.Fatalf("runSecretsAdd failed: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), ".env")
+	content := "# app\nAPI_KEY=new\nexport CERT=\"line1\nline2\"\nDEBUG=false # dev only\nDEBUG=true\n"
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write .env: %v", err)
+	}
+
+	var err error
+	out := captureStdout(t, func() {
+		err = runSecretsImport(secretsImportCmd, []string{path})
+	})
+	if err != nil {
+		t.Fatalf("runSecretsImport failed: %v", err)
+	}
+	for _, want := range []string{"Imported 3 secrets into my-project (2 created, 1 updated)", "+ CERT", "+ DEBUG", "~ API_KEY"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in output, got %q", want, out)
+		}
+	}
+
+	for key, want := range map[string]string{"API_KEY": "new", "CERT": "line1\nline2", "DEBUG": "true"} {
+		out := captureStdout(t, func() {
+			err = runSecretsGet(secretsGetCmd, []string{key})
+		})
+		if err != nil || out != want {
+			t.Errorf("Expected %s to decrypt to %q, got %q, %v", key, want, out, err)
+		}
+	}
+}
+
+func TestSecretsImportRejectsBadFiles(t *testing.T) {
+	dir := t.TempDir()
+	tests := map[string]string{
+		"bad-key":      "GOOD=1\n1BAD=2\n",
+		"unterminated": "A=\"open\n",
+	}
+	wants := map[string]string{
+		"bad-key":      "line 2: invalid secret key \"1BAD\"",
+		"unterminated": "line 1: unterminated",
+	}
+
+	for name, content := range tests {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		err := runSecretsImport(secretsImportCmd, []string{path})
+		if err == nil || !strings.Contains(err.Error(), wants[name]) {
+			t.Errorf("%s: expected error containing %q, got %v", name, wants[name], err)
+		}
+	}
+}
+
+func TestSecretsExport(t *testing.T) {
+	setupSecretsTest(t)
+	t.Cleanup(func() {
+		secretsExportFormat = "dotenv"
+		secretsExportOut = ""
+	})
+
+	if err := runSecretsAdd(secretsAddCmd, []string{"PLAIN=abc", "MULTI=line1\nline2", "QUOTED=say \"hi\" $HOME"}); err != nil {
+		t.Fatalf("runSecretsAdd failed: %v", err)
+	}
+
+	var err error
+	out := captureStdout(t, func() {
+		err = runSecretsExport(secretsExportCmd, []string{})
+	})
+	if err != nil {
+		t.Fatalf("runSecretsExport failed: %v", err)
+	}
+	want := "MULTI=\"line1\\nline2\"\nPLAIN=abc\nQUOTED=\"say \\\"hi\\\" \\$HOME\"\n"
+	if out != want {
+		t.Errorf("Expected sorted, quoted dotenv output\n got: %q\nwant: %q", out, want)
+	}
+
+	path := filepath.Join(t.TempDir(), ".env")
+	secretsExportOut = path
+	secretsExportFormat = "json"
+	if err := runSecretsExport(secretsExportCmd, []string{}); err != nil {
+		t.Fatalf("runSecretsExport --out failed: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("Expected %s to be written owner-only, got %v, %v", path, info, err)
+	}
+	data, _ := os.ReadFile(path)
+	var values map[string]string
+	if err := json.Unmarshal(data, &values); err != nil || values["MULTI"] != "line1\nline2" || len(values) != 3 {
+		t.Errorf("Expected the JSON export to hold every value, got %s, %v", data, err)
+	}
+
+	secretsExportFormat = "yaml"
+	if err := runSecretsExport(secretsExportCmd, []string{}); err == nil || !strings.Contains(err.Error(), "dotenv, json") {
+		t.Errorf("Expected an unknown format to be rejected, got %v", err)
+	}
+
+	secretsExportFormat, secretsExportOut = "k8s", ""
+	out = captureStdout(t, func() { err = runSecretsExport(secretsExportCmd, []string{}) })
+	if err != nil || !strings.Contains(out, "name: my-project\n") || !strings.Contains(out, "  PLAIN: YWJj\n") {
+		t.Errorf("Expected a Secret named after the workspace, got %q, %v", out, err)
+	}
+
+	secretsExportFormat, secretsExportTemplate = "dotenv", "{{ .Key }}"
+	t.Cleanup(func() { secretsExportTemplate = "" })
+	out = captureStdout(t, func() { err = runSecretsExport(secretsExportCmd, []string{}) })
+	if err != nil || out != "MULTI\nPLAIN\nQUOTED\n" {
+		t.Errorf("Expected --template to render each secret, got %q, %v", out, err)
+	}
+}
+
+func TestSecretsListYAML(t *testing.T) {
+	setupSecretsTest(t)
+	outputFormat = outputYAML
+	t.Cleanup(func() { outputFormat = outputTable })
+
+	var err error
+	captureStdout(t, func() {
+		err = runSecretsAdd(secretsAddCmd, []string{"API_KEY=abc"})
+	})
+	if err != nil {
+		t.Fatalf("runSecretsAdd failed: %v", err)
+	}
+
+	out := captureStdout(t, func() {
+		err = runSecretsList(secretsListCmd, []string{})
+	})
+	if err != nil {
+		t.Fatalf("runSecretsList failed: %v", err)
+	}
+	want := "- key: API_KEY\n  version: 1\n  size: 3\n  key_version: 1\n  created_at: \"2025-10-01T12:00:00Z\"\n  updated_at: \"2025-10-01T12:00:00Z\"\n"
+	if out != want {
+		t.Errorf("Expected only YAML on stdout\n got: %q\nwant: %q", out, want)
+	}
+}
+
+func TestSecretsHistoryAndRollback(t *testing.T) {
+	fake, _ := setupSecretsTest(t)
+	t.Cleanup(func() {
+		secretsGetVersion = 0
+		secretsRollbackVersion = 0
+	})
+
+	for _, value := range []string{"v1", "second", "third!"} {
+		if err := runSecretsAdd(secretsAddCmd, []string{"API_KEY=" + value}); err != nil {
+			t.Fatalf("runSecretsAdd failed: %v", err)
+		}
+	}
+
+	var err error
+	out := captureStdout(t, func() {
+		err = runSecretsHistory(secretsHistoryCmd, []string{"API_KEY"})
+	})
+	if err != nil {
+		t.Fatalf("runSecretsHistory failed: %v", err)
+	}
+	if !strings.Contains(out, "3 (current)") || strings.Index(out, "3 (current)") > strings.Index(out, "2 ") ||
+		!strings.Contains(out, "6 B") {
+		t.Errorf("Expected versions newest first with the current one marked, got %q", out)
+	}
+
+	secretsGetVersion = 1
+	out = captureStdout(t, func() {
+		err = runSecretsGet(secretsGetCmd, []string{"API_KEY"})
+	})
+	if err != nil || out != "v1" {
+		t.Errorf("Expected get --version 1 to print v1, got %q, %v", out, err)
+	}
+	secretsGetVersion = 0
+
+	secretsRollbackVersion = 1
+	out = captureStdout(t, func() {
+		err = runSecretsRollback(secretsRollbackCmd, []string{"API_KEY"})
+	})
+	if err != nil {
+		t.Fatalf("runSecretsRollback failed: %v", err)
+	}
+	if !strings.Contains(out, "Restored API_KEY in my-project to version 1, saved as version 4") {
+		t.Errorf("Unexpected rollback output %q", out)
+	}
+	if len(fake.versions["API_KEY"]) != 4 || fake.secrets["API_KEY"].Size != 2 {
+		t.Errorf("Expected the old value to be saved as a new version, got %+v", fake.secrets["API_KEY"])
+	}
+
+	out = captureStdout(t, func() {
+		err = runSecretsGet(secretsGetCmd, []string{"API_KEY"})
+	})
+	if err != nil || out != "v1" {
+		t.Errorf("Expected the current value to be v1 after rollback, got %q, %v", out, err)
+	}
+
+	secretsRollbackVersion = 9
+	err = runSecretsRollback(secretsRollbackCmd, []string{"API_KEY"})
+	if exitCodeFor(err) != exitNotFound {
+		t.Errorf("Expected rolling back to a missing version to exit %d, got %v", exitNotFound, err)
+	}
+}
+
+func TestSecretsRollbackRejectsOldKeyVersion(t *testing.T) {
+	fake, _ := setupSecretsTest(t)
+	t.Cleanup(func() { secretsRollbackVersion = 0 })
+
+	if err := runSecretsAdd(secretsAddCmd, []string{"API_KEY=new"}); err != nil {
+		t.Fatalf("runSecretsAdd failed: %v", err)
+	}
+	fake.put("", "API_KEY", "c2VhbGVk", 3, 1)
+	fake.versions["API_KEY"][1].KeyVersion = 7
+
+	secretsRollbackVersion = 2
+	err := runSecretsRollback(secretsRollbackCmd, []string{"API_KEY"})
+	if err == nil || !strings.Contains(err.Error(), "encrypted with workspace key version 7, but this device holds version 1") {
+		t.Fatalf("Expected a key version mismatch, got %v", err)
+	}
+}
+
+func TestSecretsEnvironments(t *testing.T) {
+	fake, _ := setupSecretsTest(t)
+	t.Cleanup(func() {
+		secretsEnv = ""
+		runEnv = ""
+		runWorkspace = ""
+	})
+
+	if err := runSecretsAdd(secretsAddCmd, []string{"DB_URL=postgres://dev"}); err != nil {
+		t.Fatalf("runSecretsAdd failed: %v", err)
+	}
+	secretsEnv = "prod"
+	var err error
+	out := captureStdout(t, func() {
+		err = runSecretsAdd(secretsAddCmd, []string{"DB_URL=postgres://prod"})
+	})
+	if err != nil || !strings.Contains(out, "Stored DB_URL in my-project (prod)") {
+		t.Fatalf("Expected the prod value to be stored, got %q, %v", out, err)
+	}
+	if fake.secrets["prod:DB_URL"].Environment != "prod" || len(fake.secrets) != 2 {
+		t.Fatalf("Expected one DB_URL per environment, got %v", fake.secrets)
+	}
+
+	out = captureStdout(t, func() {
+		err = runSecretsGet(secretsGetCmd, []string{"DB_URL"})
+	})
+	if err != nil || out != "postgres://prod" {
+		t.Errorf("Expected the prod value with --env prod, got %q, %v", out, err)
+	}
+
+	runWorkspace, runEnv = "my-project", "prod"
+	out = captureStdout(t, func() {
+		err = runRun(runCmd, []string{"sh", "-c", `printf %s "$DB_URL"`})
+	})
+	if err != nil || out != "postgres://prod" {
+		t.Errorf("Expected run --env prod to inject the prod value, got %q, %v", out, err)
+	}
+
+	// A value moved between environments by the server must not decrypt
+	moved := fake.secrets["DB_URL"]
+	moved.Environment = "prod"
+	fake.secrets["prod:DB_URL"] = moved
+	err = runSecretsGet(secretsGetCmd, []string{"DB_URL"})
+	if err == nil || !strings.Contains(err.Error(), "ciphertext was altered") {
+		t.Errorf("Expected a value copied from another environment to be rejected, got %v", err)
+	}
+
+	secretsEnv = "Prod!"
+	if err := runSecretsGet(secretsGetCmd, []string{"DB_URL"}); err == nil || !strings.Contains(err.Error(), "invalid environment") {
+		t.Errorf("Expected a bad environment name to be rejected, got %v", err)
+	}
+}
+
+func TestSecretsPaths(t *testing.T) {
+	fake, _ := setupSecretsTest(t)
+	t.Cleanup(func() {
+		secretsListPath = ""
+		secretsListTree = false
+		secretsListTable = tableOptions{}
+		secretsExportPath = ""
+	})
+
+	err := runSecretsAdd(secretsAddCmd, []string{
+		"backend/db/PASSWORD=hunter2", "backend/db/USER=app", "backend/API_URL=https://api", "FRONTEND_URL=https://web",
+	})
+	if err != nil {
+		t.Fatalf("runSecretsAdd failed: %v", err)
+	}
+	if _, ok := fake.secrets["backend/db/PASSWORD"]; !ok {
+		t.Fatalf("Expected the path to be stored as one key, got %v", fake.secrets)
+	}
+
+	secretsListPath = "backend"
+	secretsListTable = tableOptions{columns: []string{"key"}, noHeader: true}
+	out := captureStdout(t, func() {
+		err = runSecretsList(secretsListCmd, []string{})
+	})
+	if err != nil || out != "backend/API_URL\nbackend/db/PASSWORD\nbackend/db/USER\n" {
+		t.Errorf("Expected only the backend folder with --path backend, got %q, %v", out, err)
+	}
+
+	secretsListPath, secretsListTree = "", true
+	out = captureStdout(t, func() {
+		err = runSecretsList(secretsListCmd, []string{})
+	})
+	want := `.
+├── FRONTEND_URL
+└── backend/
+    ├── API_URL
+    └── db/
+        ├── PASSWORD
+        └── USER
+`
+	if err != nil || out != want {
+		t.Errorf("Expected a tree of folders\n got: %q\nwant: %q", out, want)
+	}
+
+	secretsExportPath = "backend/"
+	out = captureStdout(t, func() {
+		err = runSecretsExport(secretsExportCmd, []string{})
+	})
+	if err != nil || out != "API_URL=https://api\ndb_PASSWORD=hunter2\ndb_USER=app\n" {
+		t.Errorf("Expected the backend folder exported without its prefix, got %q, %v", out, err)
+	}
+
+	secretsExportPath = "backend/../"
+	if err := runSecretsExport(secretsExportCmd, []string{}); err == nil || !strings.Contains(err.Error(), "invalid --path") {
+		t.Errorf("Expected a bad path to be rejected, got %v", err)
+	}
+}
+
+func TestVariableNamesRejectsCollisions(t *testing.T) {
+	_, err := variableNames(map[string]string{"db/PASSWORD": "a", "db_PASSWORD": "b"}, "")
+	if err == nil || !strings.Contains(err.Error(), "db/PASSWORD and db_PASSWORD would both be named db_PASSWORD") {
+		t.Errorf("Expected a name collision error, got %v", err)
+	}
+}
+
+func TestSecretsEnvPrefix(t *testing.T) {
+	setupSecretsTest(t)
+	runWorkspace = "my-project"
+	t.Cleanup(func() {
+		runWorkspace, runEnvPrefix = "", ""
+		secretsExportEnvPrefix, secretsListOnlyPrefix, secretsGetOnlyPrefix = "", "", ""
+		outputFormat = outputTable
+	})
+
+	captureStdout(t, func() {
+		if err := runSecretsAdd(secretsAddCmd, []string{"APP_DB_URL=postgres://db", "APP_=bare", "DEBUG=true"}); err != nil {
+			t.Fatalf("runSecretsAdd failed: %v", err)
+		}
+	})
+
+	var err error
+	secretsExportEnvPrefix = "MY_"
+	out := captureStdout(t, func() { err = runSecretsExport(secretsExportCmd, nil) })
+	if err != nil || out != "MY_APP_=bare\nMY_APP_DB_URL=postgres://db\nMY_DEBUG=true\n" {
+		t.Errorf("Expected every exported name prefixed, got %q, %v", out, err)
+	}
+
+	runEnvPrefix = "MY_"
+	out = captureStdout(t, func() {
+		err = runRun(runCmd, []string{"sh", "-c", `printf '%s|%s' "$MY_DEBUG" "$DEBUG"`})
+	})
+	if err != nil || out != "true|" {
+		t.Errorf("Expected run to set only prefixed names, got %q, %v", out, err)
+	}
+
+	for _, prefix := range []string{"1APP", "APP-", "APP/"} {
+		secretsExportEnvPrefix, runEnvPrefix = prefix, prefix
+		if err := runSecretsExport(secretsExportCmd, nil); err == nil || !strings.Contains(err.Error(), "invalid --env-prefix") {
+			t.Errorf("Expected export to reject %q, got %v", prefix, err)
+		}
+		if err := runRun(runCmd, []string{"true"}); err == nil || !strings.Contains(err.Error(), "invalid --env-prefix") {
+			t.Errorf("Expected run to reject %q, got %v", prefix, err)
+		}
+	}
+
+	secretsListOnlyPrefix = "APP_"
+	outputFormat = outputJSON
+	out = captureStdout(t, func() { err = runSecretsList(secretsListCmd, nil) })
+	var listed []client.Secret
+	if err != nil || json.Unmarshal([]byte(out), &listed) != nil || len(listed) != 1 || listed[0].Key != "DB_URL" {
+		t.Errorf("Expected only APP_DB_URL, listed as DB_URL, got %q, %v", out, err)
+	}
+
+	secretsGetOnlyPrefix = "APP_"
+	outputFormat = outputTable
+	out = captureStdout(t, func() { err = runSecretsGet(secretsGetCmd, []string{"DB_URL"}) })
+	if err != nil || out != "postgres://db" {
+		t.Errorf("Expected DB_URL to read APP_DB_URL, got %q, %v", out, err)
+	}
+}
+
+func TestFormatSecretExpiry(t *testing.T) {
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		expiresAt string
+		want      string
+	}{
+		{"", "never"},
+		{"2025-10-08T12:00:00Z", "in 7d"},
+		{"2025-10-02T15:20:00Z", "in 27h20m"},
+		{"2025-10-01T14:00:00Z", "⚠️  in 2h"},
+		{"2025-10-01T12:45:10Z", "⚠️  in 45m"},
+		{"2025-10-01T12:00:20Z", "⚠️  in <1m"},
+		{"2025-10-01T11:30:00Z", "⚠️  expired 30m ago"},
+		{"not a time", "not a time"},
+	}
+	for _, tt := range tests {
+		if got := formatSecretExpiry(tt.expiresAt, now); got != tt.want {
+			t.Errorf("formatSecretExpiry(%q) = %q, want %q", tt.expiresAt, got, tt.want)
+		}
+	}
+}
+
+func TestSecretsTTL(t *testing.T) {
+	fake, _ := setupSecretsTest(t)
+	t.Cleanup(func() {
+		secretsListIncludeExpired = false
+		flag := secretsAddCmd.Flags().Lookup("ttl")
+		_ = flag.Value.Set(flag.DefValue)
+		flag.Changed = false
+	})
+
+	if err := secretsAddCmd.Flags().Set("ttl", "0s"); err != nil {
+		t.Fatalf("Failed to set --ttl: %v", err)
+	}
+	if err := runSecretsAdd(secretsAddCmd, []string{"TOKEN=abc"}); err == nil || !strings.Contains(err.Error(), "--ttl must be positive") {
+		t.Errorf("Expected a zero --ttl to be rejected, got %v", err)
+	}
+
+	if err := secretsAddCmd.Flags().Set("ttl", "2h"); err != nil {
+		t.Fatalf("Failed to set --ttl: %v", err)
+	}
+	captureStdout(t, func() {
+		if err := runSecretsAdd(secretsAddCmd, []string{"DEPLOY_TOKEN=abc"}); err != nil {
+			t.Fatalf("runSecretsAdd --ttl failed: %v", err)
+		}
+	})
+	expiresAt, err := time.Parse(time.RFC3339, fake.secrets["DEPLOY_TOKEN"].ExpiresAt)
+	if remaining := time.Until(expiresAt); err != nil || remaining < time.Hour || remaining > 2*time.Hour {
+		t.Errorf("Expected DEPLOY_TOKEN to expire in 2h, got %q", fake.secrets["DEPLOY_TOKEN"].ExpiresAt)
+	}
+
+	fake.put("", "OLD_TOKEN", "sealed", 3, 1)
+	old := fake.secrets["OLD_TOKEN"]
+	old.ExpiresAt = "2025-10-01T12:00:00Z"
+	fake.secrets["OLD_TOKEN"] = old
+	fake.put("", "STATIC", "sealed", 3, 1)
+
+	out := captureStdout(t, func() { err = runSecretsList(secretsListCmd, nil) })
+	if err != nil {
+		t.Fatalf("runSecretsList failed: %v", err)
+	}
+	for _, want := range []string{"Expires", "⚠️  in 2h", "never"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in secrets list, got %q", want, out)
+		}
+	}
+	if strings.Contains(out, "OLD_TOKEN") {
+		t.Errorf("Expected expired secrets to be hidden, got %q", out)
+	}
+
+	secretsListIncludeExpired = true
+	out = captureStdout(t, func() { err = runSecretsList(secretsListCmd, nil) })
+	if err != nil || !strings.Contains(out, "OLD_TOKEN") || !strings.Contains(out, "expired") {
+		t.Errorf("Expected --include-expired to list OLD_TOKEN as expired, got %q, %v", out, err)
+	}
+}
+
+func TestSecretsListPages(t *testing.T) {
+	fake, _ := setupSecretsTest(t)
+	t.Cleanup(func() { secretsListPageSize, secretsListNDJSON = 0, false })
+
+	for _, key := range []string{"E_KEY", "A_KEY", "D_KEY", "B_KEY", "C_KEY"} {
+		fake.put("", key, "sealed", 3, 1)
+	}
+	expired := fake.secrets["C_KEY"]
+	expired.ExpiresAt = "2025-10-01T12:00:00Z"
+	fake.secrets["C_KEY"] = expired
+
+	secretsListPageSize, secretsListNDJSON = 2, true
+	var err error
+	out := captureStdout(t, func() { err = runSecretsList(secretsListCmd, nil) })
+	if err != nil {
+		t.Fatalf("runSecretsList --ndjson failed: %v", err)
+	}
+	if fake.pages != 3 {
+		t.Errorf("Expected 5 secrets in 3 pages of 2, got %d pages", fake.pages)
+	}
+
+	// One complete JSON object per line, with no array or indentation around them
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	var keys []string
+	for _, line := range lines {
+		var secret client.Secret
+		if err := json.Unmarshal([]byte(line), &secret); err != nil || !strings.HasPrefix(line, "{") {
+			t.Fatalf("Expected a JSON object per line, got %q in %q", line, out)
+		}
+		if secret.Ciphertext != "" {
+			t.Errorf("Expected no ciphertext in %q", line)
+		}
+		keys = append(keys, secret.Key)
+	}
+	if want := []string{"A_KEY", "B_KEY", "D_KEY", "E_KEY"}; !slices.Equal(keys, want) {
+		t.Errorf("Expected %v without the expired C_KEY, got %v", want, keys)
+	}
+
+	secretsListNDJSON = false
+	out = captureStdout(t, func() { err = runSecretsList(secretsListCmd, nil) })
+	if err != nil || strings.Index(out, "A_KEY") > strings.Index(out, "E_KEY") || !strings.Contains(out, "D_KEY") {
+		t.Errorf("Expected the table to hold every page sorted, got %q, %v", out, err)
+	}
+
+	secretsListPageSize = maxSecretsPageSize + 1
+	if err := runSecretsList(secretsListCmd, nil); err == nil || !strings.Contains(err.Error(), "--page-size must be between") {
+		t.Errorf("Expected an oversized --page-size to be rejected, got %v", err)
+	}
+}
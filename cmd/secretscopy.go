@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/storage"
+)
+
+var secretsCopyCmd = &cobra.Command{
+	Use:     "copy --from <env> --to <env> [KEY]... [--only KEY,...]",
+	Aliases: []string{"promote"},
+	Short:   "Copy secrets to another environment or workspace",
+	Long: "Decrypt the given secrets, or all of them, on this device and store them encrypted for the " +
+		"destination, with the destination workspace's key when it's another workspace. Each side is an " +
+		"environment of the --workspace workspace, or workspace:env for another workspace; 'default' names " +
+		"the default environment. Name the secrets as arguments or with --only. Secrets the destination " +
+		"already has are skipped and reported unless --overwrite is set. --dry-run shows what would be " +
+		"copied without uploading anything.",
+	Example: "  initflow secrets promote -w api --from staging --to prod STRIPE_KEY SENTRY_DSN\n" +
+		"  initflow secrets copy -w api --from prod --to web:prod --dry-run\n" +
+		"  initflow secrets copy --from api:staging --to web:staging --only DB_URL,REDIS_URL --overwrite",
+	RunE: runSecretsCopy,
+}
+
+var (
+	secretsCopyFrom      string
+	secretsCopyTo        string
+	secretsCopyOnly      []string
+	secretsCopyOverwrite bool
+	secretsCopyDryRun    bool
+)
+
+func init() {
+	secretsCmd.AddCommand(secretsCopyCmd)
+
+	secretsCopyCmd.Flags().StringVar(&secretsCopyFrom, "from", "", "environment, or workspace:env, to copy from")
+	secretsCopyCmd.Flags().StringVar(&secretsCopyTo, "to", "", "environment, or workspace:env, to copy to")
+	secretsCopyCmd.Flags().StringSliceVar(&secretsCopyOnly, "only", nil, "copy only these secrets (comma separated or repeated)")
+	secretsCopyCmd.Flags().BoolVar(&secretsCopyOverwrite, "overwrite", false, "replace secrets the destination already has")
+	secretsCopyCmd.Flags().BoolVar(&secretsCopyDryRun, "dry-run", false, "show what would be copied without uploading")
+	_ = secretsCopyCmd.MarkFlagRequired("from")
+	_ = secretsCopyCmd.MarkFlagRequired("to")
+}
+
+// secretsCopyPlan sorts the copied keys by what happens to them at the destination
+type secretsCopyPlan struct {
+	Create    []string `json:"create"`
+	Overwrite []string `json:"overwrite"`
+	Skip      []string `json:"skip"`
+	DryRun    bool     `json:"dry_run"`
+}
+
+// planSecretsCopy picks the keys to copy, all of source when none are named,
+// and checks them against the keys the destination already has
+func planSecretsCopy(source map[string]string, keys []string, existing map[string]bool, overwrite bool) (*secretsCopyPlan, error) {
+	if len(keys) == 0 {
+		for key := range source {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	keys = slices.Compact(keys)
+
+	plan := &secretsCopyPlan{Create: []string{}, Overwrite: []string{}, Skip: []string{}}
+	for _, key := range keys {
+		if _, ok := source[key]; !ok {
+			return nil, fmt.Errorf("secret %s not found", key)
+		}
+		switch {
+		case !existing[key]:
+			plan.Create = append(plan.Create, key)
+		case overwrite:
+			plan.Overwrite = append(plan.Overwrite, key)
+		default:
+			plan.Skip = append(plan.Skip, key)
+		}
+	}
+	return plan, nil
+}
+
+func runSecretsCopy(cmd *cobra.Command, args []string) error {
+	args = append(args, secretsCopyOnly...)
+	for _, key := range args {
+		if err := validateSecretKey(key); err != nil {
+			return fmt.Errorf("❌ %w", err)
+		}
+	}
+
+	fromSlug, fromEnv := parseSecretsSide(secretsCopyFrom, secretsWorkspace)
+	toSlug, toEnv := parseSecretsSide(secretsCopyTo, secretsWorkspace)
+	fromLocation, toLocation := secretsLocation(fromSlug, fromEnv), secretsLocation(toSlug, toEnv)
+	if fromSlug == toSlug && fromEnv == toEnv {
+		return fmt.Errorf("❌ --from and --to are both %s", fromLocation)
+	}
+
+	_, source, err := loadSecrets(fromSlug, fromEnv, "")
+	if err != nil {
+		return err
+	}
+
+	store := storage.New()
+	c, err := newSecretsClient(toEnv)
+	if err != nil {
+		return err
+	}
+	workspace, workspaceKey, err := openWorkspace(c, store, toSlug)
+	if err != nil {
+		return err
+	}
+
+	current, err := c.ListSecrets(workspace.ID, "")
+	if err != nil {
+		return fmt.Errorf("❌ Failed to list secrets in %s: %w", toLocation, err)
+	}
+	existing := make(map[string]bool, len(current))
+	for _, secret := range current {
+		existing[secret.Key] = true
+	}
+
+	plan, err := planSecretsCopy(source, args, existing, secretsCopyOverwrite)
+	if err != nil {
+		return fmt.Errorf("❌ %w in %s", err, fromLocation)
+	}
+	plan.DryRun = secretsCopyDryRun
+
+	keys := append(append([]string{}, plan.Create...), plan.Overwrite...)
+	if !secretsCopyDryRun && len(keys) > 0 {
+		infof("🔐 Encrypting %d secrets for %s...\n", len(keys), toLocation)
+		uploads, err := sealUploads(workspaceKey, workspace, toEnv, keys, source)
+		if err != nil {
+			return fmt.Errorf("❌ %w", err)
+		}
+
+		infoln("📡 Uploading...")
+		if _, err := c.PutSecrets(workspace.ID, uploads); err != nil {
+			return fmt.Errorf("❌ Failed to copy secrets: %w", err)
+		}
+	}
+
+	if structuredOutput() {
+		return writeOutput(plan)
+	}
+
+	if secretsCopyDryRun {
+		infof("🔍 Would copy %d secrets from %s to %s\n", len(keys), fromLocation, toLocation)
+	} else {
+		infof("✅ Copied %d secrets from %s to %s\n", len(keys), fromLocation, toLocation)
+	}
+	for _, key := range plan.Create {
+		infof("  + %s\n", key)
+	}
+	for _, key := range plan.Overwrite {
+		infof("  ~ %s\n", key)
+	}
+	if len(plan.Overwrite) > 0 && !secretsCopyDryRun {
+		infof("ℹ️ Overwrote %d secrets %s already had\n", len(plan.Overwrite), toLocation)
+	}
+	if len(plan.Skip) > 0 {
+		for _, key := range plan.Skip {
+			infof("  = %s\n", key)
+		}
+		infof("ℹ️ Skipped %d secrets %s already has; use --overwrite to replace them\n", len(plan.Skip), toLocation)
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/client"
+	"github.com/DylanBlakemore/initflow-cli/internal/encoding"
+)
+
+func TestSecretsCopy(t *testing.T) {
+	setupSecretsTest(t)
+	t.Cleanup(func() {
+		secretsEnv, secretsCopyFrom, secretsCopyTo = "", "", ""
+		secretsCopyOnly, secretsCopyOverwrite, secretsCopyDryRun = nil, false, false
+		outputFormat = outputTable
+	})
+
+	captureStdout(t, func() {
+		if err := runSecretsAdd(secretsAddCmd, []string{"DB_URL=postgres://staging", "API_KEY=abc", "DEBUG=true"}); err != nil {
+			t.Fatalf("runSecretsAdd failed: %v", err)
+		}
+		secretsEnv = "prod"
+		if err := runSecretsAdd(secretsAddCmd, []string{"DB_URL=postgres://prod"}); err != nil {
+			t.Fatalf("runSecretsAdd failed: %v", err)
+		}
+		secretsEnv = ""
+	})
+
+	secretsCopyFrom, secretsCopyTo = "default", "prod"
+	secretsCopyDryRun = true
+	var err error
+	out := captureStdout(t, func() { err = runSecretsCopy(secretsCopyCmd, []string{"API_KEY", "DB_URL"}) })
+	if err != nil {
+		t.Fatalf("runSecretsCopy failed: %v", err)
+	}
+	for _, want := range []string{"Would copy 1 secrets", "+ API_KEY", "= DB_URL", "--overwrite"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in the dry run, got %q", want, out)
+		}
+	}
+	_, prod, err := loadSecrets("my-project", "prod", "")
+	if err != nil {
+		t.Fatalf("loadSecrets failed: %v", err)
+	}
+	if _, ok := prod["API_KEY"]; ok {
+		t.Errorf("Expected --dry-run not to upload anything, got %v", prod)
+	}
+
+	// --only names secrets like the arguments do
+	secretsCopyDryRun, secretsCopyOnly = false, []string{"DB_URL"}
+	out = captureStdout(t, func() { err = runSecretsCopy(secretsCopyCmd, nil) })
+	if err != nil {
+		t.Fatalf("runSecretsCopy failed: %v", err)
+	}
+	for _, want := range []string{"Copied 0 secrets", "= DB_URL", "Skipped 1 secrets"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in the report, got %q", want, out)
+		}
+	}
+
+	secretsCopyOnly, secretsCopyOverwrite = nil, true
+	outputFormat = outputJSON
+	out = captureStdout(t, func() { err = runSecretsCopy(secretsCopyCmd, nil) })
+	if err != nil {
+		t.Fatalf("runSecretsCopy failed: %v", err)
+	}
+	var plan secretsCopyPlan
+	if err := json.Unmarshal([]byte(out), &plan); err != nil {
+		t.Fatalf("Expected JSON output, got %q: %v", out, err)
+	}
+	if len(plan.Create) != 2 || len(plan.Overwrite) != 1 || plan.Overwrite[0] != "DB_URL" {
+		t.Errorf("Expected two new secrets and DB_URL overwritten, got %+v", plan)
+	}
+	_, prod, err = loadSecrets("my-project", "prod", "")
+	if err != nil {
+		t.Fatalf("loadSecrets failed: %v", err)
+	}
+	if prod["DB_URL"] != "postgres://staging" || prod["API_KEY"] != "abc" || prod["DEBUG"] != "true" {
+		t.Errorf("Expected the copied secrets to decrypt in prod, got %v", prod)
+	}
+
+	err = runSecretsCopy(secretsCopyCmd, []string{"MISSING"})
+	if err == nil || !strings.Contains(err.Error(), "secret MISSING not found") {
+		t.Errorf("Expected copying a missing secret to fail, got %v", err)
+	}
+
+	secretsCopyTo = "my-project:default"
+	err = runSecretsCopy(secretsCopyCmd, nil)
+	if err == nil || !strings.Contains(err.Error(), "--from and --to are both") {
+		t.Errorf("Expected copying onto itself to fail, got %v", err)
+	}
+}
+
+func TestSealUploadsReencryptsForAnotherWorkspace(t *testing.T) {
+	sourceKey, destKey := make([]byte, encoding.WorkspaceKeySize), make([]byte, encoding.WorkspaceKeySize)
+	rand.Read(sourceKey)
+	rand.Read(destKey)
+	source := &client.Workspace{ID: 1, Slug: "api", KeyVersion: 1}
+	dest := &client.Workspace{ID: 2, Slug: "web", KeyVersion: 3}
+
+	sealed, err := encryptSecret(sourceKey, source.ID, "staging", "DB_URL", []byte("postgres://db"))
+	if err != nil {
+		t.Fatalf("encryptSecret failed: %v", err)
+	}
+	values, err := decryptSecrets(sourceKey, source, "staging", []client.Secret{
+		{Key: "DB_URL", Ciphertext: encoding.Encode(sealed), KeyVersion: 1},
+	})
+	if err != nil {
+		t.Fatalf("decryptSecrets failed: %v", err)
+	}
+
+	uploads, err := sealUploads(destKey, dest, "prod", []string{"DB_URL"}, values)
+	if err != nil {
+		t.Fatalf("sealUploads failed: %v", err)
+	}
+	if len(uploads) != 1 || uploads[0].KeyVersion != 3 || uploads[0].Size != len("postgres://db") {
+		t.Fatalf("Expected one upload under key version 3, got %+v", uploads)
+	}
+
+	copied := &client.Secret{Key: "DB_URL", Ciphertext: uploads[0].Ciphertext, KeyVersion: uploads[0].KeyVersion}
+	value, err := openSecret(destKey, dest, "prod", copied)
+	if err != nil || string(value) != "postgres://db" {
+		t.Errorf("Expected the copy to decrypt with the destination key, got %q, %v", value, err)
+	}
+	if _, err := openSecret(sourceKey, &client.Workspace{ID: 2, KeyVersion: 3}, "prod", copied); err == nil {
+		t.Error("Expected the source key not to decrypt the copy")
+	}
+	if _, err := openSecret(destKey, &client.Workspace{ID: 1, KeyVersion: 3}, "prod", copied); err == nil {
+		t.Error("Expected the copy to be bound to the destination workspace")
+	}
+}
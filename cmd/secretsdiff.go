@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var secretsDiffCmd = &cobra.Command{
+	Use:   "diff {--from <env> --to <env> | FILE [--to <env>]}",
+	Short: "Compare the secrets of two environments or workspaces, or a local file",
+	Long: "Decrypt the secrets on both sides and list the keys added, removed or changed going from --from " +
+		"to --to. Each side is an environment of the --workspace workspace, or workspace:env for another " +
+		"workspace; 'default' names the default environment. Values are only printed with --show-values. " +
+		"--exit-code exits with 1 when anything differs, e.g. to stop a release missing a prod secret.\n\n" +
+		"Given a dotenv or JSON file instead of --from, the file is compared with --to, or with the --env " +
+		"environment of the --workspace workspace, and the command exits with 1 when they differ, so it " +
+		"can gate CI before a sync.",
+	Example: "  initflow secrets diff -w api --from staging --to prod\n" +
+		"  initflow secrets diff -w api --from default --to web:default --show-values\n" +
+		"  initflow secrets diff -w api --env prod .env.prod",
+	Args: cobra.MaximumNArgs(1),
+	RunE: runSecretsDiff,
+}
+
+var (
+	secretsDiffFrom       string
+	secretsDiffTo         string
+	secretsDiffShowValues bool
+	secretsDiffExitCode   bool
+)
+
+// Kinds of change secrets diff reports
+const (
+	secretAdded   = "added"
+	secretRemoved = "removed"
+	secretChanged = "changed"
+)
+
+// secretDiffColumns are the columns of secrets diff; the values only show
+// with --show-values
+var secretDiffColumns = []tableColumn{
+	{Name: "change", Header: "Change", Default: true},
+	{Name: "key", Header: "Key", Default: true},
+	{Name: "from", Header: "From"},
+	{Name: "to", Header: "To"},
+}
+
+func init() {
+	secretsCmd.AddCommand(secretsDiffCmd)
+
+	secretsDiffCmd.Flags().StringVar(&secretsDiffFrom, "from", "", "environment, or workspace:env, to compare from")
+	secretsDiffCmd.Flags().StringVar(&secretsDiffTo, "to", "", "environment, or workspace:env, to compare to")
+	secretsDiffCmd.Flags().BoolVar(&secretsDiffShowValues, "show-values", false, "print the decrypted values that differ")
+	secretsDiffCmd.Flags().BoolVar(&secretsDiffExitCode, "exit-code", false,
+		"exit with 1 when the secrets differ (always on when comparing a file)")
+}
+
+// secretChange is a key that differs between the two sides of a diff. From
+// and To are only set when values are shown.
+type secretChange struct {
+	Key    string  `json:"key"`
+	Change string  `json:"change"`
+	From   *string `json:"from,omitempty"`
+	To     *string `json:"to,omitempty"`
+}
+
+// parseSecretsSide splits a --from or --to value into a workspace, defaulting
+// to the --workspace one, and an environment
+func parseSecretsSide(spec, workspace string) (string, string) {
+	if slug, env, ok := strings.Cut(spec, ":"); ok {
+		workspace, spec = slug, env
+	}
+	if spec == "default" {
+		spec = ""
+	}
+	return workspace, spec
+}
+
+// diffSecrets lists the keys added, removed or changed from one set of
+// secrets to another, sorted by key
+func diffSecrets(from, to map[string]string, showValues bool) []secretChange {
+	var changes []secretChange
+	for key, fromValue := range from {
+		toValue, ok := to[key]
+		switch {
+		case !ok:
+			changes = append(changes, secretChange{Key: key, Change: secretRemoved})
+		case toValue != fromValue:
+			changes = append(changes, secretChange{Key: key, Change: secretChanged})
+		default:
+			continue
+		}
+		if showValues {
+			changes[len(changes)-1].From = &fromValue
+			if ok {
+				changes[len(changes)-1].To = &toValue
+			}
+		}
+	}
+	for key, toValue := range to {
+		if _, ok := from[key]; !ok {
+			change := secretChange{Key: key, Change: secretAdded}
+			if showValues {
+				change.To = &toValue
+			}
+			changes = append(changes, change)
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes
+}
+
+// readSecretsFile reads the secrets of a local file: a JSON object of
+// strings for .json files, else dotenv
+func readSecretsFile(path string) (map[string]string, error) {
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		data, err := os.ReadFile(path) // #nosec G304 - the user names the file to compare
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		var values map[string]string
+		if err := json.Unmarshal(data, &values); err != nil {
+			return nil, fmt.Errorf("%s must be a JSON object of strings: %w", path, err)
+		}
+		for key := range values {
+			if err := validateSecretKey(key); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+		}
+		return values, nil
+	}
+
+	entries, err := readDotenv(path)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		values[entry.Key] = entry.Value
+	}
+	return values, nil
+}
+
+func runSecretsDiff(cmd *cobra.Command, args []string) error {
+	compareFile := len(args) == 1
+	switch {
+	case compareFile && secretsDiffFrom != "":
+		return fmt.Errorf("❌ --from can't be used with a file; the file is compared with --to")
+	case !compareFile && (secretsDiffFrom == "" || secretsDiffTo == ""):
+		return fmt.Errorf("❌ --from and --to are required unless a file is given")
+	}
+
+	toSlug, toEnv := secretsWorkspace, secretsEnv
+	if secretsDiffTo != "" {
+		toSlug, toEnv = parseSecretsSide(secretsDiffTo, secretsWorkspace)
+	}
+	toLocation := secretsLocation(toSlug, toEnv)
+
+	var fromLocation string
+	var fromSecrets map[string]string
+	if compareFile {
+		fromLocation = args[0]
+		values, err := readSecretsFile(args[0])
+		if err != nil {
+			return fmt.Errorf("❌ %w", err)
+		}
+		fromSecrets = values
+	} else {
+		fromSlug, fromEnv := parseSecretsSide(secretsDiffFrom, secretsWorkspace)
+		fromLocation = secretsLocation(fromSlug, fromEnv)
+		_, values, err := loadSecrets(fromSlug, fromEnv, "")
+		if err != nil {
+			return err
+		}
+		fromSecrets = values
+	}
+	_, toSecrets, err := loadSecrets(toSlug, toEnv, "")
+	if err != nil {
+		return err
+	}
+
+	changes := diffSecrets(fromSecrets, toSecrets, secretsDiffShowValues)
+
+	if structuredOutput() {
+		if changes == nil {
+			changes = []secretChange{}
+		}
+		if err := writeOutput(changes); err != nil {
+			return err
+		}
+	} else if len(changes) == 0 {
+		infof("✅ No differences between %s and %s\n", fromLocation, toLocation)
+	} else {
+		columns := secretDiffColumns[:2]
+		if secretsDiffShowValues {
+			columns = secretDiffColumns
+		}
+
+		rows := make([]map[string]string, len(changes))
+		counts := map[string]int{}
+		for i, change := range changes {
+			counts[change.Change]++
+			rows[i] = map[string]string{"change": change.Change, "key": change.Key}
+			if change.From != nil {
+				rows[i]["from"] = *change.From
+			}
+			if change.To != nil {
+				rows[i]["to"] = *change.To
+			}
+		}
+
+		infof("🔍 Comparing %s with %s\n", fromLocation, toLocation)
+		writeTable(cmd.OutOrStdout(), columns, rows, true)
+		infof("%d added, %d removed, %d changed\n", counts[secretAdded], counts[secretRemoved], counts[secretChanged])
+	}
+
+	if (secretsDiffExitCode || compareFile) && len(changes) > 0 {
+		return &silentExit{code: exitError}
+	}
+	return nil
+}
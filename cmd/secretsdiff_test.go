@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSecretsDiff(t *testing.T) {
+	setupSecretsTest(t)
+	t.Cleanup(func() {
+		secretsEnv, secretsDiffFrom, secretsDiffTo = "", "", ""
+		secretsDiffShowValues, secretsDiffExitCode = false, false
+		outputFormat = outputTable
+	})
+
+	captureStdout(t, func() {
+		if err := runSecretsAdd(secretsAddCmd, []string{"DB_URL=postgres://dev", "DEBUG=true", "SHARED=same"}); err != nil {
+			t.Fatalf("runSecretsAdd failed: %v", err)
+		}
+		secretsEnv = "prod"
+		if err := runSecretsAdd(secretsAddCmd, []string{"DB_URL=postgres://prod", "SENTRY_DSN=https://sentry", "SHARED=same"}); err != nil {
+			t.Fatalf("runSecretsAdd failed: %v", err)
+		}
+		secretsEnv = ""
+	})
+
+	secretsDiffFrom, secretsDiffTo = "default", "my-project:prod"
+	var err error
+	out := captureStdout(t, func() { err = runSecretsDiff(secretsDiffCmd, nil) })
+	if err != nil {
+		t.Fatalf("runSecretsDiff failed: %v", err)
+	}
+	for _, want := range []string{"changed", "DB_URL", "removed", "DEBUG", "added", "SENTRY_DSN", "1 added, 1 removed, 1 changed"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in the diff, got %q", want, out)
+		}
+	}
+	if strings.Contains(out, "SHARED") || strings.Contains(out, "postgres://") {
+		t.Errorf("Expected unchanged keys and all values to be left out, got %q", out)
+	}
+
+	secretsDiffShowValues, secretsDiffExitCode = true, true
+	outputFormat = outputJSON
+	out = captureStdout(t, func() { err = runSecretsDiff(secretsDiffCmd, nil) })
+	if code := exitCodeFor(err); code != exitError {
+		t.Errorf("Expected --exit-code to exit with 1 when secrets differ, got %d (%v)", code, err)
+	}
+	var changes []secretChange
+	if err := json.Unmarshal([]byte(out), &changes); err != nil {
+		t.Fatalf("Expected JSON output, got %q: %v", out, err)
+	}
+	if len(changes) != 3 || changes[0].Key != "DB_URL" || *changes[0].From != "postgres://dev" || *changes[0].To != "postgres://prod" {
+		t.Errorf("Expected the changed values with --show-values, got %+v", changes)
+	}
+
+	secretsDiffTo = "default"
+	out = captureStdout(t, func() { err = runSecretsDiff(secretsDiffCmd, nil) })
+	if err != nil || strings.TrimSpace(out) != "[]" {
+		t.Errorf("Expected no differences comparing an environment with itself, got %q, %v", out, err)
+	}
+}
+
+func TestDiffSecrets(t *testing.T) {
+	from := map[string]string{"A": "1", "B": "2", "C": "3"}
+	to := map[string]string{"B": "2", "C": "30", "D": "4"}
+
+	changes := diffSecrets(from, to, false)
+	want := []secretChange{{Key: "A", Change: secretRemoved}, {Key: "C", Change: secretChanged}, {Key: "D", Change: secretAdded}}
+	if len(changes) != len(want) {
+		t.Fatalf("Expected %+v, got %+v", want, changes)
+	}
+	for i := range want {
+		if changes[i].Key != want[i].Key || changes[i].Change != want[i].Change || changes[i].From != nil || changes[i].To != nil {
+			t.Errorf("Expected %+v without values, got %+v", want[i], changes[i])
+		}
+	}
+
+	changes = diffSecrets(from, to, true)
+	if changes[0].From == nil || *changes[0].From != "1" || changes[0].To != nil {
+		t.Errorf("Expected only the from value of a removed key, got %+v", changes[0])
+	}
+	if *changes[1].From != "3" || *changes[1].To != "30" {
+		t.Errorf("Expected both values of a changed key, got %+v", changes[1])
+	}
+	if diffSecrets(from, from, true) != nil {
+		t.Error("Expected no changes between identical sets")
+	}
+}
+
+func TestSecretsDiffFile(t *testing.T) {
+	setupSecretsTest(t)
+	t.Cleanup(func() {
+		secretsEnv, secretsDiffFrom, secretsDiffTo = "", "", ""
+		outputFormat = outputTable
+	})
+
+	captureStdout(t, func() {
+		secretsEnv = "prod"
+		if err := runSecretsAdd(secretsAddCmd, []string{"DB_URL=postgres://prod", "API_KEY=abc"}); err != nil {
+			t.Fatalf("runSecretsAdd failed: %v", err)
+		}
+	})
+
+	dir := t.TempDir()
+	dotenvFile := filepath.Join(dir, ".env.prod")
+	if err := os.WriteFile(dotenvFile, []byte("DB_URL=postgres://prod\nAPI_KEY=abc\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var err error
+	out := captureStdout(t, func() { err = runSecretsDiff(secretsDiffCmd, []string{dotenvFile}) })
+	if err != nil || !strings.Contains(out, "No differences") {
+		t.Errorf("Expected a file matching prod to pass, got %q, %v", out, err)
+	}
+
+	jsonFile := filepath.Join(dir, "prod.json")
+	if err := os.WriteFile(jsonFile, []byte(`{"DB_URL": "postgres://local", "LOCAL_ONLY": "x"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	out = captureStdout(t, func() { err = runSecretsDiff(secretsDiffCmd, []string{jsonFile}) })
+	if code := exitCodeFor(err); code != exitError {
+		t.Errorf("Expected drift from a file to exit with 1, got %d (%v)", code, err)
+	}
+	for _, want := range []string{"1 added, 1 removed, 1 changed", "LOCAL_ONLY", "API_KEY"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in the diff, got %q", want, out)
+		}
+	}
+	if strings.Contains(out, "postgres://") {
+		t.Errorf("Expected values to be masked, got %q", out)
+	}
+
+	// --to picks another environment to compare the file with
+	secretsEnv, secretsDiffTo = "", "prod"
+	captureStdout(t, func() { err = runSecretsDiff(secretsDiffCmd, []string{dotenvFile}) })
+	if err != nil {
+		t.Errorf("Expected --to prod to match the file, got %v", err)
+	}
+
+	secretsDiffFrom = "default"
+	err = runSecretsDiff(secretsDiffCmd, []string{dotenvFile})
+	if err == nil || !strings.Contains(err.Error(), "--from can't be used with a file") {
+		t.Errorf("Expected --from with a file to fail, got %v", err)
+	}
+	secretsDiffFrom, secretsDiffTo = "", ""
+	err = runSecretsDiff(secretsDiffCmd, nil)
+	if err == nil || !strings.Contains(err.Error(), "--from and --to are required") {
+		t.Errorf("Expected --from and --to to be required without a file, got %v", err)
+	}
+}
@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/client"
+	"github.com/DylanBlakemore/initflow-cli/internal/storage"
+)
+
+var secretsEditCmd = &cobra.Command{
+	Use:   "edit <KEY>",
+	Short: "Edit a secret in your editor",
+	Long: "Decrypt a secret into a temp file only you can read, in /dev/shm when it exists so the value " +
+		"stays in memory, and open it in $VISUAL or $EDITOR (vi when neither is set). When the editor exits " +
+		"the file is overwritten and deleted, and the edited value is encrypted and stored as a new version. " +
+		"Nothing is stored when the value is unchanged or the editor exits with an error, nor when someone " +
+		"else stored a new version meanwhile. A newline the editor adds at the end is dropped unless the " +
+		"value already ended with one.",
+	Example: "  initflow secrets edit -w api TLS_PRIVATE_KEY",
+	Args:    cobra.ExactArgs(1),
+	RunE:    runSecretsEdit,
+}
+
+// editTempDirs are tried in order for the temp file; /dev/shm is a tmpfs on
+// Linux, so the value never reaches a disk
+var editTempDirs = []string{"/dev/shm", ""}
+
+func init() {
+	secretsCmd.AddCommand(secretsEditCmd)
+}
+
+// editorCommand is the editor to run: $VISUAL, then $EDITOR, then vi. Either
+// may carry arguments, e.g. "code --wait".
+func editorCommand() []string {
+	for _, name := range []string{"VISUAL", "EDITOR"} {
+		if fields := strings.Fields(os.Getenv(name)); len(fields) > 0 {
+			return fields
+		}
+	}
+	return []string{"vi"}
+}
+
+// createEditFile writes value to a new owner-only temp file for key
+func createEditFile(key string, value []byte) (string, error) {
+	pattern := "initflow-" + path.Base(key) + "-*"
+	for _, dir := range editTempDirs {
+		if dir != "" {
+			if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+				continue
+			}
+		}
+		file, err := os.CreateTemp(dir, pattern) // created 0600
+		if err != nil {
+			continue
+		}
+		name := file.Name()
+		_, err = file.Write(value)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			shredFile(name)
+			return "", fmt.Errorf("failed to write temp file: %w", err)
+		}
+		return name, nil
+	}
+	return "", fmt.Errorf("failed to create a temp file")
+}
+
+// shredFile overwrites a file with zeros before removing it, so the value
+// isn't left in freed blocks. It is best effort; copy-on-write filesystems
+// may keep the old blocks anyway.
+func shredFile(name string) {
+	if info, err := os.Stat(name); err == nil {
+		if file, err := os.OpenFile(name, os.O_WRONLY, 0); err == nil { // #nosec G304 - our own temp file
+			_, _ = file.Write(make([]byte, info.Size()))
+			_ = file.Sync()
+			_ = file.Close()
+		}
+	}
+	_ = os.Remove(name)
+}
+
+// editValue opens value in the editor and returns what the file holds when
+// it exits. The temp file is shredded however it ends.
+func editValue(key string, value []byte) ([]byte, error) {
+	name, err := createEditFile(key, value)
+	if err != nil {
+		return nil, err
+	}
+	defer shredFile(name)
+
+	editor := editorCommand()
+	child := exec.Command(editor[0], append(editor[1:], name)...) // #nosec G204 - the editor the user chose
+	child.Stdin = os.Stdin
+	child.Stdout = os.Stdout
+	child.Stderr = os.Stderr
+	if err := child.Run(); err != nil {
+		return nil, fmt.Errorf("editor %s failed, nothing was stored: %w", editor[0], err)
+	}
+
+	edited, err := os.ReadFile(name) // #nosec G304 - our own temp file
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the edited value: %w", err)
+	}
+	if !bytes.HasSuffix(value, []byte("\n")) {
+		edited = bytes.TrimSuffix(bytes.TrimSuffix(edited, []byte("\n")), []byte("\r"))
+	}
+	return edited, nil
+}
+
+func runSecretsEdit(cmd *cobra.Command, args []string) error {
+	key := args[0]
+	if err := validateSecretKey(key); err != nil {
+		return fmt.Errorf("❌ %w", err)
+	}
+
+	store := storage.New()
+	if err := ensureSecretsAccess(store); err != nil {
+		return fmt.Errorf("❌ %w", err)
+	}
+
+	c, err := newSecretsClient(secretsEnv)
+	if err != nil {
+		return err
+	}
+	workspace, workspaceKey, err := openWorkspace(c, store, secretsWorkspace)
+	if err != nil {
+		return err
+	}
+
+	secret, err := c.GetSecret(workspace.ID, key)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to get %s: %w", key, err)
+	}
+	value, err := openSecret(workspaceKey, workspace, secretsEnv, secret)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to read %s: %w", key, err)
+	}
+
+	edited, err := editValue(key, value)
+	if err != nil {
+		return fmt.Errorf("❌ %w", err)
+	}
+	if bytes.Equal(edited, value) {
+		infof("ℹ️ %s is unchanged; nothing was stored\n", key)
+		return nil
+	}
+
+	ciphertext, err := encryptSecret(workspaceKey, workspace.ID, secretsEnv, key, edited)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to encrypt %s: %w", key, err)
+	}
+	// Keep the secret's expiry; a new version shouldn't outlive a --ttl
+	opts := client.PutOptions{IfVersion: secret.Version,
+		Checksum: secretChecksum(workspaceKey, workspace.ID, secretsEnv, key, edited)}
+	if expiresAt, err := time.Parse(time.RFC3339, secret.ExpiresAt); err == nil {
+		opts.ExpiresAt = expiresAt
+	}
+	stored, err := c.PutSecretWith(workspace.ID, key, ciphertext, len(edited), workspace.KeyVersion, opts)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to store %s: %w", key, err)
+	}
+
+	if structuredOutput() {
+		return writeOutput(stored)
+	}
+	infof("✅ Stored %s in %s as version %d\n", key, secretsLocation(workspace.Slug, secretsEnv), stored.Version)
+	return nil
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// useEditor points $EDITOR at a shell script with body, run with the temp
+// file as $1, and keeps temp files in a directory the test can inspect
+func useEditor(t *testing.T, body string) string {
+	t.Helper()
+	dir := t.TempDir()
+	script := filepath.Join(dir, "editor.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\n"+body+"\n"), 0700); err != nil {
+		t.Fatalf("Failed to write editor: %v", err)
+	}
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", script)
+
+	tempDir := filepath.Join(dir, "tmp")
+	if err := os.Mkdir(tempDir, 0700); err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	saved := editTempDirs
+	editTempDirs = []string{tempDir}
+	t.Cleanup(func() { editTempDirs = saved })
+	return tempDir
+}
+
+func expectNoTempFiles(t *testing.T, dir string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read temp dir: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected the temp file to be removed, found %v", entries)
+	}
+}
+
+func TestSecretsEdit(t *testing.T) {
+	fake, _ := setupSecretsTest(t)
+	captureStdout(t, func() {
+		if err := runSecretsAdd(secretsAddCmd, []string{"TLS_KEY=old"}); err != nil {
+			t.Fatalf("runSecretsAdd failed: %v", err)
+		}
+	})
+
+	// The editor sees the value in an owner-only file and replaces it,
+	// adding a newline at the end as editors do
+	tempDir := useEditor(t, `[ "$(cat "$1")" = old ] && [ "$(ls -l "$1" | cut -c1-10)" = -rw------- ] || exit 3
+printf 'line1\nline2\n' > "$1"`)
+	var err error
+	out := captureStdout(t, func() { err = runSecretsEdit(secretsEditCmd, []string{"TLS_KEY"}) })
+	if err != nil {
+		t.Fatalf("runSecretsEdit failed: %v", err)
+	}
+	if secret := fake.secrets["TLS_KEY"]; secret.Version != 2 || secret.Size != len("line1\nline2") {
+		t.Errorf("Expected the edited value without the added newline, got %+v", secret)
+	}
+	if !strings.Contains(out, "Stored TLS_KEY in my-project as version 2") {
+		t.Errorf("Expected a confirmation, got %q", out)
+	}
+	expectNoTempFiles(t, tempDir)
+}
+
+func TestSecretsEditUnchangedOrFailed(t *testing.T) {
+	fake, _ := setupSecretsTest(t)
+	captureStdout(t, func() {
+		if err := runSecretsAdd(secretsAddCmd, []string{"TLS_KEY=old"}); err != nil {
+			t.Fatalf("runSecretsAdd failed: %v", err)
+		}
+	})
+
+	tempDir := useEditor(t, "true")
+	var err error
+	out := captureStdout(t, func() { err = runSecretsEdit(secretsEditCmd, []string{"TLS_KEY"}) })
+	if err != nil || !strings.Contains(out, "TLS_KEY is unchanged; nothing was stored") {
+		t.Errorf("Expected an unchanged value to be skipped, got %q, %v", out, err)
+	}
+	if fake.secrets["TLS_KEY"].Version != 1 {
+		t.Errorf("Expected no upload, got %+v", fake.secrets["TLS_KEY"])
+	}
+	expectNoTempFiles(t, tempDir)
+
+	tempDir = useEditor(t, `printf new > "$1"; exit 1`)
+	err = runSecretsEdit(secretsEditCmd, []string{"TLS_KEY"})
+	if err == nil || !strings.Contains(err.Error(), "nothing was stored") {
+		t.Errorf("Expected a failing editor to abort, got %v", err)
+	}
+	if fake.secrets["TLS_KEY"].Version != 1 {
+		t.Errorf("Expected no upload after the editor failed, got %+v", fake.secrets["TLS_KEY"])
+	}
+	expectNoTempFiles(t, tempDir)
+}
@@ -0,0 +1,218 @@
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/spf13/cobra"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/config"
+)
+
+var secretsLintCmd = &cobra.Command{
+	Use:   "lint",
+	Short: "Check secrets for risky or malformed values",
+	Long: "Decrypt the secrets of a workspace environment on this device and warn about empty values, " +
+		"leading or trailing whitespace, placeholders such as changeme or TODO, short values for keys named " +
+		"*_KEY, *_SECRET, *_TOKEN or *_PASSWORD, values shared by several keys, and keys that aren't " +
+		"UPPER_SNAKE_CASE. Values are never printed. Skip rules with the lint_skip setting. --strict exits " +
+		"with 1 when anything is found, e.g. to gate CI.",
+	Example: "  initflow secrets lint -w api --env prod --strict\n" +
+		"  initflow config set lint_skip naming,short",
+	Args: cobra.NoArgs,
+	RunE: runSecretsLint,
+}
+
+var (
+	secretsLintPath   string
+	secretsLintStrict bool
+)
+
+// minSecretLength is the length below which a value of a key named like a
+// credential is reported as short
+const minSecretLength = 16
+
+// lintRule checks one secret and returns a warning, or "" when it passes
+type lintRule struct {
+	Name  string
+	Check func(key, value string) string
+}
+
+// lintDuplicate names the rule comparing values across keys, which isn't
+// one of lintRules
+const lintDuplicate = "duplicate"
+
+// placeholderValues are values left in from a template, compared lowercase
+var placeholderValues = map[string]bool{
+	"changeme": true, "change_me": true, "change-me": true, "todo": true, "tbd": true, "fixme": true,
+	"xxx": true, "placeholder": true, "replaceme": true, "replace_me": true, "dummy": true, "example": true,
+	"secret": true, "password": true, "null": true, "none": true, "undefined": true,
+}
+
+// credentialKeyPattern matches the last segment of keys that name a credential
+var credentialKeyPattern = regexp.MustCompile(`(^|_)(KEY|SECRET|TOKEN|PASSWORD)$`)
+
+// upperSnakeCase is the naming convention for the last segment of a key
+var upperSnakeCase = regexp.MustCompile(`^[A-Z][A-Z0-9]*(_[A-Z0-9]+)*$`)
+
+var lintRules = []lintRule{
+	{"empty", func(key, value string) string {
+		if value == "" {
+			return "value is empty"
+		}
+		return ""
+	}},
+	{"whitespace", func(key, value string) string {
+		if value != "" && strings.TrimSpace(value) != value {
+			return "value has leading or trailing whitespace"
+		}
+		return ""
+	}},
+	{"placeholder", func(key, value string) string {
+		trimmed := strings.ToLower(strings.TrimSpace(value))
+		if placeholderValues[trimmed] || (strings.HasPrefix(trimmed, "<") && strings.HasSuffix(trimmed, ">")) {
+			return "value looks like a placeholder"
+		}
+		return ""
+	}},
+	{"short", func(key, value string) string {
+		if value != "" && len(value) < minSecretLength && credentialKeyPattern.MatchString(path.Base(key)) {
+			return fmt.Sprintf("value is only %d characters", len(value))
+		}
+		return ""
+	}},
+	{"naming", func(key, value string) string {
+		if !upperSnakeCase.MatchString(path.Base(key)) {
+			return "key isn't UPPER_SNAKE_CASE"
+		}
+		return ""
+	}},
+}
+
+// lintRuleNames lists every rule, for help and errors
+func lintRuleNames() []string {
+	names := make([]string, 0, len(lintRules)+1)
+	for _, rule := range lintRules {
+		names = append(names, rule.Name)
+	}
+	return append(names, lintDuplicate)
+}
+
+// lintFinding is one warning about one secret
+type lintFinding struct {
+	Key     string `json:"key"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+var lintColumns = []tableColumn{
+	{Name: "key", Header: "Key", Default: true},
+	{Name: "rule", Header: "Rule", Default: true},
+	{Name: "message", Header: "Warning", Default: true},
+}
+
+func init() {
+	secretsCmd.AddCommand(secretsLintCmd)
+
+	secretsLintCmd.Flags().StringVar(&secretsLintPath, "path", "", "only lint secrets in this folder, e.g. backend/")
+	secretsLintCmd.Flags().BoolVar(&secretsLintStrict, "strict", false, "exit with 1 when anything is found")
+}
+
+// lintSecrets runs every rule not in skip over values and returns the
+// findings sorted by key, then rule
+func lintSecrets(values map[string]string, skip []string) ([]lintFinding, error) {
+	known := lintRuleNames()
+	skipped := make(map[string]bool, len(skip))
+	for _, name := range skip {
+		if !slices.Contains(known, name) {
+			return nil, fmt.Errorf("unknown lint rule %q in lint_skip (rules: %s)", name, strings.Join(known, ", "))
+		}
+		skipped[name] = true
+	}
+
+	findings := []lintFinding{}
+	for key, value := range values {
+		for _, rule := range lintRules {
+			if skipped[rule.Name] {
+				continue
+			}
+			if message := rule.Check(key, value); message != "" {
+				findings = append(findings, lintFinding{Key: key, Rule: rule.Name, Message: message})
+			}
+		}
+	}
+
+	if !skipped[lintDuplicate] {
+		byValue := map[string][]string{}
+		for key, value := range values {
+			if strings.TrimFunc(value, unicode.IsSpace) != "" {
+				byValue[value] = append(byValue[value], key)
+			}
+		}
+		for _, keys := range byValue {
+			if len(keys) < 2 {
+				continue
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				others := slices.DeleteFunc(slices.Clone(keys), func(other string) bool { return other == key })
+				findings = append(findings, lintFinding{Key: key, Rule: lintDuplicate,
+					Message: "same value as " + strings.Join(others, ", ")})
+			}
+		}
+	}
+
+	sort.Slice(findings, func(i, j int) bool {
+		if findings[i].Key != findings[j].Key {
+			return findings[i].Key < findings[j].Key
+		}
+		return findings[i].Rule < findings[j].Rule
+	})
+	return findings, nil
+}
+
+func runSecretsLint(cmd *cobra.Command, args []string) error {
+	prefix, err := parseSecretPath(secretsLintPath)
+	if err != nil {
+		return fmt.Errorf("❌ %w", err)
+	}
+
+	workspace, values, err := loadSecrets(secretsWorkspace, secretsEnv, prefix)
+	if err != nil {
+		return err
+	}
+
+	findings, err := lintSecrets(values, config.Get().LintSkip)
+	if err != nil {
+		return fmt.Errorf("❌ %w", err)
+	}
+
+	location := secretsLocation(workspace.Slug, secretsEnv)
+	if structuredOutput() {
+		if err := writeOutput(findings); err != nil {
+			return err
+		}
+	} else if len(findings) == 0 {
+		infof("✅ No problems found in %d secrets in %s\n", len(values), location)
+	} else {
+		rows := make([]map[string]string, len(findings))
+		keys := map[string]bool{}
+		for i, finding := range findings {
+			rows[i] = map[string]string{"key": finding.Key, "rule": finding.Rule, "message": finding.Message}
+			keys[finding.Key] = true
+		}
+		infof("🔍 Linting %d secrets in %s\n", len(values), location)
+		writeTable(cmd.OutOrStdout(), lintColumns, rows, true)
+		infof("⚠️  %d warnings on %d secrets\n", len(findings), len(keys))
+	}
+
+	if secretsLintStrict && len(findings) > 0 {
+		return &silentExit{code: exitError}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/config"
+)
+
+func TestLintSecretsRules(t *testing.T) {
+	tests := []struct {
+		name   string
+		values map[string]string
+		want   []lintFinding
+	}{
+		{"clean", map[string]string{"API_KEY": "sk_live_0123456789abcdef", "DEBUG": "false"}, []lintFinding{}},
+		{"empty", map[string]string{"DEBUG": ""},
+			[]lintFinding{{"DEBUG", "empty", "value is empty"}}},
+		{"whitespace", map[string]string{"DB_URL": "postgres://db \n"},
+			[]lintFinding{{"DB_URL", "whitespace", "value has leading or trailing whitespace"}}},
+		{"placeholder", map[string]string{"SMTP_HOST": "ChangeMe", "REGION": "<region>", "OWNER": "TODO"},
+			[]lintFinding{
+				{"OWNER", "placeholder", "value looks like a placeholder"},
+				{"REGION", "placeholder", "value looks like a placeholder"},
+				{"SMTP_HOST", "placeholder", "value looks like a placeholder"},
+			}},
+		{"short", map[string]string{"STRIPE_SECRET": "abc123", "backend/TOKEN": "t0k", "MONKEY": "x"},
+			[]lintFinding{
+				{"STRIPE_SECRET", "short", "value is only 6 characters"},
+				{"backend/TOKEN", "short", "value is only 3 characters"},
+			}},
+		{"duplicate", map[string]string{"A_URL": "https://same", "B_URL": "https://same", "C_URL": "https://other"},
+			[]lintFinding{
+				{"A_URL", "duplicate", "same value as B_URL"},
+				{"B_URL", "duplicate", "same value as A_URL"},
+			}},
+		{"naming", map[string]string{"apiUrl": "https://api", "backend/db/PASSWORD": "correct-horse-battery"},
+			[]lintFinding{{"apiUrl", "naming", "key isn't UPPER_SNAKE_CASE"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings, err := lintSecrets(tt.values, nil)
+			if err != nil {
+				t.Fatalf("lintSecrets failed: %v", err)
+			}
+			got, _ := json.Marshal(findings)
+			want, _ := json.Marshal(tt.want)
+			if string(got) != string(want) {
+				t.Errorf("Expected %s, got %s", want, got)
+			}
+		})
+	}
+}
+
+func TestLintSecretsSkip(t *testing.T) {
+	values := map[string]string{"API_KEY": "", "OTHER_KEY": "", "lower": "changeme"}
+
+	findings, err := lintSecrets(values, []string{"empty", "naming"})
+	if err != nil {
+		t.Fatalf("lintSecrets failed: %v", err)
+	}
+	if len(findings) != 1 || findings[0].Rule != "placeholder" {
+		t.Errorf("Expected only the placeholder warning, got %+v", findings)
+	}
+
+	if _, err := lintSecrets(values, []string{"spelling"}); err == nil || !strings.Contains(err.Error(), `unknown lint rule "spelling"`) {
+		t.Errorf("Expected an unknown rule to be rejected, got %v", err)
+	}
+}
+
+func TestSecretsLint(t *testing.T) {
+	setupSecretsTest(t)
+	t.Cleanup(func() {
+		secretsLintStrict = false
+		config.Set("lint_skip", nil)
+	})
+
+	captureStdout(t, func() {
+		if err := runSecretsAdd(secretsAddCmd, []string{"API_KEY=changeme", "DEBUG=true"}); err != nil {
+			t.Fatalf("runSecretsAdd failed: %v", err)
+		}
+	})
+
+	var err error
+	out := captureStdout(t, func() { err = runSecretsLint(secretsLintCmd, nil) })
+	if err != nil {
+		t.Fatalf("Expected warnings without --strict to succeed, got %v", err)
+	}
+	for _, want := range []string{"API_KEY", "placeholder", "short", "2 warnings on 1 secrets"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in the output, got %q", want, out)
+		}
+	}
+	if strings.Contains(out, "changeme") {
+		t.Errorf("Expected values to stay hidden, got %q", out)
+	}
+
+	secretsLintStrict = true
+	captureStdout(t, func() { err = runSecretsLint(secretsLintCmd, nil) })
+	if code := exitCodeFor(err); code != exitError {
+		t.Errorf("Expected --strict to exit with 1, got %d (%v)", code, err)
+	}
+
+	config.Set("lint_skip", []string{"placeholder", "short"})
+	out = captureStdout(t, func() { err = runSecretsLint(secretsLintCmd, nil) })
+	if err != nil || !strings.Contains(out, "No problems found in 2 secrets") {
+		t.Errorf("Expected lint_skip to silence the rules, got %q, %v", out, err)
+	}
+}
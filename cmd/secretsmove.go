@@ -0,0 +1,388 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/client"
+	"github.com/DylanBlakemore/initflow-cli/internal/encoding"
+	"github.com/DylanBlakemore/initflow-cli/internal/storage"
+)
+
+var secretsMoveCmd = &cobra.Command{
+	Use:     "move <SRC> <DST> | --namespace <FOLDER> --to <FOLDER>",
+	Aliases: []string{"mv"},
+	Short:   "Rename a secret or move a folder of secrets",
+	Long: "Re-key a secret within a workspace environment, e.g. staging/DB_URL to prod/DB_URL, or with " +
+		"--namespace and --to every secret in one folder to another. Values are decrypted on this device " +
+		"and encrypted again for their new key, keeping the expiry and tags; --history copies every " +
+		"version, not just the current one. Destination keys that already exist are refused unless " +
+		"--overwrite is set. Everything is read and decrypted before anything is written. A folder is " +
+		"written in one atomic request, or secret by secret with --history; when a write or delete fails " +
+		"the secrets already moved are put back, and a source restored this way keeps its current value " +
+		"but loses its older versions.",
+	Example: "  initflow secrets move -w api staging/DB_URL prod/DB_URL\n" +
+		"  initflow secrets move -w api --namespace legacy --to backend --history",
+	RunE: runSecretsMove,
+}
+
+var (
+	secretsMoveNamespace string
+	secretsMoveTo        string
+	secretsMoveOverwrite bool
+	secretsMoveHistory   bool
+)
+
+func init() {
+	secretsCmd.AddCommand(secretsMoveCmd)
+
+	secretsMoveCmd.Flags().StringVar(&secretsMoveNamespace, "namespace", "", "move every secret in this folder, e.g. legacy/")
+	secretsMoveCmd.Flags().StringVar(&secretsMoveTo, "to", "", "folder to move --namespace into")
+	secretsMoveCmd.Flags().BoolVar(&secretsMoveOverwrite, "overwrite", false, "replace destination secrets that already exist")
+	secretsMoveCmd.Flags().BoolVar(&secretsMoveHistory, "history", false, "copy every version, not just the current one")
+	secretsMoveCmd.MarkFlagsRequiredTogether("namespace", "to")
+}
+
+// secretMove is one secret being re-keyed. source is its current version,
+// values its plaintexts oldest first, and replaced the destination secret it
+// overwrites, if any.
+type secretMove struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	source   *client.Secret
+	values   [][]byte
+	replaced *client.Secret
+}
+
+// parseNamespace turns a --namespace or --to folder into its key prefix
+func parseNamespace(flag, folder string) (string, error) {
+	prefix, err := parseSecretPath(folder)
+	if err != nil || prefix == "" {
+		return "", fmt.Errorf("invalid --%s %q: use folder names of letters, digits and underscores, "+
+			"separated by /", flag, folder)
+	}
+	return prefix, nil
+}
+
+// namespaceMoves maps each key under from to the same key under to
+func namespaceMoves(keys []string, from, to string) []*secretMove {
+	sort.Strings(keys)
+	moves := make([]*secretMove, 0, len(keys))
+	for _, key := range keys {
+		if rest, ok := strings.CutPrefix(key, from); ok {
+			moves = append(moves, &secretMove{From: key, To: to + rest})
+		}
+	}
+	return moves
+}
+
+// prepareMove fetches and decrypts what a move writes, and the secret at its
+// destination, refusing one that exists without --overwrite
+func prepareMove(c *client.Client, workspace *client.Workspace, workspaceKey []byte, env string, move *secretMove) error {
+	source := move.source
+	if source == nil {
+		secret, err := c.GetSecret(workspace.ID, move.From)
+		if err != nil {
+			return fmt.Errorf("failed to get %s: %w", move.From, err)
+		}
+		source = secret
+	}
+	move.source = source
+
+	versions := []*client.Secret{source}
+	if secretsMoveHistory {
+		listed, err := c.ListSecretVersions(workspace.ID, move.From)
+		if err != nil {
+			return fmt.Errorf("failed to get history of %s: %w", move.From, err)
+		}
+		sort.Slice(listed, func(i, j int) bool { return listed[i].Version < listed[j].Version })
+		versions = versions[:0]
+		for _, version := range listed {
+			if version.Version == source.Version {
+				versions = append(versions, source)
+				continue
+			}
+			old, err := c.GetSecretVersion(workspace.ID, move.From, version.Version)
+			if err != nil {
+				return fmt.Errorf("failed to get version %d of %s: %w", version.Version, move.From, err)
+			}
+			versions = append(versions, old)
+		}
+		if len(versions) == 0 {
+			versions = append(versions, source)
+		}
+	}
+	for _, version := range versions {
+		value, err := openSecret(workspaceKey, workspace, env, version)
+		if err != nil {
+			return fmt.Errorf("can't read version %d of %s: %w", version.Version, move.From, err)
+		}
+		move.values = append(move.values, value)
+	}
+
+	existing, err := c.GetSecret(workspace.ID, move.To)
+	switch {
+	case err == nil && !secretsMoveOverwrite:
+		return fmt.Errorf("%s already exists; use --overwrite to replace it", move.To)
+	case err == nil:
+		move.replaced = existing
+	case exitCodeFor(err) != exitNotFound:
+		return fmt.Errorf("failed to check %s: %w", move.To, err)
+	}
+	return nil
+}
+
+// setSecretTags makes a secret's tags want, given that it has have
+func setSecretTags(c *client.Client, workspaceID int, key string, have, want []string) error {
+	var update client.UpdateSecretTagsRequest
+	for _, tag := range want {
+		if !slices.Contains(have, tag) {
+			update.AddTags = append(update.AddTags, tag)
+		}
+	}
+	for _, tag := range have {
+		if !slices.Contains(want, tag) {
+			update.RemoveTags = append(update.RemoveTags, tag)
+		}
+	}
+	if len(update.AddTags) == 0 && len(update.RemoveTags) == 0 {
+		return nil
+	}
+	_, err := c.UpdateSecretTags(workspaceID, key, update)
+	return err
+}
+
+// writeMove stores the values of a move under its destination key, the last
+// one with the source's expiry, and copies the source's tags. It reports
+// whether the destination was changed, even when it then fails.
+func writeMove(c *client.Client, workspace *client.Workspace, workspaceKey []byte, env string, move *secretMove) (bool, error) {
+	stored := move.replaced
+	for i, value := range move.values {
+		ciphertext, err := encryptSecret(workspaceKey, workspace.ID, env, move.To, value)
+		if err != nil {
+			return i > 0, fmt.Errorf("failed to encrypt %s: %w", move.To, err)
+		}
+		opts := client.PutOptions{Checksum: secretChecksum(workspaceKey, workspace.ID, env, move.To, value)}
+		if i == 0 && move.replaced == nil {
+			opts.IfNotExists = true
+		}
+		if i == len(move.values)-1 {
+			if expiresAt, err := time.Parse(time.RFC3339, move.source.ExpiresAt); err == nil {
+				opts.ExpiresAt = expiresAt
+			}
+		}
+		if stored, err = c.PutSecretWith(workspace.ID, move.To, ciphertext, len(value), workspace.KeyVersion, opts); err != nil {
+			return i > 0, fmt.Errorf("failed to store %s: %w", move.To, err)
+		}
+	}
+	if err := setSecretTags(c, workspace.ID, move.To, stored.Tags, move.source.Tags); err != nil {
+		return true, fmt.Errorf("failed to tag %s: %w", move.To, err)
+	}
+	return true, nil
+}
+
+// restoreSecret puts a secret back as it was fetched: its ciphertext was
+// sealed for the same key, so it is stored again as is, with its checksum,
+// expiry and tags
+func restoreSecret(c *client.Client, workspaceID int, secret *client.Secret) error {
+	sealed, err := encoding.Decode(secret.Ciphertext)
+	if err != nil {
+		return fmt.Errorf("failed to decode %s: %w", secret.Key, err)
+	}
+	opts := client.PutOptions{Checksum: secret.Checksum}
+	if expiresAt, err := time.Parse(time.RFC3339, secret.ExpiresAt); err == nil {
+		opts.ExpiresAt = expiresAt
+	}
+	stored, err := c.PutSecretWith(workspaceID, secret.Key, sealed, secret.Size, secret.KeyVersion, opts)
+	if err != nil {
+		return fmt.Errorf("failed to restore %s: %w", secret.Key, err)
+	}
+	if err := setSecretTags(c, workspaceID, secret.Key, stored.Tags, secret.Tags); err != nil {
+		return fmt.Errorf("failed to restore the tags of %s: %w", secret.Key, err)
+	}
+	return nil
+}
+
+// rollbackMoves undoes moves newest first: deleted sources are restored, and
+// each destination is deleted again or restored to the secret it replaced
+func rollbackMoves(c *client.Client, workspaceID int, written, deleted []*secretMove) error {
+	var errs []error
+	for i := len(deleted) - 1; i >= 0; i-- {
+		errs = append(errs, restoreSecret(c, workspaceID, deleted[i].source))
+	}
+	for i := len(written) - 1; i >= 0; i-- {
+		move := written[i]
+		if move.replaced != nil {
+			errs = append(errs, restoreSecret(c, workspaceID, move.replaced))
+		} else if err := c.DeleteSecret(workspaceID, move.To); err != nil && exitCodeFor(err) != exitNotFound {
+			errs = append(errs, fmt.Errorf("failed to delete %s: %w", move.To, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// writeMovesBatch stores the current value of every move in one PutSecrets
+// request, which the server applies atomically, then copies the sources'
+// tags. It returns the moves whose destination was written.
+func writeMovesBatch(c *client.Client, workspace *client.Workspace, workspaceKey []byte, env string, moves []*secretMove) ([]*secretMove, error) {
+	keys := make([]string, len(moves))
+	values := make(map[string]string, len(moves))
+	for i, move := range moves {
+		keys[i], values[move.To] = move.To, string(move.values[len(move.values)-1])
+	}
+	uploads, err := sealUploads(workspaceKey, workspace, env, keys, values)
+	if err != nil {
+		return nil, err
+	}
+	for i, move := range moves {
+		uploads[i].ExpiresAt = move.source.ExpiresAt
+	}
+	if _, err := c.PutSecrets(workspace.ID, uploads); err != nil {
+		return nil, fmt.Errorf("failed to store the moved secrets: %w", err)
+	}
+
+	for _, move := range moves {
+		var have []string
+		if move.replaced != nil {
+			have = move.replaced.Tags
+		}
+		if err := setSecretTags(c, workspace.ID, move.To, have, move.source.Tags); err != nil {
+			return moves, fmt.Errorf("failed to tag %s: %w", move.To, err)
+		}
+	}
+	return moves, nil
+}
+
+// moveSecrets writes every destination, then deletes every source. With
+// batch set the destinations are written in one atomic request, else one
+// by one, as --history needs. When a step fails, what was done is rolled
+// back and the failure returned.
+func moveSecrets(c *client.Client, workspace *client.Workspace, workspaceKey []byte, env string, moves []*secretMove, batch bool) error {
+	var written, deleted []*secretMove
+	fail := func(err error) error {
+		if rollbackErr := rollbackMoves(c, workspace.ID, written, deleted); rollbackErr != nil {
+			return fmt.Errorf("%w; rolling back also failed, check the keys by hand: %w", err, rollbackErr)
+		}
+		return fmt.Errorf("%w; nothing was moved", err)
+	}
+
+	if batch {
+		var err error
+		if written, err = writeMovesBatch(c, workspace, workspaceKey, env, moves); err != nil {
+			return fail(err)
+		}
+	} else {
+		for _, move := range moves {
+			changed, err := writeMove(c, workspace, workspaceKey, env, move)
+			if changed {
+				written = append(written, move)
+			}
+			if err != nil {
+				return fail(err)
+			}
+		}
+	}
+	for _, move := range moves {
+		if err := c.DeleteSecret(workspace.ID, move.From); err != nil {
+			return fail(fmt.Errorf("failed to delete %s: %w", move.From, err))
+		}
+		deleted = append(deleted, move)
+	}
+	return nil
+}
+
+func runSecretsMove(cmd *cobra.Command, args []string) error {
+	var moves []*secretMove
+	var from, to string
+	if secretsMoveNamespace != "" {
+		if len(args) > 0 {
+			return fmt.Errorf("❌ Give either <SRC> <DST> or --namespace and --to, not both")
+		}
+		var err error
+		if from, err = parseNamespace("namespace", secretsMoveNamespace); err != nil {
+			return fmt.Errorf("❌ %w", err)
+		}
+		if to, err = parseNamespace("to", secretsMoveTo); err != nil {
+			return fmt.Errorf("❌ %w", err)
+		}
+		if strings.HasPrefix(from, to) || strings.HasPrefix(to, from) {
+			return fmt.Errorf("❌ --namespace %s and --to %s overlap", from, to)
+		}
+	} else {
+		if len(args) != 2 {
+			return fmt.Errorf("❌ Give <SRC> <DST>, or --namespace and --to to move a folder")
+		}
+		for _, key := range args {
+			if err := validateSecretKey(key); err != nil {
+				return fmt.Errorf("❌ %w", err)
+			}
+		}
+		if args[0] == args[1] {
+			return fmt.Errorf("❌ %s and its destination are the same key", args[0])
+		}
+		moves = []*secretMove{{From: args[0], To: args[1]}}
+	}
+
+	store := storage.New()
+	if err := ensureSecretsAccess(store); err != nil {
+		return fmt.Errorf("❌ %w", err)
+	}
+	c, err := newSecretsClient(secretsEnv)
+	if err != nil {
+		return err
+	}
+	workspace, workspaceKey, err := openWorkspace(c, store, secretsWorkspace)
+	if err != nil {
+		return err
+	}
+	location := secretsLocation(workspace.Slug, secretsEnv)
+
+	if from != "" {
+		secrets, err := c.FetchSecrets(workspace.ID, from)
+		if err != nil {
+			return fmt.Errorf("❌ Failed to fetch secrets: %w", err)
+		}
+		sources := make(map[string]*client.Secret, len(secrets))
+		keys := make([]string, len(secrets))
+		for i := range secrets {
+			sources[secrets[i].Key], keys[i] = &secrets[i], secrets[i].Key
+		}
+		moves = namespaceMoves(keys, from, to)
+		if len(moves) == 0 {
+			return fmt.Errorf("❌ No secrets in %s in %s", from, location)
+		}
+		for _, move := range moves {
+			move.source = sources[move.From]
+		}
+	}
+
+	var errs []error
+	for _, move := range moves {
+		if err := prepareMove(c, workspace, workspaceKey, secretsEnv, move); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("❌ Nothing was moved:\n%w", err)
+	}
+
+	infof("🔐 Moving %d secrets in %s...\n", len(moves), location)
+	if err := moveSecrets(c, workspace, workspaceKey, secretsEnv, moves, from != "" && !secretsMoveHistory); err != nil {
+		return fmt.Errorf("❌ %w", err)
+	}
+
+	if structuredOutput() {
+		return writeOutput(map[string]any{"workspace": workspace.Slug, "environment": secretsEnv, "moved": moves})
+	}
+	for _, move := range moves {
+		infof("  %s -> %s\n", move.From, move.To)
+	}
+	infof("✅ Moved %d secrets in %s\n", len(moves), location)
+	return nil
+}
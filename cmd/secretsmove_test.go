@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// resetMoveFlags clears the secrets move flags
+func resetMoveFlags() {
+	secretsMoveNamespace, secretsMoveTo = "", ""
+	secretsMoveOverwrite, secretsMoveHistory = false, false
+}
+
+func TestSecretsMove(t *testing.T) {
+	fake, _ := setupSecretsTest(t)
+	t.Cleanup(resetMoveFlags)
+
+	captureStdout(t, func() {
+		for _, arg := range []string{"staging/DB_URL=postgres://v1", "staging/DB_URL=postgres://v2", "prod/TAKEN=x"} {
+			if err := runSecretsAdd(secretsAddCmd, []string{arg}); err != nil {
+				t.Fatalf("runSecretsAdd failed: %v", err)
+			}
+		}
+	})
+	source := fake.secrets["staging/DB_URL"]
+	source.Tags, source.ExpiresAt = []string{"db"}, "2099-01-01T00:00:00Z"
+	fake.secrets["staging/DB_URL"] = source
+
+	err := runSecretsMove(secretsMoveCmd, []string{"staging/DB_URL", "prod/TAKEN"})
+	if err == nil || !strings.Contains(err.Error(), "prod/TAKEN already exists; use --overwrite") {
+		t.Errorf("Expected an existing destination to be refused, got %v", err)
+	}
+
+	secretsMoveHistory = true
+	out := captureStdout(t, func() { err = runSecretsMove(secretsMoveCmd, []string{"staging/DB_URL", "prod/DB_URL"}) })
+	if err != nil || !strings.Contains(out, "Moved 1 secrets in my-project") {
+		t.Fatalf("Expected the move to succeed, got %q, %v", out, err)
+	}
+	if _, ok := fake.secrets["staging/DB_URL"]; ok {
+		t.Error("Expected the source to be deleted")
+	}
+	moved := fake.secrets["prod/DB_URL"]
+	if len(fake.versions["prod/DB_URL"]) != 2 || !slices.Equal(moved.Tags, []string{"db"}) || moved.ExpiresAt != "2099-01-01T00:00:00Z" {
+		t.Errorf("Expected both versions, the tag and the expiry moved, got %+v", moved)
+	}
+	values, err := loadSecretsMap()
+	if err != nil || values["prod/DB_URL"] != "postgres://v2" {
+		t.Errorf("Expected the moved value to decrypt under its new key, got %v, %v", values, err)
+	}
+}
+
+func TestSecretsMoveNamespace(t *testing.T) {
+	fake, _ := setupSecretsTest(t)
+	t.Cleanup(resetMoveFlags)
+
+	captureStdout(t, func() {
+		if err := runSecretsAdd(secretsAddCmd, []string{"legacy/A=1", "legacy/db/B=2", "legacyish/C=3"}); err != nil {
+			t.Fatalf("runSecretsAdd failed: %v", err)
+		}
+	})
+
+	expiring := fake.secrets["legacy/A"]
+	expiring.ExpiresAt = "2099-01-01T00:00:00Z"
+	fake.secrets["legacy/A"] = expiring
+
+	secretsMoveNamespace, secretsMoveTo = "legacy", "backend/"
+	batches := fake.batches
+	var err error
+	captureStdout(t, func() { err = runSecretsMove(secretsMoveCmd, nil) })
+	if err != nil {
+		t.Fatalf("runSecretsMove failed: %v", err)
+	}
+	if fake.batches != batches+1 || fake.secrets["backend/A"].ExpiresAt != "2099-01-01T00:00:00Z" {
+		t.Errorf("Expected the folder written in one batch, keeping expiry, got %d batches, %+v",
+			fake.batches-batches, fake.secrets["backend/A"])
+	}
+	values, err := loadSecretsMap()
+	if err != nil {
+		t.Fatalf("loadSecrets failed: %v", err)
+	}
+	want := map[string]string{"backend/A": "1", "backend/db/B": "2", "legacyish/C": "3"}
+	if len(values) != len(want) {
+		t.Errorf("Expected %v, got %v", want, values)
+	}
+	for key, value := range want {
+		if values[key] != value {
+			t.Errorf("Expected %s=%s, got %v", key, value, values)
+		}
+	}
+
+	secretsMoveTo = "backend/legacy"
+	secretsMoveNamespace = "backend"
+	err = runSecretsMove(secretsMoveCmd, nil)
+	if err == nil || !strings.Contains(err.Error(), "overlap") {
+		t.Errorf("Expected overlapping folders to be refused, got %v", err)
+	}
+	if len(fake.secrets) != 3 {
+		t.Errorf("Expected nothing to change, got %v", fake.secrets)
+	}
+}
+
+func TestSecretsMoveRollsBack(t *testing.T) {
+	fake, _ := setupSecretsTest(t)
+	t.Cleanup(resetMoveFlags)
+
+	captureStdout(t, func() {
+		if err := runSecretsAdd(secretsAddCmd, []string{"old/A=1", "old/B=2", "old/C=3", "new/C=kept"}); err != nil {
+			t.Fatalf("runSecretsAdd failed: %v", err)
+		}
+	})
+	before, err := loadSecretsMap()
+	if err != nil {
+		t.Fatalf("loadSecrets failed: %v", err)
+	}
+
+	secretsMoveNamespace, secretsMoveTo, secretsMoveOverwrite = "old", "new", true
+	for _, tc := range []struct {
+		name    string
+		history bool
+		failing string
+	}{
+		{"batch write", false, "new/B"},
+		{"batch then delete", false, "old/C"},
+		{"write with history", true, "new/B"},
+		{"delete with history", true, "old/C"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fake.forbidden = map[string]bool{tc.failing: true}
+			secretsMoveHistory = tc.history
+			t.Cleanup(func() { fake.forbidden, secretsMoveHistory = nil, false })
+
+			err := runSecretsMove(secretsMoveCmd, nil)
+			if err == nil || !strings.Contains(err.Error(), "nothing was moved") {
+				t.Errorf("Expected the failure on %s to be rolled back, got %v", tc.failing, err)
+			}
+			after, err := loadSecretsMap()
+			if err != nil {
+				t.Fatalf("loadSecrets failed: %v", err)
+			}
+			if len(after) != len(before) {
+				t.Errorf("Expected %v after rolling back, got %v", before, after)
+			}
+			for key, value := range before {
+				if after[key] != value {
+					t.Errorf("Expected %s=%s after rolling back, got %v", key, value, after)
+				}
+			}
+		})
+	}
+}
+
+// loadSecretsMap decrypts the default environment of my-project
+func loadSecretsMap() (map[string]string, error) {
+	_, values, err := loadSecrets("my-project", "", "")
+	return values, err
+}
@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/client"
+	"github.com/DylanBlakemore/initflow-cli/internal/storage"
+)
+
+var secretsPruneExpiredCmd = &cobra.Command{
+	Use:   "prune-expired",
+	Short: "Delete secrets past their TTL",
+	Long: "Find the secrets of a workspace environment whose --ttl has run out and delete them. " +
+		"--older-than 2160h also deletes secrets that haven't been updated for that long, though they " +
+		"haven't expired. The secrets found are listed by category first; --dry-run stops there, and " +
+		"deleting needs --confirm. Expiry is judged by the server's clock. Only metadata is read, so this " +
+		"works without the workspace key.",
+	Example: "  initflow secrets prune-expired -w api --env staging --dry-run\n" +
+		"  initflow secrets prune-expired -w api --older-than 2160h --confirm",
+	Args: cobra.NoArgs,
+	RunE: runSecretsPruneExpired,
+}
+
+var (
+	secretsPruneDryRun    bool
+	secretsPruneOlderThan time.Duration
+	secretsPruneConfirm   bool
+	secretsPrunePath      string
+)
+
+const (
+	pruneExpired = "expired"
+	pruneStale   = "stale"
+)
+
+// prunePlan lists the keys prune-expired deletes, by why
+type prunePlan struct {
+	Expired []string `json:"expired"`
+	Stale   []string `json:"stale"`
+	Deleted bool     `json:"deleted"`
+}
+
+func init() {
+	secretsCmd.AddCommand(secretsPruneExpiredCmd)
+
+	secretsPruneExpiredCmd.Flags().BoolVar(&secretsPruneDryRun, "dry-run", false, "only list what would be deleted")
+	secretsPruneExpiredCmd.Flags().DurationVar(&secretsPruneOlderThan, "older-than", 0,
+		"also delete secrets not updated for this long, e.g. 2160h")
+	secretsPruneExpiredCmd.Flags().BoolVar(&secretsPruneConfirm, "confirm", false, "delete the secrets found")
+	secretsPruneExpiredCmd.Flags().StringVar(&secretsPrunePath, "path", "", "only prune secrets in this folder, e.g. backend/")
+	secretsPruneExpiredCmd.MarkFlagsMutuallyExclusive("dry-run", "confirm")
+}
+
+// selectPrunable sorts out the secrets to prune at now: expired ones, and
+// with olderThan set, unexpired ones last updated longer ago than that.
+// Secrets without a readable update time are never stale.
+func selectPrunable(secrets []client.Secret, now time.Time, olderThan time.Duration) *prunePlan {
+	plan := &prunePlan{Expired: []string{}, Stale: []string{}}
+	for _, secret := range secrets {
+		if secretExpired(secret, now) {
+			plan.Expired = append(plan.Expired, secret.Key)
+			continue
+		}
+		if olderThan <= 0 {
+			continue
+		}
+		if updatedAt, err := time.Parse(time.RFC3339, secret.UpdatedAt); err == nil && now.Sub(updatedAt) > olderThan {
+			plan.Stale = append(plan.Stale, secret.Key)
+		}
+	}
+	sort.Strings(plan.Expired)
+	sort.Strings(plan.Stale)
+	return plan
+}
+
+func runSecretsPruneExpired(cmd *cobra.Command, args []string) error {
+	if cmd.Flags().Changed("older-than") && secretsPruneOlderThan <= 0 {
+		return fmt.Errorf("❌ --older-than must be positive, got %s", secretsPruneOlderThan)
+	}
+	prefix, err := parseSecretPath(secretsPrunePath)
+	if err != nil {
+		return fmt.Errorf("❌ %w", err)
+	}
+
+	store := storage.New()
+	if err := requireAPIAccess(store); err != nil {
+		return err
+	}
+
+	c, err := newSecretsClient(secretsEnv)
+	if err != nil {
+		return err
+	}
+	workspace, err := c.GetWorkspaceBySlug(secretsWorkspace)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to get workspace info: %w", err)
+	}
+
+	secrets, err := c.ListSecrets(workspace.ID, prefix)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to list secrets: %w", err)
+	}
+	plan := selectPrunable(secrets, serverNow(store, time.Now()), secretsPruneOlderThan)
+	total := len(plan.Expired) + len(plan.Stale)
+
+	location := secretsLocation(workspace.Slug, secretsEnv)
+	if !structuredOutput() {
+		infof("🔍 %d expired and %d stale secrets in %s\n", len(plan.Expired), len(plan.Stale), location)
+		for _, key := range plan.Expired {
+			infof("  - %s (%s)\n", key, pruneExpired)
+		}
+		for _, key := range plan.Stale {
+			infof("  - %s (%s)\n", key, pruneStale)
+		}
+	}
+	if total == 0 || secretsPruneDryRun {
+		if structuredOutput() {
+			return writeOutput(plan)
+		}
+		return nil
+	}
+	if !secretsPruneConfirm {
+		return fmt.Errorf("❌ Pruning would delete %d secrets; re-run with --confirm, or --dry-run to only list them", total)
+	}
+
+	for _, key := range append(append([]string{}, plan.Expired...), plan.Stale...) {
+		if err := c.DeleteSecret(workspace.ID, key); err != nil && exitCodeFor(err) != exitNotFound {
+			return fmt.Errorf("❌ Failed to delete %s: %w", key, err)
+		}
+	}
+	plan.Deleted = true
+
+	if structuredOutput() {
+		return writeOutput(plan)
+	}
+	infof("🗑️  Deleted %d expired and %d stale secrets from %s\n", len(plan.Expired), len(plan.Stale), location)
+	return nil
+}
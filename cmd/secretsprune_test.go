@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/client"
+)
+
+func TestSelectPrunable(t *testing.T) {
+	now := time.Date(2025, 10, 15, 12, 0, 0, 0, time.UTC)
+	secrets := []client.Secret{
+		{Key: "EXPIRED", ExpiresAt: "2025-10-15T11:00:00Z", UpdatedAt: "2025-10-15T10:00:00Z"},
+		{Key: "EXPIRES_NOW", ExpiresAt: "2025-10-15T12:00:00Z", UpdatedAt: "2025-10-15T10:00:00Z"},
+		{Key: "LIVE_TTL", ExpiresAt: "2025-10-16T12:00:00Z", UpdatedAt: "2025-01-01T00:00:00Z"},
+		{Key: "OLD", UpdatedAt: "2025-01-01T00:00:00Z"},
+		{Key: "RECENT", UpdatedAt: "2025-10-14T12:00:00Z"},
+		{Key: "NO_TIMESTAMP"},
+	}
+
+	tests := []struct {
+		name      string
+		olderThan time.Duration
+		expired   []string
+		stale     []string
+	}{
+		{"expired only", 0, []string{"EXPIRED", "EXPIRES_NOW"}, []string{}},
+		{"older than 30 days", 30 * 24 * time.Hour, []string{"EXPIRED", "EXPIRES_NOW"}, []string{"LIVE_TTL", "OLD"}},
+		{"older than a year", 365 * 24 * time.Hour, []string{"EXPIRED", "EXPIRES_NOW"}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := selectPrunable(secrets, now, tt.olderThan)
+			if !slices.Equal(plan.Expired, tt.expired) || !slices.Equal(plan.Stale, tt.stale) {
+				t.Errorf("Expected expired %v and stale %v, got %v and %v", tt.expired, tt.stale, plan.Expired, plan.Stale)
+			}
+		})
+	}
+}
+
+func TestSecretsPruneExpired(t *testing.T) {
+	fake, _ := setupSecretsTest(t)
+	t.Cleanup(func() { secretsPruneDryRun, secretsPruneConfirm = false, false })
+
+	fake.put("", "KEEP", "sealed", 3, 1)
+	fake.put("", "GONE", "sealed", 3, 1)
+	gone := fake.secrets["GONE"]
+	gone.ExpiresAt = "2025-10-01T13:00:00Z"
+	fake.secrets["GONE"] = gone
+
+	secretsPruneDryRun = true
+	var err error
+	out := captureStdout(t, func() { err = runSecretsPruneExpired(secretsPruneExpiredCmd, nil) })
+	if err != nil || !strings.Contains(out, "1 expired and 0 stale secrets in my-project") || !strings.Contains(out, "- GONE (expired)") {
+		t.Errorf("Expected a preview, got %q, %v", out, err)
+	}
+	if _, ok := fake.secrets["GONE"]; !ok {
+		t.Error("Expected --dry-run to delete nothing")
+	}
+
+	secretsPruneDryRun = false
+	captureStdout(t, func() { err = runSecretsPruneExpired(secretsPruneExpiredCmd, nil) })
+	if err == nil || !strings.Contains(err.Error(), "re-run with --confirm") {
+		t.Errorf("Expected deleting to need --confirm, got %v", err)
+	}
+
+	secretsPruneConfirm = true
+	out = captureStdout(t, func() { err = runSecretsPruneExpired(secretsPruneExpiredCmd, nil) })
+	if _, ok := fake.secrets["GONE"]; err != nil || ok || !strings.Contains(out, "Deleted 1 expired and 0 stale secrets") {
+		t.Errorf("Expected GONE deleted, got %q, %v", out, err)
+	}
+	if _, ok := fake.secrets["KEEP"]; !ok {
+		t.Error("Expected KEEP left alone")
+	}
+}
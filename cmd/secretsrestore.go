@@ -0,0 +1,290 @@
+package cmd
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/client"
+	"github.com/DylanBlakemore/initflow-cli/internal/storage"
+)
+
+var secretsRestoreCmd = &cobra.Command{
+	Use:   "restore <SNAPSHOT>",
+	Short: "Restore secrets from an export snapshot",
+	Long: "Read a file written by 'secrets export --format snapshot', check its checksum and store every " +
+		"secret it holds in the --workspace workspace and --env environment, skipping those already equal. " +
+		"--prune also deletes secrets the snapshot doesn't have, within the folder it was taken from, so the " +
+		"environment matches it exactly; deleting needs --confirm. A summary of what is added, updated and " +
+		"deleted is printed first, and a warning when the snapshot comes from another workspace or " +
+		"environment. The checksum is keyed by the workspace key of the workspace the snapshot was taken from, " +
+		"so only a holder of that key can make one that passes: restoring needs that key, and a snapshot " +
+		"taken before the key was rotated can't be checked any more. A file that fails the checksum is " +
+		"rejected and nothing is changed.",
+	Example: "  initflow secrets export -w api --env prod --format snapshot --out prod.snapshot.json\n" +
+		"  initflow secrets restore -w api --env prod prod.snapshot.json --prune --confirm",
+	Args: cobra.ExactArgs(1),
+	RunE: runSecretsRestore,
+}
+
+var (
+	secretsRestorePrune   bool
+	secretsRestoreConfirm bool
+)
+
+// snapshotFormat is the version of the snapshot layout restore reads.
+// Format 1 carried a plain SHA-256 anyone could recompute.
+const snapshotFormat = 2
+
+// snapshotKeyLabel derives the snapshot checksum key from the workspace key,
+// apart from the keys of the cipher and the secret checksums
+const snapshotKeyLabel = "initflow snapshot checksum v1"
+
+// secretsSnapshot is the export --format snapshot file: the decrypted
+// secrets of one environment, where and when they were taken, and a
+// checksum over all of it. Path is the --path folder the snapshot covers;
+// KeyVersion is the version of the workspace key the checksum is keyed by.
+type secretsSnapshot struct {
+	Format      int               `json:"format"`
+	Workspace   string            `json:"workspace"`
+	Environment string            `json:"environment,omitempty"`
+	Path        string            `json:"path,omitempty"`
+	KeyVersion  int               `json:"key_version"`
+	CreatedAt   string            `json:"created_at"`
+	Secrets     map[string]string `json:"secrets"`
+	Checksum    string            `json:"checksum"`
+}
+
+// secretsRestorePlan sorts the snapshot's keys by what restoring does to them
+type secretsRestorePlan struct {
+	Add       []string `json:"add"`
+	Update    []string `json:"update"`
+	Delete    []string `json:"delete"`
+	Unchanged int      `json:"unchanged"`
+}
+
+func init() {
+	secretsCmd.AddCommand(secretsRestoreCmd)
+
+	secretsRestoreCmd.Flags().BoolVar(&secretsRestorePrune, "prune", false, "delete secrets the snapshot doesn't have")
+	secretsRestoreCmd.Flags().BoolVar(&secretsRestoreConfirm, "confirm", false, "allow the restore to delete secrets")
+}
+
+// checksum is the HMAC-SHA256 of the snapshot's JSON without its checksum,
+// under a key derived from the workspace key, so a snapshot edited without
+// that key can't be given a checksum that passes. JSON object keys are
+// sorted, so the same snapshot always hashes the same.
+func (s secretsSnapshot) checksum(workspaceKey []byte) (string, error) {
+	s.Checksum = ""
+	data, err := json.Marshal(s)
+	if err != nil {
+		return "", err
+	}
+	derive := hmac.New(sha256.New, workspaceKey)
+	derive.Write([]byte(snapshotKeyLabel))
+	mac := hmac.New(sha256.New, derive.Sum(nil))
+	mac.Write(data)
+	return "hmac-sha256:" + hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// writeSnapshot writes the secrets of a workspace environment as a snapshot
+// checksummed under the workspace key
+func writeSnapshot(w io.Writer, workspace *client.Workspace, workspaceKey []byte, env, path string, values map[string]string) error {
+	snapshot := secretsSnapshot{
+		Format:      snapshotFormat,
+		Workspace:   workspace.Slug,
+		Environment: env,
+		Path:        path,
+		KeyVersion:  workspace.KeyVersion,
+		CreatedAt:   time.Now().UTC().Format(time.RFC3339),
+		Secrets:     values,
+	}
+	checksum, err := snapshot.checksum(workspaceKey)
+	if err != nil {
+		return err
+	}
+	snapshot.Checksum = checksum
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(snapshot)
+}
+
+// snapshotWorkspaceKey opens a workspace for the key its snapshots are
+// checksummed under. Export may read the secrets through the agent, which
+// never hands out the key, so it is always read here.
+func snapshotWorkspaceKey(slug, env string) (*client.Workspace, []byte, error) {
+	store := storage.New()
+	if err := ensureSecretsAccess(store); err != nil {
+		return nil, nil, fmt.Errorf("❌ %w", err)
+	}
+	c, err := newSecretsClient(env)
+	if err != nil {
+		return nil, nil, err
+	}
+	return openWorkspace(c, store, slug)
+}
+
+// readSnapshot reads a snapshot file and checks its format. Its checksum
+// needs the key of the workspace it names, so verify checks that.
+func readSnapshot(path string) (*secretsSnapshot, error) {
+	data, err := os.ReadFile(path) // #nosec G304 - the user names the snapshot to restore
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var snapshot secretsSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("%s isn't a secrets snapshot: %w", path, err)
+	}
+	if snapshot.Format != snapshotFormat {
+		return nil, fmt.Errorf("%s has snapshot format %d; this CLI reads format %d", path, snapshot.Format, snapshotFormat)
+	}
+	return &snapshot, nil
+}
+
+// verify checks the snapshot's checksum under the key of the workspace it was
+// taken from, and its keys
+func (s *secretsSnapshot) verify(path string, source *client.Workspace, workspaceKey []byte) error {
+	if s.KeyVersion != source.KeyVersion {
+		return fmt.Errorf("%s was taken under key version %d of %s, which is now at version %d; "+
+			"its checksum can't be checked", path, s.KeyVersion, source.Slug, source.KeyVersion)
+	}
+	checksum, err := s.checksum(workspaceKey)
+	if err != nil {
+		return fmt.Errorf("failed to check %s: %w", path, err)
+	}
+	if !hmac.Equal([]byte(s.Checksum), []byte(checksum)) {
+		return fmt.Errorf("%s fails its checksum; it was changed or corrupted after export", path)
+	}
+
+	for key := range s.Secrets {
+		if err := validateSecretKey(key); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if !strings.HasPrefix(key, s.Path) {
+			return fmt.Errorf("%s: %s is outside the snapshot's folder %s", path, key, s.Path)
+		}
+	}
+	return nil
+}
+
+// planRestore compares a snapshot with the current secrets. Secrets the
+// snapshot lacks are only deleted when pruning.
+func planRestore(snapshot, current map[string]string, prune bool) *secretsRestorePlan {
+	plan := &secretsRestorePlan{Add: []string{}, Update: []string{}, Delete: []string{}}
+	for key, value := range snapshot {
+		currentValue, ok := current[key]
+		switch {
+		case !ok:
+			plan.Add = append(plan.Add, key)
+		case currentValue != value:
+			plan.Update = append(plan.Update, key)
+		default:
+			plan.Unchanged++
+		}
+	}
+	if prune {
+		for key := range current {
+			if _, ok := snapshot[key]; !ok {
+				plan.Delete = append(plan.Delete, key)
+			}
+		}
+	}
+	sort.Strings(plan.Add)
+	sort.Strings(plan.Update)
+	sort.Strings(plan.Delete)
+	return plan
+}
+
+func runSecretsRestore(cmd *cobra.Command, args []string) error {
+	snapshot, err := readSnapshot(args[0])
+	if err != nil {
+		return fmt.Errorf("❌ %w; nothing was restored", err)
+	}
+
+	store := storage.New()
+	if err := ensureSecretsAccess(store); err != nil {
+		return fmt.Errorf("❌ %w", err)
+	}
+	c, err := newSecretsClient(secretsEnv)
+	if err != nil {
+		return err
+	}
+	workspace, workspaceKey, err := openWorkspace(c, store, secretsWorkspace)
+	if err != nil {
+		return err
+	}
+	source, sourceKey := workspace, workspaceKey
+	if snapshot.Workspace != workspace.Slug {
+		if source, sourceKey, err = openWorkspace(c, store, snapshot.Workspace); err != nil {
+			return fmt.Errorf("%w; the snapshot's checksum needs the key of %s", err, snapshot.Workspace)
+		}
+	}
+	if err := snapshot.verify(args[0], source, sourceKey); err != nil {
+		return fmt.Errorf("❌ %w; nothing was restored", err)
+	}
+
+	location := secretsLocation(workspace.Slug, secretsEnv)
+	if source := secretsLocation(snapshot.Workspace, snapshot.Environment); source != location {
+		infof("⚠️  The snapshot was taken from %s at %s; restoring it into %s\n", source, snapshot.CreatedAt, location)
+	}
+
+	secrets, err := c.FetchSecrets(workspace.ID, snapshot.Path)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to fetch secrets: %w", err)
+	}
+	current, err := decryptSecrets(workspaceKey, workspace, secretsEnv, secrets)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to decrypt secrets: %w", err)
+	}
+
+	plan := planRestore(snapshot.Secrets, current, secretsRestorePrune)
+	if !structuredOutput() {
+		infof("🔍 Restoring %s into %s: %d to add, %d to update, %d to delete, %d unchanged\n",
+			args[0], location, len(plan.Add), len(plan.Update), len(plan.Delete), plan.Unchanged)
+		for _, key := range plan.Add {
+			infof("  + %s\n", key)
+		}
+		for _, key := range plan.Update {
+			infof("  ~ %s\n", key)
+		}
+		for _, key := range plan.Delete {
+			infof("  - %s\n", key)
+		}
+	}
+	if len(plan.Delete) > 0 && !secretsRestoreConfirm {
+		return fmt.Errorf("❌ Restoring would delete %d secrets; re-run with --confirm", len(plan.Delete))
+	}
+
+	keys := append(append([]string{}, plan.Add...), plan.Update...)
+	if len(keys) > 0 {
+		uploads, err := sealUploads(workspaceKey, workspace, secretsEnv, keys, snapshot.Secrets)
+		if err != nil {
+			return fmt.Errorf("❌ %w", err)
+		}
+		if _, err := c.PutSecrets(workspace.ID, uploads); err != nil {
+			return fmt.Errorf("❌ Failed to restore secrets: %w", err)
+		}
+	}
+	for _, key := range plan.Delete {
+		if err := c.DeleteSecret(workspace.ID, key); err != nil {
+			return fmt.Errorf("❌ Failed to delete %s: %w", key, err)
+		}
+	}
+
+	if structuredOutput() {
+		return writeOutput(plan)
+	}
+	infof("✅ Restored %s into %s\n", args[0], location)
+	return nil
+}
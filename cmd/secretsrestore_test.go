@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// exportSnapshot writes a snapshot of the default environment to a temp file
+func exportSnapshot(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	secretsExportFormat, secretsExportOut = "snapshot", path
+	defer func() { secretsExportFormat, secretsExportOut = "dotenv", "" }()
+	captureStdout(t, func() {
+		if err := runSecretsExport(secretsExportCmd, nil); err != nil {
+			t.Fatalf("runSecretsExport --format snapshot failed: %v", err)
+		}
+	})
+	return path
+}
+
+func TestSecretsRestore(t *testing.T) {
+	fake, _ := setupSecretsTest(t)
+	t.Cleanup(func() {
+		secretsEnv = ""
+		secretsRestorePrune, secretsRestoreConfirm = false, false
+	})
+
+	captureStdout(t, func() {
+		if err := runSecretsAdd(secretsAddCmd, []string{"API_KEY=abc", "DB_URL=postgres://db", "KEEP=same"}); err != nil {
+			t.Fatalf("runSecretsAdd failed: %v", err)
+		}
+	})
+	path := exportSnapshot(t)
+
+	captureStdout(t, func() {
+		if err := runSecretsAdd(secretsAddCmd, []string{"API_KEY=changed", "EXTRA=new"}); err != nil {
+			t.Fatalf("runSecretsAdd failed: %v", err)
+		}
+	})
+	delete(fake.secrets, "DB_URL")
+
+	var err error
+	out := captureStdout(t, func() { err = runSecretsRestore(secretsRestoreCmd, []string{path}) })
+	if err != nil {
+		t.Fatalf("runSecretsRestore failed: %v", err)
+	}
+	if !strings.Contains(out, "1 to add, 1 to update, 0 to delete, 1 unchanged") {
+		t.Errorf("Expected a summary, got %q", out)
+	}
+	if fake.secrets["API_KEY"].Size != len("abc") || fake.secrets["DB_URL"].Size != len("postgres://db") ||
+		fake.secrets["KEEP"].Version != 1 {
+		t.Errorf("Expected changed and missing secrets restored and equal ones left alone, got %+v", fake.secrets)
+	}
+
+	secretsRestorePrune = true
+	out = captureStdout(t, func() { err = runSecretsRestore(secretsRestoreCmd, []string{path}) })
+	if err == nil || !strings.Contains(err.Error(), "would delete 1 secrets; re-run with --confirm") ||
+		!strings.Contains(out, "- EXTRA") {
+		t.Errorf("Expected --prune to ask for --confirm, got %q, %v", out, err)
+	}
+	if _, ok := fake.secrets["EXTRA"]; !ok {
+		t.Error("Expected nothing deleted without --confirm")
+	}
+
+	secretsRestoreConfirm = true
+	captureStdout(t, func() { err = runSecretsRestore(secretsRestoreCmd, []string{path}) })
+	if _, ok := fake.secrets["EXTRA"]; err != nil || ok {
+		t.Errorf("Expected --prune --confirm to delete EXTRA, got %v", err)
+	}
+
+	secretsEnv = "prod"
+	out = captureStdout(t, func() { err = runSecretsRestore(secretsRestoreCmd, []string{path}) })
+	if err != nil || !strings.Contains(out, "The snapshot was taken from my-project at") ||
+		fake.secrets["prod:API_KEY"].Size != len("abc") {
+		t.Errorf("Expected a warning when restoring into another environment, got %q, %v", out, err)
+	}
+}
+
+func TestSecretsRestoreRejectsTamperedSnapshot(t *testing.T) {
+	fake, _ := setupSecretsTest(t)
+
+	captureStdout(t, func() {
+		if err := runSecretsAdd(secretsAddCmd, []string{"API_KEY=abc"}); err != nil {
+			t.Fatalf("runSecretsAdd failed: %v", err)
+		}
+	})
+	path := exportSnapshot(t)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read snapshot: %v", err)
+	}
+	tampered := strings.Replace(string(data), `"API_KEY": "abc"`, `"API_KEY": "evil"`, 1)
+	if tampered == string(data) {
+		t.Fatalf("Expected API_KEY in the snapshot, got %s", data)
+	}
+	if err := os.WriteFile(path, []byte(tampered), 0600); err != nil {
+		t.Fatalf("Failed to write snapshot: %v", err)
+	}
+
+	err = runSecretsRestore(secretsRestoreCmd, []string{path})
+	if err == nil || !strings.Contains(err.Error(), "fails its checksum") {
+		t.Errorf("Expected a checksum failure, got %v", err)
+	}
+	if secret := fake.secrets["API_KEY"]; secret.Version != 1 || secret.Size != len("abc") {
+		t.Errorf("Expected nothing restored, got %+v", secret)
+	}
+}
+
+func TestSecretsRestoreRejectsRecomputedPlainHash(t *testing.T) {
+	fake, _ := setupSecretsTest(t)
+
+	captureStdout(t, func() {
+		if err := runSecretsAdd(secretsAddCmd, []string{"API_KEY=abc"}); err != nil {
+			t.Fatalf("runSecretsAdd failed: %v", err)
+		}
+	})
+	path := exportSnapshot(t)
+
+	snapshot, err := readSnapshot(path)
+	if err != nil {
+		t.Fatalf("readSnapshot failed: %v", err)
+	}
+	if !strings.HasPrefix(snapshot.Checksum, "hmac-sha256:") {
+		t.Errorf("Expected a keyed checksum, got %q", snapshot.Checksum)
+	}
+
+	// Whoever can write the file but has no workspace key can only rehash it
+	snapshot.Secrets["API_KEY"] = "evil"
+	snapshot.Checksum = ""
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		t.Fatalf("Failed to encode snapshot: %v", err)
+	}
+	sum := sha256.Sum256(data)
+	snapshot.Checksum = "sha256:" + hex.EncodeToString(sum[:])
+	if data, err = json.Marshal(snapshot); err != nil {
+		t.Fatalf("Failed to encode snapshot: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("Failed to write snapshot: %v", err)
+	}
+
+	err = runSecretsRestore(secretsRestoreCmd, []string{path})
+	if err == nil || !strings.Contains(err.Error(), "fails its checksum") {
+		t.Errorf("Expected a checksum failure, got %v", err)
+	}
+	if secret := fake.secrets["API_KEY"]; secret.Version != 1 || secret.Size != len("abc") {
+		t.Errorf("Expected nothing restored, got %+v", secret)
+	}
+}
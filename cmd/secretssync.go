@@ -0,0 +1,457 @@
+package cmd
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/client"
+	"github.com/DylanBlakemore/initflow-cli/internal/config"
+	"github.com/DylanBlakemore/initflow-cli/internal/encoding"
+	"github.com/DylanBlakemore/initflow-cli/internal/fsutil"
+	"github.com/DylanBlakemore/initflow-cli/internal/storage"
+)
+
+var secretsSyncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Send changes queued while offline",
+	Long: "When the server can't be reached, 'secrets add' and 'secrets rm' queue their changes on this " +
+		"device, values encrypted with a key only this device holds, and exit with 8. sync replays the queue in " +
+		"order once the server is back. Each change is sent with the idempotency key it was queued with, so " +
+		"one replayed twice is applied once. A secret someone else changed after it was queued is a " +
+		"conflict, but not one this sync wrote or one already holding the queued value, e.g. because the " +
+		"response to an earlier sync was lost. The conflicting change stays queued, with any later ones for " +
+		"that secret, until sync runs with " +
+		"--force to apply it anyway or --discard to drop the whole queue. 'auth status' shows how many " +
+		"changes are waiting.",
+	Example: "  initflow secrets sync\n" +
+		"  initflow secrets sync --force",
+	Args: cobra.NoArgs,
+	RunE: runSecretsSync,
+}
+
+var (
+	secretsSyncForce   bool
+	secretsSyncDiscard bool
+)
+
+const (
+	pendingPut    = "put"
+	pendingDelete = "delete"
+)
+
+// pendingDirPermissions are for the config folder when the queue creates it
+const pendingDirPermissions = 0700
+
+// pendingOp is a change queued while offline. ID is the idempotency key it
+// is sent with. Sealed is the value of a put, encrypted with queueKey.
+// BaseVersion is the remote version the change applies over once an earlier
+// queued change of the same secret was synced, and 0 while unknown.
+type pendingOp struct {
+	ID          string `json:"id"`
+	Op          string `json:"op"`
+	Workspace   string `json:"workspace"`
+	Environment string `json:"environment,omitempty"`
+	Key         string `json:"key"`
+	Sealed      string `json:"sealed,omitempty"`
+	Size        int    `json:"size,omitempty"`
+	ExpiresAt   string `json:"expires_at,omitempty"`
+	QueuedAt    string `json:"queued_at"`
+	BaseVersion int    `json:"base_version,omitempty"`
+}
+
+// secretsSyncResult is what sync did, for -o json
+type secretsSyncResult struct {
+	Synced    []string `json:"synced"`
+	Conflicts []string `json:"conflicts"`
+	Pending   int      `json:"pending"`
+}
+
+func init() {
+	secretsCmd.AddCommand(secretsSyncCmd)
+
+	secretsSyncCmd.Flags().BoolVar(&secretsSyncForce, "force", false, "apply queued changes even over newer remote ones")
+	secretsSyncCmd.Flags().BoolVar(&secretsSyncDiscard, "discard", false, "drop every queued change without sending it")
+	secretsSyncCmd.MarkFlagsMutuallyExclusive("force", "discard")
+}
+
+// pendingPath is the queue file of the active profile
+func pendingPath() (string, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	profile := config.Get().ActiveProfile()
+	if profile == "" || profile == config.DefaultProfile {
+		return filepath.Join(dir, "pending.json"), nil
+	}
+	return filepath.Join(dir, "pending-"+profile+".json"), nil
+}
+
+// readPending reads the queue, which is empty when the file doesn't exist
+func readPending() ([]pendingOp, error) {
+	path, err := pendingPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path) // #nosec G304 - our own queue file
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the offline queue: %w", err)
+	}
+	var ops []pendingOp
+	if err := json.Unmarshal(data, &ops); err != nil {
+		return nil, fmt.Errorf("failed to parse the offline queue %s: %w", path, err)
+	}
+	return ops, nil
+}
+
+// writePending replaces the queue, removing the file once it is empty
+func writePending(ops []pendingOp) error {
+	path, err := pendingPath()
+	if err != nil {
+		return err
+	}
+	if len(ops) == 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to clear the offline queue: %w", err)
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), pendingDirPermissions); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	return fsutil.WriteFileAtomic(path, fsutil.PrivateFilePermissions, func(w io.Writer) error {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(ops)
+	})
+}
+
+// queueKeyLabel derives the queue key from the device's encryption key, so
+// the two never share a key
+const queueKeyLabel = "initflow offline queue v1"
+
+// queueKey is the key queued values are sealed with, derived from this
+// device's X25519 private key. Unlike a cached workspace key, a rotation
+// can't replace it before the queue is synced.
+func queueKey(store *storage.Storage) ([]byte, error) {
+	privateKey, err := store.GetEncryptionPrivateKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read this device's encryption key: %w", err)
+	}
+	mac := hmac.New(sha256.New, privateKey)
+	mac.Write([]byte(queueKeyLabel))
+	return mac.Sum(nil), nil
+}
+
+// sealPending encrypts a queued value under workspace ID 0, which no stored
+// secret has, so a queued value never opens as a secret on the server
+func sealPending(sealKey []byte, env, key string, value []byte) (string, error) {
+	sealed, err := encryptSecret(sealKey, 0, env, key, value)
+	if err != nil {
+		return "", err
+	}
+	return encoding.Encode(sealed), nil
+}
+
+// openPending decrypts a value sealed by sealPending
+func openPending(sealKey []byte, op pendingOp) ([]byte, error) {
+	sealed, err := encoding.Decode(op.Sealed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the queued value of %s: %w", op.Key, err)
+	}
+	return decryptSecret(sealKey, 0, op.Environment, op.Key, sealed)
+}
+
+// queueOffline adds ops to the queue after a network error and tells the
+// user, exiting with exitNetwork since nothing reached the server yet
+func queueOffline(cause error, ops []pendingOp) error {
+	queued, err := readPending()
+	if err != nil {
+		return fmt.Errorf("❌ %w; nothing was queued after: %w", err, cause)
+	}
+	if err := writePending(append(queued, ops...)); err != nil {
+		return fmt.Errorf("❌ Failed to queue changes: %w; nothing was queued after: %w", err, cause)
+	}
+
+	for _, op := range ops {
+		verb := "store"
+		if op.Op == pendingDelete {
+			verb = "delete"
+		}
+		infof("📥 Queued: %s %s in %s\n", verb, op.Key, secretsLocation(op.Workspace, op.Environment))
+	}
+	infof("⚠️  The server can't be reached. Run 'initflow secrets sync' when you're back online "+
+		"(%d changes waiting)\n", len(queued)+len(ops))
+	return &silentExit{code: exitNetwork}
+}
+
+// newPendingOp starts a queued change of key in the --workspace workspace
+func newPendingOp(store *storage.Storage, op, key string) (pendingOp, error) {
+	id, err := client.NewIdempotencyKey()
+	if err != nil {
+		return pendingOp{}, err
+	}
+	return pendingOp{
+		ID:          id,
+		Op:          op,
+		Workspace:   secretsWorkspace,
+		Environment: secretsEnv,
+		Key:         key,
+		QueuedAt:    serverNow(store, time.Now()).UTC().Format(time.RFC3339),
+	}, nil
+}
+
+// queuePuts queues storing values[key] for each of keys in the --workspace
+// workspace, which this device must hold the key of to sync them later
+func queuePuts(store *storage.Storage, cause error, keys []string, values map[string]string, expiresAt time.Time) error {
+	if scopedToken() != "" || !store.HasWorkspaceKey(secretsWorkspace) {
+		return cause
+	}
+	sealKey, err := queueKey(store)
+	if err != nil {
+		return cause
+	}
+
+	ops := make([]pendingOp, 0, len(keys))
+	for _, key := range keys {
+		op, err := newPendingOp(store, pendingPut, key)
+		if err != nil {
+			return fmt.Errorf("❌ %w", err)
+		}
+		if op.Sealed, err = sealPending(sealKey, secretsEnv, key, []byte(values[key])); err != nil {
+			return fmt.Errorf("❌ Failed to encrypt %s: %w", key, err)
+		}
+		op.Size = len(values[key])
+		if !expiresAt.IsZero() {
+			op.ExpiresAt = expiresAt.UTC().Format(time.RFC3339)
+		}
+		ops = append(ops, op)
+	}
+	return queueOffline(cause, ops)
+}
+
+// queueDeletes queues deleting each of keys from the --workspace workspace
+func queueDeletes(store *storage.Storage, cause error, keys []string) error {
+	ops := make([]pendingOp, 0, len(keys))
+	for _, key := range keys {
+		op, err := newPendingOp(store, pendingDelete, key)
+		if err != nil {
+			return fmt.Errorf("❌ %w", err)
+		}
+		ops = append(ops, op)
+	}
+	return queueOffline(cause, ops)
+}
+
+// offline reports whether err means the server couldn't be reached
+func offline(err error) bool {
+	return exitCodeFor(err) == exitNetwork
+}
+
+// syncTarget is an opened workspace environment the queue writes to
+type syncTarget struct {
+	client       *client.Client
+	workspace    *client.Workspace
+	workspaceKey []byte
+}
+
+// remoteChanged reports whether the secret changed on the server after op
+// was queued: it moved past the op's BaseVersion when that is known, and
+// otherwise was updated after the op was queued. A secret that is gone, or
+// already holds the value a put queued, never conflicts.
+func remoteChanged(target *syncTarget, op pendingOp, sealKey []byte) (bool, error) {
+	current, err := target.client.GetSecret(target.workspace.ID, op.Key)
+	if exitCodeFor(err) == exitNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if op.BaseVersion > 0 {
+		return current.Version != op.BaseVersion, nil
+	}
+	if op.Op == pendingPut && current.Checksum != "" {
+		value, err := openPending(sealKey, op)
+		if err != nil {
+			return false, fmt.Errorf("failed to read the queued value of %s: %w", op.Key, err)
+		}
+		checksum := secretChecksum(target.workspaceKey, target.workspace.ID, op.Environment, op.Key, value)
+		if hmac.Equal([]byte(current.Checksum), []byte(checksum)) {
+			return false, nil
+		}
+	}
+	updatedAt, err := time.Parse(time.RFC3339, current.UpdatedAt)
+	if err != nil {
+		return false, nil
+	}
+	queuedAt, err := time.Parse(time.RFC3339, op.QueuedAt)
+	return err == nil && updatedAt.After(queuedAt), nil
+}
+
+// replay sends one queued change, opening a queued value with sealKey and
+// encrypting it with the workspace's current key. It returns the version a
+// put stored, and 0 for a delete.
+func replay(target *syncTarget, op pendingOp, sealKey []byte) (int, error) {
+	if op.Op == pendingDelete {
+		err := target.client.DeleteSecretWith(target.workspace.ID, op.Key, client.DeleteOptions{IdempotencyKey: op.ID})
+		if exitCodeFor(err) == exitNotFound {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	value, err := openPending(sealKey, op)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read the queued value of %s: %w", op.Key, err)
+	}
+	ciphertext, err := encryptSecret(target.workspaceKey, target.workspace.ID, op.Environment, op.Key, value)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encrypt %s: %w", op.Key, err)
+	}
+	opts := client.PutOptions{IdempotencyKey: op.ID,
+		Checksum: secretChecksum(target.workspaceKey, target.workspace.ID, op.Environment, op.Key, value)}
+	if expiresAt, err := time.Parse(time.RFC3339, op.ExpiresAt); err == nil {
+		opts.ExpiresAt = expiresAt
+	}
+	stored, err := target.client.PutSecretWith(target.workspace.ID, op.Key, ciphertext, op.Size, target.workspace.KeyVersion, opts)
+	if err != nil {
+		return 0, err
+	}
+	return stored.Version, nil
+}
+
+func runSecretsSync(cmd *cobra.Command, args []string) error {
+	ops, err := readPending()
+	if err != nil {
+		return fmt.Errorf("❌ %w", err)
+	}
+	if secretsSyncDiscard {
+		if err := writePending(nil); err != nil {
+			return fmt.Errorf("❌ %w", err)
+		}
+		infof("🗑️  Discarded %d queued changes\n", len(ops))
+		return nil
+	}
+	if len(ops) == 0 {
+		if structuredOutput() {
+			return writeOutput(secretsSyncResult{Synced: []string{}, Conflicts: []string{}})
+		}
+		infoln("✅ Nothing to sync")
+		return nil
+	}
+
+	store := storage.New()
+	if err := ensureSecretsAccess(store); err != nil {
+		return fmt.Errorf("❌ %w", err)
+	}
+
+	sealKey, err := queueKey(store)
+	if err != nil {
+		return fmt.Errorf("❌ %w", err)
+	}
+
+	result := secretsSyncResult{Synced: []string{}, Conflicts: []string{}}
+	targets := map[string]*syncTarget{}
+	blocked := map[string]bool{}
+	// Secrets this run wrote: their later changes apply over our own
+	written := map[string]bool{}
+	var remaining []pendingOp
+	for i, op := range ops {
+		location := secretsLocation(op.Workspace, op.Environment)
+		name := location + " " + op.Key
+		// A later change to a secret waits behind a conflicting earlier one
+		if blocked[name] {
+			remaining = append(remaining, op)
+			continue
+		}
+
+		target, ok := targets[location]
+		if !ok {
+			c, err := newSecretsClient(op.Environment)
+			if err != nil {
+				return err
+			}
+			workspace, workspaceKey, err := openWorkspace(c, store, op.Workspace)
+			if err != nil {
+				if saveErr := writePending(append(remaining, ops[i:]...)); saveErr != nil {
+					return fmt.Errorf("❌ %w", saveErr)
+				}
+				return err
+			}
+			target = &syncTarget{client: c, workspace: workspace, workspaceKey: workspaceKey}
+			targets[location] = target
+		}
+
+		if !secretsSyncForce && !written[name] {
+			changed, err := remoteChanged(target, op, sealKey)
+			if err != nil {
+				remaining = append(remaining, ops[i:]...)
+				return syncStopped(err, remaining, result)
+			}
+			if changed {
+				blocked[name] = true
+				remaining = append(remaining, op)
+				result.Conflicts = append(result.Conflicts, name)
+				infof("⚠️  %s changed on the server after it was queued; kept in the queue\n", name)
+				continue
+			}
+		}
+
+		version, err := replay(target, op, sealKey)
+		if err != nil {
+			remaining = append(remaining, ops[i:]...)
+			return syncStopped(err, remaining, result)
+		}
+		written[name] = true
+		// Later changes of the secret apply over this version, should sync
+		// stop before them and a later run pick them up
+		for j := i + 1; j < len(ops); j++ {
+			if ops[j].Workspace == op.Workspace && ops[j].Environment == op.Environment && ops[j].Key == op.Key {
+				ops[j].BaseVersion = version
+			}
+		}
+		result.Synced = append(result.Synced, name)
+		infof("✅ Synced: %s %s\n", op.Op, name)
+	}
+
+	if err := writePending(remaining); err != nil {
+		return fmt.Errorf("❌ %w", err)
+	}
+	result.Pending = len(remaining)
+
+	if structuredOutput() {
+		if err := writeOutput(result); err != nil {
+			return err
+		}
+	} else if len(result.Conflicts) > 0 {
+		infof("⚠️  %d conflicts; %d changes are still queued. Re-run with --force to overwrite or --discard to drop them\n",
+			len(result.Conflicts), result.Pending)
+	} else {
+		infof("✅ Sent %d queued changes\n", len(result.Synced))
+	}
+	if len(result.Conflicts) > 0 {
+		return &silentExit{code: exitConflict}
+	}
+	return nil
+}
+
+// syncStopped saves what is left of the queue when a change fails to send
+func syncStopped(cause error, remaining []pendingOp, result secretsSyncResult) error {
+	if err := writePending(remaining); err != nil {
+		return fmt.Errorf("❌ %w; after: %w", err, cause)
+	}
+	return fmt.Errorf("❌ Sync stopped after %d changes, %d still queued: %w",
+		len(result.Synced), len(remaining), cause)
+}
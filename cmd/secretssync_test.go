@@ -0,0 +1,250 @@
+package cmd
+
+import (
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/config"
+	"github.com/DylanBlakemore/initflow-cli/internal/storage"
+)
+
+// goOffline points the CLI at a server that refuses connections until the
+// returned function restores serverURL
+func goOffline(t *testing.T) func() {
+	t.Helper()
+	serverURL := config.Get().APIBaseURL
+	closed := httptest.NewServer(nil)
+	closed.Close()
+	if err := config.Set("api_base_url", closed.URL); err != nil {
+		t.Fatalf("Failed to set API URL: %v", err)
+	}
+	return func() {
+		if err := config.Set("api_base_url", serverURL); err != nil {
+			t.Fatalf("Failed to set API URL: %v", err)
+		}
+	}
+}
+
+func TestSecretsQueueOfflineAndSync(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	fake, _ := setupSecretsTest(t)
+	t.Cleanup(func() { secretsRmForce = false })
+
+	captureStdout(t, func() {
+		if err := runSecretsAdd(secretsAddCmd, []string{"OLD_KEY=old"}); err != nil {
+			t.Fatalf("runSecretsAdd failed: %v", err)
+		}
+	})
+
+	online := goOffline(t)
+	var err error
+	out := captureStdout(t, func() { err = runSecretsAdd(secretsAddCmd, []string{"DB_URL=postgres://offline"}) })
+	if exitCodeFor(err) != exitNetwork || !strings.Contains(out, "Queued: store DB_URL in my-project") {
+		t.Fatalf("Expected the add to be queued and exit %d, got %q, %v", exitNetwork, out, err)
+	}
+	secretsRmForce = true
+	captureStdout(t, func() { err = runSecretsRm(secretsRmCmd, []string{"OLD_KEY"}) })
+	if exitCodeFor(err) != exitNetwork {
+		t.Fatalf("Expected the rm to be queued, got %v", err)
+	}
+
+	path, _ := pendingPath()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Expected a queue file: %v", err)
+	}
+	if strings.Contains(string(data), "postgres://offline") {
+		t.Errorf("Expected the queued value encrypted, got %s", data)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected an owner-only queue, got %v, %v", info.Mode(), err)
+	}
+	out = captureStdout(t, func() { _ = runAuthStatus(authStatusCmd, nil) })
+	if !strings.Contains(out, "2 changes queued offline") {
+		t.Errorf("Expected auth status to count the queue, got %q", out)
+	}
+	queued, _ := readPending()
+
+	online()
+	out = captureStdout(t, func() { err = runSecretsSync(secretsSyncCmd, nil) })
+	if err != nil || !strings.Contains(out, "Sent 2 queued changes") {
+		t.Fatalf("Expected the queue replayed, got %q, %v", out, err)
+	}
+	if _, ok := fake.secrets["OLD_KEY"]; ok {
+		t.Error("Expected OLD_KEY deleted")
+	}
+	keys := fake.idempotencyKeys[len(fake.idempotencyKeys)-2:]
+	if keys[0] != queued[0].ID || keys[1] != queued[1].ID {
+		t.Errorf("Expected the queued idempotency keys %s and %s, got %v", queued[0].ID, queued[1].ID, keys)
+	}
+	out = captureStdout(t, func() { err = runSecretsGet(secretsGetCmd, []string{"DB_URL"}) })
+	if err != nil || out != "postgres://offline" {
+		t.Errorf("Expected the queued value stored, got %q, %v", out, err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected the queue cleared, got %v", err)
+	}
+}
+
+func TestSecretsSyncConflict(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	fake, _ := setupSecretsTest(t)
+	t.Cleanup(func() { secretsSyncForce = false })
+
+	online := goOffline(t)
+	captureStdout(t, func() { _ = runSecretsAdd(secretsAddCmd, []string{"API_KEY=mine", "OTHER=fine"}) })
+	online()
+
+	// Someone else stores API_KEY after the change was queued
+	secret, _ := fake.put("", "API_KEY", "theirs", 6, 1)
+	secret.UpdatedAt = "2099-01-01T00:00:00Z"
+	fake.secrets["API_KEY"] = secret
+
+	var err error
+	out := captureStdout(t, func() { err = runSecretsSync(secretsSyncCmd, nil) })
+	if exitCodeFor(err) != exitConflict || !strings.Contains(out, "my-project API_KEY changed on the server") {
+		t.Fatalf("Expected a conflict, got %q, %v", out, err)
+	}
+	if fake.secrets["API_KEY"].Ciphertext != "theirs" || fake.secrets["OTHER"].Size != len("fine") {
+		t.Errorf("Expected only the change without a conflict applied, got %+v", fake.secrets)
+	}
+	if pending, _ := readPending(); len(pending) != 1 || pending[0].Key != "API_KEY" {
+		t.Errorf("Expected API_KEY left in the queue, got %+v", pending)
+	}
+
+	secretsSyncForce = true
+	captureStdout(t, func() { err = runSecretsSync(secretsSyncCmd, nil) })
+	if err != nil || fake.secrets["API_KEY"].Size != len("mine") {
+		t.Errorf("Expected --force to apply the queued value, got %+v, %v", fake.secrets["API_KEY"], err)
+	}
+}
+
+func TestSecretsSyncAfterRotation(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	fake, _ := setupSecretsTest(t)
+
+	online := goOffline(t)
+	captureStdout(t, func() { _ = runSecretsAdd(secretsAddCmd, []string{"API_KEY=queued"}) })
+	online()
+
+	// The key is rotated, and cached on this device, before the queue is synced
+	captureStdout(t, func() {
+		if err := runWorkspaceRotate(workspaceRotateCmd, []string{"my-project"}); err != nil {
+			t.Fatalf("runWorkspaceRotate failed: %v", err)
+		}
+	})
+
+	var err error
+	captureStdout(t, func() { err = runSecretsSync(secretsSyncCmd, nil) })
+	if err != nil || fake.secrets["API_KEY"].KeyVersion != 2 {
+		t.Fatalf("Expected the queued value stored under the new key, got %+v, %v", fake.secrets["API_KEY"], err)
+	}
+	out := captureStdout(t, func() { err = runSecretsGet(secretsGetCmd, []string{"API_KEY"}) })
+	if err != nil || out != "queued" {
+		t.Errorf("Expected the queued value after the rotation, got %q, %v", out, err)
+	}
+}
+
+// backdateQueue makes every queued change look an hour old
+func backdateQueue(t *testing.T) {
+	t.Helper()
+	ops, err := readPending()
+	if err != nil {
+		t.Fatalf("readPending failed: %v", err)
+	}
+	for i := range ops {
+		ops[i].QueuedAt = time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	}
+	if err := writePending(ops); err != nil {
+		t.Fatalf("writePending failed: %v", err)
+	}
+}
+
+func TestSecretsSyncSameSecretTwice(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	fake, _ := setupSecretsTest(t)
+	fake.liveTimestamps = true
+	t.Cleanup(func() { secretsRmForce = false })
+
+	online := goOffline(t)
+	secretsRmForce = true
+	captureStdout(t, func() {
+		_ = runSecretsAdd(secretsAddCmd, []string{"API_KEY=one"})
+		_ = runSecretsAdd(secretsAddCmd, []string{"API_KEY=two"})
+		_ = runSecretsAdd(secretsAddCmd, []string{"TEMP=x"})
+		_ = runSecretsRm(secretsRmCmd, []string{"TEMP"})
+	})
+	backdateQueue(t)
+	online()
+
+	var err error
+	out := captureStdout(t, func() { err = runSecretsSync(secretsSyncCmd, nil) })
+	if err != nil || !strings.Contains(out, "Sent 4 queued changes") {
+		t.Fatalf("Expected both changes of each secret synced, got %q, %v", out, err)
+	}
+	if _, ok := fake.secrets["TEMP"]; ok || len(fake.versions["API_KEY"]) != 2 {
+		t.Errorf("Expected API_KEY stored twice and TEMP deleted, got %+v", fake.secrets)
+	}
+	out = captureStdout(t, func() { err = runSecretsGet(secretsGetCmd, []string{"API_KEY"}) })
+	if err != nil || out != "two" {
+		t.Errorf("Expected the last queued value, got %q, %v", out, err)
+	}
+}
+
+func TestSecretsSyncAfterLostResponse(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	fake, _ := setupSecretsTest(t)
+	fake.liveTimestamps = true
+
+	online := goOffline(t)
+	captureStdout(t, func() { _ = runSecretsAdd(secretsAddCmd, []string{"API_KEY=one"}) })
+	backdateQueue(t)
+	online()
+
+	// The change reached the server, but its response never came back
+	queued, _ := readPending()
+	c, _ := newSecretsClient("")
+	sealKey, _ := queueKey(storage.New())
+	workspace, workspaceKey, _ := openWorkspace(c, storage.New(), "my-project")
+	if _, err := replay(&syncTarget{client: c, workspace: workspace, workspaceKey: workspaceKey}, queued[0], sealKey); err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+
+	var err error
+	out := captureStdout(t, func() { err = runSecretsSync(secretsSyncCmd, nil) })
+	if err != nil || !strings.Contains(out, "Sent 1 queued changes") {
+		t.Errorf("Expected a change already on the server not to conflict with itself, got %q, %v", out, err)
+	}
+}
+
+func TestSecretsSyncResumesAfterStop(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	fake, _ := setupSecretsTest(t)
+	fake.liveTimestamps = true
+
+	online := goOffline(t)
+	captureStdout(t, func() {
+		_ = runSecretsAdd(secretsAddCmd, []string{"API_KEY=one"})
+		_ = runSecretsAdd(secretsAddCmd, []string{"OTHER=x"})
+		_ = runSecretsAdd(secretsAddCmd, []string{"API_KEY=two"})
+	})
+	backdateQueue(t)
+	online()
+
+	fake.forbidden = map[string]bool{"OTHER": true}
+	var err error
+	captureStdout(t, func() { err = runSecretsSync(secretsSyncCmd, nil) })
+	if pending, _ := readPending(); err == nil || len(pending) != 2 || pending[1].BaseVersion != 1 {
+		t.Fatalf("Expected sync to stop with API_KEY's next change based on version 1, got %+v, %v", pending, err)
+	}
+
+	// The next run applies the rest over the version the first run stored
+	fake.forbidden = nil
+	out := captureStdout(t, func() { err = runSecretsSync(secretsSyncCmd, nil) })
+	if err != nil || fake.secrets["API_KEY"].Size != len("two") {
+		t.Errorf("Expected the rest of the queue synced, got %q, %v", out, err)
+	}
+}
@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/client"
+	"github.com/DylanBlakemore/initflow-cli/internal/storage"
+)
+
+var secretsTagsCmd = &cobra.Command{
+	Use:   "tags",
+	Short: "List the tags used on secrets",
+	Long: "List every tag on the secrets of a workspace environment with the number of secrets carrying " +
+		"it, most used first. Tags are plaintext labels stored beside the encrypted value, so this works " +
+		"without the workspace key. --path backend/ counts one folder.",
+	Example: "  initflow secrets tags -w api --env prod",
+	Args:    cobra.NoArgs,
+	RunE:    runSecretsTags,
+}
+
+var secretsTagCmd = &cobra.Command{
+	Use:   "tag",
+	Short: "Add or remove tags on a secret",
+	Long: "Change the tags of one secret. Only its metadata is updated: the value isn't downloaded or " +
+		"uploaded again and no new version is created. Tags are lowercase letters, digits, '.', '_' " +
+		"and '-'; they aren't encrypted, so don't put anything sensitive in them.",
+}
+
+var secretsTagAddCmd = &cobra.Command{
+	Use:     "add <KEY> <TAG>...",
+	Short:   "Tag a secret",
+	Example: "  initflow secrets tag add -w api STRIPE_KEY payments rotate-quarterly",
+	Args:    cobra.MinimumNArgs(2),
+	RunE:    runSecretsTagAdd,
+}
+
+var secretsTagRmCmd = &cobra.Command{
+	Use:     "rm <KEY> <TAG>...",
+	Aliases: []string{"remove"},
+	Short:   "Remove tags from a secret",
+	Args:    cobra.MinimumNArgs(2),
+	RunE:    runSecretsTagRm,
+}
+
+var secretsTagsPath string
+
+// tagPattern is the shape of a secret tag, e.g. payments or rotate-quarterly
+var tagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+
+// tagCount is how many secrets carry a tag
+type tagCount struct {
+	Tag     string `json:"tag"`
+	Secrets int    `json:"secrets"`
+}
+
+var tagColumns = []tableColumn{
+	{Name: "tag", Header: "Tag", Default: true},
+	{Name: "secrets", Header: "Secrets", Default: true},
+}
+
+func init() {
+	secretsCmd.AddCommand(secretsTagsCmd)
+	secretsCmd.AddCommand(secretsTagCmd)
+	secretsTagCmd.AddCommand(secretsTagAddCmd)
+	secretsTagCmd.AddCommand(secretsTagRmCmd)
+
+	secretsTagsCmd.Flags().StringVar(&secretsTagsPath, "path", "", "only count secrets in this folder, e.g. backend/")
+}
+
+// validateTags checks every tag is shaped like tagPattern
+func validateTags(tags []string) error {
+	for _, tag := range tags {
+		if !tagPattern.MatchString(tag) {
+			return fmt.Errorf("invalid tag %q: use lowercase letters, digits, '.', '_' and '-'", tag)
+		}
+	}
+	return nil
+}
+
+// countTags counts the secrets carrying each tag, most used first, then by name
+func countTags(secrets []client.Secret) []tagCount {
+	counts := map[string]int{}
+	for _, secret := range secrets {
+		for _, tag := range secret.Tags {
+			counts[tag]++
+		}
+	}
+	tags := make([]tagCount, 0, len(counts))
+	for tag, n := range counts {
+		tags = append(tags, tagCount{Tag: tag, Secrets: n})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Secrets != tags[j].Secrets {
+			return tags[i].Secrets > tags[j].Secrets
+		}
+		return tags[i].Tag < tags[j].Tag
+	})
+	return tags
+}
+
+func runSecretsTags(cmd *cobra.Command, args []string) error {
+	prefix, err := parseSecretPath(secretsTagsPath)
+	if err != nil {
+		return fmt.Errorf("❌ %w", err)
+	}
+
+	store := storage.New()
+	if err := requireAPIAccess(store); err != nil {
+		return err
+	}
+
+	c, err := newSecretsClient(secretsEnv)
+	if err != nil {
+		return err
+	}
+	workspace, err := c.GetWorkspaceBySlug(secretsWorkspace)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to get workspace info: %w", err)
+	}
+
+	secrets, err := c.ListSecrets(workspace.ID, prefix)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to list secrets: %w", err)
+	}
+	tags := countTags(secrets)
+
+	if structuredOutput() {
+		return writeOutput(tags)
+	}
+	if len(tags) == 0 {
+		infof("No tagged secrets in %s. Tag one with 'initflow secrets tag add -w %s KEY TAG'\n",
+			secretsLocation(workspace.Slug, secretsEnv), workspace.Slug)
+		return nil
+	}
+
+	rows := make([]map[string]string, len(tags))
+	for i, tag := range tags {
+		rows[i] = map[string]string{"tag": tag.Tag, "secrets": strconv.Itoa(tag.Secrets)}
+	}
+	writeTable(cmd.OutOrStdout(), tagColumns, rows, true)
+	return nil
+}
+
+func runSecretsTagAdd(cmd *cobra.Command, args []string) error {
+	return updateSecretTags(args[0], client.UpdateSecretTagsRequest{AddTags: args[1:]})
+}
+
+func runSecretsTagRm(cmd *cobra.Command, args []string) error {
+	return updateSecretTags(args[0], client.UpdateSecretTagsRequest{RemoveTags: args[1:]})
+}
+
+// updateSecretTags applies a tag change to one secret and prints its tags
+func updateSecretTags(key string, update client.UpdateSecretTagsRequest) error {
+	if err := validateSecretKey(key); err != nil {
+		return fmt.Errorf("❌ %w", err)
+	}
+	if err := validateTags(append(append([]string{}, update.AddTags...), update.RemoveTags...)); err != nil {
+		return fmt.Errorf("❌ %w", err)
+	}
+
+	store := storage.New()
+	if err := requireAPIAccess(store); err != nil {
+		return err
+	}
+
+	c, err := newSecretsClient(secretsEnv)
+	if err != nil {
+		return err
+	}
+	workspace, err := c.GetWorkspaceBySlug(secretsWorkspace)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to get workspace info: %w", err)
+	}
+
+	secret, err := c.UpdateSecretTags(workspace.ID, key, update)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to update the tags of %s: %w", key, err)
+	}
+	secret.Ciphertext, secret.Checksum = "", ""
+
+	if structuredOutput() {
+		return writeOutput(secret)
+	}
+	tags := "none"
+	if len(secret.Tags) > 0 {
+		tags = strings.Join(secret.Tags, ", ")
+	}
+	infof("✅ Tags of %s in %s: %s\n", key, secretsLocation(workspace.Slug, secretsEnv), tags)
+	return nil
+}
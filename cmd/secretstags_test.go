@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSecretsTagAddAndRm(t *testing.T) {
+	fake, _ := setupSecretsTest(t)
+
+	captureStdout(t, func() {
+		if err := runSecretsAdd(secretsAddCmd, []string{"STRIPE_KEY=sk_live_123", "DB_URL=postgres://db"}); err != nil {
+			t.Fatalf("runSecretsAdd failed: %v", err)
+		}
+	})
+	before := fake.secrets["STRIPE_KEY"]
+
+	var err error
+	out := captureStdout(t, func() {
+		err = runSecretsTagAdd(secretsTagAddCmd, []string{"STRIPE_KEY", "payments", "rotate-quarterly"})
+	})
+	if err != nil || !strings.Contains(out, "Tags of STRIPE_KEY in my-project: payments, rotate-quarterly") {
+		t.Fatalf("Expected the new tags, got %q, %v", out, err)
+	}
+	captureStdout(t, func() { err = runSecretsTagRm(secretsTagRmCmd, []string{"STRIPE_KEY", "rotate-quarterly"}) })
+	if err != nil {
+		t.Fatalf("runSecretsTagRm failed: %v", err)
+	}
+
+	after := fake.secrets["STRIPE_KEY"]
+	if !slices.Equal(after.Tags, []string{"payments"}) {
+		t.Errorf("Expected only the payments tag left, got %v", after.Tags)
+	}
+	if after.Ciphertext != before.Ciphertext || after.Version != before.Version || len(fake.versions["STRIPE_KEY"]) != 1 {
+		t.Errorf("Expected tagging to leave the encrypted value and versions alone, got %+v", after)
+	}
+
+	if err := runSecretsTagAdd(secretsTagAddCmd, []string{"STRIPE_KEY", "Has Space"}); err == nil ||
+		!strings.Contains(err.Error(), "invalid tag") {
+		t.Errorf("Expected a malformed tag to be rejected, got %v", err)
+	}
+	if err := runSecretsTagAdd(secretsTagAddCmd, []string{"MISSING", "payments"}); exitCodeFor(err) != exitNotFound {
+		t.Errorf("Expected a missing secret to exit %d, got %v", exitNotFound, err)
+	}
+}
+
+func TestSecretsTags(t *testing.T) {
+	setupSecretsTest(t)
+	t.Cleanup(func() { outputFormat = outputTable })
+
+	var err error
+	out := captureStdout(t, func() { err = runSecretsTags(secretsTagsCmd, nil) })
+	if err != nil || !strings.Contains(out, "No tagged secrets in my-project") {
+		t.Errorf("Expected a hint without tags, got %q, %v", out, err)
+	}
+
+	captureStdout(t, func() {
+		if err := runSecretsAdd(secretsAddCmd, []string{"A=1", "B=2", "C=3"}); err != nil {
+			t.Fatalf("runSecretsAdd failed: %v", err)
+		}
+		for _, args := range [][]string{{"A", "prod", "db"}, {"B", "prod"}, {"C", "cache"}} {
+			if err := runSecretsTagAdd(secretsTagAddCmd, args); err != nil {
+				t.Fatalf("runSecretsTagAdd failed: %v", err)
+			}
+		}
+	})
+
+	outputFormat = outputJSON
+	out = captureStdout(t, func() { err = runSecretsTags(secretsTagsCmd, nil) })
+	if err != nil {
+		t.Fatalf("runSecretsTags failed: %v", err)
+	}
+	var tags []tagCount
+	if err := json.Unmarshal([]byte(out), &tags); err != nil {
+		t.Fatalf("Expected JSON, got %q: %v", out, err)
+	}
+	want := []tagCount{{"prod", 2}, {"cache", 1}, {"db", 1}}
+	if !slices.Equal(tags, want) {
+		t.Errorf("Expected %v, got %v", want, tags)
+	}
+}
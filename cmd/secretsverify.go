@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/client"
+	"github.com/DylanBlakemore/initflow-cli/internal/storage"
+)
+
+var secretsVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check every secret decrypts and matches its checksum",
+	Long: "Download and decrypt every secret of a workspace environment on this device and check it against " +
+		"the checksum stored with it, a MAC of the value under the workspace key. A ciphertext that fails to " +
+		"decrypt or a value that doesn't match its checksum is reported as failed; values are never printed. " +
+		"Secrets written before checksums existed are reported as unchecked, and get a checksum the next " +
+		"time they are stored. Exits with 1 when any secret fails, e.g. after a restore or migration.",
+	Example: "  initflow secrets verify -w api --env prod",
+	Args:    cobra.NoArgs,
+	RunE:    runSecretsVerify,
+}
+
+var secretsVerifyPath string
+
+const (
+	verifyOK        = "ok"
+	verifyUnchecked = "unchecked"
+	verifyFailed    = "failed"
+)
+
+// verifyResult is the outcome of checking one secret
+type verifyResult struct {
+	Key    string `json:"key"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+var verifyColumns = []tableColumn{
+	{Name: "key", Header: "Key", Default: true},
+	{Name: "status", Header: "Status", Default: true},
+	{Name: "detail", Header: "Detail", Default: true},
+}
+
+func init() {
+	secretsCmd.AddCommand(secretsVerifyCmd)
+
+	secretsVerifyCmd.Flags().StringVar(&secretsVerifyPath, "path", "", "only verify secrets in this folder, e.g. backend/")
+}
+
+// verifySecrets decrypts and checks each secret, in parallel, sorted by key
+func verifySecrets(workspaceKey []byte, workspace *client.Workspace, env string, secrets []client.Secret) []verifyResult {
+	results := make([]verifyResult, len(secrets))
+	_ = forEachParallel(len(secrets), func(i int) error {
+		result := verifyResult{Key: secrets[i].Key, Status: verifyOK}
+		if _, err := openSecret(workspaceKey, workspace, env, &secrets[i]); err != nil {
+			result.Status, result.Detail = verifyFailed, err.Error()
+		} else if secrets[i].Checksum == "" {
+			result.Status, result.Detail = verifyUnchecked, "no checksum stored"
+		}
+		results[i] = result
+		return nil
+	})
+	sort.Slice(results, func(i, j int) bool { return results[i].Key < results[j].Key })
+	return results
+}
+
+func runSecretsVerify(cmd *cobra.Command, args []string) error {
+	prefix, err := parseSecretPath(secretsVerifyPath)
+	if err != nil {
+		return fmt.Errorf("❌ %w", err)
+	}
+
+	store := storage.New()
+	if err := ensureSecretsAccess(store); err != nil {
+		return fmt.Errorf("❌ %w", err)
+	}
+	c, err := newSecretsClient(secretsEnv)
+	if err != nil {
+		return err
+	}
+	workspace, workspaceKey, err := openWorkspace(c, store, secretsWorkspace)
+	if err != nil {
+		return err
+	}
+
+	secrets, err := c.FetchSecrets(workspace.ID, prefix)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to fetch secrets: %w", err)
+	}
+	results := verifySecrets(workspaceKey, workspace, secretsEnv, secrets)
+
+	counts := map[string]int{}
+	for _, result := range results {
+		counts[result.Status]++
+	}
+
+	if structuredOutput() {
+		if err := writeOutput(results); err != nil {
+			return err
+		}
+	} else {
+		infof("🔍 Verifying %d secrets in %s\n", len(results), secretsLocation(workspace.Slug, secretsEnv))
+		rows := make([]map[string]string, 0, len(results))
+		for _, result := range results {
+			if result.Status != verifyOK {
+				rows = append(rows, map[string]string{"key": result.Key, "status": result.Status, "detail": result.Detail})
+			}
+		}
+		if len(rows) > 0 {
+			writeTable(cmd.OutOrStdout(), verifyColumns, rows, true)
+		}
+		summary := fmt.Sprintf("%d ok, %d unchecked, %d failed", counts[verifyOK], counts[verifyUnchecked], counts[verifyFailed])
+		if counts[verifyFailed] > 0 {
+			infof("❌ %s\n", summary)
+		} else {
+			infof("✅ %s\n", summary)
+		}
+	}
+
+	if counts[verifyFailed] > 0 {
+		return &silentExit{code: exitError}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/encoding"
+)
+
+func TestSecretsVerify(t *testing.T) {
+	fake, workspaceKey := setupSecretsTest(t)
+	t.Cleanup(func() { outputFormat = outputTable })
+
+	captureStdout(t, func() {
+		if err := runSecretsAdd(secretsAddCmd, []string{"API_KEY=first-value", "DB_URL=postgres://one"}); err != nil {
+			t.Fatalf("runSecretsAdd failed: %v", err)
+		}
+	})
+	// A secret stored before checksums existed
+	sealed, err := encryptSecret(workspaceKey, 1, "", "LEGACY", []byte("old"))
+	if err != nil {
+		t.Fatalf("encryptSecret failed: %v", err)
+	}
+	fake.put("", "LEGACY", encoding.Encode(sealed), 3, 1)
+
+	out := captureStdout(t, func() { err = runSecretsVerify(secretsVerifyCmd, nil) })
+	if err != nil || !strings.Contains(out, "2 ok, 1 unchecked, 0 failed") || !strings.Contains(out, "LEGACY") {
+		t.Fatalf("Expected everything to verify, got %q, %v", out, err)
+	}
+
+	// Flip one bit of API_KEY's ciphertext
+	secret := fake.secrets["API_KEY"]
+	raw, _ := encoding.Decode(secret.Ciphertext)
+	raw[len(raw)-1] ^= 1
+	secret.Ciphertext = encoding.Encode(raw)
+	fake.secrets["API_KEY"] = secret
+
+	outputFormat = outputJSON
+	out = captureStdout(t, func() { err = runSecretsVerify(secretsVerifyCmd, nil) })
+	if code := exitCodeFor(err); code != exitError {
+		t.Errorf("Expected a failure to exit with 1, got %d (%v)", code, err)
+	}
+	var results []verifyResult
+	if err := json.Unmarshal([]byte(out), &results); err != nil {
+		t.Fatalf("Expected JSON, got %q: %v", out, err)
+	}
+	if results[0].Key != "API_KEY" || results[0].Status != verifyFailed || results[1].Status != verifyOK {
+		t.Errorf("Expected API_KEY to fail and DB_URL to pass, got %+v", results)
+	}
+	if strings.Contains(out, "first-value") || strings.Contains(out, "postgres://one") {
+		t.Errorf("Expected values to stay hidden, got %q", out)
+	}
+}
+
+func TestSecretsChecksumCatchesMispairedCiphertext(t *testing.T) {
+	fake, _ := setupSecretsTest(t)
+
+	captureStdout(t, func() {
+		for _, arg := range []string{"DB_URL=postgres://one", "DB_URL=postgres://two"} {
+			if err := runSecretsAdd(secretsAddCmd, []string{arg}); err != nil {
+				t.Fatalf("runSecretsAdd failed: %v", err)
+			}
+		}
+	})
+
+	// The first version's ciphertext paired with the current checksum
+	// decrypts fine, but not to the value the checksum was made for
+	secret := fake.secrets["DB_URL"]
+	secret.Ciphertext = fake.versions["DB_URL"][0].Ciphertext
+	fake.secrets["DB_URL"] = secret
+
+	err := runSecretsGet(secretsGetCmd, []string{"DB_URL"})
+	if err == nil || !strings.Contains(err.Error(), "integrity check failed") {
+		t.Errorf("Expected an integrity failure, got %v", err)
+	}
+}
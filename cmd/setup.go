@@ -0,0 +1,312 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/client"
+	"github.com/DylanBlakemore/initflow-cli/internal/config"
+	"github.com/DylanBlakemore/initflow-cli/internal/storage"
+)
+
+const passwordEnvVar = "INITFLOW_PASSWORD"
+
+var setupCmd = &cobra.Command{
+	Use:   "setup",
+	Short: "Guided first-run setup",
+	Long: `Walk through logging in, registering this device and initializing a first workspace key.
+Steps that are already done are skipped, and each remaining step is confirmed before it runs.
+
+Every prompt can be answered up front: use --email, --device-name and --workspace, and set
+INITFLOW_PASSWORD (and INITFLOW_OTP for two-factor accounts). With --non-interactive, missing
+required values are an error instead of a prompt.`,
+	Args: cobra.NoArgs,
+	RunE: runSetup,
+}
+
+var (
+	setupEmail          string
+	setupDeviceName     string
+	setupWorkspace      string
+	setupNonInteractive bool
+)
+
+func init() {
+	rootCmd.AddCommand(setupCmd)
+
+	setupCmd.Flags().StringVar(&setupEmail, "email", "", "email to log in with")
+	setupCmd.Flags().StringVar(&setupDeviceName, "device-name", "", "name for this device (default: hostname)")
+	setupCmd.Flags().StringVar(&setupWorkspace, "workspace", "", "slug of a workspace whose key to initialize")
+	setupCmd.Flags().BoolVar(&setupNonInteractive, "non-interactive", false,
+		"never prompt; fail if a required value is missing")
+}
+
+// setupState is what the wizard finds on this machine before it starts.
+type setupState struct {
+	Authenticated    bool
+	DeviceRegistered bool
+}
+
+func inspectSetup(store *storage.Storage) setupState {
+	return setupState{
+		Authenticated: store.HasToken(),
+		DeviceRegistered: store.HasDeviceID() &&
+			store.HasSigningPrivateKey() &&
+			store.HasEncryptionPrivateKey(),
+	}
+}
+
+type setupWizard struct {
+	store       *storage.Storage
+	in          *bufio.Reader
+	interactive bool
+}
+
+// confirm asks a yes/no question. Without a terminal every step runs.
+func (w *setupWizard) confirm(question string, defaultYes bool) (bool, error) {
+	if !w.interactive {
+		return true, nil
+	}
+	return askYesNo(w.in, question, defaultYes)
+}
+
+// value returns given if set, otherwise prompts showing fallback as the default.
+func (w *setupWizard) value(given, prompt, fallback, flag string) (string, error) {
+	if given = strings.TrimSpace(given); given != "" {
+		return given, nil
+	}
+
+	if !w.interactive {
+		if fallback != "" {
+			return fallback, nil
+		}
+		return "", fmt.Errorf("❌ %s is required with --non-interactive", flag)
+	}
+
+	if fallback != "" {
+		fmt.Fprintf(stdout(), "%s [%s]: ", prompt, fallback)
+	} else {
+		fmt.Fprintf(stdout(), "%s: ", prompt)
+	}
+
+	answer, err := w.in.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed to read %s: %w", strings.ToLower(prompt), err)
+	}
+
+	if answer = strings.TrimSpace(answer); answer != "" {
+		return answer, nil
+	}
+	if fallback == "" {
+		return "", fmt.Errorf("%s cannot be empty", strings.ToLower(prompt))
+	}
+	return fallback, nil
+}
+
+func (w *setupWizard) password() (string, error) {
+	if password := os.Getenv(passwordEnvVar); password != "" {
+		return password, nil
+	}
+
+	if !w.interactive && askPassProgram == "" {
+		return "", fmt.Errorf("❌ %s or --ask-pass must be set with --non-interactive", passwordEnvVar)
+	}
+
+	return readPassword()
+}
+
+func (w *setupWizard) login() (bool, error) {
+	ok, err := w.confirm("Log in to InitFlow now?", true)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	email, err := w.value(setupEmail, "Email", config.Get().DefaultEmail, "--email")
+	if err != nil {
+		return false, err
+	}
+
+	password, err := w.password()
+	if err != nil {
+		return false, err
+	}
+
+	infoln("🔐 Authenticating...")
+	apiClient := newClient()
+	loginResp, err := authenticate(apiClient, email, password)
+	if err != nil {
+		return false, fmt.Errorf("❌ Authentication failed: %w", err)
+	}
+
+	if err := storeLogin(w.store, apiClient, loginResp); err != nil {
+		return false, err
+	}
+
+	if err := config.Persist("default_email", email); err != nil {
+		infof("⚠️  Could not remember email for next login: %v\n", err)
+	}
+
+	infof("✅ Authenticated as %s %s\n", loginResp.User.Name, loginResp.User.Surname)
+	return true, nil
+}
+
+func (w *setupWizard) registerDevice() (bool, error) {
+	ok, err := w.confirm("Register this device?", true)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	hostname, _ := os.Hostname()
+	deviceName, err := w.value(setupDeviceName, "Device name", hostname, "--device-name")
+	if err != nil {
+		return false, err
+	}
+
+	infof("🔑 Registering device: %s\n", deviceName)
+
+	signingPublicKey, signingPrivateKey, encryptionPublicKey, encryptionPrivateKey, err := generateKeypairs()
+	if err != nil {
+		return false, err
+	}
+
+	deviceResp, err := performDeviceRegistration(deviceName, signingPublicKey, encryptionPublicKey, w.store)
+	if err != nil {
+		return false, err
+	}
+
+	err = storeDeviceCredentials(w.store, signingPrivateKey, encryptionPrivateKey, deviceResp.Device.DeviceID)
+	if err != nil {
+		return false, err
+	}
+	if err := w.store.StoreDeviceName(deviceResp.Device.Name); err != nil {
+		return false, fmt.Errorf("failed to store device name: %w", err)
+	}
+
+	_ = w.store.DeleteToken()
+	infof("✅ Device registered with ID: %s\n", deviceResp.Device.DeviceID)
+	return true, nil
+}
+
+// pickWorkspace returns the slug to initialize, or "" to skip the step.
+func (w *setupWizard) pickWorkspace(c *client.Client) (string, error) {
+	if setupWorkspace != "" {
+		return setupWorkspace, nil
+	}
+	if !w.interactive {
+		return "", nil
+	}
+
+	workspaces, err := c.ListWorkspaces()
+	if err != nil {
+		return "", fmt.Errorf("❌ Failed to fetch workspaces: %w", err)
+	}
+
+	var candidates []string
+	for _, ws := range workspaces {
+		if !ws.KeyInitialized && canInitializeKey(ws.Role) && !w.store.HasWorkspaceKey(ws.Slug) {
+			candidates = append(candidates, ws.Slug)
+		}
+	}
+
+	if len(candidates) == 0 {
+		infoln("ℹ️  No workspaces are waiting for a key")
+		return "", nil
+	}
+
+	infof("Workspaces without a key: %s\n", strings.Join(candidates, ", "))
+	ok, err := w.confirm("Initialize a workspace key now?", false)
+	if err != nil || !ok {
+		return "", err
+	}
+
+	return w.value("", "Workspace slug", candidates[0], "--workspace")
+}
+
+func (w *setupWizard) initWorkspace() error {
+	c := newClient(client.WithCache())
+
+	slug, err := w.pickWorkspace(c)
+	if err != nil || slug == "" {
+		return err
+	}
+
+	if w.store.HasWorkspaceKey(slug) {
+		infof("ℹ️  Workspace key for %s already exists locally\n", slug)
+		return nil
+	}
+
+	workspace, err := c.GetWorkspaceBySlug(slug)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to get workspace info: %w", err)
+	}
+
+	if workspace.KeyInitialized {
+		infof("ℹ️  Workspace key for %s is already initialized\n", slug)
+		return nil
+	}
+
+	if !canInitializeKey(workspace.Role) {
+		return fmt.Errorf("❌ Your role (%s) can't initialize the key for %s", workspace.Role, slug)
+	}
+
+	infof("🔐 Initializing workspace key for \"%s\"...\n", slug)
+	if err := initializeWorkspaceKey(c, w.store, workspace, func(msg string) { infoln(msg) }); err != nil {
+		return err
+	}
+
+	infoln("✅ Workspace key initialized successfully!")
+	return nil
+}
+
+func runSetup(cmd *cobra.Command, args []string) error {
+	w := &setupWizard{
+		store:       storage.New(),
+		in:          bufio.NewReader(os.Stdin),
+		interactive: !setupNonInteractive,
+	}
+
+	infoln("🧭 Checking current setup...")
+	state := inspectSetup(w.store)
+
+	if state.DeviceRegistered {
+		deviceID, _ := w.store.GetDeviceID()
+		infof("✅ Device already registered (%s)\n", deviceID)
+	} else {
+		if state.Authenticated {
+			infoln("✅ Already logged in")
+		} else {
+			ok, err := w.login()
+			if err != nil {
+				return err
+			}
+			if !ok {
+				infoln("💡 Run 'initflow setup' again when you're ready to log in")
+				return nil
+			}
+		}
+
+		ok, err := w.registerDevice()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			infoln("💡 Register later with 'initflow device register <name>'")
+			return nil
+		}
+	}
+
+	if err := w.initWorkspace(); err != nil {
+		return err
+	}
+
+	infoln()
+	infoln("🎉 Setup complete!")
+	infoln("💡 Next: see your workspaces with 'initflow workspace list'")
+
+	return nil
+}
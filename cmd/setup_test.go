@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/client"
+	"github.com/DylanBlakemore/initflow-cli/internal/config"
+	"github.com/DylanBlakemore/initflow-cli/internal/storage"
+)
+
+func setupWizardFlags(t *testing.T, email, deviceName, workspace string) {
+	t.Helper()
+
+	setupEmail, setupDeviceName, setupWorkspace, setupNonInteractive = email, deviceName, workspace, true
+	t.Cleanup(func() {
+		setupEmail, setupDeviceName, setupWorkspace, setupNonInteractive = "", "", "", false
+	})
+}
+
+func setupWizardEnvironment(t *testing.T, serverURL string) *storage.Storage {
+	t.Helper()
+	viper.Reset()
+	t.Setenv("HOME", t.TempDir())
+
+	require.NoError(t, config.InitConfig())
+	require.NoError(t, config.Set("api_base_url", serverURL))
+	require.NoError(t, config.Set("service_name", "initflow-cli-test-"+t.Name()))
+
+	store := storage.New()
+	t.Cleanup(func() {
+		_ = store.ClearDeviceCredentials()
+		_ = store.DeleteToken()
+		_ = store.DeleteWorkspaceKey("my-project")
+		viper.Reset()
+	})
+
+	oldStdout := os.Stdout
+	os.Stdout = nil
+	t.Cleanup(func() { os.Stdout = oldStdout })
+
+	return store
+}
+
+func TestSetupNonInteractive(t *testing.T) {
+	var registeredName string
+	initialized := false
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.URL.Path {
+		case "/api/v1/auth/login":
+			var req client.LoginRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			assert.Equal(t, "user@example.com", req.Email)
+			assert.Equal(t, "secret", req.Password)
+
+			resp := client.LoginResponse{Token: "registration-token"}
+			resp.User.Name = "Test"
+			_ = json.NewEncoder(w).Encode(resp)
+
+		case "/api/v1/devices":
+			var req client.DeviceRegistrationRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			assert.Equal(t, "registration-token", req.Token)
+			registeredName = req.Name
+
+			var resp client.DeviceRegistrationResponse
+			resp.Success = true
+			resp.Device.DeviceID = "device-42"
+			resp.Device.Name = req.Name
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(resp)
+
+		case "/api/v1/workspaces":
+			assert.Equal(t, "Device device-42", r.Header.Get("Authorization"))
+			_ = json.NewEncoder(w).Encode(client.ListWorkspacesResponse{
+				Workspaces: []client.Workspace{{ID: 1, Slug: "my-project", Role: "Owner"}},
+			})
+
+		case "/api/v1/workspaces/1/initialize":
+			initialized = true
+			_ = json.NewEncoder(w).Encode(client.InitializeWorkspaceKeyResponse{Success: true})
+
+		default:
+			t.Errorf("Unexpected request path: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	store := setupWizardEnvironment(t, server.URL)
+	setupWizardFlags(t, "user@example.com", "ci-runner", "my-project")
+	t.Setenv(passwordEnvVar, "secret")
+
+	require.NoError(t, runSetup(setupCmd, nil))
+
+	assert.Equal(t, "ci-runner", registeredName)
+	assert.True(t, initialized, "expected workspace key to be initialized")
+
+	state := inspectSetup(store)
+	assert.True(t, state.DeviceRegistered)
+	assert.False(t, state.Authenticated, "registration token should be discarded")
+	assert.True(t, store.HasWorkspaceKey("my-project"))
+	assert.Equal(t, "user@example.com", config.Get().DefaultEmail)
+
+	// A second run finds everything done and makes no further changes
+	initialized = false
+	require.NoError(t, runSetup(setupCmd, nil))
+	assert.False(t, initialized)
+}
+
+func TestSetupNonInteractiveRequiresPassword(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Unexpected request: %s", r.URL.Path)
+	}))
+	defer server.Close()
+
+	setupWizardEnvironment(t, server.URL)
+	setupWizardFlags(t, "user@example.com", "", "")
+	t.Setenv(passwordEnvVar, "")
+
+	err := runSetup(setupCmd, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), passwordEnvVar)
+}
+
+func TestSetupNonInteractiveRequiresEmail(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Unexpected request: %s", r.URL.Path)
+	}))
+	defer server.Close()
+
+	setupWizardEnvironment(t, server.URL)
+	setupWizardFlags(t, "", "", "")
+	t.Setenv(passwordEnvVar, "secret")
+
+	err := runSetup(setupCmd, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--email")
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/client"
+	"github.com/DylanBlakemore/initflow-cli/internal/config"
+	"github.com/DylanBlakemore/initflow-cli/internal/storage"
+)
+
+var (
+	// ssoPollInterval is how often login --sso polls when the server suggests no interval
+	ssoPollInterval = 5 * time.Second
+	// ssoSlowDownStep is added to the interval each time the server asks to poll slower
+	ssoSlowDownStep = 5 * time.Second
+	// ssoTimeout bounds the wait when the server doesn't say when the code expires
+	ssoTimeout = 15 * time.Minute
+)
+
+var loginSSO bool
+
+func init() {
+	loginCmd.Flags().BoolVar(&loginSSO, "sso", false,
+		"log in through your organization's identity provider in a browser instead of with a password")
+}
+
+// waitForSSOLogin polls until the login started with code is approved in the
+// browser, slowing down when the server asks to, and fails once it expires.
+func waitForSSOLogin(c *client.Client, code *client.DeviceCodeResponse) (*client.LoginResponse, error) {
+	interval := ssoPollInterval
+	if code.Interval > 0 {
+		interval = time.Duration(code.Interval) * time.Second
+	}
+	timeout := ssoTimeout
+	if code.ExpiresIn > 0 {
+		timeout = time.Duration(code.ExpiresIn) * time.Second
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		if time.Now().Add(interval).After(deadline) {
+			return nil, client.ErrSSOExpired
+		}
+		time.Sleep(interval)
+
+		loginResp, err := c.PollSSOLogin(code.DeviceCode)
+		switch {
+		case errors.Is(err, client.ErrAuthorizationPending):
+		case errors.Is(err, client.ErrSlowDown):
+			interval += ssoSlowDownStep
+		default:
+			return loginResp, err
+		}
+	}
+}
+
+func runSSOLogin(args []string) error {
+	var loginHint string
+	if len(args) > 0 {
+		loginHint = strings.TrimSpace(args[0])
+	}
+
+	apiClient := newClient()
+	code, err := apiClient.StartSSOLogin(loginHint)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to start SSO login: %w", err)
+	}
+
+	if code.VerificationURIComplete != "" {
+		infof("🌐 Open %s to log in and check that it shows the code %s\n", code.VerificationURIComplete, code.UserCode)
+	} else {
+		infof("🌐 Open %s and enter the code %s to log in\n", code.VerificationURI, code.UserCode)
+	}
+	infoln("⏳ Waiting for the login to be approved...")
+
+	loginResp, err := waitForSSOLogin(apiClient, code)
+	switch {
+	case errors.Is(err, client.ErrSSODenied):
+		return fmt.Errorf("❌ SSO login was denied in the browser")
+	case errors.Is(err, client.ErrSSOExpired):
+		return fmt.Errorf("❌ The login code expired. Run 'initflow auth login --sso' again")
+	case err != nil:
+		return fmt.Errorf("❌ SSO login failed: %w", err)
+	}
+
+	store := storage.New()
+	if err := storeLogin(store, apiClient, loginResp); err != nil {
+		return err
+	}
+
+	if loginResp.User.Email != "" {
+		if err := config.Persist("default_email", loginResp.User.Email); err != nil {
+			infof("⚠️  Could not remember email for next login: %v\n", err)
+		}
+	}
+
+	printLoginSuccess(store, loginResp)
+	return nil
+}
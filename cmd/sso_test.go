@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/client"
+	"github.com/DylanBlakemore/initflow-cli/internal/config"
+	"github.com/DylanBlakemore/initflow-cli/internal/storage"
+)
+
+// ssoServer hands out a device code and answers polls with the given error
+// codes in turn, then with a login (or with the last code, if it isn't "")
+type ssoServer struct {
+	t     *testing.T
+	polls []string
+	hint  string
+}
+
+func (s *ssoServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	switch r.URL.Path {
+	case "/api/v1/auth/device/code":
+		var req client.DeviceCodeRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		s.hint = req.LoginHint
+		json.NewEncoder(w).Encode(client.DeviceCodeResponse{
+			DeviceCode: "device-code", UserCode: "WDJB-MJHT",
+			VerificationURI: "https://sso.example.com/activate", ExpiresIn: 60,
+		})
+	case "/api/v1/auth/token":
+		var req client.DeviceTokenRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.DeviceCode != "device-code" || req.GrantType != client.DeviceCodeGrantType {
+			s.t.Errorf("Unexpected token request %+v", req)
+		}
+		if len(s.polls) > 0 {
+			code := s.polls[0]
+			if len(s.polls) > 1 {
+				s.polls = s.polls[1:]
+			}
+			if code != "" {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(client.ErrorResponse{Error: code})
+				return
+			}
+		}
+		resp := client.LoginResponse{Token: "sso-token", RefreshToken: "sso-refresh"}
+		resp.User.Email = "jane@example.com"
+		json.NewEncoder(w).Encode(resp)
+	default:
+		s.t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestLoginSSO(t *testing.T) {
+	fake := &ssoServer{t: t, polls: []string{"authorization_pending", "slow_down", ""}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	t.Setenv("HOME", t.TempDir()) // the login remembers the email in the config file
+	setupTestEnvironment(t, server.URL)
+	t.Cleanup(func() { config.Set("default_email", "") })
+
+	interval, step := ssoPollInterval, ssoSlowDownStep
+	ssoPollInterval, ssoSlowDownStep, loginSSO = time.Millisecond, time.Millisecond, true
+	t.Cleanup(func() { ssoPollInterval, ssoSlowDownStep, loginSSO = interval, step, false })
+
+	store := storage.New()
+	t.Cleanup(func() { store.DeleteToken() })
+
+	if err := runLogin(loginCmd, []string{"jane@example.com"}); err != nil {
+		t.Fatalf("runLogin --sso failed: %v", err)
+	}
+	if fake.hint != "jane@example.com" {
+		t.Errorf("Expected the email to be sent as the login hint, got %q", fake.hint)
+	}
+	if token, _ := store.GetToken(); token != "sso-token" {
+		t.Errorf("Expected the SSO token to be stored, got %q", token)
+	}
+	if refreshToken, _ := store.GetRefreshToken(); refreshToken != "sso-refresh" {
+		t.Errorf("Expected the refresh token to be stored, got %q", refreshToken)
+	}
+
+	for _, tc := range []struct{ poll, want string }{
+		{"access_denied", "denied"},
+		{"expired_token", "expired"},
+	} {
+		fake.polls = []string{"authorization_pending", tc.poll}
+		err := runLogin(loginCmd, nil)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("Expected %s to fail with %q, got %v", tc.poll, tc.want, err)
+		}
+	}
+}
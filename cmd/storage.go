@@ -0,0 +1,375 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/backup"
+	"github.com/DylanBlakemore/initflow-cli/internal/config"
+	"github.com/DylanBlakemore/initflow-cli/internal/fsutil"
+	"github.com/DylanBlakemore/initflow-cli/internal/storage"
+)
+
+const (
+	backupPassphraseEnvVar = "INITFLOW_BACKUP_PASSPHRASE"
+	keyPassphraseEnvVar    = "INITFLOW_KEY_PASSPHRASE"
+)
+
+const cloneWarning = `⚠️  A backup is a full copy of this device's identity: anyone holding the file
+   and its passphrase can act as this device. Keep it offline, move it directly
+   to the new machine and delete it once imported.`
+
+var storageCmd = &cobra.Command{
+	Use:   "storage",
+	Short: "Back up and restore local credentials",
+	Long:  `Move this device's keys and cached workspace keys to another machine.`,
+}
+
+var storageExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export device and workspace keys to an encrypted file",
+	Long: `Bundle the device keypairs and cached workspace keys into a single file encrypted
+under a passphrase (Argon2id + ChaCha20-Poly1305). Set INITFLOW_BACKUP_PASSPHRASE to avoid the prompt.`,
+	Args: cobra.NoArgs,
+	RunE: runStorageExport,
+}
+
+var storageImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Restore device and workspace keys from an exported file",
+	Long: `Restore the device identity and workspace keys from a file written by 'initflow storage export'.
+Set INITFLOW_BACKUP_PASSPHRASE to avoid the prompt.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runStorageImport,
+}
+
+var deviceBackupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Back up device and workspace keys to an encrypted file",
+	Long: `The same as 'initflow storage export': seal this device's keys and cached workspace keys into a
+passphrase-encrypted file, to restore on a new machine with 'initflow device restore'.`,
+	Example: "  initflow device backup --out backup.enc",
+	Args:    cobra.NoArgs,
+	RunE:    runStorageExport,
+}
+
+var deviceRestoreCmd = &cobra.Command{
+	Use:     "restore <file>",
+	Short:   "Restore device and workspace keys from a backup",
+	Long:    `The same as 'initflow storage import', for a file written by 'initflow device backup'.`,
+	Example: "  initflow device restore backup.enc",
+	Args:    cobra.ExactArgs(1),
+	RunE:    runStorageImport,
+}
+
+var storageProtectCmd = &cobra.Command{
+	Use:   "protect",
+	Short: "Encrypt local keys with a passphrase",
+	Long: `Encrypt this device's private keys and cached workspace keys with a passphrase (Argon2id +
+XChaCha20-Poly1305), so a copy of the keychain or credentials file alone doesn't expose them. Commands that
+use the keys ask for the passphrase once; set INITFLOW_KEY_PASSPHRASE to avoid the prompt.`,
+	Args: cobra.NoArgs,
+	RunE: runStorageProtect,
+}
+
+var storageUnprotectCmd = &cobra.Command{
+	Use:   "unprotect",
+	Short: "Store local keys without a passphrase again",
+	Long:  `Decrypt the keys protected with 'initflow storage protect' and stop asking for the passphrase.`,
+	Args:  cobra.NoArgs,
+	RunE:  runStorageUnprotect,
+}
+
+var (
+	exportOut   string
+	importForce bool
+)
+
+func init() {
+	rootCmd.AddCommand(storageCmd)
+	storageCmd.AddCommand(storageExportCmd)
+	storageCmd.AddCommand(storageImportCmd)
+	storageCmd.AddCommand(storageProtectCmd)
+	storageCmd.AddCommand(storageUnprotectCmd)
+	deviceCmd.AddCommand(deviceBackupCmd)
+	deviceCmd.AddCommand(deviceRestoreCmd)
+
+	for _, c := range []*cobra.Command{storageExportCmd, deviceBackupCmd} {
+		c.Flags().StringVar(&exportOut, "out", "", "path of the backup file to write")
+		_ = c.MarkFlagRequired("out")
+	}
+
+	for _, c := range []*cobra.Command{storageImportCmd, deviceRestoreCmd} {
+		c.Flags().BoolVar(&importForce, "force", false,
+			"replace the device already registered on this machine")
+	}
+
+	storage.SetPassphraseSource(readKeyPassphrase)
+}
+
+// readBackupPassphrase takes the passphrase from the environment or a hidden
+// prompt, asking twice when confirm is set.
+func readBackupPassphrase(confirm bool) ([]byte, error) {
+	if passphrase := os.Getenv(backupPassphraseEnvVar); passphrase != "" {
+		return []byte(passphrase), nil
+	}
+
+	fmt.Fprint(stdout(), "Backup passphrase: ")
+	passphrase, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(stdout())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	if len(passphrase) == 0 {
+		return nil, fmt.Errorf("passphrase cannot be empty")
+	}
+
+	if confirm {
+		fmt.Fprint(stdout(), "Confirm passphrase: ")
+		again, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(stdout())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read passphrase: %w", err)
+		}
+		if !bytes.Equal(passphrase, again) {
+			return nil, fmt.Errorf("passphrases do not match")
+		}
+	}
+
+	return passphrase, nil
+}
+
+// readKeyPassphrase supplies the passphrase protecting local keys from the
+// environment or a prompt, asking twice when a new one is chosen
+func readKeyPassphrase(create bool) ([]byte, error) {
+	if passphrase := os.Getenv(keyPassphraseEnvVar); passphrase != "" {
+		return []byte(passphrase), nil
+	}
+
+	reader := currentPasswordReader()
+	if create {
+		infoln("🔐 Choose a passphrase to protect this device's keys")
+	}
+	passphrase, err := reader.ReadPassword("Key passphrase")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key passphrase: %w", err)
+	}
+	if passphrase == "" {
+		return nil, fmt.Errorf("passphrase cannot be empty")
+	}
+
+	if create {
+		again, err := reader.ReadPassword("Confirm key passphrase")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key passphrase: %w", err)
+		}
+		if again != passphrase {
+			return nil, fmt.Errorf("passphrases do not match")
+		}
+	}
+
+	return []byte(passphrase), nil
+}
+
+// cachedWorkspaceKeys returns the workspace keys cached on this device. Keys
+// cached in a keychain before it kept an index of them are found through the
+// workspaces the server lists.
+func cachedWorkspaceKeys(store *storage.Storage) (map[string][]byte, error) {
+	slugs, err := store.WorkspaceKeySlugs()
+	if err != nil {
+		return nil, err
+	}
+	if workspaces, err := newClient().ListWorkspaces(); err == nil {
+		for _, workspace := range workspaces {
+			slugs = append(slugs, workspace.Slug)
+		}
+	} else {
+		infof("⚠️  Couldn't list workspaces (%v); only indexed workspace keys are included\n", err)
+	}
+
+	keys := make(map[string][]byte)
+	for _, slug := range slugs {
+		if _, ok := keys[slug]; ok {
+			continue
+		}
+		if key, err := store.GetWorkspaceKey(slug); err == nil {
+			keys[slug] = key
+		}
+	}
+	return keys, nil
+}
+
+func runStorageExport(cmd *cobra.Command, args []string) error {
+	store := storage.New()
+	if !store.HasDeviceID() {
+		return fmt.Errorf("❌ Device not registered. Please run 'initflow device register <name>' first")
+	}
+
+	deviceID, err := store.GetDeviceID()
+	if err != nil {
+		return fmt.Errorf("❌ Failed to read device ID: %w", err)
+	}
+	signingKey, err := store.GetSigningPrivateKey()
+	if err != nil {
+		return fmt.Errorf("❌ Failed to read signing key: %w", err)
+	}
+	encryptionKey, err := store.GetEncryptionPrivateKey()
+	if err != nil {
+		return fmt.Errorf("❌ Failed to read encryption key: %w", err)
+	}
+
+	infoln("🔍 Collecting cached workspace keys...")
+	workspaceKeys, err := cachedWorkspaceKeys(store)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to fetch workspaces: %w", err)
+	}
+
+	infoln(cloneWarning)
+	passphrase, err := readBackupPassphrase(true)
+	if err != nil {
+		return err
+	}
+
+	sealed, err := backup.Seal(&backup.Bundle{
+		DeviceID:             deviceID,
+		SigningPrivateKey:    signingKey,
+		EncryptionPrivateKey: encryptionKey,
+		WorkspaceKeys:        workspaceKeys,
+	}, passphrase)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to encrypt backup: %w", err)
+	}
+
+	if err := fsutil.WriteFile(exportOut, sealed, fsutil.PrivateFilePermissions); err != nil {
+		return fmt.Errorf("❌ Failed to write backup: %w", err)
+	}
+
+	infof("✅ Exported device %s and %d workspace key(s) to %s\n", deviceID, len(workspaceKeys), exportOut)
+	restore := "initflow storage import"
+	if cmd.Parent() == deviceCmd {
+		restore = "initflow device restore"
+	}
+	infof("💡 Restore on the new machine with '%s <file>'\n", restore)
+
+	return nil
+}
+
+func runStorageImport(cmd *cobra.Command, args []string) error {
+	store := storage.New()
+	if store.HasDeviceID() && !importForce {
+		return fmt.Errorf("❌ A device is already registered on this machine. Use --force to replace it")
+	}
+
+	data, err := os.ReadFile(args[0]) // #nosec G304 - path is the backup file the user asked to import
+	if err != nil {
+		return fmt.Errorf("❌ Failed to read backup: %w", err)
+	}
+
+	passphrase, err := readBackupPassphrase(false)
+	if err != nil {
+		return err
+	}
+
+	bundle, err := backup.Open(data, passphrase)
+	if errors.Is(err, backup.ErrDecryptionFailed) {
+		return fmt.Errorf("❌ Failed to open backup: %w", err)
+	}
+	if err != nil {
+		return fmt.Errorf("❌ Failed to read backup: %w", err)
+	}
+
+	infoln(cloneWarning)
+
+	if err := storeDeviceCredentials(store, bundle.SigningPrivateKey, bundle.EncryptionPrivateKey, bundle.DeviceID); err != nil {
+		return fmt.Errorf("❌ Failed to restore device credentials: %w", err)
+	}
+
+	slugs := make([]string, 0, len(bundle.WorkspaceKeys))
+	for slug := range bundle.WorkspaceKeys {
+		slugs = append(slugs, slug)
+	}
+	sort.Strings(slugs)
+
+	for _, slug := range slugs {
+		if err := store.StoreWorkspaceKey(slug, bundle.WorkspaceKeys[slug]); err != nil {
+			return fmt.Errorf("❌ Failed to restore workspace key for %s: %w", slug, err)
+		}
+	}
+
+	infof("✅ Restored device %s and %d workspace key(s)\n", bundle.DeviceID, len(slugs))
+	infoln("💡 Next: check access with 'initflow workspace list'")
+
+	return nil
+}
+
+// resealKeys turns passphrase protection on or off and stores the device keys
+// and cached workspace keys again to match. encrypt_keys is only saved once
+// every key is stored the new way; sealed keys open either way, so a failure
+// part way leaves them all readable. It returns how many workspace keys were
+// rewritten.
+func resealKeys(protect bool) (int, error) {
+	store := storage.New()
+	if !store.HasDeviceID() {
+		return 0, fmt.Errorf("device not registered. Please run 'initflow device register <name>' first")
+	}
+
+	signingKey, err := store.GetSigningPrivateKey()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read signing key: %w", err)
+	}
+	encryptionKey, err := store.GetEncryptionPrivateKey()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read encryption key: %w", err)
+	}
+
+	infoln("🔍 Collecting cached workspace keys...")
+	workspaceKeys, err := cachedWorkspaceKeys(store)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read cached workspace keys: %w", err)
+	}
+
+	store = store.WithKeyProtection(protect)
+	if err := store.StoreSigningPrivateKey(signingKey); err != nil {
+		return 0, fmt.Errorf("failed to store signing key: %w", err)
+	}
+	if err := store.StoreEncryptionPrivateKey(encryptionKey); err != nil {
+		return 0, fmt.Errorf("failed to store encryption key: %w", err)
+	}
+	for slug, key := range workspaceKeys {
+		if err := store.StoreWorkspaceKey(slug, key); err != nil {
+			return 0, fmt.Errorf("failed to store workspace key for %s: %w", slug, err)
+		}
+	}
+
+	if err := config.Persist("encrypt_keys", protect); err != nil {
+		return 0, fmt.Errorf("failed to save encrypt_keys: %w", err)
+	}
+	return len(workspaceKeys), nil
+}
+
+func runStorageProtect(cmd *cobra.Command, args []string) error {
+	count, err := resealKeys(true)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to protect keys: %w", err)
+	}
+
+	infof("✅ Encrypted the device keys and %d workspace key(s) with your passphrase\n", count)
+	infof("💡 Commands that use the keys ask for it once; set %s for scripts\n", keyPassphraseEnvVar)
+	return nil
+}
+
+func runStorageUnprotect(cmd *cobra.Command, args []string) error {
+	count, err := resealKeys(false)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to unprotect keys: %w", err)
+	}
+
+	infof("✅ Stored the device keys and %d workspace key(s) without a passphrase\n", count)
+	return nil
+}
@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zalando/go-keyring"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/client"
+	"github.com/DylanBlakemore/initflow-cli/internal/config"
+	"github.com/DylanBlakemore/initflow-cli/internal/storage"
+)
+
+func TestStorageExportImportRoundTrip(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(client.ListWorkspacesResponse{
+			Workspaces: []client.Workspace{
+				{ID: 1, Slug: "my-project", KeyInitialized: true},
+				{ID: 2, Slug: "team-secrets", KeyInitialized: true},
+			},
+		})
+	}))
+	defer server.Close()
+
+	setupTestEnvironment(t, server.URL)
+	store := storage.New()
+	workspaceKey := []byte("0123456789abcdef0123456789abcdef")
+	require.NoError(t, store.StoreWorkspaceKey("my-project", workspaceKey))
+
+	signingKey, err := store.GetSigningPrivateKey()
+	require.NoError(t, err)
+
+	exportOut = filepath.Join(t.TempDir(), "backup.age")
+	t.Cleanup(func() { exportOut, importForce = "", false })
+	t.Setenv(backupPassphraseEnvVar, "correct horse battery staple")
+
+	require.NoError(t, runStorageExport(storageExportCmd, nil))
+
+	info, err := os.Stat(exportOut)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// Simulate the new machine
+	require.NoError(t, store.ClearDeviceCredentials())
+	require.NoError(t, store.DeleteWorkspaceKey("my-project"))
+
+	t.Setenv(backupPassphraseEnvVar, "wrong passphrase")
+	err = runStorageImport(storageImportCmd, []string{exportOut})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "wrong passphrase or corrupted backup")
+	assert.False(t, store.HasDeviceID())
+
+	t.Setenv(backupPassphraseEnvVar, "correct horse battery staple")
+	require.NoError(t, runStorageImport(storageImportCmd, []string{exportOut}))
+
+	deviceID, err := store.GetDeviceID()
+	require.NoError(t, err)
+	assert.Equal(t, "test-device-123", deviceID)
+
+	restoredSigningKey, err := store.GetSigningPrivateKey()
+	require.NoError(t, err)
+	assert.Equal(t, signingKey, restoredSigningKey)
+
+	restoredWorkspaceKey, err := store.GetWorkspaceKey("my-project")
+	require.NoError(t, err)
+	assert.Equal(t, workspaceKey, restoredWorkspaceKey)
+	assert.False(t, store.HasWorkspaceKey("team-secrets"))
+
+	// Importing over a registered device needs --force
+	err = runStorageImport(storageImportCmd, []string{exportOut})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--force")
+}
+
+func TestDeviceBackupRestore(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(client.ListWorkspacesResponse{
+			Workspaces: []client.Workspace{{ID: 1, Slug: "my-project", KeyInitialized: true}},
+		})
+	}))
+	defer server.Close()
+
+	setupTestEnvironment(t, server.URL)
+	store := storage.New()
+	workspaceKey := []byte("0123456789abcdef0123456789abcdef")
+	require.NoError(t, store.StoreWorkspaceKey("my-project", workspaceKey))
+	t.Cleanup(func() { _ = store.DeleteWorkspaceKey("my-project") })
+	encryptionKey, err := store.GetEncryptionPrivateKey()
+	require.NoError(t, err)
+
+	require.NotNil(t, deviceBackupCmd.Flags().Lookup("out"))
+	require.NotNil(t, deviceRestoreCmd.Flags().Lookup("force"))
+
+	exportOut = filepath.Join(t.TempDir(), "backup.enc")
+	t.Cleanup(func() { exportOut, importForce = "", false })
+	t.Setenv(backupPassphraseEnvVar, "correct horse battery staple")
+
+	out := captureStdout(t, func() { require.NoError(t, runStorageExport(deviceBackupCmd, nil)) })
+	assert.Contains(t, out, "initflow device restore <file>")
+
+	// Simulate the new machine
+	require.NoError(t, store.ClearDeviceCredentials())
+	require.NoError(t, store.DeleteWorkspaceKey("my-project"))
+
+	captureStdout(t, func() { require.NoError(t, runStorageImport(deviceRestoreCmd, []string{exportOut})) })
+
+	restoredEncryptionKey, err := store.GetEncryptionPrivateKey()
+	require.NoError(t, err)
+	assert.Equal(t, encryptionKey, restoredEncryptionKey)
+	restoredWorkspaceKey, err := store.GetWorkspaceKey("my-project")
+	require.NoError(t, err)
+	assert.Equal(t, workspaceKey, restoredWorkspaceKey)
+
+	err = runStorageImport(deviceRestoreCmd, []string{exportOut})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--force")
+}
+
+func TestStorageProtect(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(client.ListWorkspacesResponse{
+			Workspaces: []client.Workspace{{ID: 1, Slug: "my-project", KeyInitialized: true}},
+		})
+	}))
+	defer server.Close()
+
+	t.Setenv("HOME", t.TempDir()) // protect saves encrypt_keys to the config file
+	setupTestEnvironment(t, server.URL)
+	t.Cleanup(func() {
+		config.Set("encrypt_keys", false)
+		storage.ForgetPassphrase()
+	})
+
+	store := storage.New()
+	workspaceKey := []byte("0123456789abcdef0123456789abcdef")
+	require.NoError(t, store.StoreWorkspaceKey("my-project", workspaceKey))
+	t.Cleanup(func() { _ = store.DeleteWorkspaceKey("my-project") })
+	// A workspace the server no longer lists still has its cached key sealed
+	require.NoError(t, store.StoreWorkspaceKey("left-project", workspaceKey))
+	t.Cleanup(func() { _ = store.DeleteWorkspaceKey("left-project") })
+	signingKey, err := store.GetSigningPrivateKey()
+	require.NoError(t, err)
+
+	stub := &stubPasswordReader{password: "correct horse"}
+	usePasswordReader(t, stub)
+	require.NoError(t, runStorageProtect(storageProtectCmd, nil))
+	assert.True(t, config.Get().EncryptKeys)
+	assert.Equal(t, []string{"Key passphrase", "Confirm key passphrase"}, stub.prompts)
+	raw, err := keyring.Get("initflow-cli-test-"+t.Name(), "workspace-key-left-project")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(raw, "ifsealed1:"), "unlisted workspace keys are sealed too")
+
+	// A later command asks for the passphrase once and rejects a wrong one
+	storage.ForgetPassphrase()
+	stub.password, stub.prompts = "wrong", nil
+	_, err = storage.New().GetSigningPrivateKey()
+	assert.ErrorIs(t, err, storage.ErrWrongPassphrase)
+
+	storage.ForgetPassphrase()
+	stub.password, stub.prompts = "correct horse", nil
+	store = storage.New()
+	restored, err := store.GetSigningPrivateKey()
+	require.NoError(t, err)
+	assert.Equal(t, signingKey, restored)
+	cached, err := store.GetWorkspaceKey("my-project")
+	require.NoError(t, err)
+	assert.Equal(t, workspaceKey, cached)
+	assert.Len(t, stub.prompts, 1)
+
+	require.NoError(t, runStorageUnprotect(storageUnprotectCmd, nil))
+	assert.False(t, config.Get().EncryptKeys)
+
+	// Unprotected keys need no passphrase
+	storage.ForgetPassphrase()
+	stub.prompts = nil
+	_, err = storage.New().GetEncryptionPrivateKey()
+	require.NoError(t, err)
+	assert.Empty(t, stub.prompts)
+}
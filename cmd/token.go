@@ -0,0 +1,267 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/client"
+	"github.com/DylanBlakemore/initflow-cli/internal/encoding"
+	"github.com/DylanBlakemore/initflow-cli/internal/storage"
+)
+
+// scopedTokenEnvVar holds a workspace-scoped access token to use instead of this device
+const scopedTokenEnvVar = "INITFLOW_TOKEN"
+
+// scopedTokenKeySeparator joins a scoped token to the private key that
+// decrypts its workspace key. Only the part before it is sent to the server.
+const scopedTokenKeySeparator = "~"
+
+// maxTokenTTL caps how long a scoped token may live
+const maxTokenTTL = 90 * 24 * time.Hour
+
+var tokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Manage workspace-scoped access tokens",
+	Long: "Create, list and revoke access tokens limited to one workspace, for automation that " +
+		"shouldn't use your device. Commands run with INITFLOW_TOKEN set use the token instead of this device.",
+}
+
+var tokenCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create a scoped access token",
+	Long: "Create an access token for one workspace with read or write scope. The token is printed once; " +
+		"pass it to automation as INITFLOW_TOKEN. It carries its own copy of the workspace key, so automation " +
+		"can decrypt secrets, e.g. with 'initflow run', without registering a device. Rotating the workspace " +
+		"key leaves existing tokens unable to decrypt. Requires the owner or admin role.",
+	Args: cobra.NoArgs,
+	RunE: runTokenCreate,
+}
+
+var tokenListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List scoped access tokens",
+	Args:  cobra.NoArgs,
+	RunE:  runTokenList,
+}
+
+var tokenRevokeCmd = &cobra.Command{
+	Use:   "revoke <token-id>",
+	Short: "Revoke a scoped access token",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runTokenRevoke,
+}
+
+var (
+	tokenWorkspace string
+	tokenScope     string
+	tokenTTL       time.Duration
+)
+
+func init() {
+	authCmd.AddCommand(tokenCmd)
+	tokenCmd.AddCommand(tokenCreateCmd)
+	tokenCmd.AddCommand(tokenListCmd)
+	tokenCmd.AddCommand(tokenRevokeCmd)
+
+	tokenCreateCmd.Flags().StringVar(&tokenWorkspace, "workspace", "", "slug of the workspace the token is limited to")
+	tokenCreateCmd.Flags().StringVar(&tokenScope, "scope", client.ScopeRead, "read or write")
+	tokenCreateCmd.Flags().DurationVar(&tokenTTL, "ttl", 24*time.Hour, "how long the token stays valid (max 2160h)")
+	_ = tokenCreateCmd.MarkFlagRequired("workspace")
+}
+
+// scopedToken returns the access token from INITFLOW_TOKEN, if any
+func scopedToken() string {
+	token, _, _ := strings.Cut(os.Getenv(scopedTokenEnvVar), scopedTokenKeySeparator)
+	return token
+}
+
+// scopedTokenKey returns the private key that INITFLOW_TOKEN carries for
+// decrypting its workspace key
+func scopedTokenKey() ([]byte, error) {
+	_, encoded, ok := strings.Cut(os.Getenv(scopedTokenEnvVar), scopedTokenKeySeparator)
+	if !ok {
+		return nil, fmt.Errorf("%s can't decrypt secrets. Create a new token with 'initflow auth token create'",
+			scopedTokenEnvVar)
+	}
+
+	key, err := encoding.Decode(encoded)
+	if err != nil || len(key) != encoding.X25519PrivateKeySize {
+		return nil, fmt.Errorf("%s has a malformed decryption key; copy the whole token", scopedTokenEnvVar)
+	}
+	return key, nil
+}
+
+// ensureSecretsAccess checks that this machine can decrypt secrets, either as
+// a registered device or with a scoped token carrying its own key
+func ensureSecretsAccess(store *storage.Storage) error {
+	if scopedToken() != "" {
+		return nil
+	}
+	return store.EnsureDeviceReady()
+}
+
+// openWorkspaceWithToken looks up a workspace and unwraps the copy of its key
+// held by the scoped token in INITFLOW_TOKEN
+func openWorkspaceWithToken(c *client.Client, slug string) (*client.Workspace, []byte, error) {
+	privateKey, err := scopedTokenKey()
+	if err != nil {
+		return nil, nil, fmt.Errorf("❌ %w", err)
+	}
+
+	workspace, err := c.GetWorkspaceBySlug(slug)
+	if err != nil {
+		return nil, nil, fmt.Errorf("❌ Failed to get workspace info: %w", err)
+	}
+
+	wrapped, version, err := c.GetDeviceWorkspaceKey(workspace.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("❌ Failed to fetch the token's workspace key: %w", err)
+	}
+	if version != 0 && version < workspace.KeyVersion {
+		return nil, nil, fmt.Errorf("❌ The key of %s was rotated after this token was created. "+
+			"Create a new token with 'initflow auth token create --workspace %s'", slug, slug)
+	}
+
+	workspaceKey, err := unwrapWorkspaceKeyWith(wrapped, workspace.ID, privateKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("❌ Failed to decrypt the workspace key with %s: %w", scopedTokenEnvVar, err)
+	}
+	return workspace, workspaceKey, nil
+}
+
+// requireAPIAccess fails unless this machine can make authenticated API calls,
+// either as a registered device or with a scoped token
+func requireAPIAccess(store *storage.Storage) error {
+	if store.HasDeviceID() || scopedToken() != "" {
+		return nil
+	}
+	return fmt.Errorf("❌ Device not registered. Please run 'initflow device register <name>' first")
+}
+
+func validateTokenScope(scope string) error {
+	switch scope {
+	case client.ScopeRead, client.ScopeWrite:
+		return nil
+	default:
+		return fmt.Errorf("invalid scope %q: must be read or write", scope)
+	}
+}
+
+func runTokenCreate(cmd *cobra.Command, args []string) error {
+	if err := validateTokenScope(tokenScope); err != nil {
+		return err
+	}
+	if tokenTTL <= 0 || tokenTTL > maxTokenTTL {
+		return fmt.Errorf("--ttl must be between 1s and %s", maxTokenTTL)
+	}
+
+	store := storage.New()
+	if !store.HasDeviceID() {
+		return fmt.Errorf("❌ Device not registered. Please run 'initflow device register <name>' first")
+	}
+
+	c := newClient()
+	workspace, err := c.GetWorkspaceBySlug(tokenWorkspace)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to get workspace info: %w", err)
+	}
+
+	if !hasRole(workspace.Role, workspaceAdminRoles) {
+		return fmt.Errorf("❌ Creating tokens for %s requires the owner or admin role (you are %s)",
+			tokenWorkspace, workspace.Role)
+	}
+
+	if !store.HasWorkspaceKey(tokenWorkspace) {
+		return fmt.Errorf("❌ This device has no key for %s to give the token", tokenWorkspace)
+	}
+	workspaceKey, err := currentWorkspaceKey(c, store, workspace)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to read workspace key: %w", err)
+	}
+
+	// The token gets its own keypair; the server only sees the public half
+	publicKey, privateKey, err := generateX25519Keypair()
+	if err != nil {
+		return fmt.Errorf("❌ Failed to generate token keypair: %w", err)
+	}
+	wrapped, err := wrapWorkspaceKeyFor(workspaceKey, workspace.ID, publicKey)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to encrypt workspace key: %w", err)
+	}
+
+	token, err := c.CreateScopedToken(client.CreateScopedTokenRequest{
+		WorkspaceID:         workspace.ID,
+		Scope:               tokenScope,
+		TTLSeconds:          int64(tokenTTL / time.Second),
+		WrappedWorkspaceKey: encoding.Encode(wrapped),
+		KeyVersion:          workspace.KeyVersion,
+	})
+	if err != nil {
+		return fmt.Errorf("❌ Failed to create token: %w", err)
+	}
+	token.Token += scopedTokenKeySeparator + encoding.Encode(privateKey)
+
+	if structuredOutput() {
+		return writeOutput(token)
+	}
+
+	infof("✅ Created %s token %s for %s\n", token.Scope, token.ID, tokenWorkspace)
+	fmt.Fprintln(cmd.OutOrStdout(), token.Token)
+	infoln("⚠️  This is the only time the token is shown. Pass it to automation as INITFLOW_TOKEN.")
+
+	return nil
+}
+
+func runTokenList(cmd *cobra.Command, args []string) error {
+	store := storage.New()
+	if !store.HasDeviceID() {
+		return fmt.Errorf("❌ Device not registered. Please run 'initflow device register <name>' first")
+	}
+
+	tokens, err := newClient().ListScopedTokens()
+	if err != nil {
+		return fmt.Errorf("❌ Failed to list tokens: %w", err)
+	}
+
+	if structuredOutput() {
+		return writeOutput(tokens)
+	}
+
+	if len(tokens) == 0 {
+		infoln("No tokens found")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 1, ' ', tabwriter.Debug)
+	fmt.Fprintln(w, "ID\tWorkspace\tScope\tExpires")
+	fmt.Fprintln(w, "──\t─────────\t─────\t───────")
+	for _, token := range tokens {
+		workspace := token.WorkspaceSlug
+		if workspace == "" {
+			workspace = fmt.Sprintf("#%d", token.WorkspaceID)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", token.ID, workspace, token.Scope, token.ExpiresAt)
+	}
+	_ = w.Flush()
+
+	return nil
+}
+
+func runTokenRevoke(cmd *cobra.Command, args []string) error {
+	store := storage.New()
+	if !store.HasDeviceID() {
+		return fmt.Errorf("❌ Device not registered. Please run 'initflow device register <name>' first")
+	}
+
+	if err := newClient().RevokeScopedToken(args[0]); err != nil {
+		return fmt.Errorf("❌ Failed to revoke token: %w", err)
+	}
+
+	infof("✅ Token %s revoked\n", args[0])
+	return nil
+}
@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/client"
+	"github.com/DylanBlakemore/initflow-cli/internal/storage"
+)
+
+// tokenServer keeps a list of tokens that DELETE removes
+func tokenServer(t *testing.T, tokens *[]client.ScopedToken) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/api/v1/tokens":
+			json.NewEncoder(w).Encode(client.ListScopedTokensResponse{Tokens: *tokens})
+		case r.Method == "DELETE" && strings.HasPrefix(r.URL.Path, "/api/v1/tokens/"):
+			id := strings.TrimPrefix(r.URL.Path, "/api/v1/tokens/")
+			for i, token := range *tokens {
+				if token.ID == id {
+					*tokens = append((*tokens)[:i], (*tokens)[i+1:]...)
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+			}
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(client.ErrorResponse{Error: "not_found", Message: "Token not found"})
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestTokenListAndRevoke(t *testing.T) {
+	tokens := []client.ScopedToken{
+		{ID: "tok-1", WorkspaceID: 1, WorkspaceSlug: "my-project", Scope: client.ScopeRead},
+		{ID: "tok-2", WorkspaceID: 2, Scope: client.ScopeWrite},
+	}
+	server := tokenServer(t, &tokens)
+	defer server.Close()
+
+	setupTestEnvironment(t, server.URL)
+
+	var err error
+	out := captureStdout(t, func() {
+		err = runTokenList(tokenListCmd, []string{})
+	})
+	if err != nil {
+		t.Fatalf("runTokenList failed: %v", err)
+	}
+	for _, want := range []string{"tok-1", "my-project", "tok-2", "#2", "write"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in token list, got %q", want, out)
+		}
+	}
+
+	if err := runTokenRevoke(tokenRevokeCmd, []string{"tok-1"}); err != nil {
+		t.Fatalf("runTokenRevoke failed: %v", err)
+	}
+	if len(tokens) != 1 || tokens[0].ID != "tok-2" {
+		t.Errorf("Expected only tok-2 to remain, got %v", tokens)
+	}
+
+	err = runTokenRevoke(tokenRevokeCmd, []string{"tok-1"})
+	if exitCodeFor(err) != exitNotFound {
+		t.Errorf("Expected revoking an unknown token to exit %d, got %v", exitNotFound, err)
+	}
+}
+
+func TestScopedTokenExceedingScope(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer scoped-secret" {
+			t.Errorf("Expected the scoped token to authenticate the request, got %q", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(client.ErrorResponse{Error: "insufficient_scope", Message: "token is limited to my-project"})
+	}))
+	defer server.Close()
+
+	setupTestEnvironment(t, server.URL)
+	t.Setenv(scopedTokenEnvVar, "scoped-secret")
+
+	err := runWorkspaceList(workspaceListCmd, []string{})
+	if !errors.Is(err, client.ErrInsufficientScope) {
+		t.Fatalf("Expected a scope error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "not allowed by the access token's scope (token is limited to my-project)") {
+		t.Errorf("Expected a clear scope message, got %q", err.Error())
+	}
+	if code := exitCodeFor(err); code != exitAuth {
+		t.Errorf("Expected exit code %d, got %d", exitAuth, code)
+	}
+}
+
+func TestTokenCreateRejectsBadScope(t *testing.T) {
+	tokenScope = "admin"
+	t.Cleanup(func() { tokenScope = client.ScopeRead })
+
+	err := runTokenCreate(tokenCreateCmd, []string{})
+	if err == nil || !strings.Contains(err.Error(), "must be read or write") {
+		t.Fatalf("Expected a scope validation error, got %v", err)
+	}
+}
+
+func TestScopedTokenDecryptsSecrets(t *testing.T) {
+	setupSecretsTest(t)
+	if err := runSecretsAdd(secretsAddCmd, []string{"API_KEY=s3cret"}); err != nil {
+		t.Fatalf("runSecretsAdd failed: %v", err)
+	}
+
+	tokenWorkspace = "my-project"
+	t.Cleanup(func() { tokenWorkspace = "" })
+	var err error
+	out := captureStdout(t, func() {
+		err = runTokenCreate(tokenCreateCmd, []string{})
+	})
+	if err != nil {
+		t.Fatalf("runTokenCreate failed: %v", err)
+	}
+	var token string
+	for _, line := range strings.Split(out, "\n") {
+		if strings.HasPrefix(line, "tok-secret") {
+			token = line
+		}
+	}
+	if !strings.HasPrefix(token, "tok-secret"+scopedTokenKeySeparator) {
+		t.Fatalf("Expected the token to carry its decryption key, got %q", token)
+	}
+
+	// A CI machine has nothing but the token
+	store := storage.New()
+	store.ClearDeviceCredentials()
+	store.DeleteWorkspaceKey("my-project")
+	t.Setenv(scopedTokenEnvVar, token)
+	runWorkspace = "my-project"
+	t.Cleanup(func() { runWorkspace = "" })
+
+	out = captureStdout(t, func() {
+		err = runRun(runCmd, []string{"sh", "-c", `printf '%s' "$API_KEY"`})
+	})
+	if err != nil || out != "s3cret" {
+		t.Errorf("Expected the token to decrypt API_KEY, got %q, %v", out, err)
+	}
+
+	// Tokens created before they carried a key still authenticate, but can't decrypt
+	t.Setenv(scopedTokenEnvVar, "tok-secret")
+	err = runSecretsGet(secretsGetCmd, []string{"API_KEY"})
+	if err == nil || !strings.Contains(err.Error(), "can't decrypt secrets") {
+		t.Errorf("Expected a token without a key to be explained, got %v", err)
+	}
+}
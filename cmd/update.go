@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/spf13/cobra"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/config"
+	"github.com/DylanBlakemore/initflow-cli/internal/update"
+)
+
+var updateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Update initflow to the latest release",
+	Long: `Download the latest release for this platform, verify its SHA-256 checksum and replace
+the running executable. With --check, only report whether an update is available, exiting
+non-zero when one is.`,
+	Args: cobra.NoArgs,
+	RunE: runUpdate,
+}
+
+var (
+	updateCheckOnly  bool
+	latestReleaseURL = update.LatestReleaseURL
+)
+
+func init() {
+	rootCmd.AddCommand(updateCmd)
+
+	updateCmd.Flags().BoolVar(&updateCheckOnly, "check", false,
+		"only check for a newer release; exit non-zero if one is available")
+}
+
+func runUpdate(cmd *cobra.Command, args []string) error {
+	httpClient := &http.Client{Timeout: config.Get().Timeout}
+
+	infoln("🔍 Checking for updates...")
+	release, err := update.LatestRelease(httpClient, latestReleaseURL)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to check for updates: %w", err)
+	}
+
+	cmp, err := update.CompareVersions(version, release.Version)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to compare versions: %w", err)
+	}
+	if cmp >= 0 {
+		infof("✅ initflow %s is up to date\n", version)
+		return nil
+	}
+
+	if updateCheckOnly {
+		return fmt.Errorf("ℹ️ Update available: %s → %s (run 'initflow update')", version, release.Version)
+	}
+
+	assetName := update.AssetName(runtime.GOOS, runtime.GOARCH)
+	assetURL, ok := release.Assets[assetName]
+	if !ok {
+		return fmt.Errorf("❌ Release %s has no build for %s/%s", release.Version, runtime.GOOS, runtime.GOARCH)
+	}
+	checksumsURL, ok := release.Assets[update.ChecksumsAsset]
+	if !ok {
+		return fmt.Errorf("❌ Release %s has no %s; refusing to install an unverified binary",
+			release.Version, update.ChecksumsAsset)
+	}
+
+	infof("📦 Downloading %s %s...\n", assetName, release.Version)
+	archive, err := update.Download(httpClient, assetURL)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to download update: %w", err)
+	}
+	checksums, err := update.Download(httpClient, checksumsURL)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to download checksums: %w", err)
+	}
+
+	infoln("🔒 Verifying checksum...")
+	if err := update.VerifyChecksum(archive, checksums, assetName); err != nil {
+		return fmt.Errorf("❌ Failed to verify update: %w", err)
+	}
+
+	binary, err := update.ExtractBinary(archive, assetName, update.BinaryName(runtime.GOOS, runtime.GOARCH))
+	if err != nil {
+		return fmt.Errorf("❌ Failed to unpack update: %w", err)
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("❌ Failed to locate the running executable: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(executable); err == nil {
+		executable = resolved
+	}
+
+	if err := update.ReplaceExecutable(executable, binary); err != nil {
+		if errors.Is(err, update.ErrNotWritable) {
+			return fmt.Errorf("❌ Can't replace %s: %w. Re-run with elevated permissions "+
+				"(e.g. sudo initflow update) or reinstall to a user-writable directory", executable, err)
+		}
+		return fmt.Errorf("❌ Failed to install update: %w", err)
+	}
+
+	infof("✅ Updated initflow %s → %s\n", version, release.Version)
+	return nil
+}
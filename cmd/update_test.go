@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/update"
+)
+
+func TestUpdateCheck(t *testing.T) {
+	latest := version
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"tag_name": "` + latest + `", "assets": []}`))
+	}))
+	defer server.Close()
+
+	latestReleaseURL, updateCheckOnly = server.URL, true
+	t.Cleanup(func() {
+		latestReleaseURL, updateCheckOnly = update.LatestReleaseURL, false
+	})
+
+	captureStdout(t, func() {
+		assert.NoError(t, runUpdate(updateCmd, nil), "same version is up to date")
+
+		latest = "v99.0.0"
+		err := runUpdate(updateCmd, nil)
+		if assert.Error(t, err, "newer release should exit non-zero") {
+			assert.Contains(t, err.Error(), "Update available: "+version+" → v99.0.0")
+		}
+	})
+}
@@ -12,7 +12,7 @@ var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Print the CLI version",
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("initflow-cli", version)
+		fmt.Fprintln(cmd.OutOrStdout(), "initflow-cli", version)
 	},
 }
 
@@ -1,16 +1,25 @@
 package cmd
 
 import (
+	"bufio"
+	"bytes"
 	"crypto/rand"
 	"crypto/sha256"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"text/tabwriter"
 
 	"github.com/spf13/cobra"
 	"golang.org/x/crypto/chacha20poly1305"
 	"golang.org/x/crypto/curve25519"
 	"golang.org/x/crypto/hkdf"
+	"golang.org/x/term"
 
 	"github.com/DylanBlakemore/initflow-cli/internal/client"
 	"github.com/DylanBlakemore/initflow-cli/internal/encoding"
@@ -38,48 +47,271 @@ var workspaceInitCmd = &cobra.Command{
 	RunE:  runWorkspaceInit,
 }
 
+var workspaceInitAllCmd = &cobra.Command{
+	Use:   "init-all",
+	Short: "Initialize keys for all uninitialized workspaces",
+	Long: `Initialize workspace keys for every workspace that doesn't have one yet and where your role allows it.
+Failures for individual workspaces are reported and don't stop the remaining workspaces.`,
+	Args: cobra.NoArgs,
+	RunE: runWorkspaceInitAll,
+}
+
+var workspaceRenameCmd = &cobra.Command{
+	Use:   "rename <workspace-slug> <new-name>",
+	Short: "Rename a workspace",
+	Long: `Change a workspace's display name and, with --new-slug, its slug. Requires the owner or admin role.
+A slug change moves the locally cached workspace key to the new slug and asks for confirmation unless --yes is set.`,
+	Args: cobra.ExactArgs(2),
+	RunE: runWorkspaceRename,
+}
+
+var workspaceInfoCmd = &cobra.Command{
+	Use:   "info <workspace-slug>",
+	Short: "Show details of a workspace",
+	Long:  `Show a workspace's details, its member and device counts, and whether its key is cached on this machine.`,
+	Args:  cobra.ExactArgs(1),
+	RunE:  runWorkspaceInfo,
+}
+
+var initAllDryRun bool
+
+var (
+	renameNewSlug string
+	renameYes     bool
+)
+
+var (
+	listRoles               []string
+	listSort                string
+	listUninitialized       bool
+	listFailOnUninitialized bool
+	listRequiredSlugs       []string
+)
+
+// workspaceColumns are the columns workspace list can show
+var workspaceColumns = []tableColumn{
+	{Name: "id", Header: "ID"},
+	{Name: "name", Header: "Name", Default: true},
+	{Name: "slug", Header: "Slug", Default: true},
+	{Name: "key", Header: "Key Initialized", Default: true},
+	{Name: "role", Header: "Role", Default: true},
+	{Name: "organization", Header: "Organization"},
+}
+
+var listTable tableOptions
+
+// workspaceSortKeys are the accepted values of workspace list --sort
+var workspaceSortKeys = []string{"name", "slug", "role"}
+
+// workspaceAdminRoles lists the workspace roles allowed to change workspace
+// settings, manage devices and tokens, and initialize or rotate its key
+var workspaceAdminRoles = []string{"owner", "admin"}
+
 func init() {
 	rootCmd.AddCommand(workspaceCmd)
 	workspaceCmd.AddCommand(workspaceListCmd)
 	workspaceCmd.AddCommand(workspaceInitCmd)
+	workspaceCmd.AddCommand(workspaceInitAllCmd)
+	workspaceCmd.AddCommand(workspaceRenameCmd)
+	workspaceCmd.AddCommand(workspaceInfoCmd)
+
+	workspaceListCmd.Flags().StringSliceVar(&listRoles, "role", nil,
+		"only show workspaces where you have this role (repeatable)")
+	workspaceListCmd.Flags().StringVar(&listSort, "sort", "",
+		"sort by name, slug or role (default: server order)")
+	workspaceListCmd.Flags().BoolVar(&listUninitialized, "uninitialized", false,
+		"only show workspaces whose key still needs 'workspace init'")
+	workspaceListCmd.Flags().BoolVar(&listFailOnUninitialized, "fail-on-uninitialized", false,
+		"exit non-zero if a listed workspace (or one named in --slugs) has no key yet")
+	workspaceListCmd.Flags().StringSliceVar(&listRequiredSlugs, "slugs", nil,
+		"with --fail-on-uninitialized, only check these workspaces")
+	addTableFlags(workspaceListCmd, &listTable, workspaceColumns)
+
+	workspaceInitAllCmd.Flags().BoolVar(&initAllDryRun, "dry-run", false,
+		"show which workspaces would be initialized without making changes")
+
+	workspaceInitCmd.Flags().BoolVar(&waitForOperations, "wait", false,
+		"if the server finishes initialization in the background, wait until it is done")
+	workspaceInitCmd.Flags().DurationVar(&operationWaitTimeout, "wait-timeout", operationWaitTimeout,
+		"how long --wait waits before giving up")
+
+	workspaceRenameCmd.Flags().StringVar(&renameNewSlug, "new-slug", "", "also change the workspace slug")
+	workspaceRenameCmd.Flags().BoolVarP(&renameYes, "yes", "y", false, "don't ask before changing the slug")
+}
+
+// canInitializeKey reports whether role may generate a workspace key;
+// workspace init and init-all check it first
+func canInitializeKey(role string) bool {
+	return hasRole(role, workspaceAdminRoles)
+}
+
+// filterWorkspaces keeps workspaces matching any of roles (all when empty) and,
+// if uninitialized is set, only those without a key yet.
+func filterWorkspaces(workspaces []client.Workspace, roles []string, uninitialized bool) []client.Workspace {
+	filtered := make([]client.Workspace, 0, len(workspaces))
+	for _, workspace := range workspaces {
+		if uninitialized && workspace.KeyInitialized {
+			continue
+		}
+		if len(roles) > 0 && !hasRole(workspace.Role, roles) {
+			continue
+		}
+		filtered = append(filtered, workspace)
+	}
+	return filtered
+}
+
+func hasRole(role string, roles []string) bool {
+	for _, r := range roles {
+		if strings.EqualFold(role, strings.TrimSpace(r)) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortWorkspaces orders workspaces by the given key, falling back to slug for
+// ties. An empty key keeps the server order.
+func sortWorkspaces(workspaces []client.Workspace, by string) error {
+	var field func(client.Workspace) string
+	switch by {
+	case "":
+		return nil
+	case "name":
+		field = func(w client.Workspace) string { return strings.ToLower(w.Name) }
+	case "slug":
+		field = func(w client.Workspace) string { return w.Slug }
+	case "role":
+		field = func(w client.Workspace) string { return strings.ToLower(w.Role) }
+	default:
+		return fmt.Errorf("invalid sort %q: must be one of %s", by, strings.Join(workspaceSortKeys, ", "))
+	}
+
+	sort.SliceStable(workspaces, func(i, j int) bool {
+		a, b := field(workspaces[i]), field(workspaces[j])
+		if a != b {
+			return a < b
+		}
+		return workspaces[i].Slug < workspaces[j].Slug
+	})
+	return nil
 }
 
 func runWorkspaceList(cmd *cobra.Command, args []string) error {
-	fmt.Println("🔍 Fetching workspaces...")
+	// Reject bad flags before making any requests
+	if err := sortWorkspaces(nil, listSort); err != nil {
+		return err
+	}
+	if len(listRequiredSlugs) > 0 && !listFailOnUninitialized {
+		return fmt.Errorf("--slugs requires --fail-on-uninitialized")
+	}
+	columns, err := selectColumns(workspaceColumns, listTable.columns)
+	if err != nil {
+		return err
+	}
+
+	infoln("🔍 Fetching workspaces...")
 
 	store := storage.New()
-	if !store.HasDeviceID() {
-		return fmt.Errorf("❌ Device not registered. Please run 'initflow device register <name>' first")
+	if err := requireAPIAccess(store); err != nil {
+		return err
 	}
 
-	c := client.New()
+	c := newClient()
 	workspaces, err := c.ListWorkspaces()
 	if err != nil {
 		return fmt.Errorf("❌ Failed to fetch workspaces: %w", err)
 	}
 
+	filtered := filterWorkspaces(workspaces, listRoles, listUninitialized)
+	if err := sortWorkspaces(filtered, listSort); err != nil {
+		return err
+	}
+
+	if err := printWorkspaces(cmd.OutOrStdout(), workspaces, filtered, columns, !listTable.noHeader); err != nil {
+		return err
+	}
+
+	if listFailOnUninitialized {
+		return checkWorkspacesInitialized(workspaces, filtered, listRequiredSlugs)
+	}
+	return nil
+}
+
+// checkWorkspacesInitialized fails when any of the named workspaces, or any
+// listed workspace if none are named, has no key yet. Named workspaces that
+// don't exist fail too.
+func checkWorkspacesInitialized(all, listed []client.Workspace, slugs []string) error {
+	targets := listed
+	var missing []string
+	if len(slugs) > 0 {
+		bySlug := make(map[string]client.Workspace, len(all))
+		for _, workspace := range all {
+			bySlug[workspace.Slug] = workspace
+		}
+
+		targets = nil
+		for _, slug := range slugs {
+			slug = strings.TrimSpace(slug)
+			workspace, ok := bySlug[slug]
+			if !ok {
+				missing = append(missing, slug)
+				continue
+			}
+			targets = append(targets, workspace)
+		}
+	}
+
+	var uninitialized []string
+	for _, workspace := range targets {
+		if !workspace.KeyInitialized {
+			uninitialized = append(uninitialized, workspace.Slug)
+		}
+	}
+
+	switch {
+	case len(missing) > 0:
+		return fmt.Errorf("❌ Workspaces not found: %s", strings.Join(missing, ", "))
+	case len(uninitialized) > 0:
+		return fmt.Errorf("❌ Workspace keys not initialized: %s", strings.Join(uninitialized, ", "))
+	}
+	return nil
+}
+
+// printWorkspaces renders the filtered listing to out as a table, JSON or YAML
+func printWorkspaces(out io.Writer, workspaces, filtered []client.Workspace, columns []tableColumn, header bool) error {
+	if structuredOutput() {
+		return writeOutput(filtered)
+	}
+
 	if len(workspaces) == 0 {
-		fmt.Println("No workspaces found. Create one at https://app.initflow.com")
+		infoln("No workspaces found. Create one at https://app.initflow.com")
 		return nil
 	}
 
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.Debug)
-	fmt.Fprintln(w, "Name\tSlug\tKey Initialized\tRole")
-	fmt.Fprintln(w, "────\t────\t───────────────\t────")
+	if len(filtered) == 0 {
+		infoln("No workspaces match the given filters")
+		return nil
+	}
+	workspaces = filtered
 
-	for _, workspace := range workspaces {
+	rows := make([]map[string]string, len(workspaces))
+	for i, workspace := range workspaces {
 		keyStatus := "❌ No"
 		if workspace.KeyInitialized {
 			keyStatus = "✅ Yes"
 		}
 
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
-			workspace.Name,
-			workspace.Slug,
-			keyStatus,
-			workspace.Role)
+		rows[i] = map[string]string{
+			"id":           strconv.Itoa(workspace.ID),
+			"name":         workspace.Name,
+			"slug":         workspace.Slug,
+			"key":          keyStatus,
+			"role":         workspace.Role,
+			"organization": workspace.Organization.Name,
+		}
 	}
-	_ = w.Flush()
+	writeTable(out, columns, rows, header)
 
 	hasUninitialized := false
 	for _, workspace := range workspaces {
@@ -90,8 +322,8 @@ func runWorkspaceList(cmd *cobra.Command, args []string) error {
 	}
 
 	if hasUninitialized {
-		fmt.Println("\n💡 Initialize keys for workspaces marked \"No\" using:")
-		fmt.Println("   initflow workspace init <workspace-slug>")
+		infoln("\n💡 Initialize keys for workspaces marked \"No\" using:")
+		infoln("   initflow workspace init <workspace-slug>")
 	}
 
 	return nil
@@ -100,66 +332,413 @@ func runWorkspaceList(cmd *cobra.Command, args []string) error {
 func runWorkspaceInit(cmd *cobra.Command, args []string) error {
 	workspaceSlug := args[0]
 
-	fmt.Printf("🔐 Initializing workspace key for \"%s\"...\n", workspaceSlug)
+	infof("🔐 Initializing workspace key for \"%s\"...\n", workspaceSlug)
 
 	store := storage.New()
-	if !store.HasDeviceID() {
-		return fmt.Errorf("❌ Device not registered. Please run 'initflow device register <name>' first")
+	if err := store.EnsureDeviceReady(); err != nil {
+		return fmt.Errorf("❌ %w", err)
 	}
 
-	if store.HasWorkspaceKey(workspaceSlug) {
-		fmt.Println("ℹ️ Workspace key already exists locally")
+	hasLocalKey := store.HasWorkspaceKey(workspaceSlug)
+	if hasLocalKey && !initWithRecovery {
+		infoln("ℹ️ Workspace key already exists locally")
 		return nil
 	}
 
-	c := client.New()
+	c := newClient()
 	workspace, err := c.GetWorkspaceBySlug(workspaceSlug)
 	if err != nil {
 		return fmt.Errorf("❌ Failed to get workspace info: %w", err)
 	}
 
+	if hasLocalKey {
+		infoln("ℹ️ Workspace key already exists locally")
+		return addRecovery(c, store, workspace)
+	}
+
 	if workspace.KeyInitialized {
 		return fmt.Errorf("ℹ️ Workspace key already initialized")
 	}
 
-	fmt.Println("⚡ Generating secure 256-bit workspace key...")
+	// Check before generating anything; the server would refuse the upload anyway
+	if !canInitializeKey(workspace.Role) {
+		return fmt.Errorf("❌ Initializing the key for %s requires the %s role (you are %s). "+
+			"Ask a workspace owner or admin to run 'initflow workspace init %s'",
+			workspaceSlug, strings.Join(workspaceAdminRoles, " or "), workspace.Role, workspaceSlug)
+	}
+
+	if err := initializeWorkspaceKey(c, store, workspace, func(msg string) { infoln(msg) }); err != nil {
+		return err
+	}
+
+	infoln("✅ Workspace key initialized successfully!")
+
+	if initWithRecovery {
+		if err := addRecovery(c, store, workspace); err != nil {
+			return fmt.Errorf("%w. Retry with 'initflow workspace init %s --with-recovery'", err, workspaceSlug)
+		}
+	}
+
+	infoln("🎯 You can now store and retrieve secrets in this workspace.")
+	infoln()
+	infoln("Next steps:")
+	infof("  • Add secrets: initflow secrets add -w %s API_KEY=your-secret\n", workspaceSlug)
+	infof("  • List secrets: initflow secrets list -w %s\n", workspaceSlug)
+	infof("  • Invite devices: initflow workspace invite-device %s <device-id>\n", workspaceSlug)
+
+	return nil
+}
+
+func runWorkspaceRename(cmd *cobra.Command, args []string) error {
+	workspaceSlug, newName := args[0], strings.TrimSpace(args[1])
+	if newName == "" {
+		return fmt.Errorf("❌ Workspace name cannot be empty")
+	}
+
+	store := storage.New()
+	if err := requireAPIAccess(store); err != nil {
+		return err
+	}
+
+	c := newClient()
+	workspace, err := c.GetWorkspaceBySlug(workspaceSlug)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to get workspace info: %w", err)
+	}
+
+	if !hasRole(workspace.Role, workspaceAdminRoles) {
+		return fmt.Errorf("❌ Renaming %s requires the owner or admin role (you are %s)", workspaceSlug, workspace.Role)
+	}
+
+	newSlug := renameNewSlug
+	if newSlug == workspaceSlug {
+		newSlug = ""
+	}
+
+	if newSlug != "" && !renameYes {
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			return fmt.Errorf("❌ Changing the slug breaks scripts and configs that use %s. Re-run with --yes to confirm",
+				workspaceSlug)
+		}
+		proceed, err := askYesNo(bufio.NewReader(os.Stdin),
+			fmt.Sprintf("Change slug %s → %s? Anything referring to %s will stop working", workspaceSlug, newSlug, workspaceSlug),
+			false)
+		if err != nil {
+			return err
+		}
+		if !proceed {
+			return fmt.Errorf("ℹ️ Rename cancelled")
+		}
+	}
+
+	infof("✏️  Renaming \"%s\"...\n", workspaceSlug)
+	updated, err := c.UpdateWorkspace(workspace.ID, client.UpdateWorkspaceRequest{Name: newName, Slug: newSlug})
+	if errors.Is(err, client.ErrSlugTaken) {
+		return fmt.Errorf("❌ Slug '%s' is already taken: %w", newSlug, err)
+	}
+	if err != nil {
+		return fmt.Errorf("❌ Failed to rename workspace: %w", err)
+	}
+
+	if updated.Slug != "" && updated.Slug != workspaceSlug && store.HasWorkspaceKey(workspaceSlug) {
+		if err := store.RenameWorkspaceKey(workspaceSlug, updated.Slug); err != nil {
+			return fmt.Errorf("❌ Workspace renamed, but moving the local key to %s failed: %w", updated.Slug, err)
+		}
+	}
+
+	infof("✅ Workspace renamed to \"%s\" (%s)\n", updated.Name, updated.Slug)
+
+	return nil
+}
+
+// workspaceInfo is the detail view printed by workspace info
+type workspaceInfo struct {
+	ID               int    `json:"id"`
+	Name             string `json:"name"`
+	Slug             string `json:"slug"`
+	Description      string `json:"description,omitempty"`
+	Organization     string `json:"organization,omitempty"`
+	Role             string `json:"role"`
+	KeyInitialized   bool   `json:"key_initialized"`
+	KeyVersion       int    `json:"key_version"`
+	KeyCachedLocally bool   `json:"key_cached_locally"`
+	MemberCount      int    `json:"member_count"`
+	DeviceCount      *int   `json:"device_count"` // nil when the device list isn't visible to you
+	CreatedAt        string `json:"created_at,omitempty"`
+}
+
+func runWorkspaceInfo(cmd *cobra.Command, args []string) error {
+	workspaceSlug := args[0]
+
+	store := storage.New()
+	if err := requireAPIAccess(store); err != nil {
+		return err
+	}
+
+	c := newClient()
+	workspace, err := c.GetWorkspaceBySlug(workspaceSlug)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to get workspace info: %w", err)
+	}
+
+	info := workspaceInfo{
+		ID:               workspace.ID,
+		Name:             workspace.Name,
+		Slug:             workspace.Slug,
+		Description:      workspace.Description,
+		Organization:     workspace.Organization.Name,
+		Role:             workspace.Role,
+		KeyInitialized:   workspace.KeyInitialized,
+		KeyVersion:       workspace.KeyVersion,
+		KeyCachedLocally: store.HasWorkspaceKey(workspace.Slug),
+		MemberCount:      workspace.MemberCount,
+		CreatedAt:        workspace.CreatedAt,
+	}
+	if devices, err := c.ListWorkspaceDevices(workspace.ID); err == nil {
+		count := len(devices)
+		info.DeviceCount = &count
+	}
+
+	if structuredOutput() {
+		return writeOutput(info)
+	}
+
+	yesNo := func(b bool) string {
+		if b {
+			return "✅ Yes"
+		}
+		return "❌ No"
+	}
+	deviceCount := "unknown"
+	if info.DeviceCount != nil {
+		deviceCount = fmt.Sprint(*info.DeviceCount)
+	}
+	createdAt := info.CreatedAt
+	if createdAt == "" {
+		createdAt = "unknown"
+	}
+
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Name:\t%s\n", info.Name)
+	fmt.Fprintf(w, "Slug:\t%s\n", info.Slug)
+	fmt.Fprintf(w, "ID:\t%d\n", info.ID)
+	if info.Description != "" {
+		fmt.Fprintf(w, "Description:\t%s\n", info.Description)
+	}
+	if info.Organization != "" {
+		fmt.Fprintf(w, "Organization:\t%s\n", info.Organization)
+	}
+	fmt.Fprintf(w, "Role:\t%s\n", info.Role)
+	fmt.Fprintf(w, "Key Initialized:\t%s\n", yesNo(info.KeyInitialized))
+	fmt.Fprintf(w, "Key Version:\t%d\n", info.KeyVersion)
+	fmt.Fprintf(w, "Key Cached Locally:\t%s\n", yesNo(info.KeyCachedLocally))
+	fmt.Fprintf(w, "Members:\t%d\n", info.MemberCount)
+	fmt.Fprintf(w, "Devices:\t%s\n", deviceCount)
+	fmt.Fprintf(w, "Created:\t%s\n", createdAt)
+	_ = w.Flush()
+
+	return nil
+}
+
+// initializeWorkspaceKey generates a new workspace key, uploads it wrapped to this
+// device and caches it locally. Each step is reported through progress.
+func initializeWorkspaceKey(
+	c *client.Client,
+	store *storage.Storage,
+	workspace *client.Workspace,
+	progress func(msg string),
+) error {
+	progress("⚡ Generating secure 256-bit workspace key...")
 	workspaceKey := make([]byte, encoding.WorkspaceKeySize)
 	if _, err := rand.Read(workspaceKey); err != nil {
 		return fmt.Errorf("❌ Failed to generate workspace key: %w", err)
 	}
 
-	fmt.Println("🔒 Encrypting with your device's X25519 key...")
-	wrappedKey, err := wrapWorkspaceKey(workspaceKey, store)
+	progress("🔒 Encrypting with your device's X25519 key...")
+	wrappedKey, err := wrapWorkspaceKey(workspaceKey, workspace.ID, store)
 	if err != nil {
 		return fmt.Errorf("❌ Failed to encrypt workspace key: %w", err)
 	}
 
-	fmt.Println("📡 Uploading encrypted key to server...")
-	if err := c.InitializeWorkspaceKey(workspace.ID, wrappedKey); err != nil {
+	progress("📡 Uploading encrypted key to server...")
+	op, err := c.InitializeWorkspaceKey(workspace.ID, wrappedKey)
+	if err != nil {
 		return fmt.Errorf("❌ Failed to initialize workspace key: %w", err)
 	}
+	if op != nil {
+		if err := finishOperation(c, op, "workspace key"); err != nil {
+			return fmt.Errorf("❌ Failed to initialize workspace key: %w", err)
+		}
+	}
 
-	if err := store.StoreWorkspaceKey(workspaceSlug, workspaceKey); err != nil {
+	if err := store.StoreWorkspaceKey(workspace.Slug, workspaceKey); err != nil {
 		return fmt.Errorf("❌ Failed to store workspace key locally: %w", err)
 	}
 
-	fmt.Println("✅ Workspace key initialized successfully!")
-	fmt.Println("🎯 You can now store and retrieve secrets in this workspace.")
-	fmt.Println()
-	fmt.Println("Next steps:")
-	fmt.Println("  • Add secrets: initflow secrets add API_KEY=your-secret")
-	fmt.Println("  • List secrets: initflow secrets list")
-	fmt.Println("  • Invite devices: initflow workspace invite-device")
+	return nil
+}
+
+func runWorkspaceInitAll(cmd *cobra.Command, args []string) error {
+	infoln("🔍 Fetching workspaces...")
+
+	store := storage.New()
+	if err := store.EnsureDeviceReady(); err != nil {
+		return fmt.Errorf("❌ %w", err)
+	}
+
+	c := newClient()
+	workspaces, err := c.ListWorkspaces()
+	if err != nil {
+		return fmt.Errorf("❌ Failed to fetch workspaces: %w", err)
+	}
+
+	pending := make([]*client.Workspace, 0, len(workspaces))
+	for i := range workspaces {
+		if !workspaces[i].KeyInitialized {
+			pending = append(pending, &workspaces[i])
+		}
+	}
+
+	total := len(pending)
+	if initAllDryRun {
+		total = 0 // nothing slow to report
+	}
+	bar := newProgress("Initializing", total)
+
+	var initialized, failed, skipped int
+	for _, workspace := range pending {
+		if !canInitializeKey(workspace.Role) {
+			bar.Clear()
+			infof("⏭️  %s: skipped (role %q can't initialize keys)\n", workspace.Slug, workspace.Role)
+			bar.Increment()
+			skipped++
+			continue
+		}
+
+		if initAllDryRun {
+			fmt.Fprintf(cmd.OutOrStdout(), "📝 %s: would be initialized\n", workspace.Slug)
+			initialized++
+			continue
+		}
+
+		err := initializeWorkspaceKey(c, store, workspace, func(string) {})
+		bar.Clear()
+		if err != nil {
+			infof("❌ %s: %s\n", workspace.Slug, strings.TrimPrefix(err.Error(), "❌ "))
+			failed++
+		} else {
+			infof("✅ %s: initialized\n", workspace.Slug)
+			initialized++
+		}
+		bar.Increment()
+	}
+	bar.Finish()
+
+	infoln()
+	if initAllDryRun {
+		fmt.Fprintf(cmd.OutOrStdout(), "Dry run: %d to initialize, %d skipped\n", initialized, skipped)
+		return nil
+	}
+
+	infof("Summary: %d initialized, %d failed, %d skipped\n", initialized, failed, skipped)
+	if failed > 0 {
+		return fmt.Errorf("❌ Failed to initialize %d workspace(s)", failed)
+	}
+
+	return nil
+}
+
+// lowOrderX25519Points are the curve25519 public keys that force the shared
+// secret into a small subgroup, making the derived wrapping key predictable.
+var lowOrderX25519Points = [][]byte{
+	make([]byte, encoding.X25519PublicKeySize),
+	{
+		0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	},
+	{
+		0xe0, 0xeb, 0x7a, 0x7c, 0x3b, 0x41, 0xb8, 0xae, 0x16, 0x56, 0xe3, 0xfa, 0xf1, 0x9f, 0xc4, 0x6a,
+		0xda, 0x09, 0x8d, 0xeb, 0x9c, 0x32, 0xb1, 0xfd, 0x86, 0x62, 0x05, 0x16, 0x5f, 0x49, 0xb8, 0x00,
+	},
+	{
+		0x5f, 0x9c, 0x95, 0xbc, 0xa3, 0x50, 0x8c, 0x24, 0xb1, 0xd0, 0xb1, 0x55, 0x9c, 0x83, 0xef, 0x5b,
+		0x04, 0x44, 0x5c, 0xc4, 0x58, 0x1c, 0x8e, 0x86, 0xd8, 0x22, 0x4e, 0xdd, 0xd0, 0x9f, 0x11, 0x57,
+	},
+	{
+		0xec, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f,
+	},
+	{
+		0xed, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f,
+	},
+	{
+		0xee, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f,
+	},
+}
+
+// validateRecipientPublicKey rejects X25519 public keys that can't safely receive a wrapped key
+func validateRecipientPublicKey(publicKey []byte) error {
+	if len(publicKey) != encoding.X25519PublicKeySize {
+		return fmt.Errorf("invalid device public key: expected %d bytes, got %d",
+			encoding.X25519PublicKeySize, len(publicKey))
+	}
+
+	for _, point := range lowOrderX25519Points {
+		if bytes.Equal(publicKey, point) {
+			return fmt.Errorf("invalid device public key: key is a low-order point")
+		}
+	}
 
 	return nil
 }
 
-func wrapWorkspaceKey(workspaceKey []byte, store *storage.Storage) ([]byte, error) {
+// wrapFormatVersion identifies the wrapped key layout and is bound into the AEAD
+// associated data, so a blob can't be reinterpreted under a future format.
+const wrapFormatVersion = 1
+
+// wrappedKeyHeaderSize is the ephemeral public key and nonce preceding the ciphertext
+const wrappedKeyHeaderSize = encoding.X25519PublicKeySize + encoding.ChaCha20NonceSize
+
+// wrapAssociatedData binds a wrapped key to its format version and workspace,
+// so a blob replayed into another workspace fails to decrypt.
+func wrapAssociatedData(workspaceID int) []byte {
+	ad := []byte{wrapFormatVersion}
+	return binary.BigEndian.AppendUint64(ad, uint64(workspaceID)) // #nosec G115 - IDs are non-negative
+}
+
+// deriveWrappingKey turns an X25519 shared secret into a ChaCha20-Poly1305 key
+func deriveWrappingKey(sharedSecret []byte) ([]byte, error) {
+	hkdf := hkdf.New(sha256.New, sharedSecret, []byte("initflow.wrap"), []byte("workspace"))
+	encryptionKey := make([]byte, encoding.WorkspaceKeySize)
+	if _, err := hkdf.Read(encryptionKey); err != nil {
+		return nil, fmt.Errorf("failed to derive encryption key: %w", err)
+	}
+	return encryptionKey, nil
+}
+
+// wrapWorkspaceKey wraps the workspace key to this device's own X25519 key
+func wrapWorkspaceKey(workspaceKey []byte, workspaceID int, store *storage.Storage) ([]byte, error) {
 	encryptionPrivateKey, err := store.GetEncryptionPrivateKey()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get encryption private key: %w", err)
 	}
 
+	devicePublicKey, err := curve25519.X25519(encryptionPrivateKey, curve25519.Basepoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive device public key: %w", err)
+	}
+
+	return wrapWorkspaceKeyFor(workspaceKey, workspaceID, devicePublicKey)
+}
+
+// wrapWorkspaceKeyFor wraps the workspace key to a recipient device's X25519 public key
+func wrapWorkspaceKeyFor(workspaceKey []byte, workspaceID int, recipientPublicKey []byte) ([]byte, error) {
+	if err := validateRecipientPublicKey(recipientPublicKey); err != nil {
+		return nil, err
+	}
+
 	ephemeralPrivate := make([]byte, encoding.X25519PrivateKeySize)
 	if _, err := rand.Read(ephemeralPrivate); err != nil {
 		return nil, fmt.Errorf("failed to generate ephemeral private key: %w", err)
@@ -170,15 +749,14 @@ func wrapWorkspaceKey(workspaceKey []byte, store *storage.Storage) ([]byte, erro
 		return nil, fmt.Errorf("failed to generate ephemeral public key: %w", err)
 	}
 
-	sharedSecret, err := curve25519.X25519(encryptionPrivateKey, ephemeralPublic)
+	sharedSecret, err := curve25519.X25519(ephemeralPrivate, recipientPublicKey)
 	if err != nil {
 		return nil, fmt.Errorf("failed to compute shared secret: %w", err)
 	}
 
-	hkdf := hkdf.New(sha256.New, sharedSecret, []byte("initflow.wrap"), []byte("workspace"))
-	encryptionKey := make([]byte, encoding.WorkspaceKeySize)
-	if _, err := hkdf.Read(encryptionKey); err != nil {
-		return nil, fmt.Errorf("failed to derive encryption key: %w", err)
+	encryptionKey, err := deriveWrappingKey(sharedSecret)
+	if err != nil {
+		return nil, err
 	}
 
 	cipher, err := chacha20poly1305.New(encryptionKey)
@@ -191,12 +769,61 @@ func wrapWorkspaceKey(workspaceKey []byte, store *storage.Storage) ([]byte, erro
 		return nil, fmt.Errorf("failed to generate nonce: %w", err)
 	}
 
-	ciphertext := cipher.Seal(nil, nonce, workspaceKey, nil) // #nosec G407 - nonce is randomly generated above
+	// #nosec G407 - nonce is randomly generated above
+	ciphertext := cipher.Seal(nil, nonce, workspaceKey, wrapAssociatedData(workspaceID))
 
-	wrapped := make([]byte, 0, 32+12+len(ciphertext))
+	wrapped := make([]byte, 0, wrappedKeyHeaderSize+len(ciphertext))
 	wrapped = append(wrapped, ephemeralPublic...)
 	wrapped = append(wrapped, nonce...)
 	wrapped = append(wrapped, ciphertext...)
 
 	return wrapped, nil
 }
+
+// unwrapWorkspaceKey recovers a workspace key wrapped to this device for the given workspace
+func unwrapWorkspaceKey(wrapped []byte, workspaceID int, store *storage.Storage) ([]byte, error) {
+	if len(wrapped) < wrappedKeyHeaderSize+chacha20poly1305.Overhead {
+		return nil, fmt.Errorf("wrapped workspace key is too short: %d bytes", len(wrapped))
+	}
+
+	encryptionPrivateKey, err := store.GetEncryptionPrivateKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get encryption private key: %w", err)
+	}
+
+	return unwrapWorkspaceKeyWith(wrapped, workspaceID, encryptionPrivateKey)
+}
+
+// unwrapWorkspaceKeyWith recovers a workspace key wrapped to the X25519 public
+// key of encryptionPrivateKey
+func unwrapWorkspaceKeyWith(wrapped []byte, workspaceID int, encryptionPrivateKey []byte) ([]byte, error) {
+	if len(wrapped) < wrappedKeyHeaderSize+chacha20poly1305.Overhead {
+		return nil, fmt.Errorf("wrapped workspace key is too short: %d bytes", len(wrapped))
+	}
+
+	ephemeralPublic := wrapped[:encoding.X25519PublicKeySize]
+	nonce := wrapped[encoding.X25519PublicKeySize:wrappedKeyHeaderSize]
+	ciphertext := wrapped[wrappedKeyHeaderSize:]
+
+	sharedSecret, err := curve25519.X25519(encryptionPrivateKey, ephemeralPublic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute shared secret: %w", err)
+	}
+
+	encryptionKey, err := deriveWrappingKey(sharedSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	cipher, err := chacha20poly1305.New(encryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	workspaceKey, err := cipher.Open(nil, nonce, ciphertext, wrapAssociatedData(workspaceID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt workspace key: wrong device or workspace")
+	}
+
+	return workspaceKey, nil
+}
@@ -1,12 +1,18 @@
 package cmd
 
 import (
+	"bytes"
 	"crypto/ed25519"
 	"crypto/rand"
 	"encoding/json"
+	"errors"
+	"flag"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/DylanBlakemore/initflow-cli/internal/client"
@@ -186,6 +192,38 @@ func TestWorkspaceInitKeyAlreadyInitialized(t *testing.T) {
 	}
 }
 
+func TestWorkspaceInitRequiresKeyInitRole(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			t.Errorf("Expected no writes for a viewer, got %s %s", r.Method, r.URL.Path)
+		}
+		response := client.ListWorkspacesResponse{
+			Workspaces: []client.Workspace{
+				{ID: 1, Name: "My Project", Slug: "my-project", Role: "viewer"},
+			},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	setupTestEnvironment(t, server.URL)
+
+	var err error
+	out := captureStdout(t, func() {
+		err = runWorkspaceInit(workspaceInitCmd, []string{"my-project"})
+	})
+	if err == nil || !strings.Contains(err.Error(), "requires the owner or admin role (you are viewer)") {
+		t.Fatalf("Expected a role error, got %v", err)
+	}
+	if strings.Contains(out, "Generating") {
+		t.Errorf("Expected no key to be generated, got %q", out)
+	}
+	if storage.New().HasWorkspaceKey("my-project") {
+		t.Error("Expected no workspace key to be stored")
+	}
+}
+
 func TestWorkspaceInitKeyNotFound(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		response := client.ListWorkspacesResponse{
@@ -218,7 +256,7 @@ func TestWrapWorkspaceKey(t *testing.T) {
 	workspaceKey := make([]byte, 32)
 	rand.Read(workspaceKey)
 
-	wrappedKey, err := wrapWorkspaceKey(workspaceKey, store)
+	wrappedKey, err := wrapWorkspaceKey(workspaceKey, 1, store)
 	if err != nil {
 		t.Fatalf("wrapWorkspaceKey failed: %v", err)
 	}
@@ -259,6 +297,11 @@ func setupTestEnvironment(t *testing.T, serverURL string) {
 		t.Fatalf("Failed to set service name: %v", err)
 	}
 
+	// Command tests count requests; retries are covered by the client tests
+	if err := config.Set("retries", 0); err != nil {
+		t.Fatalf("Failed to disable retries: %v", err)
+	}
+
 	store := storage.New()
 
 	signingPublic, signingPrivate, err := ed25519.GenerateKey(rand.Reader)
@@ -299,3 +342,607 @@ func setupTestEnvironment(t *testing.T, serverURL string) {
 
 	_ = signingPublic
 }
+
+func TestWorkspaceInitAllContinuesPastFailures(t *testing.T) {
+	var initRequests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/workspaces":
+			response := client.ListWorkspacesResponse{
+				Workspaces: []client.Workspace{
+					{ID: 1, Name: "My Project", Slug: "my-project", Role: "Owner"},
+					{ID: 2, Name: "Team Secrets", Slug: "team-secrets", Role: "Owner"},
+					{ID: 3, Name: "Personal Vault", Slug: "personal-vault", Role: "Owner", KeyInitialized: true},
+					{ID: 4, Name: "Shared", Slug: "non-existent", Role: "Member"},
+				},
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(response)
+
+		case "/api/v1/workspaces/1/initialize":
+			initRequests = append(initRequests, r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(client.ErrorResponse{Error: "internal", Message: "boom"})
+
+		case "/api/v1/workspaces/2/initialize":
+			initRequests = append(initRequests, r.URL.Path)
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(client.InitializeWorkspaceKeyResponse{Success: true})
+
+		default:
+			t.Errorf("Unexpected request path: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	setupTestEnvironment(t, server.URL)
+
+	initAllDryRun = false
+	err := runWorkspaceInitAll(workspaceInitAllCmd, []string{})
+	if err == nil {
+		t.Fatal("Expected error summarizing the failed workspace")
+	}
+	if err.Error() != "❌ Failed to initialize 1 workspace(s)" {
+		t.Errorf("Expected summary error, got: %v", err)
+	}
+
+	if len(initRequests) != 2 {
+		t.Errorf("Expected 2 initialize requests, got %d: %v", len(initRequests), initRequests)
+	}
+
+	store := storage.New()
+	if store.HasWorkspaceKey("my-project") {
+		t.Error("Expected no local key for the workspace that failed")
+	}
+	if !store.HasWorkspaceKey("team-secrets") {
+		t.Error("Expected workspace key to be stored for the workspace initialized after the failure")
+	}
+	if store.HasWorkspaceKey("non-existent") {
+		t.Error("Expected workspace with insufficient role to be skipped")
+	}
+}
+
+func TestWorkspaceInitAllDryRun(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/workspaces" {
+			t.Errorf("Dry run should not call %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		response := client.ListWorkspacesResponse{
+			Workspaces: []client.Workspace{
+				{ID: 1, Name: "My Project", Slug: "my-project", Role: "Owner"},
+			},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	setupTestEnvironment(t, server.URL)
+
+	initAllDryRun = true
+	defer func() { initAllDryRun = false }()
+
+	if err := runWorkspaceInitAll(workspaceInitAllCmd, []string{}); err != nil {
+		t.Fatalf("runWorkspaceInitAll dry run failed: %v", err)
+	}
+
+	if storage.New().HasWorkspaceKey("my-project") {
+		t.Error("Expected dry run not to store a workspace key")
+	}
+}
+
+func TestCanInitializeKey(t *testing.T) {
+	cases := map[string]bool{
+		"Owner":  true,
+		"admin":  true,
+		"Member": false,
+		"viewer": false,
+		"":       false,
+	}
+	for role, expected := range cases {
+		if got := canInitializeKey(role); got != expected {
+			t.Errorf("canInitializeKey(%q) = %v, expected %v", role, got, expected)
+		}
+	}
+}
+
+func TestWrapWorkspaceKeyForRejectsInvalidRecipient(t *testing.T) {
+	workspaceKey := make([]byte, 32)
+	rand.Read(workspaceKey)
+
+	tests := []struct {
+		name      string
+		publicKey []byte
+		expected  string
+	}{
+		{"all zero", make([]byte, 32), "invalid device public key: key is a low-order point"},
+		{"too short", make([]byte, 31), "invalid device public key: expected 32 bytes, got 31"},
+		{"too long", make([]byte, 33), "invalid device public key: expected 32 bytes, got 33"},
+		{"empty", nil, "invalid device public key: expected 32 bytes, got 0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := wrapWorkspaceKeyFor(workspaceKey, 1, tt.publicKey)
+			if err == nil {
+				t.Fatal("Expected error for invalid recipient public key")
+			}
+			if err.Error() != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, err.Error())
+			}
+		})
+	}
+}
+
+func TestWrapWorkspaceKeyForValidRecipient(t *testing.T) {
+	recipientPublic, _, err := generateX25519Keypair()
+	if err != nil {
+		t.Fatalf("Failed to generate recipient keypair: %v", err)
+	}
+
+	workspaceKey := make([]byte, 32)
+	rand.Read(workspaceKey)
+
+	wrappedKey, err := wrapWorkspaceKeyFor(workspaceKey, 1, recipientPublic)
+	if err != nil {
+		t.Fatalf("wrapWorkspaceKeyFor failed: %v", err)
+	}
+	if len(wrappedKey) != 32+12+32+16 {
+		t.Errorf("Unexpected wrapped key length: %d", len(wrappedKey))
+	}
+}
+
+func TestUnwrapWorkspaceKeyIsBoundToWorkspace(t *testing.T) {
+	setupTestEnvironment(t, "http://localhost")
+	store := storage.New()
+
+	workspaceKey := make([]byte, 32)
+	rand.Read(workspaceKey)
+
+	const workspaceA, workspaceB = 1, 2
+	wrappedKey, err := wrapWorkspaceKey(workspaceKey, workspaceA, store)
+	if err != nil {
+		t.Fatalf("wrapWorkspaceKey failed: %v", err)
+	}
+
+	unwrapped, err := unwrapWorkspaceKey(wrappedKey, workspaceA, store)
+	if err != nil {
+		t.Fatalf("unwrapWorkspaceKey failed: %v", err)
+	}
+	if !bytes.Equal(unwrapped, workspaceKey) {
+		t.Error("Unwrapped key does not match the original")
+	}
+
+	if _, err := unwrapWorkspaceKey(wrappedKey, workspaceB, store); err == nil {
+		t.Error("Expected a key wrapped for workspace A to fail to unwrap as workspace B")
+	}
+
+	tampered := append([]byte(nil), wrappedKey...)
+	tampered[len(tampered)-1] ^= 0x01
+	if _, err := unwrapWorkspaceKey(tampered, workspaceA, store); err == nil {
+		t.Error("Expected a tampered key to fail to unwrap")
+	}
+
+	if _, err := unwrapWorkspaceKey(wrappedKey[:40], workspaceA, store); err == nil {
+		t.Error("Expected a truncated key to fail to unwrap")
+	}
+}
+
+func workspaceFixtures() []client.Workspace {
+	return []client.Workspace{
+		{Name: "Zeta", Slug: "zeta", Role: "Member", KeyInitialized: true},
+		{Name: "alpha", Slug: "alpha", Role: "Owner"},
+		{Name: "Gamma", Slug: "gamma", Role: "Admin", KeyInitialized: true},
+		{Name: "Beta", Slug: "beta", Role: "owner", KeyInitialized: true},
+		{Name: "Delta", Slug: "delta", Role: "Member"},
+	}
+}
+
+func workspaceSlugs(workspaces []client.Workspace) []string {
+	slugs := make([]string, 0, len(workspaces))
+	for _, w := range workspaces {
+		slugs = append(slugs, w.Slug)
+	}
+	return slugs
+}
+
+func TestFilterAndSortWorkspaces(t *testing.T) {
+	tests := []struct {
+		name          string
+		roles         []string
+		uninitialized bool
+		sortBy        string
+		expected      []string
+	}{
+		{"no filters keeps server order", nil, false, "", []string{"zeta", "alpha", "gamma", "beta", "delta"}},
+		{"sort by name", nil, false, "name", []string{"alpha", "beta", "delta", "gamma", "zeta"}},
+		{"sort by slug", nil, false, "slug", []string{"alpha", "beta", "delta", "gamma", "zeta"}},
+		{"sort by role breaks ties by slug", nil, false, "role", []string{"gamma", "delta", "zeta", "alpha", "beta"}},
+		{"single role is case-insensitive", []string{"OWNER"}, false, "", []string{"alpha", "beta"}},
+		{"repeated roles", []string{"owner", "admin"}, false, "slug", []string{"alpha", "beta", "gamma"}},
+		{"uninitialized only", nil, true, "name", []string{"alpha", "delta"}},
+		{"role and uninitialized", []string{"member"}, true, "", []string{"delta"}},
+		{"no matches", []string{"viewer"}, false, "name", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workspaces := filterWorkspaces(workspaceFixtures(), tt.roles, tt.uninitialized)
+			if err := sortWorkspaces(workspaces, tt.sortBy); err != nil {
+				t.Fatalf("sortWorkspaces failed: %v", err)
+			}
+			if got := workspaceSlugs(workspaces); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestSortWorkspacesRejectsUnknownKey(t *testing.T) {
+	if err := sortWorkspaces(workspaceFixtures(), "created"); err == nil {
+		t.Error("Expected error for unknown sort key")
+	}
+}
+
+func TestWorkspaceListFiltersJSONOutput(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(client.ListWorkspacesResponse{Workspaces: workspaceFixtures()})
+	}))
+	defer server.Close()
+
+	setupTestEnvironment(t, server.URL)
+
+	outputFormat, listRoles, listSort, listUninitialized = outputJSON, []string{"owner", "member"}, "name", true
+	t.Cleanup(func() {
+		outputFormat, listRoles, listSort, listUninitialized = outputTable, nil, "", false
+	})
+
+	var err error
+	out := captureStdout(t, func() {
+		err = runWorkspaceList(workspaceListCmd, []string{})
+	})
+	if err != nil {
+		t.Fatalf("runWorkspaceList failed: %v", err)
+	}
+
+	var workspaces []client.Workspace
+	if err := json.Unmarshal([]byte(out), &workspaces); err != nil {
+		t.Fatalf("Expected JSON output, got %q: %v", out, err)
+	}
+	if got := workspaceSlugs(workspaces); !reflect.DeepEqual(got, []string{"alpha", "delta"}) {
+		t.Errorf("Expected [alpha delta], got %v", got)
+	}
+}
+
+// renameServer serves a single owned workspace and answers PATCH with updateStatus
+// and updateBody, recording the decoded update request.
+func renameServer(t *testing.T, updateStatus int, updateBody any, got *client.UpdateWorkspaceRequest) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/api/v1/workspaces":
+			json.NewEncoder(w).Encode(client.ListWorkspacesResponse{
+				Workspaces: []client.Workspace{{ID: 1, Name: "My Project", Slug: "my-project", Role: "Admin"}},
+			})
+		case r.Method == "PATCH" && r.URL.Path == "/api/v1/workspaces/1":
+			if err := json.NewDecoder(r.Body).Decode(got); err != nil {
+				t.Errorf("Failed to decode request: %v", err)
+			}
+			w.WriteHeader(updateStatus)
+			json.NewEncoder(w).Encode(updateBody)
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+}
+
+func TestWorkspaceRenameMovesCachedKey(t *testing.T) {
+	var got client.UpdateWorkspaceRequest
+	server := renameServer(t, http.StatusOK, client.UpdateWorkspaceResponse{
+		Workspace: client.Workspace{ID: 1, Name: "Renamed", Slug: "renamed-project", Role: "Admin"},
+	}, &got)
+	defer server.Close()
+
+	setupTestEnvironment(t, server.URL)
+	renameNewSlug, renameYes = "renamed-project", true
+	t.Cleanup(func() { renameNewSlug, renameYes = "", false })
+
+	store := storage.New()
+	workspaceKey := bytes.Repeat([]byte{7}, encoding.WorkspaceKeySize)
+	if err := store.StoreWorkspaceKey("my-project", workspaceKey); err != nil {
+		t.Fatalf("Failed to store workspace key: %v", err)
+	}
+	t.Cleanup(func() { store.DeleteWorkspaceKey("renamed-project") })
+
+	if err := runWorkspaceRename(workspaceRenameCmd, []string{"my-project", "Renamed"}); err != nil {
+		t.Fatalf("runWorkspaceRename failed: %v", err)
+	}
+
+	if got.Name != "Renamed" || got.Slug != "renamed-project" {
+		t.Errorf("Unexpected update request: %+v", got)
+	}
+	if store.HasWorkspaceKey("my-project") {
+		t.Error("Expected key under the old slug to be removed")
+	}
+	moved, err := store.GetWorkspaceKey("renamed-project")
+	if err != nil {
+		t.Fatalf("Expected key under the new slug: %v", err)
+	}
+	if !bytes.Equal(moved, workspaceKey) {
+		t.Error("Moved workspace key does not match")
+	}
+}
+
+func TestWorkspaceRenameSlugTaken(t *testing.T) {
+	var got client.UpdateWorkspaceRequest
+	server := renameServer(t, http.StatusConflict,
+		client.ErrorResponse{Error: "slug_taken", Message: "Slug has already been taken"}, &got)
+	defer server.Close()
+
+	setupTestEnvironment(t, server.URL)
+	renameNewSlug, renameYes = "team-secrets", true
+	t.Cleanup(func() { renameNewSlug, renameYes = "", false })
+
+	store := storage.New()
+	if err := store.StoreWorkspaceKey("my-project", bytes.Repeat([]byte{7}, encoding.WorkspaceKeySize)); err != nil {
+		t.Fatalf("Failed to store workspace key: %v", err)
+	}
+
+	err := runWorkspaceRename(workspaceRenameCmd, []string{"my-project", "Renamed"})
+	if !errors.Is(err, client.ErrSlugTaken) {
+		t.Fatalf("Expected ErrSlugTaken, got %v", err)
+	}
+	if exitCodeFor(err) != exitConflict {
+		t.Errorf("Expected conflict exit code, got %d", exitCodeFor(err))
+	}
+	if !store.HasWorkspaceKey("my-project") {
+		t.Error("Expected key to stay under the old slug")
+	}
+}
+
+func TestWorkspaceListFailOnUninitialized(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(client.ListWorkspacesResponse{Workspaces: workspaceFixtures()})
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		roles    []string
+		slugs    []string
+		wantFail bool
+	}{
+		{"any listed workspace uninitialized", nil, nil, true},
+		{"filtered to initialized workspaces", []string{"admin"}, nil, false},
+		{"named workspaces initialized", nil, []string{"zeta", "beta"}, false},
+		{"named workspace uninitialized", nil, []string{"zeta", "delta"}, true},
+		{"named workspace missing", nil, []string{"zeta", "nope"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestEnvironment(t, server.URL)
+			outputFormat, listRoles, listRequiredSlugs, listFailOnUninitialized = outputJSON, tt.roles, tt.slugs, true
+			t.Cleanup(func() {
+				outputFormat, listRoles, listRequiredSlugs, listFailOnUninitialized = outputTable, nil, nil, false
+			})
+
+			var err error
+			out := captureStdout(t, func() {
+				err = runWorkspaceList(workspaceListCmd, []string{})
+			})
+
+			var workspaces []client.Workspace
+			if jsonErr := json.Unmarshal([]byte(out), &workspaces); jsonErr != nil {
+				t.Fatalf("Expected the listing to render, got %q: %v", out, jsonErr)
+			}
+
+			if tt.wantFail && (err == nil || exitCodeFor(err) == 0) {
+				t.Errorf("Expected a non-zero exit code, got %v", err)
+			}
+			if !tt.wantFail && err != nil {
+				t.Errorf("Expected success, got %v", err)
+			}
+		})
+	}
+}
+
+func TestWorkspaceInfoReportsLocalKeyCache(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v1/workspaces":
+			json.NewEncoder(w).Encode(client.ListWorkspacesResponse{Workspaces: []client.Workspace{{
+				ID: 1, Name: "My Project", Slug: "my-project", Role: "Owner", KeyInitialized: true,
+				KeyVersion: 2, MemberCount: 4, CreatedAt: "2025-01-02T03:04:05Z",
+			}}})
+		case "/api/v1/workspaces/1/devices":
+			json.NewEncoder(w).Encode(client.ListDevicesResponse{Devices: []client.Device{{DeviceID: "a"}, {DeviceID: "b"}}})
+		default:
+			t.Errorf("Unexpected request path: %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	setupTestEnvironment(t, server.URL)
+	outputFormat = outputJSON
+	t.Cleanup(func() { outputFormat = outputTable })
+
+	info := func() workspaceInfo {
+		var err error
+		out := captureStdout(t, func() {
+			err = runWorkspaceInfo(workspaceInfoCmd, []string{"my-project"})
+		})
+		if err != nil {
+			t.Fatalf("runWorkspaceInfo failed: %v", err)
+		}
+		var info workspaceInfo
+		if err := json.Unmarshal([]byte(out), &info); err != nil {
+			t.Fatalf("Expected JSON output, got %q: %v", out, err)
+		}
+		return info
+	}
+
+	got := info()
+	if got.KeyCachedLocally {
+		t.Error("Expected key not to be cached before it is stored")
+	}
+	if got.MemberCount != 4 || got.DeviceCount == nil || *got.DeviceCount != 2 || got.KeyVersion != 2 {
+		t.Errorf("Unexpected workspace details: %+v", got)
+	}
+
+	if err := storage.New().StoreWorkspaceKey("my-project", bytes.Repeat([]byte{1}, encoding.WorkspaceKeySize)); err != nil {
+		t.Fatalf("Failed to store workspace key: %v", err)
+	}
+	if !info().KeyCachedLocally {
+		t.Error("Expected key to be reported as cached once stored")
+	}
+}
+
+func TestWorkspaceListColumns(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(client.ListWorkspacesResponse{Workspaces: workspaceFixtures()[:2]})
+	}))
+	defer server.Close()
+
+	setupTestEnvironment(t, server.URL)
+
+	listTable, quiet = tableOptions{columns: []string{"role", "Slug"}, noHeader: true}, true
+	t.Cleanup(func() { listTable, quiet = tableOptions{}, false })
+
+	var err error
+	out := captureStdout(t, func() {
+		err = runWorkspaceList(workspaceListCmd, []string{})
+	})
+	if err != nil {
+		t.Fatalf("runWorkspaceList failed: %v", err)
+	}
+
+	expected := "Member |zeta\nOwner  |alpha\n"
+	if out != expected {
+		t.Errorf("Expected %q, got %q", expected, out)
+	}
+}
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// assertGolden compares got with testdata/<name>, rewriting it with -update
+func assertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name)
+	if *updateGolden {
+		if err := os.WriteFile(path, got, 0600); err != nil {
+			t.Fatalf("Failed to update %s: %v", path, err)
+		}
+	}
+
+	expected, err := os.ReadFile(path) // #nosec G304 - golden file path is controlled
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", path, err)
+	}
+	if !bytes.Equal(got, expected) {
+		t.Errorf("Output differs from %s (rerun with -update to accept):\n--- got ---\n%s\n--- expected ---\n%s",
+			path, got, expected)
+	}
+}
+
+func TestWorkspaceListGolden(t *testing.T) {
+	workspaces := workspaceFixtures()
+	for i := range workspaces {
+		workspaces[i].ID = i + 1
+		workspaces[i].Organization.Name = "Acme"
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(client.ListWorkspacesResponse{Workspaces: workspaces})
+	}))
+	defer server.Close()
+
+	setupTestEnvironment(t, server.URL)
+
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	t.Cleanup(func() { rootCmd.SetOut(nil) })
+
+	if err := runWorkspaceList(workspaceListCmd, []string{}); err != nil {
+		t.Fatalf("runWorkspaceList failed: %v", err)
+	}
+
+	assertGolden(t, "workspace_list.golden", out.Bytes())
+}
+
+func TestWorkspaceListUnknownColumn(t *testing.T) {
+	listTable = tableOptions{columns: []string{"name", "colour"}}
+	t.Cleanup(func() { listTable = tableOptions{} })
+
+	err := runWorkspaceList(workspaceListCmd, []string{})
+	if err == nil || !strings.Contains(err.Error(), `unknown column "colour"`) {
+		t.Fatalf("Expected an unknown column error, got %v", err)
+	}
+}
+
+func TestWorkspaceInitWaitsForFailedOperation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/api/v1/workspaces":
+			json.NewEncoder(w).Encode(client.ListWorkspacesResponse{
+				Workspaces: []client.Workspace{{ID: 1, Name: "My Project", Slug: "my-project", Role: "Owner"}},
+			})
+		case r.URL.Path == "/api/v1/workspaces/1/initialize":
+			w.WriteHeader(http.StatusAccepted)
+			json.NewEncoder(w).Encode(client.OperationResponse{
+				Operation: client.Operation{ID: "op-1", Status: client.OperationPending},
+			})
+		case r.URL.Path == "/api/v1/operations/op-1":
+			json.NewEncoder(w).Encode(client.OperationResponse{
+				Operation: client.Operation{ID: "op-1", Status: client.OperationFailed, Error: "quota exceeded"},
+			})
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	setupTestEnvironment(t, server.URL)
+	waitForOperations = true
+	t.Cleanup(func() { waitForOperations = false })
+
+	err := runWorkspaceInit(workspaceInitCmd, []string{"my-project"})
+	if err == nil || !strings.Contains(err.Error(), "quota exceeded") {
+		t.Fatalf("Expected the operation failure to be reported, got %v", err)
+	}
+	if storage.New().HasWorkspaceKey("my-project") {
+		t.Error("Expected no workspace key to be cached after the operation failed")
+	}
+}
+
+func TestWorkspaceInitRequiresEncryptionKey(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	setupTestEnvironment(t, server.URL)
+	if err := storage.New().DeleteEncryptionPrivateKey(); err != nil {
+		t.Fatalf("Failed to delete encryption key: %v", err)
+	}
+
+	err := runWorkspaceInit(workspaceInitCmd, []string{"my-project"})
+	if !errors.Is(err, storage.ErrDeviceNotReady) {
+		t.Fatalf("Expected ErrDeviceNotReady, got %v", err)
+	}
+	if requests != 0 {
+		t.Errorf("Expected no requests before the precondition check, got %d", requests)
+	}
+}
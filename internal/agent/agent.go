@@ -0,0 +1,176 @@
+// Package agent is the protocol between the background agent, which keeps
+// workspace keys in memory, and the commands that ask it for secrets. Each
+// connection to the agent's Unix socket carries one JSON request and one
+// JSON response.
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/client"
+	"github.com/DylanBlakemore/initflow-cli/internal/config"
+)
+
+// SocketEnvVar overrides the socket the agent listens on and commands connect to
+const SocketEnvVar = "INITFLOW_AGENT_SOCK"
+
+const (
+	socketDirPermissions = 0700
+	socketPermissions    = 0600
+	callTimeout          = 2 * time.Minute
+)
+
+// Operations a Request can ask for
+const (
+	OpSecrets = "secrets"
+	OpStatus  = "status"
+	OpStop    = "stop"
+)
+
+// CodeWrongIdentity marks a request from a command using another API or
+// credential store than the agent
+const CodeWrongIdentity = "wrong_identity"
+
+var (
+	// ErrNotRunning is returned by Call when nothing listens on the socket
+	ErrNotRunning = errors.New("the agent is not running")
+	// ErrWrongIdentity is returned by Call when the agent serves another
+	// API or credential store than the caller's
+	ErrWrongIdentity = errors.New("the agent serves another API or profile")
+)
+
+// Request asks the agent for the secrets of a workspace environment under
+// Prefix, or only Key when it is set. Identity names the caller's API and
+// credential store; the agent only answers callers with its own.
+type Request struct {
+	Op        string `json:"op"`
+	Identity  string `json:"identity,omitempty"`
+	Workspace string `json:"workspace,omitempty"`
+	Env       string `json:"env,omitempty"`
+	Prefix    string `json:"prefix,omitempty"`
+	Key       string `json:"key,omitempty"`
+}
+
+type Response struct {
+	Error     string            `json:"error,omitempty"`
+	Code      string            `json:"code,omitempty"`
+	Workspace *client.Workspace `json:"workspace,omitempty"`
+	Secrets   map[string]string `json:"secrets,omitempty"`
+	Status    *Status           `json:"status,omitempty"`
+}
+
+// Status describes a running agent
+type Status struct {
+	PID        int       `json:"pid"`
+	Socket     string    `json:"socket"`
+	StartedAt  time.Time `json:"started_at"`
+	Workspaces []string  `json:"workspaces"`
+}
+
+// SocketPath returns the socket of a profile's agent: $INITFLOW_AGENT_SOCK,
+// else agent.sock in the config directory, or agent-<profile>.sock for
+// profiles other than the default one
+func SocketPath(profile string) (string, error) {
+	if path := os.Getenv(SocketEnvVar); path != "" {
+		return path, nil
+	}
+
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	if profile == "" || profile == config.DefaultProfile {
+		return filepath.Join(dir, "agent.sock"), nil
+	}
+	return filepath.Join(dir, "agent-"+profile+".sock"), nil
+}
+
+// Call sends req to the agent at path and returns its response. Errors the
+// agent reports are returned as errors.
+func Call(path string, req Request) (*Response, error) {
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err != nil {
+		return nil, ErrNotRunning
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(callTimeout))
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, fmt.Errorf("failed to send request to the agent: %w", err)
+	}
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("failed to read the agent's response: %w", err)
+	}
+
+	switch {
+	case resp.Code == CodeWrongIdentity:
+		return nil, ErrWrongIdentity
+	case resp.Error != "":
+		return nil, errors.New(resp.Error)
+	}
+	return &resp, nil
+}
+
+// Listen creates the socket at path, readable only by the current user. A
+// socket left behind by an agent that died is replaced; one a running agent
+// answers on is an error.
+func Listen(path string) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(path), socketDirPermissions); err != nil {
+		return nil, fmt.Errorf("failed to create the socket directory: %w", err)
+	}
+	if resp, err := Call(path, Request{Op: OpStatus}); err == nil && resp.Status != nil {
+		return nil, fmt.Errorf("an agent is already running (pid %d)", resp.Status.PID)
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to remove the stale socket: %w", err)
+	}
+
+	listener, err := listenUnix(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	if err := os.Chmod(path, socketPermissions); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to restrict the socket: %w", err)
+	}
+	return listener, nil
+}
+
+// Serve answers requests on listener with handle until the listener is
+// closed, then waits for the requests in flight. Connections are served
+// concurrently.
+func Serve(listener net.Listener, handle func(Request) Response) error {
+	var inFlight sync.WaitGroup
+	defer inFlight.Wait()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		inFlight.Go(func() { serveConn(conn, handle) })
+	}
+}
+
+func serveConn(conn net.Conn, handle func(Request) Response) {
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(callTimeout))
+
+	var req Request
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		_ = json.NewEncoder(conn).Encode(Response{Error: fmt.Sprintf("invalid request: %v", err)})
+		return
+	}
+	_ = json.NewEncoder(conn).Encode(handle(req))
+}
@@ -0,0 +1,69 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListenAndCall(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+
+	_, err := Call(socket, Request{Op: OpStatus})
+	assert.ErrorIs(t, err, ErrNotRunning)
+
+	// A socket file nobody listens on is left over from an agent that died
+	require.NoError(t, os.WriteFile(socket, nil, 0600))
+	listener, err := Listen(socket)
+	require.NoError(t, err)
+	info, err := os.Stat(socket)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	served := make(chan error, 1)
+	go func() {
+		served <- Serve(listener, func(req Request) Response {
+			switch req.Op {
+			case OpStatus:
+				return Response{Status: &Status{PID: 42}}
+			case OpSecrets:
+				if req.Identity != "me" {
+					return Response{Code: CodeWrongIdentity}
+				}
+				return Response{Secrets: map[string]string{req.Key: "value"}}
+			}
+			return Response{Error: "unknown operation"}
+		})
+	}()
+
+	resp, err := Call(socket, Request{Op: OpSecrets, Identity: "me", Key: "API_KEY"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"API_KEY": "value"}, resp.Secrets)
+
+	_, err = Call(socket, Request{Op: OpSecrets, Identity: "someone else"})
+	assert.ErrorIs(t, err, ErrWrongIdentity)
+	_, err = Call(socket, Request{Op: "bogus"})
+	assert.EqualError(t, err, "unknown operation")
+
+	_, err = Listen(socket)
+	assert.EqualError(t, err, "an agent is already running (pid 42)")
+
+	require.NoError(t, listener.Close())
+	assert.NoError(t, <-served)
+}
+
+func TestLockedKey(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	locked, err := NewLockedKey(key)
+	require.NoError(t, err)
+
+	assert.Equal(t, "0123456789abcdef0123456789abcdef", string(locked.Bytes()))
+	assert.Equal(t, make([]byte, len(key)), key, "the original should be zeroed")
+
+	locked.Destroy()
+	assert.Nil(t, locked.Bytes())
+	locked.Destroy()
+}
@@ -0,0 +1,11 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package agent
+
+import "net"
+
+// listenUnix creates the socket; there is no umask to tighten here, so Listen
+// restricts it afterwards
+func listenUnix(path string) (net.Listener, error) {
+	return net.Listen("unix", path)
+}
@@ -0,0 +1,16 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package agent
+
+import (
+	"net"
+	"syscall"
+)
+
+// listenUnix creates the socket under a 0077 umask, so it is never open to
+// other users, not even between its creation and the Chmod in Listen
+func listenUnix(path string) (net.Listener, error) {
+	umask := syscall.Umask(0077)
+	defer syscall.Umask(umask)
+	return net.Listen("unix", path)
+}
@@ -0,0 +1,28 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListenUnixCreatesPrivateSocket(t *testing.T) {
+	umask := syscall.Umask(0022)
+	defer syscall.Umask(umask)
+
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	listener, err := listenUnix(socket)
+	require.NoError(t, err)
+	defer listener.Close()
+
+	info, err := os.Stat(socket)
+	require.NoError(t, err)
+	assert.Zero(t, info.Mode().Perm()&0077, "socket is open to other users: %v", info.Mode())
+	assert.Equal(t, 0022, syscall.Umask(0022), "expected the umask to be restored")
+}
@@ -0,0 +1,33 @@
+package agent
+
+// LockedKey holds a key in memory of its own, outside the Go heap and locked
+// so it is never swapped to disk where the platform allows it
+type LockedKey struct {
+	mem []byte
+}
+
+// NewLockedKey copies key into locked memory and zeroes key
+func NewLockedKey(key []byte) (*LockedKey, error) {
+	mem, err := lockedAlloc(len(key))
+	if err != nil {
+		return nil, err
+	}
+	copy(mem, key)
+	clear(key)
+	return &LockedKey{mem: mem}, nil
+}
+
+// Bytes returns the key. It must not be used after Destroy.
+func (k *LockedKey) Bytes() []byte {
+	return k.mem
+}
+
+// Destroy zeroes the key and releases its memory
+func (k *LockedKey) Destroy() {
+	if k.mem == nil {
+		return
+	}
+	clear(k.mem)
+	lockedFree(k.mem)
+	k.mem = nil
+}
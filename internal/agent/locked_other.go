@@ -0,0 +1,11 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package agent
+
+// lockedAlloc falls back to ordinary memory where mlock isn't available; keys
+// are still zeroed on Destroy
+func lockedAlloc(n int) ([]byte, error) {
+	return make([]byte, n), nil
+}
+
+func lockedFree([]byte) {}
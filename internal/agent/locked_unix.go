@@ -0,0 +1,26 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package agent
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// lockedAlloc maps n bytes of anonymous memory and locks them in RAM
+func lockedAlloc(n int) ([]byte, error) {
+	mem, err := syscall.Mmap(-1, 0, n, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_ANON|syscall.MAP_PRIVATE)
+	if err != nil {
+		return nil, fmt.Errorf("failed to allocate key memory: %w", err)
+	}
+	if err := syscall.Mlock(mem); err != nil {
+		_ = syscall.Munmap(mem)
+		return nil, fmt.Errorf("failed to lock key memory (check ulimit -l): %w", err)
+	}
+	return mem, nil
+}
+
+func lockedFree(mem []byte) {
+	_ = syscall.Munlock(mem)
+	_ = syscall.Munmap(mem)
+}
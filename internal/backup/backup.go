@@ -0,0 +1,162 @@
+// Package backup seals device credentials into a passphrase-protected file so
+// they can be moved to another machine.
+package backup
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Version is the current backup file format version
+const Version = 1
+
+const (
+	magic      = "IFBACKUP"
+	saltSize   = 16
+	headerSize = len(magic) + 1 + 4 + 4 + 1 + saltSize + chacha20poly1305.NonceSize
+
+	// Argon2id cost for new backups
+	argonTime    = 3
+	argonMemory  = 64 * 1024 // KiB
+	argonThreads = 4
+
+	// Upper bounds accepted when opening, so a crafted file can't exhaust memory
+	maxArgonTime   = 16
+	maxArgonMemory = 1024 * 1024 // KiB
+)
+
+var (
+	// ErrInvalidFormat is returned for data that isn't an InitFlow backup
+	ErrInvalidFormat = errors.New("not an InitFlow backup file")
+	// ErrUnsupportedVersion is returned for backups written by a newer CLI
+	ErrUnsupportedVersion = errors.New("unsupported backup version")
+	// ErrDecryptionFailed is returned for a wrong passphrase or a tampered file
+	ErrDecryptionFailed = errors.New("wrong passphrase or corrupted backup")
+)
+
+// Bundle is the device identity and workspace keys carried by a backup
+type Bundle struct {
+	DeviceID             string            `json:"device_id"`
+	SigningPrivateKey    []byte            `json:"signing_private_key"`
+	EncryptionPrivateKey []byte            `json:"encryption_private_key"`
+	WorkspaceKeys        map[string][]byte `json:"workspace_keys"`
+}
+
+type params struct {
+	time    uint32
+	memory  uint32
+	threads uint8
+	salt    []byte
+	nonce   []byte
+}
+
+// Seal encrypts bundle under passphrase. The layout is
+// magic || version || argon2id time, memory, threads || salt || nonce || ciphertext,
+// with everything before the ciphertext authenticated as additional data.
+func Seal(bundle *Bundle, passphrase []byte) ([]byte, error) {
+	if len(passphrase) == 0 {
+		return nil, fmt.Errorf("passphrase cannot be empty")
+	}
+
+	plaintext, err := json.Marshal(bundle)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode backup: %w", err)
+	}
+
+	p := params{
+		time:    argonTime,
+		memory:  argonMemory,
+		threads: argonThreads,
+		salt:    make([]byte, saltSize),
+		nonce:   make([]byte, chacha20poly1305.NonceSize),
+	}
+	if _, err := rand.Read(p.salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	if _, err := rand.Read(p.nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	aead, err := chacha20poly1305.New(deriveKey(passphrase, p))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	header := encodeHeader(p)
+	return aead.Seal(header, p.nonce, plaintext, header), nil
+}
+
+// Open decrypts a backup produced by Seal
+func Open(data, passphrase []byte) (*Bundle, error) {
+	p, err := decodeHeader(data)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := chacha20poly1305.New(deriveKey(passphrase, p))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	header := data[:headerSize]
+	plaintext, err := aead.Open(nil, p.nonce, data[headerSize:], header)
+	if err != nil {
+		return nil, ErrDecryptionFailed
+	}
+
+	var bundle Bundle
+	if err := json.Unmarshal(plaintext, &bundle); err != nil {
+		return nil, fmt.Errorf("failed to decode backup: %w", err)
+	}
+
+	return &bundle, nil
+}
+
+func deriveKey(passphrase []byte, p params) []byte {
+	return argon2.IDKey(passphrase, p.salt, p.time, p.memory, p.threads, chacha20poly1305.KeySize)
+}
+
+func encodeHeader(p params) []byte {
+	header := make([]byte, 0, headerSize)
+	header = append(header, magic...)
+	header = append(header, Version)
+	header = binary.BigEndian.AppendUint32(header, p.time)
+	header = binary.BigEndian.AppendUint32(header, p.memory)
+	header = append(header, p.threads)
+	header = append(header, p.salt...)
+	return append(header, p.nonce...)
+}
+
+func decodeHeader(data []byte) (params, error) {
+	if len(data) < headerSize+chacha20poly1305.Overhead || !bytes.HasPrefix(data, []byte(magic)) {
+		return params{}, ErrInvalidFormat
+	}
+
+	rest := data[len(magic):]
+	if rest[0] != Version {
+		return params{}, fmt.Errorf("%w: %d", ErrUnsupportedVersion, rest[0])
+	}
+	rest = rest[1:]
+
+	p := params{
+		time:    binary.BigEndian.Uint32(rest[0:4]),
+		memory:  binary.BigEndian.Uint32(rest[4:8]),
+		threads: rest[8],
+	}
+	rest = rest[9:]
+	p.salt = rest[:saltSize]
+	p.nonce = rest[saltSize : saltSize+chacha20poly1305.NonceSize]
+
+	if p.time == 0 || p.time > maxArgonTime || p.memory == 0 || p.memory > maxArgonMemory || p.threads == 0 {
+		return params{}, fmt.Errorf("%w: unsupported key derivation parameters", ErrInvalidFormat)
+	}
+
+	return p, nil
+}
@@ -0,0 +1,86 @@
+package backup
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testBundle() *Bundle {
+	return &Bundle{
+		DeviceID:             "device-123",
+		SigningPrivateKey:    []byte("signing-private-key-bytes-0123456789012345678901234567890123456"),
+		EncryptionPrivateKey: []byte("encryption-private-key-bytes-012"),
+		WorkspaceKeys: map[string][]byte{
+			"my-project":   []byte("workspace-key-my-project-0123456"),
+			"team-secrets": []byte("workspace-key-team-secrets-01234"),
+		},
+	}
+}
+
+func TestSealOpenRoundTripThroughFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "backup.age")
+
+	sealed, err := Seal(testBundle(), []byte("correct horse battery staple"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, sealed, 0600))
+
+	data, err := os.ReadFile(path) // #nosec G304 - test file path is controlled
+	require.NoError(t, err)
+
+	bundle, err := Open(data, []byte("correct horse battery staple"))
+	require.NoError(t, err)
+	assert.Equal(t, testBundle(), bundle)
+
+	_, err = Open(data, []byte("wrong passphrase"))
+	assert.True(t, errors.Is(err, ErrDecryptionFailed), "got %v", err)
+}
+
+func TestOpenRejectsTamperedHeader(t *testing.T) {
+	sealed, err := Seal(testBundle(), []byte("passphrase"))
+	require.NoError(t, err)
+
+	// The salt is authenticated, so changing it must fail decryption
+	sealed[len(magic)+10] ^= 0xff
+	_, err = Open(sealed, []byte("passphrase"))
+	assert.True(t, errors.Is(err, ErrDecryptionFailed), "got %v", err)
+}
+
+func TestOpenRejectsInvalidData(t *testing.T) {
+	sealed, err := Seal(testBundle(), []byte("passphrase"))
+	require.NoError(t, err)
+
+	newerVersion := append([]byte(nil), sealed...)
+	newerVersion[len(magic)] = Version + 1
+
+	hugeMemory := append([]byte(nil), sealed...)
+	copy(hugeMemory[len(magic)+5:], []byte{0xff, 0xff, 0xff, 0xff})
+
+	tests := []struct {
+		name     string
+		data     []byte
+		expected error
+	}{
+		{"empty", nil, ErrInvalidFormat},
+		{"wrong magic", append([]byte("NOTABACK"), sealed[len(magic):]...), ErrInvalidFormat},
+		{"truncated", sealed[:headerSize], ErrInvalidFormat},
+		{"newer version", newerVersion, ErrUnsupportedVersion},
+		{"excessive memory cost", hugeMemory, ErrInvalidFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Open(tt.data, []byte("passphrase"))
+			assert.True(t, errors.Is(err, tt.expected), "got %v", err)
+		})
+	}
+}
+
+func TestSealRejectsEmptyPassphrase(t *testing.T) {
+	_, err := Seal(testBundle(), nil)
+	assert.Error(t, err)
+}
@@ -3,62 +3,494 @@ package client
 import (
 	"bytes"
 	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/DylanBlakemore/initflow-cli/internal/config"
 	"github.com/DylanBlakemore/initflow-cli/internal/encoding"
+	"github.com/DylanBlakemore/initflow-cli/internal/logging"
 	"github.com/DylanBlakemore/initflow-cli/internal/routes"
 	"github.com/DylanBlakemore/initflow-cli/internal/storage"
 )
 
 const (
-	defaultTimeoutSeconds = 30
-	debugPreviewLength    = 20 // Length of key preview for debug output
+	debugPreviewLength = 20 // Length of key preview for debug output
+	initialBackoff     = 500 * time.Millisecond
+	maxBackoff         = 5 * time.Second
+	// maxRateLimitWait caps how long a client waits out a 429 before giving up
+	maxRateLimitWait = 30 * time.Second
 )
 
+// sleep waits between retries; tests replace it to avoid real delays
+var sleep = time.Sleep
+
 type Client struct {
 	baseURL    string
 	httpClient *http.Client
+	cache      *workspaceCache
+
+	retries   int
+	retryable func(resp *http.Response, err error) bool
+	backoff   func(attempt int) time.Duration
+	now       func() time.Time
+	sleep     func(time.Duration)
+
+	waitOnRateLimit bool
+	pollInterval    time.Duration
+	scopedToken     string
+	environment     string
+
+	refreshToken string
+	onRefresh    func(*LoginResponse) error
+
+	// replayTolerance adds a nonce to request signatures, with the clock skew
+	// the server should accept, when sign_requests is on
+	replayTolerance time.Duration
+
+	clockMu     sync.Mutex
+	clockOffset time.Duration
+	clockKnown  bool
 }
 
-func New() *Client {
-	cfg := config.Get()
+// Option configures a Client
+type Option func(*Client)
 
-	return &Client{
-		baseURL: cfg.APIBaseURL,
-		httpClient: &http.Client{
-			Timeout: defaultTimeoutSeconds * time.Second,
-		},
+// WithCache memoizes workspace lookups for the lifetime of the client, so a
+// command that resolves the same workspace several times fetches it once.
+// Workspace mutations made through the client invalidate the cache.
+func WithCache() Option {
+	return func(c *Client) {
+		c.cache = &workspaceCache{}
+	}
+}
+
+// WithRateLimitWait waits out 429 responses, honouring Retry-After, within the
+// retry budget instead of failing straight away. Meant for interactive use.
+func WithRateLimitWait() Option {
+	return func(c *Client) {
+		c.waitOnRateLimit = true
+	}
+}
+
+// WithPollInterval sets how often PollOperation checks on an operation
+func WithPollInterval(interval time.Duration) Option {
+	return func(c *Client) {
+		c.pollInterval = interval
+	}
+}
+
+// WithScopedToken authenticates requests with a workspace-scoped access token
+// instead of this device's signature.
+func WithScopedToken(token string) Option {
+	return func(c *Client) {
+		c.scopedToken = token
+	}
+}
+
+// WithTokenRefresh lets the client renew an expired registration token with
+// refreshToken when the server answers 401, and retry once. onRefresh is given
+// the new tokens so they can be stored.
+func WithTokenRefresh(refreshToken string, onRefresh func(*LoginResponse) error) Option {
+	return func(c *Client) {
+		c.refreshToken = refreshToken
+		c.onRefresh = onRefresh
+	}
+}
+
+// WithEnvironment scopes secret requests to a named environment of the
+// workspace, such as staging or prod. Without it the server uses the
+// workspace's default environment.
+func WithEnvironment(environment string) Option {
+	return func(c *Client) {
+		c.environment = environment
+	}
+}
+
+// RetryPolicy decides which failed requests are retried and how often
+type RetryPolicy struct {
+	// MaxRetries is how many times an idempotent request is retried
+	MaxRetries int
+	// Retryable reports whether an attempt that ended with resp or err should
+	// be retried. Nil retries network errors and 5xx responses.
+	Retryable func(resp *http.Response, err error) bool
+}
+
+// Options tunes how a Client retries and keeps time. Zero fields keep the
+// defaults: retries from the config, exponential backoff and the real clock.
+type Options struct {
+	RetryPolicy *RetryPolicy
+	// Backoff returns the delay before the given retry (1-based)
+	Backoff func(attempt int) time.Duration
+	// Now and Sleep are the clock used to time retries and Retry-After waits
+	Now   func() time.Time
+	Sleep func(time.Duration)
+}
+
+// WithOptions applies opts on top of the client's defaults
+func WithOptions(opts Options) Option {
+	return func(c *Client) {
+		if opts.RetryPolicy != nil {
+			c.retries = opts.RetryPolicy.MaxRetries
+			if opts.RetryPolicy.Retryable != nil {
+				c.retryable = opts.RetryPolicy.Retryable
+			}
+		}
+		if opts.Backoff != nil {
+			c.backoff = opts.Backoff
+		}
+		if opts.Now != nil {
+			c.now = opts.Now
+		}
+		if opts.Sleep != nil {
+			c.sleep = opts.Sleep
+		}
 	}
 }
 
-func NewWithBaseURL(baseURL string) *Client {
+// newClient returns a client with the default retry behaviour
+func newClient(baseURL string, timeout time.Duration, retries int) *Client {
 	return &Client{
 		baseURL: baseURL,
 		httpClient: &http.Client{
-			Timeout: defaultTimeoutSeconds * time.Second,
+			Timeout: timeout,
 		},
+		retries:   retries,
+		retryable: defaultRetryable,
+		backoff:   backoff,
+		now:       time.Now,
+		// Look sleep up on each call so tests can swap it after the client is built
+		sleep: func(d time.Duration) { sleep(d) },
+	}
+}
+
+func New(opts ...Option) *Client {
+	cfg := config.Get()
+
+	c := newClient(cfg.APIBaseURL, cfg.Timeout, cfg.Retries)
+	var transport http.RoundTripper = http.DefaultTransport
+	if len(cfg.PinnedCertSHA256) > 0 {
+		transport = newPinnedTransport(http.DefaultTransport.(*http.Transport), cfg.PinnedCertSHA256)
+	}
+	if cfg.SignRequests {
+		c.replayTolerance = cfg.SignatureTolerance
+	}
+	if transport != http.DefaultTransport {
+		c.httpClient.Transport = transport
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// NewWithOptions is New with the retry policy, backoff and clock in opts
+func NewWithOptions(opts Options, extra ...Option) *Client {
+	return New(append([]Option{WithOptions(opts)}, extra...)...)
+}
+
+func NewWithBaseURL(baseURL string, opts ...Option) *Client {
+	defaults := config.DefaultConfig()
+
+	c := newClient(baseURL, defaults.Timeout, defaults.Retries)
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// workspaceCache holds the last workspace listing, keyed by slug
+type workspaceCache struct {
+	mu     sync.Mutex
+	bySlug map[string]Workspace
+}
+
+func (wc *workspaceCache) get(slug string) (Workspace, bool, bool) {
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
+
+	if wc.bySlug == nil {
+		return Workspace{}, false, false
+	}
+	workspace, found := wc.bySlug[slug]
+	return workspace, found, true
+}
+
+func (wc *workspaceCache) store(workspaces []Workspace) {
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
+
+	wc.bySlug = make(map[string]Workspace, len(workspaces))
+	for _, workspace := range workspaces {
+		wc.bySlug[workspace.Slug] = workspace
+	}
+}
+
+func (wc *workspaceCache) invalidate() {
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
+
+	wc.bySlug = nil
+}
+
+// invalidateWorkspaces drops cached workspaces after a mutation
+func (c *Client) invalidateWorkspaces() {
+	if c.cache != nil {
+		c.cache.invalidate()
+	}
+}
+
+// IdempotencyKeyHeader carries the key that lets the server deduplicate
+// retried mutations
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// send executes req and reads the whole response body. Mutating requests get an
+// idempotency key that stays the same across retries, so every request can be
+// retried; by default with exponential backoff on network errors and 5xx responses. Rate
+// limited responses are only retried when the client waits on rate limits.
+func (c *Client) send(req *http.Request) (*http.Response, []byte, error) {
+	if err := ensureIdempotencyKey(req); err != nil {
+		return nil, nil, err
+	}
+
+	attempts := 1
+	if isRetryable(req) {
+		attempts += c.retries
+	}
+
+	var (
+		resp  *http.Response
+		body  []byte
+		err   error
+		delay time.Duration
+	)
+	start := c.now()
+	tries := 0
+	for attempt := 0; attempt < attempts; attempt++ {
+		tries++
+		if attempt > 0 {
+			c.sleep(delay)
+			if err := c.resignRequest(req); err != nil {
+				return nil, nil, err
+			}
+			if req.GetBody != nil {
+				if req.Body, err = req.GetBody(); err != nil {
+					return nil, nil, fmt.Errorf("failed to rewind request body: %w", err)
+				}
+			}
+		}
+
+		resp, body, err = c.attempt(req)
+		if err == nil && resp.StatusCode == http.StatusTooManyRequests {
+			wait, ok := retryAfter(resp.Header, c.now())
+			if !c.waitOnRateLimit || wait > maxRateLimitWait {
+				break
+			}
+			if !ok {
+				wait = c.backoff(attempt + 1)
+			}
+			delay = wait
+			continue
+		}
+		if !c.retryable(resp, err) {
+			break
+		}
+		delay = c.backoff(attempt + 1)
+	}
+
+	logRequest(req, resp, err, tries, c.now().Sub(start))
+
+	if err != nil {
+		return nil, nil, err
+	}
+	c.observeClock(resp, c.now())
+	return resp, body, nil
+}
+
+// observeClock records the offset between the server's Date header and now
+func (c *Client) observeClock(resp *http.Response, now time.Time) {
+	date, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return
+	}
+
+	c.clockMu.Lock()
+	defer c.clockMu.Unlock()
+	// Date has one-second resolution, so offsets below that are noise
+	c.clockOffset = date.Sub(now.Truncate(time.Second))
+	c.clockKnown = true
+}
+
+// ClockOffset returns how far the server's clock was ahead of this machine's
+// on the last response carrying a Date header
+func (c *Client) ClockOffset() (time.Duration, bool) {
+	c.clockMu.Lock()
+	defer c.clockMu.Unlock()
+
+	return c.clockOffset, c.clockKnown
+}
+
+// logRequest records an API call in the log file; headers and bodies are never logged
+func logRequest(req *http.Request, resp *http.Response, err error, attempts int, duration time.Duration) {
+	attrs := []any{
+		"method", req.Method,
+		"path", req.URL.Path,
+		"attempts", attempts,
+		"duration_ms", duration.Milliseconds(),
+	}
+	if err != nil {
+		logging.Logger().Warn("api request failed", append(attrs, "error", err.Error())...)
+		return
+	}
+	logging.Logger().Info("api request", append(attrs, "status", resp.StatusCode)...)
+}
+
+func (c *Client) attempt(req *http.Request) (*http.Response, []byte, error) {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	return resp, body, nil
+}
+
+// ensureIdempotencyKey gives mutating requests an idempotency key unless the
+// caller already set one for the operation
+func ensureIdempotencyKey(req *http.Request) error {
+	if req.Method == routes.GET || req.Header.Get(IdempotencyKeyHeader) != "" {
+		return nil
+	}
+
+	key, err := NewIdempotencyKey()
+	if err != nil {
+		return err
+	}
+	req.Header.Set(IdempotencyKeyHeader, key)
+	return nil
+}
+
+// NewIdempotencyKey returns a random version 4 UUID
+func NewIdempotencyKey() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("failed to generate idempotency key: %w", err)
+	}
+
+	b[6] = (b[6] & 0x0f) | 0x40 // version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // RFC 4122 variant
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+func isRetryable(req *http.Request) bool {
+	return req.Method == routes.GET || req.Header.Get(IdempotencyKeyHeader) != ""
+}
+
+// retryAfter reads a Retry-After header given in seconds or as an HTTP date
+func retryAfter(header http.Header, now time.Time) (time.Duration, bool) {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
 	}
+
+	if at, err := http.ParseTime(value); err == nil {
+		return max(at.Sub(now), 0), true
+	}
+
+	return 0, false
+}
+
+// defaultRetryable retries network errors and 5xx responses. A certificate
+// that fails pinning won't pass on a retry.
+func defaultRetryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, ErrCertificatePinMismatch)
+	}
+	return resp.StatusCode >= http.StatusInternalServerError
+}
+
+// backoff returns the delay before the given retry attempt (1-based)
+func backoff(attempt int) time.Duration {
+	delay := initialBackoff << (attempt - 1)
+	if delay <= 0 || delay > maxBackoff {
+		return maxBackoff
+	}
+	return delay
 }
 
+var (
+	// ErrOTPRequired is returned by Login when the account needs a second factor
+	ErrOTPRequired = errors.New("one-time password required")
+	// ErrInvalidOTP is returned by LoginWithOTP when the code is wrong or expired
+	ErrInvalidOTP = errors.New("invalid or expired one-time password")
+	// ErrSlugTaken is returned by UpdateWorkspace when another workspace already uses the slug
+	ErrSlugTaken = errors.New("workspace slug already taken")
+	// ErrInsufficientScope is returned when a scoped access token doesn't cover the request
+	ErrInsufficientScope = errors.New("not allowed by the access token's scope")
+	// ErrPreconditionFailed is returned by PutSecretWith when the secret no longer matches its condition
+	ErrPreconditionFailed = errors.New("the secret changed since it was read")
+)
+
+// Error codes the server uses for the two-factor login challenge
+const (
+	errorCodeOTPRequired = "otp_required"
+	errorCodeInvalidOTP  = "invalid_otp"
+)
+
+const errorCodeSlugTaken = "slug_taken"
+
+const errorCodeInsufficientScope = "insufficient_scope"
+
 type LoginRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	OTP      string `json:"otp,omitempty"`
+}
+
+// User is an InitFlow account
+type User struct {
+	ID      int    `json:"id"`
+	Email   string `json:"email"`
+	Name    string `json:"name"`
+	Surname string `json:"surname"`
 }
 
 type LoginResponse struct {
-	Token string `json:"token"`
-	User  struct {
-		ID      int    `json:"id"`
-		Email   string `json:"email"`
-		Name    string `json:"name"`
-		Surname string `json:"surname"`
-	} `json:"user"`
+	Token            string `json:"token"`
+	ExpiresAt        string `json:"expires_at,omitempty"`
+	RefreshToken     string `json:"refresh_token,omitempty"`
+	RefreshExpiresAt string `json:"refresh_expires_at,omitempty"`
+	User             User   `json:"user"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type CurrentUserResponse struct {
+	User User `json:"user"`
 }
 
 type ErrorResponse struct {
@@ -66,6 +498,71 @@ type ErrorResponse struct {
 	Message string `json:"message"`
 }
 
+// APIError is a non-success response from the API
+type APIError struct {
+	Op      string // what the client was doing, e.g. "list workspaces"
+	Status  int    // HTTP status code
+	Code    string // machine-readable error code from the response, if any
+	Message string // human-readable message from the response, if any
+	Body    string // raw response body when it carried no message
+
+	RetryAfter time.Duration // how long a rate limited client should wait, if the server said
+}
+
+func (e *APIError) Error() string {
+	if e.Status == http.StatusTooManyRequests {
+		if e.RetryAfter > 0 {
+			return fmt.Sprintf("%s failed: rate limited, retry in %s", e.Op, e.RetryAfter.Round(time.Second))
+		}
+		return fmt.Sprintf("%s failed: rate limited, retry later", e.Op)
+	}
+	if e.Code == errorCodeInsufficientScope {
+		if e.Message != "" {
+			return fmt.Sprintf("%s failed: %s (%s)", e.Op, ErrInsufficientScope, e.Message)
+		}
+		return fmt.Sprintf("%s failed: %s", e.Op, ErrInsufficientScope)
+	}
+	if e.Message != "" {
+		return fmt.Sprintf("%s failed: %s", e.Op, e.Message)
+	}
+	if e.Code != "" {
+		return fmt.Sprintf("%s failed with status %d, error: %s, raw response: %s", e.Op, e.Status, e.Code, e.Body)
+	}
+	return fmt.Sprintf("%s failed with status %d: %s", e.Op, e.Status, e.Body)
+}
+
+// Is lets errors.Is match the sentinel behind a known error code while
+// keeping the status available to errors.As.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrSlugTaken:
+		return e.Code == errorCodeSlugTaken
+	case ErrInsufficientScope:
+		return e.Code == errorCodeInsufficientScope
+	case ErrPreconditionFailed:
+		return e.Status == http.StatusPreconditionFailed
+	}
+	return false
+}
+
+func newAPIError(op string, resp *http.Response, body []byte) *APIError {
+	apiErr := &APIError{Op: op, Status: resp.StatusCode}
+
+	var errResp ErrorResponse
+	if err := json.Unmarshal(body, &errResp); err == nil {
+		apiErr.Code = errResp.Error
+		apiErr.Message = errResp.Message
+	}
+	if apiErr.Message == "" {
+		apiErr.Body = string(body)
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		apiErr.RetryAfter, _ = retryAfter(resp.Header, time.Now())
+	}
+
+	return apiErr
+}
+
 type DeviceRegistrationRequest struct {
 	Token            string `json:"token"`
 	Name             string `json:"name"`
@@ -80,9 +577,18 @@ type DeviceRegistrationResponse struct {
 		DeviceID  string `json:"device_id"`
 		Name      string `json:"name"`
 		CreatedAt string `json:"created_at"`
+		Status    string `json:"status,omitempty"`
 	} `json:"device"`
 }
 
+// Device approval states. Servers without admin approval omit the status,
+// which counts as approved.
+const (
+	DeviceStatusPending  = "pending_approval"
+	DeviceStatusApproved = "approved"
+	DeviceStatusDenied   = "denied"
+)
+
 type Workspace struct {
 	ID             int    `json:"id"`
 	Name           string `json:"name"`
@@ -91,6 +597,8 @@ type Workspace struct {
 	KeyInitialized bool   `json:"key_initialized"`
 	KeyVersion     int    `json:"key_version"`
 	Role           string `json:"role"`
+	MemberCount    int    `json:"member_count,omitempty"`
+	CreatedAt      string `json:"created_at,omitempty"`
 	Organization   struct {
 		ID   int    `json:"id"`
 		Name string `json:"name"`
@@ -98,33 +606,652 @@ type Workspace struct {
 	} `json:"organization"`
 }
 
-type ListWorkspacesResponse struct {
-	Workspaces []Workspace `json:"workspaces"`
-}
+type Device struct {
+	DeviceID         string `json:"device_id"`
+	Name             string `json:"name"`
+	Platform         string `json:"platform"`
+	PublicKeyEd25519 string `json:"public_key_ed25519"`
+	PublicKeyX25519  string `json:"public_key_x25519"`
+	CreatedAt        string `json:"created_at"`
+	LastSeenAt       string `json:"last_seen_at"`
+	Status           string `json:"status,omitempty"`
+}
+
+// Pending reports whether the device is still waiting for an admin to approve it
+func (d Device) Pending() bool {
+	return d.Status == DeviceStatusPending
+}
+
+// Approved reports whether the device may be given workspace keys: an admin
+// approved it, or the server doesn't use approval and sends no status
+func (d Device) Approved() bool {
+	return d.Status == "" || d.Status == DeviceStatusApproved
+}
+
+// Fingerprint identifies the device by its Ed25519 signing public key
+func (d Device) Fingerprint() (string, error) {
+	publicKey, err := encoding.Decode(d.PublicKeyEd25519)
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		return "", fmt.Errorf("invalid Ed25519 public key for device %s", d.DeviceID)
+	}
+	return encoding.Fingerprint(publicKey), nil
+}
+
+type RenameDeviceRequest struct {
+	Name string `json:"name"`
+}
+
+type RenameDeviceResponse struct {
+	Device Device `json:"device"`
+}
+
+type DeviceResponse struct {
+	Device Device `json:"device"`
+}
+
+type ListDevicesResponse struct {
+	Devices []Device `json:"devices"`
+}
+
+type ListWorkspacesResponse struct {
+	Workspaces []Workspace `json:"workspaces"`
+}
+
+type InitializeWorkspaceKeyRequest struct {
+	WrappedWorkspaceKey string `json:"wrapped_workspace_key"`
+}
+
+// DeviceKeyResponse is this device's wrapped copy of the current workspace key
+type DeviceKeyResponse struct {
+	WrappedWorkspaceKey string `json:"wrapped_workspace_key"`
+	KeyVersion          int    `json:"key_version"`
+}
+
+// InviteDeviceRequest carries the workspace key wrapped to another device
+type InviteDeviceRequest struct {
+	DeviceID            string `json:"device_id"`
+	WrappedWorkspaceKey string `json:"wrapped_workspace_key"`
+}
+
+// DeviceWorkspaceKey is a workspace key wrapped to a device's new X25519 key
+type DeviceWorkspaceKey struct {
+	WorkspaceID         int    `json:"workspace_id"`
+	WrappedWorkspaceKey string `json:"wrapped_workspace_key"`
+	KeyVersion          int    `json:"key_version"`
+}
+
+// RotateDeviceKeyRequest replaces a device's X25519 public key together with
+// every workspace key wrapped to it, so the server swaps them in one step
+type RotateDeviceKeyRequest struct {
+	PublicKeyX25519 string               `json:"public_key_x25519"`
+	WorkspaceKeys   []DeviceWorkspaceKey `json:"workspace_keys"`
+}
+
+// RecoveryKeyRequest carries the workspace key wrapped to an offline recovery key
+type RecoveryKeyRequest struct {
+	WrappedRecoveryKey string `json:"wrapped_recovery_key"`
+}
+
+type RecoveryKeyResponse struct {
+	WrappedRecoveryKey string `json:"wrapped_recovery_key"`
+}
+
+// UpdateWorkspaceRequest changes a workspace's name and, optionally, its slug
+type UpdateWorkspaceRequest struct {
+	Name string `json:"name"`
+	Slug string `json:"slug,omitempty"`
+}
+
+type UpdateWorkspaceResponse struct {
+	Workspace Workspace `json:"workspace"`
+}
+
+type InitializeWorkspaceKeyResponse struct {
+	Success   bool      `json:"success"`
+	Message   string    `json:"message"`
+	Workspace Workspace `json:"workspace"`
+}
+
+// Login authenticates with email and password. If the account has two-factor
+// authentication enabled it returns ErrOTPRequired; retry with LoginWithOTP.
+func (c *Client) Login(email, password string) (*LoginResponse, error) {
+	return c.login(LoginRequest{
+		Email:    email,
+		Password: password,
+	})
+}
+
+// LoginWithOTP authenticates with email, password and a TOTP code
+func (c *Client) LoginWithOTP(email, password, otp string) (*LoginResponse, error) {
+	return c.login(LoginRequest{
+		Email:    email,
+		Password: password,
+		OTP:      otp,
+	})
+}
+
+func (c *Client) login(loginReq LoginRequest) (*LoginResponse, error) {
+	jsonData, err := json.Marshal(loginReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal login request: %w", err)
+	}
+
+	url := routes.BuildURL(c.baseURL, routes.AuthLogin)
+	req, err := http.NewRequest(routes.POST, url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "initflow-cli/1.0")
+
+	resp, body, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		apiErr := newAPIError("login", resp, body)
+		switch apiErr.Code {
+		case errorCodeOTPRequired:
+			return nil, ErrOTPRequired
+		case errorCodeInvalidOTP:
+			return nil, ErrInvalidOTP
+		}
+		return nil, apiErr
+	}
+
+	var loginResp LoginResponse
+	if err := json.Unmarshal(body, &loginResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return &loginResp, nil
+}
+
+// RefreshSession exchanges a refresh token for a new registration token. The
+// server may rotate the refresh token too; if it doesn't, the old one is kept
+// in the response.
+func (c *Client) RefreshSession(refreshToken string) (*LoginResponse, error) {
+	jsonData, err := json.Marshal(RefreshRequest{RefreshToken: refreshToken})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal refresh request: %w", err)
+	}
+
+	url := routes.BuildURL(c.baseURL, routes.RefreshRoute.Path)
+	req, err := http.NewRequest(routes.RefreshRoute.Method, url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "initflow-cli/1.0")
+
+	resp, body, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError("refresh session", resp, body)
+	}
+
+	var loginResp LoginResponse
+	if err := json.Unmarshal(body, &loginResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if loginResp.RefreshToken == "" {
+		loginResp.RefreshToken = refreshToken
+	}
+
+	return &loginResp, nil
+}
+
+// refresh renews the registration token with the client's refresh token
+func (c *Client) refresh() (*LoginResponse, error) {
+	loginResp, err := c.RefreshSession(c.refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	c.refreshToken = loginResp.RefreshToken
+
+	if c.onRefresh != nil {
+		if err := c.onRefresh(loginResp); err != nil {
+			return nil, err
+		}
+	}
+	return loginResp, nil
+}
+
+func (c *Client) encodeKeys(signingPublicKey ed25519.PublicKey, encryptionPublicKey []byte) (string, string, error) {
+	ed25519Encoded, err := encoding.EncodeEd25519PublicKey(signingPublicKey)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to encode Ed25519 public key: %w", err)
+	}
+
+	x25519Encoded, err := encoding.EncodeX25519PublicKey(encryptionPublicKey)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to encode X25519 public key: %w", err)
+	}
+
+	// Debug: show encoded keys (first few chars only for security)
+	ed25519Preview := ed25519Encoded
+	if len(ed25519Preview) > debugPreviewLength {
+		ed25519Preview = ed25519Preview[:debugPreviewLength] + "..."
+	}
+	x25519Preview := x25519Encoded
+	if len(x25519Preview) > debugPreviewLength {
+		x25519Preview = x25519Preview[:debugPreviewLength] + "..."
+	}
+	fmt.Printf("🔍 Debug: Ed25519 encoded: %s\n", ed25519Preview)
+	fmt.Printf("🔍 Debug: X25519 encoded: %s\n", x25519Preview)
+
+	return ed25519Encoded, x25519Encoded, nil
+}
+
+func (c *Client) handleRegistrationResponse(resp *http.Response, body []byte) (*DeviceRegistrationResponse, error) {
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return nil, newAPIError("device registration", resp, body)
+	}
+
+	var deviceResp DeviceRegistrationResponse
+	if err := json.Unmarshal(body, &deviceResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return &deviceResp, nil
+}
+
+func (c *Client) RegisterDevice(
+	token, name string,
+	signingPublicKey ed25519.PublicKey,
+	encryptionPublicKey []byte,
+) (*DeviceRegistrationResponse, error) {
+	ed25519Encoded, x25519Encoded, err := c.encodeKeys(signingPublicKey, encryptionPublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	deviceReq := DeviceRegistrationRequest{
+		Token:            token,
+		Name:             name,
+		PublicKeyEd25519: ed25519Encoded,
+		PublicKeyX25519:  x25519Encoded,
+	}
+
+	resp, body, err := c.postRegistration(deviceReq)
+	if err != nil {
+		return nil, err
+	}
+
+	// The registration token expired; renew it and try once more
+	if resp.StatusCode == http.StatusUnauthorized && c.refreshToken != "" {
+		loginResp, err := c.refresh()
+		if err != nil {
+			return nil, fmt.Errorf("registration token expired and couldn't be refreshed: %w", err)
+		}
+		deviceReq.Token = loginResp.Token
+		if resp, body, err = c.postRegistration(deviceReq); err != nil {
+			return nil, err
+		}
+	}
+
+	return c.handleRegistrationResponse(resp, body)
+}
+
+func (c *Client) postRegistration(deviceReq DeviceRegistrationRequest) (*http.Response, []byte, error) {
+	jsonData, err := json.Marshal(deviceReq)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal device registration request: %w", err)
+	}
+
+	url := routes.BuildURL(c.baseURL, routes.Devices)
+	req, err := http.NewRequest(routes.POST, url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "initflow-cli/1.0")
+
+	return c.send(req)
+}
+
+// Headers added to signatures when sign_requests is on
+const (
+	NonceHeader              = "X-Nonce"
+	SignatureToleranceHeader = "X-Signature-Tolerance"
+)
+
+// signRequest signs the method, path, body and a timestamp with the device's
+// Ed25519 key. With sign_requests on, a random nonce and the accepted clock
+// skew are signed too, so the server can reject replayed requests.
+func (c *Client) signRequest(req *http.Request, body []byte) error {
+	if c.scopedToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.scopedToken)
+		return nil
+	}
+
+	store := storage.New()
+
+	deviceID, err := store.GetDeviceID()
+	if err != nil {
+		return fmt.Errorf("failed to get device ID: %w", err)
+	}
+
+	signingKey, err := store.GetSigningPrivateKey()
+	if err != nil {
+		return fmt.Errorf("failed to get signing key: %w", err)
+	}
+
+	timestamp := time.Now().Unix()
+
+	var bodyStr string
+	if body != nil {
+		bodyStr = string(body)
+	}
+
+	message := fmt.Sprintf("%s\n%s\n%s\n%d",
+		req.Method,
+		req.URL.Path+req.URL.RawQuery,
+		bodyStr,
+		timestamp)
+
+	if c.replayTolerance > 0 {
+		nonce, err := randomNonce()
+		if err != nil {
+			return err
+		}
+		tolerance := strconv.FormatInt(int64(c.replayTolerance/time.Second), 10)
+		message += "\n" + nonce + "\n" + tolerance
+		req.Header.Set(NonceHeader, nonce)
+		req.Header.Set(SignatureToleranceHeader, tolerance)
+	}
+
+	signature := ed25519.Sign(signingKey, []byte(message))
+
+	signatureEncoded, err := encoding.EncodeEd25519Signature(signature)
+	if err != nil {
+		return fmt.Errorf("failed to encode signature: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Device "+deviceID)
+	req.Header.Set("X-Signature", signatureEncoded)
+	req.Header.Set("X-Timestamp", strconv.FormatInt(timestamp, 10))
+
+	return nil
+}
+
+// resignRequest signs a retry afresh when its signature carries a nonce,
+// which the server accepts only once
+func (c *Client) resignRequest(req *http.Request) error {
+	if req.Header.Get(NonceHeader) == "" {
+		return nil
+	}
+
+	var body []byte
+	if req.GetBody != nil {
+		reader, err := req.GetBody()
+		if err != nil {
+			return fmt.Errorf("failed to rewind request body: %w", err)
+		}
+		if body, err = io.ReadAll(reader); err != nil {
+			return fmt.Errorf("failed to read request body for signing: %w", err)
+		}
+	}
+	if err := c.signRequest(req, body); err != nil {
+		return fmt.Errorf("failed to sign request: %w", err)
+	}
+	return nil
+}
+
+func randomNonce() (string, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate request nonce: %w", err)
+	}
+	return hex.EncodeToString(nonce), nil
+}
+
+func (c *Client) ListWorkspaces() ([]Workspace, error) {
+	url := routes.BuildURL(c.baseURL, routes.Workspaces)
+	req, err := http.NewRequest(routes.GET, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("User-Agent", "initflow-cli/1.0")
+
+	if err := c.signRequest(req, nil); err != nil {
+		return nil, fmt.Errorf("failed to sign request: %w", err)
+	}
+
+	resp, body, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError("list workspaces", resp, body)
+	}
+
+	var workspacesResp ListWorkspacesResponse
+	if err := json.Unmarshal(body, &workspacesResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	if c.cache != nil {
+		c.cache.store(workspacesResp.Workspaces)
+	}
+
+	return workspacesResp.Workspaces, nil
+}
+
+func (c *Client) GetWorkspaceBySlug(slug string) (*Workspace, error) {
+	if c.cache != nil {
+		if workspace, found, cached := c.cache.get(slug); cached {
+			if !found {
+				return nil, fmt.Errorf("workspace '%s' not found", slug)
+			}
+			return &workspace, nil
+		}
+	}
+
+	workspaces, err := c.ListWorkspaces()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, workspace := range workspaces {
+		if workspace.Slug == slug {
+			return &workspace, nil
+		}
+	}
+
+	return nil, fmt.Errorf("workspace '%s' not found", slug)
+}
+
+// InitializeWorkspaceKey uploads the first wrapped copy of a workspace key. If
+// the server finishes the work asynchronously, the returned operation tracks it;
+// otherwise it is nil.
+func (c *Client) InitializeWorkspaceKey(workspaceID int, wrappedKey []byte) (*Operation, error) {
+	initReq := InitializeWorkspaceKeyRequest{
+		WrappedWorkspaceKey: encoding.Encode(wrappedKey),
+	}
+
+	jsonData, err := json.Marshal(initReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal initialize key request: %w", err)
+	}
+
+	url := routes.BuildURL(c.baseURL, routes.Workspace.InitializeKey(workspaceID))
+	req, err := http.NewRequest(routes.POST, url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "initflow-cli/1.0")
+
+	if err := c.signRequest(req, jsonData); err != nil {
+		return nil, fmt.Errorf("failed to sign request: %w", err)
+	}
+
+	resp, body, err := c.send(req)
+	c.invalidateWorkspaces()
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		return nil, nil
+	case http.StatusAccepted:
+		return decodeOperation(body)
+	default:
+		return nil, newAPIError("initialize workspace key", resp, body)
+	}
+}
+
+// UpdateWorkspace renames a workspace. A slug already used by another
+// workspace yields an error matching ErrSlugTaken.
+func (c *Client) UpdateWorkspace(workspaceID int, update UpdateWorkspaceRequest) (*Workspace, error) {
+	jsonData, err := json.Marshal(update)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal update workspace request: %w", err)
+	}
+
+	url := routes.BuildURL(c.baseURL, routes.Workspace.GetByID(workspaceID))
+	req, err := http.NewRequest(routes.PATCH, url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "initflow-cli/1.0")
+
+	if err := c.signRequest(req, jsonData); err != nil {
+		return nil, fmt.Errorf("failed to sign request: %w", err)
+	}
+
+	resp, body, err := c.send(req)
+	c.invalidateWorkspaces()
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError("update workspace", resp, body)
+	}
+
+	var updateResp UpdateWorkspaceResponse
+	if err := json.Unmarshal(body, &updateResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return &updateResp.Workspace, nil
+}
+
+func (c *Client) ListWorkspaceDevices(workspaceID int) ([]Device, error) {
+	url := routes.BuildURL(c.baseURL, routes.Workspace.Devices(workspaceID))
+	req, err := http.NewRequest(routes.GET, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("User-Agent", "initflow-cli/1.0")
+
+	if err := c.signRequest(req, nil); err != nil {
+		return nil, fmt.Errorf("failed to sign request: %w", err)
+	}
+
+	resp, body, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError("list devices", resp, body)
+	}
+
+	var devicesResp ListDevicesResponse
+	if err := json.Unmarshal(body, &devicesResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return devicesResp.Devices, nil
+}
+
+// CurrentUser returns the account the device or scoped token belongs to
+func (c *Client) CurrentUser() (*User, error) {
+	url := routes.BuildURL(c.baseURL, routes.CurrentUserRoute.Path)
+	req, err := http.NewRequest(routes.CurrentUserRoute.Method, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("User-Agent", "initflow-cli/1.0")
+
+	if err := c.signRequest(req, nil); err != nil {
+		return nil, fmt.Errorf("failed to sign request: %w", err)
+	}
+
+	resp, body, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError("get current user", resp, body)
+	}
 
-type InitializeWorkspaceKeyRequest struct {
-	WrappedWorkspaceKey string `json:"wrapped_workspace_key"`
-}
+	var userResp CurrentUserResponse
+	if err := json.Unmarshal(body, &userResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
 
-type InitializeWorkspaceKeyResponse struct {
-	Success   bool      `json:"success"`
-	Message   string    `json:"message"`
-	Workspace Workspace `json:"workspace"`
+	return &userResp.User, nil
 }
 
-func (c *Client) Login(email, password string) (*LoginResponse, error) {
-	loginReq := LoginRequest{
-		Email:    email,
-		Password: password,
+// ListDevices returns every device registered to the signed-in account
+func (c *Client) ListDevices() ([]Device, error) {
+	url := routes.BuildURL(c.baseURL, routes.ListDevicesRoute.Path)
+	req, err := http.NewRequest(routes.ListDevicesRoute.Method, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	jsonData, err := json.Marshal(loginReq)
+	req.Header.Set("User-Agent", "initflow-cli/1.0")
+
+	if err := c.signRequest(req, nil); err != nil {
+		return nil, fmt.Errorf("failed to sign request: %w", err)
+	}
+
+	resp, body, err := c.send(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal login request: %w", err)
+		return nil, err
 	}
 
-	url := routes.BuildURL(c.baseURL, routes.AuthLogin)
-	req, err := http.NewRequest(routes.POST, url, bytes.NewBuffer(jsonData))
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError("list devices", resp, body)
+	}
+
+	var devicesResp ListDevicesResponse
+	if err := json.Unmarshal(body, &devicesResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return devicesResp.Devices, nil
+}
+
+func (c *Client) RenameDevice(deviceID, name string) (*Device, error) {
+	jsonData, err := json.Marshal(RenameDeviceRequest{Name: name})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal rename device request: %w", err)
+	}
+
+	url := routes.BuildURL(c.baseURL, routes.Device.GetByID(deviceID))
+	req, err := http.NewRequest(routes.PATCH, url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -132,171 +1259,185 @@ func (c *Client) Login(email, password string) (*LoginResponse, error) {
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("User-Agent", "initflow-cli/1.0")
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
+	if err := c.signRequest(req, jsonData); err != nil {
+		return nil, fmt.Errorf("failed to sign request: %w", err)
 	}
-	defer func() {
-		_ = resp.Body.Close()
-	}()
 
-	body, err := io.ReadAll(resp.Body)
+	resp, body, err := c.send(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, err
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		var errResp ErrorResponse
-		if err := json.Unmarshal(body, &errResp); err != nil {
-			return nil, fmt.Errorf("login failed with status %d: %s", resp.StatusCode, string(body))
-		}
-		return nil, fmt.Errorf("login failed: %s", errResp.Message)
+		return nil, newAPIError("rename device", resp, body)
 	}
 
-	var loginResp LoginResponse
-	if err := json.Unmarshal(body, &loginResp); err != nil {
+	var renameResp RenameDeviceResponse
+	if err := json.Unmarshal(body, &renameResp); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
-	return &loginResp, nil
+	return &renameResp.Device, nil
 }
 
-func (c *Client) encodeKeys(signingPublicKey ed25519.PublicKey, encryptionPublicKey []byte) (string, string, error) {
-	ed25519Encoded, err := encoding.EncodeEd25519PublicKey(signingPublicKey)
+// GetDevice fetches a single device, e.g. to check whether it has been approved
+func (c *Client) GetDevice(deviceID string) (*Device, error) {
+	url := routes.BuildURL(c.baseURL, routes.Device.GetByID(deviceID))
+	req, err := http.NewRequest(routes.GET, url, nil)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to encode Ed25519 public key: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	x25519Encoded, err := encoding.EncodeX25519PublicKey(encryptionPublicKey)
-	if err != nil {
-		return "", "", fmt.Errorf("failed to encode X25519 public key: %w", err)
-	}
+	req.Header.Set("User-Agent", "initflow-cli/1.0")
 
-	// Debug: show encoded keys (first few chars only for security)
-	ed25519Preview := ed25519Encoded
-	if len(ed25519Preview) > debugPreviewLength {
-		ed25519Preview = ed25519Preview[:debugPreviewLength] + "..."
-	}
-	x25519Preview := x25519Encoded
-	if len(x25519Preview) > debugPreviewLength {
-		x25519Preview = x25519Preview[:debugPreviewLength] + "..."
+	if err := c.signRequest(req, nil); err != nil {
+		return nil, fmt.Errorf("failed to sign request: %w", err)
 	}
-	fmt.Printf("🔍 Debug: Ed25519 encoded: %s\n", ed25519Preview)
-	fmt.Printf("🔍 Debug: X25519 encoded: %s\n", x25519Preview)
 
-	return ed25519Encoded, x25519Encoded, nil
-}
+	resp, body, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
 
-func (c *Client) handleRegistrationResponse(resp *http.Response, body []byte) (*DeviceRegistrationResponse, error) {
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		var errResp ErrorResponse
-		if err := json.Unmarshal(body, &errResp); err != nil {
-			return nil, fmt.Errorf("device registration failed with status %d, raw response: %s", resp.StatusCode, string(body))
-		}
-		if errResp.Message == "" {
-			return nil, fmt.Errorf("device registration failed with status %d, error: %s, raw response: %s",
-				resp.StatusCode, errResp.Error, string(body))
-		}
-		return nil, fmt.Errorf("device registration failed: %s", errResp.Message)
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError("get device", resp, body)
 	}
 
-	var deviceResp DeviceRegistrationResponse
+	var deviceResp DeviceResponse
 	if err := json.Unmarshal(body, &deviceResp); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
-	return &deviceResp, nil
+	return &deviceResp.Device, nil
 }
 
-func (c *Client) RegisterDevice(
-	token, name string,
-	signingPublicKey ed25519.PublicKey,
-	encryptionPublicKey []byte,
-) (*DeviceRegistrationResponse, error) {
-	ed25519Encoded, x25519Encoded, err := c.encodeKeys(signingPublicKey, encryptionPublicKey)
+// RevokeDevice cuts a device off from the account and every workspace it belonged to
+func (c *Client) RevokeDevice(deviceID string) error {
+	url := routes.BuildURL(c.baseURL, routes.Device.Revoke(deviceID))
+	req, err := http.NewRequest(routes.POST, url, nil)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("failed to create request: %w", err)
 	}
 
-	deviceReq := DeviceRegistrationRequest{
-		Token:            token,
-		Name:             name,
-		PublicKeyEd25519: ed25519Encoded,
-		PublicKeyX25519:  x25519Encoded,
+	req.Header.Set("User-Agent", "initflow-cli/1.0")
+
+	if err := c.signRequest(req, nil); err != nil {
+		return fmt.Errorf("failed to sign request: %w", err)
 	}
 
-	jsonData, err := json.Marshal(deviceReq)
+	resp, body, err := c.send(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal device registration request: %w", err)
+		return err
 	}
 
-	url := routes.BuildURL(c.baseURL, routes.Devices)
-	req, err := http.NewRequest(routes.POST, url, bytes.NewBuffer(jsonData))
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return newAPIError("revoke device", resp, body)
+	}
+
+	return nil
+}
+
+// RotateDeviceKey uploads a device's new X25519 public key along with the
+// workspace keys re-wrapped to it. The server answers 409 if one of the
+// workspace keys was rotated in the meantime.
+func (c *Client) RotateDeviceKey(deviceID string, rotateReq RotateDeviceKeyRequest) error {
+	jsonData, err := json.Marshal(rotateReq)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return fmt.Errorf("failed to marshal rotate device key request: %w", err)
+	}
+
+	url := routes.BuildURL(c.baseURL, routes.Device.EncryptionKey(deviceID))
+	req, err := http.NewRequest(routes.PUT, url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("User-Agent", "initflow-cli/1.0")
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
+	if err := c.signRequest(req, jsonData); err != nil {
+		return fmt.Errorf("failed to sign request: %w", err)
 	}
-	defer func() {
-		_ = resp.Body.Close()
-	}()
 
-	body, err := io.ReadAll(resp.Body)
+	resp, body, err := c.send(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return err
 	}
 
-	return c.handleRegistrationResponse(resp, body)
-}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return newAPIError("rotate device key", resp, body)
+	}
 
-func (c *Client) signRequest(req *http.Request, body []byte) error {
-	store := storage.New()
+	return nil
+}
 
-	deviceID, err := store.GetDeviceID()
+// ApproveDevice lets a pending device into the workspace. Requires an admin role.
+func (c *Client) ApproveDevice(workspaceID int, deviceID string) (*Device, error) {
+	url := routes.BuildURL(c.baseURL, routes.Workspace.ApproveDevice(workspaceID, deviceID))
+	req, err := http.NewRequest(routes.POST, url, nil)
 	if err != nil {
-		return fmt.Errorf("failed to get device ID: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	signingKey, err := store.GetSigningPrivateKey()
+	req.Header.Set("User-Agent", "initflow-cli/1.0")
+
+	if err := c.signRequest(req, nil); err != nil {
+		return nil, fmt.Errorf("failed to sign request: %w", err)
+	}
+
+	resp, body, err := c.send(req)
 	if err != nil {
-		return fmt.Errorf("failed to get signing key: %w", err)
+		return nil, err
 	}
 
-	timestamp := time.Now().Unix()
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError("approve device", resp, body)
+	}
 
-	var bodyStr string
-	if body != nil {
-		bodyStr = string(body)
+	var deviceResp DeviceResponse
+	if err := json.Unmarshal(body, &deviceResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
-	message := fmt.Sprintf("%s\n%s\n%s\n%d",
-		req.Method,
-		req.URL.Path+req.URL.RawQuery,
-		bodyStr,
-		timestamp)
+	return &deviceResp.Device, nil
+}
 
-	signature := ed25519.Sign(signingKey, []byte(message))
+// SetRecoveryKey stores the workspace key wrapped to an offline recovery key,
+// replacing any previous one.
+func (c *Client) SetRecoveryKey(workspaceID int, wrappedKey []byte) error {
+	jsonData, err := json.Marshal(RecoveryKeyRequest{WrappedRecoveryKey: encoding.Encode(wrappedKey)})
+	if err != nil {
+		return fmt.Errorf("failed to marshal recovery key request: %w", err)
+	}
 
-	signatureEncoded, err := encoding.EncodeEd25519Signature(signature)
+	url := routes.BuildURL(c.baseURL, routes.Workspace.RecoveryKey(workspaceID))
+	req, err := http.NewRequest(routes.PUT, url, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return fmt.Errorf("failed to encode signature: %w", err)
+		return fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Authorization", "Device "+deviceID)
-	req.Header.Set("X-Signature", signatureEncoded)
-	req.Header.Set("X-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "initflow-cli/1.0")
+
+	if err := c.signRequest(req, jsonData); err != nil {
+		return fmt.Errorf("failed to sign request: %w", err)
+	}
+
+	resp, body, err := c.send(req)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return newAPIError("store recovery key", resp, body)
+	}
 
 	return nil
 }
 
-func (c *Client) ListWorkspaces() ([]Workspace, error) {
-	url := routes.BuildURL(c.baseURL, routes.Workspaces)
+// GetRecoveryKey fetches the workspace key wrapped to the workspace's recovery key
+func (c *Client) GetRecoveryKey(workspaceID int) ([]byte, error) {
+	url := routes.BuildURL(c.baseURL, routes.Workspace.RecoveryKey(workspaceID))
 	req, err := http.NewRequest(routes.GET, url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
@@ -308,61 +1449,109 @@ func (c *Client) ListWorkspaces() ([]Workspace, error) {
 		return nil, fmt.Errorf("failed to sign request: %w", err)
 	}
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
-	}
-	defer func() {
-		_ = resp.Body.Close()
-	}()
-
-	body, err := io.ReadAll(resp.Body)
+	resp, body, err := c.send(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, err
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		var errResp ErrorResponse
-		if err := json.Unmarshal(body, &errResp); err != nil {
-			return nil, fmt.Errorf("list workspaces failed with status %d: %s", resp.StatusCode, string(body))
-		}
-		return nil, fmt.Errorf("list workspaces failed: %s", errResp.Message)
+		return nil, newAPIError("get recovery key", resp, body)
 	}
 
-	var workspacesResp ListWorkspacesResponse
-	if err := json.Unmarshal(body, &workspacesResp); err != nil {
+	var recoveryResp RecoveryKeyResponse
+	if err := json.Unmarshal(body, &recoveryResp); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
-	return workspacesResp.Workspaces, nil
+	wrapped, err := encoding.Decode(recoveryResp.WrappedRecoveryKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode recovery key: %w", err)
+	}
+	return wrapped, nil
 }
 
-func (c *Client) GetWorkspaceBySlug(slug string) (*Workspace, error) {
-	workspaces, err := c.ListWorkspaces()
+// SetDeviceWorkspaceKey uploads the workspace key wrapped to this device, for a
+// workspace that already has a key.
+func (c *Client) SetDeviceWorkspaceKey(workspaceID int, wrappedKey []byte) error {
+	jsonData, err := json.Marshal(InitializeWorkspaceKeyRequest{WrappedWorkspaceKey: encoding.Encode(wrappedKey)})
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("failed to marshal device key request: %w", err)
 	}
 
-	for _, workspace := range workspaces {
-		if workspace.Slug == slug {
-			return &workspace, nil
-		}
+	url := routes.BuildURL(c.baseURL, routes.Workspace.DeviceKey(workspaceID))
+	req, err := http.NewRequest(routes.PUT, url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
 	}
 
-	return nil, fmt.Errorf("workspace '%s' not found", slug)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "initflow-cli/1.0")
+
+	if err := c.signRequest(req, jsonData); err != nil {
+		return fmt.Errorf("failed to sign request: %w", err)
+	}
+
+	resp, body, err := c.send(req)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return newAPIError("store device key", resp, body)
+	}
+
+	return nil
 }
 
-func (c *Client) InitializeWorkspaceKey(workspaceID int, wrappedKey []byte) error {
-	initReq := InitializeWorkspaceKeyRequest{
-		WrappedWorkspaceKey: encoding.Encode(wrappedKey),
+// GetDeviceWorkspaceKey fetches the current workspace key wrapped to this
+// device, e.g. after another member rotated it, along with its version. With
+// a scoped token it returns the key wrapped for the token instead.
+func (c *Client) GetDeviceWorkspaceKey(workspaceID int) ([]byte, int, error) {
+	url := routes.BuildURL(c.baseURL, routes.Workspace.DeviceKey(workspaceID))
+	req, err := http.NewRequest(routes.GET, url, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	jsonData, err := json.Marshal(initReq)
+	req.Header.Set("User-Agent", "initflow-cli/1.0")
+
+	if err := c.signRequest(req, nil); err != nil {
+		return nil, 0, fmt.Errorf("failed to sign request: %w", err)
+	}
+
+	resp, body, err := c.send(req)
 	if err != nil {
-		return fmt.Errorf("failed to marshal initialize key request: %w", err)
+		return nil, 0, err
 	}
 
-	url := routes.BuildURL(c.baseURL, routes.Workspace.InitializeKey(workspaceID))
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, newAPIError("get device key", resp, body)
+	}
+
+	var keyResp DeviceKeyResponse
+	if err := json.Unmarshal(body, &keyResp); err != nil {
+		return nil, 0, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	wrapped, err := encoding.Decode(keyResp.WrappedWorkspaceKey)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to decode device key: %w", err)
+	}
+	return wrapped, keyResp.KeyVersion, nil
+}
+
+// InviteDevice uploads the workspace key wrapped to another device's X25519
+// public key, giving that device access to the workspace.
+func (c *Client) InviteDevice(workspaceID int, deviceID string, wrappedKey []byte) error {
+	jsonData, err := json.Marshal(InviteDeviceRequest{
+		DeviceID:            deviceID,
+		WrappedWorkspaceKey: encoding.Encode(wrappedKey),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal invite request: %w", err)
+	}
+
+	url := routes.BuildURL(c.baseURL, routes.Workspace.InviteDevice(workspaceID))
 	req, err := http.NewRequest(routes.POST, url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
@@ -375,26 +1564,15 @@ func (c *Client) InitializeWorkspaceKey(workspaceID int, wrappedKey []byte) erro
 		return fmt.Errorf("failed to sign request: %w", err)
 	}
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to make request: %w", err)
-	}
-	defer func() {
-		_ = resp.Body.Close()
-	}()
-
-	body, err := io.ReadAll(resp.Body)
+	resp, body, err := c.send(req)
 	if err != nil {
-		return fmt.Errorf("failed to read response body: %w", err)
+		return err
 	}
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		var errResp ErrorResponse
-		if err := json.Unmarshal(body, &errResp); err != nil {
-			return fmt.Errorf("initialize workspace key failed with status %d: %s", resp.StatusCode, string(body))
-		}
-		return fmt.Errorf("initialize workspace key failed: %s", errResp.Message)
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusNoContent:
+		return nil
+	default:
+		return newAPIError("invite device", resp, body)
 	}
-
-	return nil
 }
@@ -4,16 +4,31 @@ import (
 	"crypto/ed25519"
 	"crypto/rand"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/zalando/go-keyring"
 
+	"github.com/DylanBlakemore/initflow-cli/internal/encoding"
+	"github.com/DylanBlakemore/initflow-cli/internal/logging"
 	"github.com/DylanBlakemore/initflow-cli/internal/routes"
+	"github.com/DylanBlakemore/initflow-cli/internal/storage"
 )
 
+func TestMain(m *testing.M) {
+	sleep = func(time.Duration) {}
+	keyring.MockInit()
+	os.Exit(m.Run())
+}
+
 func TestNew(t *testing.T) {
 	client := New()
 	assert.NotNil(t, client)
@@ -236,6 +251,7 @@ func TestRegisterDevice_Success(t *testing.T) {
 				DeviceID  string `json:"device_id"`
 				Name      string `json:"name"`
 				CreatedAt string `json:"created_at"`
+				Status    string `json:"status,omitempty"`
 			}{
 				DeviceID:  "device-123",
 				Name:      "Test Device",
@@ -326,6 +342,7 @@ func TestRegisterDevice_Success_With200(t *testing.T) {
 				DeviceID  string `json:"device_id"`
 				Name      string `json:"name"`
 				CreatedAt string `json:"created_at"`
+				Status    string `json:"status,omitempty"`
 			}{
 				DeviceID:  "device-456",
 				Name:      "Test Device 200",
@@ -356,3 +373,418 @@ func TestRegisterDevice_Success_With200(t *testing.T) {
 	assert.True(t, resp.Success)
 	assert.Equal(t, "device-456", resp.Device.DeviceID)
 }
+
+func TestRegisterDevice_RefreshesExpiredToken(t *testing.T) {
+	var registrations []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case routes.AuthRefresh:
+			var req RefreshRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			assert.Equal(t, "refresh-token", req.RefreshToken)
+			json.NewEncoder(w).Encode(LoginResponse{Token: "fresh-token", ExpiresAt: "2026-01-01T12:15:00Z"})
+		case routes.Devices:
+			var req DeviceRegistrationRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			registrations = append(registrations, req.Token)
+			if req.Token != "fresh-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				json.NewEncoder(w).Encode(ErrorResponse{Error: "token_expired", Message: "Token expired"})
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(DeviceRegistrationResponse{Success: true})
+		default:
+			t.Errorf("Unexpected request to %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	var refreshed *LoginResponse
+	client := NewWithBaseURL(server.URL, WithTokenRefresh("refresh-token", func(resp *LoginResponse) error {
+		refreshed = resp
+		return nil
+	}))
+
+	signingPublic, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	encryptionPublic := make([]byte, 32)
+	_, err = rand.Read(encryptionPublic)
+	require.NoError(t, err)
+
+	_, err = client.RegisterDevice("expired-token", "Test Device", signingPublic, encryptionPublic)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"expired-token", "fresh-token"}, registrations)
+	require.NotNil(t, refreshed)
+	assert.Equal(t, "fresh-token", refreshed.Token)
+	assert.Equal(t, "refresh-token", refreshed.RefreshToken, "a refresh token the server didn't rotate is kept")
+
+	// Without a refresh token the 401 is returned as is
+	registrations = nil
+	_, err = NewWithBaseURL(server.URL).RegisterDevice("expired-token", "Test Device", signingPublic, encryptionPublic)
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusUnauthorized, apiErr.Status)
+	assert.Equal(t, []string{"expired-token"}, registrations)
+}
+
+func TestLogin_OTPChallenge(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var loginReq LoginRequest
+		err := json.NewDecoder(r.Body).Decode(&loginReq)
+		require.NoError(t, err)
+
+		w.Header().Set("Content-Type", "application/json")
+
+		switch loginReq.OTP {
+		case "":
+			w.WriteHeader(http.StatusUnauthorized)
+			_ = json.NewEncoder(w).Encode(ErrorResponse{
+				Error:   "otp_required",
+				Message: "Two-factor authentication code required",
+			})
+		case "123456":
+			_ = json.NewEncoder(w).Encode(LoginResponse{Token: "otp-token"})
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+			_ = json.NewEncoder(w).Encode(ErrorResponse{
+				Error:   "invalid_otp",
+				Message: "Invalid or expired code",
+			})
+		}
+	}))
+	defer server.Close()
+
+	client := NewWithBaseURL(server.URL)
+
+	resp, err := client.Login("test@example.com", "password123")
+	assert.ErrorIs(t, err, ErrOTPRequired)
+	assert.Nil(t, resp)
+
+	resp, err = client.LoginWithOTP("test@example.com", "password123", "654321")
+	assert.ErrorIs(t, err, ErrInvalidOTP)
+	assert.Nil(t, resp)
+
+	resp, err = client.LoginWithOTP("test@example.com", "password123", "123456")
+	require.NoError(t, err)
+	assert.Equal(t, "otp-token", resp.Token)
+}
+
+func TestSend_RetriesIdempotentRequests(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	client := NewWithBaseURL(server.URL)
+	client.retries = 1
+
+	req, err := http.NewRequest(routes.GET, server.URL, nil)
+	require.NoError(t, err)
+
+	resp, body, err := client.send(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "ok", string(body))
+	assert.Equal(t, 2, calls)
+}
+
+func TestSend_ReusesIdempotencyKeyAcrossRetries(t *testing.T) {
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get(IdempotencyKeyHeader))
+		if len(keys) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(LoginResponse{Token: "token"})
+	}))
+	defer server.Close()
+
+	client := NewWithBaseURL(server.URL)
+	client.retries = 2
+
+	resp, err := client.Login("test@example.com", "password123")
+	require.NoError(t, err)
+	assert.Equal(t, "token", resp.Token)
+
+	require.Len(t, keys, 3)
+	assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, keys[0])
+	assert.Equal(t, keys[0], keys[1])
+	assert.Equal(t, keys[0], keys[2])
+
+	// A new logical operation gets a new key
+	_, err = client.Login("test@example.com", "password123")
+	require.NoError(t, err)
+	assert.NotEqual(t, keys[0], keys[3])
+}
+
+func TestSend_LogsRequestsWithoutSecrets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(LoginResponse{Token: "registration-token-abc123"})
+	}))
+	defer server.Close()
+
+	logFile := filepath.Join(t.TempDir(), "initflow.log")
+	require.NoError(t, logging.Open(logFile, logging.DefaultMaxSize))
+	t.Cleanup(func() { _ = logging.Close() })
+
+	_, err := NewWithBaseURL(server.URL).Login("test@example.com", "super-secret-password")
+	require.NoError(t, err)
+	require.NoError(t, logging.Close())
+
+	content, err := os.ReadFile(logFile) // #nosec G304 - test file path is controlled
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), "super-secret-password")
+	assert.NotContains(t, string(content), "registration-token-abc123")
+
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	require.Len(t, lines, 1)
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.Equal(t, "api request", entry["msg"])
+	assert.Equal(t, routes.POST, entry["method"])
+	assert.Equal(t, routes.AuthLogin, entry["path"])
+	assert.Equal(t, float64(http.StatusOK), entry["status"])
+	assert.Contains(t, entry, "duration_ms")
+}
+
+// storeTestDevice gives signed requests a device identity in the mock keychain
+func storeTestDevice(t *testing.T) {
+	store := storage.New()
+	_, signingKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	require.NoError(t, store.StoreSigningPrivateKey(signingKey))
+	require.NoError(t, store.StoreDeviceID("test-device"))
+	t.Cleanup(func() {
+		_ = store.DeleteSigningPrivateKey()
+		_ = store.DeleteDeviceID()
+	})
+}
+
+// workspaceServer lists one workspace and counts listing requests
+func workspaceServer(t *testing.T, listings *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case routes.GET:
+			*listings++
+			_ = json.NewEncoder(w).Encode(ListWorkspacesResponse{
+				Workspaces: []Workspace{{ID: 1, Name: "My Project", Slug: "my-project"}},
+			})
+		case routes.PATCH:
+			_ = json.NewEncoder(w).Encode(UpdateWorkspaceResponse{
+				Workspace: Workspace{ID: 1, Name: "Renamed", Slug: "my-project"},
+			})
+		}
+	}))
+}
+
+func TestGetWorkspaceBySlug_CachedClientFetchesOnce(t *testing.T) {
+	storeTestDevice(t)
+	var listings int
+	server := workspaceServer(t, &listings)
+	defer server.Close()
+
+	client := NewWithBaseURL(server.URL, WithCache())
+	for range 2 {
+		workspace, err := client.GetWorkspaceBySlug("my-project")
+		require.NoError(t, err)
+		assert.Equal(t, 1, workspace.ID)
+	}
+	_, err := client.GetWorkspaceBySlug("missing")
+	assert.Error(t, err)
+	assert.Equal(t, 1, listings)
+
+	// Without the option every lookup hits the server
+	uncached := NewWithBaseURL(server.URL)
+	_, err = uncached.GetWorkspaceBySlug("my-project")
+	require.NoError(t, err)
+	_, err = uncached.GetWorkspaceBySlug("my-project")
+	require.NoError(t, err)
+	assert.Equal(t, 3, listings)
+}
+
+func TestGetWorkspaceBySlug_MutationInvalidatesCache(t *testing.T) {
+	storeTestDevice(t)
+	var listings int
+	server := workspaceServer(t, &listings)
+	defer server.Close()
+
+	client := NewWithBaseURL(server.URL, WithCache())
+	_, err := client.GetWorkspaceBySlug("my-project")
+	require.NoError(t, err)
+
+	_, err = client.UpdateWorkspace(1, UpdateWorkspaceRequest{Name: "Renamed"})
+	require.NoError(t, err)
+
+	_, err = client.GetWorkspaceBySlug("my-project")
+	require.NoError(t, err)
+	assert.Equal(t, 2, listings)
+}
+
+// rateLimitedServer answers 429 to the first limited requests, then 200
+func rateLimitedServer(retryAfter string, limited int, calls *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		if *calls <= limited {
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(LoginResponse{Token: "token"})
+	}))
+}
+
+func recordSleeps(t *testing.T) *[]time.Duration {
+	var sleeps []time.Duration
+	sleep = func(d time.Duration) { sleeps = append(sleeps, d) }
+	t.Cleanup(func() { sleep = func(time.Duration) {} })
+	return &sleeps
+}
+
+func TestSend_RateLimited(t *testing.T) {
+	tests := []struct {
+		name       string
+		retryAfter string
+		wait       time.Duration
+		message    string
+	}{
+		{"with Retry-After", "3", 3 * time.Second, "login failed: rate limited, retry in 3s"},
+		{"without Retry-After", "", initialBackoff, "login failed: rate limited, retry later"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name+" fails fast", func(t *testing.T) {
+			var calls int
+			server := rateLimitedServer(tt.retryAfter, 1, &calls)
+			defer server.Close()
+			sleeps := recordSleeps(t)
+
+			_, err := NewWithBaseURL(server.URL).Login("test@example.com", "password123")
+
+			var apiErr *APIError
+			require.ErrorAs(t, err, &apiErr)
+			assert.Equal(t, http.StatusTooManyRequests, apiErr.Status)
+			assert.Equal(t, tt.message, err.Error())
+			if tt.retryAfter != "" {
+				assert.Equal(t, tt.wait, apiErr.RetryAfter)
+			}
+			assert.Equal(t, 1, calls)
+			assert.Empty(t, *sleeps)
+		})
+
+		t.Run(tt.name+" waits and retries", func(t *testing.T) {
+			var calls int
+			server := rateLimitedServer(tt.retryAfter, 1, &calls)
+			defer server.Close()
+			sleeps := recordSleeps(t)
+
+			resp, err := NewWithBaseURL(server.URL, WithRateLimitWait()).Login("test@example.com", "password123")
+			require.NoError(t, err)
+			assert.Equal(t, "token", resp.Token)
+			assert.Equal(t, 2, calls)
+			assert.Equal(t, []time.Duration{tt.wait}, *sleeps)
+		})
+	}
+}
+
+func TestSend_RateLimitWaitStopsAtRetryBudget(t *testing.T) {
+	var calls int
+	server := rateLimitedServer("1", 10, &calls)
+	defer server.Close()
+	recordSleeps(t)
+
+	client := NewWithBaseURL(server.URL, WithRateLimitWait())
+	client.retries = 2
+
+	_, err := client.Login("test@example.com", "password123")
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusTooManyRequests, apiErr.Status)
+	assert.Equal(t, 3, calls)
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	wait, ok := retryAfter(http.Header{"Retry-After": {now.Add(90 * time.Second).Format(http.TimeFormat)}}, now)
+	assert.True(t, ok)
+	assert.Equal(t, 90*time.Second, wait)
+
+	_, ok = retryAfter(http.Header{"Retry-After": {"soon"}}, now)
+	assert.False(t, ok)
+}
+
+func TestClient_RecordsServerClockOffset(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", time.Now().Add(10*time.Minute).UTC().Format(http.TimeFormat))
+		_ = json.NewEncoder(w).Encode(LoginResponse{Token: "token"})
+	}))
+	defer server.Close()
+
+	client := NewWithBaseURL(server.URL)
+	_, known := client.ClockOffset()
+	assert.False(t, known)
+
+	_, err := client.Login("test@example.com", "password123")
+	require.NoError(t, err)
+
+	offset, known := client.ClockOffset()
+	assert.True(t, known)
+	assert.InDelta(t, float64(10*time.Minute), float64(offset), float64(2*time.Second))
+}
+
+func TestSignRequest_ReplayProtection(t *testing.T) {
+	storeTestDevice(t)
+	signingKey, err := storage.New().GetSigningPrivateKey()
+	require.NoError(t, err)
+
+	var requests []*http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		if len(requests) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(ListWorkspacesResponse{})
+	}))
+	defer server.Close()
+
+	c := NewWithBaseURL(server.URL, WithOptions(Options{RetryPolicy: &RetryPolicy{MaxRetries: 1}}))
+	c.replayTolerance = 90 * time.Second
+	_, err = c.ListWorkspaces()
+	require.NoError(t, err)
+
+	require.Len(t, requests, 2)
+	assert.NotEqual(t, requests[0].Header.Get(NonceHeader), requests[1].Header.Get(NonceHeader),
+		"a retry must not reuse the nonce")
+	for _, req := range requests {
+		assert.Len(t, req.Header.Get(NonceHeader), 32)
+		assert.Equal(t, "90", req.Header.Get(SignatureToleranceHeader))
+
+		message := fmt.Sprintf("GET\n%s\n\n%s\n%s\n90", req.URL.Path, req.Header.Get("X-Timestamp"), req.Header.Get(NonceHeader))
+		signature, err := encoding.Decode(req.Header.Get("X-Signature"))
+		require.NoError(t, err)
+		assert.True(t, ed25519.Verify(signingKey.Public().(ed25519.PublicKey), []byte(message), signature))
+	}
+
+	// Without sign_requests the signature stays as it was
+	requests = nil
+	c = NewWithBaseURL(server.URL)
+	_, err = c.ListWorkspaces()
+	require.NoError(t, err)
+	assert.Empty(t, requests[0].Header.Get(NonceHeader))
+}
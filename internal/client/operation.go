@@ -0,0 +1,115 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/routes"
+)
+
+// defaultPollInterval is how often PollOperation checks on an operation
+const defaultPollInterval = 2 * time.Second
+
+// Operation states reported by the server
+const (
+	OperationPending   = "pending"
+	OperationRunning   = "running"
+	OperationSucceeded = "succeeded"
+	OperationFailed    = "failed"
+)
+
+// ErrOperationFailed is returned by PollOperation when the operation ends in failure
+var ErrOperationFailed = errors.New("operation failed")
+
+// Operation is server-side work that carries on after the request that
+// started it was answered with 202 Accepted.
+type Operation struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type OperationResponse struct {
+	Operation Operation `json:"operation"`
+}
+
+// decodeOperation reads the operation from a 202 Accepted response body
+func decodeOperation(body []byte) (*Operation, error) {
+	var opResp OperationResponse
+	if err := json.Unmarshal(body, &opResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal operation: %w", err)
+	}
+	if opResp.Operation.ID == "" {
+		return nil, fmt.Errorf("server accepted the request without an operation ID")
+	}
+	return &opResp.Operation, nil
+}
+
+// GetOperation fetches the current state of an operation
+func (c *Client) GetOperation(operationID string) (*Operation, error) {
+	url := routes.BuildURL(c.baseURL, routes.Operation.GetByID(operationID))
+	req, err := http.NewRequest(routes.GET, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("User-Agent", "initflow-cli/1.0")
+
+	if err := c.signRequest(req, nil); err != nil {
+		return nil, fmt.Errorf("failed to sign request: %w", err)
+	}
+
+	resp, body, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError("get operation", resp, body)
+	}
+
+	var opResp OperationResponse
+	if err := json.Unmarshal(body, &opResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return &opResp.Operation, nil
+}
+
+// PollOperation checks on an operation until it finishes or ctx is done. An
+// operation that fails is returned along with an error matching ErrOperationFailed.
+func (c *Client) PollOperation(ctx context.Context, operationID string) (*Operation, error) {
+	interval := c.pollInterval
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+
+	for {
+		op, err := c.GetOperation(operationID)
+		if err != nil {
+			return nil, err
+		}
+
+		switch op.Status {
+		case OperationSucceeded:
+			return op, nil
+		case OperationFailed:
+			if op.Error == "" {
+				return op, ErrOperationFailed
+			}
+			return op, fmt.Errorf("%w: %s", ErrOperationFailed, op.Error)
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return op, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
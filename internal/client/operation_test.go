@@ -0,0 +1,91 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// operationServer answers successive polls of op-1 with the given states
+func operationServer(t *testing.T, states []Operation, polls *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/operations/op-1", r.URL.Path)
+		state := states[min(*polls, len(states)-1)]
+		*polls++
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(OperationResponse{Operation: state})
+	}))
+}
+
+func TestPollOperation_Succeeds(t *testing.T) {
+	storeTestDevice(t)
+
+	var polls int
+	server := operationServer(t, []Operation{
+		{ID: "op-1", Status: OperationPending},
+		{ID: "op-1", Status: OperationRunning},
+		{ID: "op-1", Status: OperationSucceeded},
+	}, &polls)
+	defer server.Close()
+
+	c := NewWithBaseURL(server.URL, WithPollInterval(time.Millisecond))
+	op, err := c.PollOperation(context.Background(), "op-1")
+	require.NoError(t, err)
+	assert.Equal(t, OperationSucceeded, op.Status)
+	assert.Equal(t, 3, polls)
+}
+
+func TestPollOperation_Fails(t *testing.T) {
+	storeTestDevice(t)
+
+	var polls int
+	server := operationServer(t, []Operation{
+		{ID: "op-1", Status: OperationRunning},
+		{ID: "op-1", Status: OperationFailed, Error: "key already initialized"},
+	}, &polls)
+	defer server.Close()
+
+	c := NewWithBaseURL(server.URL, WithPollInterval(time.Millisecond))
+	op, err := c.PollOperation(context.Background(), "op-1")
+	require.ErrorIs(t, err, ErrOperationFailed)
+	assert.EqualError(t, err, "operation failed: key already initialized")
+	assert.Equal(t, OperationFailed, op.Status)
+}
+
+func TestPollOperation_StopsWithContext(t *testing.T) {
+	storeTestDevice(t)
+
+	var polls int
+	server := operationServer(t, []Operation{{ID: "op-1", Status: OperationRunning}}, &polls)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	c := NewWithBaseURL(server.URL, WithPollInterval(time.Millisecond))
+	op, err := c.PollOperation(ctx, "op-1")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, OperationRunning, op.Status)
+}
+
+func TestInitializeWorkspaceKey_Accepted(t *testing.T) {
+	storeTestDevice(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_ = json.NewEncoder(w).Encode(OperationResponse{Operation: Operation{ID: "op-1", Status: OperationPending}})
+	}))
+	defer server.Close()
+
+	op, err := NewWithBaseURL(server.URL).InitializeWorkspaceKey(1, []byte("wrapped"))
+	require.NoError(t, err)
+	require.NotNil(t, op)
+	assert.Equal(t, "op-1", op.ID)
+}
@@ -0,0 +1,101 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock advances only when slept on
+type fakeClock struct {
+	now    time.Time
+	sleeps []time.Duration
+}
+
+func (f *fakeClock) Now() time.Time { return f.now }
+
+func (f *fakeClock) Sleep(d time.Duration) {
+	f.sleeps = append(f.sleeps, d)
+	f.now = f.now.Add(d)
+}
+
+func (f *fakeClock) options() Options {
+	return Options{Now: f.Now, Sleep: f.Sleep}
+}
+
+func statusServer(status int, calls *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		w.WriteHeader(status)
+	}))
+}
+
+func TestOptions_DefaultBackoffSequence(t *testing.T) {
+	var calls int
+	server := statusServer(http.StatusServiceUnavailable, &calls)
+	defer server.Close()
+
+	clock := &fakeClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	opts := clock.options()
+	opts.RetryPolicy = &RetryPolicy{MaxRetries: 5}
+
+	_, err := NewWithBaseURL(server.URL, WithOptions(opts)).Login("user@example.com", "password")
+	require.Error(t, err)
+
+	assert.Equal(t, 6, calls)
+	assert.Equal(t, []time.Duration{
+		500 * time.Millisecond, time.Second, 2 * time.Second, 4 * time.Second, maxBackoff,
+	}, clock.sleeps)
+	assert.Equal(t, 12500*time.Millisecond, clock.now.Sub(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestOptions_CustomPolicyAndBackoff(t *testing.T) {
+	var calls int
+	server := statusServer(http.StatusConflict, &calls)
+	defer server.Close()
+
+	clock := &fakeClock{}
+	opts := clock.options()
+	opts.RetryPolicy = &RetryPolicy{
+		MaxRetries: 2,
+		Retryable: func(resp *http.Response, err error) bool {
+			return err == nil && resp.StatusCode == http.StatusConflict
+		},
+	}
+	opts.Backoff = func(attempt int) time.Duration { return time.Duration(attempt) * 10 * time.Millisecond }
+
+	_, err := NewWithBaseURL(server.URL, WithOptions(opts)).Login("user@example.com", "password")
+	require.Error(t, err)
+
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, []time.Duration{10 * time.Millisecond, 20 * time.Millisecond}, clock.sleeps)
+}
+
+func TestOptions_NoRetries(t *testing.T) {
+	var calls int
+	server := statusServer(http.StatusBadGateway, &calls)
+	defer server.Close()
+
+	clock := &fakeClock{}
+	opts := clock.options()
+	opts.RetryPolicy = &RetryPolicy{MaxRetries: 0}
+
+	_, err := NewWithBaseURL(server.URL, WithOptions(opts)).Login("user@example.com", "password")
+	require.Error(t, err)
+
+	assert.Equal(t, 1, calls)
+	assert.Empty(t, clock.sleeps)
+}
+
+func TestNewWithOptions_KeepsDefaultsForZeroFields(t *testing.T) {
+	c := NewWithOptions(Options{Backoff: func(int) time.Duration { return time.Minute }})
+
+	assert.Equal(t, time.Minute, c.backoff(1))
+	assert.Equal(t, 2, c.retries)
+	assert.NotNil(t, c.now)
+	assert.NotNil(t, c.sleep)
+}
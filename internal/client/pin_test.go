@@ -0,0 +1,77 @@
+package client
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPinnedTransport(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	pin := CertificatePin(server.Certificate())
+	base := server.Client().Transport.(*http.Transport)
+
+	t.Run("match", func(t *testing.T) {
+		// Pins from openssl are upper case and colon separated
+		colons := strings.ToUpper(pin[:2])
+		for i := 2; i < len(pin); i += 2 {
+			colons += ":" + strings.ToUpper(pin[i:i+2])
+		}
+
+		httpClient := &http.Client{Transport: newPinnedTransport(base, []string{strings.Repeat("0", 64), colons})}
+		resp, err := httpClient.Get(server.URL)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("mismatch", func(t *testing.T) {
+		httpClient := &http.Client{Transport: newPinnedTransport(base, []string{strings.Repeat("0", 64)})}
+		_, err := httpClient.Get(server.URL)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrCertificatePinMismatch), "got %v", err)
+		assert.Contains(t, err.Error(), pin)
+	})
+}
+
+func TestSend_DoesNotRetryPinMismatch(t *testing.T) {
+	var connections atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			connections.Add(1)
+		}
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	c := NewWithBaseURL(server.URL)
+	c.httpClient.Transport = newPinnedTransport(server.Client().Transport.(*http.Transport), []string{strings.Repeat("0", 64)})
+
+	_, err := c.Login("user@example.com", "password")
+	assert.ErrorIs(t, err, ErrCertificatePinMismatch)
+	assert.Equal(t, int32(1), connections.Load(), "a pin mismatch should not be retried")
+}
+
+func TestFetchCertificatePin(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	pin, err := FetchCertificatePin(server.Client(), server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, CertificatePin(server.Certificate()), pin)
+
+	_, err = FetchCertificatePin(server.Client(), "http://example.com")
+	assert.ErrorContains(t, err, "not an https URL")
+}
@@ -0,0 +1,65 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/routes"
+)
+
+// WrappedDeviceKey is a workspace key wrapped to one device's X25519 public key
+type WrappedDeviceKey struct {
+	DeviceID            string `json:"device_id"`
+	WrappedWorkspaceKey string `json:"wrapped_workspace_key"`
+}
+
+// RotateWorkspaceKeyRequest replaces a workspace key. It carries every secret
+// re-encrypted under the new key and the new key wrapped for every device that
+// keeps access. The server applies it only if the workspace is still at
+// FromKeyVersion, so two concurrent rotations can't both succeed.
+type RotateWorkspaceKeyRequest struct {
+	FromKeyVersion int                `json:"from_key_version"`
+	KeyVersion     int                `json:"key_version"`
+	DeviceKeys     []WrappedDeviceKey `json:"device_keys"`
+	Secrets        []SecretUpload     `json:"secrets"`
+}
+
+// RotateWorkspaceKey swaps a workspace to a new key in one atomic request. If
+// the server finishes the work asynchronously, the returned operation tracks
+// it; otherwise it is nil.
+func (c *Client) RotateWorkspaceKey(workspaceID int, rotation RotateWorkspaceKeyRequest) (*Operation, error) {
+	jsonData, err := json.Marshal(rotation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal rotate key request: %w", err)
+	}
+
+	url := routes.BuildURL(c.baseURL, routes.Workspace.RotateKey(workspaceID))
+	req, err := http.NewRequest(routes.POST, url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "initflow-cli/1.0")
+
+	if err := c.signRequest(req, jsonData); err != nil {
+		return nil, fmt.Errorf("failed to sign request: %w", err)
+	}
+
+	resp, body, err := c.send(req)
+	c.invalidateWorkspaces()
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		return nil, nil
+	case http.StatusAccepted:
+		return decodeOperation(body)
+	default:
+		return nil, newAPIError("rotate workspace key", resp, body)
+	}
+}
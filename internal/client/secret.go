@@ -0,0 +1,546 @@
+package client
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/encoding"
+	"github.com/DylanBlakemore/initflow-cli/internal/routes"
+)
+
+// Secret is a secret stored in a workspace. Ciphertext is encrypted with the
+// workspace key on the client; the server never sees the value. Size is the
+// plaintext length in bytes. Every write creates a new Version; KeyVersion is
+// the workspace key version the value was encrypted with. ExpiresAt is set
+// for secrets stored with a TTL. Tags are plaintext labels for sorting
+// secrets, never part of the encrypted value. Checksum is a MAC of the
+// plaintext the client computed, stored alongside and opaque to the server.
+type Secret struct {
+	Key         string   `json:"key"`
+	Environment string   `json:"environment,omitempty"`
+	Version     int      `json:"version,omitempty"`
+	Ciphertext  string   `json:"ciphertext,omitempty"`
+	Size        int      `json:"size"`
+	KeyVersion  int      `json:"key_version"`
+	CreatedAt   string   `json:"created_at,omitempty"`
+	UpdatedAt   string   `json:"updated_at,omitempty"`
+	ExpiresAt   string   `json:"expires_at,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Checksum    string   `json:"checksum,omitempty"`
+}
+
+type PutSecretRequest struct {
+	Ciphertext string `json:"ciphertext"`
+	Size       int    `json:"size"`
+	KeyVersion int    `json:"key_version"`
+	ExpiresAt  string `json:"expires_at,omitempty"`
+	Checksum   string `json:"checksum,omitempty"`
+}
+
+// UpdateSecretTagsRequest adds and removes tags on a secret, leaving its
+// value and version alone
+type UpdateSecretTagsRequest struct {
+	AddTags    []string `json:"add_tags,omitempty"`
+	RemoveTags []string `json:"remove_tags,omitempty"`
+}
+
+type SecretResponse struct {
+	Secret Secret `json:"secret"`
+}
+
+// ListSecretsResponse is one page of secrets; NextCursor asks for the next
+// one and is empty on the last
+type ListSecretsResponse struct {
+	Secrets    []Secret `json:"secrets"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+}
+
+type ListSecretVersionsResponse struct {
+	Versions []Secret `json:"versions"`
+}
+
+// SecretUpload is one already encrypted secret in a batch upload. Environment
+// is only set in key rotations, which span every environment.
+type SecretUpload struct {
+	Key         string `json:"key"`
+	Environment string `json:"environment,omitempty"`
+	Ciphertext  string `json:"ciphertext"`
+	Size        int    `json:"size"`
+	KeyVersion  int    `json:"key_version"`
+	Checksum    string `json:"checksum,omitempty"`
+	ExpiresAt   string `json:"expires_at,omitempty"`
+}
+
+type PutSecretsRequest struct {
+	Secrets []SecretUpload `json:"secrets"`
+}
+
+type ListEnvironmentsResponse struct {
+	Environments []string `json:"environments"`
+}
+
+// PutSecretsResponse lists which keys of a batch upload were new and which replaced a secret
+type PutSecretsResponse struct {
+	Created []string `json:"created"`
+	Updated []string `json:"updated"`
+}
+
+// secretsURL builds the URL of a secrets route, adding the client's
+// environment to query
+func (c *Client) secretsURL(route string, query url.Values) string {
+	if c.environment != "" {
+		if query == nil {
+			query = url.Values{}
+		}
+		query.Set("environment", c.environment)
+	}
+
+	endpoint := routes.BuildURL(c.baseURL, route)
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+	return endpoint
+}
+
+// secretURL is the URL of one secret; the key is escaped so it stays one path segment
+func (c *Client) secretURL(workspaceID int, key string) string {
+	return c.secretsURL(routes.Workspace.SecretByKey(workspaceID, url.PathEscape(key)), nil)
+}
+
+// PutOptions adjust a secret write. IfNotExists only creates the secret;
+// IfVersion only replaces it while Version is still that one. A non-zero
+// ExpiresAt has the server expire the secret then. IdempotencyKey replaces
+// the random one, so a write replayed later is applied once. Checksum is
+// stored with the secret as given.
+type PutOptions struct {
+	IfNotExists    bool
+	IfVersion      int
+	ExpiresAt      time.Time
+	IdempotencyKey string
+	Checksum       string
+}
+
+// DeleteOptions adjust a secret delete; IdempotencyKey is as in PutOptions
+type DeleteOptions struct {
+	IdempotencyKey string
+}
+
+// PutSecret creates or replaces a secret with an already encrypted value
+func (c *Client) PutSecret(workspaceID int, key string, ciphertext []byte, size, keyVersion int) (*Secret, error) {
+	return c.PutSecretWith(workspaceID, key, ciphertext, size, keyVersion, PutOptions{})
+}
+
+// PutSecretWith is PutSecret with opts, whose conditions are sent as
+// If-None-Match or If-Match headers. A write a condition rejects yields an
+// error matching ErrPreconditionFailed.
+func (c *Client) PutSecretWith(workspaceID int, key string, ciphertext []byte, size, keyVersion int, opts PutOptions) (*Secret, error) {
+	putReq := PutSecretRequest{
+		Ciphertext: encoding.Encode(ciphertext),
+		Size:       size,
+		KeyVersion: keyVersion,
+		Checksum:   opts.Checksum,
+	}
+	if !opts.ExpiresAt.IsZero() {
+		putReq.ExpiresAt = opts.ExpiresAt.UTC().Format(time.RFC3339)
+	}
+	jsonData, err := json.Marshal(putReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal secret request: %w", err)
+	}
+
+	req, err := http.NewRequest(routes.PUT, c.secretURL(workspaceID, key), bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "initflow-cli/1.0")
+	if opts.IfNotExists {
+		req.Header.Set("If-None-Match", "*")
+	}
+	if opts.IfVersion > 0 {
+		req.Header.Set("If-Match", strconv.Quote(strconv.Itoa(opts.IfVersion)))
+	}
+	if opts.IdempotencyKey != "" {
+		req.Header.Set(IdempotencyKeyHeader, opts.IdempotencyKey)
+	}
+
+	if err := c.signRequest(req, jsonData); err != nil {
+		return nil, fmt.Errorf("failed to sign request: %w", err)
+	}
+
+	resp, body, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return nil, newAPIError("store secret", resp, body)
+	}
+
+	var secretResp SecretResponse
+	if err := json.Unmarshal(body, &secretResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return &secretResp.Secret, nil
+}
+
+// GetSecret fetches one secret with its ciphertext
+func (c *Client) GetSecret(workspaceID int, key string) (*Secret, error) {
+	req, err := http.NewRequest(routes.GET, c.secretURL(workspaceID, key), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("User-Agent", "initflow-cli/1.0")
+
+	if err := c.signRequest(req, nil); err != nil {
+		return nil, fmt.Errorf("failed to sign request: %w", err)
+	}
+
+	resp, body, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError("get secret", resp, body)
+	}
+
+	var secretResp SecretResponse
+	if err := json.Unmarshal(body, &secretResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return &secretResp.Secret, nil
+}
+
+// UpdateSecretTags adds and removes tags on a secret with a metadata-only
+// PATCH, so the ciphertext isn't uploaded again and no version is created
+func (c *Client) UpdateSecretTags(workspaceID int, key string, update UpdateSecretTagsRequest) (*Secret, error) {
+	jsonData, err := json.Marshal(update)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal secret tags request: %w", err)
+	}
+
+	req, err := http.NewRequest(routes.PATCH, c.secretURL(workspaceID, key), bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "initflow-cli/1.0")
+
+	if err := c.signRequest(req, jsonData); err != nil {
+		return nil, fmt.Errorf("failed to sign request: %w", err)
+	}
+
+	resp, body, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError("update secret tags", resp, body)
+	}
+
+	var secretResp SecretResponse
+	if err := json.Unmarshal(body, &secretResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return &secretResp.Secret, nil
+}
+
+// ListSecrets returns the secrets of a workspace whose keys start with prefix,
+// e.g. "backend/", or all of them when it is empty. Only metadata is needed, so
+// ciphertexts may be left out by the server.
+func (c *Client) ListSecrets(workspaceID int, prefix string) ([]Secret, error) {
+	return c.listSecrets(workspaceID, prefix, false)
+}
+
+// FetchSecrets returns the secrets under prefix with their ciphertexts, for
+// commands that decrypt the whole workspace.
+func (c *Client) FetchSecrets(workspaceID int, prefix string) ([]Secret, error) {
+	return c.listSecrets(workspaceID, prefix, true)
+}
+
+// ListSecretPages calls fn with each page of the secrets under prefix as it
+// arrives, asking for pageSize secrets at a time, or the server's default
+// when it is 0. Ciphertexts are left out. It stops at the first error,
+// from the server or fn.
+func (c *Client) ListSecretPages(workspaceID int, prefix string, pageSize int, fn func([]Secret) error) error {
+	cursor := ""
+	for {
+		secrets, next, err := c.listSecretsPage(workspaceID, prefix, false, pageSize, cursor)
+		if err != nil {
+			return err
+		}
+		if err := fn(secrets); err != nil {
+			return err
+		}
+		if next == "" {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// listSecrets collects every page of the secrets under prefix
+func (c *Client) listSecrets(workspaceID int, prefix string, withCiphertext bool) ([]Secret, error) {
+	var all []Secret
+	cursor := ""
+	for {
+		secrets, next, err := c.listSecretsPage(workspaceID, prefix, withCiphertext, 0, cursor)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, secrets...)
+		if next == "" {
+			return all, nil
+		}
+		cursor = next
+	}
+}
+
+// listSecretsPage fetches the page of secrets at cursor, the first when it is
+// empty, and returns the cursor of the next one
+func (c *Client) listSecretsPage(workspaceID int, prefix string, withCiphertext bool, limit int, cursor string) ([]Secret, string, error) {
+	query := url.Values{}
+	if prefix != "" {
+		query.Set("prefix", prefix)
+	}
+	if withCiphertext {
+		query.Set("include", "ciphertext")
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+	endpoint := c.secretsURL(routes.Workspace.Secrets(workspaceID), query)
+
+	req, err := http.NewRequest(routes.GET, endpoint, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("User-Agent", "initflow-cli/1.0")
+
+	if err := c.signRequest(req, nil); err != nil {
+		return nil, "", fmt.Errorf("failed to sign request: %w", err)
+	}
+
+	resp, body, err := c.send(req)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", newAPIError("list secrets", resp, body)
+	}
+
+	var secretsResp ListSecretsResponse
+	if err := json.Unmarshal(body, &secretsResp); err != nil {
+		return nil, "", fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	// Servers without path support ignore the prefix, so filter here as well
+	secrets := secretsResp.Secrets[:0]
+	for _, secret := range secretsResp.Secrets {
+		if strings.HasPrefix(secret.Key, prefix) {
+			secrets = append(secrets, secret)
+		}
+	}
+	return secrets, secretsResp.NextCursor, nil
+}
+
+// SecretsFingerprint summarises the keys and versions of listed secrets, so a
+// secret added, removed or written gives a different fingerprint. Only
+// metadata is used, so ListSecrets is enough to check for changes.
+func SecretsFingerprint(secrets []Secret) string {
+	entries := make([]string, len(secrets))
+	for i, secret := range secrets {
+		entries[i] = fmt.Sprintf("%s\x00%d\x00%d\x00%s", secret.Key, secret.Version, secret.KeyVersion, secret.UpdatedAt)
+	}
+	sort.Strings(entries)
+
+	sum := sha256.Sum256([]byte(strings.Join(entries, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// DeleteSecret removes a secret from a workspace
+func (c *Client) DeleteSecret(workspaceID int, key string) error {
+	return c.DeleteSecretWith(workspaceID, key, DeleteOptions{})
+}
+
+// DeleteSecretWith is DeleteSecret with opts
+func (c *Client) DeleteSecretWith(workspaceID int, key string, opts DeleteOptions) error {
+	req, err := http.NewRequest(routes.DELETE, c.secretURL(workspaceID, key), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("User-Agent", "initflow-cli/1.0")
+	if opts.IdempotencyKey != "" {
+		req.Header.Set(IdempotencyKeyHeader, opts.IdempotencyKey)
+	}
+
+	if err := c.signRequest(req, nil); err != nil {
+		return fmt.Errorf("failed to sign request: %w", err)
+	}
+
+	resp, body, err := c.send(req)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return newAPIError("delete secret", resp, body)
+	}
+
+	return nil
+}
+
+// PutSecrets creates or replaces several secrets in one request. The server
+// applies the batch atomically.
+func (c *Client) PutSecrets(workspaceID int, secrets []SecretUpload) (*PutSecretsResponse, error) {
+	jsonData, err := json.Marshal(PutSecretsRequest{Secrets: secrets})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal secrets request: %w", err)
+	}
+
+	endpoint := c.secretsURL(routes.Workspace.Secrets(workspaceID), nil)
+	req, err := http.NewRequest(routes.PUT, endpoint, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "initflow-cli/1.0")
+
+	if err := c.signRequest(req, jsonData); err != nil {
+		return nil, fmt.Errorf("failed to sign request: %w", err)
+	}
+
+	resp, body, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError("put secrets", resp, body)
+	}
+
+	var putResp PutSecretsResponse
+	if err := json.Unmarshal(body, &putResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return &putResp, nil
+}
+
+// ListSecretVersions returns every version of a secret, without ciphertexts
+func (c *Client) ListSecretVersions(workspaceID int, key string) ([]Secret, error) {
+	endpoint := c.secretsURL(routes.Workspace.SecretVersions(workspaceID, url.PathEscape(key)), nil)
+	req, err := http.NewRequest(routes.GET, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("User-Agent", "initflow-cli/1.0")
+
+	if err := c.signRequest(req, nil); err != nil {
+		return nil, fmt.Errorf("failed to sign request: %w", err)
+	}
+
+	resp, body, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError("list secret versions", resp, body)
+	}
+
+	var versionsResp ListSecretVersionsResponse
+	if err := json.Unmarshal(body, &versionsResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return versionsResp.Versions, nil
+}
+
+// GetSecretVersion fetches one earlier version of a secret with its ciphertext
+func (c *Client) GetSecretVersion(workspaceID int, key string, version int) (*Secret, error) {
+	endpoint := c.secretsURL(routes.Workspace.SecretVersion(workspaceID, url.PathEscape(key), version), nil)
+	req, err := http.NewRequest(routes.GET, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("User-Agent", "initflow-cli/1.0")
+
+	if err := c.signRequest(req, nil); err != nil {
+		return nil, fmt.Errorf("failed to sign request: %w", err)
+	}
+
+	resp, body, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError("get secret version", resp, body)
+	}
+
+	var secretResp SecretResponse
+	if err := json.Unmarshal(body, &secretResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return &secretResp.Secret, nil
+}
+
+// ListEnvironments returns the names of the environments of a workspace that
+// hold secrets, not counting the default one
+func (c *Client) ListEnvironments(workspaceID int) ([]string, error) {
+	url := routes.BuildURL(c.baseURL, routes.Workspace.Environments(workspaceID))
+	req, err := http.NewRequest(routes.GET, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("User-Agent", "initflow-cli/1.0")
+
+	if err := c.signRequest(req, nil); err != nil {
+		return nil, fmt.Errorf("failed to sign request: %w", err)
+	}
+
+	resp, body, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError("list environments", resp, body)
+	}
+
+	var envResp ListEnvironmentsResponse
+	if err := json.Unmarshal(body, &envResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return envResp.Environments, nil
+}
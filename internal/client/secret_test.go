@@ -0,0 +1,175 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/routes"
+)
+
+func TestSecretRequestsCarryEnvironment(t *testing.T) {
+	storeTestDevice(t)
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.RequestURI())
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case routes.GET:
+			_ = json.NewEncoder(w).Encode(ListSecretsResponse{Secrets: []Secret{{Key: "API_KEY", Environment: "staging"}}})
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	staging := NewWithBaseURL(server.URL, WithEnvironment("staging"))
+	secrets, err := staging.FetchSecrets(1, "")
+	require.NoError(t, err)
+	assert.Equal(t, "staging", secrets[0].Environment)
+	require.NoError(t, staging.DeleteSecret(1, "a/b"))
+
+	_, err = NewWithBaseURL(server.URL).ListSecrets(1, "")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"GET /api/v1/workspaces/1/secrets?environment=staging&include=ciphertext",
+		"DELETE /api/v1/workspaces/1/secrets/a%2Fb?environment=staging",
+		"GET /api/v1/workspaces/1/secrets",
+	}, requests)
+}
+
+func TestListSecretsUnderPrefix(t *testing.T) {
+	storeTestDevice(t)
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		w.Header().Set("Content-Type", "application/json")
+		// Answer like a server that ignores the prefix
+		_ = json.NewEncoder(w).Encode(ListSecretsResponse{Secrets: []Secret{
+			{Key: "API_KEY"}, {Key: "backend/db/PASSWORD"}, {Key: "backendX"},
+		}})
+	}))
+	defer server.Close()
+
+	secrets, err := NewWithBaseURL(server.URL).ListSecrets(1, "backend/")
+	require.NoError(t, err)
+	assert.Equal(t, "prefix=backend%2F", query)
+	require.Len(t, secrets, 1)
+	assert.Equal(t, "backend/db/PASSWORD", secrets[0].Key)
+}
+
+func TestListSecretsFollowsCursors(t *testing.T) {
+	storeTestDevice(t)
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("cursor") == "" {
+			_ = json.NewEncoder(w).Encode(ListSecretsResponse{Secrets: []Secret{{Key: "A"}, {Key: "B"}}, NextCursor: "c2"})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(ListSecretsResponse{Secrets: []Secret{{Key: "C"}}})
+	}))
+	defer server.Close()
+	c := NewWithBaseURL(server.URL)
+
+	secrets, err := c.ListSecrets(1, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"", "cursor=c2"}, queries)
+	require.Len(t, secrets, 3)
+
+	queries = nil
+	var pages [][]Secret
+	err = c.ListSecretPages(1, "", 2, func(page []Secret) error {
+		pages = append(pages, page)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"limit=2", "cursor=c2&limit=2"}, queries)
+	assert.Len(t, pages, 2)
+
+	queries = nil
+	stop := errors.New("stop")
+	err = c.ListSecretPages(1, "", 2, func([]Secret) error { return stop })
+	assert.ErrorIs(t, err, stop)
+	assert.Len(t, queries, 1)
+}
+
+func TestUpdateSecretTags(t *testing.T) {
+	storeTestDevice(t)
+	var method string
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(SecretResponse{Secret: Secret{Key: "API_KEY", Tags: []string{"prod"}}})
+	}))
+	defer server.Close()
+
+	secret, err := NewWithBaseURL(server.URL).UpdateSecretTags(1, "API_KEY", UpdateSecretTagsRequest{AddTags: []string{"prod"}})
+	require.NoError(t, err)
+	assert.Equal(t, routes.PATCH, method)
+	assert.Equal(t, map[string]any{"add_tags": []any{"prod"}}, body)
+	assert.Equal(t, []string{"prod"}, secret.Tags)
+}
+
+func TestPutSecretWith(t *testing.T) {
+	storeTestDevice(t)
+	var ifNoneMatch, ifMatch, idempotencyKey string
+	var body PutSecretRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ifNoneMatch, ifMatch = r.Header.Get("If-None-Match"), r.Header.Get("If-Match")
+		idempotencyKey = r.Header.Get(IdempotencyKeyHeader)
+		body = PutSecretRequest{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusPreconditionFailed)
+		_ = json.NewEncoder(w).Encode(ErrorResponse{Error: "precondition_failed", Message: "Secret changed"})
+	}))
+	defer server.Close()
+	c := NewWithBaseURL(server.URL)
+
+	_, err := c.PutSecretWith(1, "API_KEY", []byte("sealed"), 6, 1, PutOptions{IfNotExists: true})
+	assert.ErrorIs(t, err, ErrPreconditionFailed)
+	assert.Equal(t, "*", ifNoneMatch)
+	assert.Empty(t, ifMatch)
+
+	_, err = c.PutSecretWith(1, "API_KEY", []byte("sealed"), 6, 1, PutOptions{IfVersion: 3})
+	assert.ErrorIs(t, err, ErrPreconditionFailed)
+	assert.Empty(t, ifNoneMatch)
+	assert.Equal(t, `"3"`, ifMatch)
+	assert.Empty(t, body.ExpiresAt)
+
+	expiresAt := time.Date(2025, 10, 2, 12, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+	_, _ = c.PutSecretWith(1, "API_KEY", []byte("sealed"), 6, 1, PutOptions{ExpiresAt: expiresAt})
+	assert.Equal(t, "2025-10-02T10:00:00Z", body.ExpiresAt)
+	assert.Empty(t, ifNoneMatch)
+	assert.Empty(t, ifMatch)
+
+	_, _ = c.PutSecretWith(1, "API_KEY", []byte("sealed"), 6, 1, PutOptions{IdempotencyKey: "queued-1", Checksum: "hmac-sha256:ab"})
+	assert.Equal(t, "queued-1", idempotencyKey)
+	assert.Equal(t, "hmac-sha256:ab", body.Checksum)
+	_ = c.DeleteSecretWith(1, "API_KEY", DeleteOptions{IdempotencyKey: "queued-2"})
+	assert.Equal(t, "queued-2", idempotencyKey)
+}
+
+func TestSecretsFingerprint(t *testing.T) {
+	secrets := []Secret{{Key: "API_KEY", Version: 1, KeyVersion: 1}, {Key: "DB_URL", Version: 3, KeyVersion: 1}}
+	fingerprint := SecretsFingerprint(secrets)
+
+	reordered := []Secret{secrets[1], secrets[0]}
+	assert.Equal(t, fingerprint, SecretsFingerprint(reordered), "order should not matter")
+
+	written := []Secret{secrets[0], {Key: "DB_URL", Version: 4, KeyVersion: 1}}
+	assert.NotEqual(t, fingerprint, SecretsFingerprint(written))
+	assert.NotEqual(t, fingerprint, SecretsFingerprint(secrets[:1]))
+	assert.NotEqual(t, fingerprint, SecretsFingerprint(append(secrets, Secret{Key: "NEW", Version: 1})))
+}
@@ -0,0 +1,136 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/routes"
+)
+
+// DeviceCodeGrantType identifies the OAuth device authorization grant when
+// exchanging a device code for a token
+const DeviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+var (
+	// ErrAuthorizationPending is returned by PollSSOLogin until the user has
+	// approved the login in the browser
+	ErrAuthorizationPending = errors.New("authorization pending")
+	// ErrSlowDown is returned by PollSSOLogin when polling too often; wait
+	// five seconds longer between polls from then on
+	ErrSlowDown = errors.New("polling too fast")
+	// ErrSSODenied is returned by PollSSOLogin when the user declined the login
+	ErrSSODenied = errors.New("login was denied in the browser")
+	// ErrSSOExpired is returned by PollSSOLogin once the device code has expired
+	ErrSSOExpired = errors.New("login code expired")
+)
+
+// Error codes of the OAuth device authorization grant (RFC 8628)
+const (
+	errorCodeAuthorizationPending = "authorization_pending"
+	errorCodeSlowDown             = "slow_down"
+	errorCodeAccessDenied         = "access_denied"
+	errorCodeExpiredToken         = "expired_token"
+)
+
+type DeviceCodeRequest struct {
+	LoginHint string `json:"login_hint,omitempty"`
+}
+
+// DeviceCodeResponse is the code the user enters at VerificationURI to
+// approve the login with their identity provider
+type DeviceCodeResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete,omitempty"`
+	ExpiresIn               int    `json:"expires_in"` // seconds
+	Interval                int    `json:"interval,omitempty"`
+}
+
+type DeviceTokenRequest struct {
+	GrantType  string `json:"grant_type"`
+	DeviceCode string `json:"device_code"`
+}
+
+// StartSSOLogin begins a single sign-on login through the account's identity
+// provider. loginHint, usually an email, helps the server pick the provider
+// and may be empty.
+func (c *Client) StartSSOLogin(loginHint string) (*DeviceCodeResponse, error) {
+	jsonData, err := json.Marshal(DeviceCodeRequest{LoginHint: loginHint})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal device code request: %w", err)
+	}
+
+	url := routes.BuildURL(c.baseURL, routes.DeviceCodeRoute.Path)
+	req, err := http.NewRequest(routes.DeviceCodeRoute.Method, url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "initflow-cli/1.0")
+
+	resp, body, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError("start SSO login", resp, body)
+	}
+
+	var codeResp DeviceCodeResponse
+	if err := json.Unmarshal(body, &codeResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return &codeResp, nil
+}
+
+// PollSSOLogin asks whether the login started with deviceCode was approved.
+// Until it is, it returns ErrAuthorizationPending or ErrSlowDown.
+func (c *Client) PollSSOLogin(deviceCode string) (*LoginResponse, error) {
+	jsonData, err := json.Marshal(DeviceTokenRequest{GrantType: DeviceCodeGrantType, DeviceCode: deviceCode})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal token request: %w", err)
+	}
+
+	url := routes.BuildURL(c.baseURL, routes.DeviceTokenRoute.Path)
+	req, err := http.NewRequest(routes.DeviceTokenRoute.Method, url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "initflow-cli/1.0")
+
+	resp, body, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		apiErr := newAPIError("SSO login", resp, body)
+		switch apiErr.Code {
+		case errorCodeAuthorizationPending:
+			return nil, ErrAuthorizationPending
+		case errorCodeSlowDown:
+			return nil, ErrSlowDown
+		case errorCodeAccessDenied:
+			return nil, ErrSSODenied
+		case errorCodeExpiredToken:
+			return nil, ErrSSOExpired
+		}
+		return nil, apiErr
+	}
+
+	var loginResp LoginResponse
+	if err := json.Unmarshal(body, &loginResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return &loginResp, nil
+}
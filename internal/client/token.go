@@ -0,0 +1,142 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/routes"
+)
+
+// Access token scopes
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+)
+
+// ScopedToken is an access token limited to one workspace and scope. Token
+// holds the secret itself and is only set in the response that created it.
+type ScopedToken struct {
+	ID            string `json:"id"`
+	WorkspaceID   int    `json:"workspace_id"`
+	WorkspaceSlug string `json:"workspace_slug,omitempty"`
+	Scope         string `json:"scope"`
+	CreatedAt     string `json:"created_at,omitempty"`
+	ExpiresAt     string `json:"expires_at,omitempty"`
+	Token         string `json:"token,omitempty"`
+}
+
+// CreateScopedTokenRequest mints a token. WrappedWorkspaceKey is the workspace
+// key wrapped to a keypair whose private half only the token's holder gets, so
+// automation can decrypt secrets; GetDeviceWorkspaceKey returns it when
+// called with the token.
+type CreateScopedTokenRequest struct {
+	WorkspaceID         int    `json:"workspace_id"`
+	Scope               string `json:"scope"`
+	TTLSeconds          int64  `json:"ttl_seconds"`
+	WrappedWorkspaceKey string `json:"wrapped_workspace_key,omitempty"`
+	KeyVersion          int    `json:"key_version,omitempty"`
+}
+
+type ScopedTokenResponse struct {
+	Token ScopedToken `json:"token"`
+}
+
+type ListScopedTokensResponse struct {
+	Tokens []ScopedToken `json:"tokens"`
+}
+
+// CreateScopedToken mints an access token for one workspace
+func (c *Client) CreateScopedToken(tokenReq CreateScopedTokenRequest) (*ScopedToken, error) {
+	jsonData, err := json.Marshal(tokenReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal create token request: %w", err)
+	}
+
+	url := routes.BuildURL(c.baseURL, routes.Tokens)
+	req, err := http.NewRequest(routes.POST, url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "initflow-cli/1.0")
+
+	if err := c.signRequest(req, jsonData); err != nil {
+		return nil, fmt.Errorf("failed to sign request: %w", err)
+	}
+
+	resp, body, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return nil, newAPIError("create token", resp, body)
+	}
+
+	var tokenResp ScopedTokenResponse
+	if err := json.Unmarshal(body, &tokenResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return &tokenResp.Token, nil
+}
+
+// ListScopedTokens lists the access tokens the caller has created
+func (c *Client) ListScopedTokens() ([]ScopedToken, error) {
+	url := routes.BuildURL(c.baseURL, routes.Tokens)
+	req, err := http.NewRequest(routes.GET, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("User-Agent", "initflow-cli/1.0")
+
+	if err := c.signRequest(req, nil); err != nil {
+		return nil, fmt.Errorf("failed to sign request: %w", err)
+	}
+
+	resp, body, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError("list tokens", resp, body)
+	}
+
+	var listResp ListScopedTokensResponse
+	if err := json.Unmarshal(body, &listResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return listResp.Tokens, nil
+}
+
+// RevokeScopedToken invalidates an access token
+func (c *Client) RevokeScopedToken(tokenID string) error {
+	url := routes.BuildURL(c.baseURL, routes.Token.GetByID(tokenID))
+	req, err := http.NewRequest(routes.DELETE, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("User-Agent", "initflow-cli/1.0")
+
+	if err := c.signRequest(req, nil); err != nil {
+		return fmt.Errorf("failed to sign request: %w", err)
+	}
+
+	resp, body, err := c.send(req)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return newAPIError("revoke token", resp, body)
+	}
+
+	return nil
+}
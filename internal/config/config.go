@@ -1,54 +1,218 @@
 package config
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/fsutil"
+	"github.com/DylanBlakemore/initflow-cli/internal/output"
 )
 
 const (
 	configDirPermissions = 0750
+
+	MaxTimeout = 10 * time.Minute
+	MaxRetries = 10
+
+	MaxSignatureTolerance = time.Hour
+
+	MaxConcurrency = 64
 )
 
+// Values of the credential_store setting
+const (
+	CredentialStoreKeychain = "keychain"
+	CredentialStoreFile     = "file"
+)
+
+// DefaultProfile is the profile used when none is selected. Its credentials
+// live under the plain service name, as they did before profiles existed.
+const DefaultProfile = "default"
+
+// profilePattern matches profile names, which become part of the keyring
+// service name
+var profilePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
 type Config struct {
-	APIBaseURL  string `mapstructure:"api_base_url"`
-	ServiceName string `mapstructure:"service_name"`
+	APIBaseURL   string        `mapstructure:"api_base_url"`
+	ServiceName  string        `mapstructure:"service_name"`
+	DefaultEmail string        `mapstructure:"default_email"`
+	Timeout      time.Duration `mapstructure:"timeout"`
+	Retries      int           `mapstructure:"retries"`
+	LogFile      string        `mapstructure:"log_file"`
+
+	// Concurrency is how many secrets bulk commands encrypt or decrypt at once
+	Concurrency int `mapstructure:"concurrency"`
+
+	// CredentialStore is where tokens and keys are kept: the OS keychain, or
+	// a private file for machines without one
+	CredentialStore string `mapstructure:"credential_store"`
+	// EncryptKeys seals private and workspace keys under a passphrase
+	EncryptKeys bool `mapstructure:"encrypt_keys"`
+
+	// DefaultWorkspace is used when a command's --workspace flag is not
+	// given; Output is the default for --output
+	DefaultWorkspace string `mapstructure:"default_workspace"`
+	Output           string `mapstructure:"output"`
+
+	// Profile selects a separate set of credentials on this machine;
+	// Profiles lists the ones created with 'profile use'
+	Profile  string   `mapstructure:"profile"`
+	Profiles []string `mapstructure:"profiles"`
+
+	// SignRequests adds a nonce to every request signature; the server
+	// accepts each nonce once, within SignatureTolerance of its timestamp
+	SignRequests       bool          `mapstructure:"sign_requests"`
+	SignatureTolerance time.Duration `mapstructure:"signature_tolerance"`
+
+	// LintSkip names secrets lint rules that aren't run
+	LintSkip []string `mapstructure:"lint_skip"`
+
+	// PinnedCertSHA256 lists the accepted SHA-256 hashes of the API server's
+	// public key; when set, connections to any other server key fail
+	PinnedCertSHA256 []string `mapstructure:"pinned_cert_sha256"`
 }
 
 var globalConfig *Config
 
+// explicitFile is the config file named with --config, if any
+var explicitFile string
+
+// configFileNames are searched in order in each config directory
+var configFileNames = []string{"config.yaml", "config.yml", "config.json"}
+
 func DefaultConfig() *Config {
 	return &Config{
 		APIBaseURL:  "https://api.initflow.com",
 		ServiceName: "initflow-cli",
+		Timeout:     30 * time.Second,
+		Retries:     2,
+		Concurrency: 8,
+
+		CredentialStore: CredentialStoreKeychain,
+
+		SignatureTolerance: 5 * time.Minute,
 	}
 }
 
-func InitConfig() error {
-	viper.SetConfigName("config")
-	viper.SetConfigType("yaml")
+// Validate checks that request limits are within sane bounds
+func (c *Config) Validate() error {
+	if c.Timeout <= 0 || c.Timeout > MaxTimeout {
+		return fmt.Errorf("timeout must be greater than 0 and at most %s, got %s", MaxTimeout, c.Timeout)
+	}
 
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return fmt.Errorf("failed to get user home directory: %w", err)
+	if c.Retries < 0 || c.Retries > MaxRetries {
+		return fmt.Errorf("retries must be between 0 and %d, got %d", MaxRetries, c.Retries)
+	}
+
+	if c.Concurrency < 1 || c.Concurrency > MaxConcurrency {
+		return fmt.Errorf("concurrency must be between 1 and %d, got %d", MaxConcurrency, c.Concurrency)
+	}
+
+	if c.SignRequests && (c.SignatureTolerance <= 0 || c.SignatureTolerance > MaxSignatureTolerance) {
+		return fmt.Errorf("signature_tolerance must be greater than 0 and at most %s, got %s",
+			MaxSignatureTolerance, c.SignatureTolerance)
+	}
+
+	switch c.CredentialStore {
+	case "", CredentialStoreKeychain, CredentialStoreFile:
+	default:
+		return fmt.Errorf("credential_store must be %s or %s, got %q",
+			CredentialStoreKeychain, CredentialStoreFile, c.CredentialStore)
 	}
 
-	configDir := filepath.Join(home, ".initflow")
-	viper.AddConfigPath(configDir)
-	viper.AddConfigPath(".")
+	if c.Output != "" {
+		if _, err := output.Parse(c.Output); err != nil {
+			return err
+		}
+	}
 
+	if c.Profile != "" {
+		if err := ValidateProfile(c.Profile); err != nil {
+			return err
+		}
+	}
+
+	for _, pin := range c.PinnedCertSHA256 {
+		if !validPin(pin) {
+			return fmt.Errorf("pinned_cert_sha256 must be 64 hex characters (run 'initflow --print-pin'), got %q", pin)
+		}
+	}
+	if len(c.PinnedCertSHA256) > 0 && !strings.HasPrefix(strings.ToLower(c.APIBaseURL), "https://") {
+		// Pins are only checked in TLS handshakes, so they'd protect nothing
+		return fmt.Errorf("pinned_cert_sha256 needs an https api_base_url, got %q", c.APIBaseURL)
+	}
+
+	return nil
+}
+
+// ValidateProfile checks that name can be used as a profile name
+func ValidateProfile(name string) error {
+	if !profilePattern.MatchString(name) {
+		return fmt.Errorf("profile must be 1-32 lowercase letters, digits, - or _, starting with a letter or digit, got %q", name)
+	}
+	return nil
+}
+
+// ActiveProfile returns the selected profile, or DefaultProfile
+func (c *Config) ActiveProfile() string {
+	if c.Profile == "" {
+		return DefaultProfile
+	}
+	return c.Profile
+}
+
+// validPin reports whether pin is a hex SHA-256, optionally colon separated
+func validPin(pin string) bool {
+	decoded, err := hex.DecodeString(strings.ReplaceAll(strings.TrimSpace(pin), ":", ""))
+	return err == nil && len(decoded) == sha256.Size
+}
+
+// SetFile makes InitConfig read path instead of searching for a config file.
+// An empty path restores the search.
+func SetFile(path string) {
+	explicitFile = path
+}
+
+func InitConfig() error {
 	defaults := DefaultConfig()
 	viper.SetDefault("api_base_url", defaults.APIBaseURL)
 	viper.SetDefault("service_name", defaults.ServiceName)
+	viper.SetDefault("timeout", defaults.Timeout)
+	viper.SetDefault("retries", defaults.Retries)
+	viper.SetDefault("concurrency", defaults.Concurrency)
+	viper.SetDefault("credential_store", defaults.CredentialStore)
+	viper.SetDefault("signature_tolerance", defaults.SignatureTolerance)
 
 	viper.SetEnvPrefix("INITFLOW")
 	viper.AutomaticEnv()
+	// Keys without a default are only read from the environment once bound
+	bindEnv()
 
-	if err := viper.ReadInConfig(); err != nil {
-		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
-			return fmt.Errorf("failed to read config file: %w", err)
+	configFile, err := findConfigFile()
+	if err != nil {
+		return err
+	}
+	if configFile != "" {
+		settings, err := readSettings(configFile)
+		if err != nil {
+			return err
+		}
+		if err := viper.MergeConfigMap(settings); err != nil {
+			return fmt.Errorf("failed to load config file %s: %w", configFile, err)
 		}
 	}
 
@@ -57,7 +221,101 @@ func InitConfig() error {
 		return fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
-	return nil
+	return globalConfig.Validate()
+}
+
+// findConfigFile returns the --config file, or the first config file found in
+// the config directory or the working directory. It returns "" when there is none.
+func findConfigFile() (string, error) {
+	if explicitFile != "" {
+		if _, err := os.Stat(explicitFile); err != nil {
+			return "", fmt.Errorf("failed to read config file: %w", err)
+		}
+		return explicitFile, nil
+	}
+
+	configDir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+
+	for _, dir := range []string{configDir, "."} {
+		for _, name := range configFileNames {
+			path := filepath.Join(dir, name)
+			if _, err := os.Stat(path); err == nil {
+				return path, nil
+			}
+		}
+	}
+
+	return "", nil
+}
+
+// fileFormat picks the config format from the file extension
+func fileFormat(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return "yaml", nil
+	case ".json":
+		return "json", nil
+	default:
+		return "", fmt.Errorf("unsupported config file extension %q for %s (use .yaml, .yml or .json)",
+			filepath.Ext(path), path)
+	}
+}
+
+// readSettings parses a YAML or JSON config file. A missing file has no settings.
+func readSettings(path string) (map[string]interface{}, error) {
+	format, err := fileFormat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := map[string]interface{}{}
+	data, err := os.ReadFile(path) // #nosec G304 - path is the CLI's own config file
+	if os.IsNotExist(err) {
+		return settings, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	if format == "json" {
+		if len(bytes.TrimSpace(data)) == 0 {
+			return settings, nil
+		}
+		if err := json.Unmarshal(data, &settings); err != nil {
+			return nil, jsonParseError(path, data, err)
+		}
+		return settings, nil
+	}
+
+	// yaml.v3 errors already name the line
+	if err := yaml.Unmarshal(data, &settings); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return settings, nil
+}
+
+// jsonParseError adds the line of a JSON syntax or type error to err
+func jsonParseError(path string, data []byte, err error) error {
+	var offset int64 = -1
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset
+	}
+
+	if offset < 0 || offset > int64(len(data)) {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	line := bytes.Count(data[:offset], []byte("\n")) + 1
+	return fmt.Errorf("failed to parse config file %s: line %d: %w", path, line, err)
 }
 
 func Get() *Config {
@@ -67,8 +325,10 @@ func Get() *Config {
 	return globalConfig
 }
 
+// Set overrides a setting for the current command, as flags do
 func Set(key string, value interface{}) error {
 	viper.Set(key, value)
+	overridden[key] = true
 
 	if err := viper.Unmarshal(globalConfig); err != nil {
 		return fmt.Errorf("failed to update config: %w", err)
@@ -78,16 +338,95 @@ func Set(key string, value interface{}) error {
 }
 
 func Save() error {
+	configFile, err := ensureConfigFile()
+	if err != nil {
+		return err
+	}
+
+	return writeSettings(configFile, viper.AllSettings())
+}
+
+// Persist saves a single setting to the config file. Other settings already in
+// the file are kept, and flag or environment overrides are not written out.
+// The setting is loaded as a file value, so those overrides still win for the
+// current command.
+func Persist(key string, value interface{}) error {
+	configFile, err := ensureConfigFile()
+	if err != nil {
+		return err
+	}
+
+	settings, err := readSettings(configFile)
+	if err != nil {
+		return err
+	}
+
+	settings[key] = value
+	if err := writeSettings(configFile, settings); err != nil {
+		return err
+	}
+
+	if err := viper.MergeConfigMap(map[string]interface{}{key: value}); err != nil {
+		return fmt.Errorf("failed to update config: %w", err)
+	}
+	if globalConfig != nil {
+		if err := viper.Unmarshal(globalConfig); err != nil {
+			return fmt.Errorf("failed to update config: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Dir returns the directory holding the CLI's config file
+func Dir() (string, error) {
 	home, err := os.UserHomeDir()
 	if err != nil {
-		return fmt.Errorf("failed to get user home directory: %w", err)
+		return "", fmt.Errorf("failed to get user home directory: %w", err)
+	}
+
+	return filepath.Join(home, ".initflow"), nil
+}
+
+// ensureConfigFile returns the file settings are saved to: the --config file,
+// else the existing config in the config directory, else a new config.yaml there.
+func ensureConfigFile() (string, error) {
+	if explicitFile != "" {
+		return explicitFile, nil
+	}
+
+	configDir, err := Dir()
+	if err != nil {
+		return "", err
 	}
 
-	configDir := filepath.Join(home, ".initflow")
 	if err := os.MkdirAll(configDir, configDirPermissions); err != nil {
-		return fmt.Errorf("failed to create config directory: %w", err)
+		return "", fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	for _, name := range configFileNames {
+		path := filepath.Join(configDir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+
+	return filepath.Join(configDir, configFileNames[0]), nil
+}
+
+// writeSettings saves settings in the format matching the file extension
+func writeSettings(configFile string, settings map[string]interface{}) error {
+	format, err := fileFormat(configFile)
+	if err != nil {
+		return err
 	}
 
-	configFile := filepath.Join(configDir, "config.yaml")
-	return viper.WriteConfigAs(configFile)
+	return fsutil.WriteFileAtomic(configFile, fsutil.PrivateFilePermissions, func(w io.Writer) error {
+		if format == "json" {
+			encoder := json.NewEncoder(w)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(settings)
+		}
+		return yaml.NewEncoder(w).Encode(settings)
+	})
 }
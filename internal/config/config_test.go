@@ -1,9 +1,12 @@
 package config
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
@@ -156,3 +159,196 @@ func TestGet_BeforeInit(t *testing.T) {
 	cfg := Get()
 	assert.Equal(t, "https://api.initflow.com", cfg.APIBaseURL)
 }
+
+func TestPersist_KeepsExistingSettings(t *testing.T) {
+	// Reset viper for clean test
+	viper.Reset()
+
+	tmpDir := t.TempDir()
+	configDir := filepath.Join(tmpDir, ".initflow")
+	require.NoError(t, os.MkdirAll(configDir, 0750))
+
+	configFile := filepath.Join(configDir, "config.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte(`api_base_url: "http://localhost:4000"`), 0600))
+
+	originalHome := os.Getenv("HOME")
+	_ = os.Setenv("HOME", tmpDir)
+	defer func() { _ = os.Setenv("HOME", originalHome) }()
+
+	require.NoError(t, InitConfig())
+
+	// A flag-style override must not leak into the file
+	require.NoError(t, Set("service_name", "override"))
+
+	require.NoError(t, Persist("default_email", "user@example.com"))
+	assert.Equal(t, "user@example.com", Get().DefaultEmail)
+
+	content, err := os.ReadFile(configFile) // #nosec G304 - test file path is controlled
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "api_base_url: http://localhost:4000")
+	assert.Contains(t, string(content), "default_email: user@example.com")
+	assert.NotContains(t, string(content), "override")
+
+	// Persisting a setting overridden by a flag or the environment saves it
+	// without taking the override's place
+	require.NoError(t, Persist("service_name", "persisted"))
+	assert.Equal(t, "override", Get().ServiceName)
+	t.Setenv("INITFLOW_DEFAULT_EMAIL", "env@example.com")
+	require.NoError(t, Persist("default_email", "other@example.com"))
+	assert.Equal(t, "env@example.com", Get().DefaultEmail)
+	content, err = os.ReadFile(configFile) // #nosec G304 - test file path is controlled
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "service_name: persisted")
+	assert.Contains(t, string(content), "default_email: other@example.com")
+}
+
+func TestInitConfig_JSONAndYAMLAreEquivalent(t *testing.T) {
+	files := map[string]string{
+		"config.yaml": "api_base_url: http://localhost:4000\ndefault_email: dev@example.com\ntimeout: 45s\nretries: 4\n",
+		"config.json": `{"api_base_url": "http://localhost:4000", "default_email": "dev@example.com", "timeout": "45s", "retries": 4}`,
+	}
+
+	resolved := map[string]Config{}
+	for name, content := range files {
+		viper.Reset()
+		tmpDir := t.TempDir()
+		configDir := filepath.Join(tmpDir, ".initflow")
+		require.NoError(t, os.MkdirAll(configDir, 0750))
+		require.NoError(t, os.WriteFile(filepath.Join(configDir, name), []byte(content), 0600))
+		t.Setenv("HOME", tmpDir)
+		t.Setenv("INITFLOW_SERVICE_NAME", "from-env")
+
+		require.NoError(t, InitConfig(), name)
+		resolved[name] = *Get()
+	}
+
+	assert.Equal(t, resolved["config.yaml"], resolved["config.json"])
+	assert.Equal(t, 45*time.Second, resolved["config.json"].Timeout)
+	assert.Equal(t, 4, resolved["config.json"].Retries)
+	assert.Equal(t, "from-env", resolved["config.json"].ServiceName)
+}
+
+func TestInitConfig_FileErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		content  string
+		contains []string
+	}{
+		{"malformed json", "custom.json", "{\n  \"api_base_url\": \"http://localhost:4000\",\n  \"retries\" 4\n}",
+			[]string{"custom.json", "line 3"}},
+		{"malformed yaml", "custom.yaml", "api_base_url: http://localhost:4000\nretries: [4\n",
+			[]string{"custom.yaml", "line"}},
+		{"unknown extension", "custom.toml", `api_base_url = "http://localhost:4000"`,
+			[]string{"custom.toml", `unsupported config file extension ".toml"`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			t.Setenv("HOME", t.TempDir())
+
+			path := filepath.Join(t.TempDir(), tt.file)
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0600))
+			SetFile(path)
+			t.Cleanup(func() { SetFile("") })
+
+			err := InitConfig()
+			require.Error(t, err)
+			for _, want := range tt.contains {
+				assert.Contains(t, err.Error(), want)
+			}
+		})
+	}
+}
+
+func TestPersist_KeepsJSONFormat(t *testing.T) {
+	viper.Reset()
+
+	tmpDir := t.TempDir()
+	configDir := filepath.Join(tmpDir, ".initflow")
+	require.NoError(t, os.MkdirAll(configDir, 0750))
+	configFile := filepath.Join(configDir, "config.json")
+	require.NoError(t, os.WriteFile(configFile, []byte(`{"api_base_url": "http://localhost:4000"}`), 0600))
+	t.Setenv("HOME", tmpDir)
+
+	require.NoError(t, InitConfig())
+	require.NoError(t, Persist("default_email", "user@example.com"))
+
+	content, err := os.ReadFile(configFile) // #nosec G304 - test file path is controlled
+	require.NoError(t, err)
+
+	var settings map[string]string
+	require.NoError(t, json.Unmarshal(content, &settings))
+	assert.Equal(t, map[string]string{
+		"api_base_url":  "http://localhost:4000",
+		"default_email": "user@example.com",
+	}, settings)
+	assert.NoFileExists(t, filepath.Join(configDir, "config.yaml"))
+}
+
+func TestValidate_SignatureTolerance(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.SignatureTolerance = 0
+	assert.NoError(t, cfg.Validate(), "tolerance only matters when signing is on")
+
+	cfg.SignRequests = true
+	assert.ErrorContains(t, cfg.Validate(), "signature_tolerance")
+
+	cfg.SignatureTolerance = 90 * time.Second
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestValidate_PinnedCertSHA256(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.PinnedCertSHA256 = []string{strings.Repeat("ab", 32), strings.Repeat("CD:", 31) + "CD"}
+	assert.NoError(t, cfg.Validate())
+
+	cfg.PinnedCertSHA256 = []string{"abcd"}
+	assert.ErrorContains(t, cfg.Validate(), "pinned_cert_sha256")
+
+	// Pins are only checked over TLS
+	cfg.PinnedCertSHA256 = []string{strings.Repeat("ab", 32)}
+	cfg.APIBaseURL = "http://localhost:4000"
+	assert.ErrorContains(t, cfg.Validate(), "pinned_cert_sha256 needs an https api_base_url")
+	cfg.PinnedCertSHA256 = nil
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestValidate_Profile(t *testing.T) {
+	cfg := DefaultConfig()
+	assert.Equal(t, DefaultProfile, cfg.ActiveProfile())
+
+	cfg.Profile = "work-2"
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, "work-2", cfg.ActiveProfile())
+
+	for _, name := range []string{"Work", "-work", "work/../x", strings.Repeat("a", 33)} {
+		cfg.Profile = name
+		assert.ErrorContains(t, cfg.Validate(), "profile must be", name)
+	}
+}
+
+func TestInitConfig_ProfileFromEnvironment(t *testing.T) {
+	viper.Reset()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("INITFLOW_PROFILE", "work")
+
+	require.NoError(t, InitConfig())
+	assert.Equal(t, "work", Get().ActiveProfile())
+}
+
+func TestInitConfig_APIURLFromEnvironment(t *testing.T) {
+	viper.Reset()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("INITFLOW_API_URL", "https://initflow.internal.example.com")
+
+	require.NoError(t, InitConfig())
+	assert.Equal(t, "https://initflow.internal.example.com", Get().APIBaseURL)
+
+	// The full name wins when both are set
+	viper.Reset()
+	t.Setenv("INITFLOW_API_BASE_URL", "https://staging.example.com")
+	require.NoError(t, InitConfig())
+	assert.Equal(t, "https://staging.example.com", Get().APIBaseURL)
+}
@@ -0,0 +1,210 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Kinds of value a setting holds, which decide how config set parses it
+const (
+	KindString   = "string"
+	KindDuration = "duration"
+	KindInt      = "int"
+	KindBool     = "bool"
+	KindList     = "list"
+)
+
+// Where a setting's effective value comes from, highest precedence first
+const (
+	SourceFlag    = "flag"
+	SourceEnv     = "env"
+	SourceFile    = "file"
+	SourceDefault = "default"
+)
+
+// Setting is a key that config get and set accept
+type Setting struct {
+	Key         string
+	Kind        string
+	Description string
+}
+
+// knownSettings are the keys users set by hand. The profiles list is left
+// out; profile use maintains it.
+var knownSettings = []Setting{
+	{"api_base_url", KindString, "Base URL of the init.Flow API"},
+	{"default_workspace", KindString, "Workspace used when --workspace is not given"},
+	{"output", KindString, "Default output format: table, json or yaml"},
+	{"timeout", KindDuration, "HTTP request timeout"},
+	{"retries", KindInt, "Retries for idempotent requests"},
+	{"concurrency", KindInt, "Secrets bulk commands encrypt or decrypt at once"},
+	{"default_email", KindString, "Email auth login uses when none is given"},
+	{"profile", KindString, "Profile whose login and keys are used"},
+	{"log_file", KindString, "File to append a redacted JSON log to"},
+	{"service_name", KindString, "Keyring service name for credentials"},
+	{"credential_store", KindString, "Where credentials are kept: keychain or file"},
+	{"encrypt_keys", KindBool, "Encrypt private and workspace keys with a passphrase"},
+	{"sign_requests", KindBool, "Sign a nonce into every request so replays are rejected"},
+	{"signature_tolerance", KindDuration, "Clock skew the server accepts for signed requests"},
+	{"lint_skip", KindList, "Secrets lint rules to skip"},
+	{"pinned_cert_sha256", KindList, "Accepted SHA-256 pins of the API server's key"},
+}
+
+// envAliases are environment variables read besides INITFLOW_<KEY>, which
+// wins when both are set
+var envAliases = map[string][]string{
+	"api_base_url": {"INITFLOW_API_URL"},
+}
+
+// overridden holds the keys set with Set, i.e. from command-line flags
+var overridden = map[string]bool{}
+
+// Settings returns the keys config get and set accept
+func Settings() []Setting {
+	return append([]Setting(nil), knownSettings...)
+}
+
+// LookupSetting returns the setting named key
+func LookupSetting(key string) (Setting, error) {
+	for _, setting := range knownSettings {
+		if setting.Key == key {
+			return setting, nil
+		}
+	}
+	return Setting{}, fmt.Errorf("unknown setting %q (run 'initflow config list' to see them all)", key)
+}
+
+// bindEnv makes every setting readable from the environment, including keys
+// without a default, which AutomaticEnv alone doesn't find
+func bindEnv() {
+	for _, setting := range knownSettings {
+		_ = viper.BindEnv(append([]string{setting.Key, envName(setting.Key)}, envAliases[setting.Key]...)...)
+	}
+}
+
+func envName(key string) string {
+	return "INITFLOW_" + strings.ToUpper(key)
+}
+
+// parseValue converts raw to the type a setting holds. Durations stay
+// strings so the config file keeps them readable.
+func parseValue(setting Setting, raw string) (interface{}, error) {
+	switch setting.Kind {
+	case KindDuration:
+		if _, err := time.ParseDuration(raw); err != nil {
+			return nil, fmt.Errorf("%s must be a duration like 45s or 2m, got %q", setting.Key, raw)
+		}
+		return raw, nil
+	case KindInt:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a whole number, got %q", setting.Key, raw)
+		}
+		return n, nil
+	case KindBool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be true or false, got %q", setting.Key, raw)
+		}
+		return b, nil
+	case KindList:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		return items, nil
+	default:
+		return raw, nil
+	}
+}
+
+// Update validates raw as the value of key and saves it to the config file.
+// Flag and environment overrides still win for the current command.
+func Update(key, raw string) error {
+	setting, err := LookupSetting(key)
+	if err != nil {
+		return err
+	}
+
+	value, err := parseValue(setting, raw)
+	if err != nil {
+		return err
+	}
+
+	candidate := viper.New()
+	if err := candidate.MergeConfigMap(viper.AllSettings()); err != nil {
+		return fmt.Errorf("failed to check setting: %w", err)
+	}
+	candidate.Set(key, value)
+
+	var cfg Config
+	if err := candidate.Unmarshal(&cfg); err != nil {
+		return fmt.Errorf("failed to check setting: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	return Persist(key, value)
+}
+
+// Value returns the effective value of key, formatted for display
+func Value(key string) string {
+	setting, err := LookupSetting(key)
+	if err != nil {
+		return ""
+	}
+
+	switch setting.Kind {
+	case KindDuration:
+		return viper.GetDuration(key).String()
+	case KindInt:
+		return strconv.Itoa(viper.GetInt(key))
+	case KindBool:
+		return strconv.FormatBool(viper.GetBool(key))
+	case KindList:
+		return strings.Join(viper.GetStringSlice(key), ",")
+	default:
+		return viper.GetString(key)
+	}
+}
+
+// Source reports where the effective value of key comes from: a flag, the
+// environment, the config file or the built-in default, in that precedence
+func Source(key string) string {
+	if overridden[key] {
+		return SourceFlag
+	}
+
+	for _, name := range append([]string{envName(key)}, envAliases[key]...) {
+		if _, ok := os.LookupEnv(name); ok {
+			return SourceEnv
+		}
+	}
+
+	if path, err := findConfigFile(); err == nil && path != "" {
+		if fileSettings, err := readSettings(path); err == nil {
+			if _, ok := fileSettings[key]; ok {
+				return SourceFile
+			}
+		}
+	}
+
+	return SourceDefault
+}
+
+// File returns the config file settings are read from, or "" when there is none
+func File() string {
+	path, err := findConfigFile()
+	if err != nil {
+		return ""
+	}
+	return path
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestUpdate(t *testing.T) {
+	viper.Reset()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	require.NoError(t, InitConfig())
+
+	require.NoError(t, Update("default_workspace", "my-project"))
+	require.NoError(t, Update("timeout", "45s"))
+	require.NoError(t, Update("retries", "4"))
+	require.NoError(t, Update("pinned_cert_sha256", strings.Repeat("ab", 32)))
+
+	cfg := Get()
+	assert.Equal(t, "my-project", cfg.DefaultWorkspace)
+	assert.Equal(t, "45s", Value("timeout"))
+	assert.Equal(t, 4, cfg.Retries)
+	assert.Len(t, cfg.PinnedCertSHA256, 1)
+
+	data, err := os.ReadFile(filepath.Join(home, ".initflow", "config.yaml"))
+	require.NoError(t, err)
+	var saved map[string]interface{}
+	require.NoError(t, yaml.Unmarshal(data, &saved))
+	assert.Equal(t, "45s", saved["timeout"], "durations are saved as written")
+	assert.Equal(t, 4, saved["retries"])
+
+	for key, value := range map[string]string{
+		"timeout":       "forever",
+		"retries":       "99",
+		"output":        "xml",
+		"profile":       "Work!",
+		"unknown":       "x",
+		"sign_requests": "maybe",
+	} {
+		assert.Error(t, Update(key, value), "%s=%s", key, value)
+	}
+	assert.Equal(t, 4, Get().Retries, "rejected values are not applied")
+}
+
+func TestSource(t *testing.T) {
+	viper.Reset()
+	overridden = map[string]bool{}
+	t.Cleanup(func() { overridden = map[string]bool{} })
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	require.NoError(t, os.MkdirAll(filepath.Join(home, ".initflow"), 0750))
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".initflow", "config.yaml"),
+		[]byte("output: json\nretries: 5\ndefault_workspace: from-file\n"), 0600))
+	t.Setenv("INITFLOW_RETRIES", "1")
+
+	require.NoError(t, InitConfig())
+	require.NoError(t, Set("default_workspace", "from-flag"))
+
+	assert.Equal(t, SourceDefault, Source("timeout"))
+	assert.Equal(t, SourceFile, Source("output"))
+	assert.Equal(t, "json", Get().Output)
+	assert.Equal(t, SourceEnv, Source("retries"))
+	assert.Equal(t, "1", Value("retries"))
+	assert.Equal(t, SourceFlag, Source("default_workspace"))
+	assert.Equal(t, "from-flag", Value("default_workspace"))
+}
@@ -0,0 +1,234 @@
+// Package dotenv reads and writes .env files.
+//
+// Parse accepts the common dotenv dialect: KEY=VALUE lines with an optional
+// export prefix, # comments, single-quoted literal values, and double-quoted
+// values with backslash escapes. Quoted values may span lines. Write produces
+// files that Parse, shells and the usual dotenv libraries read back unchanged.
+package dotenv
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Entry is one KEY=VALUE assignment. Line is where it starts, counting from 1.
+type Entry struct {
+	Key   string
+	Value string
+	Line  int
+}
+
+// parser walks the input one byte at a time, tracking the line for errors
+type parser struct {
+	src  string
+	pos  int
+	line int
+}
+
+// Parse reads assignments in file order. A key assigned twice appears twice;
+// callers decide which one wins.
+func Parse(data []byte) ([]Entry, error) {
+	p := &parser{src: strings.TrimPrefix(string(data), "\ufeff"), line: 1}
+
+	var entries []Entry
+	for p.pos < len(p.src) {
+		p.skipBlank()
+		if p.pos >= len(p.src) {
+			break
+		}
+		if p.peek() == '#' || p.peek() == '\n' || p.peek() == '\r' {
+			p.skipLine()
+			continue
+		}
+
+		entry, err := p.assignment()
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (p *parser) peek() byte {
+	return p.src[p.pos]
+}
+
+// skipBlank skips spaces and tabs, but not newlines
+func (p *parser) skipBlank() {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// skipLine moves past the next newline
+func (p *parser) skipLine() {
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		p.pos++
+		if c == '\n' {
+			p.line++
+			return
+		}
+	}
+}
+
+// restOfLine returns the text up to the next newline and moves past it
+func (p *parser) restOfLine() string {
+	start := p.pos
+	end := strings.IndexByte(p.src[start:], '\n')
+	if end < 0 {
+		p.pos = len(p.src)
+		return strings.TrimSuffix(p.src[start:], "\r")
+	}
+	p.pos = start + end + 1
+	p.line++
+	return strings.TrimSuffix(p.src[start:start+end], "\r")
+}
+
+func (p *parser) assignment() (Entry, error) {
+	line := p.line
+	if strings.HasPrefix(p.src[p.pos:], "export ") || strings.HasPrefix(p.src[p.pos:], "export\t") {
+		p.pos += len("export")
+		p.skipBlank()
+	}
+
+	start := p.pos
+	for p.pos < len(p.src) && p.src[p.pos] != '=' && p.src[p.pos] != '\n' {
+		p.pos++
+	}
+	key := strings.TrimSpace(p.src[start:p.pos])
+	if p.pos >= len(p.src) || p.src[p.pos] != '=' {
+		return Entry{}, fmt.Errorf("line %d: expected KEY=VALUE, got %q", line, key)
+	}
+	if key == "" || strings.ContainsAny(key, " \t") {
+		return Entry{}, fmt.Errorf("line %d: invalid key %q", line, key)
+	}
+	p.pos++ // =
+	p.skipBlank()
+
+	var value string
+	var err error
+	switch {
+	case p.pos < len(p.src) && p.peek() == '"':
+		value, err = p.quoted('"', true)
+	case p.pos < len(p.src) && p.peek() == '\'':
+		value, err = p.quoted('\'', false)
+	default:
+		value = unquoted(p.restOfLine())
+	}
+	if err != nil {
+		return Entry{}, fmt.Errorf("line %d: %w", line, err)
+	}
+
+	return Entry{Key: key, Value: value, Line: line}, nil
+}
+
+// unquoted trims a bare value and drops a trailing comment, which needs
+// whitespace before the # so values like a#b survive
+func unquoted(raw string) string {
+	for i := 1; i < len(raw); i++ {
+		if raw[i] == '#' && (raw[i-1] == ' ' || raw[i-1] == '\t') {
+			raw = raw[:i]
+			break
+		}
+	}
+	return strings.TrimSpace(raw)
+}
+
+// quoted reads a value up to the closing quote. Only double quotes interpret
+// escapes. Anything after the closing quote must be blank or a comment.
+func (p *parser) quoted(quote byte, escapes bool) (string, error) {
+	p.pos++ // opening quote
+
+	var b strings.Builder
+	for {
+		if p.pos >= len(p.src) {
+			return "", fmt.Errorf("unterminated %c quote", quote)
+		}
+		c := p.src[p.pos]
+		p.pos++
+
+		switch {
+		case c == quote:
+			p.skipBlank()
+			if rest := p.restOfLine(); rest != "" && !strings.HasPrefix(rest, "#") {
+				return "", fmt.Errorf("unexpected %q after closing quote", rest)
+			}
+			return b.String(), nil
+		case c == '\\' && escapes && p.pos < len(p.src):
+			next := p.src[p.pos]
+			p.pos++
+			switch next {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case '"', '\\', '$', '`':
+				b.WriteByte(next)
+			default:
+				b.WriteByte('\\')
+				b.WriteByte(next)
+				if next == '\n' {
+					p.line++
+				}
+			}
+		default:
+			if c == '\n' {
+				p.line++
+			}
+			b.WriteByte(c)
+		}
+	}
+}
+
+// Quote returns value as it should appear after KEY=. Plain values are left
+// bare; anything else is double-quoted with newlines, quotes, backslashes and
+// $ escaped so no consumer expands or splits it.
+func Quote(value string) string {
+	if value != "" && strings.IndexFunc(value, needsQuotes) < 0 {
+		return value
+	}
+
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '"', '\\', '$', '`':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// needsQuotes reports whether r can't appear in a bare value
+func needsQuotes(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return false
+	}
+	return !strings.ContainsRune("_-.,:/@+=%", r)
+}
+
+// Write writes entries as KEY=VALUE lines in the given order
+func Write(w io.Writer, entries []Entry) error {
+	for _, entry := range entries {
+		if _, err := fmt.Fprintf(w, "%s=%s\n", entry.Key, Quote(entry.Value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package dotenv
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	input := "# database\n" +
+		"DATABASE_URL=postgres://u:p@db/app\n" +
+		"export API_KEY = abc123   # inline comment\n" +
+		"\n" +
+		"HASH=a#b\n" +
+		"EMPTY=\n" +
+		"SINGLE='literal \\n $HOME'\n" +
+		"DOUBLE=\"tab\\tquote\\\" backslash\\\\\"\n" +
+		"CERT=\"-----BEGIN-----\n" +
+		"abc\n" +
+		"-----END-----\"\n" +
+		"WINDOWS=crlf\r\n" +
+		"LAST=no newline"
+
+	entries, err := Parse([]byte(input))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	want := []Entry{
+		{Key: "DATABASE_URL", Value: "postgres://u:p@db/app", Line: 2},
+		{Key: "API_KEY", Value: "abc123", Line: 3},
+		{Key: "HASH", Value: "a#b", Line: 5},
+		{Key: "EMPTY", Value: "", Line: 6},
+		{Key: "SINGLE", Value: "literal \\n $HOME", Line: 7},
+		{Key: "DOUBLE", Value: "tab\tquote\" backslash\\", Line: 8},
+		{Key: "CERT", Value: "-----BEGIN-----\nabc\n-----END-----", Line: 9},
+		{Key: "WINDOWS", Value: "crlf", Line: 12},
+		{Key: "LAST", Value: "no newline", Line: 13},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("Parse mismatch\n got: %#v\nwant: %#v", entries, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"missing equals", "A=1\nNOT_AN_ASSIGNMENT\n", "line 2: expected KEY=VALUE"},
+		{"unterminated quote", "A=\"open\nstill open\n", "line 1: unterminated \" quote"},
+		{"text after quote", "A='x' y\n", "unexpected \"y\" after closing quote"},
+		{"space in key", "MY KEY=1\n", "invalid key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.input))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestQuote(t *testing.T) {
+	tests := map[string]string{
+		"abc123":                 "abc123",
+		"postgres://u@db:5432/x": "postgres://u@db:5432/x",
+		"":                       `""`,
+		"two words":              `"two words"`,
+		"a#b":                    `"a#b"`,
+		"line1\nline2":           `"line1\nline2"`,
+		`say "hi" $HOME \`:       `"say \"hi\" \$HOME \\"`,
+	}
+
+	for value, want := range tests {
+		if got := Quote(value); got != want {
+			t.Errorf("Quote(%q) = %s, want %s", value, got, want)
+		}
+	}
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	entries := []Entry{
+		{Key: "PLAIN", Value: "abc"},
+		{Key: "EMPTY", Value: ""},
+		{Key: "CERT", Value: "-----BEGIN-----\r\nabc\n-----END-----\n"},
+		{Key: "TRICKY", Value: `it's "quoted" # not a comment $PATH \n` + "\t`cmd`"},
+		{Key: "UNICODE", Value: "pässwörd 🔑"},
+	}
+
+	var b strings.Builder
+	if err := Write(&b, entries); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	parsed, err := Parse([]byte(b.String()))
+	if err != nil {
+		t.Fatalf("Parse failed on written output %q: %v", b.String(), err)
+	}
+	if len(parsed) != len(entries) {
+		t.Fatalf("Expected %d entries back, got %d from %q", len(entries), len(parsed), b.String())
+	}
+	for i, entry := range entries {
+		if parsed[i].Key != entry.Key || parsed[i].Value != entry.Value {
+			t.Errorf("Round trip changed %s: %q became %q", entry.Key, entry.Value, parsed[i].Value)
+		}
+	}
+}
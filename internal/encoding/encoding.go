@@ -2,17 +2,69 @@ package encoding
 
 import (
 	"crypto/ed25519"
+	"crypto/sha256"
 	"encoding/base64"
 	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/curve25519"
 )
 
 const (
 	WorkspaceKeySize     = 32 // 256 bits for ChaCha20-Poly1305
 	X25519PrivateKeySize = 32 // X25519 private key size
+	X25519PublicKeySize  = 32 // X25519 public key size
 	ChaCha20NonceSize    = 12 // ChaCha20-Poly1305 nonce size
 	UserTokenSize        = 32 // User authentication token size
 )
 
+// Compile-time checks: an array with a negative length fails to build, so each
+// pair breaks the build if a size drifts from its primitive in either direction.
+var (
+	_ [WorkspaceKeySize - chacha20poly1305.KeySize]struct{}
+	_ [chacha20poly1305.KeySize - WorkspaceKeySize]struct{}
+	_ [ChaCha20NonceSize - chacha20poly1305.NonceSize]struct{}
+	_ [chacha20poly1305.NonceSize - ChaCha20NonceSize]struct{}
+	_ [X25519PrivateKeySize - curve25519.ScalarSize]struct{}
+	_ [curve25519.ScalarSize - X25519PrivateKeySize]struct{}
+	_ [X25519PublicKeySize - curve25519.PointSize]struct{}
+	_ [curve25519.PointSize - X25519PublicKeySize]struct{}
+)
+
+// sizeCheck pairs one of our size constants with the primitive's own value
+type sizeCheck struct {
+	name     string
+	size     int
+	expected int
+}
+
+var sizeChecks = []sizeCheck{
+	{"WorkspaceKeySize", WorkspaceKeySize, chacha20poly1305.KeySize},
+	{"ChaCha20NonceSize", ChaCha20NonceSize, chacha20poly1305.NonceSize},
+	{"X25519PrivateKeySize", X25519PrivateKeySize, curve25519.ScalarSize},
+	{"X25519PublicKeySize", X25519PublicKeySize, curve25519.PointSize},
+}
+
+// Validate reports any size constant that no longer matches the crypto
+// primitive it describes.
+func Validate() error {
+	return checkSizes(sizeChecks)
+}
+
+func checkSizes(checks []sizeCheck) error {
+	var mismatches []string
+	for _, check := range checks {
+		if check.size != check.expected {
+			mismatches = append(mismatches, fmt.Sprintf("%s is %d, expected %d", check.name, check.size, check.expected))
+		}
+	}
+	if len(mismatches) > 0 {
+		return fmt.Errorf("encoding size constants out of sync: %s", strings.Join(mismatches, "; "))
+	}
+	return nil
+}
+
 // EncodeEd25519PublicKey encodes an Ed25519 public key for API transmission
 func EncodeEd25519PublicKey(publicKey ed25519.PublicKey) (string, error) {
 	if len(publicKey) != ed25519.PublicKeySize {
@@ -23,7 +75,7 @@ func EncodeEd25519PublicKey(publicKey ed25519.PublicKey) (string, error) {
 
 // EncodeX25519PublicKey encodes an X25519 public key for API transmission
 func EncodeX25519PublicKey(publicKey []byte) (string, error) {
-	if len(publicKey) != X25519PrivateKeySize {
+	if len(publicKey) != X25519PublicKeySize {
 		return "", fmt.Errorf("invalid X25519 public key size: %d", len(publicKey))
 	}
 	return base64.RawURLEncoding.EncodeToString(publicKey), nil
@@ -46,3 +98,39 @@ func Encode(data []byte) string {
 func Decode(encoded string) ([]byte, error) {
 	return base64.RawURLEncoding.DecodeString(encoded)
 }
+
+// keyEncoding is the canonical encoding for keys: URL-safe base64 without padding,
+// rejecting non-zero trailing bits so every key has exactly one valid encoding
+var keyEncoding = base64.RawURLEncoding.Strict()
+
+// EncodeKey encodes raw key material in the canonical base64url form
+func EncodeKey(key []byte) string {
+	return keyEncoding.EncodeToString(key)
+}
+
+// DecodeKey decodes a canonical base64url key and validates its length
+// against the key sizes used by InitFlow
+func DecodeKey(encoded string) ([]byte, error) {
+	if strings.ContainsAny(encoded, "=\r\n") {
+		return nil, fmt.Errorf("invalid key format")
+	}
+
+	key, err := keyEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key format")
+	}
+
+	switch len(key) {
+	case WorkspaceKeySize, ed25519.PrivateKeySize:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("invalid key size: %d", len(key))
+	}
+}
+
+// Fingerprint returns a short, stable identifier for a public key: the
+// base64url-encoded SHA-256 digest prefixed with the hash name
+func Fingerprint(publicKey []byte) string {
+	digest := sha256.Sum256(publicKey)
+	return "SHA256:" + EncodeKey(digest[:])
+}
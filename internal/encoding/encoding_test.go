@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"crypto/ed25519"
 	"crypto/rand"
+	"strings"
 	"testing"
 )
 
@@ -75,3 +76,72 @@ func TestEncodeEd25519Signature(t *testing.T) {
 		t.Error("Encoded signature is empty")
 	}
 }
+
+func TestEncodeDecodeKey(t *testing.T) {
+	_, signingKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	workspaceKey := make([]byte, WorkspaceKeySize)
+	rand.Read(workspaceKey)
+
+	for _, key := range [][]byte{workspaceKey, signingKey, signingKey.Public().(ed25519.PublicKey)} {
+		encoded := EncodeKey(key)
+		if strings.ContainsAny(encoded, "+/=") {
+			t.Errorf("EncodeKey() produced non-URL-safe output: %s", encoded)
+		}
+
+		decoded, err := DecodeKey(encoded)
+		if err != nil {
+			t.Fatalf("DecodeKey() error = %v", err)
+		}
+		if !bytes.Equal(decoded, key) {
+			t.Errorf("Round-trip failed: got %v, expected %v", decoded, key)
+		}
+	}
+}
+
+func TestDecodeKeyMalformed(t *testing.T) {
+	key := make([]byte, WorkspaceKeySize)
+	rand.Read(key)
+	encoded := EncodeKey(key)
+
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{"empty", ""},
+		{"wrong length", EncodeKey(make([]byte, 16))},
+		{"padded", encoded + "="},
+		{"standard alphabet", strings.NewReplacer("-", "+", "_", "/").Replace(encoded) + "+"},
+		{"invalid characters", "not a key!"},
+		{"embedded newline", encoded[:20] + "\n" + encoded[20:]},
+		{"non-canonical trailing bits", encoded[:len(encoded)-1] + "B"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeKey(tt.encoded); err == nil {
+				t.Errorf("DecodeKey(%q) expected error", tt.encoded)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate(); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+
+	err := checkSizes([]sizeCheck{
+		{"WorkspaceKeySize", 16, 32},
+		{"ChaCha20NonceSize", 12, 12},
+	})
+	if err == nil || !strings.Contains(err.Error(), "WorkspaceKeySize is 16, expected 32") {
+		t.Errorf("Expected mismatch to be reported, got %v", err)
+	}
+	if strings.Contains(err.Error(), "ChaCha20NonceSize") {
+		t.Errorf("Expected only mismatched sizes to be reported, got %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package fsutil
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+const (
+	PrivateFilePermissions = 0600
+)
+
+// WriteFileAtomic replaces path with the content produced by write. The content is
+// written to a temp file in the same directory, fsync-ed and renamed over path, so
+// a crash or write error never leaves path truncated.
+func WriteFileAtomic(path string, perm os.FileMode, write func(w io.Writer) error) (err error) {
+	dir := filepath.Dir(path)
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpName := tmp.Name()
+
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmpName)
+		}
+	}()
+
+	if err := tmp.Chmod(perm); err != nil {
+		return fmt.Errorf("failed to set file permissions: %w", err)
+	}
+
+	if err := write(tmp); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	if err := tmp.Sync(); err != nil {
+		return fmt.Errorf("failed to sync %s: %w", path, err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temp file: %w", err)
+	}
+
+	if err := os.Rename(tmpName, path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+
+	syncDir(dir)
+
+	return nil
+}
+
+// WriteFile atomically replaces path with data
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	return WriteFileAtomic(path, perm, func(w io.Writer) error {
+		_, err := io.Copy(w, bytes.NewReader(data))
+		return err
+	})
+}
+
+// syncDir flushes the directory entry for a rename. It is best effort since not
+// every platform supports syncing directories.
+func syncDir(dir string) {
+	d, err := os.Open(dir) // #nosec G304 - dir is the parent of a path we just wrote
+	if err != nil {
+		return
+	}
+	_ = d.Sync()
+	_ = d.Close()
+}
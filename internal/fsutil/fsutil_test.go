@@ -0,0 +1,63 @@
+package fsutil
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteFile_CreatesWithPermissions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "device.key")
+
+	err := WriteFile(path, []byte("secret"), PrivateFilePermissions)
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(path) // #nosec G304 - test file path is controlled
+	require.NoError(t, err)
+	assert.Equal(t, "secret", string(content))
+
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(PrivateFilePermissions), info.Mode().Perm())
+	}
+}
+
+func TestWriteFile_ReplacesExisting(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "device.key")
+	require.NoError(t, os.WriteFile(path, []byte("old"), 0600))
+
+	require.NoError(t, WriteFile(path, []byte("new"), PrivateFilePermissions))
+
+	content, err := os.ReadFile(path) // #nosec G304 - test file path is controlled
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(content))
+}
+
+func TestWriteFileAtomic_WriteErrorLeavesOriginalUntouched(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "device.key")
+	require.NoError(t, os.WriteFile(path, []byte("original"), 0600))
+
+	writeErr := errors.New("disk full")
+	err := WriteFileAtomic(path, PrivateFilePermissions, func(w io.Writer) error {
+		_, _ = w.Write([]byte("half-writ"))
+		return writeErr
+	})
+	require.Error(t, err)
+	assert.ErrorIs(t, err, writeErr)
+
+	content, err := os.ReadFile(path) // #nosec G304 - test file path is controlled
+	require.NoError(t, err)
+	assert.Equal(t, "original", string(content))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "temp file should be removed after a failed write")
+}
@@ -0,0 +1,110 @@
+// Package logging writes a structured JSON log of CLI activity to a file for
+// support cases. Logging is off until Open is called.
+package logging
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/fsutil"
+)
+
+// DefaultMaxSize is the size at which the log file is rotated
+const DefaultMaxSize = 5 << 20
+
+// Redacted replaces the value of sensitive attributes
+const Redacted = "[REDACTED]"
+
+// sensitiveKeys are attribute names whose values never reach the log
+var sensitiveKeys = []string{
+	"authorization", "x-signature", "password", "passphrase", "token", "otp", "secret", "value", "key",
+}
+
+var (
+	logger atomic.Pointer[slog.Logger]
+
+	mu   sync.Mutex
+	file *os.File
+)
+
+func init() {
+	logger.Store(slog.New(slog.DiscardHandler))
+}
+
+// Logger returns the current logger, which discards everything until Open is called
+func Logger() *slog.Logger {
+	return logger.Load()
+}
+
+// Open starts appending JSON log lines to path. If the file has grown past
+// maxSize it is first moved to path + ".1", replacing any older rotation.
+func Open(path string, maxSize int64) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if err := rotate(path, maxSize); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, fsutil.PrivateFilePermissions) // #nosec G304 - path is the user's chosen log file
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+
+	if file != nil {
+		_ = file.Close()
+	}
+	file = f
+	logger.Store(slog.New(slog.NewJSONHandler(f, &slog.HandlerOptions{ReplaceAttr: redact})))
+
+	return nil
+}
+
+// Close stops logging and closes the log file
+func Close() error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	logger.Store(slog.New(slog.DiscardHandler))
+	if file == nil {
+		return nil
+	}
+
+	err := file.Close()
+	file = nil
+	return err
+}
+
+func rotate(path string, maxSize int64) error {
+	info, err := os.Stat(path)
+	if err != nil || maxSize <= 0 || info.Size() < maxSize {
+		return nil
+	}
+
+	if err := os.Rename(path, path+".1"); err != nil {
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+	return nil
+}
+
+func redact(_ []string, a slog.Attr) slog.Attr {
+	if IsSensitive(a.Key) {
+		return slog.String(a.Key, Redacted)
+	}
+	return a
+}
+
+// IsSensitive reports whether an attribute or header name may carry a secret
+func IsSensitive(name string) bool {
+	name = strings.ToLower(name)
+	for _, key := range sensitiveKeys {
+		if name == key || strings.HasSuffix(name, "_"+key) || strings.HasSuffix(name, "-"+key) {
+			return true
+		}
+	}
+	return false
+}
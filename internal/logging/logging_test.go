@@ -0,0 +1,90 @@
+package logging
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readLines(t *testing.T, path string) []map[string]any {
+	t.Helper()
+
+	data, err := os.ReadFile(path) // #nosec G304 - test file path is controlled
+	require.NoError(t, err)
+
+	var lines []map[string]any
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var line map[string]any
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &line), "line is not JSON: %s", scanner.Text())
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestOpenWritesRedactedJSONLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "initflow.log")
+
+	require.NoError(t, Open(path, DefaultMaxSize))
+	Logger().Info("api request", "method", "POST", "path", "/api/v1/auth/login", "status", 200)
+	Logger().Info("headers", "Authorization", "Device abc", "X-Signature", "sig", "password", "hunter2",
+		"registration_token", "tok", "wrapped_workspace_key", "wrapped")
+	require.NoError(t, Close())
+
+	// Logging after Close is discarded
+	Logger().Info("after close")
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	content, err := os.ReadFile(path) // #nosec G304 - test file path is controlled
+	require.NoError(t, err)
+	for _, secret := range []string{"Device abc", "sig\"", "hunter2", "\"tok\"", "\"wrapped\""} {
+		assert.NotContains(t, string(content), secret)
+	}
+
+	lines := readLines(t, path)
+	require.Len(t, lines, 2)
+	assert.Equal(t, "api request", lines[0]["msg"])
+	assert.Equal(t, float64(200), lines[0]["status"])
+	assert.NotEmpty(t, lines[0]["time"])
+	assert.Equal(t, Redacted, lines[1]["Authorization"])
+	assert.Equal(t, Redacted, lines[1]["password"])
+}
+
+func TestOpenAppendsAndRotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "initflow.log")
+
+	require.NoError(t, Open(path, DefaultMaxSize))
+	Logger().Info("first run")
+	require.NoError(t, Close())
+
+	require.NoError(t, Open(path, DefaultMaxSize))
+	Logger().Info("second run")
+	require.NoError(t, Close())
+	assert.Len(t, readLines(t, path), 2, "runs should append")
+
+	// Once the file reaches the size cap it is rotated aside
+	require.NoError(t, Open(path, 1))
+	Logger().Info("third run")
+	require.NoError(t, Close())
+
+	assert.Len(t, readLines(t, path), 1)
+	assert.Len(t, readLines(t, path+".1"), 2)
+}
+
+func TestIsSensitive(t *testing.T) {
+	for _, name := range []string{"Authorization", "X-Signature", "password", "registration_token", "secret_value", "api-key"} {
+		assert.True(t, IsSensitive(name), name)
+	}
+	for _, name := range []string{"method", "path", "status", "command", "duration_ms", "keyboard"} {
+		assert.False(t, IsSensitive(name), name)
+	}
+}
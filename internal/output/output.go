@@ -0,0 +1,83 @@
+// Package output renders command results in the structured formats --output
+// accepts, so scripts can parse them with jq or yq.
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Format is a value of the global --output flag
+type Format string
+
+const (
+	Table Format = "table"
+	JSON  Format = "json"
+	YAML  Format = "yaml"
+)
+
+// Parse checks that s names a supported format
+func Parse(s string) (Format, error) {
+	switch f := Format(s); f {
+	case Table, JSON, YAML:
+		return f, nil
+	default:
+		return "", fmt.Errorf("invalid output format %q: must be one of table, json, yaml", s)
+	}
+}
+
+// Structured reports whether f is machine-readable rather than a table
+func (f Format) Structured() bool {
+	return f == JSON || f == YAML
+}
+
+// Write encodes v to w as JSON or YAML. YAML uses the same field names and
+// order as JSON, since result types only carry json tags.
+func Write(w io.Writer, f Format, v any) error {
+	switch f {
+	case JSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(v); err != nil {
+			return fmt.Errorf("failed to encode JSON output: %w", err)
+		}
+		return nil
+	case YAML:
+		return writeYAML(w, v)
+	default:
+		return fmt.Errorf("%s is not a structured output format", f)
+	}
+}
+
+func writeYAML(w io.Writer, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode YAML output: %w", err)
+	}
+
+	// JSON is valid YAML, so decoding it into a node keeps the key order
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return fmt.Errorf("failed to encode YAML output: %w", err)
+	}
+	resetStyle(&node)
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return fmt.Errorf("failed to encode YAML output: %w", err)
+	}
+	return encoder.Close()
+}
+
+// resetStyle drops the flow style and quoting the node kept from its JSON
+// source, so the encoder picks plain block YAML and quotes only when needed
+func resetStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		resetStyle(child)
+	}
+}
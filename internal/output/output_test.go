@@ -0,0 +1,64 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+type result struct {
+	Name    string   `json:"name"`
+	ID      int      `json:"id"`
+	Tags    []string `json:"tags,omitempty"`
+	Enabled bool     `json:"enabled"`
+	Note    string   `json:"note,omitempty"`
+}
+
+func TestParse(t *testing.T) {
+	for _, s := range []string{"table", "json", "yaml"} {
+		if _, err := Parse(s); err != nil {
+			t.Errorf("Parse(%q) failed: %v", s, err)
+		}
+	}
+	if _, err := Parse("xml"); err == nil || !strings.Contains(err.Error(), "table, json, yaml") {
+		t.Errorf("Expected xml to be rejected, got %v", err)
+	}
+	if Table.Structured() || !JSON.Structured() || !YAML.Structured() {
+		t.Error("Expected only json and yaml to be structured")
+	}
+}
+
+func TestWriteYAMLFollowsJSONTags(t *testing.T) {
+	var buf bytes.Buffer
+	v := []result{{Name: "api", ID: 1, Tags: []string{"prod", "123"}, Enabled: true}}
+	if err := Write(&buf, YAML, v); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	want := "- name: api\n  id: 1\n  tags:\n    - prod\n    - \"123\"\n  enabled: true\n"
+	if buf.String() != want {
+		t.Errorf("Unexpected YAML\n got: %q\nwant: %q", buf.String(), want)
+	}
+
+	var back []result
+	if err := yaml.Unmarshal(buf.Bytes(), &back); err != nil || back[0].Tags[1] != "123" {
+		t.Errorf("Expected the YAML to read back with strings intact, got %+v, %v", back, err)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, JSON, result{Name: "api", ID: 1}); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	want := "{\n  \"name\": \"api\",\n  \"id\": 1,\n  \"enabled\": false\n}\n"
+	if buf.String() != want {
+		t.Errorf("Unexpected JSON\n got: %q\nwant: %q", buf.String(), want)
+	}
+
+	if err := Write(&buf, Table, nil); err == nil {
+		t.Error("Expected Write to refuse the table format")
+	}
+}
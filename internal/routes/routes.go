@@ -9,9 +9,15 @@ const (
 )
 
 const (
-	AuthLogin  = APIBasePath + "/auth/login"
-	Devices    = APIBasePath + "/devices"
-	Workspaces = APIBasePath + "/workspaces"
+	AuthLogin   = APIBasePath + "/auth/login"
+	AuthMe      = APIBasePath + "/auth/me"
+	AuthRefresh = APIBasePath + "/auth/refresh"
+	AuthDevice  = APIBasePath + "/auth/device/code"
+	AuthToken   = APIBasePath + "/auth/token"
+	Devices     = APIBasePath + "/devices"
+	Workspaces  = APIBasePath + "/workspaces"
+	Operations  = APIBasePath + "/operations"
+	Tokens      = APIBasePath + "/tokens"
 )
 
 type WorkspaceRoutes struct{}
@@ -32,10 +38,42 @@ func (w WorkspaceRoutes) SecretByKey(workspaceID int, secretKey string) string {
 	return fmt.Sprintf("%s/%d/secrets/%s", Workspaces, workspaceID, secretKey)
 }
 
+func (w WorkspaceRoutes) SecretVersions(workspaceID int, secretKey string) string {
+	return fmt.Sprintf("%s/%d/secrets/%s/versions", Workspaces, workspaceID, secretKey)
+}
+
+func (w WorkspaceRoutes) SecretVersion(workspaceID int, secretKey string, version int) string {
+	return fmt.Sprintf("%s/%d/secrets/%s/versions/%d", Workspaces, workspaceID, secretKey, version)
+}
+
+func (w WorkspaceRoutes) Devices(workspaceID int) string {
+	return fmt.Sprintf("%s/%d/devices", Workspaces, workspaceID)
+}
+
 func (w WorkspaceRoutes) InviteDevice(workspaceID int) string {
 	return fmt.Sprintf("%s/%d/invite-device", Workspaces, workspaceID)
 }
 
+func (w WorkspaceRoutes) RecoveryKey(workspaceID int) string {
+	return fmt.Sprintf("%s/%d/recovery-key", Workspaces, workspaceID)
+}
+
+func (w WorkspaceRoutes) DeviceKey(workspaceID int) string {
+	return fmt.Sprintf("%s/%d/device-key", Workspaces, workspaceID)
+}
+
+func (w WorkspaceRoutes) RotateKey(workspaceID int) string {
+	return fmt.Sprintf("%s/%d/rotate-key", Workspaces, workspaceID)
+}
+
+func (w WorkspaceRoutes) Environments(workspaceID int) string {
+	return fmt.Sprintf("%s/%d/environments", Workspaces, workspaceID)
+}
+
+func (w WorkspaceRoutes) ApproveDevice(workspaceID int, deviceID string) string {
+	return fmt.Sprintf("%s/%d/devices/%s/approve", Workspaces, workspaceID, deviceID)
+}
+
 var Workspace = WorkspaceRoutes{}
 
 type DeviceRoutes struct{}
@@ -48,8 +86,28 @@ func (d DeviceRoutes) Revoke(deviceID string) string {
 	return fmt.Sprintf("%s/%s/revoke", Devices, deviceID)
 }
 
+func (d DeviceRoutes) EncryptionKey(deviceID string) string {
+	return fmt.Sprintf("%s/%s/encryption-key", Devices, deviceID)
+}
+
 var Device = DeviceRoutes{}
 
+type OperationRoutes struct{}
+
+func (o OperationRoutes) GetByID(operationID string) string {
+	return fmt.Sprintf("%s/%s", Operations, operationID)
+}
+
+var Operation = OperationRoutes{}
+
+type TokenRoutes struct{}
+
+func (t TokenRoutes) GetByID(tokenID string) string {
+	return fmt.Sprintf("%s/%s", Tokens, tokenID)
+}
+
+var Token = TokenRoutes{}
+
 type SecretRoutes struct{}
 
 var Secret = SecretRoutes{}
@@ -73,6 +131,26 @@ var (
 		Path:   AuthLogin,
 	}
 
+	RefreshRoute = Route{
+		Method: POST,
+		Path:   AuthRefresh,
+	}
+
+	DeviceCodeRoute = Route{
+		Method: POST,
+		Path:   AuthDevice,
+	}
+
+	DeviceTokenRoute = Route{
+		Method: POST,
+		Path:   AuthToken,
+	}
+
+	CurrentUserRoute = Route{
+		Method: GET,
+		Path:   AuthMe,
+	}
+
 	ListWorkspacesRoute = Route{
 		Method: GET,
 		Path:   Workspaces,
@@ -43,11 +43,31 @@ func TestWorkspaceRoutes_SecretByKey(t *testing.T) {
 	assert.Equal(t, "/api/v1/workspaces/123/secrets/API_KEY", route)
 }
 
+func TestWorkspaceRoutes_SecretVersions(t *testing.T) {
+	route := Workspace.SecretVersions(123, "API_KEY")
+	assert.Equal(t, "/api/v1/workspaces/123/secrets/API_KEY/versions", route)
+}
+
+func TestWorkspaceRoutes_SecretVersion(t *testing.T) {
+	route := Workspace.SecretVersion(123, "API_KEY", 4)
+	assert.Equal(t, "/api/v1/workspaces/123/secrets/API_KEY/versions/4", route)
+}
+
 func TestWorkspaceRoutes_InviteDevice(t *testing.T) {
 	route := Workspace.InviteDevice(456)
 	assert.Equal(t, "/api/v1/workspaces/456/invite-device", route)
 }
 
+func TestWorkspaceRoutes_RotateKey(t *testing.T) {
+	route := Workspace.RotateKey(456)
+	assert.Equal(t, "/api/v1/workspaces/456/rotate-key", route)
+}
+
+func TestWorkspaceRoutes_Environments(t *testing.T) {
+	route := Workspace.Environments(456)
+	assert.Equal(t, "/api/v1/workspaces/456/environments", route)
+}
+
 func TestDeviceRoutes_GetByID(t *testing.T) {
 	route := Device.GetByID("abc123")
 	assert.Equal(t, "/api/v1/devices/abc123", route)
@@ -58,6 +78,11 @@ func TestDeviceRoutes_Revoke(t *testing.T) {
 	assert.Equal(t, "/api/v1/devices/def456/revoke", route)
 }
 
+func TestDeviceRoutes_EncryptionKey(t *testing.T) {
+	route := Device.EncryptionKey("def456")
+	assert.Equal(t, "/api/v1/devices/def456/encryption-key", route)
+}
+
 func TestHTTPMethods(t *testing.T) {
 	assert.Equal(t, "GET", GET)
 	assert.Equal(t, "POST", POST)
@@ -68,6 +93,10 @@ func TestHTTPMethods(t *testing.T) {
 
 func TestPredefinedRoutes(t *testing.T) {
 	assert.Equal(t, Route{Method: POST, Path: "/api/v1/auth/login"}, LoginRoute)
+	assert.Equal(t, Route{Method: POST, Path: "/api/v1/auth/refresh"}, RefreshRoute)
+	assert.Equal(t, Route{Method: POST, Path: "/api/v1/auth/device/code"}, DeviceCodeRoute)
+	assert.Equal(t, Route{Method: POST, Path: "/api/v1/auth/token"}, DeviceTokenRoute)
+	assert.Equal(t, Route{Method: GET, Path: "/api/v1/auth/me"}, CurrentUserRoute)
 	assert.Equal(t, Route{Method: GET, Path: "/api/v1/workspaces"}, ListWorkspacesRoute)
 	assert.Equal(t, Route{Method: POST, Path: "/api/v1/devices"}, RegisterDeviceRoute)
 	assert.Equal(t, Route{Method: GET, Path: "/api/v1/devices"}, ListDevicesRoute)
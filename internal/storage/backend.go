@@ -0,0 +1,164 @@
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/zalando/go-keyring"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/config"
+	"github.com/DylanBlakemore/initflow-cli/internal/fsutil"
+)
+
+const credentialsDirPermissions = 0700
+
+// backend keeps credentials by service and name. Get returns
+// keyring.ErrNotFound for a missing credential whatever the backend.
+type backend interface {
+	Set(service, name, value string) error
+	Get(service, name string) (string, error)
+	Delete(service, name string) error
+}
+
+// lister is implemented by backends that can enumerate the names of a
+// service's credentials; the OS keychain can't
+type lister interface {
+	List(service string) ([]string, error)
+}
+
+// backendFor returns the backend selected by the credential_store setting,
+// sealing keys under the passphrase when encrypt_keys is set
+func backendFor(cfg *config.Config) backend {
+	var inner backend = keyringBackend{}
+	if cfg.CredentialStore == config.CredentialStoreFile {
+		inner = fileBackend{}
+	}
+	return sealedBackend{inner: inner, seal: cfg.EncryptKeys}
+}
+
+// keyringBackend uses the OS keychain: macOS Keychain, Windows Credential
+// Manager, or the Secret Service (GNOME Keyring, KWallet) on Linux
+type keyringBackend struct{}
+
+func (keyringBackend) Set(service, name, value string) error {
+	return keyring.Set(service, name, value)
+}
+
+func (keyringBackend) Get(service, name string) (string, error) {
+	return keyring.Get(service, name)
+}
+
+func (keyringBackend) Delete(service, name string) error {
+	return keyring.Delete(service, name)
+}
+
+// fileBackend keeps each service's credentials in a JSON file only the
+// current user can read, for machines without a keychain. It protects
+// nothing beyond file permissions.
+type fileBackend struct {
+	// dir overrides the credentials directory in the config directory
+	dir string
+}
+
+func (b fileBackend) path(service string) (string, error) {
+	dir := b.dir
+	if dir == "" {
+		configDir, err := config.Dir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(configDir, "credentials")
+	}
+	return filepath.Join(dir, service+".json"), nil
+}
+
+// load reads a service's credentials; values are base64 so binary keys
+// survive JSON
+func (b fileBackend) load(service string) (map[string]string, error) {
+	path, err := b.path(service)
+	if err != nil {
+		return nil, err
+	}
+
+	credentials := map[string]string{}
+	data, err := os.ReadFile(path) // #nosec G304 - path is the CLI's own credentials file
+	if os.IsNotExist(err) {
+		return credentials, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials file: %w", err)
+	}
+	if err := json.Unmarshal(data, &credentials); err != nil {
+		return nil, fmt.Errorf("failed to parse credentials file %s: %w", path, err)
+	}
+	return credentials, nil
+}
+
+func (b fileBackend) save(service string, credentials map[string]string) error {
+	path, err := b.path(service)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), credentialsDirPermissions); err != nil {
+		return fmt.Errorf("failed to create credentials directory: %w", err)
+	}
+
+	return fsutil.WriteFileAtomic(path, fsutil.PrivateFilePermissions, func(w io.Writer) error {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(credentials)
+	})
+}
+
+func (b fileBackend) Set(service, name, value string) error {
+	credentials, err := b.load(service)
+	if err != nil {
+		return err
+	}
+	credentials[name] = base64.StdEncoding.EncodeToString([]byte(value))
+	return b.save(service, credentials)
+}
+
+func (b fileBackend) Get(service, name string) (string, error) {
+	credentials, err := b.load(service)
+	if err != nil {
+		return "", err
+	}
+	encoded, ok := credentials[name]
+	if !ok {
+		return "", keyring.ErrNotFound
+	}
+	value, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode credential %s: %w", name, err)
+	}
+	return string(value), nil
+}
+
+func (b fileBackend) Delete(service, name string) error {
+	credentials, err := b.load(service)
+	if err != nil {
+		return err
+	}
+	if _, ok := credentials[name]; !ok {
+		return keyring.ErrNotFound
+	}
+	delete(credentials, name)
+	return b.save(service, credentials)
+}
+
+func (b fileBackend) List(service string) ([]string, error) {
+	credentials, err := b.load(service)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(credentials))
+	for name := range credentials {
+		names = append(names, name)
+	}
+	return names, nil
+}
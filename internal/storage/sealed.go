@@ -0,0 +1,232 @@
+package storage
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/zalando/go-keyring"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+const (
+	// sealedPrefix marks a credential encrypted under the key passphrase
+	sealedPrefix = "ifsealed1:"
+
+	keySaltName   = "key-passphrase-salt"
+	keyCheckName  = "key-passphrase-check"
+	keyCheckValue = "initflow-key-check"
+	keySaltSize   = 16
+
+	// Argon2id cost of the key passphrase, as for backups
+	keyArgonTime    = 3
+	keyArgonMemory  = 64 * 1024 // KiB
+	keyArgonThreads = 4
+)
+
+var (
+	// ErrPassphraseRequired is returned when a key is passphrase protected
+	// and no passphrase source is set
+	ErrPassphraseRequired = errors.New("keys are passphrase protected but no passphrase was given")
+	// ErrWrongPassphrase is returned when the passphrase doesn't open the keys
+	ErrWrongPassphrase = errors.New("wrong key passphrase")
+)
+
+// PassphraseFunc supplies the passphrase protecting private and workspace
+// keys. create is set when none was chosen yet, so it can be asked twice.
+type PassphraseFunc func(create bool) ([]byte, error)
+
+var passphraseSource PassphraseFunc
+
+// SetPassphraseSource sets where protected keys get their passphrase from
+func SetPassphraseSource(fn PassphraseFunc) {
+	passphraseSource = fn
+}
+
+// sessionKeys caches the key derived from the passphrase per service name, so
+// it is asked for at most once per command
+var (
+	sessionKeysMu sync.Mutex
+	sessionKeys   = map[string][]byte{}
+)
+
+// ForgetPassphrase drops cached passphrase keys, so the next protected key
+// read asks again
+func ForgetPassphrase() {
+	sessionKeysMu.Lock()
+	defer sessionKeysMu.Unlock()
+	sessionKeys = map[string][]byte{}
+}
+
+// protectedName reports whether a credential is a private or workspace key,
+// the ones sealed under the passphrase
+func protectedName(name string) bool {
+	switch {
+	case name == "signing-private-key", name == "encryption-private-key":
+		return true
+	case strings.HasPrefix(name, "workspace-key-version-"):
+		return false
+	default:
+		return strings.HasPrefix(name, "workspace-key-")
+	}
+}
+
+// sealedBackend encrypts private and workspace keys before handing them to
+// another backend when seal is set. Sealed values are opened either way, so
+// turning protection off never strands a key.
+type sealedBackend struct {
+	inner backend
+	seal  bool
+}
+
+func (b sealedBackend) Set(service, name, value string) error {
+	if !b.seal || !protectedName(name) {
+		return b.inner.Set(service, name, value)
+	}
+
+	key, err := b.key(service, true)
+	if err != nil {
+		return err
+	}
+	sealed, err := sealValue(key, service, name, value)
+	if err != nil {
+		return err
+	}
+	return b.inner.Set(service, name, sealed)
+}
+
+func (b sealedBackend) Get(service, name string) (string, error) {
+	value, err := b.inner.Get(service, name)
+	if err != nil || !strings.HasPrefix(value, sealedPrefix) {
+		return value, err
+	}
+
+	key, err := b.key(service, false)
+	if err != nil {
+		return "", err
+	}
+	return openValue(key, service, name, value)
+}
+
+func (b sealedBackend) Delete(service, name string) error {
+	return b.inner.Delete(service, name)
+}
+
+// List enumerates the inner backend's credentials, or returns no names when
+// it can't list them
+func (b sealedBackend) List(service string) ([]string, error) {
+	if l, ok := b.inner.(lister); ok {
+		return l.List(service)
+	}
+	return nil, nil
+}
+
+// key derives the passphrase key of service, checking the passphrase against
+// the stored check value. With create set and no passphrase chosen yet, it
+// asks for a new one and stores its salt and check value.
+func (b sealedBackend) key(service string, create bool) ([]byte, error) {
+	sessionKeysMu.Lock()
+	defer sessionKeysMu.Unlock()
+	if key, ok := sessionKeys[service]; ok {
+		return key, nil
+	}
+
+	encodedSalt, err := b.inner.Get(service, keySaltName)
+	isNew := errors.Is(err, keyring.ErrNotFound)
+	switch {
+	case isNew && !create:
+		return nil, fmt.Errorf("key passphrase salt is missing; restore the keys from a backup")
+	case err != nil && !isNew:
+		return nil, fmt.Errorf("failed to read key passphrase salt: %w", err)
+	}
+
+	if passphraseSource == nil {
+		return nil, ErrPassphraseRequired
+	}
+	passphrase, err := passphraseSource(isNew)
+	if err != nil {
+		return nil, err
+	}
+	if len(passphrase) == 0 {
+		return nil, ErrPassphraseRequired
+	}
+
+	var salt []byte
+	if isNew {
+		salt = make([]byte, keySaltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, fmt.Errorf("failed to generate salt: %w", err)
+		}
+	} else if salt, err = base64.StdEncoding.DecodeString(encodedSalt); err != nil {
+		return nil, fmt.Errorf("failed to decode key passphrase salt: %w", err)
+	}
+
+	key := argon2.IDKey(passphrase, salt, keyArgonTime, keyArgonMemory, keyArgonThreads, chacha20poly1305.KeySize)
+
+	if isNew {
+		check, err := sealValue(key, service, keyCheckName, keyCheckValue)
+		if err != nil {
+			return nil, err
+		}
+		if err := b.inner.Set(service, keySaltName, base64.StdEncoding.EncodeToString(salt)); err != nil {
+			return nil, fmt.Errorf("failed to store key passphrase salt: %w", err)
+		}
+		if err := b.inner.Set(service, keyCheckName, check); err != nil {
+			return nil, fmt.Errorf("failed to store key passphrase check: %w", err)
+		}
+	} else {
+		check, err := b.inner.Get(service, keyCheckName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key passphrase check: %w", err)
+		}
+		if value, err := openValue(key, service, keyCheckName, check); err != nil || value != keyCheckValue {
+			return nil, ErrWrongPassphrase
+		}
+	}
+
+	sessionKeys[service] = key
+	return key, nil
+}
+
+// sealValue encrypts value with XChaCha20-Poly1305, bound to its service and
+// name so sealed values can't be swapped
+func sealValue(key []byte, service, name, value string) (string, error) {
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(value), []byte(service+"/"+name))
+	return sealedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func openValue(key []byte, service, name, value string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, sealedPrefix))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode %s: %w", name, err)
+	}
+
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to create cipher: %w", err)
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("failed to decrypt %s: value too short", name)
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(service+"/"+name))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt %s: %w", name, err)
+	}
+	return string(plaintext), nil
+}
@@ -2,59 +2,188 @@ package storage
 
 import (
 	"crypto/ed25519"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
-	"github.com/DylanBlakemore/initflow-cli/internal/config"
 	"github.com/zalando/go-keyring"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/config"
 )
 
 const (
 	DefaultServiceName = "initflow-cli"
+
+	// workspaceKeyIndexName lists the slugs of the cached workspace keys
+	workspaceKeyIndexName = "workspace-keys"
 )
 
 type Storage struct {
 	serviceName string
+	backend     backend
 }
 
+// New returns the storage of the active profile
 func New() *Storage {
+	return ForProfile(config.Get().ActiveProfile())
+}
+
+// ForProfile returns the storage of a profile. Every profile but the default
+// one keeps its token, device keys and workspace keys under its own service
+// name, so profiles never see each other's credentials.
+func ForProfile(profile string) *Storage {
 	serviceName := DefaultServiceName
 	cfg := config.Get()
 	if cfg.ServiceName != "" {
 		serviceName = cfg.ServiceName
 	}
-	return &Storage{
-		serviceName: serviceName,
+	if profile != "" && profile != config.DefaultProfile {
+		serviceName += "-" + profile
 	}
+	return NewWithServiceName(serviceName)
 }
 
 func NewWithServiceName(serviceName string) *Storage {
 	return &Storage{
 		serviceName: serviceName,
+		backend:     backendFor(config.Get()),
+	}
+}
+
+// WithKeyProtection returns the same storage, sealing private and workspace
+// keys it stores under the passphrase when protect is set, whatever the
+// encrypt_keys setting says
+func (s *Storage) WithKeyProtection(protect bool) *Storage {
+	inner := s.backend
+	if sealed, ok := inner.(sealedBackend); ok {
+		inner = sealed.inner
 	}
+	return &Storage{serviceName: s.serviceName, backend: sealedBackend{inner: inner, seal: protect}}
 }
 
 func (s *Storage) StoreToken(token string) error {
-	return keyring.Set(s.serviceName, "registration-token", token)
+	return s.backend.Set(s.serviceName, "registration-token", token)
 }
 
 func (s *Storage) GetToken() (string, error) {
-	token, err := keyring.Get(s.serviceName, "registration-token")
+	token, err := s.backend.Get(s.serviceName, "registration-token")
 	if err != nil {
 		return "", fmt.Errorf("failed to get token: %w", err)
 	}
 	return token, nil
 }
 
+// DeleteToken removes the registration token along with its expiry and the
+// refresh token that renews it
 func (s *Storage) DeleteToken() error {
-	return keyring.Delete(s.serviceName, "registration-token")
+	_ = s.backend.Delete(s.serviceName, "registration-token-expiry") // Older logins stored no expiry
+	_ = s.DeleteRefreshToken()
+	return s.backend.Delete(s.serviceName, "registration-token")
+}
+
+// StoreRefreshToken keeps the long-lived token that renews the registration token
+func (s *Storage) StoreRefreshToken(token string) error {
+	return s.backend.Set(s.serviceName, "refresh-token", token)
+}
+
+func (s *Storage) GetRefreshToken() (string, error) {
+	token, err := s.backend.Get(s.serviceName, "refresh-token")
+	if err != nil {
+		return "", fmt.Errorf("failed to get refresh token: %w", err)
+	}
+	return token, nil
+}
+
+// DeleteRefreshToken removes the refresh token and its expiry
+func (s *Storage) DeleteRefreshToken() error {
+	_ = s.backend.Delete(s.serviceName, "refresh-token-expiry")
+	return s.backend.Delete(s.serviceName, "refresh-token")
+}
+
+func (s *Storage) HasRefreshToken() bool {
+	_, err := s.GetRefreshToken()
+	return err == nil
+}
+
+// StoreRefreshTokenExpiry records when the refresh token stops being valid
+func (s *Storage) StoreRefreshTokenExpiry(expiresAt time.Time) error {
+	return s.backend.Set(s.serviceName, "refresh-token-expiry", expiresAt.UTC().Format(time.RFC3339))
+}
+
+// RefreshTokenExpiry returns when the stored refresh token expires
+func (s *Storage) RefreshTokenExpiry() (time.Time, error) {
+	value, err := s.backend.Get(s.serviceName, "refresh-token-expiry")
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get refresh token expiry: %w", err)
+	}
+
+	expiresAt, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid refresh token expiry %q: %w", value, err)
+	}
+	return expiresAt, nil
+}
+
+// StoreTokenExpiry records when the registration token stops being valid
+func (s *Storage) StoreTokenExpiry(expiresAt time.Time) error {
+	return s.backend.Set(s.serviceName, "registration-token-expiry", expiresAt.UTC().Format(time.RFC3339))
+}
+
+// TokenExpiry returns when the stored registration token expires
+func (s *Storage) TokenExpiry() (time.Time, error) {
+	value, err := s.backend.Get(s.serviceName, "registration-token-expiry")
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get token expiry: %w", err)
+	}
+
+	expiresAt, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid token expiry %q: %w", value, err)
+	}
+	return expiresAt, nil
+}
+
+// StoreClockOffset records how far the server's clock is ahead of this
+// machine's, so expiry checks stay right offline on a skewed clock
+func (s *Storage) StoreClockOffset(offset time.Duration) error {
+	return s.backend.Set(s.serviceName, "clock-offset", offset.String())
+}
+
+// ClockOffset returns the last recorded server clock offset, or 0 if none was
+func (s *Storage) ClockOffset() time.Duration {
+	value, err := s.backend.Get(s.serviceName, "clock-offset")
+	if err != nil {
+		return 0
+	}
+
+	offset, err := time.ParseDuration(value)
+	if err != nil {
+		return 0
+	}
+	return offset
+}
+
+// TokenExpired reports whether the registration token has expired at now,
+// judged by the server's clock using the last recorded offset
+func (s *Storage) TokenExpired(now time.Time) (bool, error) {
+	expiresAt, err := s.TokenExpiry()
+	if err != nil {
+		return false, err
+	}
+	return !now.Add(s.ClockOffset()).Before(expiresAt), nil
 }
 
 func (s *Storage) StoreDeviceID(deviceID string) error {
-	return keyring.Set(s.serviceName, "device-id", deviceID)
+	return s.backend.Set(s.serviceName, "device-id", deviceID)
 }
 
 func (s *Storage) GetDeviceID() (string, error) {
-	deviceID, err := keyring.Get(s.serviceName, "device-id")
+	deviceID, err := s.backend.Get(s.serviceName, "device-id")
 	if err != nil {
 		return "", fmt.Errorf("failed to get device ID: %w", err)
 	}
@@ -62,7 +191,24 @@ func (s *Storage) GetDeviceID() (string, error) {
 }
 
 func (s *Storage) DeleteDeviceID() error {
-	return keyring.Delete(s.serviceName, "device-id")
+	return s.backend.Delete(s.serviceName, "device-id")
+}
+
+// StoreDeviceName records the name this device was registered or renamed with
+func (s *Storage) StoreDeviceName(name string) error {
+	return s.backend.Set(s.serviceName, "device-name", name)
+}
+
+func (s *Storage) GetDeviceName() (string, error) {
+	name, err := s.backend.Get(s.serviceName, "device-name")
+	if err != nil {
+		return "", fmt.Errorf("failed to get device name: %w", err)
+	}
+	return name, nil
+}
+
+func (s *Storage) DeleteDeviceName() error {
+	return s.backend.Delete(s.serviceName, "device-name")
 }
 
 func (s *Storage) HasToken() bool {
@@ -76,11 +222,11 @@ func (s *Storage) HasDeviceID() bool {
 }
 
 func (s *Storage) StoreSigningPrivateKey(privateKey ed25519.PrivateKey) error {
-	return keyring.Set(s.serviceName, "signing-private-key", string(privateKey))
+	return s.backend.Set(s.serviceName, "signing-private-key", string(privateKey))
 }
 
 func (s *Storage) GetSigningPrivateKey() (ed25519.PrivateKey, error) {
-	keyStr, err := keyring.Get(s.serviceName, "signing-private-key")
+	keyStr, err := s.backend.Get(s.serviceName, "signing-private-key")
 	if err != nil {
 		return nil, fmt.Errorf("failed to get signing private key: %w", err)
 	}
@@ -88,15 +234,15 @@ func (s *Storage) GetSigningPrivateKey() (ed25519.PrivateKey, error) {
 }
 
 func (s *Storage) DeleteSigningPrivateKey() error {
-	return keyring.Delete(s.serviceName, "signing-private-key")
+	return s.backend.Delete(s.serviceName, "signing-private-key")
 }
 
 func (s *Storage) StoreEncryptionPrivateKey(privateKey []byte) error {
-	return keyring.Set(s.serviceName, "encryption-private-key", string(privateKey))
+	return s.backend.Set(s.serviceName, "encryption-private-key", string(privateKey))
 }
 
 func (s *Storage) GetEncryptionPrivateKey() ([]byte, error) {
-	keyStr, err := keyring.Get(s.serviceName, "encryption-private-key")
+	keyStr, err := s.backend.Get(s.serviceName, "encryption-private-key")
 	if err != nil {
 		return nil, fmt.Errorf("failed to get encryption private key: %w", err)
 	}
@@ -104,7 +250,7 @@ func (s *Storage) GetEncryptionPrivateKey() ([]byte, error) {
 }
 
 func (s *Storage) DeleteEncryptionPrivateKey() error {
-	return keyring.Delete(s.serviceName, "encryption-private-key")
+	return s.backend.Delete(s.serviceName, "encryption-private-key")
 }
 
 func (s *Storage) HasSigningPrivateKey() bool {
@@ -117,14 +263,35 @@ func (s *Storage) HasEncryptionPrivateKey() bool {
 	return err == nil
 }
 
+// ErrDeviceNotReady is returned by EnsureDeviceReady when this machine lacks
+// part of its device identity
+var ErrDeviceNotReady = errors.New("device keys missing, run 'initflow device register'")
+
+// EnsureDeviceReady checks that the device ID and both private keys are stored,
+// so commands can fail up front instead of midway through network or crypto work.
+func (s *Storage) EnsureDeviceReady() error {
+	switch {
+	case !s.HasDeviceID():
+		return fmt.Errorf("%w (no device ID)", ErrDeviceNotReady)
+	case !s.HasSigningPrivateKey():
+		return fmt.Errorf("%w (no signing private key)", ErrDeviceNotReady)
+	case !s.HasEncryptionPrivateKey():
+		return fmt.Errorf("%w (no encryption private key)", ErrDeviceNotReady)
+	}
+	return nil
+}
+
 func (s *Storage) StoreWorkspaceKey(workspaceSlug string, key []byte) error {
 	keyName := fmt.Sprintf("workspace-key-%s", workspaceSlug)
-	return keyring.Set(s.serviceName, keyName, string(key))
+	if err := s.backend.Set(s.serviceName, keyName, string(key)); err != nil {
+		return err
+	}
+	return s.indexWorkspaceKey(workspaceSlug, true)
 }
 
 func (s *Storage) GetWorkspaceKey(workspaceSlug string) ([]byte, error) {
 	keyName := fmt.Sprintf("workspace-key-%s", workspaceSlug)
-	keyStr, err := keyring.Get(s.serviceName, keyName)
+	keyStr, err := s.backend.Get(s.serviceName, keyName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get workspace key for %s: %w", workspaceSlug, err)
 	}
@@ -133,7 +300,121 @@ func (s *Storage) GetWorkspaceKey(workspaceSlug string) ([]byte, error) {
 
 func (s *Storage) DeleteWorkspaceKey(workspaceSlug string) error {
 	keyName := fmt.Sprintf("workspace-key-%s", workspaceSlug)
-	return keyring.Delete(s.serviceName, keyName)
+	_ = s.backend.Delete(s.serviceName, fmt.Sprintf("workspace-key-version-%s", workspaceSlug))
+	if err := s.backend.Delete(s.serviceName, keyName); err != nil {
+		return err
+	}
+	return s.indexWorkspaceKey(workspaceSlug, false)
+}
+
+// WorkspaceKeySlugs lists the workspaces with a cached key. Keychains can't
+// be enumerated, so it reads the index StoreWorkspaceKey keeps, plus the
+// credentials themselves where the backend can list them.
+func (s *Storage) WorkspaceKeySlugs() ([]string, error) {
+	slugs, err := s.workspaceKeyIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	if l, ok := s.backend.(lister); ok {
+		names, err := l.List(s.serviceName)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			slug, ok := strings.CutPrefix(name, "workspace-key-")
+			if ok && protectedName(name) && !slices.Contains(slugs, slug) {
+				slugs = append(slugs, slug)
+			}
+		}
+	}
+
+	sort.Strings(slugs)
+	return slugs, nil
+}
+
+// workspaceKeyIndex reads the slugs StoreWorkspaceKey has recorded
+func (s *Storage) workspaceKeyIndex() ([]string, error) {
+	value, err := s.backend.Get(s.serviceName, workspaceKeyIndexName)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the workspace key index: %w", err)
+	}
+
+	var slugs []string
+	if err := json.Unmarshal([]byte(value), &slugs); err != nil {
+		return nil, fmt.Errorf("failed to parse the workspace key index: %w", err)
+	}
+	return slugs, nil
+}
+
+// indexWorkspaceKey adds a slug to the workspace key index, or removes it
+func (s *Storage) indexWorkspaceKey(workspaceSlug string, stored bool) error {
+	slugs, err := s.workspaceKeyIndex()
+	if err != nil {
+		return err
+	}
+	if slices.Contains(slugs, workspaceSlug) == stored {
+		return nil
+	}
+
+	if stored {
+		slugs = append(slugs, workspaceSlug)
+	} else {
+		slugs = slices.DeleteFunc(slugs, func(slug string) bool { return slug == workspaceSlug })
+	}
+	data, err := json.Marshal(slugs)
+	if err != nil {
+		return err
+	}
+	if err := s.backend.Set(s.serviceName, workspaceKeyIndexName, string(data)); err != nil {
+		return fmt.Errorf("failed to update the workspace key index: %w", err)
+	}
+	return nil
+}
+
+// StoreWorkspaceKeyVersion records which version of the workspace key is cached
+func (s *Storage) StoreWorkspaceKeyVersion(workspaceSlug string, version int) error {
+	keyName := fmt.Sprintf("workspace-key-version-%s", workspaceSlug)
+	return s.backend.Set(s.serviceName, keyName, strconv.Itoa(version))
+}
+
+// WorkspaceKeyVersion returns the version of the cached workspace key, or 0 if
+// it was cached before versions were recorded
+func (s *Storage) WorkspaceKeyVersion(workspaceSlug string) int {
+	value, err := s.backend.Get(s.serviceName, fmt.Sprintf("workspace-key-version-%s", workspaceSlug))
+	if err != nil {
+		return 0
+	}
+
+	version, err := strconv.Atoi(value)
+	if err != nil {
+		return 0
+	}
+	return version
+}
+
+// RenameWorkspaceKey moves a cached workspace key to a new slug
+func (s *Storage) RenameWorkspaceKey(oldSlug, newSlug string) error {
+	key, err := s.GetWorkspaceKey(oldSlug)
+	if err != nil {
+		return err
+	}
+	version := s.WorkspaceKeyVersion(oldSlug)
+	if err := s.StoreWorkspaceKey(newSlug, key); err != nil {
+		return fmt.Errorf("failed to store workspace key for %s: %w", newSlug, err)
+	}
+	if version > 0 {
+		if err := s.StoreWorkspaceKeyVersion(newSlug, version); err != nil {
+			return fmt.Errorf("failed to store workspace key version for %s: %w", newSlug, err)
+		}
+	}
+	if err := s.DeleteWorkspaceKey(oldSlug); err != nil {
+		return fmt.Errorf("failed to delete workspace key for %s: %w", oldSlug, err)
+	}
+	return nil
 }
 
 func (s *Storage) HasWorkspaceKey(workspaceSlug string) bool {
@@ -157,6 +438,9 @@ func (s *Storage) ClearDeviceCredentials() error {
 		errors = append(errors, fmt.Errorf("failed to delete encryption private key: %w", err))
 	}
 
+	// Devices registered before names were stored locally have none
+	_ = s.DeleteDeviceName()
+
 	// Also clean up any leftover registration token
 	_ = s.DeleteToken() // Ignore error as token might not exist
 
@@ -3,9 +3,14 @@ package storage
 import (
 	"crypto/ed25519"
 	"crypto/rand"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/zalando/go-keyring"
 )
 
 // Note: These tests use the actual keyring, which may require user interaction
@@ -211,3 +216,247 @@ func TestStorage_EncryptionPrivateKeyOperations(t *testing.T) {
 	_, err = storage.GetEncryptionPrivateKey()
 	assert.Error(t, err)
 }
+
+func TestStorage_TokenExpiryOperations(t *testing.T) {
+	storage := NewWithServiceName("initflow-cli-test-expiry")
+	expiresAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	_ = storage.DeleteToken()
+
+	_, err := storage.TokenExpiry()
+	assert.Error(t, err)
+
+	err = storage.StoreToken("expiring-token")
+	if err != nil {
+		t.Skipf("Skipping keyring test due to error: %v", err)
+		return
+	}
+
+	err = storage.StoreTokenExpiry(expiresAt)
+	assert.NoError(t, err)
+
+	retrieved, err := storage.TokenExpiry()
+	assert.NoError(t, err)
+	assert.True(t, expiresAt.Equal(retrieved))
+
+	// Deleting the token also forgets its expiry
+	err = storage.DeleteToken()
+	assert.NoError(t, err)
+
+	_, err = storage.TokenExpiry()
+	assert.Error(t, err)
+}
+
+func TestStorage_RefreshTokenOperations(t *testing.T) {
+	storage := NewWithServiceName("initflow-cli-test-refresh")
+	expiresAt := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	_ = storage.DeleteToken()
+	assert.False(t, storage.HasRefreshToken())
+
+	err := storage.StoreToken("registration-token")
+	if err != nil {
+		t.Skipf("Skipping keyring test due to error: %v", err)
+		return
+	}
+	assert.NoError(t, storage.StoreRefreshToken("refresh-token"))
+	assert.NoError(t, storage.StoreRefreshTokenExpiry(expiresAt))
+
+	token, err := storage.GetRefreshToken()
+	assert.NoError(t, err)
+	assert.Equal(t, "refresh-token", token)
+	retrieved, err := storage.RefreshTokenExpiry()
+	assert.NoError(t, err)
+	assert.True(t, expiresAt.Equal(retrieved))
+
+	// Logging out forgets the refresh token too
+	assert.NoError(t, storage.DeleteToken())
+	assert.False(t, storage.HasRefreshToken())
+	_, err = storage.RefreshTokenExpiry()
+	assert.Error(t, err)
+}
+
+func TestStorage_TokenExpiredUsesClockOffset(t *testing.T) {
+	storage := NewWithServiceName("initflow-cli-test-clock-offset")
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	_, err := storage.TokenExpired(now)
+	assert.Error(t, err, "no expiry stored")
+
+	if err := storage.StoreTokenExpiry(now.Add(time.Minute)); err != nil {
+		t.Skipf("Skipping keyring test due to error: %v", err)
+		return
+	}
+	defer func() { _ = storage.DeleteToken() }()
+
+	expired, err := storage.TokenExpired(now)
+	assert.NoError(t, err)
+	assert.False(t, expired)
+
+	// The server is five minutes ahead, so by its clock the token is gone
+	assert.NoError(t, storage.StoreClockOffset(5*time.Minute))
+	assert.Equal(t, 5*time.Minute, storage.ClockOffset())
+	expired, err = storage.TokenExpired(now)
+	assert.NoError(t, err)
+	assert.True(t, expired)
+
+	// A machine clock running ahead must not expire the token early
+	assert.NoError(t, storage.StoreClockOffset(-5*time.Minute))
+	expired, err = storage.TokenExpired(now.Add(3 * time.Minute))
+	assert.NoError(t, err)
+	assert.False(t, expired)
+}
+
+func TestStorage_EnsureDeviceReady(t *testing.T) {
+	storage := NewWithServiceName("initflow-cli-test-device-ready")
+
+	err := storage.EnsureDeviceReady()
+	assert.ErrorIs(t, err, ErrDeviceNotReady)
+
+	if err := storage.StoreDeviceID("device-123"); err != nil {
+		t.Skipf("Skipping keyring test due to error: %v", err)
+		return
+	}
+	defer func() { _ = storage.ClearDeviceCredentials() }()
+
+	_, signingKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	assert.NoError(t, storage.StoreSigningPrivateKey(signingKey))
+
+	err = storage.EnsureDeviceReady()
+	assert.ErrorIs(t, err, ErrDeviceNotReady)
+	assert.Contains(t, err.Error(), "no encryption private key")
+
+	assert.NoError(t, storage.StoreEncryptionPrivateKey(make([]byte, 32)))
+	assert.NoError(t, storage.EnsureDeviceReady())
+}
+
+func TestStorage_WorkspaceKeyVersion(t *testing.T) {
+	storage := NewWithServiceName("initflow-cli-test-key-version")
+
+	if err := storage.StoreWorkspaceKey("old-slug", []byte("key")); err != nil {
+		t.Skipf("Skipping keyring test due to error: %v", err)
+		return
+	}
+	defer func() { _ = storage.DeleteWorkspaceKey("new-slug") }()
+
+	assert.Equal(t, 0, storage.WorkspaceKeyVersion("old-slug"))
+	assert.NoError(t, storage.StoreWorkspaceKeyVersion("old-slug", 3))
+	assert.Equal(t, 3, storage.WorkspaceKeyVersion("old-slug"))
+
+	assert.NoError(t, storage.RenameWorkspaceKey("old-slug", "new-slug"))
+	assert.Equal(t, 3, storage.WorkspaceKeyVersion("new-slug"))
+	assert.Equal(t, 0, storage.WorkspaceKeyVersion("old-slug"))
+
+	assert.NoError(t, storage.DeleteWorkspaceKey("new-slug"))
+	assert.Equal(t, 0, storage.WorkspaceKeyVersion("new-slug"))
+}
+
+func TestStorage_FileBackend(t *testing.T) {
+	dir := t.TempDir()
+	storage := &Storage{serviceName: "initflow-cli-test-file", backend: fileBackend{dir: dir}}
+
+	signingPublic, signingKey, _ := ed25519.GenerateKey(rand.Reader)
+	workspaceKey := make([]byte, 32)
+	_, _ = rand.Read(workspaceKey)
+
+	assert.False(t, storage.HasToken())
+	assert.NoError(t, storage.StoreToken("file-token"))
+	assert.NoError(t, storage.StoreSigningPrivateKey(signingKey))
+	assert.NoError(t, storage.StoreWorkspaceKey("my-project", workspaceKey))
+
+	// A new Storage reads what the first one wrote
+	reopened := &Storage{serviceName: "initflow-cli-test-file", backend: fileBackend{dir: dir}}
+	token, err := reopened.GetToken()
+	assert.NoError(t, err)
+	assert.Equal(t, "file-token", token)
+	key, err := reopened.GetSigningPrivateKey()
+	assert.NoError(t, err)
+	assert.Equal(t, signingPublic, key.Public())
+	cached, err := reopened.GetWorkspaceKey("my-project")
+	assert.NoError(t, err)
+	assert.Equal(t, workspaceKey, cached)
+
+	info, err := os.Stat(filepath.Join(dir, "initflow-cli-test-file.json"))
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	assert.NoError(t, reopened.DeleteToken())
+	assert.False(t, storage.HasToken())
+	assert.ErrorIs(t, storage.DeleteDeviceID(), keyring.ErrNotFound)
+}
+
+func TestStorage_PassphraseProtectedKeys(t *testing.T) {
+	dir := t.TempDir()
+	inner := fileBackend{dir: dir}
+	passphrase := "correct horse"
+	asked := 0
+	SetPassphraseSource(func(create bool) ([]byte, error) {
+		asked++
+		return []byte(passphrase), nil
+	})
+	t.Cleanup(func() {
+		SetPassphraseSource(nil)
+		ForgetPassphrase()
+	})
+
+	protected := &Storage{serviceName: "initflow-cli-test-sealed", backend: sealedBackend{inner: inner, seal: true}}
+	_, signingKey, _ := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, protected.StoreSigningPrivateKey(signingKey))
+	assert.NoError(t, protected.StoreWorkspaceKey("my-project", []byte("workspace-key")))
+	assert.NoError(t, protected.StoreDeviceID("device-1"))
+	assert.Equal(t, 1, asked, "the passphrase is asked once per session")
+
+	raw, err := inner.Get("initflow-cli-test-sealed", "signing-private-key")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(raw, sealedPrefix), "private keys are sealed at rest")
+	raw, err = inner.Get("initflow-cli-test-sealed", "device-id")
+	assert.NoError(t, err)
+	assert.Equal(t, "device-1", raw, "only keys are sealed")
+
+	// A new session asks again and rejects a wrong passphrase
+	ForgetPassphrase()
+	passphrase = "wrong"
+	_, err = protected.GetSigningPrivateKey()
+	assert.ErrorIs(t, err, ErrWrongPassphrase)
+
+	// Keys stay readable with protection turned off
+	passphrase = "correct horse"
+	unprotected := &Storage{serviceName: "initflow-cli-test-sealed", backend: sealedBackend{inner: inner}}
+	key, err := unprotected.GetSigningPrivateKey()
+	assert.NoError(t, err)
+	assert.Equal(t, signingKey, key)
+	cached, err := unprotected.GetWorkspaceKey("my-project")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("workspace-key"), cached)
+}
+
+func TestStorage_WorkspaceKeySlugs(t *testing.T) {
+	dir := t.TempDir()
+	inner := fileBackend{dir: dir}
+	storage := &Storage{serviceName: "initflow-cli-test-slugs", backend: sealedBackend{inner: inner}}
+
+	assert.NoError(t, storage.StoreWorkspaceKey("web", []byte("web-key")))
+	assert.NoError(t, storage.StoreWorkspaceKey("api", []byte("api-key")))
+	assert.NoError(t, storage.StoreWorkspaceKeyVersion("api", 2))
+	// Cached before the index was kept
+	assert.NoError(t, inner.Set("initflow-cli-test-slugs", "workspace-key-legacy", "legacy-key"))
+
+	slugs, err := storage.WorkspaceKeySlugs()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"api", "legacy", "web"}, slugs)
+
+	assert.NoError(t, storage.RenameWorkspaceKey("web", "site"))
+	assert.NoError(t, storage.DeleteWorkspaceKey("legacy"))
+	slugs, err = storage.WorkspaceKeySlugs()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"api", "site"}, slugs)
+
+	// Keychains can't be listed, so only the index is read
+	keyring.MockInit()
+	keychain := &Storage{serviceName: "initflow-cli-test-slugs", backend: sealedBackend{inner: keyringBackend{}}}
+	assert.NoError(t, keychain.StoreWorkspaceKey("api", []byte("api-key")))
+	slugs, err = keychain.WorkspaceKeySlugs()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"api"}, slugs)
+}
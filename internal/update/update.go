@@ -0,0 +1,340 @@
+// Package update finds, verifies and installs new CLI releases.
+package update
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"cmp"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/DylanBlakemore/initflow-cli/internal/fsutil"
+)
+
+// LatestReleaseURL is the GitHub API endpoint describing the newest release
+const LatestReleaseURL = "https://api.github.com/repos/DylanBlakemore/initflow-cli/releases/latest"
+
+// ChecksumsAsset is the release asset listing the SHA-256 of every archive
+const ChecksumsAsset = "checksums.txt"
+
+// maxDownloadSize bounds release downloads
+const maxDownloadSize = 100 << 20
+
+var (
+	// ErrChecksumMismatch is returned when a download doesn't match checksums.txt
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	// ErrNotWritable is returned when the running executable can't be replaced
+	ErrNotWritable = errors.New("install location is not writable")
+)
+
+// Release is a published CLI version and its downloadable assets by name
+type Release struct {
+	Version string
+	Assets  map[string]string
+}
+
+type githubRelease struct {
+	TagName string `json:"tag_name"`
+	Assets  []struct {
+		Name string `json:"name"`
+		URL  string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+// LatestRelease fetches the newest release from url
+func LatestRelease(httpClient *http.Client, url string) (*Release, error) {
+	body, err := Download(httpClient, url)
+	if err != nil {
+		return nil, err
+	}
+
+	var gh githubRelease
+	if err := json.Unmarshal(body, &gh); err != nil {
+		return nil, fmt.Errorf("failed to parse release: %w", err)
+	}
+	if gh.TagName == "" {
+		return nil, fmt.Errorf("release has no version tag")
+	}
+
+	release := &Release{Version: gh.TagName, Assets: make(map[string]string, len(gh.Assets))}
+	for _, asset := range gh.Assets {
+		release.Assets[asset.Name] = asset.URL
+	}
+	return release, nil
+}
+
+// Download fetches url, failing on non-200 responses
+func Download(httpClient *http.Client, url string) ([]byte, error) {
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: status %d", url, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxDownloadSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", url, err)
+	}
+	return body, nil
+}
+
+// CompareVersions compares two vMAJOR.MINOR.PATCH versions, returning -1, 0 or 1.
+// A pre-release (v1.2.0-rc.1) sorts before its release, and pre-releases
+// compare as semver orders them, so rc.10 comes after rc.9.
+func CompareVersions(a, b string) (int, error) {
+	va, preA, err := parseVersion(a)
+	if err != nil {
+		return 0, err
+	}
+	vb, preB, err := parseVersion(b)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range va {
+		if va[i] != vb[i] {
+			if va[i] < vb[i] {
+				return -1, nil
+			}
+			return 1, nil
+		}
+	}
+
+	switch {
+	case preA == preB:
+		return 0, nil
+	case preA == "":
+		return 1, nil
+	case preB == "":
+		return -1, nil
+	}
+	return comparePrerelease(preA, preB), nil
+}
+
+// comparePrerelease compares dot-separated pre-release tags field by field:
+// numeric fields numerically and below alphanumeric ones, others as strings,
+// and a tag that is a prefix of the other first
+func comparePrerelease(a, b string) int {
+	fieldsA, fieldsB := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(fieldsA) && i < len(fieldsB); i++ {
+		na, errA := strconv.ParseUint(fieldsA[i], 10, 64)
+		nb, errB := strconv.ParseUint(fieldsB[i], 10, 64)
+		switch {
+		case errA == nil && errB == nil:
+			if na != nb {
+				return cmp.Compare(na, nb)
+			}
+		case errA == nil:
+			return -1
+		case errB == nil:
+			return 1
+		default:
+			if c := strings.Compare(fieldsA[i], fieldsB[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return cmp.Compare(len(fieldsA), len(fieldsB))
+}
+
+func parseVersion(v string) ([3]int, string, error) {
+	var parts [3]int
+
+	core := strings.TrimPrefix(strings.TrimSpace(v), "v")
+	core, _, _ = strings.Cut(core, "+")
+	core, pre, _ := strings.Cut(core, "-")
+
+	fields := strings.Split(core, ".")
+	if len(fields) != len(parts) {
+		return parts, "", fmt.Errorf("invalid version %q", v)
+	}
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return parts, "", fmt.Errorf("invalid version %q", v)
+		}
+		parts[i] = n
+	}
+	return parts, pre, nil
+}
+
+// AssetName is the release archive for a platform, e.g. initflow-linux-amd64.tar.gz
+func AssetName(goos, goarch string) string {
+	if goos == "windows" {
+		return fmt.Sprintf("initflow-%s-%s.zip", goos, goarch)
+	}
+	return fmt.Sprintf("initflow-%s-%s.tar.gz", goos, goarch)
+}
+
+// BinaryName is the executable inside the platform's release archive
+func BinaryName(goos, goarch string) string {
+	if goos == "windows" {
+		return fmt.Sprintf("initflow-%s-%s.exe", goos, goarch)
+	}
+	return fmt.Sprintf("initflow-%s-%s", goos, goarch)
+}
+
+// VerifyChecksum checks data against the entry for name in a sha256sum-style
+// checksums file ("<hex digest>  <file name>" per line).
+func VerifyChecksum(data, checksums []byte, name string) error {
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || strings.TrimPrefix(fields[1], "*") != name {
+			continue
+		}
+
+		expected, err := hex.DecodeString(fields[0])
+		if err != nil || len(expected) != sha256.Size {
+			return fmt.Errorf("invalid checksum for %s", name)
+		}
+
+		actual := sha256.Sum256(data)
+		if !bytes.Equal(actual[:], expected) {
+			return fmt.Errorf("%w for %s", ErrChecksumMismatch, name)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("no checksum listed for %s", name)
+}
+
+// ExtractBinary returns the file called binaryName from a .tar.gz or .zip archive
+func ExtractBinary(archive []byte, assetName, binaryName string) ([]byte, error) {
+	if strings.HasSuffix(assetName, ".zip") {
+		return extractZip(archive, binaryName)
+	}
+	return extractTarGz(archive, binaryName)
+}
+
+func extractTarGz(archive []byte, binaryName string) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+		if header.Typeflag == tar.TypeReg && filepath.Base(header.Name) == binaryName {
+			return io.ReadAll(io.LimitReader(tr, maxDownloadSize))
+		}
+	}
+
+	return nil, fmt.Errorf("%s not found in archive", binaryName)
+}
+
+func extractZip(archive []byte, binaryName string) ([]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+
+	for _, file := range zr.File {
+		if filepath.Base(file.Name) != binaryName {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+		defer func() {
+			_ = rc.Close()
+		}()
+		return io.ReadAll(io.LimitReader(rc, maxDownloadSize))
+	}
+
+	return nil, fmt.Errorf("%s not found in archive", binaryName)
+}
+
+// executablePermissions are for the installed binary
+const executablePermissions = 0755
+
+// ReplaceExecutable swaps the executable at path for binary. The new file is
+// written beside it, fsync-ed and renamed over path, so path always holds a
+// whole binary. Windows can't overwrite a running program, so there the old
+// file is moved aside first instead.
+func ReplaceExecutable(path string, binary []byte) error {
+	if runtime.GOOS == "windows" {
+		return replaceMovingAside(path, binary)
+	}
+	err := fsutil.WriteFile(path, binary, executablePermissions)
+	if errors.Is(err, os.ErrPermission) {
+		return fmt.Errorf("%w: %s", ErrNotWritable, filepath.Dir(path))
+	}
+	return err
+}
+
+// replaceMovingAside installs binary at path by renaming the running one to
+// path.old and the new one into its place, putting the old one back when the
+// second rename fails
+func replaceMovingAside(path string, binary []byte) error {
+	dir := filepath.Dir(path)
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".new-*")
+	if err != nil {
+		if errors.Is(err, os.ErrPermission) {
+			return fmt.Errorf("%w: %s", ErrNotWritable, dir)
+		}
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpName := tmp.Name()
+
+	_, err = tmp.Write(binary)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmpName)
+		return fmt.Errorf("failed to write new executable: %w", err)
+	}
+
+	old := path + ".old"
+	_ = os.Remove(old)
+	if err := os.Rename(path, old); err != nil {
+		_ = os.Remove(tmpName)
+		if errors.Is(err, os.ErrPermission) {
+			return fmt.Errorf("%w: %s", ErrNotWritable, path)
+		}
+		return fmt.Errorf("failed to move old executable aside: %w", err)
+	}
+
+	if err := os.Rename(tmpName, path); err != nil {
+		if restoreErr := os.Rename(old, path); restoreErr != nil {
+			return fmt.Errorf("failed to install new executable: %w; the old one is at %s", err, old)
+		}
+		_ = os.Remove(tmpName)
+		return fmt.Errorf("failed to install new executable: %w", err)
+	}
+
+	_ = os.Remove(old) // Fails harmlessly while the old binary is running
+	return nil
+}
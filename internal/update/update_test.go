@@ -0,0 +1,139 @@
+package update
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"v0.1.0", "v0.1.0", 0},
+		{"v0.1.0", "0.1.0", 0},
+		{"v0.1.0", "v0.2.0", -1},
+		{"v0.10.0", "v0.9.0", 1},
+		{"v1.0.0", "v0.99.99", 1},
+		{"v1.2.3", "v1.2.4", -1},
+		{"v1.2.0-rc.1", "v1.2.0", -1},
+		{"v1.2.0", "v1.2.0-rc.1", 1},
+		{"v1.2.0-rc.1", "v1.2.0-rc.2", -1},
+		{"v1.2.0-rc.10", "v1.2.0-rc.9", 1},
+		{"v1.2.0-rc.9", "v1.2.0-rc.10", -1},
+		{"v1.2.0-alpha", "v1.2.0-alpha.1", -1},
+		{"v1.2.0-alpha.1", "v1.2.0-alpha.beta", -1},
+		{"v1.2.0-beta.2", "v1.2.0-alpha.10", 1},
+		{"v1.2.0-rc.1", "v1.2.0-rc.1", 0},
+		{"v1.2.0+build.5", "v1.2.0", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+" vs "+tt.b, func(t *testing.T) {
+			cmp, err := CompareVersions(tt.a, tt.b)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, cmp)
+		})
+	}
+}
+
+func TestCompareVersionsInvalid(t *testing.T) {
+	for _, v := range []string{"", "latest", "v1.2", "v1.2.3.4", "v1.x.3", "v-1.0.0"} {
+		_, err := CompareVersions(v, "v1.0.0")
+		assert.Error(t, err, "expected error for %q", v)
+	}
+}
+
+func checksumsFor(name string, data []byte) []byte {
+	sum := sha256.Sum256(data)
+	return []byte(fmt.Sprintf("0000000000000000000000000000000000000000000000000000000000000000  other.tar.gz\n"+
+		"%s  %s\n", hex.EncodeToString(sum[:]), name))
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	data := []byte("release archive contents")
+	checksums := checksumsFor("initflow-linux-amd64.tar.gz", data)
+
+	assert.NoError(t, VerifyChecksum(data, checksums, "initflow-linux-amd64.tar.gz"))
+
+	err := VerifyChecksum([]byte("tampered"), checksums, "initflow-linux-amd64.tar.gz")
+	assert.True(t, errors.Is(err, ErrChecksumMismatch), "got %v", err)
+
+	err = VerifyChecksum(data, checksums, "initflow-darwin-arm64.tar.gz")
+	assert.ErrorContains(t, err, "no checksum listed")
+
+	err = VerifyChecksum(data, []byte("nothex  initflow-linux-amd64.tar.gz\n"), "initflow-linux-amd64.tar.gz")
+	assert.ErrorContains(t, err, "invalid checksum")
+}
+
+func TestVerifyChecksumBinaryModeMarker(t *testing.T) {
+	data := []byte("release archive contents")
+	sum := sha256.Sum256(data)
+	checksums := []byte(hex.EncodeToString(sum[:]) + " *initflow-windows-amd64.zip\n")
+
+	assert.NoError(t, VerifyChecksum(data, checksums, "initflow-windows-amd64.zip"))
+}
+
+func TestExtractBinaryTarGz(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	content := []byte("#!/bin/sh\necho new\n")
+	require.NoError(t, tw.WriteHeader(&tar.Header{
+		Name: "initflow-linux-amd64", Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg,
+	}))
+	_, err := tw.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+
+	binary, err := ExtractBinary(buf.Bytes(), "initflow-linux-amd64.tar.gz", "initflow-linux-amd64")
+	require.NoError(t, err)
+	assert.Equal(t, content, binary)
+
+	_, err = ExtractBinary(buf.Bytes(), "initflow-linux-amd64.tar.gz", "initflow-linux-arm64")
+	assert.Error(t, err)
+}
+
+func TestReplaceExecutable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "initflow")
+	require.NoError(t, os.WriteFile(path, []byte("old"), 0755)) // #nosec G306 - test executable
+
+	require.NoError(t, ReplaceExecutable(path, []byte("new")))
+
+	content, err := os.ReadFile(path) // #nosec G304 - test file path is controlled
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(content))
+
+	_, err = os.Stat(path + ".old")
+	assert.True(t, os.IsNotExist(err), "old executable should be cleaned up")
+
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+	}
+}
+
+func TestReplaceMovingAside(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "initflow.exe")
+	require.NoError(t, os.WriteFile(path, []byte("old"), 0755)) // #nosec G306 - test executable
+
+	require.NoError(t, replaceMovingAside(path, []byte("new")))
+
+	content, err := os.ReadFile(path) // #nosec G304 - test file path is controlled
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(content))
+}